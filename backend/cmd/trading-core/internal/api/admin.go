@@ -0,0 +1,344 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-core/internal/events"
+	"trading-core/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin lets through logged-in sessions of users whose email is in
+// s.AdminEmails; API keys never reach admin endpoints.
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(authMethodKey) == authMethodAPIKey {
+			abortWithError(c, "SESSION_REQUIRED", "admin endpoints require a logged-in session")
+			return
+		}
+		user, err := s.DB.GetUserByID(c.Request.Context(), CurrentUserID(c))
+		if err != nil {
+			abortWithError(c, "DB_ERROR", err.Error())
+			return
+		}
+		if user == nil || !s.isAdminEmail(user.Email) {
+			abortWithError(c, "FORBIDDEN", "admin access required")
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) isAdminEmail(email string) bool {
+	for _, admin := range s.AdminEmails {
+		if strings.EqualFold(strings.TrimSpace(admin), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// haltSymbolRequest is the optional body of POST /admin/symbols/:symbol/halt.
+type haltSymbolRequest struct {
+	Reason string `json:"reason"`
+}
+
+// haltSymbol blocks new entries on one symbol; exits stay allowed.
+func (s *Server) haltSymbol(c *gin.Context) {
+	if s.Halts == nil {
+		respondError(c, "HALTS_UNAVAILABLE", "")
+		return
+	}
+	var req haltSymbolRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, "INVALID_PAYLOAD", err.Error())
+			return
+		}
+	}
+	halt := s.Halts.Halt(c.Param("symbol"), req.Reason)
+	s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "symbol_halted", UserID: CurrentUserID(c), Symbol: halt.Symbol, Message: halt.BlockReason()})
+	c.JSON(http.StatusOK, halt)
+}
+
+// resumeSymbol lifts a symbol halt.
+func (s *Server) resumeSymbol(c *gin.Context) {
+	if s.Halts == nil {
+		respondError(c, "HALTS_UNAVAILABLE", "")
+		return
+	}
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if !s.Halts.Resume(symbol) {
+		respondError(c, "SYMBOL_NOT_HALTED", "")
+		return
+	}
+	s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "symbol_resumed", UserID: CurrentUserID(c), Symbol: symbol, Message: fmt.Sprintf("symbol %s resumed", symbol)})
+	c.JSON(http.StatusOK, gin.H{"status": "resumed", "symbol": symbol})
+}
+
+// listQuarantinedFills returns the fills held for an abnormal price.
+func (s *Server) listQuarantinedFills(c *gin.Context) {
+	if s.Quarantine == nil {
+		respondError(c, "FILLS_UNAVAILABLE", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"fills": s.Quarantine.List()})
+}
+
+// releaseQuarantinedFill books a held fill at its reported price.
+func (s *Server) releaseQuarantinedFill(c *gin.Context) {
+	if s.Quarantine == nil {
+		respondError(c, "FILLS_UNAVAILABLE", "")
+		return
+	}
+	fill, ok := s.Quarantine.Release(c.Param("id"))
+	if !ok {
+		respondError(c, "FILL_NOT_QUARANTINED", "")
+		return
+	}
+	s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "fill_released", UserID: fill.UserID, Symbol: fill.Symbol,
+		Message: fmt.Sprintf("quarantined fill %s of order %s released by %s", fill.FillID, fill.OrderID, CurrentUserID(c))})
+	c.JSON(http.StatusOK, gin.H{"status": "released", "fill": fill})
+}
+
+// discardQuarantinedFill drops a held fill without booking it.
+func (s *Server) discardQuarantinedFill(c *gin.Context) {
+	if s.Quarantine == nil {
+		respondError(c, "FILLS_UNAVAILABLE", "")
+		return
+	}
+	fill, ok := s.Quarantine.Discard(c.Param("id"))
+	if !ok {
+		respondError(c, "FILL_NOT_QUARANTINED", "")
+		return
+	}
+	s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "fill_discarded", UserID: fill.UserID, Symbol: fill.Symbol,
+		Message: fmt.Sprintf("quarantined fill %s of order %s discarded by %s", fill.FillID, fill.OrderID, CurrentUserID(c))})
+	c.JSON(http.StatusOK, gin.H{"status": "discarded", "fill": fill})
+}
+
+// StrategyFreeze blocks creating and starting strategies (e.g. during an
+// incident) while strategies already running and their orders carry on. The
+// zero value is not frozen.
+type StrategyFreeze struct {
+	mu    sync.RWMutex
+	state FreezeState
+}
+
+// FreezeState is reported by /system/status and the admin freeze endpoints.
+type FreezeState struct {
+	Frozen bool       `json:"frozen"`
+	Reason string     `json:"reason,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+}
+
+// Freeze blocks new strategies. Freezing again keeps the original time and
+// updates the reason.
+func (f *StrategyFreeze) Freeze(reason string) FreezeState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.state.Frozen {
+		now := time.Now().UTC()
+		f.state = FreezeState{Frozen: true, Since: &now}
+	}
+	f.state.Reason = reason
+	return f.state
+}
+
+// Unfreeze allows new strategies again and reports whether they were frozen.
+func (f *StrategyFreeze) Unfreeze() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	was := f.state.Frozen
+	f.state = FreezeState{}
+	return was
+}
+
+// State returns the current freeze.
+func (f *StrategyFreeze) State() FreezeState {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.state
+}
+
+// rejectIfFrozen responds STRATEGIES_FROZEN and returns true while new
+// strategies are frozen.
+func (s *Server) rejectIfFrozen(c *gin.Context) bool {
+	state := s.Freeze.State()
+	if !state.Frozen {
+		return false
+	}
+	msg := "new strategies are frozen; running strategies are unaffected"
+	if state.Reason != "" {
+		msg += ": " + state.Reason
+	}
+	respondError(c, "STRATEGIES_FROZEN", msg)
+	return true
+}
+
+// freezeStrategiesRequest is the optional body of POST /admin/strategies/freeze.
+type freezeStrategiesRequest struct {
+	Reason string `json:"reason"`
+}
+
+// freezeStrategies blocks creating and starting strategies.
+func (s *Server) freezeStrategies(c *gin.Context) {
+	var req freezeStrategiesRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, "INVALID_PAYLOAD", err.Error())
+			return
+		}
+	}
+	state := s.Freeze.Freeze(req.Reason)
+	s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "strategies_frozen", UserID: CurrentUserID(c), Message: "new strategies frozen: " + req.Reason})
+	c.JSON(http.StatusOK, state)
+}
+
+// unfreezeStrategies lifts the freeze; unfreezing when not frozen is a no-op.
+func (s *Server) unfreezeStrategies(c *gin.Context) {
+	if s.Freeze.Unfreeze() {
+		s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "strategies_unfrozen", UserID: CurrentUserID(c), Message: "new strategies allowed again"})
+	}
+	c.JSON(http.StatusOK, s.Freeze.State())
+}
+
+// userLimitsResponse is the body of GET/PUT /admin/users/:id/limits. Zero
+// counts use the global default.
+type userLimitsResponse struct {
+	UserID         string `json:"user_id"`
+	MaxStrategies  int    `json:"max_strategies"`
+	MaxConnections int    `json:"max_connections"`
+	MaxLeverage    int    `json:"max_leverage"`
+	// MaxConcurrentPositions below zero lifts the global cap.
+	MaxConcurrentPositions int      `json:"max_concurrent_positions"`
+	AllowedSymbols         []string `json:"allowed_symbols"`
+}
+
+// updateUserLimitsRequest is the body of PUT /admin/users/:id/limits; fields
+// left out keep their current value.
+type updateUserLimitsRequest struct {
+	MaxStrategies          *int      `json:"max_strategies"`
+	MaxConnections         *int      `json:"max_connections"`
+	MaxLeverage            *int      `json:"max_leverage"`
+	MaxConcurrentPositions *int      `json:"max_concurrent_positions"`
+	AllowedSymbols         *[]string `json:"allowed_symbols"`
+}
+
+// userLimitsView converts stored limits to the response shape.
+func userLimitsView(userID string, l db.UserLimits) userLimitsResponse {
+	symbols := l.AllowedSymbols
+	if symbols == nil {
+		symbols = []string{}
+	}
+	return userLimitsResponse{
+		UserID:                 userID,
+		MaxStrategies:          l.MaxStrategies,
+		MaxConnections:         l.MaxConnections,
+		MaxLeverage:            l.MaxLeverage,
+		MaxConcurrentPositions: l.MaxConcurrentPositions,
+		AllowedSymbols:         symbols,
+	}
+}
+
+// adminTargetUser loads the user named by :id, responding and returning
+// false when it does not exist.
+func (s *Server) adminTargetUser(c *gin.Context) (string, bool) {
+	userID := c.Param("id")
+	user, err := s.DB.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return "", false
+	}
+	if user == nil {
+		respondError(c, "USER_NOT_FOUND", "")
+		return "", false
+	}
+	return userID, true
+}
+
+// getUserLimits returns a user's limit overrides.
+func (s *Server) getUserLimits(c *gin.Context) {
+	userID, ok := s.adminTargetUser(c)
+	if !ok {
+		return
+	}
+	limits, err := s.DB.Queries().GetUserLimits(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, userLimitsView(userID, limits))
+}
+
+// updateUserLimits sets a user's strategy, connection, leverage and
+// concurrent-position caps and symbol allow-list.
+func (s *Server) updateUserLimits(c *gin.Context) {
+	userID, ok := s.adminTargetUser(c)
+	if !ok {
+		return
+	}
+	var req updateUserLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, "INVALID_PAYLOAD", err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	limits, err := s.DB.Queries().GetUserLimits(ctx, userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	for _, f := range []struct {
+		name string
+		in   *int
+		out  *int
+	}{
+		{"max_strategies", req.MaxStrategies, &limits.MaxStrategies},
+		{"max_connections", req.MaxConnections, &limits.MaxConnections},
+		{"max_leverage", req.MaxLeverage, &limits.MaxLeverage},
+	} {
+		if f.in == nil {
+			continue
+		}
+		if *f.in < 0 {
+			respondError(c, "INVALID_LIMITS", f.name+" must be >= 0")
+			return
+		}
+		*f.out = *f.in
+	}
+	if limits.MaxLeverage > 125 {
+		respondError(c, "INVALID_LIMITS", "max_leverage must be <= 125")
+		return
+	}
+	if req.MaxConcurrentPositions != nil {
+		limits.MaxConcurrentPositions = *req.MaxConcurrentPositions
+	}
+	if req.AllowedSymbols != nil {
+		limits.AllowedSymbols = nil
+		seen := make(map[string]bool)
+		for _, sym := range *req.AllowedSymbols {
+			sym = strings.ToUpper(strings.TrimSpace(sym))
+			if sym == "" || strings.Contains(sym, ",") {
+				respondError(c, "INVALID_LIMITS", fmt.Sprintf("invalid symbol %q", sym))
+				return
+			}
+			if !seen[sym] {
+				seen[sym] = true
+				limits.AllowedSymbols = append(limits.AllowedSymbols, sym)
+			}
+		}
+	}
+	if err := s.DB.Queries().SetUserLimits(ctx, userID, limits); err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "user_limits_changed", UserID: userID,
+		Message: fmt.Sprintf("limits of user %s changed by %s", userID, CurrentUserID(c))})
+	c.JSON(http.StatusOK, userLimitsView(userID, limits))
+}
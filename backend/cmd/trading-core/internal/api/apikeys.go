@@ -0,0 +1,274 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"trading-core/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// API key scopes. A read key may only call GET endpoints; a trade key may
+// also place orders and manage strategies.
+const (
+	ScopeRead  = "read"
+	ScopeTrade = "trade"
+)
+
+const (
+	apiKeyHeader     = "X-API-Key"
+	apiKeyPrefix     = "desk_"
+	authMethodKey    = "AuthMethod"
+	authMethodAPIKey = "api_key"
+
+	// apiKeyTouchInterval bounds how often a key's last_used_at is written, so
+	// a busy bot does not turn every request into a DB write.
+	apiKeyTouchInterval = time.Minute
+)
+
+// hashAPIKey returns the stored form of a key. Keys are 32 random bytes, so a
+// plain SHA-256 is enough (unlike passwords, they cannot be guessed).
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// authenticate accepts either an X-API-Key header or a JWT bearer token.
+func (s *Server) authenticate() gin.HandlerFunc {
+	jwtAuth := AuthMiddleware(s.JWTSecret, &s.Tokens)
+	return func(c *gin.Context) {
+		if c.GetHeader(apiKeyHeader) == "" {
+			jwtAuth(c)
+			return
+		}
+		s.apiKeyAuth(c)
+	}
+}
+
+// apiKeyAuth authenticates the X-API-Key header and enforces its scopes.
+func (s *Server) apiKeyAuth(c *gin.Context) {
+	if s.DB == nil {
+		abortWithError(c, "INVALID_API_KEY", "invalid API key")
+		return
+	}
+	ctx := c.Request.Context()
+	key, err := s.DB.Queries().GetActiveAPIKeyByHash(ctx, hashAPIKey(c.GetHeader(apiKeyHeader)))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			abortWithError(c, "INVALID_API_KEY", "invalid or revoked API key")
+			return
+		}
+		abortWithError(c, "DB_ERROR", err.Error())
+		return
+	}
+	if !apiKeyAllows(key.Scopes, c.Request.Method) {
+		abortWithError(c, "INSUFFICIENT_SCOPE", "API key lacks the trade scope")
+		return
+	}
+	if s.shouldTouchAPIKey(key.ID, time.Now()) {
+		_ = s.DB.Queries().TouchAPIKey(ctx, key.ID)
+	}
+
+	c.Set(userContextKey, key.UserID)
+	c.Set(authMethodKey, authMethodAPIKey)
+	c.Next()
+}
+
+// shouldTouchAPIKey reports whether keyID's last use should be recorded now,
+// i.e. it was not recorded within apiKeyTouchInterval.
+func (s *Server) shouldTouchAPIKey(keyID string, now time.Time) bool {
+	s.keyTouchMu.Lock()
+	defer s.keyTouchMu.Unlock()
+	if last, ok := s.keyTouched[keyID]; ok && now.Sub(last) < apiKeyTouchInterval {
+		return false
+	}
+	if s.keyTouched == nil {
+		s.keyTouched = make(map[string]time.Time)
+	}
+	for id, t := range s.keyTouched {
+		if now.Sub(t) >= apiKeyTouchInterval {
+			delete(s.keyTouched, id)
+		}
+	}
+	s.keyTouched[keyID] = now
+	return true
+}
+
+// apiKeyAllows reports whether scopes permit a request with the given method.
+func apiKeyAllows(scopes []string, method string) bool {
+	for _, sc := range scopes {
+		if sc == ScopeTrade {
+			return true
+		}
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		for _, sc := range scopes {
+			if sc == ScopeRead {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requireSession rejects API-key requests on endpoints that manage credentials.
+func requireSession(c *gin.Context) bool {
+	if c.GetString(authMethodKey) == authMethodAPIKey {
+		respondError(c, "SESSION_REQUIRED", "API keys cannot manage API keys; log in instead")
+		return false
+	}
+	return true
+}
+
+// sessionOnly is requireSession as route middleware, for endpoints that touch
+// exchange credentials or loosen account protections.
+func sessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(authMethodKey) == authMethodAPIKey {
+			abortWithError(c, "SESSION_REQUIRED", "this endpoint requires a logged-in session")
+			return
+		}
+		c.Next()
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read trade"`
+}
+
+type apiKeyView struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// createAPIKeyResponse carries the plaintext key, which is never shown again.
+type createAPIKeyResponse struct {
+	apiKeyView
+	Key string `json:"key"`
+}
+
+func newAPIKeyView(k db.APIKey) apiKeyView {
+	v := apiKeyView{ID: k.ID, Name: k.Name, Prefix: k.Prefix, Scopes: k.Scopes, CreatedAt: k.CreatedAt}
+	if !k.LastUsedAt.IsZero() {
+		t := k.LastUsedAt
+		v.LastUsedAt = &t
+	}
+	if !k.RevokedAt.IsZero() {
+		t := k.RevokedAt
+		v.RevokedAt = &t
+	}
+	return v
+}
+
+// createAPIKey issues a key for the current user and returns it once.
+func (s *Server) createAPIKey(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
+		return
+	}
+	if !requireSession(c) {
+		return
+	}
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	plain, err := generateAPIKey()
+	if err != nil {
+		respondError(c, "KEY_GENERATION_FAILED", err.Error())
+		return
+	}
+	key := db.APIKey{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plain[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(plain),
+		Scopes:    dedupeScopes(req.Scopes),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.DB.Queries().CreateAPIKey(c.Request.Context(), key); err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, createAPIKeyResponse{apiKeyView: newAPIKeyView(key), Key: plain})
+}
+
+func dedupeScopes(scopes []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(scopes))
+	for _, sc := range scopes {
+		if !seen[sc] {
+			seen[sc] = true
+			out = append(out, sc)
+		}
+	}
+	return out
+}
+
+// listAPIKeys returns the current user's keys (without the secrets).
+func (s *Server) listAPIKeys(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
+		return
+	}
+	if !requireSession(c) {
+		return
+	}
+	keys, err := s.DB.Queries().ListAPIKeysByUser(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	out := make([]apiKeyView, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, newAPIKeyView(k))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// revokeAPIKey revokes one of the current user's keys.
+func (s *Server) revokeAPIKey(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
+		return
+	}
+	if !requireSession(c) {
+		return
+	}
+	if err := s.DB.Queries().RevokeAPIKey(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondError(c, "API_KEY_NOT_FOUND", "API key not found or already revoked")
+			return
+		}
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
@@ -12,7 +12,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const userContextKey = "UserID"
@@ -23,70 +22,102 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 }
 
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(bytes), nil
+// TokenConfig controls the access tokens issued at login and the claims
+// AuthMiddleware requires. Zero fields fall back to the defaults below.
+type TokenConfig struct {
+	TTL      time.Duration
+	Issuer   string
+	Audience string
+	Leeway   time.Duration // tolerated clock skew on exp/nbf/iat
 }
 
-func checkPassword(hash, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+const (
+	defaultTokenTTL      = 72 * time.Hour
+	defaultTokenIssuer   = "des-trading-core"
+	defaultTokenAudience = "des-trading-api"
+	defaultTokenLeeway   = 30 * time.Second
+)
+
+func (t TokenConfig) withDefaults() TokenConfig {
+	if t.TTL <= 0 {
+		t.TTL = defaultTokenTTL
+	}
+	if t.Issuer == "" {
+		t.Issuer = defaultTokenIssuer
+	}
+	if t.Audience == "" {
+		t.Audience = defaultTokenAudience
+	}
+	if t.Leeway <= 0 {
+		t.Leeway = defaultTokenLeeway
+	}
+	return t
 }
 
-func generateToken(userID, secret string, expiresAt time.Time) (string, error) {
+// generateToken issues an access token for userID valid from now for cfg.TTL.
+func generateToken(userID, secret string, cfg TokenConfig, now time.Time) (string, time.Time, error) {
+	cfg = cfg.withDefaults()
+	expiresAt := now.Add(cfg.TTL)
 	claims := UserClaims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
 			Subject:   userID,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	signed, err := token.SignedString([]byte(secret))
+	return signed, expiresAt, err
 }
 
-func parseToken(tokenStr, secret string) (string, error) {
+// parseToken verifies the signature and the exp/nbf/iss/aud claims and
+// returns the user ID.
+func parseToken(tokenStr, secret string, cfg TokenConfig) (string, error) {
+	cfg = cfg.withDefaults()
 	token, err := jwt.ParseWithClaims(tokenStr, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithLeeway(cfg.Leeway),
+	)
 	if err != nil {
 		return "", err
 	}
-	if claims, ok := token.Claims.(*UserClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*UserClaims); ok && token.Valid && claims.UserID != "" {
 		return claims.UserID, nil
 	}
 	return "", errors.New("invalid token claims")
 }
 
-// AuthMiddleware enforces JWT auth for protected routes.
-func AuthMiddleware(secret string) gin.HandlerFunc {
+// AuthMiddleware enforces JWT auth for protected routes. tokens is read per
+// request, so it may be filled in after NewServer.
+func AuthMiddleware(secret string, tokens *TokenConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"code":  "MISSING_TOKEN",
-				"error": "missing Authorization header",
-			})
+			abortWithError(c, "MISSING_TOKEN", "missing Authorization header")
 			return
 		}
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"code":  "INVALID_AUTH_HEADER",
-				"error": "invalid Authorization header",
-			})
+			abortWithError(c, "INVALID_AUTH_HEADER", "invalid Authorization header")
 			return
 		}
 
-		userID, err := parseToken(parts[1], secret)
+		userID, err := parseToken(parts[1], secret, *tokens)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			abortWithError(c, "TOKEN_EXPIRED", "token expired")
+			return
+		}
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"code":  "INVALID_TOKEN",
-				"error": "invalid or expired token",
-			})
+			abortWithError(c, "INVALID_TOKEN", "invalid or expired token")
 			return
 		}
 
@@ -105,61 +136,93 @@ func CurrentUserID(c *gin.Context) string {
 	return ""
 }
 
+// maxEmailLength is the longest address RFC 5321 allows in a mail path.
+const maxEmailLength = 254
+
+// normalizeEmail trims and lowercases a bare address ("user@example.com")
+// and reports whether it is well formed. Display-name forms such as
+// "Bob <bob@example.com>" are rejected.
+func normalizeEmail(raw string) (string, bool) {
+	email := strings.TrimSpace(raw)
+	if email == "" || len(email) > maxEmailLength {
+		return "", false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", false
+	}
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || !strings.Contains(email[at+1:], ".") {
+		return "", false
+	}
+	return strings.ToLower(email), true
+}
+
+// dummyPasswordHash is a hash, made with the configured algorithm, checked
+// against when no user matches a login, so unknown emails take as long to
+// reject as wrong passwords.
+func (s *Server) dummyPasswordHash() string {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.Passwords.Hash(uuid.NewString())
+	})
+	return s.dummyHash
+}
+
+// registerRequest is the body of POST /auth/register.
+type registerRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// loginRequest is the body of POST /auth/login.
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
 // registerUser handles user registration.
 func (s *Server) registerUser(c *gin.Context) {
-	var req struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var req registerRequest
 	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":  "INVALID_PAYLOAD",
-			"error": "invalid request payload",
-		})
+		respondError(c, "INVALID_PAYLOAD", "invalid request payload")
 		return
 	}
 	req.Email = strings.TrimSpace(req.Email)
 	req.Username = strings.TrimSpace(req.Username)
 	if req.Email == "" || req.Password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":  "MISSING_CREDENTIALS",
-			"error": "email and password are required",
-		})
+		respondError(c, "MISSING_CREDENTIALS", "email and password are required")
 		return
 	}
 
-	if _, err := mail.ParseAddress(req.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":  "INVALID_EMAIL",
-			"error": "invalid email format",
-		})
+	email, ok := normalizeEmail(req.Email)
+	if !ok {
+		respondError(c, "INVALID_EMAIL", "invalid email format")
 		return
 	}
+	req.Email = email
 
-	ctx := c.Request.Context()
-	existing, err := s.DB.GetUserByEmail(ctx, req.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":  "INTERNAL_ERROR",
-			"error": err.Error(),
-		})
+	if err := s.Passwords.Validate(req.Password); err != nil {
+		respondError(c, "WEAK_PASSWORD", err.Error())
 		return
 	}
-	if existing != nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"code":  "EMAIL_ALREADY_REGISTERED",
-			"error": "email already registered",
-		})
+
+	// Hash before looking the email up so a taken address answers no faster
+	// than a new one.
+	pwHash, err := s.Passwords.Hash(req.Password)
+	if err != nil {
+		respondError(c, "INTERNAL_ERROR", "failed to hash password")
 		return
 	}
 
-	pwHash, err := hashPassword(req.Password)
+	ctx := c.Request.Context()
+	existing, err := s.DB.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":  "INTERNAL_ERROR",
-			"error": "failed to hash password",
-		})
+		respondError(c, "INTERNAL_ERROR", "")
+		return
+	}
+	if existing != nil {
+		respondError(c, "EMAIL_ALREADY_REGISTERED", "")
 		return
 	}
 
@@ -172,10 +235,12 @@ func (s *Server) registerUser(c *gin.Context) {
 		UpdatedAt:    now,
 	}
 	if err := s.DB.CreateUser(ctx, user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":  "INTERNAL_ERROR",
-			"error": err.Error(),
-		})
+		// A concurrent registration of the same email won the insert.
+		if errors.Is(err, db.ErrDuplicate) {
+			respondError(c, "EMAIL_ALREADY_REGISTERED", "")
+			return
+		}
+		respondError(c, "INTERNAL_ERROR", "")
 		return
 	}
 
@@ -187,58 +252,38 @@ func (s *Server) registerUser(c *gin.Context) {
 
 // loginUser handles user login.
 func (s *Server) loginUser(c *gin.Context) {
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var req loginRequest
 	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":  "INVALID_PAYLOAD",
-			"error": "invalid request payload",
-		})
+		respondError(c, "INVALID_PAYLOAD", "invalid request payload")
 		return
 	}
 	req.Email = strings.TrimSpace(req.Email)
 	if req.Email == "" || req.Password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":  "MISSING_CREDENTIALS",
-			"error": "email and password are required",
-		})
+		respondError(c, "MISSING_CREDENTIALS", "email and password are required")
 		return
 	}
 
 	ctx := c.Request.Context()
 	user, err := s.DB.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":  "INTERNAL_ERROR",
-			"error": err.Error(),
-		})
+		respondError(c, "INTERNAL_ERROR", err.Error())
 		return
 	}
 	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":  "INVALID_CREDENTIALS",
-			"error": "invalid credentials",
-		})
+		// Spend the same hashing time as a wrong password on a real account.
+		_ = checkPassword(s.dummyPasswordHash(), req.Password)
+		respondError(c, "INVALID_CREDENTIALS", "invalid credentials")
 		return
 	}
 
 	if err := checkPassword(user.PasswordHash, req.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"code":  "INVALID_CREDENTIALS",
-			"error": "invalid credentials",
-		})
+		respondError(c, "INVALID_CREDENTIALS", "invalid credentials")
 		return
 	}
 
-	expiresAt := time.Now().Add(72 * time.Hour)
-	token, err := generateToken(user.ID, s.JWTSecret, expiresAt)
+	token, expiresAt, err := generateToken(user.ID, s.JWTSecret, s.Tokens, time.Now())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":  "INTERNAL_ERROR",
-			"error": "failed to generate token",
-		})
+		respondError(c, "INTERNAL_ERROR", "failed to generate token")
 		return
 	}
 
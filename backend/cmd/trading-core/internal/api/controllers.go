@@ -1,24 +1,39 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"trading-core/internal/engine"
+	"trading-core/internal/events"
+	"trading-core/internal/gateway"
+	"trading-core/internal/market"
 	"trading-core/internal/monitor"
 	"trading-core/internal/order"
+	"trading-core/internal/risk"
+	"trading-core/internal/state"
+	"trading-core/internal/tracing"
 	"trading-core/pkg/db"
 	exchange "trading-core/pkg/exchanges/common"
+	"trading-core/pkg/ids"
+	binancemarket "trading-core/pkg/market/binance"
+	"trading-core/pkg/money"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type createStrategyRequest struct {
@@ -38,14 +53,24 @@ type listStrategiesQuery struct {
 type createOrderRequest struct {
 	Symbol       string  `json:"symbol" binding:"required,min=1"`
 	Side         string  `json:"side" binding:"required,oneof=BUY SELL"`
-	Type         string  `json:"type" binding:"required,oneof=LIMIT MARKET"`
+	Type         string  `json:"type" binding:"required,oneof=LIMIT MARKET STOP_MARKET TAKE_PROFIT_MARKET STOP_LIMIT"`
 	Price        float64 `json:"price"`
+	StopPrice    float64 `json:"stop_price" binding:"gte=0"`
 	Qty          float64 `json:"qty" binding:"gt=0"`
 	ConnectionID string  `json:"connection_id" binding:"required"`
+	// Optional GTC/IOC/FOK/GTX for limit-style orders; omitted uses the venue default.
+	TimeInForce string `json:"time_in_force"`
+	// Optional "maker_first": post at the best bid/ask, reprice, then cross (LIMIT/MARKET only).
+	Routing string `json:"routing" binding:"omitempty,oneof=maker_first"`
+	// Optional trade-journal annotation and tags (e.g. the setup traded).
+	Note string   `json:"note" binding:"max=500"`
+	Tags []string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=32"`
 }
 
+// listOrdersQuery is shared by the order and trade listings.
 type listOrdersQuery struct {
-	Limit int `form:"limit"`
+	Limit int    `form:"limit"`
+	Tag   string `form:"tag"` // only orders (or fills of orders) carrying this tag
 }
 
 type createConnectionRequest struct {
@@ -53,6 +78,17 @@ type createConnectionRequest struct {
 	ExchangeType string `json:"exchange_type" binding:"required,min=1"`
 	APIKey       string `json:"api_key" binding:"required,min=1"`
 	APISecret    string `json:"api_secret" binding:"required,min=1"`
+	// Optional fee schedule; omitted values fall back to the global flat fee rate.
+	MakerFeeBps float64 `json:"maker_fee_bps" binding:"omitempty,gte=0"`
+	TakerFeeBps float64 `json:"taker_fee_bps" binding:"omitempty,gte=0"`
+	BNBDiscount float64 `json:"bnb_discount" binding:"omitempty,gte=0,lt=1"`
+	// Optional futures leverage set before each order (0 = keep the exchange setting).
+	Leverage int `json:"leverage" binding:"omitempty,gte=1,lte=125"`
+	// Optional risk budgets for this account, on top of the user's limits (0 = none).
+	MaxDailyLoss float64 `json:"max_daily_loss" binding:"omitempty,gte=0"`
+	MaxExposure  float64 `json:"max_exposure" binding:"omitempty,gte=0"`
+	// Paper simulates this connection's orders even when the server trades live.
+	Paper bool `json:"paper"`
 }
 
 type updateStrategyBindingRequest struct {
@@ -78,16 +114,33 @@ func (q *listOrdersQuery) normalize() {
 	if q.Limit > 500 {
 		q.Limit = 500
 	}
+	q.Tag = strings.ToLower(strings.TrimSpace(q.Tag))
 }
 
-func respondError(c *gin.Context, status int, code, msg string) {
-	c.JSON(status, gin.H{
-		"code":  code,
-		"error": msg,
-	})
+// normalizeTags lower-cases and de-duplicates journal tags. Tags are stored
+// comma-separated, so they may not contain commas.
+func normalizeTags(tags []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || strings.Contains(t, ",") {
+			return nil, fmt.Errorf("invalid tag %q", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out, nil
 }
 
 func validateStrategyParams(strategyType string, params map[string]any) error {
+	if v, ok := params["price_source"]; ok {
+		if src, _ := v.(string); !binancemarket.ValidPriceSource(src) {
+			return fmt.Errorf("price_source must be kline, trade, aggTrade or bookMid")
+		}
+	}
 	switch strings.ToLower(strategyType) {
 	case "ma_cross":
 		fast, ok := asFloat(params["fast"])
@@ -129,6 +182,34 @@ func validateStrategyParams(strategyType string, params map[string]any) error {
 		if size, ok := asFloat(params["size"]); ok && size <= 0 {
 			return fmt.Errorf("bollinger.size must be > 0")
 		}
+	case "ob_imbalance":
+		threshold, ok := asFloat(params["threshold"])
+		if !ok {
+			return fmt.Errorf("ob_imbalance.threshold is required")
+		}
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("ob_imbalance.threshold must be in (0, 1]")
+		}
+		if size, ok := asFloat(params["size"]); ok && size <= 0 {
+			return fmt.Errorf("ob_imbalance.size must be > 0")
+		}
+	case "mtf":
+		fast, ok := asFloat(params["fast"])
+		slow, ok2 := asFloat(params["slow"])
+		trendPeriod, ok3 := asFloat(params["trend_period"])
+		trendInterval, _ := params["trend_interval"].(string)
+		if !ok || !ok2 || !ok3 || trendInterval == "" {
+			return fmt.Errorf("mtf.fast, mtf.slow, mtf.trend_interval and mtf.trend_period are required")
+		}
+		if fast <= 0 || slow <= 0 || fast >= slow {
+			return fmt.Errorf("mtf.fast/slow must be >0 and fast < slow")
+		}
+		if trendPeriod <= 0 {
+			return fmt.Errorf("mtf.trend_period must be > 0")
+		}
+		if size, ok := asFloat(params["size"]); ok && size <= 0 {
+			return fmt.Errorf("mtf.size must be > 0")
+		}
 	default:
 		// Unknown strategy type: no-op (could be validated elsewhere)
 	}
@@ -158,13 +239,16 @@ func asFloat(v any) (float64, bool) {
 func (s *Server) createStrategy(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "user not authenticated")
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+	if s.rejectIfFrozen(c) {
 		return
 	}
 
 	var req createStrategyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload")
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
 		return
 	}
 	if req.Parameters == nil {
@@ -172,31 +256,40 @@ func (s *Server) createStrategy(c *gin.Context) {
 	}
 
 	if err := validateStrategyParams(req.StrategyType, req.Parameters); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_PARAMETERS", err.Error())
+		respondError(c, "INVALID_PARAMETERS", err.Error())
 		return
 	}
 
 	ctx := c.Request.Context()
+	if !s.withinResourceLimit(c, userID, "strategies") {
+		return
+	}
+
 	// If connection_id provided, validate ownership and active status.
+	exchangeType := ""
 	if req.ConnectionID != "" {
 		conn, err := s.DB.Queries().GetConnectionByID(ctx, userID, req.ConnectionID)
 		if err != nil {
 			if errors.Is(err, db.ErrNotFound) {
-				respondError(c, http.StatusBadRequest, "INVALID_CONNECTION", "invalid connection for current user")
+				respondError(c, "INVALID_CONNECTION", "invalid connection for current user")
 			} else {
-				respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+				respondError(c, "DB_ERROR", err.Error())
 			}
 			return
 		}
 		if !conn.IsActive {
-			respondError(c, http.StatusBadRequest, "CONNECTION_INACTIVE", "connection is not active")
+			respondError(c, "CONNECTION_INACTIVE", "connection is not active")
 			return
 		}
+		exchangeType = conn.ExchangeType
+	}
+	if !s.strategySymbolAllowed(c, userID, req.Symbol, exchangeType) {
+		return
 	}
 
 	paramsJSON, err := json.Marshal(req.Parameters)
 	if err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_PARAMETERS", "invalid parameters")
+		respondError(c, "INVALID_PARAMETERS", "invalid parameters")
 		return
 	}
 
@@ -210,7 +303,7 @@ func (s *Server) createStrategy(c *gin.Context) {
 	`, id, req.Name, req.StrategyType, req.Symbol, req.Interval, string(paramsJSON),
 		userID, req.ConnectionID, now, now)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 
@@ -235,7 +328,7 @@ func (s *Server) getStrategies(c *gin.Context) {
 
 	var q listStrategiesQuery
 	if err := c.ShouldBindQuery(&q); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_QUERY", "invalid query parameters")
+		respondError(c, "INVALID_QUERY", "invalid query parameters")
 		return
 	}
 	q.normalize()
@@ -265,7 +358,7 @@ func (s *Server) getStrategies(c *gin.Context) {
 		LIMIT ? OFFSET ?
 	`, userID, q.Limit, q.Offset)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 	defer rows.Close()
@@ -323,7 +416,7 @@ func (s *Server) getStrategies(c *gin.Context) {
 	}
 
 	if err := rows.Err(); err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_SCAN_ERROR", "failed to read strategies")
+		respondError(c, "DB_SCAN_ERROR", "failed to read strategies")
 		return
 	}
 
@@ -344,268 +437,1516 @@ func nullableString(ns sql.NullString) *string {
 func (s *Server) getOrders(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "user not authenticated")
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
 		return
 	}
 
 	var q listOrdersQuery
 	if err := c.ShouldBindQuery(&q); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_QUERY", "invalid query parameters")
+		respondError(c, "INVALID_QUERY", "invalid query parameters")
 		return
 	}
 	q.normalize()
 
-	orders, err := s.DB.Queries().GetOrdersByUser(c.Request.Context(), userID, q.Limit)
+	orders, err := s.DB.Queries().ListOrdersByUser(c.Request.Context(), userID, db.ListFilter{Limit: q.Limit, Tag: q.Tag})
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 	c.Header("X-Result-Limit", strconv.Itoa(q.Limit))
 	c.JSON(http.StatusOK, orders)
 }
 
-// getPositions returns current positions for the authenticated user.
-func (s *Server) getPositions(c *gin.Context) {
+// getTrades returns recent fills for the authenticated user, optionally only
+// those of orders carrying a journal tag.
+func (s *Server) getTrades(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+
+	var q listOrdersQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		respondError(c, "INVALID_QUERY", "invalid query parameters")
+		return
+	}
+	q.normalize()
+
+	trades, err := s.DB.Queries().ListTradesByUser(c.Request.Context(), userID, db.ListFilter{Limit: q.Limit, Tag: q.Tag})
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.Header("X-Result-Limit", strconv.Itoa(q.Limit))
+	c.JSON(http.StatusOK, trades)
+}
+
+// orderFill is one execution in an order report.
+type orderFill struct {
+	ID       string    `json:"id"`
+	Price    float64   `json:"price"`
+	Qty      float64   `json:"qty"`
+	Fee      float64   `json:"fee"`
+	FeeAsset string    `json:"fee_asset,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// orderReport summarizes how an order executed.
+type orderReport struct {
+	ID           string      `json:"id"`
+	Symbol       string      `json:"symbol"`
+	Side         string      `json:"side"`
+	Status       string      `json:"status"`
+	Price        float64     `json:"price"`
+	Qty          float64     `json:"qty"`
+	FilledQty    float64     `json:"filled_qty"`
+	RefPrice     float64     `json:"ref_price"`
+	AvgFillPrice float64     `json:"avg_fill_price"`
+	TotalFee     float64     `json:"total_fee"`
+	SlippageBps  *float64    `json:"slippage_bps,omitempty"` // positive = worse than ref_price; nil without fills or ref
+	Note         string      `json:"note,omitempty"`
+	Tags         []string    `json:"tags,omitempty"`
+	Fills        []orderFill `json:"fills"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// getOrderReport returns an order's execution report: each fill, average fill
+// price, total fee and slippage against the price at submission.
+func (s *Server) getOrderReport(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+
+	ctx := c.Request.Context()
+	o, err := s.DB.Queries().GetOrderByID(ctx, userID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondError(c, "NOT_FOUND", "order not found")
+		} else {
+			respondError(c, "DB_ERROR", err.Error())
+		}
+		return
+	}
+	trades, err := s.DB.Queries().GetTradesByOrder(ctx, userID, o.ID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, buildOrderReport(*o, trades))
+}
+
+func buildOrderReport(o db.Order, trades []db.Trade) orderReport {
+	r := orderReport{
+		ID:        o.ID,
+		Symbol:    o.Symbol,
+		Side:      o.Side,
+		Status:    o.Status,
+		Price:     o.Price,
+		Qty:       o.Qty,
+		FilledQty: o.FilledQty,
+		RefPrice:  o.RefPrice,
+		Note:      o.Note,
+		Tags:      o.Tags,
+		Fills:     make([]orderFill, 0, len(trades)),
+		CreatedAt: o.CreatedAt,
+	}
+	if r.RefPrice <= 0 {
+		r.RefPrice = o.Price
+	}
+
+	var notional, filled, fees money.Amount
+	for _, t := range trades {
+		r.Fills = append(r.Fills, orderFill{ID: t.ID, Price: t.Price, Qty: t.Qty, Fee: t.Fee, FeeAsset: t.FeeAsset, Time: t.CreatedAt})
+		notional = notional.Add(money.FromFloat(t.Price).Mul(money.FromFloat(t.Qty)))
+		filled = filled.Add(money.FromFloat(t.Qty))
+		fees = fees.Add(money.FromFloat(t.Fee))
+	}
+	r.TotalFee = fees.Float64()
+	if !filled.IsZero() {
+		r.AvgFillPrice = notional.Div(filled).Float64()
+		if filled.Float64() > r.FilledQty {
+			r.FilledQty = filled.Float64()
+		}
+		if r.RefPrice > 0 {
+			bps := (r.AvgFillPrice - r.RefPrice) / r.RefPrice * 10000
+			if strings.EqualFold(o.Side, "SELL") {
+				bps = -bps
+			}
+			r.SlippageBps = &bps
+		}
+	}
+	return r
+}
+
+// getPositions returns current positions for the authenticated user.
+func (s *Server) getPositions(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+
+	positions, err := s.DB.Queries().GetPositionsByUser(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+
+	out := make([]positionView, 0, len(positions))
+	for _, p := range positions {
+		v := positionView{Position: p}
+		if s.Prices != nil {
+			v.MarkPrice = s.Prices.GetField(p.Symbol, market.PriceFieldMark)
+			v.UnrealizedPnL = state.UnrealizedPnL(p.Qty, p.AvgPrice, v.MarkPrice)
+		}
+		out = append(out, v)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// positionView is a stored position marked to the latest price.
+type positionView struct {
+	db.Position
+	MarkPrice     float64 `json:"mark_price"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+}
+
+// createOrder submits a manual order for the authenticated user on a specific connection.
+func (s *Server) createOrder(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+	if s.OrderQueue == nil {
+		respondError(c, "QUEUE_UNAVAILABLE", "order queue not available")
+		return
+	}
+
+	var req createOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
+		return
+	}
+	o, ok := s.orderFromRequest(c, userID, req)
+	if !ok {
+		return
+	}
+	decision, _, oerr := s.admitOrder(c.Request.Context(), userID, &o, 0, nil)
+	if oerr != nil {
+		respondError(c, oerr.Code, oerr.Message)
+		return
+	}
+	s.enqueueOrder(c.Request.Context(), userID, o, decision)
+
+	resp := orderResponse(o)
+	if decision != nil {
+		resp["risk"] = decision
+	}
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// maxOrderBatch caps the number of orders accepted by one batch request.
+const maxOrderBatch = 50
+
+// batchOrderResult is the outcome of one order in a batch request.
+type batchOrderResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"` // "accepted" or "rejected"
+	Order  gin.H  `json:"order,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchOrderResponse is the body returned by POST /orders/batch.
+type batchOrderResponse struct {
+	Accepted int                `json:"accepted"`
+	Rejected int                `json:"rejected"`
+	Results  []batchOrderResult `json:"results"`
+}
+
+// createOrderBatch validates and enqueues an array of orders, returning a
+// result per order. Each order is checked like POST /orders, with balance
+// reserved by earlier orders in the batch; a rejected order does not stop the
+// rest. Orders are submitted asynchronously through the order queue.
+func (s *Server) createOrderBatch(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+	if s.OrderQueue == nil {
+		respondError(c, "QUEUE_UNAVAILABLE", "order queue not available")
+		return
+	}
+
+	var items []json.RawMessage
+	if err := c.ShouldBindJSON(&items); err != nil {
+		respondError(c, "INVALID_REQUEST", "request body must be a JSON array of orders")
+		return
+	}
+	if len(items) == 0 {
+		respondError(c, "INVALID_REQUEST", "batch is empty")
+		return
+	}
+	if len(items) > maxOrderBatch {
+		respondError(c, "BATCH_TOO_LARGE", fmt.Sprintf("at most %d orders per batch", maxOrderBatch))
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]batchOrderResult, len(items))
+	reserved := 0.0
+	var admitted []order.Order
+	for i, raw := range items {
+		results[i] = batchOrderResult{Index: i, Status: "rejected"}
+		var req createOrderRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			results[i].Code, results[i].Error = "INVALID_REQUEST", "invalid order payload"
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&req); err != nil {
+			results[i].Code, results[i].Error = "INVALID_REQUEST", err.Error()
+			continue
+		}
+		o, oerr := s.buildOrder(ctx, userID, req)
+		if oerr == nil {
+			var decision *risk.RiskDecision
+			var cost float64
+			if decision, cost, oerr = s.admitOrder(ctx, userID, &o, reserved, admitted); oerr == nil {
+				s.enqueueOrder(ctx, userID, o, decision)
+				reserved += cost
+				admitted = append(admitted, o)
+				results[i].Status = "accepted"
+				results[i].Order = orderResponse(o)
+				continue
+			}
+		}
+		results[i].Code, results[i].Error = oerr.Code, oerr.Message
+	}
+
+	c.JSON(http.StatusOK, batchOrderResponse{
+		Accepted: len(admitted),
+		Rejected: len(items) - len(admitted),
+		Results:  results,
+	})
+}
+
+// orderError is a validation failure for one order, carrying the catalog
+// error code the API responds with.
+type orderError struct {
+	Code    string
+	Message string
+}
+
+func (e *orderError) Error() string { return e.Code + ": " + e.Message }
+
+// admitOrder runs the risk and balance checks for o, shrinking its quantity if
+// risk adjusts it. reserved is balance already committed by earlier orders in
+// the same request and admitted are those orders, whose positions and exposure
+// count as if they had filled. It returns the risk decision (nil without a
+// risk manager) and the balance the order reserves.
+func (s *Server) admitOrder(ctx context.Context, userID string, o *order.Order, reserved float64, admitted []order.Order) (*risk.RiskDecision, float64, *orderError) {
+	// Manual orders go through the same risk evaluation as strategy signals.
+	// The notional caps are checked first so fat-finger orders are rejected
+	// outright instead of being shrunk to the position limit.
+	var decision *risk.RiskDecision
+	if s.Risk != nil {
+		mgr, err := s.Risk.GetOrCreate(userID)
+		if err != nil {
+			return nil, 0, &orderError{"RISK_ERROR", err.Error()}
+		}
+		// A price is only required when a notional limit has to be checked.
+		refPrice := s.referencePrice(*o)
+		if refPrice <= 0 && mgr.NeedsPrice(o.ConnectionID) {
+			return nil, 0, &orderError{"PRICE_UNAVAILABLE", "no price known for symbol; cannot check order size"}
+		}
+		if err := mgr.CheckOrderSize(o.Qty * refPrice); err != nil {
+			return nil, 0, &orderError{"ORDER_SIZE_LIMIT", err.Error()}
+		}
+		position, account, err := s.riskSnapshot(ctx, userID, o.Symbol, admitted)
+		if err != nil {
+			return nil, 0, &orderError{"DB_ERROR", err.Error()}
+		}
+		_, span := tracing.Start(ctx, "risk.evaluate", attribute.String("order.symbol", o.Symbol), attribute.String("order.side", o.Side))
+		// EvaluateForUser also applies the user's concurrent-position cap.
+		dec, err := s.Risk.EvaluateForUser(userID, risk.SignalInput{
+			Symbol: o.Symbol,
+			Action: o.Side,
+			Size:   o.Qty,
+			Price:  refPrice,
+
+			ConnectionID: o.ConnectionID,
+		}, position, account, "")
+		if err != nil {
+			span.End()
+			return nil, 0, &orderError{"RISK_ERROR", err.Error()}
+		}
+		span.SetAttributes(attribute.Bool("risk.allowed", dec.Allowed))
+		span.End()
+		if !dec.Allowed {
+			if s.Bus != nil {
+				s.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "risk_rejected", UserID: userID, Symbol: o.Symbol, Message: dec.Reason})
+			}
+			return nil, 0, &orderError{"RISK_REJECTED", dec.Reason}
+		}
+		if dec.AdjustedSize > 0 && dec.AdjustedSize < o.Qty {
+			o.Qty = dec.AdjustedSize
+		}
+		decision = &dec
+	}
+
+	cost := o.Price * o.Qty
+	if cost <= 0 && o.StopPrice > 0 {
+		cost = o.StopPrice * o.Qty
+	}
+	if cost <= 0 {
+		cost = o.Qty
+	}
+	if s.UserBalances != nil {
+		if mgr, err := s.UserBalances.GetOrCreate(userID); err == nil && mgr != nil {
+			if bal := mgr.GetBalance(); reserved+cost > bal.Available {
+				return nil, 0, &orderError{"INSUFFICIENT_BALANCE", "insufficient balance"}
+			}
+		}
+	}
+	return decision, cost, nil
+}
+
+// enqueueOrder queues an admitted order and registers its SL/TP for trailing
+// logic, as for strategy signals. The order carries the request's trace
+// context so its execution joins the request's trace.
+func (s *Server) enqueueOrder(ctx context.Context, userID string, o order.Order, decision *risk.RiskDecision) {
+	o.RefPrice = s.referencePrice(o)
+	ctx, span := tracing.Start(ctx, "order.enqueue", attribute.String("order.id", o.ID))
+	o.Trace = tracing.Inject(ctx)
+	s.OrderQueue.Enqueue(o)
+	span.End()
+
+	if decision == nil || s.StopLoss == nil || (decision.StopLoss <= 0 && decision.TakeProfit <= 0) {
+		return
+	}
+	var riskCfg risk.RiskConfig
+	if mgr, err := s.Risk.GetOrCreate(userID); err == nil {
+		riskCfg = mgr.GetConfig()
+	}
+	side := "LONG"
+	if o.Side == "SELL" {
+		side = "SHORT"
+	}
+	entry := s.referencePrice(o)
+	s.StopLoss.AddPosition(risk.StopLossPosition{
+		Symbol:         o.Symbol,
+		Side:           side,
+		EntryPrice:     entry,
+		CurrentPrice:   entry,
+		StopLoss:       decision.StopLoss,
+		TakeProfit:     decision.TakeProfit,
+		TrailingStop:   riskCfg.UseTrailingStop,
+		TrailingOffset: riskCfg.TrailingPercent,
+	})
+}
+
+// orderResponse is the JSON view of an accepted order.
+func orderResponse(o order.Order) gin.H {
+	return gin.H{
+		"id":            o.ID,
+		"symbol":        o.Symbol,
+		"side":          o.Side,
+		"type":          o.Type,
+		"price":         o.Price,
+		"stop_price":    o.StopPrice,
+		"qty":           o.Qty,
+		"time_in_force": o.TimeInForce,
+		"routing":       o.Routing,
+		"status":        o.Status,
+		"connection_id": o.ConnectionID,
+		"note":          o.Note,
+		"tags":          o.Tags,
+	}
+}
+
+// orderFromRequest validates an order request against the user's connection and
+// builds the order. On failure it writes the error response and returns false.
+func (s *Server) orderFromRequest(c *gin.Context, userID string, req createOrderRequest) (order.Order, bool) {
+	o, oerr := s.buildOrder(c.Request.Context(), userID, req)
+	if oerr != nil {
+		respondError(c, oerr.Code, oerr.Message)
+		return order.Order{}, false
+	}
+	return o, true
+}
+
+// buildOrder validates an order request against the user's connection and
+// builds the order.
+func (s *Server) buildOrder(ctx context.Context, userID string, req createOrderRequest) (order.Order, *orderError) {
+	orderType := exchange.OrderType(strings.ToUpper(req.Type))
+	if (orderType == exchange.OrderTypeLimit || orderType == exchange.OrderTypeStopLimit) && req.Price <= 0 {
+		return order.Order{}, &orderError{"INVALID_PRICE", "price must be > 0 for LIMIT orders"}
+	}
+	if orderType.RequiresStopPrice() && req.StopPrice <= 0 {
+		return order.Order{}, &orderError{"INVALID_STOP_PRICE", "stop_price must be > 0 for stop/take-profit orders"}
+	}
+	if req.Routing != "" && orderType != exchange.OrderTypeLimit && orderType != exchange.OrderTypeMarket {
+		return order.Order{}, &orderError{"INVALID_ROUTING", "routing is only supported for LIMIT and MARKET orders"}
+	}
+
+	conn, err := s.DB.Queries().GetConnectionByID(ctx, userID, req.ConnectionID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return order.Order{}, &orderError{"INVALID_CONNECTION", "invalid connection for current user"}
+		}
+		log.Printf("buildOrder: failed to get connection %s for user %s: %v", req.ConnectionID, userID, err)
+		return order.Order{}, &orderError{"DB_ERROR", err.Error()}
+	}
+	if conn.APIKeyEncrypted != "" && s.KeyManager == nil {
+		return order.Order{}, &orderError{"CONFIG_ERROR", "encrypted connection requires KeyManager"}
+	}
+	if !conn.IsActive {
+		return order.Order{}, &orderError{"CONNECTION_INACTIVE", "connection is not active"}
+	}
+
+	market, ok := connectionMarket(conn.ExchangeType)
+	if !ok {
+		return order.Order{}, &orderError{"UNSUPPORTED_EXCHANGE", "unsupported exchange type"}
+	}
+	if err := exchange.ValidateMarketSymbol(market, req.Symbol); err != nil {
+		return order.Order{}, &orderError{"SYMBOL_NOT_ON_MARKET",
+			fmt.Sprintf("symbol %s is not tradable on this %s connection", strings.ToUpper(req.Symbol), conn.ExchangeType)}
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return order.Order{}, &orderError{"INVALID_TAGS", err.Error()}
+	}
+
+	tif := exchange.TimeInForce(strings.ToUpper(strings.TrimSpace(req.TimeInForce)))
+	if !orderType.AcceptsTimeInForce(tif, exchange.MarketType(market)) {
+		return order.Order{}, &orderError{"INVALID_TIME_IN_FORCE",
+			fmt.Sprintf("time_in_force %q is not allowed for %s orders on %s", req.TimeInForce, orderType, market)}
+	}
+
+	o := order.Order{
+		ID:           ids.New(),
+		Symbol:       req.Symbol,
+		Side:         strings.ToUpper(req.Side),
+		Type:         string(orderType),
+		Price:        req.Price,
+		StopPrice:    req.StopPrice,
+		Qty:          req.Qty,
+		TimeInForce:  string(tif),
+		Routing:      req.Routing,
+		Status:       "NEW",
+		CreatedAt:    time.Now(),
+		Market:       string(market),
+		UserID:       userID,
+		ConnectionID: conn.ID,
+		Note:         strings.TrimSpace(req.Note),
+		Tags:         tags,
+	}
+
+	return o, nil
+}
+
+// referencePrice values an order at its own limit, else the last known price,
+// else its trigger price. It returns 0 when none is known.
+func (s *Server) referencePrice(o order.Order) float64 {
+	if o.Price > 0 {
+		return o.Price
+	}
+	if s.Prices != nil {
+		if px := s.Prices.Get(o.Symbol); px > 0 {
+			return px
+		}
+	}
+	return o.StopPrice
+}
+
+// riskSnapshot builds the position and account inputs for evaluating an order on
+// symbol: stored positions are marked to the last known price, and the balance
+// comes from the user's balance manager. pending orders (accepted earlier in
+// the same batch) are applied as if filled at their reference price, so a
+// batch cannot step past a limit one order at a time.
+func (s *Server) riskSnapshot(ctx context.Context, userID, symbol string, pending []order.Order) (risk.Position, risk.Account, error) {
+	positions, err := s.DB.Queries().GetPositionsByUser(ctx, userID)
+	if err != nil {
+		return risk.Position{}, risk.Account{}, err
+	}
+	for _, o := range pending {
+		i := 0
+		for i < len(positions) && positions[i].Symbol != o.Symbol {
+			i++
+		}
+		if i == len(positions) {
+			positions = append(positions, db.Position{Symbol: o.Symbol})
+		}
+		p := &positions[i]
+		p.Qty, p.AvgPrice, _ = state.FillPosition(p.Qty, p.AvgPrice, o.Side, o.Qty, s.referencePrice(o))
+	}
+	var position risk.Position
+	var open []string
+	exposure := 0.0
+	bySymbol := make(map[string]float64, len(positions))
+	for _, p := range positions {
+		mark := p.AvgPrice
+		if s.Prices != nil {
+			if px := s.Prices.Get(p.Symbol); px > 0 {
+				mark = px
+			}
+		}
+		exposure += math.Abs(p.Qty * mark)
+		bySymbol[strings.ToUpper(p.Symbol)] += math.Abs(p.Qty * mark)
+		if p.Qty != 0 {
+			open = append(open, p.Symbol)
+		}
+		if p.Symbol != symbol || p.Qty == 0 {
+			continue
+		}
+		side := "LONG"
+		if p.Qty < 0 {
+			side = "SHORT"
+		}
+		position = risk.Position{
+			Symbol:        p.Symbol,
+			Side:          side,
+			EntryPrice:    p.AvgPrice,
+			CurrentPrice:  mark,
+			Quantity:      p.Qty,
+			Value:         p.Qty * mark,
+			UnrealizedPnL: state.UnrealizedPnL(p.Qty, p.AvgPrice, mark),
+		}
+	}
+	account := risk.Account{TotalExposure: exposure, SymbolExposure: bySymbol, OpenPositions: open}
+	if s.UserBalances != nil {
+		if mgr, err := s.UserBalances.GetOrCreate(userID); err == nil && mgr != nil {
+			bal := mgr.GetBalance()
+			account.Balance = bal.Total
+			account.AvailableBalance = bal.Available
+			account.LockedBalance = bal.Locked
+		}
+	}
+	return position, account, nil
+}
+
+// simulateOrder runs the full risk evaluation and a paper fill for an order
+// without persisting it or sending it to the exchange.
+func (s *Server) simulateOrder(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return
+	}
+	if s.Risk == nil {
+		respondError(c, "RISK_UNAVAILABLE", "risk manager not available")
+		return
+	}
+
+	var req createOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
+		return
+	}
+	o, ok := s.orderFromRequest(c, userID, req)
+	if !ok {
+		return
+	}
+
+	refPrice := s.referencePrice(o)
+	if refPrice <= 0 {
+		respondError(c, "PRICE_UNAVAILABLE", "no price known for symbol; provide price")
+		return
+	}
+
+	ctx := c.Request.Context()
+	position, account, err := s.riskSnapshot(ctx, userID, o.Symbol, nil)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+
+	decision, err := s.Risk.SimulateForUser(userID, risk.SignalInput{
+		Symbol: o.Symbol,
+		Action: o.Side,
+		Size:   o.Qty,
+		Price:  refPrice,
+
+		ConnectionID: o.ConnectionID,
+	}, position, account, "")
+	if err != nil {
+		respondError(c, "RISK_ERROR", err.Error())
+		return
+	}
+
+	resp := gin.H{
+		"decision":        decision,
+		"requested_qty":   o.Qty,
+		"reference_price": refPrice,
+	}
+	if !decision.Allowed {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	if decision.AdjustedSize > 0 {
+		o.Qty = decision.AdjustedSize
+	}
+	o.Price = refPrice
+	resp["qty"] = o.Qty
+
+	// Fresh paper wallet seeded with the user's available balance (and held base
+	// asset, so spot sells can fill); the shared dry runner is never touched.
+	cfg := s.SimConfig
+	cfg.InitialAssets = nil
+	if base, _ := exchange.SplitSymbol(o.Symbol); base != "" && position.Quantity > 0 {
+		cfg.InitialAssets = map[string]float64{base: position.Quantity}
+	}
+	dry := order.NewDryRunExecutor(order.ModeDryRun, nil, account.AvailableBalance, cfg)
+	dry.SetFeeDB(s.DB)
+	fill, err := dry.Simulate(ctx, o)
+	if err != nil {
+		resp["fill_error"] = err.Error()
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	postQty := position.Quantity + o.Qty
+	if o.Side == "SELL" {
+		postQty = position.Quantity - o.Qty
+	}
+	postExposure := account.TotalExposure - math.Abs(position.Value) + math.Abs(postQty*fill.Price)
+
+	resp["fill"] = gin.H{
+		"price":    fill.Price,
+		"fee":      fill.Fee,
+		"fee_rate": fill.FeeRate,
+		"maker":    fill.Maker,
+		"notional": o.Qty * fill.Price,
+	}
+	resp["post_trade"] = gin.H{
+		"quote_asset":       fill.QuoteAsset,
+		"available_balance": fill.QuoteBalance,
+		"position_qty":      postQty,
+		"total_exposure":    postExposure,
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getBalance returns current balance information, plus margin state for the
+// user's futures connections when gateways are available.
+func (s *Server) getBalance(c *gin.Context) {
+	// Prefer per-user balance when multi-user manager is available.
+	userID := CurrentUserID(c)
+	var resp gin.H
+	if userID != "" && s.UserBalances != nil {
+		if mgr, err := s.UserBalances.GetOrCreate(userID); err == nil && mgr != nil {
+			b := mgr.GetBalance()
+			resp = gin.H{
+				"available": b.Available,
+				"locked":    b.Locked,
+				"total":     b.Total,
+			}
+		}
+	}
+
+	// Fallback to global engine balance.
+	if resp == nil {
+		bal, err := s.Engine.GetBalance(c.Request.Context())
+		if err != nil {
+			respondError(c, "ENGINE_UNAVAILABLE", err.Error())
+			return
+		}
+		if bal == nil {
+			bal = &engine.BalanceInfo{}
+		}
+		resp = gin.H{
+			"available": bal.Available,
+			"locked":    bal.Locked,
+			"total":     bal.Total,
+		}
+	}
+
+	if futures := s.futuresMargin(c.Request.Context(), userID); len(futures) > 0 {
+		resp["futures"] = futures
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// futuresMarginView is the margin state of one futures connection.
+type futuresMarginView struct {
+	ConnectionID   string               `json:"connection_id"`
+	ConnectionName string               `json:"connection_name"`
+	ExchangeType   string               `json:"exchange_type"`
+	Assets         []marginAssetView    `json:"assets"`
+	Positions      []marginPositionView `json:"positions"`
+	Error          string               `json:"error,omitempty"` // set when the venue could not be queried
+}
+
+type marginAssetView struct {
+	Asset            string  `json:"asset"`
+	WalletBalance    float64 `json:"wallet_balance"`
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`
+	MarginBalance    float64 `json:"margin_balance"`
+	MaintMargin      float64 `json:"maint_margin"`
+	AvailableBalance float64 `json:"available_balance"`
+	MarginRatio      float64 `json:"margin_ratio"` // liquidation at 1
+}
+
+type marginPositionView struct {
+	Symbol           string  `json:"symbol"`
+	PositionSide     string  `json:"position_side"`
+	Qty              float64 `json:"qty"`
+	EntryPrice       float64 `json:"entry_price"`
+	MarkPrice        float64 `json:"mark_price"`
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`
+	Leverage         int     `json:"leverage"`
+	MarginType       string  `json:"margin_type"`
+	MarginAsset      string  `json:"margin_asset"`
+	MaintMargin      float64 `json:"maint_margin"`
+	LiquidationPrice float64 `json:"liquidation_price"`
+}
+
+// futuresMargin queries each active futures connection of userID for wallet
+// balance, margin ratio and liquidation prices. A venue error is reported on
+// that connection rather than failing the whole balance.
+func (s *Server) futuresMargin(ctx context.Context, userID string) []futuresMarginView {
+	if userID == "" || s.Gateways == nil || s.DB == nil {
+		return nil
+	}
+	conns, err := s.DB.ListConnectionsByUser(ctx, userID)
+	if err != nil {
+		log.Printf("getBalance: list connections for %s failed: %v", userID, err)
+		return nil
+	}
+
+	var out []futuresMarginView
+	for _, conn := range conns {
+		if !conn.IsActive || (conn.ExchangeType != "binance-usdtfut" && conn.ExchangeType != "binance-coinfut") {
+			continue
+		}
+		view := futuresMarginView{ConnectionID: conn.ID, ConnectionName: conn.Name, ExchangeType: conn.ExchangeType}
+		acct, err := s.marginAccount(ctx, userID, conn.ID)
+		if err != nil {
+			view.Error = err.Error()
+			out = append(out, view)
+			continue
+		}
+		for _, a := range acct.Assets {
+			view.Assets = append(view.Assets, marginAssetView(a))
+		}
+		for _, p := range acct.Positions {
+			view.Positions = append(view.Positions, marginPositionView(p))
+		}
+		out = append(out, view)
+	}
+	return out
+}
+
+// marginCacheTTL bounds how long GET /balance reuses a connection's margin
+// account, so polling dashboards don't hit the venue on every request.
+const marginCacheTTL = 5 * time.Second
+
+type cachedMargin struct {
+	acct exchange.MarginAccount
+	at   time.Time
+}
+
+// marginAccount returns the connection's margin account, cached for
+// marginCacheTTL. Errors are not cached.
+func (s *Server) marginAccount(ctx context.Context, userID, connectionID string) (exchange.MarginAccount, error) {
+	key := userID + "/" + connectionID
+	s.marginMu.Lock()
+	cached, ok := s.marginCache[key]
+	s.marginMu.Unlock()
+	if ok && time.Since(cached.at) < marginCacheTTL {
+		return cached.acct, nil
+	}
+
+	gw, err := s.Gateways.GetOrCreate(ctx, userID, connectionID)
+	if err != nil {
+		return exchange.MarginAccount{}, err
+	}
+	reporter, ok := gw.(exchange.MarginReporter)
+	if !ok {
+		return exchange.MarginAccount{}, errors.New("exchange does not report margin")
+	}
+	acct, err := reporter.MarginAccount(ctx)
+	if err != nil {
+		return exchange.MarginAccount{}, err
+	}
+	s.marginMu.Lock()
+	s.marginCache[key] = cachedMargin{acct: acct, at: time.Now()}
+	s.marginMu.Unlock()
+	return acct, nil
+}
+
+// getSystemStatus exposes runtime mode/venue for the dashboard.
+func (s *Server) getSystemStatus(c *gin.Context) {
+	mode := "LIVE"
+	if s.Meta.DryRun {
+		mode = "DRY_RUN"
+	}
+	maintenance := gin.H{"active": false, "windows": s.Maintenance.Windows()}
+	if w, active := s.Maintenance.Active(); active {
+		maintenance["active"] = true
+		maintenance["current"] = w
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mode":          mode,
+		"dry_run":       s.Meta.DryRun,
+		"venue":         s.Meta.Venue,
+		"symbols":       s.Meta.Symbols,
+		"use_mock_feed": s.Meta.UseMockFeed,
+		"version":       s.Meta.Version,
+		"server_time":   time.Now().UTC(),
+		"maintenance":   maintenance,
+		"halted":        s.Halts.List(),
+		"frozen":        s.Freeze.State(),
+	})
+}
+
+// getRiskMetrics returns current risk metrics.
+func (s *Server) getRiskMetrics(c *gin.Context) {
+	metrics, err := s.Engine.GetRiskMetrics(c.Request.Context())
+	if err != nil {
+		respondError(c, "ENGINE_UNAVAILABLE", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+// getRiskConfig returns the caller's risk configuration.
+func (s *Server) getRiskConfig(c *gin.Context) {
+	mgr, ok := s.userRiskManager(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, mgr.GetConfig())
+}
+
+// updateRiskConfig applies a partial update to the caller's risk configuration.
+// Fields omitted from the body keep their current values; sending
+// "symbol_position_limits": {} or "symbol_sltp": {} clears those per-symbol
+// overrides and "correlation_groups": [] removes every correlation group.
+func (s *Server) updateRiskConfig(c *gin.Context) {
+	mgr, ok := s.userRiskManager(c)
+	if !ok {
+		return
+	}
+
+	current := mgr.GetConfig()
+	cfg := current
+	cfg.SymbolPositionLimits = nil
+	cfg.CorrelationGroups = nil
+	cfg.SymbolSLTP = nil
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
+		return
+	}
+	if cfg.SymbolPositionLimits == nil {
+		cfg.SymbolPositionLimits = current.SymbolPositionLimits
+	}
+	if cfg.CorrelationGroups == nil {
+		cfg.CorrelationGroups = current.CorrelationGroups
+	}
+	if cfg.SymbolSLTP == nil {
+		cfg.SymbolSLTP = current.SymbolSLTP
+	}
+	cfg.ID = current.ID
+	if cfg.MaxPositionSize < 0 {
+		respondError(c, "INVALID_RISK_CONFIG", "max_position_size must be >= 0")
+		return
+	}
+	if cfg.MaxConcurrentPositions < 0 {
+		respondError(c, "INVALID_RISK_CONFIG", "max_concurrent_positions must be >= 0")
+		return
+	}
+	for _, g := range cfg.CorrelationGroups {
+		if strings.TrimSpace(g.Name) == "" || len(g.Symbols) == 0 || g.MaxExposure < 0 {
+			respondError(c, "INVALID_RISK_CONFIG", fmt.Sprintf("invalid correlation group %q: needs a name, symbols and max_exposure >= 0", g.Name))
+			return
+		}
+	}
+	switch cfg.SizingMode {
+	case "", risk.SizingFixed:
+	case risk.SizingRiskFraction:
+		if cfg.RiskPerTrade <= 0 || cfg.RiskPerTrade > 1 {
+			respondError(c, "INVALID_RISK_CONFIG", "risk_per_trade must be in (0, 1] for risk_fraction sizing")
+			return
+		}
+	default:
+		respondError(c, "INVALID_RISK_CONFIG", fmt.Sprintf("unknown sizing_mode %q", cfg.SizingMode))
+		return
+	}
+	for sym, limit := range cfg.SymbolPositionLimits {
+		if strings.TrimSpace(sym) == "" || limit < 0 {
+			respondError(c, "INVALID_RISK_CONFIG", fmt.Sprintf("invalid position limit for symbol %q", sym))
+			return
+		}
+	}
+	for sym, o := range cfg.SymbolSLTP {
+		if strings.TrimSpace(sym) == "" || o.StopLoss < 0 || o.StopLoss >= 1 || o.TakeProfit < 0 {
+			respondError(c, "INVALID_RISK_CONFIG", fmt.Sprintf("invalid stop loss/take profit for symbol %q", sym))
+			return
+		}
+	}
+
+	// Users may tighten their limits freely but only an admin may relax one
+	// past both its current value and the default.
+	user, err := s.DB.GetUserByID(c.Request.Context(), CurrentUserID(c))
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	if user == nil || !s.isAdminEmail(user.Email) {
+		beyondDefault := make(map[string]bool)
+		for _, f := range risk.LoosenedLimits(risk.DefaultConfig(), cfg) {
+			beyondDefault[f] = true
+		}
+		var loosened []string
+		for _, f := range risk.LoosenedLimits(current, cfg) {
+			if beyondDefault[f] {
+				loosened = append(loosened, f)
+			}
+		}
+		if len(loosened) > 0 {
+			respondError(c, "RISK_LIMIT_LOOSENED", "only admins can loosen: "+strings.Join(loosened, ", "))
+			return
+		}
+	}
+
+	if err := mgr.UpdateConfig(c.Request.Context(), cfg); err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, mgr.GetConfig())
+}
+
+// getStrategyRisk returns a strategy's risk settings, including its capital allocation.
+func (s *Server) getStrategyRisk(c *gin.Context) {
+	id := c.Param("id")
+	if !s.canAccessStrategy(c, id) {
+		return
+	}
+	mgr, ok := s.userRiskManager(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, mgr.GetStrategyConfig(id))
+}
+
+// getStrategyLadder returns how the strategy's signals are split into orders.
+func (s *Server) getStrategyLadder(c *gin.Context) {
+	id := c.Param("id")
+	if !s.canAccessStrategy(c, id) {
+		return
+	}
+	l, found, err := s.DB.GetStrategyLadder(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	if !found {
+		respondError(c, "STRATEGY_NOT_FOUND", "")
+		return
+	}
+	c.JSON(http.StatusOK, order.LadderFromDB(l))
+}
+
+// updateStrategyLadder sets the strategy's scale-in/scale-out ladder. Risk
+// checks and the balance lock still apply to the signal's full size.
+func (s *Server) updateStrategyLadder(c *gin.Context) {
+	id := c.Param("id")
+	if !s.canAccessStrategy(c, id) {
+		return
+	}
+	var req order.LadderConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(c, "INVALID_LADDER", err.Error())
+		return
+	}
+	l := req.ToDB()
+	if err := s.DB.UpdateStrategyLadder(c.Request.Context(), id, l); err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, order.LadderFromDB(l))
+}
+
+// updateStrategyRisk applies a partial update to a strategy's risk settings.
+// The caller's allocations across all strategies may not exceed their
+// available balance.
+func (s *Server) updateStrategyRisk(c *gin.Context) {
+	id := c.Param("id")
+	if !s.canAccessStrategy(c, id) {
+		return
+	}
+	mgr, ok := s.userRiskManager(c)
+	if !ok {
+		return
+	}
+
+	cfg := mgr.GetStrategyConfig(id)
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
+		return
+	}
+	cfg.StrategyInstanceID = id
+	if cfg.Allocation < 0 || cfg.MaxPositionSize < 0 {
+		respondError(c, "INVALID_RISK_CONFIG", "allocation and max_position_size must be >= 0")
+		return
+	}
+	if cfg.MaxDrawdownPct < 0 || cfg.MaxDrawdownPct >= 100 {
+		respondError(c, "INVALID_RISK_CONFIG", "max_drawdown_pct must be between 0 and 100")
+		return
+	}
+	if cfg.MinHoldSeconds < 0 {
+		respondError(c, "INVALID_RISK_CONFIG", "min_hold_seconds must be >= 0")
+		return
+	}
+
+	if cfg.Allocation > 0 && s.UserBalances != nil {
+		others, err := mgr.AllocatedCapital(id)
+		if err != nil {
+			respondError(c, "DB_ERROR", err.Error())
+			return
+		}
+		if bm, err := s.UserBalances.GetOrCreate(CurrentUserID(c)); err == nil && bm != nil {
+			if avail := bm.GetBalance().Available; others+cfg.Allocation > avail {
+				respondError(c, "ALLOCATION_EXCEEDS_BALANCE",
+					fmt.Sprintf("allocations would total %.2f, above available balance %.2f", others+cfg.Allocation, avail))
+				return
+			}
+		}
+	}
+
+	if err := mgr.SetStrategyConfig(cfg); err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, mgr.GetStrategyConfig(id))
+}
+
+func (s *Server) userRiskManager(c *gin.Context) (*risk.Manager, bool) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "user not authenticated")
+		return nil, false
+	}
+	if s.Risk == nil {
+		respondError(c, "RISK_UNAVAILABLE", "risk manager not available")
+		return nil, false
+	}
+	mgr, err := s.Risk.GetOrCreate(userID)
+	if err != nil {
+		respondError(c, "RISK_ERROR", err.Error())
+		return nil, false
+	}
+	return mgr, true
+}
+
+// getStrategyPerformance returns daily realized pnl and equity curve for a strategy.
+// PnL is booked only when fills close against the average entry price (net of fees).
+func (s *Server) getStrategyPerformance(c *gin.Context) {
+	id := c.Param("id")
+	if !s.canAccessStrategy(c, id) {
+		return
+	}
+
+	from := c.Query("from")
+	to := c.Query("to")
+
+	// Default range: last 30 days
+	toTime := time.Now()
+	fromTime := toTime.AddDate(0, 0, -30)
+	var err error
+	if from != "" {
+		fromTime, err = time.Parse("2006-01-02", from)
+		if err != nil {
+			respondError(c, "INVALID_FROM_DATE", "invalid from date")
+			return
+		}
+	}
+	if to != "" {
+		toTime, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			respondError(c, "INVALID_TO_DATE", "invalid to date")
+			return
+		}
+		// include whole day
+		toTime = toTime.Add(24 * time.Hour)
+	}
+
+	// Realized PnL via average-cost matching; opening fills only contribute their fees.
+	realized, err := s.DB.StrategyDailyRealizedPnL(c.Request.Context(), id, fromTime, toTime)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+
+	type point struct {
+		Date   string  `json:"date"`
+		PNL    float64 `json:"pnl"`
+		Equity float64 `json:"equity"`
+	}
+	var daily []point
+	var equity float64
+	for _, r := range realized {
+		equity += r.PnL
+		daily = append(daily, point{Date: r.Date, PNL: r.PnL, Equity: equity})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"strategy_id": id,
+		"from":        fromTime.Format("2006-01-02"),
+		"to":          toTime.Add(-24 * time.Hour).Format("2006-01-02"),
+		"daily":       daily,
+		"total_pnl":   equity,
+	})
+}
+
+// pnlAttribution is one strategy's PnL over a range (strategy_id "" = manual
+// orders). net = gross - fees - funding; funding is positive when paid.
+type pnlAttribution struct {
+	StrategyID string  `json:"strategy_id"`
+	Gross      float64 `json:"gross"`
+	Fees       float64 `json:"fees"`
+	Funding    float64 `json:"funding"`
+	Net        float64 `json:"net"`
+	Trades     int     `json:"trades"`
+}
+
+type pnlRangeQuery struct {
+	From string `form:"from"` // RFC3339 or YYYY-MM-DD; default 30 days ago
+	To   string `form:"to"`
+}
+
+type pnlAttributionResponse struct {
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Strategies []pnlAttribution `json:"strategies"`
+	Total      pnlAttribution   `json:"total"`
+}
+
+// getPnLAttribution returns the current user's PnL per strategy broken down
+// into gross realized PnL, fees and funding, plus the user total.
+// from/to accept RFC3339 timestamps or YYYY-MM-DD dates; default is the last 30 days.
+func (s *Server) getPnLAttribution(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
+		return
+	}
+	s.respondPnLAttribution(c, userID, "")
+}
+
+// getStrategyPnLAttribution is getPnLAttribution for a single strategy.
+func (s *Server) getStrategyPnLAttribution(c *gin.Context) {
+	id := c.Param("id")
+	if !s.canAccessStrategy(c, id) {
+		return
+	}
+	s.respondPnLAttribution(c, CurrentUserID(c), id)
+}
+
+func (s *Server) respondPnLAttribution(c *gin.Context, userID, strategyID string) {
+	var q pnlRangeQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		respondError(c, "INVALID_QUERY", "invalid query parameters")
+		return
+	}
+	toTime := time.Now()
+	fromTime := toTime.AddDate(0, 0, -30)
+	if q.From != "" {
+		t, err := parseTimeParam(q.From, false)
+		if err != nil {
+			respondError(c, "INVALID_FROM_DATE", "invalid from date")
+			return
+		}
+		fromTime = t
+	}
+	if q.To != "" {
+		t, err := parseTimeParam(q.To, true)
+		if err != nil {
+			respondError(c, "INVALID_TO_DATE", "invalid to date")
+			return
+		}
+		toTime = t
+	}
+
+	rows, err := s.DB.PnLAttribution(c.Request.Context(), userID, strategyID, fromTime, toTime)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+
+	resp := pnlAttributionResponse{From: fromTime.UTC(), To: toTime.UTC(), Strategies: make([]pnlAttribution, 0, len(rows))}
+	var gross, fees, funding money.Amount
+	for _, r := range rows {
+		resp.Strategies = append(resp.Strategies, pnlAttribution{
+			StrategyID: r.StrategyID,
+			Gross:      r.Gross,
+			Fees:       r.Fees,
+			Funding:    r.Funding,
+			Net:        r.Net,
+			Trades:     r.Trades,
+		})
+		gross = gross.Add(money.FromFloat(r.Gross))
+		fees = fees.Add(money.FromFloat(r.Fees))
+		funding = funding.Add(money.FromFloat(r.Funding))
+		resp.Total.Trades += r.Trades
+	}
+	resp.Total.StrategyID = strategyID
+	resp.Total.Gross = gross.Float64()
+	resp.Total.Fees = fees.Float64()
+	resp.Total.Funding = funding.Float64()
+	resp.Total.Net = gross.Sub(fees).Sub(funding).Float64()
+	c.JSON(http.StatusOK, resp)
+}
+
+// getEquityCurve returns the current user's equity snapshots (oldest first).
+// from/to accept RFC3339 timestamps or YYYY-MM-DD dates; default is the last 30 days.
+func (s *Server) getEquityCurve(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
+		return
+	}
+
+	toTime := time.Now()
+	fromTime := toTime.AddDate(0, 0, -30)
+	if from := c.Query("from"); from != "" {
+		t, err := parseTimeParam(from, false)
+		if err != nil {
+			respondError(c, "INVALID_FROM_DATE", "invalid from date")
+			return
+		}
+		fromTime = t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseTimeParam(to, true)
+		if err != nil {
+			respondError(c, "INVALID_TO_DATE", "invalid to date")
+			return
+		}
+		toTime = t
+	}
+
+	snapshots, err := s.DB.Queries().GetEquitySnapshotsByUser(c.Request.Context(), userID, fromTime, toTime)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+
+	points := make([]gin.H, 0, len(snapshots))
+	for _, snap := range snapshots {
+		points = append(points, gin.H{
+			"time":           snap.CreatedAt,
+			"balance":        snap.Balance,
+			"position_value": snap.PositionValue,
+			"equity":         snap.Equity,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":   fromTime.UTC(),
+		"to":     toTime.UTC(),
+		"points": points,
+	})
+}
+
+type profileResponse struct {
+	DisplayName      string `json:"display_name"`
+	LeaderboardOptIn bool   `json:"leaderboard_opt_in"`
+}
+
+// updateProfileRequest changes only the fields that are present.
+type updateProfileRequest struct {
+	DisplayName      *string `json:"display_name" binding:"omitempty,max=32"`
+	LeaderboardOptIn *bool   `json:"leaderboard_opt_in"`
+}
+
+// getProfile returns the authenticated user's public profile.
+func (s *Server) getProfile(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "user not authenticated")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
-
-	positions, err := s.DB.Queries().GetPositionsByUser(c.Request.Context(), userID)
+	p, err := s.DB.Queries().GetUserProfile(c.Request.Context(), userID)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, positions)
+	c.JSON(http.StatusOK, profileResponse{DisplayName: p.DisplayName, LeaderboardOptIn: p.LeaderboardOptIn})
 }
 
-// createOrder submits a manual order for the authenticated user on a specific connection.
-func (s *Server) createOrder(c *gin.Context) {
+// updateProfile sets the display name and leaderboard opt-in.
+func (s *Server) updateProfile(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "user not authenticated")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
-	if s.OrderQueue == nil {
-		respondError(c, http.StatusServiceUnavailable, "QUEUE_UNAVAILABLE", "order queue not available")
-		return
-	}
-
-	var req createOrderRequest
+	var req updateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload")
-		return
-	}
-	if strings.EqualFold(req.Type, "LIMIT") && req.Price <= 0 {
-		respondError(c, http.StatusBadRequest, "INVALID_PRICE", "price must be > 0 for LIMIT orders")
+		respondError(c, "INVALID_REQUEST", err.Error())
 		return
 	}
 
 	ctx := c.Request.Context()
-	conn, err := s.DB.Queries().GetConnectionByID(ctx, userID, req.ConnectionID)
+	p, err := s.DB.Queries().GetUserProfile(ctx, userID)
 	if err != nil {
-		if errors.Is(err, db.ErrNotFound) {
-			respondError(c, http.StatusBadRequest, "INVALID_CONNECTION", "invalid connection for current user")
-		} else {
-			log.Printf("createOrder: failed to get connection %s for user %s: %v", req.ConnectionID, userID, err)
-			respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
-		}
-		return
-	}
-	if conn.APIKeyEncrypted != "" && s.KeyManager == nil {
-		respondError(c, http.StatusInternalServerError, "CONFIG_ERROR", "encrypted connection requires KeyManager")
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
-	if !conn.IsActive {
-		respondError(c, http.StatusBadRequest, "CONNECTION_INACTIVE", "connection is not active")
-		return
+	if req.DisplayName != nil {
+		p.DisplayName = strings.TrimSpace(*req.DisplayName)
 	}
-
-	if s.UserBalances != nil {
-		if mgr, err := s.UserBalances.GetOrCreate(userID); err == nil && mgr != nil {
-			cost := req.Price * req.Qty
-			if cost <= 0 {
-				cost = req.Qty
-			}
-			if bal := mgr.GetBalance(); cost > bal.Available {
-				respondError(c, http.StatusBadRequest, "INSUFFICIENT_BALANCE", "insufficient balance")
-				return
-			}
-		}
+	if req.LeaderboardOptIn != nil {
+		p.LeaderboardOptIn = *req.LeaderboardOptIn
 	}
-
-	var market string
-	switch conn.ExchangeType {
-	case "binance-spot":
-		market = string(exchange.MarketSpot)
-	case "binance-usdtfut":
-		market = string(exchange.MarketUSDTFut)
-	case "binance-coinfut":
-		market = string(exchange.MarketCoinFut)
-	default:
-		respondError(c, http.StatusBadRequest, "UNSUPPORTED_EXCHANGE", "unsupported exchange type")
+	if err := s.DB.Queries().SetUserProfile(ctx, userID, p); err != nil {
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
+	c.JSON(http.StatusOK, profileResponse{DisplayName: p.DisplayName, LeaderboardOptIn: p.LeaderboardOptIn})
+}
 
-	o := order.Order{
-		ID:           uuid.NewString(),
-		Symbol:       req.Symbol,
-		Side:         strings.ToUpper(req.Side),
-		Type:         strings.ToUpper(req.Type),
-		Price:        req.Price,
-		Qty:          req.Qty,
-		Status:       "NEW",
-		CreatedAt:    time.Now(),
-		Market:       market,
-		UserID:       userID,
-		ConnectionID: conn.ID,
-	}
+type leaderboardQuery struct {
+	From  string `form:"from"` // RFC3339 or YYYY-MM-DD; default 30 days ago
+	To    string `form:"to"`
+	Limit int    `form:"limit"`
+}
 
-	s.OrderQueue.Enqueue(o)
+// leaderboardEntry is anonymized: users appear by display name only.
+type leaderboardEntry struct {
+	Rank        int     `json:"rank"`
+	DisplayName string  `json:"display_name"`
+	ReturnPct   float64 `json:"return_pct"`
+	You         bool    `json:"you,omitempty"`
+}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"id":            o.ID,
-		"symbol":        o.Symbol,
-		"side":          o.Side,
-		"type":          o.Type,
-		"price":         o.Price,
-		"qty":           o.Qty,
-		"status":        o.Status,
-		"connection_id": o.ConnectionID,
-	})
+type leaderboardResponse struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	Entries []leaderboardEntry `json:"entries"`
 }
 
-// getBalance returns current balance information.
-func (s *Server) getBalance(c *gin.Context) {
-	// Prefer per-user balance when multi-user manager is available.
+// getLeaderboard ranks opted-in users by paper-trading equity growth over
+// the period, from their first to their last equity snapshot in it.
+func (s *Server) getLeaderboard(c *gin.Context) {
 	userID := CurrentUserID(c)
-	if userID != "" && s.UserBalances != nil {
-		if mgr, err := s.UserBalances.GetOrCreate(userID); err == nil && mgr != nil {
-			b := mgr.GetBalance()
-			c.JSON(http.StatusOK, gin.H{
-				"available": b.Available,
-				"locked":    b.Locked,
-				"total":     b.Total,
-			})
-			return
-		}
-	}
-
-	// Fallback to global engine balance.
-	bal, err := s.Engine.GetBalance(c.Request.Context())
-	if err != nil {
-		respondError(c, http.StatusServiceUnavailable, "ENGINE_UNAVAILABLE", err.Error())
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
-	c.JSON(http.StatusOK, bal)
-}
-
-// getSystemStatus exposes runtime mode/venue for the dashboard.
-func (s *Server) getSystemStatus(c *gin.Context) {
-	mode := "LIVE"
-	if s.Meta.DryRun {
-		mode = "DRY_RUN"
-	}
-	c.JSON(http.StatusOK, gin.H{
-		"mode":          mode,
-		"dry_run":       s.Meta.DryRun,
-		"venue":         s.Meta.Venue,
-		"symbols":       s.Meta.Symbols,
-		"use_mock_feed": s.Meta.UseMockFeed,
-		"version":       s.Meta.Version,
-		"server_time":   time.Now().UTC(),
-	})
-}
-
-// getRiskMetrics returns current risk metrics.
-func (s *Server) getRiskMetrics(c *gin.Context) {
-	metrics, err := s.Engine.GetRiskMetrics(c.Request.Context())
-	if err != nil {
-		respondError(c, http.StatusServiceUnavailable, "ENGINE_UNAVAILABLE", err.Error())
+	if !s.Meta.DryRun {
+		respondError(c, "LEADERBOARD_UNAVAILABLE", "the leaderboard ranks paper trading only; server is not in dry-run mode")
 		return
 	}
-	c.JSON(http.StatusOK, metrics)
-}
 
-// getStrategyPerformance returns daily pnl and equity curve (cash-flow based) for a strategy.
-// PnL is approximated as SELL notional minus BUY notional minus fee per trade.
-func (s *Server) getStrategyPerformance(c *gin.Context) {
-	id := c.Param("id")
-	if !s.canAccessStrategy(c, id) {
+	var q leaderboardQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		respondError(c, "INVALID_QUERY", "invalid query parameters")
 		return
 	}
-
-	from := c.Query("from")
-	to := c.Query("to")
-
-	// Default range: last 30 days
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 100
+	}
 	toTime := time.Now()
 	fromTime := toTime.AddDate(0, 0, -30)
-	var err error
-	if from != "" {
-		fromTime, err = time.Parse("2006-01-02", from)
+	if q.From != "" {
+		t, err := parseTimeParam(q.From, false)
 		if err != nil {
-			respondError(c, http.StatusBadRequest, "INVALID_FROM_DATE", "invalid from date")
+			respondError(c, "INVALID_FROM_DATE", "invalid from date")
 			return
 		}
+		fromTime = t
 	}
-	if to != "" {
-		toTime, err = time.Parse("2006-01-02", to)
+	if q.To != "" {
+		t, err := parseTimeParam(q.To, true)
 		if err != nil {
-			respondError(c, http.StatusBadRequest, "INVALID_TO_DATE", "invalid to date")
+			respondError(c, "INVALID_TO_DATE", "invalid to date")
 			return
 		}
-		// include whole day
-		toTime = toTime.Add(24 * time.Hour)
+		toTime = t
 	}
 
-	rows, err := s.DB.DB.Query(`
-		SELECT 
-			date(t.created_at) as d,
-			SUM(
-				CASE 
-					WHEN UPPER(o.side) = 'SELL' THEN (t.price * t.qty)
-					ELSE -(t.price * t.qty)
-				END - t.fee
-			) as pnl
-		FROM trades t
-		JOIN orders o ON t.order_id = o.id
-		WHERE o.strategy_instance_id = ?
-		  AND t.created_at >= ? AND t.created_at <= ?
-		GROUP BY date(t.created_at)
-		ORDER BY d ASC
-	`, id, fromTime, toTime)
+	rows, err := s.DB.ListLeaderboardEntries(c.Request.Context(), fromTime, toTime)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
-	defer rows.Close()
-
-	type point struct {
-		Date   string  `json:"date"`
-		PNL    float64 `json:"pnl"`
-		Equity float64 `json:"equity"`
-	}
-	var daily []point
-	var equity float64
-	for rows.Next() {
-		var d string
-		var pnl float64
-		if err := rows.Scan(&d, &pnl); err != nil {
+	entries := make([]leaderboardEntry, 0, len(rows))
+	for _, r := range rows {
+		if r.StartEquity <= 0 {
 			continue
 		}
-		equity += pnl
-		daily = append(daily, point{Date: d, PNL: pnl, Equity: equity})
+		name := r.DisplayName
+		if name == "" {
+			name = "anonymous"
+		}
+		entries = append(entries, leaderboardEntry{
+			DisplayName: name,
+			ReturnPct:   (r.EndEquity - r.StartEquity) / r.StartEquity * 100,
+			You:         r.UserID == userID,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ReturnPct > entries[j].ReturnPct })
+	if len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"strategy_id": id,
-		"from":        fromTime.Format("2006-01-02"),
-		"to":          toTime.Add(-24 * time.Hour).Format("2006-01-02"),
-		"daily":       daily,
-		"total_pnl":   equity,
-	})
+	c.JSON(http.StatusOK, leaderboardResponse{From: fromTime.UTC(), To: toTime.UTC(), Entries: entries})
+}
+
+// getPrices returns the last-known price for every symbol seen on the feed.
+func (s *Server) getPrices(c *gin.Context) {
+	if s.Prices == nil {
+		respondError(c, "PRICES_UNAVAILABLE", "price store not available")
+		return
+	}
+	c.JSON(http.StatusOK, s.Prices.All())
+}
+
+// getPrice returns the last-known price for a single symbol.
+func (s *Server) getPrice(c *gin.Context) {
+	if s.Prices == nil {
+		respondError(c, "PRICES_UNAVAILABLE", "price store not available")
+		return
+	}
+	p, ok := s.Prices.Lookup(strings.ToUpper(c.Param("symbol")))
+	if !ok {
+		respondError(c, "PRICE_NOT_FOUND", "no price seen for symbol")
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// parseTimeParam parses an RFC3339 timestamp or a YYYY-MM-DD date.
+// For date-only values with endOfDay set, the whole day is included.
+func parseTimeParam(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
 }
 
 // Exchange Connections (per-user)
@@ -614,41 +1955,80 @@ func (s *Server) getStrategyPerformance(c *gin.Context) {
 func (s *Server) listConnections(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "unauthorized")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
 
 	conns, err := s.DB.ListConnectionsByUser(c.Request.Context(), userID)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 
+	healthSrc, _ := s.Gateways.(connectionHealthSource)
 	var out []gin.H
 	for _, conn := range conns {
-		out = append(out, gin.H{
+		view := gin.H{
 			"id":            conn.ID,
 			"name":          conn.Name,
 			"exchange_type": conn.ExchangeType,
 			"is_active":     conn.IsActive,
+			"paper":         conn.Paper,
 			"created_at":    conn.CreatedAt,
 			"updated_at":    conn.UpdatedAt,
-		})
+		}
+		if healthSrc != nil {
+			view["health"] = newConnectionHealthView(healthSrc.Health(conn.ID))
+		}
+		out = append(out, view)
 	}
 	c.JSON(http.StatusOK, out)
 }
 
+// connectionHealthSource is implemented by gateway pools that track
+// per-connection health (gateway.Manager).
+type connectionHealthSource interface {
+	Health(connectionID string) gateway.ConnectionHealth
+}
+
+// connectionHealthView lets users see why a connection stopped trading
+// (circuit breaker open, rejected keys) without asking an operator.
+type connectionHealthView struct {
+	Status        string     `json:"status"` // healthy, unhealthy or unknown (not used yet)
+	Failures      int        `json:"consecutive_failures"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+func newConnectionHealthView(h gateway.ConnectionHealth) connectionHealthView {
+	v := connectionHealthView{Status: h.Status, Failures: h.Failures, LastError: h.LastError}
+	if !h.LastErrorAt.IsZero() {
+		t := h.LastErrorAt.UTC()
+		v.LastErrorAt = &t
+	}
+	if !h.LastSuccessAt.IsZero() {
+		t := h.LastSuccessAt.UTC()
+		v.LastSuccessAt = &t
+	}
+	return v
+}
+
+// capabilityProbeTimeout bounds the account permission probe run when a
+// connection is created; on timeout the connection is stored unprobed.
+const capabilityProbeTimeout = 10 * time.Second
+
 // createConnection creates a new exchange connection for the current user.
 func (s *Server) createConnection(c *gin.Context) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("createConnection: panic: %v\n%s", r, debug.Stack())
-			respondError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+			respondError(c, "INTERNAL_ERROR", "internal server error")
 		}
 	}()
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "unauthorized")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
 	log.Printf("createConnection: user=%s", userID)
@@ -656,19 +2036,23 @@ func (s *Server) createConnection(c *gin.Context) {
 	var req createConnectionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("createConnection: invalid payload: %v", err)
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload")
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
 		return
 	}
 	log.Printf("createConnection: payload user=%s name=%s exch=%s", userID, req.Name, req.ExchangeType)
 
 	if s.DB == nil || s.DB.DB == nil {
 		log.Printf("createConnection: DB not initialized")
-		respondError(c, http.StatusInternalServerError, "CONFIG_ERROR", "database not initialized")
+		respondError(c, "CONFIG_ERROR", "database not initialized")
 		return
 	}
 
 	if s.KeyManager == nil {
-		respondError(c, http.StatusInternalServerError, "CONFIG_ERROR", "KeyManager required for connection storage")
+		respondError(c, "CONFIG_ERROR", "KeyManager required for connection storage")
+		return
+	}
+
+	if !s.withinResourceLimit(c, userID, "connections") {
 		return
 	}
 
@@ -678,6 +2062,13 @@ func (s *Server) createConnection(c *gin.Context) {
 		UserID:        userID,
 		ExchangeType:  req.ExchangeType,
 		Name:          req.Name,
+		MakerFeeBps:   req.MakerFeeBps,
+		TakerFeeBps:   req.TakerFeeBps,
+		BNBDiscount:   req.BNBDiscount,
+		Leverage:      req.Leverage,
+		MaxDailyLoss:  req.MaxDailyLoss,
+		MaxExposure:   req.MaxExposure,
+		Paper:         req.Paper,
 		IsActive:      true,
 		CreatedAt:     now,
 		UpdatedAt:     now,
@@ -688,14 +2079,14 @@ func (s *Server) createConnection(c *gin.Context) {
 	encKey, err := s.KeyManager.Encrypt(req.APIKey)
 	if err != nil {
 		log.Printf("createConnection: encrypt api_key failed: %v", err)
-		respondError(c, http.StatusInternalServerError, "ENCRYPTION_ERROR", "failed to encrypt api_key")
+		respondError(c, "ENCRYPTION_ERROR", "failed to encrypt api_key")
 		return
 	}
 	log.Printf("createConnection: encrypted api_key for user %s", userID)
 	encSecret, err := s.KeyManager.Encrypt(req.APISecret)
 	if err != nil {
 		log.Printf("createConnection: encrypt api_secret failed: %v", err)
-		respondError(c, http.StatusInternalServerError, "ENCRYPTION_ERROR", "failed to encrypt api_secret")
+		respondError(c, "ENCRYPTION_ERROR", "failed to encrypt api_secret")
 		return
 	}
 	conn.APIKeyEncrypted = encKey
@@ -705,23 +2096,44 @@ func (s *Server) createConnection(c *gin.Context) {
 	conn.APIKey = req.APIKey
 	conn.APISecret = req.APISecret
 
+	// Record account permissions so the executor can reject unsupported orders
+	// up front. A slow exchange only delays creation by capabilityProbeTimeout.
+	if s.ProbeCapabilities != nil {
+		probeCtx, cancel := context.WithTimeout(c.Request.Context(), capabilityProbeTimeout)
+		caps, err := s.ProbeCapabilities(probeCtx, req.ExchangeType, req.APIKey, req.APISecret)
+		cancel()
+		if err != nil {
+			log.Printf("createConnection: capability probe failed for user %s: %v", userID, err)
+		} else {
+			conn.Capabilities = exchange.FormatCapabilities(caps)
+		}
+	}
+
 	if err := s.DB.Queries().CreateConnectionEncrypted(c.Request.Context(), conn); err != nil {
 		log.Printf("createConnection: db error for user %s: %v", userID, err)
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 	log.Printf("createConnection: created id=%s user=%s exch=%s", conn.ID, userID, conn.ExchangeType)
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":            conn.ID,
-		"name":          conn.Name,
-		"exchange_type": conn.ExchangeType,
-		"is_active":     conn.IsActive,
-		"encrypted":     true,
-		"key_version":   conn.KeyVersion,
-		"last_rotated":  conn.LastRotatedAt,
-		"created_at":    conn.CreatedAt,
-		"updated_at":    conn.UpdatedAt,
+		"id":             conn.ID,
+		"name":           conn.Name,
+		"exchange_type":  conn.ExchangeType,
+		"is_active":      conn.IsActive,
+		"encrypted":      true,
+		"key_version":    conn.KeyVersion,
+		"last_rotated":   conn.LastRotatedAt,
+		"maker_fee_bps":  conn.MakerFeeBps,
+		"taker_fee_bps":  conn.TakerFeeBps,
+		"bnb_discount":   conn.BNBDiscount,
+		"leverage":       conn.Leverage,
+		"max_daily_loss": conn.MaxDailyLoss,
+		"max_exposure":   conn.MaxExposure,
+		"paper":          conn.Paper,
+		"capabilities":   conn.Capabilities,
+		"created_at":     conn.CreatedAt,
+		"updated_at":     conn.UpdatedAt,
 	})
 }
 
@@ -729,80 +2141,160 @@ func (s *Server) createConnection(c *gin.Context) {
 func (s *Server) deactivateConnection(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "unauthorized")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
 
 	id := c.Param("id")
 	if id == "" {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "missing connection id")
+		respondError(c, "INVALID_REQUEST", "missing connection id")
 		return
 	}
 
 	if err := s.DB.DeactivateConnection(c.Request.Context(), id, userID); err != nil {
 		if errors.Is(err, db.ErrNotFound) {
-			respondError(c, http.StatusForbidden, "FORBIDDEN", "connection does not belong to current user")
+			respondError(c, "FORBIDDEN", "connection does not belong to current user")
 			return
 		}
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "deactivated"})
 }
 
+// testConnection checks a connection's keys against the venue without placing an order,
+// returning trade permission, account type and round-trip latency.
+func (s *Server) testConnection(c *gin.Context) {
+	userID := CurrentUserID(c)
+	if userID == "" {
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
+		return
+	}
+
+	id := c.Param("id")
+	ctx := c.Request.Context()
+	conn, err := s.DB.Queries().GetConnectionByID(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			respondError(c, "CONNECTION_NOT_FOUND", "connection not found")
+			return
+		}
+		respondError(c, "DB_ERROR", err.Error())
+		return
+	}
+	if !conn.IsActive {
+		respondError(c, "CONNECTION_INACTIVE", "connection is not active")
+		return
+	}
+	if s.Gateways == nil {
+		respondError(c, "GATEWAY_UNAVAILABLE", "gateway pool not configured")
+		return
+	}
+
+	gw, err := s.Gateways.GetOrCreate(ctx, userID, conn.ID)
+	if err != nil {
+		respondError(c, "CONNECTION_TEST_FAILED", err.Error())
+		return
+	}
+
+	start := time.Now()
+	var (
+		summary    exchange.AccountSummary
+		summarized bool
+	)
+	switch g := gw.(type) {
+	case exchange.AccountSummarizer:
+		summary, err = g.AccountSummary(ctx)
+		summarized = true
+	case interface{ Ping(context.Context) error }:
+		err = g.Ping(ctx) // connectivity only; permissions unknown
+	default:
+		respondError(c, "NOT_SUPPORTED", "exchange does not support connection tests")
+		return
+	}
+	latency := time.Since(start)
+	if err != nil {
+		respondError(c, "CONNECTION_TEST_FAILED", err.Error())
+		return
+	}
+
+	resp := gin.H{
+		"id":         conn.ID,
+		"ok":         true,
+		"latency_ms": latency.Milliseconds(),
+	}
+	if summarized {
+		resp["can_trade"] = summary.CanTrade
+		resp["account_type"] = summary.AccountType
+		resp["permissions"] = summary.Permissions
+
+		// Keep stored capabilities current so the executor's checks use fresh permissions.
+		caps := exchange.FormatCapabilities(summary.Permissions)
+		if err := s.DB.Queries().UpdateConnectionCapabilities(ctx, userID, conn.ID, caps); err != nil {
+			log.Printf("testConnection: update capabilities for %s failed: %v", conn.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // updateStrategyBinding binds a strategy instance to a user + connection.
 func (s *Server) updateStrategyBinding(c *gin.Context) {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "unauthorized")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return
 	}
 
 	id := c.Param("id")
 	if id == "" {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "missing strategy id")
+		respondError(c, "INVALID_REQUEST", "missing strategy id")
 		return
 	}
 
 	var req updateStrategyBindingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload")
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
 		return
 	}
 
 	// Check ownership of strategy
 	var owner sql.NullString
-	err := s.DB.DB.QueryRow(`SELECT user_id FROM strategy_instances WHERE id = ?`, id).Scan(&owner)
+	var symbol string
+	err := s.DB.DB.QueryRow(`SELECT user_id, symbol FROM strategy_instances WHERE id = ?`, id).Scan(&owner, &symbol)
 	if err == sql.ErrNoRows {
-		respondError(c, http.StatusNotFound, "STRATEGY_NOT_FOUND", "strategy not found")
+		respondError(c, "STRATEGY_NOT_FOUND", "strategy not found")
 		return
 	}
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 	if owner.Valid && owner.String != userID {
-		respondError(c, http.StatusForbidden, "FORBIDDEN", "strategy does not belong to current user")
+		respondError(c, "FORBIDDEN", "strategy does not belong to current user")
 		return
 	}
 
-	// If a connection is specified, validate it belongs to the user and is active.
+	// If a connection is specified, validate it belongs to the user and is
+	// active, and that the strategy's symbol trades on its market.
+	exchangeType := ""
 	if req.ConnectionID != "" {
-		var count int
 		err = s.DB.DB.QueryRow(`
-			SELECT COUNT(1) FROM connections 
+			SELECT exchange_type FROM connections
 			WHERE id = ? AND user_id = ? AND is_active = 1
-		`, req.ConnectionID, userID).Scan(&count)
-		if err != nil {
-			respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		`, req.ConnectionID, userID).Scan(&exchangeType)
+		if err == sql.ErrNoRows {
+			respondError(c, "INVALID_CONNECTION", "invalid connection for current user")
 			return
 		}
-		if count == 0 {
-			respondError(c, http.StatusBadRequest, "INVALID_CONNECTION", "invalid connection for current user")
+		if err != nil {
+			respondError(c, "DB_ERROR", err.Error())
 			return
 		}
 	}
+	if !s.strategySymbolAllowed(c, userID, symbol, exchangeType) {
+		return
+	}
 
 	// Bind strategy to user + connection (user_id is set if empty).
 	_, err = s.DB.DB.Exec(`
@@ -813,7 +2305,7 @@ func (s *Server) updateStrategyBinding(c *gin.Context) {
 		WHERE id = ?
 	`, userID, req.ConnectionID, id)
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 
@@ -827,8 +2319,11 @@ func (s *Server) startStrategy(c *gin.Context) {
 	if !s.canAccessStrategy(c, id) {
 		return
 	}
+	if s.rejectIfFrozen(c) {
+		return
+	}
 	if err := s.Engine.StartStrategy(c.Request.Context(), id); err != nil {
-		respondError(c, http.StatusInternalServerError, "ENGINE_ERROR", err.Error())
+		respondError(c, "ENGINE_ERROR", err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "started"})
@@ -840,7 +2335,7 @@ func (s *Server) pauseStrategy(c *gin.Context) {
 		return
 	}
 	if err := s.Engine.PauseStrategy(c.Request.Context(), id); err != nil {
-		respondError(c, http.StatusInternalServerError, "ENGINE_ERROR", err.Error())
+		respondError(c, "ENGINE_ERROR", err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "paused"})
@@ -852,7 +2347,7 @@ func (s *Server) stopStrategy(c *gin.Context) {
 		return
 	}
 	if err := s.Engine.StopStrategy(c.Request.Context(), id); err != nil {
-		respondError(c, http.StatusInternalServerError, "ENGINE_ERROR", err.Error())
+		respondError(c, "ENGINE_ERROR", err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "stopped"})
@@ -866,7 +2361,7 @@ func (s *Server) panicSellStrategy(c *gin.Context) {
 
 	userID := CurrentUserID(c)
 	if err := s.Engine.PanicSellStrategy(c.Request.Context(), id, userID); err != nil {
-		respondError(c, http.StatusInternalServerError, "ENGINE_ERROR", err.Error())
+		respondError(c, "ENGINE_ERROR", err.Error())
 		return
 	}
 
@@ -880,26 +2375,26 @@ func (s *Server) updateStrategyParams(c *gin.Context) {
 	}
 	var params map[string]any
 	if err := c.ShouldBindJSON(&params); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_REQUEST", "invalid request payload")
+		respondError(c, "INVALID_REQUEST", "invalid request payload")
 		return
 	}
 
 	var strategyType string
 	if err := s.DB.DB.QueryRow(`SELECT strategy_type FROM strategy_instances WHERE id = ?`, id).Scan(&strategyType); err != nil {
 		if err == sql.ErrNoRows {
-			respondError(c, http.StatusNotFound, "STRATEGY_NOT_FOUND", "strategy not found")
+			respondError(c, "STRATEGY_NOT_FOUND", "strategy not found")
 			return
 		}
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return
 	}
 	if err := validateStrategyParams(strategyType, params); err != nil {
-		respondError(c, http.StatusBadRequest, "INVALID_PARAMETERS", err.Error())
+		respondError(c, "INVALID_PARAMETERS", err.Error())
 		return
 	}
 
 	if err := s.Engine.UpdateStrategyParams(c.Request.Context(), id, params); err != nil {
-		respondError(c, http.StatusInternalServerError, "ENGINE_ERROR", err.Error())
+		respondError(c, "ENGINE_ERROR", err.Error())
 		return
 	}
 
@@ -911,24 +2406,70 @@ func (s *Server) updateStrategyParams(c *gin.Context) {
 func (s *Server) canAccessStrategy(c *gin.Context, strategyID string) bool {
 	userID := CurrentUserID(c)
 	if userID == "" {
-		respondError(c, http.StatusUnauthorized, "UNAUTHENTICATED", "unauthorized")
+		respondError(c, "UNAUTHENTICATED", "unauthorized")
 		return false
 	}
 
 	var owner sql.NullString
 	err := s.DB.DB.QueryRow(`SELECT user_id FROM strategy_instances WHERE id = ?`, strategyID).Scan(&owner)
 	if err == sql.ErrNoRows {
-		respondError(c, http.StatusNotFound, "STRATEGY_NOT_FOUND", "strategy not found")
+		respondError(c, "STRATEGY_NOT_FOUND", "strategy not found")
 		return false
 	}
 	if err != nil {
-		respondError(c, http.StatusInternalServerError, "DB_ERROR", err.Error())
+		respondError(c, "DB_ERROR", err.Error())
 		return false
 	}
 
 	// Allow if strategy is unowned (user_id NULL) or belongs to current user.
 	if owner.Valid && owner.String != userID {
-		respondError(c, http.StatusForbidden, "FORBIDDEN", "strategy does not belong to current user")
+		respondError(c, "FORBIDDEN", "strategy does not belong to current user")
+		return false
+	}
+	return true
+}
+
+// withinResourceLimit checks the per-user cap for "strategies" or "connections".
+// Admin overrides on the user row take precedence over Server.Limits; a negative
+// override lifts the cap. It writes a 429 response and returns false at the limit.
+func (s *Server) withinResourceLimit(c *gin.Context, userID, resource string) bool {
+	ctx := c.Request.Context()
+	override, err := s.DB.Queries().GetUserLimits(ctx, userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return false
+	}
+
+	var (
+		max   int
+		count func(context.Context, string) (int, error)
+	)
+	switch resource {
+	case "strategies":
+		max, count = s.Limits.MaxStrategies, s.DB.Queries().CountStrategiesByUser
+		if override.MaxStrategies != 0 {
+			max = override.MaxStrategies
+		}
+	case "connections":
+		max, count = s.Limits.MaxConnections, s.DB.Queries().CountActiveConnectionsByUser
+		if override.MaxConnections != 0 {
+			max = override.MaxConnections
+		}
+	default:
+		return true
+	}
+	if max <= 0 {
+		return true
+	}
+
+	n, err := count(ctx, userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return false
+	}
+	if n >= max {
+		respondError(c, "LIMIT_EXCEEDED",
+			fmt.Sprintf("%s limit reached (%d); remove unused %s or ask an administrator to raise the limit", resource, max, resource))
 		return false
 	}
 	return true
@@ -937,7 +2478,7 @@ func (s *Server) canAccessStrategy(c *gin.Context, strategyID string) bool {
 // getMetrics returns system performance metrics.
 func (s *Server) getMetrics(c *gin.Context) {
 	if s.Metrics == nil {
-		respondError(c, http.StatusServiceUnavailable, "METRICS_UNAVAILABLE", "metrics not available")
+		respondError(c, "METRICS_UNAVAILABLE", "metrics not available")
 		return
 	}
 	snapshot := s.Metrics.GetSnapshot()
@@ -985,6 +2526,13 @@ func (s *Server) getPromMetrics(c *gin.Context) {
 	for exType, count := range snapshot.GatewayPool.ByExchangeType {
 		fmt.Fprintf(&b, "des_gateway_by_exchange{type=\"%s\"} %d\n", exType, count)
 	}
+	for userID, count := range snapshot.GatewayPool.ByUser {
+		fmt.Fprintf(&b, "des_gateway_by_user{user_id=\"%s\"} %d\n", userID, count)
+	}
+	fmt.Fprintf(&b, "des_gateway_evictions_total %d\n", snapshot.GatewayPool.Evictions)
+	fmt.Fprintf(&b, "des_market_stream_failures_total %d\n", snapshot.StreamFailures)
+	fmt.Fprintf(&b, "des_market_parse_anomalies_total %d\n", snapshot.ParseAnomalies)
+	fmt.Fprintf(&b, "des_gateway_circuit_trips_total %d\n", snapshot.GatewayPool.CircuitTrips)
 	fmt.Fprintf(&b, "des_risk_active_users %d\n", snapshot.RiskActiveUsers)
 	fmt.Fprintf(&b, "des_balance_active_users %d\n", snapshot.BalanceActiveUsers)
 	fmt.Fprintf(&b, "des_goroutines %d\n", snapshot.GoroutineCount)
@@ -998,7 +2546,7 @@ func (s *Server) getPromMetrics(c *gin.Context) {
 // getQueueMetrics returns order queue statistics.
 func (s *Server) getQueueMetrics(c *gin.Context) {
 	if s.OrderQueue == nil {
-		respondError(c, http.StatusServiceUnavailable, "QUEUE_UNAVAILABLE", "order queue not available")
+		respondError(c, "QUEUE_UNAVAILABLE", "order queue not available")
 		return
 	}
 
@@ -1026,3 +2574,46 @@ func (s *Server) getQueueMetrics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// connectionMarket maps a connection's exchange type to its market.
+func connectionMarket(exchangeType string) (exchange.MarketType, bool) {
+	switch exchangeType {
+	case "binance-spot":
+		return exchange.MarketSpot, true
+	case "binance-usdtfut":
+		return exchange.MarketUSDTFut, true
+	case "binance-coinfut":
+		return exchange.MarketCoinFut, true
+	}
+	return "", false
+}
+
+// strategySymbolAllowed responds with an error and returns false unless the
+// user may run strategies on symbol (users.allowed_symbols) and, when the
+// strategy is bound to a connection of exchangeType, the symbol trades on
+// that market. The engine refuses such strategies at load; this rejects
+// them before they are stored.
+func (s *Server) strategySymbolAllowed(c *gin.Context, userID, symbol, exchangeType string) bool {
+	limits, err := s.DB.Queries().GetUserLimits(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, "DB_ERROR", err.Error())
+		return false
+	}
+	if !limits.AllowsSymbol(symbol) {
+		respondError(c, "SYMBOL_NOT_ALLOWED", fmt.Sprintf("symbol %s is not in your allowed symbols", strings.ToUpper(symbol)))
+		return false
+	}
+	if exchangeType == "" {
+		return true
+	}
+	market, ok := connectionMarket(exchangeType)
+	if !ok {
+		respondError(c, "UNSUPPORTED_EXCHANGE", "unsupported exchange type")
+		return false
+	}
+	if err := exchange.ValidateMarketSymbol(market, symbol); err != nil {
+		respondError(c, "SYMBOL_NOT_ON_MARKET", fmt.Sprintf("symbol %s is not tradable on this %s connection", strings.ToUpper(symbol), exchangeType))
+		return false
+	}
+	return true
+}
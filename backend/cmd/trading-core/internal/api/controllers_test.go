@@ -4,20 +4,40 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"trading-core/internal/balance"
 	"trading-core/internal/engine"
 	"trading-core/internal/events"
+	"trading-core/internal/gateway"
+	"trading-core/internal/market"
 	"trading-core/internal/monitor"
 	"trading-core/internal/order"
+	"trading-core/internal/risk"
+	"trading-core/internal/strategy"
 	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+	binancemarket "trading-core/pkg/market/binance"
 )
 
 type noopEngine struct{}
@@ -67,9 +87,23 @@ func (testKeyManager) Decrypt(ciphertext string) (string, error) {
 func (testKeyManager) CurrentVersion() int { return 1 }
 
 func newTestAPIServer(t *testing.T) (*httptest.Server, func()) {
+	t.Helper()
+	ts, _, cleanup := newTestAPIServerWithDB(t)
+	return ts, cleanup
+}
+
+// newTestAPIServerWithDB is like newTestAPIServer but also exposes the backing database.
+// Optional opts can adjust the Server before it starts serving.
+func newTestAPIServerWithDB(t *testing.T, opts ...func(*Server)) (*httptest.Server, *db.Database, func()) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 
+	// All test servers are reached from 127.0.0.1; give each test a fresh
+	// per-IP rate limit budget instead of sharing one across the package.
+	mu.Lock()
+	clear(ipLimiters)
+	mu.Unlock()
+
 	database, err := db.New(":memory:")
 	if err != nil {
 		t.Fatalf("db.New: %v", err)
@@ -107,13 +141,16 @@ func newTestAPIServer(t *testing.T) (*httptest.Server, func()) {
 		balances,
 	)
 
+	for _, opt := range opts {
+		opt(server)
+	}
 	httpServer := httptest.NewServer(server.Router)
 
 	cleanup := func() {
 		httpServer.Close()
 		_ = database.Close()
 	}
-	return httpServer, cleanup
+	return httpServer, database, cleanup
 }
 
 func doJSONRequest(t *testing.T, client *http.Client, method, url, token string, payload any, out any) int {
@@ -150,13 +187,19 @@ func doJSONRequest(t *testing.T, client *http.Client, method, url, token string,
 }
 
 func registerAndLogin(t *testing.T, client *http.Client, baseURL string) string {
+	t.Helper()
+	return registerAndLoginAs(t, client, baseURL, "tester@example.com")
+}
+
+// registerAndLoginAs registers a user with the given email and returns its token.
+func registerAndLoginAs(t *testing.T, client *http.Client, baseURL, email string) string {
 	t.Helper()
 	var regResp struct {
 		UserID string `json:"user_id"`
 	}
 	status := doJSONRequest(t, client, http.MethodPost, baseURL+"/api/v1/auth/register", "", map[string]string{
-		"username": "tester",
-		"email":    "tester@example.com",
+		"username": strings.SplitN(email, "@", 2)[0],
+		"email":    email,
 		"password": "StrongPass123!",
 	}, &regResp)
 	if status != http.StatusCreated {
@@ -167,7 +210,7 @@ func registerAndLogin(t *testing.T, client *http.Client, baseURL string) string
 		Token string `json:"token"`
 	}
 	status = doJSONRequest(t, client, http.MethodPost, baseURL+"/api/v1/auth/login", "", map[string]string{
-		"email":    "tester@example.com",
+		"email":    email,
 		"password": "StrongPass123!",
 	}, &loginResp)
 	if status != http.StatusOK || loginResp.Token == "" {
@@ -306,29 +349,2422 @@ func TestCreateAndListOrders(t *testing.T) {
 	}
 }
 
-func TestStrategyParamsValidation_RSI(t *testing.T) {
+func TestCreateStopMarketOrder(t *testing.T) {
 	ts, cleanup := newTestAPIServer(t)
 	defer cleanup()
 
 	client := ts.Client()
 	token := registerAndLogin(t, client, ts.URL)
 
-	var resp struct {
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Futures",
+		"exchange_type": "binance-usdtfut",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	// Missing stop_price must be rejected.
+	var errResp struct {
 		Code string `json:"code"`
 	}
-	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, map[string]any{
-		"name":          "bad rsi",
-		"strategy_type": "rsi",
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
 		"symbol":        "BTCUSDT",
-		"interval":      "1m",
-		"parameters": map[string]any{
-			"period":     0,   // invalid
-			"oversold":   30,  // ok
-			"overbought": 70,  // ok
-			"size":       0.1, // ok
-		},
-	}, &resp)
-	if status != http.StatusBadRequest || resp.Code != "INVALID_PARAMETERS" {
-		t.Fatalf("expected invalid parameters, got status=%d code=%s", status, resp.Code)
+		"side":          "SELL",
+		"type":          "STOP_MARKET",
+		"qty":           0.01,
+		"connection_id": connResp.ID,
+	}, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "INVALID_STOP_PRICE" {
+		t.Fatalf("expected INVALID_STOP_PRICE, got status=%d resp=%+v", status, errResp)
+	}
+
+	var createResp struct {
+		ID        string  `json:"id"`
+		Type      string  `json:"type"`
+		StopPrice float64 `json:"stop_price"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "SELL",
+		"type":          "STOP_MARKET",
+		"stop_price":    9500.0,
+		"qty":           0.01,
+		"connection_id": connResp.ID,
+	}, &createResp)
+	if status != http.StatusAccepted || createResp.ID == "" {
+		t.Fatalf("create stop order failed status=%d resp=%+v", status, createResp)
+	}
+	if createResp.Type != "STOP_MARKET" || createResp.StopPrice != 9500.0 {
+		t.Fatalf("unexpected stop order response: %+v", createResp)
+	}
+}
+
+func TestCreateOrderRejectsSpotSymbolOnCoinFuturesConnection(t *testing.T) {
+	ts, cleanup := newTestAPIServer(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Coin-M",
+		"exchange_type": "binance-coinfut",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	var errResp struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "ETHBTC",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         0.05,
+		"qty":           1,
+		"connection_id": connResp.ID,
+	}, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "SYMBOL_NOT_ON_MARKET" {
+		t.Fatalf("expected SYMBOL_NOT_ON_MARKET, got status=%d resp=%+v", status, errResp)
+	}
+
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "BTCUSD_PERP",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         50000,
+		"qty":           0.01,
+		"connection_id": connResp.ID,
+	}, &errResp)
+	if status != http.StatusAccepted {
+		t.Fatalf("coin-M contract should be accepted, got status=%d resp=%+v", status, errResp)
+	}
+}
+
+func TestStrategySymbolValidatedOnCreateAndBinding(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if err := database.Queries().SetUserLimits(context.Background(), user.ID, db.UserLimits{AllowedSymbols: []string{"BTCUSDT"}}); err != nil {
+		t.Fatalf("SetUserLimits: %v", err)
+	}
+
+	strategy := func(symbol string) map[string]any {
+		return map[string]any{
+			"name": "MA " + symbol, "strategy_type": "ma_cross", "symbol": symbol, "interval": "1m",
+			"parameters": map[string]any{"fast": 5, "slow": 20},
+		}
+	}
+	var errResp errorResponse
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, strategy("ETHUSDT"), &errResp); status != http.StatusForbidden || errResp.Code != "SYMBOL_NOT_ALLOWED" {
+		t.Fatalf("create off the allow-list: status=%d resp=%+v", status, errResp)
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, strategy("btcusdt"), &created); status != http.StatusCreated || created.ID == "" {
+		t.Fatalf("create allowed symbol: status=%d resp=%+v", status, created)
+	}
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name": "Coin-M", "exchange_type": "binance-coinfut", "api_key": "k", "api_secret": "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+	status = doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/strategies/"+created.ID+"/binding", token, map[string]any{"connection_id": connResp.ID}, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "SYMBOL_NOT_ON_MARKET" {
+		t.Fatalf("bind spot symbol to coin-M: status=%d resp=%+v", status, errResp)
+	}
+}
+
+type recordingGateway struct{ reqs []exchange.OrderRequest }
+
+func (g *recordingGateway) SubmitOrder(_ context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	g.reqs = append(g.reqs, req)
+	return exchange.OrderResult{ExchangeOrderID: "ex-1", Status: exchange.StatusNew}, nil
+}
+func (g *recordingGateway) CancelOrder(context.Context, string, string) error { return nil }
+
+// executingQueue runs each enqueued order through the executor synchronously.
+type executingQueue struct {
+	noopQueue
+	exec *order.Executor
+}
+
+func (q executingQueue) Enqueue(o order.Order) bool {
+	return q.exec.Handle(context.Background(), o) == nil
+}
+
+func TestCreateIOCLimitOrderReachesGateway(t *testing.T) {
+	gw := &recordingGateway{}
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		exec := order.NewExecutor(s.DB, nil, nil, "test", false)
+		exec.SetGatewayPool(stubGatewayPool{gw: gw})
+		s.OrderQueue = executingQueue{exec: exec}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	// MARKET orders take no time in force, and spot has no GTX.
+	for _, tc := range []struct{ typ, tif string }{{"MARKET", "IOC"}, {"LIMIT", "GTX"}, {"LIMIT", "DAY"}} {
+		var errResp struct {
+			Code string `json:"code"`
+		}
+		status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+			"symbol":        "BTCUSDT",
+			"side":          "BUY",
+			"type":          tc.typ,
+			"price":         10000.0,
+			"qty":           0.01,
+			"time_in_force": tc.tif,
+			"connection_id": connResp.ID,
+		}, &errResp)
+		if status != http.StatusBadRequest || errResp.Code != "INVALID_TIME_IN_FORCE" {
+			t.Fatalf("%s/%s: expected INVALID_TIME_IN_FORCE, got status=%d resp=%+v", tc.typ, tc.tif, status, errResp)
+		}
+	}
+	if len(gw.reqs) != 0 {
+		t.Fatalf("rejected orders must not reach the gateway, got %d", len(gw.reqs))
+	}
+
+	var createResp struct {
+		ID          string `json:"id"`
+		TimeInForce string `json:"time_in_force"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         10000.0,
+		"qty":           0.01,
+		"time_in_force": "ioc",
+		"connection_id": connResp.ID,
+	}, &createResp)
+	if status != http.StatusAccepted || createResp.TimeInForce != "IOC" {
+		t.Fatalf("create IOC order failed status=%d resp=%+v", status, createResp)
+	}
+	if len(gw.reqs) != 1 {
+		t.Fatalf("expected one gateway submission, got %d", len(gw.reqs))
+	}
+	if req := gw.reqs[0]; req.TimeInForce != exchange.TIFIOC || req.Type != exchange.OrderTypeLimit || req.Price != 10000.0 {
+		t.Fatalf("unexpected gateway request: %+v", req)
+	}
+}
+
+func TestOrderFlowEmitsTraceSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	gw := &recordingGateway{}
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risk.NewMultiUserManager(nil)
+		exec := order.NewExecutor(s.DB, nil, nil, "test", false)
+		exec.SetGatewayPool(stubGatewayPool{gw: gw})
+		s.OrderQueue = executingQueue{exec: exec}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	exporter.Reset()
+
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         30000.0,
+		"qty":           0.01,
+		"connection_id": connResp.ID,
+	}, nil)
+	if status != http.StatusAccepted || len(gw.reqs) != 1 {
+		t.Fatalf("order not executed: status=%d submissions=%d", status, len(gw.reqs))
+	}
+
+	// The executor runs off the queue with a fresh context; its spans must
+	// still join the request's trace.
+	byName := map[string]tracetest.SpanStub{}
+	for _, sp := range exporter.GetSpans() {
+		byName[sp.Name] = sp
+	}
+	root, ok := byName["POST /api/v1/orders"]
+	if !ok {
+		t.Fatalf("no request span; got %v", spanNames(exporter.GetSpans()))
+	}
+	for _, name := range []string{"risk.evaluate", "order.enqueue", "order.execute", "exchange.submit_order", "db.persist_order"} {
+		sp, ok := byName[name]
+		if !ok {
+			t.Fatalf("missing span %q; got %v", name, spanNames(exporter.GetSpans()))
+		}
+		if sp.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("span %q is not part of the request trace", name)
+		}
+	}
+	if byName["exchange.submit_order"].Parent.SpanID() != byName["order.execute"].SpanContext.SpanID() {
+		t.Error("exchange.submit_order should be a child of order.execute")
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, sp := range spans {
+		names = append(names, sp.Name)
+	}
+	return names
+}
+
+func TestSimulateOrderAdjustsSizeToPositionLimit(t *testing.T) {
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risk.NewMultiUserManager(nil)
+		s.SimConfig = order.DryRunSimConfig{FeeRate: 0.001}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	// 0.1 BTC @ 30000 = 3000 notional, above the default 1000 per-position limit.
+	var simResp struct {
+		Decision     risk.RiskDecision `json:"decision"`
+		RequestedQty float64           `json:"requested_qty"`
+		Qty          float64           `json:"qty"`
+		Fill         struct {
+			Price float64 `json:"price"`
+			Fee   float64 `json:"fee"`
+		} `json:"fill"`
+		PostTrade struct {
+			AvailableBalance float64 `json:"available_balance"`
+			PositionQty      float64 `json:"position_qty"`
+		} `json:"post_trade"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders/simulate", token, map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         30000.0,
+		"qty":           0.1,
+		"connection_id": connResp.ID,
+	}, &simResp)
+	if status != http.StatusOK || !simResp.Decision.Allowed {
+		t.Fatalf("simulate failed status=%d resp=%+v", status, simResp)
+	}
+	wantQty := 1000.0 / 30000.0
+	if simResp.RequestedQty != 0.1 || math.Abs(simResp.Qty-wantQty) > 1e-9 || math.Abs(simResp.Decision.AdjustedSize-wantQty) > 1e-9 {
+		t.Fatalf("expected size adjusted to %.6f, got %+v", wantQty, simResp)
+	}
+	if simResp.Fill.Price != 30000 || math.Abs(simResp.Fill.Fee-1) > 1e-9 {
+		t.Fatalf("unexpected fill: %+v", simResp.Fill)
+	}
+	if math.Abs(simResp.PostTrade.AvailableBalance-(10000-1000-1)) > 1e-9 || math.Abs(simResp.PostTrade.PositionQty-wantQty) > 1e-9 {
+		t.Fatalf("unexpected post-trade state: %+v", simResp.PostTrade)
+	}
+
+	var orders []map[string]any
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/orders", token, nil, &orders); status != http.StatusOK || len(orders) != 0 {
+		t.Fatalf("simulation must not persist orders, got status=%d orders=%v", status, orders)
+	}
+}
+
+func TestCreateOrderRejectsOversizedManualOrder(t *testing.T) {
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risk.NewMultiUserManager(nil)
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	// 1000 BTC @ 30000 is far above the default 10000 max order notional.
+	var errResp struct {
+		Code string `json:"code"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         30000.0,
+		"qty":           1000.0,
+		"connection_id": connResp.ID,
+	}, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "ORDER_SIZE_LIMIT" {
+		t.Fatalf("expected ORDER_SIZE_LIMIT, got status=%d resp=%+v", status, errResp)
+	}
+
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         30000.0,
+		"qty":           0.01,
+		"connection_id": connResp.ID,
+	}, nil)
+	if status != http.StatusAccepted {
+		t.Fatalf("order within caps should be accepted, got status=%d", status)
+	}
+}
+
+func TestCreateOrderRejectedAfterDailyLossLimit(t *testing.T) {
+	risks := risk.NewMultiUserManager(nil)
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risks
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	payload := map[string]any{
+		"symbol":        "BTCUSDT",
+		"side":          "BUY",
+		"type":          "LIMIT",
+		"price":         30000.0,
+		"qty":           0.01,
+		"connection_id": connResp.ID,
+	}
+	var okResp struct {
+		Risk *risk.RiskDecision `json:"risk"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, payload, &okResp)
+	if status != http.StatusAccepted || okResp.Risk == nil || !okResp.Risk.Allowed {
+		t.Fatalf("expected risk-approved order, got status=%d resp=%+v", status, okResp)
+	}
+
+	// Default daily loss limit is 2000.
+	if err := risks.UpdateMetricsForUser(context.Background(), user.ID, risk.TradeResult{Symbol: "BTCUSDT", PnL: -2500}); err != nil {
+		t.Fatalf("UpdateMetricsForUser: %v", err)
+	}
+
+	var errResp struct {
+		Code    string `json:"code"`
+		Message string `json:"error"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, payload, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "RISK_REJECTED" {
+		t.Fatalf("expected RISK_REJECTED, got status=%d resp=%+v", status, errResp)
+	}
+	if !strings.Contains(errResp.Message, "daily loss") {
+		t.Fatalf("expected daily loss reason, got %q", errResp.Message)
+	}
+}
+
+// Manual orders are held to the user's concurrent-position cap just like
+// strategy signals.
+func TestCreateOrderRespectsUserPositionCap(t *testing.T) {
+	risks := risk.NewMultiUserManager(nil)
+	risks.SetPositionCap(func(string) (int, error) { return 1, nil })
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risks
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if err := database.Queries().UpsertPositionWithUser(context.Background(), user.ID, "BTCUSDT", 0.01, 30000); err != nil {
+		t.Fatalf("UpsertPositionWithUser: %v", err)
+	}
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+	order := func(symbol string, price float64, out any) int {
+		return doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+			"symbol":        symbol,
+			"side":          "BUY",
+			"type":          "LIMIT",
+			"price":         price,
+			"qty":           0.01,
+			"connection_id": connResp.ID,
+		}, out)
+	}
+
+	var errResp struct {
+		Code    string `json:"code"`
+		Message string `json:"error"`
+	}
+	if status := order("ETHUSDT", 2000, &errResp); status != http.StatusBadRequest || errResp.Code != "RISK_REJECTED" {
+		t.Fatalf("expected RISK_REJECTED for a second position, got status=%d resp=%+v", status, errResp)
+	}
+	if !strings.Contains(errResp.Message, "concurrent positions") {
+		t.Fatalf("expected concurrent position reason, got %q", errResp.Message)
+	}
+	if status := order("BTCUSDT", 30000, nil); status != http.StatusAccepted {
+		t.Fatalf("adding to the held symbol should be accepted, got status=%d", status)
+	}
+}
+
+func TestStrategyParamsValidation_RSI(t *testing.T) {
+	ts, cleanup := newTestAPIServer(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var resp struct {
+		Code string `json:"code"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, map[string]any{
+		"name":          "bad rsi",
+		"strategy_type": "rsi",
+		"symbol":        "BTCUSDT",
+		"interval":      "1m",
+		"parameters": map[string]any{
+			"period":     0,   // invalid
+			"oversold":   30,  // ok
+			"overbought": 70,  // ok
+			"size":       0.1, // ok
+		},
+	}, &resp)
+	if status != http.StatusBadRequest || resp.Code != "INVALID_PARAMETERS" {
+		t.Fatalf("expected invalid parameters, got status=%d code=%s", status, resp.Code)
+	}
+
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, map[string]any{
+		"name":          "bad source",
+		"strategy_type": "rsi",
+		"symbol":        "BTCUSDT",
+		"interval":      "1m",
+		"parameters": map[string]any{
+			"period":       14,
+			"oversold":     30,
+			"overbought":   70,
+			"price_source": "lastTrade", // invalid
+		},
+	}, &resp)
+	if status != http.StatusBadRequest || resp.Code != "INVALID_PARAMETERS" {
+		t.Fatalf("expected an unknown price_source to be rejected, got status=%d code=%s", status, resp.Code)
+	}
+}
+
+func TestEquityCurveReturnsOrderedSnapshots(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	// Insert out of order to make sure the endpoint sorts by time.
+	for _, offset := range []int{2, 0, 1} {
+		snap := db.EquitySnapshot{
+			UserID:    user.ID,
+			Balance:   10000,
+			Equity:    10000 + float64(offset)*100,
+			CreatedAt: base.Add(time.Duration(offset) * time.Hour),
+		}
+		if err := database.Queries().CreateEquitySnapshot(context.Background(), snap); err != nil {
+			t.Fatalf("CreateEquitySnapshot: %v", err)
+		}
+	}
+	// Another user's snapshot must not leak into the response.
+	if err := database.Queries().CreateEquitySnapshot(context.Background(), db.EquitySnapshot{
+		UserID: "other-user", Equity: 1, CreatedAt: base,
+	}); err != nil {
+		t.Fatalf("CreateEquitySnapshot other: %v", err)
+	}
+
+	var resp struct {
+		Points []struct {
+			Time   time.Time `json:"time"`
+			Equity float64   `json:"equity"`
+		} `json:"points"`
+	}
+	status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/equity?from=2025-01-10&to=2025-01-10", token, nil, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(resp.Points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(resp.Points))
+	}
+	for i, want := range []float64{10000, 10100, 10200} {
+		if resp.Points[i].Equity != want {
+			t.Fatalf("point %d: expected equity %.0f, got %.0f", i, want, resp.Points[i].Equity)
+		}
+		if i > 0 && !resp.Points[i].Time.After(resp.Points[i-1].Time) {
+			t.Fatalf("points not ordered by time: %+v", resp.Points)
+		}
+	}
+
+	var errResp struct {
+		Code string `json:"code"`
+	}
+	status = doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/equity?from=yesterday", token, nil, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "INVALID_FROM_DATE" {
+		t.Fatalf("expected 400 INVALID_FROM_DATE, got %d %s", status, errResp.Code)
+	}
+}
+
+func TestPnLAttributionSplitsFeesAndFunding(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	ctx := context.Background()
+
+	user, err := database.GetUserByEmail(ctx, "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	var createResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, map[string]any{
+		"name":          "MA Cross BTC",
+		"strategy_type": "ma_cross",
+		"symbol":        "BTCUSDT",
+		"interval":      "1m",
+		"parameters":    map[string]any{"fast": 5, "slow": 20},
+	}, &createResp)
+	if status != http.StatusCreated || createResp.ID == "" {
+		t.Fatalf("create strategy status=%d resp=%+v", status, createResp)
+	}
+
+	base := time.Date(2025, 1, 10, 1, 0, 0, 0, time.UTC)
+	for i, f := range []struct {
+		side  string
+		price float64
+		fee   float64
+	}{{"BUY", 100, 0.4}, {"SELL", 130, 0.6}} {
+		id := fmt.Sprintf("pnl-%d", i)
+		at := base.Add(time.Duration(i) * time.Hour)
+		if err := database.CreateOrder(ctx, db.Order{
+			ID: id, StrategyInstanceID: createResp.ID, UserID: user.ID, Symbol: "BTCUSDT", Side: f.side,
+			Price: f.price, Qty: 1, FilledQty: 1, Status: "FILLED", CreatedAt: at,
+		}); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		if err := database.CreateTrade(ctx, db.Trade{
+			ID: "t-" + id, OrderID: id, UserID: user.ID, Symbol: "BTCUSDT", Side: f.side,
+			Price: f.price, Qty: 1, Fee: f.fee, CreatedAt: at,
+		}); err != nil {
+			t.Fatalf("CreateTrade: %v", err)
+		}
+	}
+	if _, err := database.CreateIncomeRecord(ctx, db.IncomeRecord{
+		ID: "fund-1", SourceID: "fund-1", UserID: user.ID, StrategyInstanceID: createResp.ID,
+		Symbol: "BTCUSDT", IncomeType: db.IncomeFundingFee, Amount: -2.5, Asset: "USDT", CreatedAt: base.Add(30 * time.Minute),
+	}); err != nil {
+		t.Fatalf("CreateIncomeRecord: %v", err)
+	}
+
+	var resp pnlAttributionResponse
+	status = doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/pnl?from=2025-01-10&to=2025-01-10", token, nil, &resp)
+	if status != http.StatusOK || len(resp.Strategies) != 1 {
+		t.Fatalf("pnl status=%d resp=%+v", status, resp)
+	}
+	want := pnlAttribution{StrategyID: createResp.ID, Gross: 30, Fees: 1, Funding: 2.5, Net: 26.5, Trades: 2}
+	if resp.Strategies[0] != want {
+		t.Fatalf("strategy breakdown = %+v, want %+v", resp.Strategies[0], want)
+	}
+	if resp.Total.Net != resp.Total.Gross-resp.Total.Fees-resp.Total.Funding || resp.Total.Net != 26.5 {
+		t.Fatalf("total = %+v, want net 26.5 = gross - fees - funding", resp.Total)
+	}
+
+	var strategyResp pnlAttributionResponse
+	status = doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/strategies/"+createResp.ID+"/pnl?from=2025-01-11", token, nil, &strategyResp)
+	if status != http.StatusOK || len(strategyResp.Strategies) != 0 || strategyResp.Total.Net != 0 {
+		t.Fatalf("out of range: status=%d resp=%+v", status, strategyResp)
+	}
+}
+
+func TestStrategyLimitPerUser(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if err := database.Queries().SetUserLimits(context.Background(), user.ID, db.UserLimits{MaxStrategies: 1}); err != nil {
+		t.Fatalf("SetUserLimits: %v", err)
+	}
+
+	payload := map[string]any{
+		"name":          "MA Cross BTC",
+		"strategy_type": "ma_cross",
+		"symbol":        "BTCUSDT",
+		"interval":      "1m",
+		"parameters":    map[string]any{"fast": 5, "slow": 20},
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, payload, nil); status != http.StatusCreated {
+		t.Fatalf("first strategy: expected 201, got %d", status)
+	}
+
+	var errResp struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, payload, &errResp)
+	if status != http.StatusTooManyRequests || errResp.Code != "LIMIT_EXCEEDED" {
+		t.Fatalf("expected 429 LIMIT_EXCEEDED, got %d %+v", status, errResp)
+	}
+}
+
+func TestConnectionLimitPerUser(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if err := database.Queries().SetUserLimits(context.Background(), user.ID, db.UserLimits{MaxConnections: 2}); err != nil {
+		t.Fatalf("SetUserLimits: %v", err)
+	}
+
+	payload := map[string]any{
+		"name":          "Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}
+	for i := 0; i < 2; i++ {
+		if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, payload, nil); status != http.StatusCreated {
+			t.Fatalf("connection %d: expected 201, got %d", i, status)
+		}
+	}
+
+	var errResp struct {
+		Code string `json:"code"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, payload, &errResp)
+	if status != http.StatusTooManyRequests || errResp.Code != "LIMIT_EXCEEDED" {
+		t.Fatalf("expected 429 LIMIT_EXCEEDED, got %d %+v", status, errResp)
+	}
+}
+
+func TestOrderReportAggregatesFills(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	ctx := context.Background()
+	user, err := database.GetUserByEmail(ctx, "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	// Market buy valued at 100 on submission, filled in two parts.
+	if err := database.Queries().CreateOrderWithUser(ctx, db.Order{
+		ID: "ord-1", Symbol: "BTCUSDT", Side: "BUY", Qty: 2, FilledQty: 2,
+		Status: db.OrderStatusFilled, RefPrice: 100, UserID: user.ID,
+	}); err != nil {
+		t.Fatalf("CreateOrderWithUser: %v", err)
+	}
+	base := time.Now().Add(-time.Minute)
+	for i, f := range []struct{ price, qty, fee float64 }{{100.5, 1.5, 0.15}, {102, 0.5, 0.05}} {
+		if err := database.Queries().CreateTradeWithUser(ctx, db.Trade{
+			ID: fmt.Sprintf("fill-%d", i), OrderID: "ord-1", Symbol: "BTCUSDT", Side: "BUY",
+			Price: f.price, Qty: f.qty, Fee: f.fee, UserID: user.ID, CreatedAt: base.Add(time.Duration(i) * time.Second),
+		}); err != nil {
+			t.Fatalf("CreateTradeWithUser: %v", err)
+		}
+	}
+
+	var report struct {
+		Status       string   `json:"status"`
+		FilledQty    float64  `json:"filled_qty"`
+		AvgFillPrice float64  `json:"avg_fill_price"`
+		TotalFee     float64  `json:"total_fee"`
+		SlippageBps  *float64 `json:"slippage_bps"`
+		Fills        []struct {
+			Price float64 `json:"price"`
+			Qty   float64 `json:"qty"`
+		} `json:"fills"`
+	}
+	status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/orders/ord-1", token, nil, &report)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(report.Fills) != 2 || report.Fills[0].Price != 100.5 || report.Fills[1].Qty != 0.5 {
+		t.Fatalf("unexpected fills: %+v", report.Fills)
+	}
+	// (100.5*1.5 + 102*0.5) / 2 = 100.875; 87.5 bps worse than the 100 reference.
+	if report.AvgFillPrice != 100.875 || math.Abs(report.TotalFee-0.2) > 1e-9 || report.FilledQty != 2 {
+		t.Fatalf("unexpected aggregates: %+v", report)
+	}
+	if report.SlippageBps == nil || math.Abs(*report.SlippageBps-87.5) > 1e-9 {
+		t.Fatalf("expected 87.5 bps slippage, got %v", report.SlippageBps)
+	}
+	if report.Status != db.OrderStatusFilled {
+		t.Fatalf("expected FILLED, got %s", report.Status)
+	}
+
+	// Another user's token must not see the order.
+	otherToken := registerAndLoginAs(t, client, ts.URL, "other@example.com")
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/orders/ord-1", otherToken, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's order, got %d", status)
+	}
+}
+
+func TestOrderJournalNoteAndTags(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		exec := order.NewExecutor(s.DB, nil, nil, "test", false)
+		exec.SetGatewayPool(stubGatewayPool{gw: &recordingGateway{}})
+		s.OrderQueue = executingQueue{exec: exec}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	newOrder := func(tags []string, out any) int {
+		return doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", token, map[string]any{
+			"symbol":        "BTCUSDT",
+			"side":          "BUY",
+			"type":          "LIMIT",
+			"price":         10000.0,
+			"qty":           0.01,
+			"connection_id": connResp.ID,
+			"note":          "  range breakout after CPI  ",
+			"tags":          tags,
+		}, out)
+	}
+
+	var errResp struct {
+		Code string `json:"code"`
+	}
+	if status := newOrder([]string{"a,b"}, &errResp); status != http.StatusBadRequest || errResp.Code != "INVALID_TAGS" {
+		t.Fatalf("expected INVALID_TAGS for a tag with a comma, got status=%d resp=%+v", status, errResp)
+	}
+
+	var createResp struct {
+		ID   string   `json:"id"`
+		Note string   `json:"note"`
+		Tags []string `json:"tags"`
+	}
+	if status := newOrder([]string{"Breakout", " news ", "breakout"}, &createResp); status != http.StatusAccepted {
+		t.Fatalf("create order failed status=%d", status)
+	}
+	if createResp.Note != "range breakout after CPI" || strings.Join(createResp.Tags, ",") != "breakout,news" {
+		t.Fatalf("unexpected journal fields in create response: %+v", createResp)
+	}
+
+	var orders []db.Order
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/orders?tag=NEWS", token, nil, &orders); status != http.StatusOK {
+		t.Fatalf("list orders failed status=%d", status)
+	}
+	if len(orders) != 1 || orders[0].ID != createResp.ID || orders[0].Note != "range breakout after CPI" || strings.Join(orders[0].Tags, ",") != "breakout,news" {
+		t.Fatalf("unexpected tagged orders: %+v", orders)
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/orders?tag=news2", token, nil, &orders); status != http.StatusOK || len(orders) != 0 {
+		t.Fatalf("expected no orders for an unused tag, got status=%d orders=%+v", status, orders)
+	}
+
+	// Fills are filtered by their order's tags.
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if err := database.Queries().CreateTradeWithUser(context.Background(), db.Trade{
+		ID: "fill-1", OrderID: createResp.ID, Symbol: "BTCUSDT", Side: "BUY", Price: 10000, Qty: 0.01, UserID: user.ID, CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateTradeWithUser: %v", err)
+	}
+	var trades []db.Trade
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/trades?tag=breakout", token, nil, &trades); status != http.StatusOK {
+		t.Fatalf("list trades failed status=%d", status)
+	}
+	if len(trades) != 1 || trades[0].ID != "fill-1" {
+		t.Fatalf("unexpected tagged trades: %+v", trades)
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/trades?tag=scalp", token, nil, &trades); status != http.StatusOK || len(trades) != 0 {
+		t.Fatalf("expected no trades for an unused tag, got status=%d trades=%+v", status, trades)
+	}
+}
+
+func TestLeaderboardRanksOptedInUsersByReturn(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) { s.Meta.DryRun = true })
+	defer cleanup()
+
+	client := ts.Client()
+	ctx := context.Background()
+	base := time.Now().Add(-24 * time.Hour)
+	seed := func(email string, series ...float64) string {
+		token := registerAndLoginAs(t, client, ts.URL, email)
+		user, err := database.GetUserByEmail(ctx, email)
+		if err != nil || user == nil {
+			t.Fatalf("GetUserByEmail(%s): %v", email, err)
+		}
+		for i, eq := range series {
+			if err := database.Queries().CreateEquitySnapshot(ctx, db.EquitySnapshot{
+				UserID: user.ID, Balance: eq, Equity: eq, CreatedAt: base.Add(time.Duration(i) * time.Hour),
+			}); err != nil {
+				t.Fatalf("CreateEquitySnapshot: %v", err)
+			}
+		}
+		return token
+	}
+
+	aliceToken := seed("alice@example.com", 1000, 1200, 1100) // +10%
+	bobToken := seed("bob@example.com", 500, 400, 750)        // +50%
+	seed("carol@example.com", 100, 1000)                      // +900%, never opted in
+
+	var profile profileResponse
+	if status := doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/profile", aliceToken, map[string]any{
+		"display_name": "alice", "leaderboard_opt_in": true,
+	}, &profile); status != http.StatusOK || !profile.LeaderboardOptIn || profile.DisplayName != "alice" {
+		t.Fatalf("update profile status=%d resp=%+v", status, profile)
+	}
+	// Opting in without a display name stays anonymous.
+	if status := doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/profile", bobToken, map[string]any{
+		"leaderboard_opt_in": true,
+	}, nil); status != http.StatusOK {
+		t.Fatalf("update profile status=%d", status)
+	}
+
+	var board leaderboardResponse
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/leaderboard", aliceToken, nil, &board); status != http.StatusOK {
+		t.Fatalf("leaderboard status=%d", status)
+	}
+	want := []leaderboardEntry{
+		{Rank: 1, DisplayName: "anonymous", ReturnPct: 50},
+		{Rank: 2, DisplayName: "alice", ReturnPct: 10, You: true},
+	}
+	if len(board.Entries) != len(want) {
+		t.Fatalf("entries = %+v, want %+v", board.Entries, want)
+	}
+	for i, e := range board.Entries {
+		if e.Rank != want[i].Rank || e.DisplayName != want[i].DisplayName || e.You != want[i].You || math.Abs(e.ReturnPct-want[i].ReturnPct) > 1e-9 {
+			t.Fatalf("entry %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+type summaryGateway struct{}
+
+func (summaryGateway) SubmitOrder(context.Context, exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{}, nil
+}
+func (summaryGateway) CancelOrder(context.Context, string, string) error { return nil }
+func (summaryGateway) AccountSummary(context.Context) (exchange.AccountSummary, error) {
+	return exchange.AccountSummary{CanTrade: true, AccountType: "SPOT", Permissions: []string{"SPOT", "MARGIN"}}, nil
+}
+
+type stubGatewayPool struct{ gw exchange.Gateway }
+
+func (p stubGatewayPool) GetOrCreate(context.Context, string, string) (exchange.Gateway, error) {
+	return p.gw, nil
+}
+
+func TestConnectionTestEndpoint(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Gateways = stubGatewayPool{gw: summaryGateway{}}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated {
+		t.Fatalf("create connection status=%d", status)
+	}
+
+	var resp map[string]any
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections/"+connResp.ID+"/test", token, nil, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d resp=%+v", status, resp)
+	}
+	if resp["ok"] != true || resp["can_trade"] != true || resp["account_type"] != "SPOT" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if _, ok := resp["latency_ms"].(float64); !ok {
+		t.Fatalf("expected numeric latency_ms, got %+v", resp["latency_ms"])
+	}
+	for _, key := range []string{"api_key", "api_secret"} {
+		if _, ok := resp[key]; ok {
+			t.Fatalf("response must not expose %s", key)
+		}
+	}
+
+	// Probed permissions are stored on the connection.
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	conn, err := database.Queries().GetConnectionByID(context.Background(), user.ID, connResp.ID)
+	if err != nil {
+		t.Fatalf("GetConnectionByID: %v", err)
+	}
+	if conn.Capabilities != "SPOT,MARGIN" {
+		t.Fatalf("expected stored capabilities SPOT,MARGIN, got %q", conn.Capabilities)
+	}
+
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections/missing/test", token, nil, nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown connection, got %d", status)
+	}
+}
+
+func TestListConnectionsReportsGatewayHealth(t *testing.T) {
+	var pool *gateway.Manager
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		pool = gateway.NewManager(s.DB.Queries(), nil, func(db.Connection, string, string) (exchange.Gateway, error) {
+			return summaryGateway{}, nil
+		}, gateway.DefaultConfig())
+		s.Gateways = pool
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	ctx := context.Background()
+	user, err := database.GetUserByEmail(ctx, "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	createConn := func(name string) string {
+		t.Helper()
+		var resp struct {
+			ID string `json:"id"`
+		}
+		status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+			"name":          name,
+			"exchange_type": "binance-spot",
+			"api_key":       "k",
+			"api_secret":    "s",
+		}, &resp)
+		if status != http.StatusCreated || resp.ID == "" {
+			t.Fatalf("create connection status=%d resp=%+v", status, resp)
+		}
+		return resp.ID
+	}
+	broken := createConn("Expired keys")
+	working := createConn("Working")
+	unused := createConn("Unused")
+
+	for _, id := range []string{broken, working} {
+		if _, err := pool.GetOrCreate(ctx, user.ID, id); err != nil {
+			t.Fatalf("GetOrCreate %s: %v", id, err)
+		}
+	}
+	for i := 0; i < gateway.DefaultConfig().FailureThreshold; i++ {
+		pool.RecordFailure(broken, fmt.Errorf("binance error -2015: Invalid API-key"))
+	}
+	pool.RecordSuccess(working)
+
+	var conns []struct {
+		ID     string               `json:"id"`
+		Health connectionHealthView `json:"health"`
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/connections", token, nil, &conns); status != http.StatusOK || len(conns) != 3 {
+		t.Fatalf("list connections status=%d resp=%+v", status, conns)
+	}
+	health := map[string]connectionHealthView{}
+	for _, c := range conns {
+		health[c.ID] = c.Health
+	}
+
+	if h := health[broken]; h.Status != gateway.HealthUnhealthy || h.Failures != 3 || !strings.Contains(h.LastError, "Invalid API-key") || h.LastErrorAt == nil || h.LastSuccessAt != nil {
+		t.Errorf("failing connection health = %+v", h)
+	}
+	if h := health[working]; h.Status != gateway.HealthHealthy || h.LastError != "" || h.LastSuccessAt == nil {
+		t.Errorf("working connection health = %+v", h)
+	}
+	if h := health[unused]; h.Status != gateway.HealthUnknown {
+		t.Errorf("unused connection health = %+v", h)
+	}
+}
+
+func TestPricesEndpointReturnsLastKnownPrice(t *testing.T) {
+	prices := market.NewLastPriceStore()
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Prices = prices
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	// Tick arrives before the client asks.
+	prices.Set("BTCUSDT", 50000)
+	prices.Set("BTCUSDT", 50100)
+
+	var one market.PriceSnapshot
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/prices/btcusdt", token, nil, &one); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if one.Symbol != "BTCUSDT" || one.Price != 50100 {
+		t.Fatalf("expected last price 50100, got %+v", one)
+	}
+
+	var all []market.PriceSnapshot
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/prices", token, nil, &all); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if len(all) != 1 || all[0].Price != 50100 {
+		t.Fatalf("unexpected prices: %+v", all)
+	}
+
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/prices/ETHUSDT", token, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for unseen symbol, got %d", status)
+	}
+}
+
+// recordingQueue keeps enqueued orders for inspection.
+type recordingQueue struct {
+	noopQueue
+	mu     sync.Mutex
+	orders []order.Order
+}
+
+func (q *recordingQueue) Enqueue(o order.Order) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.orders = append(q.orders, o)
+	return true
+}
+
+func TestCreateOrderBatchReturnsPerOrderResults(t *testing.T) {
+	queue := &recordingQueue{}
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risk.NewMultiUserManager(nil)
+		s.OrderQueue = queue
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	limit := func(qty, price float64) map[string]any {
+		return map[string]any{"symbol": "BTCUSDT", "side": "BUY", "type": "LIMIT", "price": price, "qty": qty, "connection_id": connResp.ID}
+	}
+	noPrice := limit(0.01, 0)
+	badConn := limit(0.01, 30000)
+	badConn["connection_id"] = "nope"
+	noSide := limit(0.01, 30000)
+	delete(noSide, "side")
+
+	var resp struct {
+		Accepted int `json:"accepted"`
+		Rejected int `json:"rejected"`
+		Results  []struct {
+			Index  int            `json:"index"`
+			Status string         `json:"status"`
+			Code   string         `json:"code"`
+			Order  map[string]any `json:"order"`
+		} `json:"results"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders/batch", token, []any{
+		limit(0.01, 30000), // accepted
+		noPrice,            // LIMIT without price
+		badConn,            // another user's / unknown connection
+		limit(1000, 30000), // above the max order notional
+		noSide,             // fails request validation
+		limit(0.02, 29000), // accepted
+	}, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("batch status=%d", status)
+	}
+
+	want := []struct{ status, code string }{
+		{"accepted", ""},
+		{"rejected", "INVALID_PRICE"},
+		{"rejected", "INVALID_CONNECTION"},
+		{"rejected", "ORDER_SIZE_LIMIT"},
+		{"rejected", "INVALID_REQUEST"},
+		{"accepted", ""},
+	}
+	if len(resp.Results) != len(want) || resp.Accepted != 2 || resp.Rejected != 4 {
+		t.Fatalf("unexpected batch summary: %+v", resp)
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Index != i || got.Status != w.status || got.Code != w.code {
+			t.Errorf("result %d = %+v, want status=%s code=%s", i, got, w.status, w.code)
+		}
+	}
+	if id, _ := resp.Results[0].Order["id"].(string); id == "" {
+		t.Errorf("accepted result should include the order, got %+v", resp.Results[0])
+	}
+
+	queue.mu.Lock()
+	enqueued := len(queue.orders)
+	queue.mu.Unlock()
+	if enqueued != 2 {
+		t.Fatalf("expected only the 2 accepted orders to be enqueued, got %d", enqueued)
+	}
+
+	// Oversized batches are refused outright.
+	big := make([]any, maxOrderBatch+1)
+	for i := range big {
+		big[i] = limit(0.01, 30000)
+	}
+	var errResp struct {
+		Code string `json:"code"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders/batch", token, big, &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "BATCH_TOO_LARGE" {
+		t.Fatalf("expected BATCH_TOO_LARGE, got status=%d resp=%+v", status, errResp)
+	}
+}
+
+func TestCreateOrderBatchCountsEarlierOrdersAgainstLimits(t *testing.T) {
+	queue := &recordingQueue{}
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risk.NewMultiUserManager(nil)
+		s.OrderQueue = queue
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+
+	// Each order is 600 notional, within the default 1000 per-position
+	// limit on its own; together they are not.
+	item := map[string]any{"symbol": "BTCUSDT", "side": "BUY", "type": "LIMIT", "price": 30000.0, "qty": 0.02, "connection_id": connResp.ID}
+	var resp struct {
+		Results []struct {
+			Status string         `json:"status"`
+			Code   string         `json:"code"`
+			Order  map[string]any `json:"order"`
+		} `json:"results"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders/batch", token, []any{item, item, item}, &resp)
+	if status != http.StatusOK || len(resp.Results) != 3 {
+		t.Fatalf("batch status=%d resp=%+v", status, resp)
+	}
+	if resp.Results[0].Status != "accepted" || resp.Results[0].Order["qty"] != 0.02 {
+		t.Fatalf("first order = %+v, want accepted in full", resp.Results[0])
+	}
+	if qty, _ := resp.Results[1].Order["qty"].(float64); resp.Results[1].Status != "accepted" || math.Abs(qty-400.0/30000) > 1e-9 {
+		t.Fatalf("second order = %+v, want shrunk to the 400 left under the limit", resp.Results[1])
+	}
+	if resp.Results[2].Status != "rejected" {
+		t.Fatalf("third order = %+v, want rejected at the position limit", resp.Results[2])
+	}
+}
+
+func TestOpenAPISpecListsRoutes(t *testing.T) {
+	srv, cleanup := newTestAPIServer(t)
+	defer cleanup()
+
+	resp, err := srv.Client().Get(srv.URL + "/api/v1/openapi.json")
+	if err != nil {
+		t.Fatalf("get spec: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("spec status = %d", resp.StatusCode)
+	}
+	var spec struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string                   `json:"required"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("openapi version = %q", spec.OpenAPI)
+	}
+
+	for path, method := range map[string]string{
+		"/api/v1/orders":               "post",
+		"/api/v1/orders/batch":         "post",
+		"/api/v1/orders/{id}":          "get",
+		"/api/v1/strategies":           "get",
+		"/api/v1/strategies/{id}/risk": "put",
+		"/api/v1/connections/{id}":     "delete",
+		"/api/v1/risk/config":          "put",
+		"/api/v1/auth/login":           "post",
+	} {
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("spec missing %s %s", strings.ToUpper(method), path)
+		}
+	}
+
+	// Request schemas come from the structs the handlers bind.
+	orderReq := spec.Components.Schemas["createOrderRequest"]
+	if _, ok := orderReq.Properties["connection_id"]; !ok || !containsString(orderReq.Required, "symbol") {
+		t.Fatalf("createOrderRequest schema = %+v", orderReq)
+	}
+
+	// Every registered route must be documented so the spec stays in sync.
+	s := NewServer(events.NewBus(), nil, noopEngine{}, nil, noopQueue{}, SystemMeta{}, "secret", nil, nil)
+	for _, r := range s.Router.Routes() {
+		if _, ok := routeDocs[r.Method+" "+r.Path]; !ok {
+			t.Errorf("route %s %s has no routeDocs entry", r.Method, r.Path)
+		}
+	}
+
+	ui, err := srv.Client().Get(srv.URL + "/docs")
+	if err != nil {
+		t.Fatalf("get docs: %v", err)
+	}
+	ui.Body.Close()
+	if ui.StatusCode != http.StatusOK || !strings.HasPrefix(ui.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("/docs status = %d, content type %q", ui.StatusCode, ui.Header.Get("Content-Type"))
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCORSPreflightHonorsAllowedOrigins(t *testing.T) {
+	srv, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Security = HTTPSecurity{AllowedOrigins: []string{"https://dash.example.com"}}
+	})
+	defer cleanup()
+
+	preflight := func(origin, method string) *http.Response {
+		req, _ := http.NewRequest(http.MethodOptions, srv.URL+"/api/v1/orders", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("preflight: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := preflight("https://dash.example.com", http.MethodPost)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("allowed origin preflight status = %d, want 204", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+	if !strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "Authorization") {
+		t.Fatalf("Access-Control-Allow-Headers = %q", resp.Header.Get("Access-Control-Allow-Headers"))
+	}
+
+	resp = preflight("https://evil.example.com", http.MethodPost)
+	if resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("disallowed origin preflight: status %d, allow-origin %q", resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+	if resp := preflight("https://dash.example.com", http.MethodPatch); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("disallowed method preflight status = %d, want 403", resp.StatusCode)
+	}
+
+	// Every response carries the baseline security headers.
+	health, err := srv.Client().Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("health: %v", err)
+	}
+	health.Body.Close()
+	if health.Header.Get("X-Content-Type-Options") != "nosniff" || health.Header.Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("missing security headers: %v", health.Header)
+	}
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	s := NewServer(events.NewBus(), nil, noopEngine{}, nil, noopQueue{}, SystemMeta{}, "secret", nil, nil)
+	started := make(chan struct{})
+	s.Router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"status": "done"})
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ln) }()
+	base := "http://" + ln.Addr().String()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws://"+ln.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer ws.Close()
+
+	type result struct {
+		status int
+		err    error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		resp.Body.Close()
+		slow <- result{status: resp.StatusCode}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	begin := time.Now()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 200*time.Millisecond {
+		t.Fatalf("Shutdown returned after %v, before the in-flight request finished", elapsed)
+	}
+	if r := <-slow; r.err != nil || r.status != http.StatusOK {
+		t.Fatalf("in-flight request: status %d, err %v", r.status, r.err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("Serve returned %v after graceful shutdown", err)
+	}
+
+	// The websocket client is told the server is going away.
+	_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Fatalf("ws read after shutdown: %v, want close 1001", err)
+			}
+			break
+		}
+	}
+
+	if _, err := http.Get(base + "/health"); err == nil {
+		t.Fatalf("server still accepting requests after shutdown")
+	}
+}
+
+func TestRegisterRejectsDuplicateAndMalformedEmail(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+	client := ts.Client()
+	register := func(email string) (int, string) {
+		var resp struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/auth/register", "", map[string]string{
+			"email":    email,
+			"password": "StrongPass123!",
+		}, &resp)
+		return status, resp.Code
+	}
+
+	if status, code := register("  Alice@Example.COM "); status != http.StatusCreated {
+		t.Fatalf("register: status=%d code=%s", status, code)
+	}
+	if u, err := database.GetUserByEmail(context.Background(), "alice@example.com"); err != nil || u == nil || u.Email != "alice@example.com" {
+		t.Fatalf("email not stored normalized: user=%+v err=%v", u, err)
+	}
+
+	// The same address in another case or padding is a duplicate.
+	for _, email := range []string{"alice@example.com", "ALICE@example.com", " alice@example.com\t"} {
+		if status, code := register(email); status != http.StatusConflict || code != "EMAIL_ALREADY_REGISTERED" {
+			t.Fatalf("duplicate %q: status=%d code=%s, want 409 EMAIL_ALREADY_REGISTERED", email, status, code)
+		}
+	}
+	// A racing insert that slips past the lookup is reported the same way.
+	err := database.CreateUser(context.Background(), db.User{ID: "dup", Email: "Alice@example.com", PasswordHash: "x"})
+	if !errors.Is(err, db.ErrDuplicate) {
+		t.Fatalf("CreateUser duplicate err = %v, want ErrDuplicate", err)
+	}
+
+	for _, email := range []string{"not-an-email", "bob@", "@example.com", "Bob <bob@example.com>", "bob@example", "a@b@example.com", strings.Repeat("a", 250) + "@example.com"} {
+		if status, code := register(email); status != http.StatusBadRequest || code != "INVALID_EMAIL" {
+			t.Fatalf("malformed %q: status=%d code=%s, want 400 INVALID_EMAIL", email, status, code)
+		}
+	}
+}
+
+func TestRegisterEnforcesPasswordPolicy(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Passwords = PasswordConfig{RequireUpper: true, RequireLower: true, RequireDigit: true}
+	})
+	defer cleanup()
+	client := ts.Client()
+	register := func(email, password string) (int, string, string) {
+		var resp struct {
+			Code  string `json:"code"`
+			Error string `json:"error"`
+		}
+		status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/auth/register", "", map[string]string{
+			"email":    email,
+			"password": password,
+		}, &resp)
+		return status, resp.Code, resp.Error
+	}
+
+	for _, pw := range []string{"Ab1", "alllowercase1", "ALLUPPERCASE1", "NoDigitsHere", strings.Repeat("Aa1", 50)} {
+		status, code, msg := register("weak@example.com", pw)
+		if status != http.StatusBadRequest || code != "WEAK_PASSWORD" || !strings.HasPrefix(msg, "password must") {
+			t.Fatalf("weak password %q: status=%d code=%s msg=%q, want 400 WEAK_PASSWORD", pw, status, code, msg)
+		}
+	}
+	if u, _ := database.GetUserByEmail(context.Background(), "weak@example.com"); u != nil {
+		t.Fatalf("user created with a weak password")
+	}
+
+	if status, code, _ := register("strong@example.com", "StrongPass123"); status != http.StatusCreated {
+		t.Fatalf("valid password: status=%d code=%s", status, code)
+	}
+	u, err := database.GetUserByEmail(context.Background(), "strong@example.com")
+	if err != nil || u == nil || !strings.HasPrefix(u.PasswordHash, "$argon2id$v=19$") {
+		t.Fatalf("password not hashed with argon2id: user=%+v err=%v", u, err)
+	}
+	login := func(email, password string) int {
+		return doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/auth/login", "", map[string]string{
+			"email":    email,
+			"password": password,
+		}, nil)
+	}
+	if status := login("strong@example.com", "StrongPass123"); status != http.StatusOK {
+		t.Fatalf("login with argon2id hash: status %d", status)
+	}
+	if status := login("strong@example.com", "StrongPass124"); status != http.StatusUnauthorized {
+		t.Fatalf("wrong password: status %d, want 401", status)
+	}
+
+	// Accounts hashed with bcrypt before the switch still log in.
+	bcryptHash, err := PasswordConfig{Algorithm: HashBcrypt, BcryptCost: 4}.Hash("LegacyPass1")
+	if err != nil || !strings.HasPrefix(bcryptHash, "$2") {
+		t.Fatalf("bcrypt hash = %q, %v", bcryptHash, err)
+	}
+	if err := database.CreateUser(context.Background(), db.User{ID: "legacy", Email: "legacy@example.com", PasswordHash: bcryptHash}); err != nil {
+		t.Fatal(err)
+	}
+	if status := login("legacy@example.com", "LegacyPass1"); status != http.StatusOK {
+		t.Fatalf("login with bcrypt hash: status %d", status)
+	}
+
+	if err := (PasswordConfig{MinLength: 16, RequireSymbol: true}).Validate("StrongPass123"); err == nil ||
+		!strings.Contains(err.Error(), "at least 16 characters") || !strings.Contains(err.Error(), "a symbol") {
+		t.Fatalf("stricter policy error = %v", err)
+	}
+}
+
+func TestAuthMiddlewareValidatesTokenClaims(t *testing.T) {
+	srv, cleanup := newTestAPIServer(t)
+	defer cleanup()
+	client := srv.Client()
+	url := srv.URL + "/api/v1/strategies"
+
+	if status := doJSONRequest(t, client, http.MethodGet, url, registerAndLogin(t, client, srv.URL), nil, nil); status != http.StatusOK {
+		t.Fatalf("valid login token: status %d, want 200", status)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		cfg      TokenConfig
+		issuedAt time.Time
+		want     int
+		code     string
+	}{
+		{name: "valid", issuedAt: now, want: http.StatusOK},
+		{name: "expired", cfg: TokenConfig{TTL: time.Hour}, issuedAt: now.Add(-2 * time.Hour), want: http.StatusUnauthorized, code: "TOKEN_EXPIRED"},
+		{name: "wrong audience", cfg: TokenConfig{Audience: "another-service"}, issuedAt: now, want: http.StatusUnauthorized, code: "INVALID_TOKEN"},
+		{name: "wrong issuer", cfg: TokenConfig{Issuer: "someone-else"}, issuedAt: now, want: http.StatusUnauthorized, code: "INVALID_TOKEN"},
+		{name: "issuer clock ahead within leeway", issuedAt: now.Add(20 * time.Second), want: http.StatusOK},
+		{name: "not yet valid", issuedAt: now.Add(time.Hour), want: http.StatusUnauthorized, code: "INVALID_TOKEN"},
+	}
+	for _, tt := range tests {
+		token, _, err := generateToken("user-1", "test-secret", tt.cfg, tt.issuedAt)
+		if err != nil {
+			t.Fatalf("%s: generateToken: %v", tt.name, err)
+		}
+		var body json.RawMessage
+		status := doJSONRequest(t, client, http.MethodGet, url, token, nil, &body)
+		if status != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, status, tt.want, body)
+			continue
+		}
+		var errBody errorResponse
+		if tt.code != "" && (json.Unmarshal(body, &errBody) != nil || errBody.Code != tt.code) {
+			t.Errorf("%s: body %s, want code %s", tt.name, body, tt.code)
+		}
+	}
+}
+
+// marginGateway is a futures gateway reporting one long position.
+type marginGateway struct{ summaryGateway }
+
+func (marginGateway) MarginAccount(context.Context) (exchange.MarginAccount, error) {
+	return exchange.MarginAccount{
+		Assets: []exchange.MarginAsset{{
+			Asset: "USDT", WalletBalance: 1000, UnrealizedPnL: 50, MarginBalance: 1050,
+			MaintMargin: 21, AvailableBalance: 800, MarginRatio: 0.02,
+		}},
+		Positions: []exchange.MarginPosition{{
+			Symbol: "BTCUSDT", PositionSide: "BOTH", Qty: 0.1, EntryPrice: 60000, MarkPrice: 60500,
+			UnrealizedPnL: 50, Leverage: 10, MarginType: "cross", MaintMargin: 21, LiquidationPrice: 54321,
+		}},
+	}, nil
+}
+
+func TestBalanceIncludesFuturesMargin(t *testing.T) {
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Gateways = stubGatewayPool{gw: marginGateway{}}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	for _, exchangeType := range []string{"binance-usdtfut", "binance-spot"} {
+		status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+			"name":          exchangeType,
+			"exchange_type": exchangeType,
+			"api_key":       "k",
+			"api_secret":    "s",
+		}, nil)
+		if status != http.StatusCreated {
+			t.Fatalf("create %s connection status=%d", exchangeType, status)
+		}
+	}
+
+	var resp struct {
+		Futures []futuresMarginView `json:"futures"`
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/balance", token, nil, &resp); status != http.StatusOK {
+		t.Fatalf("balance status=%d", status)
+	}
+	if len(resp.Futures) != 1 {
+		t.Fatalf("expected margin for the futures connection only, got %+v", resp.Futures)
+	}
+	f := resp.Futures[0]
+	if f.ExchangeType != "binance-usdtfut" || f.Error != "" || len(f.Assets) != 1 || len(f.Positions) != 1 {
+		t.Fatalf("unexpected futures margin: %+v", f)
+	}
+	if a := f.Assets[0]; a.WalletBalance != 1000 || a.UnrealizedPnL != 50 || a.MarginRatio != 0.02 {
+		t.Fatalf("asset margin fields not populated: %+v", a)
+	}
+	if p := f.Positions[0]; p.LiquidationPrice != 54321 || p.MarkPrice != 60500 || p.Leverage != 10 || p.MaintMargin != 21 {
+		t.Fatalf("position margin fields not populated: %+v", p)
+	}
+}
+
+// countingMarginGateway is a marginGateway counting MarginAccount calls.
+type countingMarginGateway struct {
+	marginGateway
+	mu    sync.Mutex
+	calls int
+}
+
+func (g *countingMarginGateway) MarginAccount(ctx context.Context) (exchange.MarginAccount, error) {
+	g.mu.Lock()
+	g.calls++
+	g.mu.Unlock()
+	return g.marginGateway.MarginAccount(ctx)
+}
+
+func TestBalanceCachesFuturesMargin(t *testing.T) {
+	gw := &countingMarginGateway{}
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Gateways = stubGatewayPool{gw: gw}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", token, map[string]any{
+		"name": "fut", "exchange_type": "binance-usdtfut", "api_key": "k", "api_secret": "s",
+	}, nil); status != http.StatusCreated {
+		t.Fatalf("create connection status=%d", status)
+	}
+
+	for i := 0; i < 3; i++ {
+		var resp struct {
+			Futures []futuresMarginView `json:"futures"`
+		}
+		if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/balance", token, nil, &resp); status != http.StatusOK || len(resp.Futures) != 1 {
+			t.Fatalf("balance status=%d futures=%+v", status, resp.Futures)
+		}
+	}
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if gw.calls != 1 {
+		t.Fatalf("margin account queried %d times, want once within the cache TTL", gw.calls)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	ts, _, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	createKey := func(scopes ...string) createAPIKeyResponse {
+		var resp createAPIKeyResponse
+		if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/api-keys", token, map[string]any{
+			"name": "bot", "scopes": scopes,
+		}, &resp); status != http.StatusCreated || resp.Key == "" || resp.Prefix == "" {
+			t.Fatalf("create key status=%d resp=%+v", status, resp)
+		}
+		return resp
+	}
+	withKey := func(method, path, key string, payload any) int {
+		var buf bytes.Buffer
+		if payload != nil {
+			_ = json.NewEncoder(&buf).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, ts.URL+path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("do request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	readKey := createKey("read")
+	order := map[string]any{"symbol": "BTCUSDT", "side": "BUY", "type": "MARKET", "qty": 0.01}
+	if status := withKey(http.MethodPost, "/api/v1/orders", readKey.Key, order); status != http.StatusForbidden {
+		t.Fatalf("read-only key on POST /orders: status=%d, want 403", status)
+	}
+	if status := withKey(http.MethodGet, "/api/v1/positions", readKey.Key, nil); status != http.StatusOK {
+		t.Fatalf("read-only key on GET /positions: status=%d, want 200", status)
+	}
+	// Keys cannot mint or list keys.
+	if status := withKey(http.MethodGet, "/api/v1/api-keys", createKey("trade").Key, nil); status != http.StatusForbidden {
+		t.Fatalf("trade key on GET /api-keys: status=%d, want 403", status)
+	}
+	// Nor manage exchange credentials or the account's risk limits.
+	tradeKey := createKey("trade")
+	for _, r := range []struct {
+		method, path string
+		payload      any
+	}{
+		{http.MethodPost, "/api/v1/connections", map[string]any{"name": "x", "exchange_type": "binance-spot", "api_key": "k", "api_secret": "s"}},
+		{http.MethodDelete, "/api/v1/connections/some-id", nil},
+		{http.MethodPut, "/api/v1/risk/config", map[string]any{"max_daily_loss": 100}},
+	} {
+		if status := withKey(r.method, r.path, tradeKey.Key, r.payload); status != http.StatusForbidden {
+			t.Fatalf("trade key on %s %s: status=%d, want 403", r.method, r.path, status)
+		}
+	}
+	if status := withKey(http.MethodGet, "/api/v1/positions", "desk_unknown", nil); status != http.StatusUnauthorized {
+		t.Fatalf("unknown key: status=%d, want 401", status)
+	}
+
+	// Listing never exposes the key; revoked keys stop working.
+	var keys []map[string]any
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/api-keys", token, nil, &keys); status != http.StatusOK || len(keys) != 3 {
+		t.Fatalf("list keys status=%d keys=%v", status, keys)
+	}
+	for _, k := range keys {
+		if _, leaked := k["key"]; leaked {
+			t.Fatalf("listing exposed a key: %v", k)
+		}
+	}
+	if status := doJSONRequest(t, client, http.MethodDelete, ts.URL+"/api/v1/api-keys/"+readKey.ID, token, nil, nil); status != http.StatusOK {
+		t.Fatalf("revoke status=%d", status)
+	}
+	if status := withKey(http.MethodGet, "/api/v1/positions", readKey.Key, nil); status != http.StatusUnauthorized {
+		t.Fatalf("revoked key: status=%d, want 401", status)
+	}
+}
+
+func TestShouldTouchAPIKeyThrottles(t *testing.T) {
+	s := &Server{}
+	now := time.Now()
+	if !s.shouldTouchAPIKey("k1", now) {
+		t.Fatal("first use should be recorded")
+	}
+	if s.shouldTouchAPIKey("k1", now.Add(10*time.Second)) {
+		t.Fatal("use within the interval should not be recorded again")
+	}
+	if !s.shouldTouchAPIKey("k2", now.Add(10*time.Second)) {
+		t.Fatal("another key should be recorded independently")
+	}
+	if !s.shouldTouchAPIKey("k1", now.Add(apiKeyTouchInterval)) {
+		t.Fatal("use after the interval should be recorded")
+	}
+}
+
+func TestRequestBodyLimitAndHandlerTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.RequestLimits = RequestLimits{MaxBodyBytes: 256, HandlerTimeout: 50 * time.Millisecond}
+		s.Router.GET("/slow", func(c *gin.Context) {
+			time.Sleep(300 * time.Millisecond)
+			if c.Request.Context().Err() != nil {
+				close(cancelled)
+			}
+			c.JSON(http.StatusOK, gin.H{"status": "too late"})
+		})
+	})
+	defer cleanup()
+	client := ts.Client()
+
+	var errResp errorResponse
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/auth/login", "", map[string]string{
+		"email": "big@example.com", "password": strings.Repeat("x", 512),
+	}, &errResp)
+	if status != http.StatusRequestEntityTooLarge || errResp.Code != "BODY_TOO_LARGE" {
+		t.Fatalf("oversized body: status=%d resp=%+v, want 413", status, errResp)
+	}
+
+	// Chunked bodies (no Content-Length) are measured too.
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/auth/login", io.MultiReader(strings.NewReader(strings.Repeat(" ", 300)), strings.NewReader("{}")))
+	req.ContentLength = -1
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("chunked request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized chunked body: status=%d, want 413", resp.StatusCode)
+	}
+
+	start := time.Now()
+	errResp = errorResponse{}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/slow", "", nil, &errResp); status != http.StatusRequestTimeout || errResp.Code != "REQUEST_TIMEOUT" {
+		t.Fatalf("slow handler: status=%d resp=%+v, want 408", status, errResp)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("timeout took %v", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not cancelled")
+	}
+
+	// Small bodies still reach the handler. (No password, so the answer does
+	// not wait on bcrypt, which unknown emails pay to keep login timing flat.)
+	errResp = errorResponse{}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/auth/login", "", map[string]string{
+		"email": "nobody@example.com",
+	}, &errResp); status == http.StatusRequestEntityTooLarge || status == http.StatusRequestTimeout || errResp.Code != "MISSING_CREDENTIALS" {
+		t.Fatalf("small body rejected with %d %s", status, errResp.Code)
+	}
+}
+
+func TestErrorCodesAreCataloged(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("glob sources: %v", err)
+	}
+	codeRe := regexp.MustCompile(`(?:respondError|abortWithError|newErrorResponse)\(c, "([A-Z_]+)"|orderError\{"([A-Z_]+)"`)
+	seen := 0
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("read %s: %v", f, err)
+		}
+		for _, m := range codeRe.FindAllStringSubmatch(string(src), -1) {
+			code := m[1] + m[2]
+			seen++
+			if _, ok := errorCatalog[code]; !ok {
+				t.Errorf("%s: error code %s is not in errorCatalog", f, code)
+			}
+		}
+	}
+	if seen == 0 {
+		t.Fatalf("no error codes found in sources")
+	}
+}
+
+func TestErrorEnvelopeCarriesRequestID(t *testing.T) {
+	srv, _, cleanup := newTestAPIServerWithDB(t)
+	defer cleanup()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/orders", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("X-Request-ID", "req-envelope-1")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("do request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	want := errorCatalog["MISSING_TOKEN"]
+	if resp.StatusCode != want.Status || body.Code != "MISSING_TOKEN" {
+		t.Fatalf("expected %d MISSING_TOKEN, got %d %+v", want.Status, resp.StatusCode, body)
+	}
+	if body.RequestID != "req-envelope-1" || resp.Header.Get("X-Request-ID") != body.RequestID {
+		t.Fatalf("request_id %q does not match header %q", body.RequestID, resp.Header.Get("X-Request-ID"))
+	}
+	if body.Timestamp.IsZero() || body.Error == "" {
+		t.Fatalf("incomplete error envelope: %+v", body)
+	}
+
+	// Handler errors use the same envelope with the catalog status.
+	var loginErr errorResponse
+	status := doJSONRequest(t, srv.Client(), http.MethodPost, srv.URL+"/api/v1/auth/login", "",
+		map[string]string{"email": "nobody@example.com", "password": "wrong-password"}, &loginErr)
+	if status != errorCatalog["INVALID_CREDENTIALS"].Status || loginErr.Code != "INVALID_CREDENTIALS" {
+		t.Fatalf("expected INVALID_CREDENTIALS, got %d %+v", status, loginErr)
+	}
+	if loginErr.RequestID == "" || loginErr.Timestamp.IsZero() {
+		t.Fatalf("login error missing request_id/timestamp: %+v", loginErr)
+	}
+}
+
+func TestDegradedStartBecomesReadyWhenDBRecovers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// A regular file where the DB directory should be keeps the DB unopenable.
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "data")
+	if err := os.WriteFile(blocker, []byte("not a directory"), 0o644); err != nil {
+		t.Fatalf("write blocker: %v", err)
+	}
+	dbPath := filepath.Join(blocker, "trading.db")
+
+	if _, err := db.OpenWithRetry(context.Background(), dbPath, db.RetryPolicy{Attempts: 2, InitialBackoff: time.Millisecond}); err == nil {
+		t.Fatal("expected OpenWithRetry to give up while the DB is unavailable")
+	}
+
+	readiness := NewReadiness()
+	ts := httptest.NewServer(NewDegradedHandler(readiness))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opened := make(chan *db.Database, 1)
+	go func() {
+		database, err := db.OpenWithRetry(ctx, dbPath, db.RetryPolicy{
+			InitialBackoff: 5 * time.Millisecond,
+			MaxBackoff:     20 * time.Millisecond,
+			OnRetry:        func(_ int, err error, _ time.Duration) { readiness.MarkUnavailable(err) },
+		})
+		if err == nil {
+			readiness.MarkReady()
+		}
+		opened <- database
+	}()
+
+	var errResp errorResponse
+	if status := doJSONRequest(t, ts.Client(), http.MethodGet, ts.URL+"/ready", "", nil, &errResp); status != http.StatusServiceUnavailable || errResp.Code != "DB_UNAVAILABLE" {
+		t.Fatalf("ready while DB down: status %d code %q", status, errResp.Code)
+	}
+	if status := doJSONRequest(t, ts.Client(), http.MethodGet, ts.URL+"/api/v1/strategies", "", nil, &errResp); status != http.StatusServiceUnavailable || errResp.Code != "DB_UNAVAILABLE" {
+		t.Fatalf("API while DB down: status %d code %q", status, errResp.Code)
+	}
+	if status := doJSONRequest(t, ts.Client(), http.MethodGet, ts.URL+"/health", "", nil, nil); status != http.StatusOK {
+		t.Fatalf("health while DB down: status %d, want 200", status)
+	}
+
+	// The outage ends.
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("remove blocker: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for doJSONRequest(t, ts.Client(), http.MethodGet, ts.URL+"/ready", "", nil, nil) != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("never became ready after the DB recovered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	database := <-opened
+	if database == nil {
+		t.Fatal("OpenWithRetry returned no database")
+	}
+	defer database.Close()
+
+	// The full server's readiness probe pings the recovered DB.
+	srv := NewServer(events.NewBus(), database, noopEngine{}, monitor.NewSystemMetrics(), noopQueue{}, SystemMeta{}, "secret", nil, nil)
+	full := httptest.NewServer(srv.Router)
+	defer full.Close()
+	if status := doJSONRequest(t, full.Client(), http.MethodGet, full.URL+"/ready", "", nil, nil); status != http.StatusOK {
+		t.Fatalf("full server ready: status %d, want 200", status)
+	}
+	database.Close()
+	if status := doJSONRequest(t, full.Client(), http.MethodGet, full.URL+"/ready", "", nil, nil); status != http.StatusServiceUnavailable {
+		t.Fatalf("full server ready after DB closed: status %d, want 503", status)
+	}
+}
+
+func TestAdminSetsUserLimits(t *testing.T) {
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.AdminEmails = []string{"Tester@example.com"}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	adminToken := registerAndLogin(t, client, ts.URL)
+	userToken := registerAndLoginAs(t, client, ts.URL, "trader@example.com")
+	trader, err := database.GetUserByEmail(context.Background(), "trader@example.com")
+	if err != nil || trader == nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	url := ts.URL + "/api/v1/admin/users/" + trader.ID + "/limits"
+
+	var errResp errorResponse
+	if status := doJSONRequest(t, client, http.MethodPut, url, userToken, map[string]any{"max_leverage": 125}, &errResp); status != http.StatusForbidden || errResp.Code != "FORBIDDEN" {
+		t.Fatalf("non-admin update: status=%d resp=%+v", status, errResp)
+	}
+	if status := doJSONRequest(t, client, http.MethodPut, url, adminToken, map[string]any{"max_leverage": 200}, &errResp); status != http.StatusBadRequest || errResp.Code != "INVALID_LIMITS" {
+		t.Fatalf("leverage above 125: status=%d resp=%+v", status, errResp)
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/admin/users/nope/limits", adminToken, nil, &errResp); status != http.StatusNotFound || errResp.Code != "USER_NOT_FOUND" {
+		t.Fatalf("unknown user: status=%d resp=%+v", status, errResp)
+	}
+
+	var limits userLimitsResponse
+	status := doJSONRequest(t, client, http.MethodPut, url, adminToken, map[string]any{
+		"max_strategies":  3,
+		"max_leverage":    5,
+		"allowed_symbols": []string{"btcusdt", " ETHUSDT ", "BTCUSDT"},
+	}, &limits)
+	if status != http.StatusOK || limits.MaxStrategies != 3 || limits.MaxLeverage != 5 || !reflect.DeepEqual(limits.AllowedSymbols, []string{"BTCUSDT", "ETHUSDT"}) {
+		t.Fatalf("update: status=%d resp=%+v", status, limits)
+	}
+
+	// Fields left out keep their value.
+	if status := doJSONRequest(t, client, http.MethodPut, url, adminToken, map[string]any{"max_concurrent_positions": 2}, &limits); status != http.StatusOK {
+		t.Fatalf("partial update: status=%d", status)
+	}
+	stored, err := database.Queries().GetUserLimits(context.Background(), trader.ID)
+	if err != nil || stored.MaxStrategies != 3 || stored.MaxLeverage != 5 || stored.MaxConcurrentPositions != 2 || !stored.AllowsSymbol("ETHUSDT") || stored.AllowsSymbol("SOLUSDT") {
+		t.Fatalf("stored limits = %+v (err %v)", stored, err)
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, url, adminToken, nil, &limits); status != http.StatusOK || limits.MaxConcurrentPositions != 2 || len(limits.AllowedSymbols) != 2 {
+		t.Fatalf("get: status=%d resp=%+v", status, limits)
+	}
+}
+
+func TestAdminSymbolHaltRejectsEntriesOnThatSymbolOnly(t *testing.T) {
+	halts := risk.NewSymbolHalts()
+	risks := risk.NewMultiUserManager(nil)
+	risks.SetSymbolHalts(halts)
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		s.Risk = risks
+		s.Halts = halts
+		s.AdminEmails = []string{"Tester@example.com"}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	adminToken := registerAndLogin(t, client, ts.URL)
+	userToken := registerAndLoginAs(t, client, ts.URL, "trader@example.com")
+
+	var errResp errorResponse
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/symbols/ETHUSDT/halt", userToken, nil, &errResp); status != http.StatusForbidden || errResp.Code != "FORBIDDEN" {
+		t.Fatalf("non-admin halt: status=%d resp=%+v", status, errResp)
+	}
+
+	var halt risk.SymbolHalt
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/symbols/ethusdt/halt", adminToken, map[string]string{"reason": "delisting"}, &halt)
+	if status != http.StatusOK || halt.Symbol != "ETHUSDT" || halt.Reason != "delisting" {
+		t.Fatalf("halt: status=%d resp=%+v", status, halt)
+	}
+
+	var connResp struct {
+		ID string `json:"id"`
+	}
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/connections", userToken, map[string]any{
+		"name":          "Test Spot",
+		"exchange_type": "binance-spot",
+		"api_key":       "k",
+		"api_secret":    "s",
+	}, &connResp)
+	if status != http.StatusCreated || connResp.ID == "" {
+		t.Fatalf("create connection failed status=%d resp=%+v", status, connResp)
+	}
+	order := func(symbol string, price float64) map[string]any {
+		return map[string]any{
+			"symbol":        symbol,
+			"side":          "BUY",
+			"type":          "LIMIT",
+			"price":         price,
+			"qty":           0.01,
+			"connection_id": connResp.ID,
+		}
+	}
+
+	status = doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", userToken, order("ETHUSDT", 2000), &errResp)
+	if status != http.StatusBadRequest || errResp.Code != "RISK_REJECTED" || !strings.Contains(errResp.Error, "ETHUSDT halted") {
+		t.Fatalf("entry on halted symbol: status=%d resp=%+v", status, errResp)
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", userToken, order("BTCUSDT", 30000), nil); status != http.StatusAccepted {
+		t.Fatalf("entry on other symbol should be accepted, got status=%d", status)
+	}
+
+	var sysStatus struct {
+		Halted []risk.SymbolHalt `json:"halted"`
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/system/status", "", nil, &sysStatus); status != http.StatusOK || len(sysStatus.Halted) != 1 || sysStatus.Halted[0].Symbol != "ETHUSDT" {
+		t.Fatalf("system status halted: status=%d resp=%+v", status, sysStatus)
+	}
+
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/symbols/ETHUSDT/resume", adminToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("resume: status=%d", status)
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/symbols/ETHUSDT/resume", adminToken, nil, &errResp); status != http.StatusNotFound || errResp.Code != "SYMBOL_NOT_HALTED" {
+		t.Fatalf("second resume: status=%d resp=%+v", status, errResp)
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/orders", userToken, order("ETHUSDT", 2000), nil); status != http.StatusAccepted {
+		t.Fatalf("entry after resume should be accepted, got status=%d", status)
+	}
+}
+
+// buyEveryTick is a running strategy that signals on every price.
+type buyEveryTick struct{ id string }
+
+func (b buyEveryTick) ID() string   { return b.id }
+func (b buyEveryTick) Name() string { return b.id }
+func (b buyEveryTick) OnTick(symbol string, price float64, ind map[string]float64) (*strategy.Signal, error) {
+	return &strategy.Signal{Action: "BUY", Symbol: symbol, Size: 1}, nil
+}
+func (b buyEveryTick) GetState() (json.RawMessage, error) { return json.RawMessage(`{}`), nil }
+func (b buyEveryTick) SetState(json.RawMessage) error     { return nil }
+
+func TestAdminStrategyFreezeBlocksNewStrategiesOnly(t *testing.T) {
+	bus := events.NewBus()
+	signals, unsub := bus.Subscribe(events.EventStrategySignal, 10)
+	defer unsub()
+	var stratEngine *strategy.Engine
+	ts, _, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		stratEngine = strategy.NewEngine(bus, s.DB.DB, strategy.Context{})
+		s.Engine = engine.NewImpl(engine.Config{StratEngine: stratEngine, Bus: bus})
+		s.AdminEmails = []string{"tester@example.com"}
+	})
+	defer cleanup()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := ts.Client()
+	adminToken := registerAndLogin(t, client, ts.URL)
+	userToken := registerAndLoginAs(t, client, ts.URL, "trader@example.com")
+
+	newStrategy := map[string]any{
+		"name":          "MA Cross BTC",
+		"strategy_type": "ma_cross",
+		"symbol":        "BTCUSDT",
+		"interval":      "1m",
+		"parameters":    map[string]any{"fast": 5, "slow": 20},
+	}
+	var createResp struct {
+		ID string `json:"id"`
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", userToken, newStrategy, &createResp); status != http.StatusCreated || createResp.ID == "" {
+		t.Fatalf("create strategy status=%d resp=%+v", status, createResp)
+	}
+	// The strategy is already running before the freeze.
+	stratEngine.Add(buyEveryTick{id: createResp.ID})
+	prices := make(chan any, 1)
+	stratEngine.Start(ctx, prices)
+
+	var errResp errorResponse
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/strategies/freeze", userToken, nil, &errResp); status != http.StatusForbidden || errResp.Code != "FORBIDDEN" {
+		t.Fatalf("non-admin freeze: status=%d resp=%+v", status, errResp)
+	}
+	var state FreezeState
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/strategies/freeze", adminToken, map[string]string{"reason": "incident"}, &state); status != http.StatusOK || !state.Frozen || state.Reason != "incident" {
+		t.Fatalf("freeze: status=%d resp=%+v", status, state)
+	}
+
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", userToken, newStrategy, &errResp); status != http.StatusServiceUnavailable || errResp.Code != "STRATEGIES_FROZEN" {
+		t.Fatalf("create while frozen: status=%d resp=%+v", status, errResp)
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies/"+createResp.ID+"/start", userToken, nil, &errResp); status != http.StatusServiceUnavailable || errResp.Code != "STRATEGIES_FROZEN" {
+		t.Fatalf("start while frozen: status=%d resp=%+v", status, errResp)
+	}
+	var sysStatus struct {
+		Frozen FreezeState `json:"frozen"`
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/system/status", "", nil, &sysStatus); status != http.StatusOK || !sysStatus.Frozen.Frozen {
+		t.Fatalf("system status frozen: status=%d resp=%+v", status, sysStatus)
+	}
+
+	// The running strategy keeps generating signals.
+	prices <- binancemarket.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 30000}
+	select {
+	case <-signals:
+	case <-time.After(2 * time.Second):
+		t.Fatal("running strategy stopped signalling while frozen")
+	}
+
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/admin/strategies/unfreeze", adminToken, nil, &state); status != http.StatusOK || state.Frozen {
+		t.Fatalf("unfreeze: status=%d resp=%+v", status, state)
+	}
+	if status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", userToken, newStrategy, nil); status != http.StatusCreated {
+		t.Fatalf("create after unfreeze: status=%d", status)
+	}
+}
+
+func TestStrategyLadderConfig(t *testing.T) {
+	ts, cleanup := newTestAPIServer(t)
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+
+	var createResp struct {
+		ID string `json:"id"`
+	}
+	status := doJSONRequest(t, client, http.MethodPost, ts.URL+"/api/v1/strategies", token, map[string]any{
+		"name":          "MA Cross BTC",
+		"strategy_type": "ma_cross",
+		"symbol":        "BTCUSDT",
+		"interval":      "1m",
+		"parameters":    map[string]any{"fast": 5, "slow": 20},
+	}, &createResp)
+	if status != http.StatusCreated || createResp.ID == "" {
+		t.Fatalf("create strategy status=%d resp=%+v", status, createResp)
+	}
+	url := ts.URL + "/api/v1/strategies/" + createResp.ID + "/ladder"
+
+	var ladder order.LadderConfig
+	if status := doJSONRequest(t, client, http.MethodGet, url, token, nil, &ladder); status != http.StatusOK || ladder.Enabled() || ladder.Distribution != "equal" || ladder.ApplyTo != "entry" {
+		t.Fatalf("default ladder: status=%d resp=%+v", status, ladder)
+	}
+
+	var errResp errorResponse
+	if status := doJSONRequest(t, client, http.MethodPut, url, token, map[string]any{"tranches": 3, "distribution": "random"}, &errResp); status != http.StatusBadRequest || errResp.Code != "INVALID_LADDER" {
+		t.Fatalf("invalid ladder: status=%d resp=%+v", status, errResp)
+	}
+
+	status = doJSONRequest(t, client, http.MethodPut, url, token, map[string]any{"tranches": 3, "spacing_pct": 0.5, "interval_seconds": 60, "distribution": "pyramid", "apply_to": "both"}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("update ladder status=%d", status)
+	}
+	if status := doJSONRequest(t, client, http.MethodGet, url, token, nil, &ladder); status != http.StatusOK ||
+		ladder != (order.LadderConfig{Tranches: 3, SpacingPct: 0.5, IntervalSec: 60, Distribution: "pyramid", ApplyTo: "both"}) {
+		t.Fatalf("stored ladder: status=%d resp=%+v", status, ladder)
+	}
+
+	other := registerAndLoginAs(t, client, ts.URL, "other@example.com")
+	if status := doJSONRequest(t, client, http.MethodPut, url, other, map[string]any{"tranches": 2}, nil); status != http.StatusForbidden {
+		t.Fatalf("other user's update status=%d, want 403", status)
+	}
+}
+
+func TestUpdateRiskConfigOnlyAdminsLoosenLimits(t *testing.T) {
+	var risks *risk.MultiUserManager
+	ts, database, cleanup := newTestAPIServerWithDB(t, func(s *Server) {
+		risks = risk.NewMultiUserManager(s.DB.DB)
+		s.Risk = risks
+		s.AdminEmails = []string{"admin@example.com"}
+	})
+	defer cleanup()
+
+	client := ts.Client()
+	token := registerAndLogin(t, client, ts.URL)
+	adminToken := registerAndLoginAs(t, client, ts.URL, "admin@example.com")
+	user, err := database.GetUserByEmail(context.Background(), "tester@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+
+	var errResp struct {
+		Code    string `json:"code"`
+		Message string `json:"error"`
+	}
+	status := doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/risk/config", token, map[string]any{
+		"enable_risk":    false,
+		"max_daily_loss": 0,
+	}, &errResp)
+	if status != http.StatusForbidden || errResp.Code != "RISK_LIMIT_LOOSENED" {
+		t.Fatalf("expected RISK_LIMIT_LOOSENED, got status=%d resp=%+v", status, errResp)
+	}
+	if !strings.Contains(errResp.Message, "enable_risk") || !strings.Contains(errResp.Message, "max_daily_loss") {
+		t.Fatalf("expected the loosened fields to be named, got %q", errResp.Message)
+	}
+
+	// Tightening is always allowed, and so is relaxing back to the default.
+	var cfg risk.RiskConfig
+	status = doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/risk/config", token, map[string]any{"max_daily_loss": 500}, &cfg)
+	if status != http.StatusOK || cfg.MaxDailyLoss != 500 {
+		t.Fatalf("tighten: status=%d cfg=%+v", status, cfg)
+	}
+	status = doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/risk/config", token, map[string]any{"max_daily_loss": risk.DefaultConfig().MaxDailyLoss}, &cfg)
+	if status != http.StatusOK {
+		t.Fatalf("relax to default: status=%d", status)
+	}
+
+	status = doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/risk/config", adminToken, map[string]any{"max_daily_loss": 0}, &cfg)
+	if status != http.StatusOK || cfg.MaxDailyLoss != 0 {
+		t.Fatalf("admin loosen: status=%d cfg=%+v", status, cfg)
+	}
+
+	// The user's config survives its manager being evicted.
+	status = doJSONRequest(t, client, http.MethodPut, ts.URL+"/api/v1/risk/config", token, map[string]any{"max_daily_trades": 5}, &cfg)
+	if status != http.StatusOK {
+		t.Fatalf("update: status=%d", status)
+	}
+	risks.Remove(user.ID)
+	status = doJSONRequest(t, client, http.MethodGet, ts.URL+"/api/v1/risk/config", token, nil, &cfg)
+	if status != http.StatusOK || cfg.MaxDailyTrades != 5 {
+		t.Fatalf("config after eviction: status=%d max_daily_trades=%d, want 5", status, cfg.MaxDailyTrades)
 	}
 }
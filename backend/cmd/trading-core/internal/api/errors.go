@@ -0,0 +1,161 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorResponse is the body of every API error (see respondError).
+type errorResponse struct {
+	Code      string    `json:"code"`
+	Error     string    `json:"error"`
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// apiError is a catalog entry: the HTTP status of an error code and the
+// message used when the handler has no more specific one.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+// errorCatalog lists every error code the API returns. Handlers reference
+// codes only through respondError, which takes the status from here;
+// TestErrorCodesAreCataloged fails when a code is missing.
+var errorCatalog = map[string]apiError{
+	// Authentication and authorization
+	"UNAUTHENTICATED":     {http.StatusUnauthorized, "unauthorized"},
+	"MISSING_TOKEN":       {http.StatusUnauthorized, "missing Authorization header"},
+	"INVALID_AUTH_HEADER": {http.StatusUnauthorized, "invalid Authorization header"},
+	"TOKEN_EXPIRED":       {http.StatusUnauthorized, "token expired"},
+	"INVALID_TOKEN":       {http.StatusUnauthorized, "invalid or expired token"},
+	"INVALID_CREDENTIALS": {http.StatusUnauthorized, "invalid credentials"},
+	"INVALID_API_KEY":     {http.StatusUnauthorized, "invalid or revoked API key"},
+	"INSUFFICIENT_SCOPE":  {http.StatusForbidden, "API key lacks the required scope"},
+	"SESSION_REQUIRED":    {http.StatusForbidden, "this endpoint requires a logged-in session"},
+	"FORBIDDEN":           {http.StatusForbidden, "forbidden"},
+	"RISK_LIMIT_LOOSENED": {http.StatusForbidden, "only admins can loosen risk limits"},
+
+	// Registration
+	"INVALID_PAYLOAD":          {http.StatusBadRequest, "invalid request payload"},
+	"MISSING_CREDENTIALS":      {http.StatusBadRequest, "email and password are required"},
+	"INVALID_EMAIL":            {http.StatusBadRequest, "invalid email format"},
+	"EMAIL_ALREADY_REGISTERED": {http.StatusConflict, "email already registered"},
+	"WEAK_PASSWORD":            {http.StatusBadRequest, "password does not meet the strength policy"},
+
+	// Request validation
+	"INVALID_REQUEST":     {http.StatusBadRequest, "invalid request"},
+	"INVALID_QUERY":       {http.StatusBadRequest, "invalid query parameters"},
+	"INVALID_FROM_DATE":   {http.StatusBadRequest, "invalid from date"},
+	"INVALID_TO_DATE":     {http.StatusBadRequest, "invalid to date"},
+	"INVALID_PARAMETERS":  {http.StatusBadRequest, "invalid strategy parameters"},
+	"INVALID_RISK_CONFIG": {http.StatusBadRequest, "invalid risk configuration"},
+	"INVALID_LADDER":      {http.StatusBadRequest, "invalid ladder configuration"},
+	"BODY_TOO_LARGE":      {http.StatusRequestEntityTooLarge, "request body too large"},
+	"BATCH_TOO_LARGE":     {http.StatusBadRequest, "too many orders in batch"},
+	"INVALID_LIMITS":      {http.StatusBadRequest, "invalid user limits"},
+
+	// Orders
+	"INVALID_PRICE":         {http.StatusBadRequest, "price must be > 0 for LIMIT orders"},
+	"INVALID_STOP_PRICE":    {http.StatusBadRequest, "stop_price must be > 0 for stop/take-profit orders"},
+	"INVALID_ROUTING":       {http.StatusBadRequest, "routing is only supported for LIMIT and MARKET orders"},
+	"INVALID_TIME_IN_FORCE": {http.StatusBadRequest, "time_in_force not allowed for this order"},
+	"INVALID_TAGS":          {http.StatusBadRequest, "invalid tags"},
+	"SYMBOL_NOT_ON_MARKET":  {http.StatusBadRequest, "symbol is not tradable on this connection"},
+	"SYMBOL_NOT_ALLOWED":    {http.StatusForbidden, "symbol is not in the user's allowed symbols"},
+	"PRICE_UNAVAILABLE":     {http.StatusBadRequest, "no price known for symbol"},
+	"ORDER_SIZE_LIMIT":      {http.StatusBadRequest, "order size outside limits"},
+	"RISK_REJECTED":         {http.StatusBadRequest, "rejected by risk checks"},
+	"INSUFFICIENT_BALANCE":  {http.StatusBadRequest, "insufficient balance"},
+
+	// Strategies and accounts
+	"ALLOCATION_EXCEEDS_BALANCE": {http.StatusBadRequest, "allocations exceed account balance"},
+	"LIMIT_EXCEEDED":             {http.StatusTooManyRequests, "resource limit reached"},
+	"LEADERBOARD_UNAVAILABLE":    {http.StatusForbidden, "leaderboard is only available in dry-run mode"},
+
+	// Connections
+	"INVALID_CONNECTION":     {http.StatusBadRequest, "invalid connection for current user"},
+	"CONNECTION_INACTIVE":    {http.StatusBadRequest, "connection is not active"},
+	"UNSUPPORTED_EXCHANGE":   {http.StatusBadRequest, "unsupported exchange type"},
+	"CONNECTION_TEST_FAILED": {http.StatusBadGateway, "connection test failed"},
+
+	// Not found
+	"NOT_FOUND":            {http.StatusNotFound, "not found"},
+	"STRATEGY_NOT_FOUND":   {http.StatusNotFound, "strategy not found"},
+	"CONNECTION_NOT_FOUND": {http.StatusNotFound, "connection not found"},
+	"PRICE_NOT_FOUND":      {http.StatusNotFound, "no price known for symbol"},
+	"API_KEY_NOT_FOUND":    {http.StatusNotFound, "API key not found or already revoked"},
+	"SYMBOL_NOT_HALTED":    {http.StatusNotFound, "symbol is not halted"},
+	"FILL_NOT_QUARANTINED": {http.StatusNotFound, "fill is not quarantined"},
+	"USER_NOT_FOUND":       {http.StatusNotFound, "user not found"},
+
+	// Limits and cross-origin access
+	"RATE_LIMITED":       {http.StatusTooManyRequests, "too many requests, please slow down"},
+	"REQUEST_TIMEOUT":    {http.StatusRequestTimeout, "request took too long to process"},
+	"CORS_ORIGIN_DENIED": {http.StatusForbidden, "origin not allowed"},
+	"CORS_METHOD_DENIED": {http.StatusForbidden, "method not allowed"},
+
+	// Unavailable dependencies
+	"ENGINE_UNAVAILABLE":  {http.StatusServiceUnavailable, "engine not available"},
+	"QUEUE_UNAVAILABLE":   {http.StatusServiceUnavailable, "order queue not available"},
+	"RISK_UNAVAILABLE":    {http.StatusServiceUnavailable, "risk manager not available"},
+	"PRICES_UNAVAILABLE":  {http.StatusServiceUnavailable, "price store not available"},
+	"METRICS_UNAVAILABLE": {http.StatusServiceUnavailable, "metrics not available"},
+	"GATEWAY_UNAVAILABLE": {http.StatusServiceUnavailable, "gateway not available"},
+	"HALTS_UNAVAILABLE":   {http.StatusServiceUnavailable, "symbol halts not available"},
+	"FILLS_UNAVAILABLE":   {http.StatusServiceUnavailable, "fill quarantine not available"},
+	"DB_UNAVAILABLE":      {http.StatusServiceUnavailable, "database unavailable, service is starting in degraded mode"},
+	"STRATEGIES_FROZEN":   {http.StatusServiceUnavailable, "new strategies are frozen"},
+	"NOT_SUPPORTED":       {http.StatusNotImplemented, "not supported"},
+
+	// Internal failures
+	"INTERNAL_ERROR":        {http.StatusInternalServerError, "internal server error"},
+	"DB_ERROR":              {http.StatusInternalServerError, "database error"},
+	"DB_SCAN_ERROR":         {http.StatusInternalServerError, "database error"},
+	"ENGINE_ERROR":          {http.StatusInternalServerError, "engine error"},
+	"RISK_ERROR":            {http.StatusInternalServerError, "risk evaluation failed"},
+	"CONFIG_ERROR":          {http.StatusInternalServerError, "server misconfigured"},
+	"ENCRYPTION_ERROR":      {http.StatusInternalServerError, "encryption failed"},
+	"KEY_GENERATION_FAILED": {http.StatusInternalServerError, "failed to generate key"},
+}
+
+// lookupError returns code's catalog entry; unknown codes are logged and
+// reported as internal errors.
+func lookupError(code string) apiError {
+	if e, ok := errorCatalog[code]; ok {
+		return e
+	}
+	log.Printf("⚠️ API error code %s is not in the error catalog", code)
+	return errorCatalog["INTERNAL_ERROR"]
+}
+
+// newErrorResponse builds the envelope for code; msg overrides the catalog
+// message when set.
+func newErrorResponse(c *gin.Context, code, msg string) (int, errorResponse) {
+	e := lookupError(code)
+	if msg == "" {
+		msg = e.Message
+	}
+	return e.Status, errorResponse{
+		Code:      code,
+		Error:     msg,
+		RequestID: c.GetString("RequestID"),
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// respondError writes the error envelope for code with the catalog status.
+func respondError(c *gin.Context, code, msg string) {
+	status, body := newErrorResponse(c, code, msg)
+	c.JSON(status, body)
+}
+
+// abortWithError is respondError for middleware: it also stops the chain.
+func abortWithError(c *gin.Context, code, msg string) {
+	status, body := newErrorResponse(c, code, msg)
+	c.AbortWithStatusJSON(status, body)
+}
@@ -1,15 +1,21 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
 	"reflect"
+	"sync"
 	"time"
 
 	"trading-core/internal/balance"
 	"trading-core/internal/engine"
 	"trading-core/internal/events"
+	"trading-core/internal/market"
 	"trading-core/internal/monitor"
 	"trading-core/internal/order"
+	"trading-core/internal/risk"
 	"trading-core/pkg/db"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +40,63 @@ type Server struct {
 
 	JWTSecret string
 	Meta      SystemMeta
+
+	// Tokens sets access-token lifetime and the iss/aud claims (zero = defaults).
+	Tokens TokenConfig
+	// Passwords sets the strength policy for new passwords and how they are
+	// hashed (zero = defaults, argon2id).
+	Passwords PasswordConfig
+
+	// Gateways resolves per-connection gateways (optional; typically gateway.Manager).
+	Gateways order.GatewayPool
+
+	// Prices holds the last-known price per symbol (optional).
+	Prices *market.LastPriceStore
+
+	// Risk evaluates manual and what-if orders per user (optional; nil skips risk
+	// checks on manual orders and disables /orders/simulate).
+	Risk *risk.MultiUserManager
+	// SimConfig configures the paper fills used by /orders/simulate.
+	SimConfig order.DryRunSimConfig
+	// StopLoss receives SL/TP levels for approved manual orders (optional).
+	StopLoss *risk.StopLossManager
+	// Maintenance is the scheduled trading-block calendar reported by /system/status (optional).
+	Maintenance *risk.MaintenanceSchedule
+	// Halts is the runtime halted-symbol set managed by /admin/symbols (optional).
+	Halts *risk.SymbolHalts
+	// Quarantine holds abnormally priced fills for review via /admin/fills (optional).
+	Quarantine *risk.FillQuarantine
+	// AdminEmails lists the users allowed to call /admin endpoints.
+	AdminEmails []string
+	// Freeze blocks creating and starting strategies; managed by /admin/strategies.
+	Freeze StrategyFreeze
+
+	// Limits caps strategies/connections per user (0 = unlimited); see withinResourceLimit.
+	Limits db.UserLimits
+
+	// RequestLimits caps request body size and handler run time (zero = defaults).
+	RequestLimits RequestLimits
+
+	// Security holds the CORS allow-lists and HSTS switch (zero value = no
+	// cross-origin access); set it before Start.
+	Security HTTPSecurity
+
+	// ProbeCapabilities checks a new connection's account permissions (optional, nil = skip).
+	ProbeCapabilities func(ctx context.Context, exchangeType, apiKey, apiSecret string) ([]string, error)
+
+	keyTouchMu sync.Mutex
+	keyTouched map[string]time.Time // last TouchAPIKey per key; see shouldTouchAPIKey
+
+	dummyHashOnce sync.Once
+	dummyHash     string // see dummyPasswordHash
+
+	marginMu    sync.Mutex
+	marginCache map[string]cachedMargin // user_id/connection_id -> margin account; see marginAccount
+
+	httpMu     sync.Mutex
+	httpServer *http.Server
+	closing    chan struct{}  // closed by Shutdown to end websocket streams
+	wsConns    sync.WaitGroup // open websocket connections
 }
 
 func normalizeKeyManager(k KeyManager) KeyManager {
@@ -84,16 +147,6 @@ func NewServer(
 	userBalances *balance.MultiUserManager,
 ) *Server {
 	r := gin.New()
-
-	// Middleware stack (order matters!)
-	r.Use(gin.Recovery())         // Panic recovery (first)
-	r.Use(RequestIDMiddleware())  // Request ID tracking
-	r.Use(RequestLogger(metrics)) // Request logging (after ID is set)
-	r.Use(RateLimitMiddleware())  // Rate limiting
-	// Security headers handled by Nginx
-	r.Use(TimeoutMiddleware(30 * time.Second)) // Request timeout (30s)
-	r.Use(CORSMiddleware())                    // CORS (last before routes)
-
 	keyMgr = normalizeKeyManager(keyMgr)
 
 	s := &Server{
@@ -107,22 +160,40 @@ func NewServer(
 		UserBalances: userBalances,
 		JWTSecret:    jwtSecret,
 		Meta:         meta,
+		marginCache:  make(map[string]cachedMargin),
+		closing:      make(chan struct{}),
 	}
+
+	// Middleware stack (order matters!)
+	r.Use(gin.Recovery())                         // Panic recovery (first)
+	r.Use(RequestIDMiddleware())                  // Request ID tracking
+	r.Use(TracingMiddleware())                    // Request span (OpenTelemetry)
+	r.Use(RequestLogger(metrics))                 // Request logging (after ID is set)
+	r.Use(SecurityHeadersMiddleware(&s.Security)) // nosniff, frame denial, HSTS in prod
+	r.Use(RateLimitMiddleware())                  // Rate limiting
+	r.Use(BodyLimitMiddleware(&s.RequestLimits))  // 413 over the body size limit
+	r.Use(TimeoutMiddleware(&s.RequestLimits))    // Cancel handlers past the timeout
+	r.Use(CORSMiddleware(&s.Security))            // CORS (last before routes)
+
 	s.routes()
 	return s
 }
 
 func (s *Server) routes() {
 	s.Router.GET("/health", s.health)
+	s.Router.GET("/ready", s.ready)
 	s.Router.GET("/ws", s.websocket)
 	// Prometheus-style metrics (unauthenticated, lightweight)
 	s.Router.GET("/metrics", s.getPromMetrics)
+	// OpenAPI spec and Swagger UI, generated from routeDocs and the request/response structs
+	s.Router.GET("/docs", s.swaggerUI)
 
 	api := s.Router.Group("/api/v1")
 	{
 		api.GET("/system/status", s.getSystemStatus)
 		api.GET("/metrics", s.getMetrics)
 		api.GET("/queue/metrics", s.getQueueMetrics)
+		api.GET("/openapi.json", s.getOpenAPISpec)
 
 		// Auth endpoints (no auth required)
 		auth := api.Group("/auth")
@@ -133,20 +204,38 @@ func (s *Server) routes() {
 
 		// Protected API
 		protected := api.Group("")
-		protected.Use(AuthMiddleware(s.JWTSecret))
+		protected.Use(s.authenticate()) // JWT bearer token or X-API-Key
 		{
 			protected.GET("/strategies", s.getStrategies)
 			protected.GET("/orders", s.getOrders)
+			protected.GET("/orders/:id", s.getOrderReport)
+			protected.GET("/trades", s.getTrades)
 			protected.GET("/positions", s.getPositions)
 			protected.GET("/balance", s.getBalance)
 			protected.GET("/risk", s.getRiskMetrics)
+			protected.GET("/risk/config", s.getRiskConfig)
+			protected.PUT("/risk/config", sessionOnly(), s.updateRiskConfig)
+			protected.GET("/strategies/:id/risk", s.getStrategyRisk)
 			protected.GET("/strategies/:id/performance", s.getStrategyPerformance)
+			protected.GET("/strategies/:id/pnl", s.getStrategyPnLAttribution)
+			protected.GET("/equity", s.getEquityCurve)
+			protected.GET("/pnl", s.getPnLAttribution)
+			protected.GET("/leaderboard", s.getLeaderboard)
+			protected.GET("/profile", s.getProfile)
+			protected.PUT("/profile", s.updateProfile)
+			protected.GET("/api-keys", s.listAPIKeys)
+			protected.POST("/api-keys", s.createAPIKey)
+			protected.DELETE("/api-keys/:id", s.revokeAPIKey)
+			protected.GET("/prices", s.getPrices)
+			protected.GET("/prices/:symbol", s.getPrice)
 
 			// Strategy management (create + bind)
 			protected.POST("/strategies", s.createStrategy)
 
 			// Manual orders (per-user, per-connection)
 			protected.POST("/orders", s.createOrder)
+			protected.POST("/orders/batch", s.createOrderBatch)
+			protected.POST("/orders/simulate", s.simulateOrder)
 
 			// Strategy Actions
 			protected.POST("/strategies/:id/start", s.startStrategy)
@@ -155,11 +244,30 @@ func (s *Server) routes() {
 			protected.POST("/strategies/:id/panic", s.panicSellStrategy)
 			protected.PUT("/strategies/:id/params", s.updateStrategyParams)
 			protected.PUT("/strategies/:id/binding", s.updateStrategyBinding)
+			protected.PUT("/strategies/:id/risk", s.updateStrategyRisk)
+			protected.GET("/strategies/:id/ladder", s.getStrategyLadder)
+			protected.PUT("/strategies/:id/ladder", s.updateStrategyLadder)
 
 			// Exchange connections (Phase 2)
 			protected.GET("/connections", s.listConnections)
-			protected.POST("/connections", s.createConnection)
-			protected.DELETE("/connections/:id", s.deactivateConnection)
+			protected.POST("/connections", sessionOnly(), s.createConnection)
+			protected.DELETE("/connections/:id", sessionOnly(), s.deactivateConnection)
+			protected.POST("/connections/:id/test", s.testConnection)
+		}
+
+		// Operator controls (session of an AdminEmails user)
+		admin := api.Group("/admin")
+		admin.Use(s.authenticate(), s.requireAdmin())
+		{
+			admin.POST("/symbols/:symbol/halt", s.haltSymbol)
+			admin.POST("/symbols/:symbol/resume", s.resumeSymbol)
+			admin.POST("/strategies/freeze", s.freezeStrategies)
+			admin.POST("/strategies/unfreeze", s.unfreezeStrategies)
+			admin.GET("/fills/quarantine", s.listQuarantinedFills)
+			admin.POST("/fills/quarantine/:id/release", s.releaseQuarantinedFill)
+			admin.POST("/fills/quarantine/:id/discard", s.discardQuarantinedFill)
+			admin.GET("/users/:id/limits", s.getUserLimits)
+			admin.PUT("/users/:id/limits", s.updateUserLimits)
 		}
 	}
 }
@@ -168,6 +276,59 @@ func (s *Server) health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// Start listens on addr and serves until Shutdown. It returns nil after a
+// graceful shutdown.
 func (s *Server) Start(addr string) error {
-	return s.Router.Run(addr)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve serves the API on ln until Shutdown.
+func (s *Server) Serve(ln net.Listener) error {
+	srv := &http.Server{Handler: s.Router}
+	s.httpMu.Lock()
+	s.httpServer = srv
+	s.httpMu.Unlock()
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests to
+// finish and closes websocket streams with a close frame, giving up when ctx
+// expires.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.httpMu.Lock()
+	srv := s.httpServer
+	select {
+	case <-s.closing:
+	default:
+		close(s.closing)
+	}
+	s.httpMu.Unlock()
+
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+
+	// Hijacked websocket connections are not tracked by http.Server.
+	done := make(chan struct{})
+	go func() {
+		s.wsConns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
 }
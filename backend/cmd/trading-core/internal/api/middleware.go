@@ -1,16 +1,26 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"trading-core/internal/monitor"
+	"trading-core/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"golang.org/x/time/rate"
 )
 
@@ -56,16 +66,80 @@ func init() {
 	}()
 }
 
-// CORSMiddleware handles Cross-Origin Resource Sharing
-func CORSMiddleware() gin.HandlerFunc {
+// HTTPSecurity configures cross-origin access and response security headers.
+// The zero value allows no cross-origin callers and omits HSTS.
+type HTTPSecurity struct {
+	AllowedOrigins []string // exact origins, or "*" for any
+	AllowedMethods []string // empty = defaultCORSMethods
+	AllowedHeaders []string // empty = defaultCORSHeaders
+	HSTS           bool     // send Strict-Transport-Security (TLS-terminated prod)
+}
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Authorization", "Content-Type", "X-Request-ID"}
+)
+
+func (h *HTTPSecurity) allowsOrigin(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, o := range h.AllowedOrigins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func orDefault(v, def []string) []string {
+	if len(v) == 0 {
+		return def
+	}
+	return v
+}
+
+// CORSMiddleware handles Cross-Origin Resource Sharing for the origins in cfg.
+// Allowed origins are echoed back (never "*") so credentialed requests work;
+// preflights from other origins, or for methods not allowed, get 403. cfg is
+// read per request, so it may be filled in after NewServer.
+func CORSMiddleware(cfg *HTTPSecurity) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" {
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		if !cfg.allowsOrigin(origin) {
+			if preflight {
+				abortWithError(c, "CORS_ORIGIN_DENIED", "origin not allowed")
+				return
+			}
+			// Without CORS headers the browser refuses the response to scripts.
+			c.Next()
+			return
+		}
+
+		methods := orDefault(cfg.AllowedMethods, defaultCORSMethods)
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		h.Set("Access-Control-Allow-Headers", strings.Join(orDefault(cfg.AllowedHeaders, defaultCORSHeaders), ", "))
+		h.Set("Access-Control-Expose-Headers", "X-Request-ID, X-Result-Limit, X-Result-Offset")
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if c.Request.Method == http.MethodOptions {
+			if preflight && !containsFold(methods, c.GetHeader("Access-Control-Request-Method")) {
+				abortWithError(c, "CORS_METHOD_DENIED", "method not allowed")
+				return
+			}
+			h.Set("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
@@ -73,6 +147,31 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityHeadersMiddleware sets baseline security headers on every response,
+// plus HSTS when cfg enables it.
+func SecurityHeadersMiddleware(cfg *HTTPSecurity) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Content-Security-Policy", "frame-ancestors 'none'")
+		if cfg.HSTS {
+			h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
+		c.Next()
+	}
+}
+
 // RequestIDMiddleware adds unique request ID for tracking
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -86,6 +185,32 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
+// TracingMiddleware starts a server span per request, continuing a trace
+// passed in W3C traceparent headers, and puts it in the request context.
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route))
+		defer span.End()
+		if id := c.GetString("RequestID"); id != "" {
+			span.SetAttributes(attribute.String("request.id", id))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
 // RateLimitMiddleware prevents API abuse with per-IP rate limiting
 func RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -94,11 +219,7 @@ func RateLimitMiddleware() gin.HandlerFunc {
 
 		if !limiter.Allow() {
 			log.Printf("[RATE_LIMIT] IP %s exceeded rate limit", ip)
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "rate limit exceeded",
-				"message": "too many requests, please slow down",
-			})
-			c.Abort()
+			abortWithError(c, "RATE_LIMITED", "")
 			return
 		}
 
@@ -106,46 +227,191 @@ func RateLimitMiddleware() gin.HandlerFunc {
 	}
 }
 
-// TimeoutMiddleware prevents long-running requests from blocking resources
-func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+// RequestLimits bounds request bodies and handler run time. Zero fields fall
+// back to the defaults below.
+type RequestLimits struct {
+	MaxBodyBytes   int64
+	HandlerTimeout time.Duration
+}
+
+const (
+	defaultMaxBodyBytes   = 1 << 20
+	defaultHandlerTimeout = 30 * time.Second
+)
+
+func (l RequestLimits) withDefaults() RequestLimits {
+	if l.MaxBodyBytes <= 0 {
+		l.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if l.HandlerTimeout <= 0 {
+		l.HandlerTimeout = defaultHandlerTimeout
+	}
+	return l
+}
+
+// BodyLimitMiddleware rejects request bodies larger than cfg.MaxBodyBytes
+// with 413. Bodies are buffered (up to the limit), so chunked uploads are
+// caught before handlers bind them. cfg is read per request.
+func BodyLimitMiddleware(cfg *RequestLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := cfg.withDefaults().MaxBodyBytes
+		if c.Request.ContentLength > limit {
+			abortWithError(c, "BODY_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", limit))
+			return
+		}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+			_ = c.Request.Body.Close()
+			if err != nil {
+				abortWithError(c, "INVALID_REQUEST", "failed to read request body")
+				return
+			}
+			if int64(len(body)) > limit {
+				abortWithError(c, "BODY_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", limit))
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		c.Next()
+	}
+}
+
+// TimeoutMiddleware cancels the request context after cfg.HandlerTimeout and
+// answers 408 if the handler has not responded by then. Writes made by the
+// handler after the deadline are discarded; the middleware still waits for it
+// to return so the gin context is not reused while in flight. Websocket
+// upgrades are exempt. cfg is read per request.
+func TimeoutMiddleware(cfg *RequestLimits) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.withDefaults().HandlerTimeout)
 		defer cancel()
-
 		c.Request = c.Request.WithContext(ctx)
 
-		finished := make(chan struct{})
-		panicChan := make(chan interface{}, 1)
+		// Built up front: c must not be touched while the handler runs.
+		timeoutStatus, timeoutResp := newErrorResponse(c, "REQUEST_TIMEOUT", "")
+		timeoutBody, _ := json.Marshal(timeoutResp)
+
+		orig := c.Writer
+		tw := &timeoutWriter{ResponseWriter: orig, h: make(http.Header)}
+		c.Writer = tw
 
+		finished := make(chan struct{})
+		var panicked any
 		go func() {
-			defer func() {
-				if p := recover(); p != nil {
-					panicChan <- p
-				}
-			}()
+			defer close(finished)
+			defer func() { panicked = recover() }()
 			c.Next()
-			finished <- struct{}{}
 		}()
 
 		select {
-		case <-panicChan:
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "internal server error",
-			})
-			c.Abort()
 		case <-finished:
-			return
+			tw.finish()
 		case <-ctx.Done():
 			log.Printf("[TIMEOUT] Request timeout: %s %s", c.Request.Method, c.Request.URL.Path)
-			c.JSON(http.StatusRequestTimeout, gin.H{
-				"error":   "request timeout",
-				"message": "request took too long to process",
-			})
+			tw.timeout(timeoutStatus, timeoutBody)
+			<-finished
+		}
+		c.Writer = orig
+
+		if panicked != nil {
+			log.Printf("❌ Handler panic: %v", panicked)
+			if !orig.Written() {
+				respondError(c, "INTERNAL_ERROR", "")
+			}
 			c.Abort()
 		}
 	}
 }
 
+// timeoutWriter gives the handler its own header map and drops its writes
+// once the request has timed out.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (w *timeoutWriter) Header() http.Header { return w.h }
+
+// sendHeader copies the handler's headers before the first write; callers hold mu.
+func (w *timeoutWriter) sendHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	dst := w.ResponseWriter.Header()
+	for k, v := range w.h {
+		dst[k] = v
+	}
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.sendHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.sendHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.sendHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.sendHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// finish passes on headers set by a handler that never wrote a body.
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sendHeader()
+}
+
+// timeout answers with body unless the handler already started its response.
+func (w *timeoutWriter) timeout(status int, body []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	if w.wroteHeader {
+		return
+	}
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.ResponseWriter.WriteHeader(status)
+	_, _ = w.ResponseWriter.Write(body)
+}
+
 // RequestLogger logs all API requests with timing and status; optionally records metrics.
 func RequestLogger(metrics *monitor.SystemMetrics) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,411 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"trading-core/internal/engine"
+	"trading-core/internal/market"
+	"trading-core/internal/monitor"
+	"trading-core/internal/order"
+	"trading-core/internal/risk"
+	"trading-core/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeDoc describes one route in the generated OpenAPI spec. Query, Request
+// and Response are zero values whose types are reflected into schemas, so the
+// spec follows the structs the handlers actually bind and return.
+type routeDoc struct {
+	Summary     string
+	Public      bool // /api/v1 routes need a bearer token or API key unless set
+	Query       any  // struct with `form` tags
+	Request     any  // JSON body
+	Response    any  // JSON body of the success response (nil = none)
+	Status      int  // success status (default 200)
+	ContentType string
+}
+
+// statusResponse is the body of action endpoints that only report a status.
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// routeDocs documents every registered route, keyed by "METHOD path" as gin
+// reports it. TestOpenAPISpecListsRoutes fails when a route is missing here.
+var routeDocs = map[string]routeDoc{
+	"GET /health":              {Summary: "Liveness probe", Response: statusResponse{}},
+	"GET /ready":               {Summary: "Readiness probe (503 DB_UNAVAILABLE while the database is down)", Response: statusResponse{}},
+	"GET /ws":                  {Summary: "Event stream websocket (upgrade)", Status: http.StatusSwitchingProtocols},
+	"GET /metrics":             {Summary: "Prometheus metrics", Response: "", ContentType: "text/plain"},
+	"GET /docs":                {Summary: "Swagger UI", Response: "", ContentType: "text/html"},
+	"GET /api/v1/openapi.json": {Summary: "This OpenAPI document", Public: true, Response: gin.H{}},
+
+	"GET /api/v1/system/status": {Summary: "Runtime mode, venue and maintenance windows", Public: true, Response: gin.H{}},
+	"GET /api/v1/metrics":       {Summary: "System performance metrics", Public: true, Response: monitor.MetricsSnapshot{}},
+	"GET /api/v1/queue/metrics": {Summary: "Order queue statistics", Public: true, Response: gin.H{}},
+
+	"POST /api/v1/auth/register": {Summary: "Register a user", Public: true, Request: registerRequest{}, Response: gin.H{}, Status: http.StatusCreated},
+	"POST /api/v1/auth/login":    {Summary: "Log in and obtain a JWT", Public: true, Request: loginRequest{}, Response: gin.H{}},
+
+	"GET /api/v1/strategies":                 {Summary: "List strategies", Query: listStrategiesQuery{}, Response: []gin.H{}},
+	"POST /api/v1/strategies":                {Summary: "Create a strategy", Request: createStrategyRequest{}, Response: gin.H{}, Status: http.StatusCreated},
+	"POST /api/v1/strategies/:id/start":      {Summary: "Start a strategy", Response: statusResponse{}},
+	"POST /api/v1/strategies/:id/pause":      {Summary: "Pause a strategy", Response: statusResponse{}},
+	"POST /api/v1/strategies/:id/stop":       {Summary: "Stop a strategy", Response: statusResponse{}},
+	"POST /api/v1/strategies/:id/panic":      {Summary: "Close a strategy's positions at market", Response: statusResponse{}},
+	"PUT /api/v1/strategies/:id/params":      {Summary: "Update strategy parameters", Request: map[string]any{}, Response: statusResponse{}},
+	"PUT /api/v1/strategies/:id/binding":     {Summary: "Bind a strategy to a connection", Request: updateStrategyBindingRequest{}, Response: statusResponse{}},
+	"GET /api/v1/strategies/:id/risk":        {Summary: "Strategy risk limits and allocation", Response: risk.StrategyRiskConfig{}},
+	"PUT /api/v1/strategies/:id/risk":        {Summary: "Update strategy risk limits and allocation", Request: risk.StrategyRiskConfig{}, Response: risk.StrategyRiskConfig{}},
+	"GET /api/v1/strategies/:id/ladder":      {Summary: "How the strategy's signals are split into tranches", Response: order.LadderConfig{}},
+	"PUT /api/v1/strategies/:id/ladder":      {Summary: "Scale in/out: split each signal into tranches by price and/or time", Request: order.LadderConfig{}, Response: order.LadderConfig{}},
+	"GET /api/v1/strategies/:id/performance": {Summary: "Daily realized PnL and equity for a strategy", Response: gin.H{}},
+	"GET /api/v1/strategies/:id/pnl":         {Summary: "Strategy PnL broken down into gross, fees and funding", Query: pnlRangeQuery{}, Response: pnlAttributionResponse{}},
+
+	"GET /api/v1/orders":           {Summary: "List orders", Query: listOrdersQuery{}, Response: []db.Order{}},
+	"GET /api/v1/orders/:id":       {Summary: "Order execution report", Response: orderReport{}},
+	"GET /api/v1/trades":           {Summary: "List trades", Query: listOrdersQuery{}, Response: []db.Trade{}},
+	"POST /api/v1/orders":          {Summary: "Submit a manual order", Request: createOrderRequest{}, Response: gin.H{}, Status: http.StatusAccepted},
+	"POST /api/v1/orders/batch":    {Summary: "Submit up to 50 orders with a result per order", Request: []createOrderRequest{}, Response: batchOrderResponse{}},
+	"POST /api/v1/orders/simulate": {Summary: "Risk check and paper fill without placing an order", Request: createOrderRequest{}, Response: gin.H{}},
+
+	"GET /api/v1/positions":      {Summary: "Positions marked to the latest price", Response: []positionView{}},
+	"GET /api/v1/balance":        {Summary: "Account balance, with margin and liquidation prices per futures connection", Response: gin.H{}},
+	"GET /api/v1/equity":         {Summary: "Equity snapshots (oldest first)", Response: gin.H{}},
+	"GET /api/v1/pnl":            {Summary: "PnL per strategy and in total: gross, fees, funding and net", Query: pnlRangeQuery{}, Response: pnlAttributionResponse{}},
+	"GET /api/v1/leaderboard":    {Summary: "Opted-in users ranked by paper-trading return (dry-run only)", Query: leaderboardQuery{}, Response: leaderboardResponse{}},
+	"GET /api/v1/profile":        {Summary: "Public profile", Response: profileResponse{}},
+	"PUT /api/v1/profile":        {Summary: "Update display name and leaderboard opt-in", Request: updateProfileRequest{}, Response: profileResponse{}},
+	"GET /api/v1/risk":           {Summary: "Daily risk metrics", Response: engine.RiskMetrics{}},
+	"GET /api/v1/risk/config":    {Summary: "Account risk configuration", Response: risk.RiskConfig{}},
+	"PUT /api/v1/risk/config":    {Summary: "Update account risk configuration (session only)", Request: risk.RiskConfig{}, Response: risk.RiskConfig{}},
+	"GET /api/v1/prices":         {Summary: "Last price of every symbol", Response: []market.PriceSnapshot{}},
+	"GET /api/v1/prices/:symbol": {Summary: "Last price of a symbol", Response: market.PriceSnapshot{}},
+
+	"GET /api/v1/connections":           {Summary: "List exchange connections with their gateway health", Response: []gin.H{}},
+	"POST /api/v1/connections":          {Summary: "Add an exchange connection (session only)", Request: createConnectionRequest{}, Response: gin.H{}, Status: http.StatusCreated},
+	"DELETE /api/v1/connections/:id":    {Summary: "Deactivate a connection (session only)", Response: statusResponse{}},
+	"POST /api/v1/connections/:id/test": {Summary: "Check a connection's keys without placing an order", Response: gin.H{}},
+
+	"GET /api/v1/api-keys":        {Summary: "List API keys (session only)", Response: []apiKeyView{}},
+	"POST /api/v1/api-keys":       {Summary: "Create an API key; the key is only returned here (session only)", Request: createAPIKeyRequest{}, Response: createAPIKeyResponse{}, Status: http.StatusCreated},
+	"DELETE /api/v1/api-keys/:id": {Summary: "Revoke an API key (session only)", Response: statusResponse{}},
+
+	"POST /api/v1/admin/symbols/:symbol/halt":   {Summary: "Block new entries on a symbol; exits stay allowed (admin only)", Request: haltSymbolRequest{}, Response: risk.SymbolHalt{}},
+	"POST /api/v1/admin/symbols/:symbol/resume": {Summary: "Lift a symbol halt (admin only)", Response: gin.H{}},
+	"POST /api/v1/admin/strategies/freeze":      {Summary: "Block creating and starting strategies; running ones continue (admin only)", Request: freezeStrategiesRequest{}, Response: FreezeState{}},
+	"POST /api/v1/admin/strategies/unfreeze":    {Summary: "Allow creating and starting strategies again (admin only)", Response: FreezeState{}},
+
+	"GET /api/v1/admin/fills/quarantine":              {Summary: "List fills held for an abnormal price (admin only)", Response: gin.H{}},
+	"POST /api/v1/admin/fills/quarantine/:id/release": {Summary: "Book a quarantined fill at its reported price (admin only)", Response: gin.H{}},
+	"POST /api/v1/admin/fills/quarantine/:id/discard": {Summary: "Drop a quarantined fill without booking it (admin only)", Response: gin.H{}},
+	"GET /api/v1/admin/users/:id/limits":              {Summary: "A user's resource caps, leverage ceiling and symbol allow-list (admin only)", Response: userLimitsResponse{}},
+	"PUT /api/v1/admin/users/:id/limits":              {Summary: "Change a user's resource caps, leverage ceiling or symbol allow-list (admin only)", Request: updateUserLimitsRequest{}, Response: userLimitsResponse{}},
+}
+
+var pathParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// getOpenAPISpec serves an OpenAPI 3 document built from the router and routeDocs.
+func (s *Server) getOpenAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPISpec())
+}
+
+func (s *Server) openAPISpec() gin.H {
+	schemas := schemaSet{}
+	paths := gin.H{}
+	for _, r := range s.Router.Routes() {
+		doc := routeDocs[r.Method+" "+r.Path]
+		path := pathParamPattern.ReplaceAllString(r.Path, "{$1}")
+		item, _ := paths[path].(gin.H)
+		if item == nil {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = schemas.operation(r, doc)
+	}
+
+	version := s.Meta.Version
+	if version == "" {
+		version = "v1"
+	}
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "DES Trading Core API",
+			"version": version,
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth": gin.H{"type": "apiKey", "in": "header", "name": apiKeyHeader},
+			},
+		},
+	}
+}
+
+// operation builds the OpenAPI operation object for one route.
+func (g schemaSet) operation(r gin.RouteInfo, doc routeDoc) gin.H {
+	op := gin.H{"operationId": handlerName(r.Handler)}
+	if doc.Summary != "" {
+		op["summary"] = doc.Summary
+	}
+	apiRoute := strings.HasPrefix(r.Path, "/api/v1/")
+	if apiRoute {
+		op["tags"] = []string{strings.SplitN(strings.TrimPrefix(r.Path, "/api/v1/"), "/", 2)[0]}
+	} else {
+		op["tags"] = []string{"system"}
+	}
+
+	var params []gin.H
+	for _, m := range pathParamPattern.FindAllStringSubmatch(r.Path, -1) {
+		params = append(params, gin.H{"name": m[1], "in": "path", "required": true, "schema": gin.H{"type": "string"}})
+	}
+	if doc.Query != nil {
+		params = append(params, g.queryParams(reflect.TypeOf(doc.Query))...)
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if doc.Request != nil {
+		op["requestBody"] = gin.H{
+			"required": true,
+			"content":  gin.H{"application/json": gin.H{"schema": g.schema(reflect.TypeOf(doc.Request))}},
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := gin.H{"description": http.StatusText(status)}
+	if doc.Response != nil {
+		contentType := doc.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success["content"] = gin.H{contentType: gin.H{"schema": g.schema(reflect.TypeOf(doc.Response))}}
+	}
+	responses := gin.H{strconv.Itoa(status): success}
+	if apiRoute {
+		responses["default"] = gin.H{
+			"description": "Error",
+			"content":     gin.H{"application/json": gin.H{"schema": g.schema(reflect.TypeOf(errorResponse{}))}},
+		}
+	}
+	op["responses"] = responses
+
+	if apiRoute && !doc.Public {
+		op["security"] = []gin.H{{"bearerAuth": []string{}}, {"apiKeyAuth": []string{}}}
+	}
+	return op
+}
+
+// handlerName turns "trading-core/internal/api.(*Server).getOrders-fm" into "getOrders".
+func handlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// schemaSet collects named component schemas while types are reflected.
+type schemaSet gin.H
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema for t, registering named structs as components
+// and referencing them.
+func (g schemaSet) schema(t reflect.Type) gin.H {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case t == reflect.TypeOf(time.Duration(0)):
+		return gin.H{"type": "integer", "format": "int64", "description": "nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return gin.H{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return gin.H{"type": "string", "format": "byte"}
+		}
+		return gin.H{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := g[name]; !ok {
+			g[name] = gin.H{} // placeholder so recursive types terminate
+			g[name] = g.structSchema(t)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	default:
+		return gin.H{}
+	}
+}
+
+// schemaName is the type name, qualified by package outside package api.
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if pkg == reflect.TypeOf(Server{}).PkgPath() {
+		return t.Name()
+	}
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + t.Name()
+}
+
+// structSchema describes a struct the way encoding/json and gin's validator see it.
+func (g schemaSet) structSchema(t reflect.Type) gin.H {
+	props := gin.H{}
+	var required []string
+	g.addFields(t, props, &required)
+	out := gin.H{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+func (g schemaSet) addFields(t reflect.Type, props gin.H, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(ft, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var s gin.H
+		if strings.Contains(opts, "string") {
+			s = gin.H{"type": "string"}
+		} else {
+			s = g.schema(f.Type)
+		}
+		if applyBinding(s, f.Tag.Get("binding")) {
+			*required = append(*required, name)
+		}
+		props[name] = s
+	}
+}
+
+// applyBinding copies validator rules onto s and reports whether the field is required.
+func applyBinding(s gin.H, tag string) (required bool) {
+	if tag == "" || s["$ref"] != nil {
+		return strings.Contains(tag, "required")
+	}
+	str := s["type"] == "string"
+	for _, rule := range strings.Split(tag, ",") {
+		key, val, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "oneof":
+			s["enum"] = strings.Fields(val)
+		case "min", "max":
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case str && key == "min":
+				s["minLength"] = int(n)
+			case str:
+				s["maxLength"] = int(n)
+			case key == "min":
+				s["minimum"] = n
+			default:
+				s["maximum"] = n
+			}
+		case "gte", "gt":
+			if n, err := strconv.ParseFloat(val, 64); err == nil {
+				s["minimum"] = n
+				if key == "gt" {
+					s["exclusiveMinimum"] = true
+				}
+			}
+		case "lte", "lt":
+			if n, err := strconv.ParseFloat(val, 64); err == nil {
+				s["maximum"] = n
+				if key == "lt" {
+					s["exclusiveMaximum"] = true
+				}
+			}
+		}
+	}
+	return required
+}
+
+// queryParams lists the `form`-tagged fields of a query struct.
+func (g schemaSet) queryParams(t reflect.Type) []gin.H {
+	var out []gin.H
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		s := g.schema(f.Type)
+		out = append(out, gin.H{"name": name, "in": "query", "required": applyBinding(s, f.Tag.Get("binding")), "schema": s})
+	}
+	return out
+}
+
+// swaggerUI serves a Swagger UI page for /api/v1/openapi.json.
+func (s *Server) swaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>DES Trading Core API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/api/v1/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
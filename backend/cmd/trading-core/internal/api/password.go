@@ -0,0 +1,175 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms for PasswordConfig.Algorithm.
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+// PasswordConfig is the strength policy for new passwords and how they are
+// hashed. Zero numeric fields and an empty algorithm fall back to the
+// defaults below; the character-class rules are off unless set. Stored
+// hashes of either algorithm verify whatever the current setting.
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	Algorithm         string // HashArgon2id (default) or HashBcrypt
+	BcryptCost        int
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+const (
+	defaultPasswordMinLength = 8
+	maxPasswordLength        = 128 // bounds hashing work per request
+
+	// OWASP's baseline argon2id parameters: 19 MiB, 2 passes, 1 lane.
+	defaultArgon2Memory      = 19 * 1024
+	defaultArgon2Iterations  = 2
+	defaultArgon2Parallelism = 1
+	argon2SaltLen            = 16
+	argon2KeyLen             = 32
+)
+
+func (p PasswordConfig) withDefaults() PasswordConfig {
+	if p.MinLength <= 0 {
+		p.MinLength = defaultPasswordMinLength
+	}
+	p.Algorithm = strings.ToLower(strings.TrimSpace(p.Algorithm))
+	if p.Algorithm == "" {
+		p.Algorithm = HashArgon2id
+	}
+	if p.BcryptCost <= 0 {
+		p.BcryptCost = bcrypt.DefaultCost
+	}
+	if p.Argon2Memory == 0 {
+		p.Argon2Memory = defaultArgon2Memory
+	}
+	if p.Argon2Iterations == 0 {
+		p.Argon2Iterations = defaultArgon2Iterations
+	}
+	if p.Argon2Parallelism == 0 {
+		p.Argon2Parallelism = defaultArgon2Parallelism
+	}
+	return p
+}
+
+// Validate returns an error describing every rule password breaks, or nil.
+func (p PasswordConfig) Validate(password string) error {
+	p = p.withDefaults()
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	var missing []string
+	if n := len([]rune(password)); n < p.MinLength {
+		missing = append(missing, fmt.Sprintf("at least %d characters", p.MinLength))
+	} else if len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordLength)
+	}
+	if p.RequireUpper && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return errors.New("password must contain " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Hash hashes password with the configured algorithm. Argon2id hashes use
+// the PHC string format ($argon2id$v=19$m=...,t=...,p=...$salt$key).
+func (p PasswordConfig) Hash(password string) (string, error) {
+	p = p.withDefaults()
+	switch p.Algorithm {
+	case HashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	case HashArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Argon2Iterations, p.Argon2Memory, p.Argon2Parallelism, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+			p.Argon2Memory, p.Argon2Iterations, p.Argon2Parallelism,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm %q", p.Algorithm)
+	}
+}
+
+var errPasswordMismatch = errors.New("password does not match")
+
+// checkPassword verifies password against an argon2id or bcrypt hash.
+func checkPassword(hash, password string) error {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+	var (
+		version       int
+		memory, iters uint32
+		threads       uint8
+	)
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return errors.New("malformed argon2id hash")
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errors.New("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iters, &threads); err != nil {
+		return errors.New("malformed argon2id parameters")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return errors.New("malformed argon2id salt")
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return errors.New("malformed argon2id key")
+	}
+	got := argon2.IDKey([]byte(password), salt, iters, memory, threads, uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Readiness tracks whether the core's dependencies (the database) are up.
+// It backs /ready on the degraded handler served while startup waits for them.
+type Readiness struct {
+	mu     sync.RWMutex
+	ready  bool
+	reason string
+}
+
+// NewReadiness returns a Readiness that is not ready yet.
+func NewReadiness() *Readiness {
+	return &Readiness{reason: "starting"}
+}
+
+// MarkReady records that all dependencies are available.
+func (r *Readiness) MarkReady() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.reason = true, ""
+}
+
+// MarkUnavailable records why the core cannot serve yet.
+func (r *Readiness) MarkUnavailable(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.reason = false, err.Error()
+}
+
+// Ready reports readiness and, when not ready, the reason.
+func (r *Readiness) Ready() (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ready, r.reason
+}
+
+// NewDegradedHandler serves the API while the database is unavailable at
+// startup: /health stays 200 so the process is not restarted, /ready follows
+// r, and every other route answers 503 DB_UNAVAILABLE.
+func NewDegradedHandler(r *Readiness) http.Handler {
+	e := gin.New()
+	e.Use(gin.Recovery())
+	e.Use(RequestIDMiddleware())
+	e.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "degraded"})
+	})
+	e.GET("/ready", func(c *gin.Context) {
+		if ok, reason := r.Ready(); !ok {
+			respondError(c, "DB_UNAVAILABLE", "database unavailable: "+reason)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	})
+	e.NoRoute(func(c *gin.Context) {
+		respondError(c, "DB_UNAVAILABLE", "")
+	})
+	return e
+}
+
+// ready is the readiness probe of the full server: 503 while the database
+// does not answer a ping.
+func (s *Server) ready(c *gin.Context) {
+	if s.DB == nil || s.DB.DB == nil {
+		respondError(c, "DB_UNAVAILABLE", "")
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	if err := s.DB.DB.PingContext(ctx); err != nil {
+		respondError(c, "DB_UNAVAILABLE", "database unavailable: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -20,19 +21,60 @@ func (s *Server) websocket(c *gin.Context) {
 		return
 	}
 	defer conn.Close()
+	if !s.trackWebsocket() {
+		return
+	}
+	defer s.wsConns.Done()
 
 	if s.Bus == nil {
 		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"error":"bus not ready"}`))
 		return
 	}
 
-	stream, unsub := s.Bus.Subscribe(events.EventPriceTick, 100)
+	stream, unsub := s.Bus.SubscribeNamed(events.EventPriceTick, "api-ws", 100)
 	defer unsub()
 
-	for msg := range stream {
-		if err := conn.WriteJSON(msg); err != nil {
-			log.Printf("ws write error: %v", err)
+	// Replay last-known prices so the client is not blank until the next tick.
+	if s.Prices != nil {
+		for _, p := range s.Prices.All() {
+			if err := conn.WriteJSON(struct {
+				Symbol string
+				Close  float64
+			}{Symbol: p.Symbol, Close: p.Price}); err != nil {
+				log.Printf("ws write error: %v", err)
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-s.closing:
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
 			return
+		case msg, ok := <-stream:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				log.Printf("ws write error: %v", err)
+				return
+			}
 		}
 	}
 }
+
+// trackWebsocket registers an open connection so Shutdown can wait for it; it
+// reports false once shutdown has begun.
+func (s *Server) trackWebsocket() bool {
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+	select {
+	case <-s.closing:
+		return false
+	default:
+		s.wsConns.Add(1)
+		return true
+	}
+}
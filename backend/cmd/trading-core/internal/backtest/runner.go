@@ -0,0 +1,51 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"trading-core/internal/indicators"
+	"trading-core/internal/strategy"
+)
+
+// Run feeds every tick from src to strat in order, mirroring the live engine:
+// indicators are kept per symbol@interval and, with closedOnly, in-progress
+// bars are skipped. It returns the non-HOLD signals the strategy emitted.
+func Run(ctx context.Context, src DataSource, strat strategy.Strategy, ind *indicators.Engine, closedOnly bool) ([]strategy.Signal, error) {
+	var signals []strategy.Signal
+	for {
+		tick, err := src.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return signals, nil
+		}
+		if err != nil {
+			return signals, err
+		}
+		k := tick.Kline
+		if k.Close <= 0 || (closedOnly && !k.IsFinal) {
+			continue
+		}
+
+		key := k.Symbol
+		if k.Interval != "" {
+			key += "@" + k.Interval
+		}
+		vals := map[string]float64{}
+		if ind != nil {
+			vals = ind.Update(key, k.Close)
+		}
+
+		sig, err := strat.OnTick(k.Symbol, k.Close, vals)
+		if err != nil {
+			log.Printf("backtest: strategy %s error at %s: %v", strat.Name(), tick.Time.UTC().Format(time.RFC3339), err)
+			continue
+		}
+		if sig != nil && sig.Action != "HOLD" {
+			sig.StrategyID = strat.ID()
+			signals = append(signals, *sig)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package backtest
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"trading-core/internal/data"
+	"trading-core/internal/strategy"
+)
+
+type tickLog struct {
+	symbols []string
+	prices  []float64
+}
+
+func (l *tickLog) ID() string   { return "log" }
+func (l *tickLog) Name() string { return "log" }
+func (l *tickLog) OnTick(symbol string, price float64, _ map[string]float64) (*strategy.Signal, error) {
+	l.symbols = append(l.symbols, symbol)
+	l.prices = append(l.prices, price)
+	return nil, nil
+}
+func (l *tickLog) GetState() (json.RawMessage, error) { return nil, nil }
+func (l *tickLog) SetState(json.RawMessage) error     { return nil }
+
+func writeRecording(t *testing.T, dir, symbol string, recs []data.RecordedKline) data.ManifestEntry {
+	t.Helper()
+	name := filepath.Join(symbol, symbol+".jsonl")
+	if err := os.MkdirAll(filepath.Join(dir, symbol), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, r := range recs {
+		if err := enc.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return data.ManifestEntry{File: filepath.ToSlash(name), Symbol: symbol, Records: len(recs), Closed: true}
+}
+
+func TestFileDataSourceReplaysSymbolsInChronologicalOrder(t *testing.T) {
+	dir := t.TempDir()
+	m := data.Manifest{Version: 1, Files: []data.ManifestEntry{
+		writeRecording(t, dir, "BTCUSDT", []data.RecordedKline{
+			{Symbol: "BTCUSDT", Interval: "1m", Close: 100, Final: true, Received: 1000},
+			{Symbol: "BTCUSDT", Interval: "1m", Close: 101, Final: true, Received: 3000},
+			// gap: nothing between 3s and 9s
+			{Symbol: "BTCUSDT", Interval: "1m", Close: 102, Final: true, Received: 9000},
+		}),
+		writeRecording(t, dir, "ETHUSDT", []data.RecordedKline{
+			{Symbol: "ETHUSDT", Interval: "1m", Close: 10, Final: true, Received: 2000},
+			{Symbol: "ETHUSDT", Interval: "1m", Close: 11, Final: true, Received: 4000},
+		}),
+	}}
+	b, _ := json.Marshal(m)
+	if err := os.WriteFile(filepath.Join(dir, data.ManifestFile), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := NewFileDataSource(dir, nil, "1m")
+	if err != nil {
+		t.Fatalf("NewFileDataSource: %v", err)
+	}
+	if src.Len() != 5 {
+		t.Fatalf("loaded %d ticks, want 5", src.Len())
+	}
+
+	strat := &tickLog{}
+	if _, err := Run(context.Background(), src, strat, nil, true); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []float64{100, 10, 101, 11, 102}
+	if len(strat.prices) != len(want) {
+		t.Fatalf("strategy saw %v, want %v", strat.prices, want)
+	}
+	for i, p := range want {
+		if strat.prices[i] != p {
+			t.Fatalf("tick %d: price %v (%s), want %v; order %v", i, strat.prices[i], strat.symbols[i], p, strat.prices)
+		}
+	}
+}
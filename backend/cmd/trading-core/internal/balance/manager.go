@@ -6,6 +6,8 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"trading-core/pkg/money"
 )
 
 // ExchangeClient interface for getting balance
@@ -13,7 +15,9 @@ type ExchangeClient interface {
 	GetBalance(ctx context.Context) (Balance, error)
 }
 
-// Balance represents account balance
+// Balance represents account balance. Values are converted from the manager's
+// fixed-point ledger; exchange clients should parse their decimal strings with
+// money.Parse before converting.
 type Balance struct {
 	Total     float64
 	Available float64
@@ -28,11 +32,12 @@ type Manager struct {
 	mu           sync.RWMutex
 }
 
-// BalanceCache caches balance data
+// BalanceCache caches balance data in fixed-point so repeated lock/deduct/add
+// cycles do not accumulate float rounding error.
 type BalanceCache struct {
-	total     float64
-	available float64
-	locked    float64
+	total     money.Amount
+	available money.Amount
+	locked    money.Amount
 	lastSync  time.Time
 	mu        sync.RWMutex
 }
@@ -80,9 +85,9 @@ func (m *Manager) Sync(ctx context.Context) error {
 	}
 
 	m.cache.mu.Lock()
-	m.cache.total = balance.Total
-	m.cache.available = balance.Available
-	m.cache.locked = balance.Locked
+	m.cache.total = money.FromFloat(balance.Total)
+	m.cache.available = money.FromFloat(balance.Available)
+	m.cache.locked = money.FromFloat(balance.Locked)
 	m.cache.lastSync = time.Now()
 	m.cache.mu.Unlock()
 
@@ -96,7 +101,7 @@ func (m *Manager) Sync(ctx context.Context) error {
 func (m *Manager) GetAvailable() float64 {
 	m.cache.mu.RLock()
 	defer m.cache.mu.RUnlock()
-	return m.cache.available
+	return m.cache.available.Float64()
 }
 
 // Lock reserves balance for order
@@ -104,15 +109,16 @@ func (m *Manager) Lock(amount float64) error {
 	m.cache.mu.Lock()
 	defer m.cache.mu.Unlock()
 
-	if amount > m.cache.available {
+	amt := money.FromFloat(amount)
+	if amt > m.cache.available {
 		return fmt.Errorf("insufficient balance: need %.2f, have %.2f",
-			amount, m.cache.available)
+			amount, m.cache.available.Float64())
 	}
 
-	m.cache.available -= amount
-	m.cache.locked += amount
+	m.cache.available = m.cache.available.Sub(amt)
+	m.cache.locked = m.cache.locked.Add(amt)
 
-	log.Printf("🔒 Balance locked: %.2f (Available: %.2f)", amount, m.cache.available)
+	log.Printf("🔒 Balance locked: %.2f (Available: %s)", amount, m.cache.available)
 	return nil
 }
 
@@ -121,10 +127,11 @@ func (m *Manager) Unlock(amount float64) {
 	m.cache.mu.Lock()
 	defer m.cache.mu.Unlock()
 
-	m.cache.locked -= amount
-	m.cache.available += amount
+	amt := money.FromFloat(amount)
+	m.cache.locked = m.cache.locked.Sub(amt)
+	m.cache.available = m.cache.available.Add(amt)
 
-	log.Printf("🔓 Balance unlocked: %.2f (Available: %.2f)", amount, m.cache.available)
+	log.Printf("🔓 Balance unlocked: %.2f (Available: %s)", amount, m.cache.available)
 }
 
 // Deduct removes balance after order filled
@@ -132,10 +139,11 @@ func (m *Manager) Deduct(amount float64) {
 	m.cache.mu.Lock()
 	defer m.cache.mu.Unlock()
 
-	m.cache.locked -= amount
-	m.cache.total -= amount
+	amt := money.FromFloat(amount)
+	m.cache.locked = m.cache.locked.Sub(amt)
+	m.cache.total = m.cache.total.Sub(amt)
 
-	log.Printf("💸 Balance deducted: %.2f (Total: %.2f)", amount, m.cache.total)
+	log.Printf("💸 Balance deducted: %.2f (Total: %s)", amount, m.cache.total)
 }
 
 // Add adds balance (for sell orders)
@@ -143,10 +151,11 @@ func (m *Manager) Add(amount float64) {
 	m.cache.mu.Lock()
 	defer m.cache.mu.Unlock()
 
-	m.cache.total += amount
-	m.cache.available += amount
+	amt := money.FromFloat(amount)
+	m.cache.total = m.cache.total.Add(amt)
+	m.cache.available = m.cache.available.Add(amt)
 
-	log.Printf("💵 Balance added: %.2f (Total: %.2f)", amount, m.cache.total)
+	log.Printf("💵 Balance added: %.2f (Total: %s)", amount, m.cache.total)
 }
 
 // GetBalance returns current balance snapshot
@@ -155,9 +164,9 @@ func (m *Manager) GetBalance() Balance {
 	defer m.cache.mu.RUnlock()
 
 	return Balance{
-		Total:     m.cache.total,
-		Available: m.cache.available,
-		Locked:    m.cache.locked,
+		Total:     m.cache.total.Float64(),
+		Available: m.cache.available.Float64(),
+		Locked:    m.cache.locked.Float64(),
 	}
 }
 
@@ -166,9 +175,9 @@ func (m *Manager) SetInitialBalance(amount float64) {
 	m.cache.mu.Lock()
 	defer m.cache.mu.Unlock()
 
-	m.cache.total = amount
-	m.cache.available = amount
-	m.cache.locked = 0
+	m.cache.total = money.FromFloat(amount)
+	m.cache.available = m.cache.total
+	m.cache.locked = money.Zero
 
 	log.Printf("💰 Initial balance set: %.2f", amount)
 }
@@ -0,0 +1,21 @@
+package balance
+
+import "testing"
+
+func TestManagerAccumulatesWithoutFloatDrift(t *testing.T) {
+	m := NewManager(nil, 0)
+	m.SetInitialBalance(1000)
+
+	for i := 0; i < 1000; i++ {
+		if err := m.Lock(0.1); err != nil {
+			t.Fatalf("lock %d: %v", i, err)
+		}
+		m.Deduct(0.1)
+		m.Add(0.07)
+	}
+
+	got := m.GetBalance()
+	if got.Total != 970 || got.Available != 970 || got.Locked != 0 {
+		t.Fatalf("balance = %+v, want total=970 available=970 locked=0", got)
+	}
+}
@@ -0,0 +1,357 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	marketpkg "trading-core/pkg/market/binance"
+)
+
+// ManifestFile is the name of the index written at the root of a recording directory.
+const ManifestFile = "manifest.json"
+
+// RecordedKline is one line of a recording file (JSON Lines). Every stream
+// update is kept, including in-progress bars; Final marks closed bars.
+type RecordedKline struct {
+	Symbol    string  `json:"symbol"`
+	Interval  string  `json:"interval"`
+	OpenTime  int64   `json:"open_time"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	Volume    float64 `json:"volume"`
+	CloseTime int64   `json:"close_time"`
+	Final     bool    `json:"final"`
+	Received  int64   `json:"received"` // local receive time (ms)
+}
+
+// Kline converts the record into the candle type used for historical data.
+func (r RecordedKline) Kline() Kline {
+	return Kline{OpenTime: r.OpenTime, Open: r.Open, High: r.High, Low: r.Low, Close: r.Close, Volume: r.Volume}
+}
+
+// ManifestEntry describes one recording file.
+type ManifestEntry struct {
+	File    string `json:"file"` // relative to the recording directory
+	Symbol  string `json:"symbol"`
+	Start   int64  `json:"start"` // first/last receive time (ms)
+	End     int64  `json:"end"`
+	Records int    `json:"records"`
+	Bytes   int64  `json:"bytes"`
+	Closed  bool   `json:"closed"`
+}
+
+// Manifest lists recording files in the order they were written.
+type Manifest struct {
+	Version int             `json:"version"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// RecorderConfig controls what is recorded and how files rotate.
+type RecorderConfig struct {
+	Dir          string
+	Symbols      []string      // empty records every symbol
+	MaxFileBytes int64         // rotate once a file reaches this size (0 = no size limit)
+	RotateEvery  time.Duration // rotate files older than this (0 = no time limit)
+}
+
+type recordingFile struct {
+	f      *os.File
+	w      *bufio.Writer
+	entry  int // index into manifest.Files
+	opened time.Time
+}
+
+// Recorder writes live klines to rotating per-symbol JSONL files with a
+// manifest, so sessions can be replayed by backtests.
+type Recorder struct {
+	cfg      RecorderConfig
+	symbols  map[string]bool
+	mu       sync.Mutex
+	files    map[string]*recordingFile
+	manifest Manifest
+	now      func() time.Time
+}
+
+// NewRecorder creates the recording directory and loads any existing manifest
+// so new files are appended to it.
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("recorder: directory required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("recorder: create dir: %w", err)
+	}
+	m, err := LoadManifest(cfg.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	m.Version = 1
+	for i := range m.Files {
+		m.Files[i].Closed = true
+	}
+
+	r := &Recorder{
+		cfg:      cfg,
+		files:    make(map[string]*recordingFile),
+		manifest: m,
+		now:      time.Now,
+	}
+	if len(cfg.Symbols) > 0 {
+		r.symbols = make(map[string]bool, len(cfg.Symbols))
+		for _, s := range cfg.Symbols {
+			r.symbols[strings.ToUpper(s)] = true
+		}
+	}
+	return r, nil
+}
+
+// Run records klines from a price-tick subscription until ctx is done or the
+// channel closes, then closes all files.
+func (r *Recorder) Run(ctx context.Context, ticks <-chan any) {
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Printf("tick recorder close error: %v", err)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-ticks:
+			if !ok {
+				return
+			}
+			k, ok := ev.(marketpkg.Kline)
+			if !ok {
+				continue
+			}
+			if err := r.Record(k); err != nil {
+				log.Printf("tick recorder: %v", err)
+			}
+		}
+	}
+}
+
+// Record appends a kline for a configured symbol; others are ignored.
+func (r *Recorder) Record(k marketpkg.Kline) error {
+	symbol := strings.ToUpper(k.Symbol)
+	if symbol == "" || (r.symbols != nil && !r.symbols[symbol]) {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	rec := RecordedKline{
+		Symbol:    symbol,
+		Interval:  k.Interval,
+		OpenTime:  k.OpenTime,
+		Open:      k.Open,
+		High:      k.High,
+		Low:       k.Low,
+		Close:     k.Close,
+		Volume:    k.Volume,
+		CloseTime: k.CloseTime,
+		Final:     k.IsFinal,
+		Received:  now.UnixMilli(),
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	rf, err := r.fileFor(symbol, now)
+	if err != nil {
+		return err
+	}
+	if _, err := rf.w.Write(line); err != nil {
+		return fmt.Errorf("write %s: %w", symbol, err)
+	}
+
+	e := &r.manifest.Files[rf.entry]
+	if e.Records == 0 {
+		e.Start = rec.Received
+	}
+	e.End = rec.Received
+	e.Records++
+	e.Bytes += int64(len(line))
+	return nil
+}
+
+// fileFor returns the open file for symbol, rotating it when it is too large or old.
+func (r *Recorder) fileFor(symbol string, now time.Time) (*recordingFile, error) {
+	if rf, ok := r.files[symbol]; ok {
+		e := r.manifest.Files[rf.entry]
+		tooBig := r.cfg.MaxFileBytes > 0 && e.Bytes >= r.cfg.MaxFileBytes
+		tooOld := r.cfg.RotateEvery > 0 && now.Sub(rf.opened) >= r.cfg.RotateEvery
+		if !tooBig && !tooOld {
+			return rf, nil
+		}
+		if err := r.closeFile(symbol); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(r.cfg.Dir, symbol)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create %s: %w", dir, err)
+	}
+	base := fmt.Sprintf("%s-%s", symbol, now.UTC().Format("20060102T150405Z"))
+	name := base + ".jsonl"
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d.jsonl", base, i)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+
+	r.manifest.Files = append(r.manifest.Files, ManifestEntry{
+		File:   filepath.ToSlash(filepath.Join(symbol, name)),
+		Symbol: symbol,
+	})
+	rf := &recordingFile{f: f, w: bufio.NewWriter(f), entry: len(r.manifest.Files) - 1, opened: now}
+	r.files[symbol] = rf
+	if err := r.writeManifest(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (r *Recorder) closeFile(symbol string) error {
+	rf := r.files[symbol]
+	delete(r.files, symbol)
+	if err := rf.w.Flush(); err != nil {
+		rf.f.Close()
+		return err
+	}
+	if err := rf.f.Close(); err != nil {
+		return err
+	}
+	r.manifest.Files[rf.entry].Closed = true
+	return r.writeManifest()
+}
+
+// Flush writes buffered records to disk and refreshes the manifest.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rf := range r.files {
+		if err := rf.w.Flush(); err != nil {
+			return err
+		}
+	}
+	return r.writeManifest()
+}
+
+// Close flushes and closes every open file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var firstErr error
+	for symbol := range r.files {
+		if err := r.closeFile(symbol); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *Recorder) writeManifest() error {
+	b, err := json.MarshalIndent(r.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.cfg.Dir, ManifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadManifest reads the manifest of a recording directory.
+func LoadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	b, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("decode manifest: %w", err)
+	}
+	return m, nil
+}
+
+// ReadRecording returns every recorded update for symbol, in recording order.
+func ReadRecording(dir, symbol string) ([]RecordedKline, error) {
+	m, err := LoadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	symbol = strings.ToUpper(symbol)
+
+	var out []RecordedKline
+	for _, e := range m.Files {
+		if e.Symbol != symbol {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(e.File)))
+		if err != nil {
+			return nil, err
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var rec RecordedKline
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s: %w", e.File, err)
+			}
+			out = append(out, rec)
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ReadKlines returns the closed bars recorded for symbol at interval, ordered by
+// open time with duplicates dropped, in the same form as GetKlines.
+func ReadKlines(dir, symbol, interval string) ([]Kline, error) {
+	recs, err := ReadRecording(dir, symbol)
+	if err != nil {
+		return nil, err
+	}
+	var out []Kline
+	for _, rec := range recs {
+		if !rec.Final || rec.Interval != interval {
+			continue
+		}
+		if n := len(out); n > 0 && rec.OpenTime <= out[n-1].OpenTime {
+			if rec.OpenTime == out[n-1].OpenTime {
+				out[n-1] = rec.Kline()
+			}
+			continue
+		}
+		out = append(out, rec.Kline())
+	}
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	marketpkg "trading-core/pkg/market/binance"
+)
+
+func TestRecorderRoundTripsTicksAcrossRotation(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(RecorderConfig{Dir: dir, Symbols: []string{"BTCUSDT"}, RotateEvery: time.Minute})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	now := time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)
+	rec.now = func() time.Time { return now }
+
+	ticks := []marketpkg.Kline{
+		{Symbol: "BTCUSDT", Interval: "1m", OpenTime: 0, Close: 100},
+		{Symbol: "BTCUSDT", Interval: "1m", OpenTime: 0, Close: 101, IsFinal: true},
+		{Symbol: "ETHUSDT", Interval: "1m", OpenTime: 0, Close: 5, IsFinal: true}, // not recorded
+		{Symbol: "BTCUSDT", Interval: "1m", OpenTime: 60000, Close: 102, IsFinal: true},
+	}
+	for i, k := range ticks {
+		if i == 3 {
+			now = now.Add(2 * time.Minute) // forces rotation
+		}
+		if err := rec.Record(k); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if len(m.Files) != 2 || m.Files[0].Records != 2 || m.Files[1].Records != 1 || !m.Files[1].Closed {
+		t.Fatalf("unexpected manifest: %+v", m.Files)
+	}
+
+	all, err := ReadRecording(dir, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("ReadRecording: %v", err)
+	}
+	if len(all) != 3 || all[0].Close != 100 || all[0].Final {
+		t.Fatalf("unexpected recording: %+v", all)
+	}
+
+	bars, err := ReadKlines(dir, "BTCUSDT", "1m")
+	if err != nil {
+		t.Fatalf("ReadKlines: %v", err)
+	}
+	if len(bars) != 2 || bars[0].Close != 101 || bars[1].OpenTime != 60000 {
+		t.Fatalf("unexpected bars: %+v", bars)
+	}
+}
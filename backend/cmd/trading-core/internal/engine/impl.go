@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"trading-core/internal/balance"
@@ -15,12 +17,16 @@ import (
 	"trading-core/pkg/db"
 )
 
+// ErrNoPosition is returned by PanicSellStrategy when the strategy is flat.
+var ErrNoPosition = errors.New("no position to close")
+
 // Impl implements the Service interface by composing existing modules.
 type Impl struct {
 	stratEngine *strategy.Engine
 	riskMgr     *risk.Manager
 	balanceMgr  *balance.Manager
 	orderQueue  order.OrderQueue
+	ladders     *order.LadderScheduler
 	bus         *events.Bus
 	db          *db.Database
 
@@ -37,6 +43,7 @@ type Config struct {
 	RiskMgr     *risk.Manager
 	BalanceMgr  *balance.Manager
 	OrderQueue  order.OrderQueue
+	Ladders     *order.LadderScheduler // optional; cancels a strategy's tranches on stop/panic-sell
 	Bus         *events.Bus
 	DB          *db.Database
 	Meta        SystemStatus
@@ -52,6 +59,7 @@ func NewImpl(cfg Config) *Impl {
 		riskMgr:          cfg.RiskMgr,
 		balanceMgr:       cfg.BalanceMgr,
 		orderQueue:       cfg.OrderQueue,
+		ladders:          cfg.Ladders,
 		bus:              cfg.Bus,
 		db:               cfg.DB,
 		meta:             cfg.Meta,
@@ -79,7 +87,22 @@ func (e *Impl) StopStrategy(ctx context.Context, id string) error {
 	if e.stratEngine == nil {
 		return fmt.Errorf("strategy engine not available")
 	}
-	return e.stratEngine.StopStrategy(id)
+	if err := e.stratEngine.StopStrategy(id); err != nil {
+		return err
+	}
+	e.cancelLadders(ctx, id)
+	return nil
+}
+
+// cancelLadders drops a strategy's pending ladder tranches and cancels its
+// resting ones.
+func (e *Impl) cancelLadders(ctx context.Context, id string) {
+	if e.ladders == nil {
+		return
+	}
+	if err := e.ladders.CancelStrategy(ctx, id); err != nil {
+		log.Printf("⚠️ Strategy %s: ladder tranches not all cancelled: %v", id, err)
+	}
 }
 
 func (e *Impl) PanicSellStrategy(ctx context.Context, id string, userID string) error {
@@ -87,6 +110,9 @@ func (e *Impl) PanicSellStrategy(ctx context.Context, id string, userID string)
 		return fmt.Errorf("strategy engine not available")
 	}
 
+	// Tranches still to come or resting would rebuild the position.
+	e.cancelLadders(ctx, id)
+
 	// Get current position
 	qty, err := e.stratEngine.GetStrategyPosition(id)
 	if err != nil {
@@ -94,7 +120,7 @@ func (e *Impl) PanicSellStrategy(ctx context.Context, id string, userID string)
 	}
 
 	if qty == 0 {
-		return fmt.Errorf("no position to close")
+		return ErrNoPosition
 	}
 
 	// Determine side and submit close order
@@ -311,26 +337,10 @@ func (e *Impl) GetRiskMetrics(ctx context.Context) (*RiskMetrics, error) {
 }
 
 func (e *Impl) GetStrategyPerformance(ctx context.Context, id string, from, to time.Time) (*Performance, error) {
-	rows, err := e.db.DB.QueryContext(ctx, `
-		SELECT 
-			date(t.created_at) as d,
-			SUM(
-				CASE 
-					WHEN UPPER(o.side) = 'SELL' THEN (t.price * t.qty)
-					ELSE -(t.price * t.qty)
-				END - t.fee
-			) as pnl
-		FROM trades t
-		JOIN orders o ON t.order_id = o.id
-		WHERE o.strategy_instance_id = ?
-		  AND t.created_at >= ? AND t.created_at <= ?
-		GROUP BY date(t.created_at)
-		ORDER BY d ASC
-	`, id, from, to)
+	realized, err := e.db.StrategyDailyRealizedPnL(ctx, id, from, to)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	perf := &Performance{
 		StrategyID: id,
@@ -339,14 +349,9 @@ func (e *Impl) GetStrategyPerformance(ctx context.Context, id string, from, to t
 	}
 
 	var equity float64
-	for rows.Next() {
-		var d string
-		var pnl float64
-		if err := rows.Scan(&d, &pnl); err != nil {
-			continue
-		}
-		equity += pnl
-		perf.Daily = append(perf.Daily, DailyPnL{Date: d, PnL: pnl, Equity: equity})
+	for _, r := range realized {
+		equity += r.PnL
+		perf.Daily = append(perf.Daily, DailyPnL{Date: r.Date, PnL: r.PnL, Equity: equity})
 	}
 	perf.TotalPnL = equity
 
@@ -0,0 +1,150 @@
+package equity
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"trading-core/pkg/db"
+	"trading-core/pkg/money"
+)
+
+// FundingPayment is one funding settlement reported by the exchange. Amount is
+// in the reporting currency, negative when paid.
+type FundingPayment struct {
+	ID           string // unique per exchange record
+	UserID       string // owner of ConnectionID
+	ConnectionID string // empty for the default gateway
+	Symbol       string
+	Amount       float64
+	Asset        string
+	Time         time.Time
+}
+
+// FundingSource returns the most recent funding payments of the default
+// gateway and of users' futures connections.
+type FundingSource func(ctx context.Context) ([]FundingPayment, error)
+
+// FundingSync imports funding payments into income_records and attributes
+// each one to the strategies holding the symbol on the payment's connection,
+// split by position size. Attribution uses the holdings at import time, so
+// the sync should run well within one funding period of each settlement.
+type FundingSync struct {
+	db       *db.Database
+	source   FundingSource
+	interval time.Duration
+}
+
+// NewFundingSync creates a sync; interval defaults to 10 minutes.
+func NewFundingSync(database *db.Database, source FundingSource, interval time.Duration) *FundingSync {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &FundingSync{db: database, source: source, interval: interval}
+}
+
+// Start syncs immediately and then every interval until ctx is canceled.
+func (f *FundingSync) Start(ctx context.Context) {
+	if f.db == nil || f.source == nil {
+		log.Println("funding sync not fully configured; skipping")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			if n, err := f.Sync(ctx); err != nil {
+				log.Printf("❌ Funding sync error: %v", err)
+			} else if n > 0 {
+				log.Printf("💸 Imported %d funding payment(s)", n)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("✓ Funding sync started (interval: %v)", f.interval)
+}
+
+// Sync imports payments not seen before and returns how many were imported.
+// A payment on a symbol no strategy holds is stored unattributed (to the
+// connection's owner, if any). Each payment's records are written in one
+// transaction, so a failed import is retried whole on the next sync.
+func (f *FundingSync) Sync(ctx context.Context) (int, error) {
+	payments, err := f.source(ctx)
+	if err != nil {
+		return 0, err
+	}
+	imported := 0
+	for _, p := range payments {
+		seen, err := f.db.IncomeSourceImported(ctx, p.ID)
+		if err != nil {
+			return imported, err
+		}
+		if seen {
+			continue
+		}
+		holdings, err := f.db.StrategyHoldings(ctx, p.Symbol, p.ConnectionID)
+		if err != nil {
+			return imported, err
+		}
+		err = f.db.WithTx(ctx, func(tx *db.Tx) error {
+			for _, r := range attributeFunding(p, holdings) {
+				if _, err := tx.CreateIncomeRecord(ctx, r); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// attributeFunding splits p across holdings by absolute quantity. The last
+// share takes the rounding remainder so the parts sum to p.Amount exactly.
+func attributeFunding(p FundingPayment, holdings []db.StrategyHolding) []db.IncomeRecord {
+	base := db.IncomeRecord{
+		ID:           p.ID,
+		SourceID:     p.ID,
+		UserID:       p.UserID,
+		ConnectionID: p.ConnectionID,
+		Symbol:       p.Symbol,
+		IncomeType:   db.IncomeFundingFee,
+		Amount:       p.Amount,
+		Asset:        p.Asset,
+		CreatedAt:    p.Time,
+	}
+	var total float64
+	for _, h := range holdings {
+		total += math.Abs(h.Qty)
+	}
+	if total == 0 {
+		return []db.IncomeRecord{base}
+	}
+
+	out := make([]db.IncomeRecord, 0, len(holdings))
+	remaining := money.FromFloat(p.Amount)
+	for i, h := range holdings {
+		r := base
+		r.ID = p.ID + ":" + h.StrategyInstanceID
+		r.UserID = h.UserID
+		r.StrategyInstanceID = h.StrategyInstanceID
+		if i == len(holdings)-1 {
+			r.Amount = remaining.Float64()
+		} else {
+			r.Amount = money.FromFloat(p.Amount * math.Abs(h.Qty) / total).Float64()
+			remaining = remaining.Sub(money.FromFloat(r.Amount))
+		}
+		out = append(out, r)
+	}
+	return out
+}
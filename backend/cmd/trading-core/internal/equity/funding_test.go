@@ -0,0 +1,68 @@
+package equity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+func TestFundingSyncAttributesConnectionPayments(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	// One strategy on the default gateway and one on u1's connection c1 hold BTCUSDT.
+	if _, err := database.DB.ExecContext(ctx, `
+		INSERT INTO strategy_instances (id, name, strategy_type, symbol, interval, parameters, user_id, connection_id)
+		VALUES ('global', 'g', 'ma', 'BTCUSDT', '1m', '{}', '', ''),
+		       ('s1', 's1', 'ma', 'BTCUSDT', '1m', '{}', 'u1', 'c1');
+		INSERT INTO strategy_positions (strategy_instance_id, symbol, qty, avg_price)
+		VALUES ('global', 'BTCUSDT', 1, 60000), ('s1', 'BTCUSDT', 0.5, 60000);
+	`); err != nil {
+		t.Fatalf("seed strategies: %v", err)
+	}
+
+	payments := []FundingPayment{
+		{ID: "c1:7", UserID: "u1", ConnectionID: "c1", Symbol: "BTCUSDT", Amount: -1.5, Asset: "USDT", Time: time.Now().UTC()},
+		{ID: "c1:8", UserID: "u1", ConnectionID: "c1", Symbol: "ETHUSDT", Amount: 0.2, Asset: "USDT", Time: time.Now().UTC()},
+	}
+	sync := NewFundingSync(database, func(ctx context.Context) ([]FundingPayment, error) { return payments, nil }, time.Minute)
+	if n, err := sync.Sync(ctx); err != nil || n != 2 {
+		t.Fatalf("Sync = %d, %v; want 2 imported", n, err)
+	}
+	if n, err := sync.Sync(ctx); err != nil || n != 0 {
+		t.Fatalf("second Sync = %d, %v; want nothing new", n, err)
+	}
+
+	rows, err := database.DB.QueryContext(ctx, `SELECT source_id, user_id, COALESCE(strategy_instance_id, ''), connection_id, amount FROM income_records ORDER BY source_id`)
+	if err != nil {
+		t.Fatalf("query income: %v", err)
+	}
+	defer rows.Close()
+	type record struct {
+		source, user, strategy, conn string
+		amount                       float64
+	}
+	var got []record
+	for rows.Next() {
+		var r record
+		if err := rows.Scan(&r.source, &r.user, &r.strategy, &r.conn, &r.amount); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, r)
+	}
+	want := []record{
+		{"c1:7", "u1", "s1", "c1", -1.5}, // only the connection's strategy, not the global one
+		{"c1:8", "u1", "", "c1", 0.2},    // unheld symbol: kept for the connection's owner
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("income records = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+// Package equity records periodic account equity snapshots for equity-curve reporting.
+package equity
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"trading-core/internal/balance"
+	"trading-core/internal/state"
+	"trading-core/pkg/db"
+)
+
+// PriceFunc returns the latest mark price for a symbol (0 if unknown).
+type PriceFunc func(symbol string) float64
+
+// MarginedFunc reports whether a user's positions are margined futures,
+// whose balance is a wallet balance rather than cash spent on the holdings.
+type MarginedFunc func(ctx context.Context, userID string) bool
+
+// Snapshotter periodically stores balance + marked positions per user. Spot
+// positions count at their marked notional; futures positions (see Margined)
+// count at their unrealized PnL, so equity is wallet balance + unrealized PnL.
+type Snapshotter struct {
+	db       *db.Database
+	balances *balance.MultiUserManager
+	prices   PriceFunc
+	interval time.Duration
+
+	// Margined selects futures valuation for a user (nil = spot for everyone).
+	Margined MarginedFunc
+}
+
+// NewSnapshotter creates a snapshotter; interval defaults to 5 minutes.
+func NewSnapshotter(database *db.Database, balances *balance.MultiUserManager, prices PriceFunc, interval time.Duration) *Snapshotter {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &Snapshotter{
+		db:       database,
+		balances: balances,
+		prices:   prices,
+		interval: interval,
+	}
+}
+
+// Start begins periodic snapshotting until ctx is canceled.
+func (s *Snapshotter) Start(ctx context.Context) {
+	if s.db == nil || s.balances == nil {
+		log.Println("equity snapshotter not fully configured; skipping")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.SnapshotAll(ctx); err != nil {
+					log.Printf("❌ Equity snapshot error: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("✓ Equity snapshotter started (interval: %v)", s.interval)
+}
+
+// SnapshotAll records one snapshot for every user with an active balance
+// manager. A user whose snapshot fails is logged and skipped; the first error
+// is returned once every user was tried.
+func (s *Snapshotter) SnapshotAll(ctx context.Context) error {
+	now := time.Now().UTC()
+	var firstErr error
+	for userID, bal := range s.balances.GetAllBalances() {
+		if err := s.snapshotUser(ctx, userID, bal, now); err != nil {
+			log.Printf("⚠️ Equity snapshot for user %s failed: %v", userID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (s *Snapshotter) snapshotUser(ctx context.Context, userID string, bal balance.Balance, at time.Time) error {
+	positions, err := s.db.Queries().GetPositionsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	margined := s.Margined != nil && s.Margined(ctx, userID)
+	var positionValue float64
+	for _, p := range positions {
+		mark := 0.0
+		if s.prices != nil {
+			mark = s.prices(p.Symbol)
+		}
+		if mark <= 0 {
+			mark = p.AvgPrice // fall back to entry when no live price is cached
+		}
+		if margined {
+			positionValue += state.UnrealizedPnL(p.Qty, p.AvgPrice, mark)
+		} else {
+			positionValue += p.Qty * mark
+		}
+	}
+
+	return s.db.Queries().CreateEquitySnapshot(ctx, db.EquitySnapshot{
+		UserID:        userID,
+		Balance:       bal.Total,
+		PositionValue: positionValue,
+		Equity:        bal.Total + positionValue,
+		CreatedAt:     at,
+	})
+}
@@ -0,0 +1,53 @@
+package equity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"trading-core/internal/balance"
+	"trading-core/pkg/db"
+)
+
+func TestSnapshotValuesFuturesAtUnrealizedPnL(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	balances := balance.NewMultiUserManager(func(userID string) (*balance.Manager, error) {
+		m := balance.NewManager(nil, 30*time.Second)
+		m.SetInitialBalance(1000)
+		return m, nil
+	})
+	for _, u := range []string{"spot", "fut"} {
+		if _, err := balances.GetOrCreate(u); err != nil {
+			t.Fatalf("GetOrCreate(%s): %v", u, err)
+		}
+		if err := database.Queries().UpsertPositionWithUser(ctx, u, "BTCUSDT", 0.1, 50000); err != nil {
+			t.Fatalf("UpsertPositionWithUser(%s): %v", u, err)
+		}
+	}
+
+	s := NewSnapshotter(database, balances, func(string) float64 { return 51000 }, time.Minute)
+	s.Margined = func(ctx context.Context, userID string) bool { return userID == "fut" }
+	if err := s.SnapshotAll(ctx); err != nil {
+		t.Fatalf("SnapshotAll: %v", err)
+	}
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for user, want := range map[string]float64{"spot": 1000 + 0.1*51000, "fut": 1000 + 0.1*1000} {
+		snaps, err := database.Queries().GetEquitySnapshotsByUser(ctx, user, from, to)
+		if err != nil || len(snaps) != 1 {
+			t.Fatalf("%s snapshots = %+v (err %v), want one", user, snaps, err)
+		}
+		if got := snaps[0].Equity; got < want-1e-6 || got > want+1e-6 {
+			t.Fatalf("%s equity = %v, want %v", user, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Alert is a structured EventRiskAlert payload. Type, UserID, Symbol and Key
+// form the key used to collapse repeats (see Bus.SetDedupWindow).
+type Alert struct {
+	Type    string    `json:"type"` // e.g. "risk_rejected"
+	UserID  string    `json:"user_id,omitempty"`
+	Symbol  string    `json:"symbol,omitempty"`
+	Key     string    `json:"key,omitempty"` // other subject telling alerts apart, e.g. a strategy ID
+	Message string    `json:"message"`
+	Count   int       `json:"count,omitempty"`    // set on aggregated alerts: repeats collapsed into this one
+	FirstAt time.Time `json:"first_at,omitempty"` // first collapsed repeat (aggregated alerts only)
+	LastAt  time.Time `json:"last_at,omitempty"`
+}
+
+func (a Alert) String() string {
+	if a.Count > 0 {
+		return fmt.Sprintf("%s (repeated %d times since %s)", a.Message, a.Count, a.FirstAt.Format(time.RFC3339))
+	}
+	return a.Message
+}
+
+// deduper passes the first payload per key and collapses repeats within the
+// window into one aggregated payload delivered when the window closes.
+type deduper struct {
+	window  time.Duration
+	deliver func(any)
+
+	mu   sync.Mutex
+	open map[string]*dedupGroup
+}
+
+type dedupGroup struct {
+	last    any
+	repeats int
+	firstAt time.Time
+	lastAt  time.Time
+}
+
+func newDeduper(window time.Duration, deliver func(any)) *deduper {
+	return &deduper{window: window, deliver: deliver, open: make(map[string]*dedupGroup)}
+}
+
+// admit reports whether payload should be delivered now.
+func (d *deduper) admit(payload any) bool {
+	key := dedupKey(payload)
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if g := d.open[key]; g != nil {
+		if g.repeats == 0 {
+			g.firstAt = now
+		}
+		g.repeats++
+		g.last, g.lastAt = payload, now
+		return false
+	}
+	d.open[key] = &dedupGroup{}
+	time.AfterFunc(d.window, func() { d.flush(key) })
+	return true
+}
+
+func (d *deduper) flush(key string) {
+	d.mu.Lock()
+	g := d.open[key]
+	delete(d.open, key)
+	d.mu.Unlock()
+
+	if g == nil || g.repeats == 0 {
+		return
+	}
+	switch p := g.last.(type) {
+	case Alert:
+		p.Count, p.FirstAt, p.LastAt = g.repeats, g.firstAt, g.lastAt
+		d.deliver(p)
+	case string:
+		d.deliver(fmt.Sprintf("%s (repeated %d times in %s)", p, g.repeats, d.window))
+	default:
+		d.deliver(g.last)
+	}
+}
+
+func dedupKey(payload any) string {
+	if a, ok := payload.(Alert); ok {
+		return "alert|" + a.Type + "|" + a.UserID + "|" + a.Symbol + "|" + a.Key
+	}
+	return fmt.Sprintf("%T|%v", payload, payload)
+}
@@ -1,53 +1,186 @@
 package events
 
 import (
+	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	// DefaultBufferSize is used when a subscription does not request a buffer.
+	DefaultBufferSize = 100
+
+	// slowFillRatio is the channel fill level treated as "near full".
+	slowFillRatio = 0.8
+	// slowStreak is how many consecutive near-full publishes mark a consumer as lagging.
+	slowStreak = 10
+	// slowWarnInterval rate-limits warnings per subscriber.
+	slowWarnInterval = 30 * time.Second
+)
+
+// SlowConsumer describes a subscriber that is falling behind its topic.
+type SlowConsumer struct {
+	Event   Event
+	Name    string
+	Len     int
+	Cap     int
+	Dropped uint64
+}
+
+type subscriber struct {
+	ch   chan any
+	name string
+
+	streak      atomic.Int64
+	dropped     atomic.Uint64
+	warnedDrops atomic.Uint64 // dropped as of the last warning
+	lastWarn    atomic.Int64  // unix nanos
+}
+
 // Bus is a lightweight pub/sub broker using channels.
 type Bus struct {
 	mu   sync.RWMutex
-	subs map[Event][]chan any
+	subs map[Event][]*subscriber
+
+	defaultBuffer int
+	onSlow        func(SlowConsumer)
+	dedup         map[Event]*deduper
 }
 
 // NewBus creates an event bus.
 func NewBus() *Bus {
-	return &Bus{subs: make(map[Event][]chan any)}
+	return &Bus{subs: make(map[Event][]*subscriber), defaultBuffer: DefaultBufferSize}
+}
+
+// SetDefaultBuffer sets the buffer used by subscriptions that pass buffer <= 0.
+func (b *Bus) SetDefaultBuffer(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n > 0 {
+		b.defaultBuffer = n
+	}
+}
+
+// SetSlowConsumerHandler registers a callback invoked (outside the bus lock) when a
+// subscriber stays near-full or drops events. Warnings are always logged.
+func (b *Bus) SetSlowConsumerHandler(fn func(SlowConsumer)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onSlow = fn
+}
+
+// SetDedupWindow collapses repeated payloads published on e within window:
+// the first is delivered at once, later repeats (same Alert type/user/symbol,
+// or identical payload otherwise) are delivered as one aggregated payload
+// when the window closes. A window <= 0 turns deduplication off.
+func (b *Bus) SetDedupWindow(e Event, window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if window <= 0 {
+		delete(b.dedup, e)
+		return
+	}
+	if b.dedup == nil {
+		b.dedup = make(map[Event]*deduper)
+	}
+	b.dedup[e] = newDeduper(window, func(payload any) { b.deliver(e, payload) })
 }
 
 // Subscribe registers a listener for an event and returns the channel and an unsubscribe function.
 func (b *Bus) Subscribe(e Event, buffer int) (<-chan any, func()) {
+	return b.SubscribeNamed(e, "", buffer)
+}
+
+// SubscribeNamed is Subscribe with a consumer name used in slow-consumer warnings.
+// A buffer <= 0 uses the bus default.
+func (b *Bus) SubscribeNamed(e Event, name string, buffer int) (<-chan any, func()) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	ch := make(chan any, buffer)
-	b.subs[e] = append(b.subs[e], ch)
+	if buffer <= 0 {
+		buffer = b.defaultBuffer
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+	sub := &subscriber{ch: make(chan any, buffer), name: name}
+	b.subs[e] = append(b.subs[e], sub)
 
 	unsub := func() {
 		b.mu.Lock()
 		defer b.mu.Unlock()
 		subs := b.subs[e]
-		for i, c := range subs {
-			if c == ch {
-				close(c)
+		for i, s := range subs {
+			if s == sub {
+				close(s.ch)
 				b.subs[e] = append(subs[:i], subs[i+1:]...)
 				break
 			}
 		}
 	}
 
-	return ch, unsub
+	return sub.ch, unsub
 }
 
 // Publish fan-outs the payload to subscribers asynchronously to avoid blocking.
 func (b *Bus) Publish(e Event, payload any) {
 	b.mu.RLock()
-	defer b.mu.RUnlock()
-	for _, ch := range b.subs[e] {
+	d := b.dedup[e]
+	b.mu.RUnlock()
+	if d != nil && !d.admit(payload) {
+		return
+	}
+	b.deliver(e, payload)
+}
+
+func (b *Bus) deliver(e Event, payload any) {
+	var slow []SlowConsumer
+
+	b.mu.RLock()
+	onSlow := b.onSlow
+	for _, s := range b.subs[e] {
 		select {
-		case ch <- payload:
+		case s.ch <- payload:
 		default:
 			// drop if subscriber is slow; keep broker non-blocking
+			s.dropped.Add(1)
 		}
+		if sc, ok := s.checkSlow(e); ok {
+			slow = append(slow, sc)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sc := range slow {
+		log.Printf("⚠️ Slow event consumer %q on %s: %d/%d buffered, %d dropped", sc.Name, sc.Event, sc.Len, sc.Cap, sc.Dropped)
+		if onSlow != nil {
+			onSlow(sc)
+		}
+	}
+}
+
+// checkSlow updates the near-full streak and reports whether a warning is due.
+func (s *subscriber) checkSlow(e Event) (SlowConsumer, bool) {
+	n, c := len(s.ch), cap(s.ch)
+	if c == 0 || float64(n) < float64(c)*slowFillRatio {
+		s.streak.Store(0)
+		return SlowConsumer{}, false
+	}
+	// Drops since the last warning bypass the streak; older ones don't.
+	dropped := s.dropped.Load()
+	if s.streak.Add(1) < slowStreak && dropped == s.warnedDrops.Load() {
+		return SlowConsumer{}, false
+	}
+
+	now := time.Now().UnixNano()
+	last := s.lastWarn.Load()
+	if last != 0 && now-last < int64(slowWarnInterval) {
+		return SlowConsumer{}, false
+	}
+	if !s.lastWarn.CompareAndSwap(last, now) {
+		return SlowConsumer{}, false
 	}
+	s.warnedDrops.Store(dropped)
+	return SlowConsumer{Event: e, Name: s.name, Len: n, Cap: c, Dropped: dropped}, true
 }
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowConsumerWarning(t *testing.T) {
+	bus := NewBus()
+	var warnings []SlowConsumer
+	bus.SetSlowConsumerHandler(func(sc SlowConsumer) { warnings = append(warnings, sc) })
+
+	// Never drained: fills up, then drops.
+	_, unsubSlow := bus.SubscribeNamed(EventPriceTick, "slow-strategy", 5)
+	defer unsubSlow()
+	fast, unsubFast := bus.SubscribeNamed(EventPriceTick, "fast", 5)
+	defer unsubFast()
+
+	for i := 0; i < 20; i++ {
+		bus.Publish(EventPriceTick, i)
+		<-fast
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected one rate-limited warning, got %d: %+v", len(warnings), warnings)
+	}
+	w := warnings[0]
+	if w.Name != "slow-strategy" || w.Event != EventPriceTick {
+		t.Errorf("warning should identify the lagging consumer, got %+v", w)
+	}
+	if w.Cap != 5 || w.Dropped == 0 {
+		t.Errorf("expected full buffer with drops, got %+v", w)
+	}
+}
+
+func TestSlowConsumerOldDropsDoNotBypassStreak(t *testing.T) {
+	bus := NewBus()
+	var warnings int
+	bus.SetSlowConsumerHandler(func(SlowConsumer) { warnings++ })
+	ch, unsub := bus.SubscribeNamed(EventPriceTick, "lagging", 5)
+	defer unsub()
+
+	// Overflow once: the drop warns straight away.
+	for i := 0; i < 6; i++ {
+		bus.Publish(EventPriceTick, i)
+	}
+	if warnings != 1 {
+		t.Fatalf("expected a warning for the drop, got %d", warnings)
+	}
+
+	// The consumer catches up, the warning interval passes, and the buffer
+	// briefly runs near-full again without dropping anything.
+	for len(ch) > 0 {
+		<-ch
+	}
+	bus.subs[EventPriceTick][0].lastWarn.Store(0)
+	for i := 0; i < 5; i++ {
+		bus.Publish(EventPriceTick, i)
+	}
+	if warnings != 1 {
+		t.Fatalf("a near-full moment without new drops warned (%d warnings)", warnings)
+	}
+}
+
+func TestSubscribeDefaultBuffer(t *testing.T) {
+	bus := NewBus()
+	bus.SetDefaultBuffer(7)
+	ch, unsub := bus.Subscribe(EventOrderFilled, 0)
+	defer unsub()
+	if cap(ch) != 7 {
+		t.Fatalf("cap = %d, want default buffer 7", cap(ch))
+	}
+}
+
+func TestDedupWindowAggregatesRepeatedAlerts(t *testing.T) {
+	bus := NewBus()
+	bus.SetDedupWindow(EventRiskAlert, 50*time.Millisecond)
+	ch, unsub := bus.Subscribe(EventRiskAlert, 200)
+	defer unsub()
+
+	alert := Alert{Type: "risk_rejected", UserID: "u1", Symbol: "BTCUSDT", Message: "daily loss limit exceeded"}
+	for i := 0; i < 100; i++ {
+		bus.Publish(EventRiskAlert, alert)
+	}
+	// Another symbol is a separate key.
+	bus.Publish(EventRiskAlert, Alert{Type: "risk_rejected", UserID: "u1", Symbol: "ETHUSDT", Message: "daily loss limit exceeded"})
+	// So is another Key on the same symbol.
+	bus.Publish(EventRiskAlert, Alert{Type: "risk_rejected", UserID: "u1", Symbol: "BTCUSDT", Key: "s2", Message: "daily loss limit exceeded"})
+
+	if got := (<-ch).(Alert); got != alert {
+		t.Fatalf("first alert = %+v, want it delivered as published", got)
+	}
+	if got := (<-ch).(Alert); got.Symbol != "ETHUSDT" {
+		t.Fatalf("expected the ETHUSDT alert next, got %+v", got)
+	}
+	if got := (<-ch).(Alert); got.Key != "s2" {
+		t.Fatalf("expected the s2 alert next, got %+v", got)
+	}
+
+	select {
+	case msg := <-ch:
+		agg := msg.(Alert)
+		if agg.Symbol != "BTCUSDT" || agg.Count != 99 || agg.FirstAt.IsZero() || agg.LastAt.Before(agg.FirstAt) {
+			t.Fatalf("unexpected aggregated alert: %+v", agg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("aggregated alert was not delivered")
+	}
+	select {
+	case msg := <-ch:
+		t.Fatalf("expected a single aggregated alert, also got %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// ProbeCapabilities fetches account info with the given key and returns the
+// trading capabilities the account grants (see exchange.Capability*).
+func ProbeCapabilities(ctx context.Context, exchangeType, apiKey, apiSecret string, testnet bool) ([]string, error) {
+	factory := DefaultFactory
+	if testnet {
+		factory = TestnetFactory
+	}
+	gw, err := factory(db.Connection{ExchangeType: exchangeType}, apiKey, apiSecret)
+	if err != nil {
+		return nil, err
+	}
+	summarizer, ok := gw.(exchange.AccountSummarizer)
+	if !ok {
+		return nil, fmt.Errorf("exchange type %s cannot report account permissions", exchangeType)
+	}
+	summary, err := summarizer.AccountSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return summary.Permissions, nil
+}
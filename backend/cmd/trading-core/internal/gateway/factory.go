@@ -10,6 +10,13 @@ import (
 	exchange "trading-core/pkg/exchanges/common"
 )
 
+// Binance clients must stay pingable so pool health checks are not no-ops.
+var (
+	_ Pinger = (*exspot.Client)(nil)
+	_ Pinger = (*exfutusdt.Client)(nil)
+	_ Pinger = (*exfutcoin.Client)(nil)
+)
+
 // DefaultFactory creates Gateway instances based on exchange type.
 func DefaultFactory(conn db.Connection, apiKey, apiSecret string) (exchange.Gateway, error) {
 	switch conn.ExchangeType {
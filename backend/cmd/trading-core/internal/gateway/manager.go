@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
@@ -19,6 +20,12 @@ var (
 	ErrPoolFull           = errors.New("gateway pool is full")
 )
 
+// Pinger is implemented by gateways that support a cheap connectivity check.
+// Health checks use it to trip and recover the per-gateway circuit breaker.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 // GatewayFactory creates a Gateway instance from a Connection.
 type GatewayFactory func(conn db.Connection, apiKey, apiSecret string) (exchange.Gateway, error)
 
@@ -32,6 +39,10 @@ type CachedGateway struct {
 	LastUsed     time.Time
 	HealthyAt    time.Time
 	Failures     int
+
+	LastError     string    // most recent failure, kept after recovery
+	LastErrorAt   time.Time // zero if the gateway never failed
+	LastSuccessAt time.Time // last successful health check; zero if none yet
 }
 
 // Config holds configuration for the GatewayManager.
@@ -41,6 +52,7 @@ type Config struct {
 	HealthInterval   time.Duration // Interval between health checks
 	FailureThreshold int           // Number of failures before marking unhealthy
 	CircuitTimeout   time.Duration // Time to wait before retrying unhealthy gateway
+	EvictionAlertMin int           // LRU evictions per minute that trigger an alert (0 = disabled)
 }
 
 // DefaultConfig returns sensible default configuration.
@@ -51,6 +63,7 @@ func DefaultConfig() Config {
 		HealthInterval:   5 * time.Minute,
 		FailureThreshold: 3,
 		CircuitTimeout:   5 * time.Minute,
+		EvictionAlertMin: 10,
 	}
 }
 
@@ -64,6 +77,14 @@ type Manager struct {
 	crypto  *crypto.KeyManager
 	queries *db.UserQueries
 	factory GatewayFactory
+	flights flightGroup // coalesces concurrent creations per connection
+
+	// Cumulative counters and eviction-rate alerting
+	evictions    uint64
+	circuitTrips uint64
+	recentEvicts []time.Time // eviction times within the last minute
+	lastAlertAt  time.Time
+	alertFn      func(string)
 
 	stopCh chan struct{}
 	wg     sync.WaitGroup
@@ -82,6 +103,13 @@ func NewManager(queries *db.UserQueries, cryptoMgr *crypto.KeyManager, factory G
 	}
 }
 
+// SetAlertFn configures the callback used to report a high eviction rate.
+func (m *Manager) SetAlertFn(fn func(string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alertFn = fn
+}
+
 // Start begins background cleanup and health check goroutines.
 func (m *Manager) Start(ctx context.Context) {
 	m.wg.Add(2)
@@ -165,31 +193,26 @@ func (m *Manager) GetOrCreate(ctx context.Context, userID, connectionID string)
 	}
 	m.mu.RUnlock()
 
-	// Slow path: need to create
-	return m.createGateway(ctx, userID, connectionID)
+	// Slow path: need to create; concurrent callers for the same connection share one creation.
+	return m.flights.do(userID+"/"+connectionID, func() (exchange.Gateway, error) {
+		return m.createGateway(ctx, userID, connectionID)
+	})
 }
 
 // createGateway creates a new Gateway instance from database.
+// DB, decryption and factory work run outside the pool lock.
 func (m *Manager) createGateway(ctx context.Context, userID, connectionID string) (exchange.Gateway, error) {
+	// Double-check: a previous flight may have just cached it
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Double-check after acquiring lock
 	if cached, ok := m.gateways[connectionID]; ok {
+		defer m.mu.Unlock()
 		if cached.UserID != userID {
 			return nil, ErrConnectionNotFound
 		}
 		m.touchLRULocked(connectionID)
 		return cached.Gateway, nil
 	}
-
-	// Check pool size
-	if len(m.gateways) >= m.config.MaxSize {
-		// Evict oldest
-		if !m.evictOldestLocked() {
-			return nil, ErrPoolFull
-		}
-	}
+	m.mu.Unlock()
 
 	// Fetch connection from database
 	conn, err := m.queries.GetConnectionByID(ctx, userID, connectionID)
@@ -223,6 +246,17 @@ func (m *Manager) createGateway(ctx context.Context, userID, connectionID string
 		return nil, fmt.Errorf("create gateway: %w", err)
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Check pool size
+	if len(m.gateways) >= m.config.MaxSize {
+		// Evict oldest
+		if !m.evictOldestLocked() {
+			return nil, ErrPoolFull
+		}
+	}
+
 	// Cache it
 	now := time.Now()
 	m.gateways[connectionID] = &CachedGateway{
@@ -270,13 +304,21 @@ func (m *Manager) RemoveByUser(userID string) {
 	}
 }
 
-// RecordFailure records a failure for a gateway.
-func (m *Manager) RecordFailure(connectionID string) {
+// RecordFailure records a failure for a gateway; err (optional) is reported
+// by Health.
+func (m *Manager) RecordFailure(connectionID string, err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if cached, ok := m.gateways[connectionID]; ok {
 		cached.Failures++
+		cached.LastErrorAt = time.Now()
+		if err != nil {
+			cached.LastError = err.Error()
+		}
+		if cached.Failures == m.config.FailureThreshold {
+			m.circuitTrips++
+		}
 	}
 }
 
@@ -286,9 +328,50 @@ func (m *Manager) RecordSuccess(connectionID string) {
 	defer m.mu.Unlock()
 
 	if cached, ok := m.gateways[connectionID]; ok {
+		now := time.Now()
 		cached.Failures = 0
-		cached.HealthyAt = time.Now()
+		cached.HealthyAt = now
+		cached.LastSuccessAt = now
+	}
+}
+
+// Connection health states reported by Health.
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy" // circuit breaker open
+	HealthUnknown   = "unknown"   // no gateway in the pool yet
+)
+
+// ConnectionHealth is the pool's view of one connection.
+type ConnectionHealth struct {
+	Status        string
+	Failures      int
+	LastError     string
+	LastErrorAt   time.Time
+	LastSuccessAt time.Time
+}
+
+// Health reports the state of connectionID's gateway. A connection that has
+// no gateway in the pool (never used, idle-evicted) is HealthUnknown.
+func (m *Manager) Health(connectionID string) ConnectionHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cached, ok := m.gateways[connectionID]
+	if !ok {
+		return ConnectionHealth{Status: HealthUnknown}
+	}
+	h := ConnectionHealth{
+		Status:        HealthHealthy,
+		Failures:      cached.Failures,
+		LastError:     cached.LastError,
+		LastErrorAt:   cached.LastErrorAt,
+		LastSuccessAt: cached.LastSuccessAt,
+	}
+	if cached.Failures >= m.config.FailureThreshold {
+		h.Status = HealthUnhealthy
 	}
+	return h
 }
 
 // Stats returns current pool statistics.
@@ -300,11 +383,15 @@ func (m *Manager) Stats() PoolStats {
 		TotalGateways:  len(m.gateways),
 		MaxSize:        m.config.MaxSize,
 		ByExchangeType: make(map[string]int),
+		ByUser:         make(map[string]int),
 		UnhealthyCount: 0,
+		Evictions:      m.evictions,
+		CircuitTrips:   m.circuitTrips,
 	}
 
 	for _, cached := range m.gateways {
 		stats.ByExchangeType[cached.ExchangeType]++
+		stats.ByUser[cached.UserID]++
 		if cached.Failures >= m.config.FailureThreshold {
 			stats.UnhealthyCount++
 		}
@@ -318,7 +405,10 @@ type PoolStats struct {
 	TotalGateways  int
 	MaxSize        int
 	ByExchangeType map[string]int
+	ByUser         map[string]int // gateways per user ID
 	UnhealthyCount int
+	Evictions      uint64 // cumulative LRU evictions
+	CircuitTrips   uint64 // cumulative circuit breaker trips
 }
 
 // --- Internal helpers ---
@@ -367,9 +457,39 @@ func (m *Manager) evictOldestLocked() bool {
 		delete(m.gateways, oldestID)
 	}
 	m.lruOrder = m.lruOrder[1:]
+	m.evictions++
+	m.checkEvictionRateLocked(time.Now())
 	return true
 }
 
+// checkEvictionRateLocked alerts (at most once per minute) when evictions in the
+// last minute reach EvictionAlertMin, which means MaxSize is too small for the
+// number of active users.
+func (m *Manager) checkEvictionRateLocked(now time.Time) {
+	if m.config.EvictionAlertMin <= 0 {
+		return
+	}
+	cutoff := now.Add(-time.Minute)
+	recent := m.recentEvicts[:0]
+	for _, t := range m.recentEvicts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	m.recentEvicts = append(recent, now)
+
+	if len(m.recentEvicts) < m.config.EvictionAlertMin || now.Sub(m.lastAlertAt) < time.Minute {
+		return
+	}
+	m.lastAlertAt = now
+	msg := fmt.Sprintf("gateway pool evicted %d gateways in the last minute (max size %d); consider raising MaxSize",
+		len(m.recentEvicts), m.config.MaxSize)
+	log.Printf("⚠️ %s", msg)
+	if m.alertFn != nil {
+		go m.alertFn(msg)
+	}
+}
+
 func (m *Manager) cleanupIdle() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -418,13 +538,13 @@ func (m *Manager) healthCheck(connectionID string) {
 	m.mu.RUnlock()
 
 	// Try to ping
-	if pinger, ok := gw.(interface{ Ping(context.Context) error }); ok {
+	if pinger, ok := gw.(Pinger); ok {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		err := pinger.Ping(ctx)
 		cancel()
 
 		if err != nil {
-			m.RecordFailure(connectionID)
+			m.RecordFailure(connectionID, err)
 		} else {
 			m.RecordSuccess(connectionID)
 		}
@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+type stubGateway struct{}
+
+func (stubGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{}, nil
+}
+
+func (stubGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func TestManagerEvictionCounter(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	ctx := context.Background()
+	q := database.Queries()
+	for i := 0; i < 4; i++ {
+		if err := q.CreateConnectionEncrypted(ctx, db.Connection{
+			ID: fmt.Sprintf("c%d", i), UserID: fmt.Sprintf("u%d", i%2), ExchangeType: "binance-spot",
+			Name: "conn", APIKey: "k", APISecret: "s",
+		}); err != nil {
+			t.Fatalf("CreateConnectionEncrypted: %v", err)
+		}
+	}
+
+	factory := func(conn db.Connection, apiKey, apiSecret string) (exchange.Gateway, error) {
+		return stubGateway{}, nil
+	}
+	cfg := DefaultConfig()
+	cfg.MaxSize = 2
+	cfg.EvictionAlertMin = 2
+	m := NewManager(q, nil, factory, cfg)
+	alerts := make(chan string, 4)
+	m.SetAlertFn(func(msg string) { alerts <- msg })
+
+	for i := 0; i < 4; i++ {
+		if _, err := m.GetOrCreate(ctx, fmt.Sprintf("u%d", i%2), fmt.Sprintf("c%d", i)); err != nil {
+			t.Fatalf("GetOrCreate c%d: %v", i, err)
+		}
+	}
+
+	stats := m.Stats()
+	if stats.TotalGateways != 2 {
+		t.Fatalf("expected pool capped at 2, got %d", stats.TotalGateways)
+	}
+	if stats.Evictions != 2 {
+		t.Fatalf("expected 2 evictions, got %d", stats.Evictions)
+	}
+	if stats.ByUser["u0"] != 1 || stats.ByUser["u1"] != 1 {
+		t.Fatalf("unexpected per-user counts: %+v", stats.ByUser)
+	}
+	select {
+	case <-alerts:
+	case <-time.After(time.Second):
+		t.Fatal("expected eviction rate alert")
+	}
+
+	for i := 0; i < cfg.FailureThreshold+1; i++ {
+		m.RecordFailure("c3", nil)
+	}
+	if got := m.Stats().CircuitTrips; got != 1 {
+		t.Fatalf("expected 1 circuit trip, got %d", got)
+	}
+}
+
+type pingGateway struct {
+	stubGateway
+	mu      sync.Mutex
+	healthy bool
+}
+
+func (g *pingGateway) Ping(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.healthy {
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (g *pingGateway) setHealthy(v bool) {
+	g.mu.Lock()
+	g.healthy = v
+	g.mu.Unlock()
+}
+
+func TestHealthCheckCircuitRecovery(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := database.Queries().CreateConnectionEncrypted(ctx, db.Connection{
+		ID: "c1", UserID: "u1", ExchangeType: "binance-spot", Name: "conn", APIKey: "k", APISecret: "s",
+	}); err != nil {
+		t.Fatalf("CreateConnectionEncrypted: %v", err)
+	}
+
+	gw := &pingGateway{healthy: true}
+	m := NewManager(database.Queries(), nil, func(conn db.Connection, apiKey, apiSecret string) (exchange.Gateway, error) {
+		return gw, nil
+	}, DefaultConfig())
+	if _, err := m.GetOrCreate(ctx, "u1", "c1"); err != nil {
+		t.Fatalf("GetOrCreate: %v", err)
+	}
+
+	// Failing pings trip the circuit breaker.
+	gw.setHealthy(false)
+	for i := 0; i < m.config.FailureThreshold; i++ {
+		m.healthCheck("c1")
+	}
+	if _, err := m.GetOrCreate(ctx, "u1", "c1"); !errors.Is(err, ErrGatewayUnhealthy) {
+		t.Fatalf("expected ErrGatewayUnhealthy, got %v", err)
+	}
+
+	// A successful ping closes it again without waiting for CircuitTimeout.
+	gw.setHealthy(true)
+	m.healthCheck("c1")
+	if _, err := m.GetOrCreate(ctx, "u1", "c1"); err != nil {
+		t.Fatalf("expected recovered gateway, got %v", err)
+	}
+	if stats := m.Stats(); stats.UnhealthyCount != 0 {
+		t.Fatalf("expected no unhealthy gateways, got %d", stats.UnhealthyCount)
+	}
+}
+
+func TestGetOrCreateCoalescesConcurrentCreation(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := database.Queries().CreateConnectionEncrypted(ctx, db.Connection{
+		ID: "c1", UserID: "u1", ExchangeType: "binance-spot", Name: "conn", APIKey: "k", APISecret: "s",
+	}); err != nil {
+		t.Fatalf("CreateConnectionEncrypted: %v", err)
+	}
+
+	var calls int32
+	m := NewManager(database.Queries(), nil, func(conn db.Connection, apiKey, apiSecret string) (exchange.Gateway, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond) // widen the window for concurrent callers
+		return stubGateway{}, nil
+	}, DefaultConfig())
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := m.GetOrCreate(ctx, "u1", "c1"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("GetOrCreate: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected factory to be called once, got %d", got)
+	}
+}
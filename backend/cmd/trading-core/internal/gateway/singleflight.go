@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"sync"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// flightGroup coalesces concurrent gateway creations for the same key so only
+// one caller does the DB/decrypt/factory work and the others share its result.
+// It is a minimal equivalent of golang.org/x/sync/singleflight.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	gw  exchange.Gateway
+	err error
+}
+
+// do runs fn once per key among concurrent callers.
+func (g *flightGroup) do(key string, fn func() (exchange.Gateway, error)) (exchange.Gateway, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.gw, call.err
+	}
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.gw, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return call.gw, call.err
+}
@@ -0,0 +1,89 @@
+package indicators
+
+import (
+	"strings"
+	"sync"
+)
+
+// Order-book feature keys merged into the indicator map handed to strategies.
+const (
+	BookImbalance  = "ob_imbalance"  // (bid qty - ask qty) / total over the top levels, in [-1, 1]
+	BookMicroprice = "ob_microprice" // top-of-book price weighted by the opposite side's size
+	BookSpreadBps  = "ob_spread_bps" // best ask - best bid, in bps of the mid
+)
+
+// BookFeatures computes order-book features from the top levels of a book
+// (bids best first, asks best first; [price, qty] pairs). ok is false when
+// either side is empty.
+func BookFeatures(bids, asks [][2]float64, levels int) (map[string]float64, bool) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, false
+	}
+	bidQty, askQty := sumQty(bids, levels), sumQty(asks, levels)
+	if bidQty+askQty <= 0 {
+		return nil, false
+	}
+
+	bestBid, bestAsk := bids[0], asks[0]
+	mid := (bestBid[0] + bestAsk[0]) / 2
+	if mid <= 0 {
+		return nil, false
+	}
+	microprice := mid
+	if top := bestBid[1] + bestAsk[1]; top > 0 {
+		microprice = (bestBid[0]*bestAsk[1] + bestAsk[0]*bestBid[1]) / top
+	}
+	return map[string]float64{
+		BookImbalance:  (bidQty - askQty) / (bidQty + askQty),
+		BookMicroprice: microprice,
+		BookSpreadBps:  (bestAsk[0] - bestBid[0]) / mid * 1e4,
+	}, true
+}
+
+func sumQty(side [][2]float64, levels int) float64 {
+	total := 0.0
+	for i, lvl := range side {
+		if levels > 0 && i >= levels {
+			break
+		}
+		total += lvl[1]
+	}
+	return total
+}
+
+// BookStore keeps the latest order-book features per symbol, fed by the
+// depth stream.
+type BookStore struct {
+	levels int
+
+	mu sync.RWMutex
+	m  map[string]map[string]float64
+}
+
+// NewBookStore creates a store computing features over the top levels of
+// each book (levels <= 0 uses every level received).
+func NewBookStore(levels int) *BookStore {
+	return &BookStore{levels: levels, m: make(map[string]map[string]float64)}
+}
+
+// Update recomputes symbol's features from a book snapshot.
+func (s *BookStore) Update(symbol string, bids, asks [][2]float64) {
+	feats, ok := BookFeatures(bids, asks, s.levels)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[strings.ToUpper(symbol)] = feats
+}
+
+// Features returns the latest features for symbol; nil when none were seen
+// (or the store is nil).
+func (s *BookStore) Features(symbol string) map[string]float64 {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[strings.ToUpper(symbol)]
+}
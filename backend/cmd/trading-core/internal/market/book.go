@@ -0,0 +1,115 @@
+package market
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-core/internal/indicators"
+	market "trading-core/pkg/market/binance"
+)
+
+// BookTop is the best bid/ask last seen for a symbol.
+type BookTop struct {
+	Bid       float64
+	Ask       float64
+	UpdatedAt time.Time
+}
+
+// BookStore retains the latest best bid/ask per symbol (fed by book tickers).
+type BookStore struct {
+	mu sync.RWMutex
+	m  map[string]BookTop
+
+	// OnQuote, if set, receives every accepted quote (e.g. LastPriceStore.SetQuote).
+	OnQuote func(symbol string, bid, ask float64)
+}
+
+// NewBookStore creates an empty store.
+func NewBookStore() *BookStore {
+	return &BookStore{m: make(map[string]BookTop)}
+}
+
+// Set records the best bid/ask for symbol; non-positive or crossed quotes are ignored.
+func (s *BookStore) Set(symbol string, bid, ask float64) {
+	if symbol == "" || bid <= 0 || ask <= 0 || bid > ask {
+		return
+	}
+	s.mu.Lock()
+	s.m[strings.ToUpper(symbol)] = BookTop{Bid: bid, Ask: ask, UpdatedAt: time.Now().UTC()}
+	s.mu.Unlock()
+	if s.OnQuote != nil {
+		s.OnQuote(symbol, bid, ask)
+	}
+}
+
+// BestBidAsk returns the latest best bid/ask for symbol.
+func (s *BookStore) BestBidAsk(symbol string) (bid, ask float64, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	top, ok := s.m[strings.ToUpper(symbol)]
+	return top.Bid, top.Ask, ok
+}
+
+// SpreadBps returns symbol's bid/ask spread in bps of the mid, if quoted
+// within maxAge (0 = any age).
+func (s *BookStore) SpreadBps(symbol string, maxAge time.Duration) (float64, bool) {
+	s.mu.RLock()
+	top, ok := s.m[strings.ToUpper(symbol)]
+	s.mu.RUnlock()
+	if !ok || (maxAge > 0 && time.Since(top.UpdatedAt) > maxAge) {
+		return 0, false
+	}
+	mid := (top.Bid + top.Ask) / 2
+	return (top.Ask - top.Bid) / mid * 1e4, true
+}
+
+// StreamBookTickers keeps store updated from the bookTicker stream of each
+// symbol until ctx is done, resubscribing after a dropped connection.
+func StreamBookTickers(ctx context.Context, stream *market.StreamClient, symbols []string, store *BookStore) {
+	for _, sym := range symbols {
+		go func(symbol string) {
+			for ctx.Err() == nil {
+				ch, stop, err := stream.SubscribeBookTicker(ctx, strings.ToLower(symbol))
+				if err != nil {
+					log.Printf("book ticker: subscribe %s error: %v", symbol, err)
+				} else {
+					for t := range ch {
+						store.Set(t.Symbol, t.BidPrice, t.AskPrice)
+					}
+					stop()
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}(sym)
+	}
+}
+
+// StreamDepth keeps book updated from the partial depth stream (top levels)
+// of each symbol until ctx is done, resubscribing after a dropped connection.
+func StreamDepth(ctx context.Context, stream *market.StreamClient, symbols []string, levels int, book *indicators.BookStore) {
+	for _, sym := range symbols {
+		go func(symbol string) {
+			for ctx.Err() == nil {
+				ch, stop, err := stream.SubscribePartialDepth(ctx, strings.ToLower(symbol), levels)
+				if err != nil {
+					log.Printf("depth: subscribe %s error: %v", symbol, err)
+				} else {
+					for d := range ch {
+						book.Update(d.Symbol, d.Bids, d.Asks)
+					}
+					stop()
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}(sym)
+	}
+}
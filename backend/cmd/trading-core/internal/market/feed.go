@@ -2,7 +2,11 @@ package market
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"trading-core/internal/events"
@@ -10,40 +14,307 @@ import (
 )
 
 // Feed streams prices from Binance and publishes to the event bus.
+// Every symbol is subscribed on each of Intervals (falling back to Interval when
+// empty), and published klines carry their interval so consumers can route them.
+// Symbols are always streamed; other symbols are streamed while at least one
+// consumer holds them via Acquire. PriceSources adds trade, aggTrade or book
+// mid streams per symbol, published as PriceTick for strategies that select them.
 type Feed struct {
-	Client   *market.Client
-	Stream   *market.StreamClient
-	Bus      *events.Bus
-	Symbols  []string
-	Interval string
+	Client    *market.Client
+	Stream    *market.StreamClient
+	Bus       *events.Bus
+	Symbols   []string
+	Interval  string
+	Intervals []string
+	// PriceSources lists non-kline price streams to open per symbol
+	// (market.PriceSourceTrade, PriceSourceAggTrade, PriceSourceBookMid).
+	PriceSources []string
+
+	// RESTFallback polls klines over REST for a stream that has permanently
+	// given up reconnecting, every FallbackPoll (default 15s).
+	RESTFallback bool
+	FallbackPoll time.Duration
+	// OnStreamLost is called when a (symbol, interval) stream stops for good (optional, e.g. metrics).
+	OnStreamLost func(symbol, interval string)
+
+	mu      sync.Mutex
+	ctx     context.Context       // set by Start
+	symbols map[string]*symbolSub // streamed (or pending, before Start) symbols
+}
+
+// symbolSub tracks one symbol's streams and who needs them.
+type symbolSub struct {
+	refs   int  // Acquire calls not yet released
+	pinned bool // listed in Feed.Symbols; never unsubscribed
+	ctx    context.Context
+	cancel context.CancelFunc
+	stops  []func()
 }
 
-// Start begins polling + websocket streaming for configured symbols.
+// intervals returns the de-duplicated set of intervals to subscribe.
+func (f *Feed) intervals() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, iv := range append([]string{f.Interval}, f.Intervals...) {
+		if iv == "" || seen[iv] {
+			continue
+		}
+		seen[iv] = true
+		out = append(out, iv)
+	}
+	if len(out) == 0 {
+		out = []string{"1m"}
+	}
+	return out
+}
+
+// Start begins polling + websocket streaming for configured symbols and any
+// symbol acquired before Start.
 func (f *Feed) Start(ctx context.Context) {
 	if f.Bus == nil || f.Client == nil || f.Stream == nil {
 		log.Println("market feed not fully configured; skipping start")
 		return
 	}
 
+	f.mu.Lock()
+	f.ctx = ctx
+	if f.symbols == nil {
+		f.symbols = make(map[string]*symbolSub)
+	}
 	for _, sym := range f.Symbols {
-		symbol := sym
-		// Kick off websocket stream per symbol.
-		ch, stop, err := f.Stream.SubscribeKlines(ctx, symbol, f.Interval)
+		sym = strings.ToUpper(sym)
+		sub, ok := f.symbols[sym]
+		if !ok {
+			sub = &symbolSub{}
+			f.symbols[sym] = sub
+		}
+		sub.pinned = true
+	}
+	for sym, sub := range f.symbols {
+		f.subscribeLocked(sym, sub)
+	}
+	f.mu.Unlock()
+
+	// Lightweight polling fallback to avoid gaps.
+	go f.pollSnapshots(ctx)
+}
+
+// Acquire streams symbol until the matching Release, e.g. while a strategy
+// trading it runs. Calls are counted, so several consumers can share a symbol.
+func (f *Feed) Acquire(symbol string) {
+	symbol = strings.ToUpper(symbol)
+	if symbol == "" {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.symbols == nil {
+		f.symbols = make(map[string]*symbolSub)
+	}
+	sub, ok := f.symbols[symbol]
+	if !ok {
+		sub = &symbolSub{}
+		f.symbols[symbol] = sub
+	}
+	sub.refs++
+	if !ok && f.ctx != nil {
+		log.Printf("📡 Market feed subscribing to %s", symbol)
+		f.subscribeLocked(symbol, sub)
+	}
+}
+
+// Release drops one Acquire of symbol and stops its streams once no consumer
+// is left, unless the symbol is in Symbols.
+func (f *Feed) Release(symbol string) {
+	symbol = strings.ToUpper(symbol)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub, ok := f.symbols[symbol]
+	if !ok || sub.refs == 0 {
+		return
+	}
+	sub.refs--
+	if sub.refs > 0 || sub.pinned {
+		return
+	}
+	delete(f.symbols, symbol)
+	if sub.cancel != nil {
+		log.Printf("📡 Market feed unsubscribing from %s", symbol)
+		sub.cancel()
+		for _, stop := range sub.stops {
+			stop()
+		}
+	}
+}
+
+// AddPriceSource starts streaming a non-kline price source for every streamed
+// symbol, and for symbols subscribed later, e.g. when a strategy selecting it
+// is loaded after Start. Sources stay streamed until the feed stops.
+func (f *Feed) AddPriceSource(source string) {
+	if source == "" || source == market.PriceSourceKline {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.PriceSources {
+		if s == source {
+			return
+		}
+	}
+	f.PriceSources = append(f.PriceSources, source)
+	for symbol, sub := range f.symbols {
+		if sub.cancel == nil {
+			continue // subscribed with every source at Start
+		}
+		stop, err := f.streamPrices(sub.ctx, symbol, source)
 		if err != nil {
-			log.Printf("market feed: ws subscribe %s error: %v", symbol, err)
+			log.Printf("market feed: ws subscribe %s %s error: %v", symbol, source, err)
 			continue
 		}
+		sub.stops = append(sub.stops, stop)
+	}
+}
+
+// StreamedSymbols returns the symbols currently streamed (or waiting for
+// Start), sorted.
+func (f *Feed) StreamedSymbols() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, 0, len(f.symbols))
+	for sym := range f.symbols {
+		out = append(out, sym)
+	}
+	sort.Strings(out)
+	return out
+}
 
+// subscribeLocked opens symbol's stream on every interval. f.mu must be held.
+func (f *Feed) subscribeLocked(symbol string, sub *symbolSub) {
+	if sub.cancel != nil {
+		return // already streaming
+	}
+	sub.ctx, sub.cancel = context.WithCancel(f.ctx)
+	for _, iv := range f.intervals() {
+		interval := iv
+		// Kick off websocket stream per (symbol, interval).
+		ch, stop, err := f.Stream.SubscribeKlines(sub.ctx, symbol, interval)
+		if err != nil {
+			log.Printf("market feed: ws subscribe %s@%s error: %v", symbol, interval, err)
+			continue
+		}
+		sub.stops = append(sub.stops, stop)
+
+		subCtx := sub.ctx
 		go func() {
 			defer stop()
 			for k := range ch {
+				if k.Interval == "" {
+					k.Interval = interval
+				}
 				f.Bus.Publish(events.EventPriceTick, k)
 			}
+			// Canceled when the feed stops or the symbol was released.
+			if subCtx.Err() == nil {
+				f.streamLost(subCtx, symbol, interval)
+			}
 		}()
 	}
+	for _, source := range f.PriceSources {
+		if source == "" || source == market.PriceSourceKline {
+			continue
+		}
+		stop, err := f.streamPrices(sub.ctx, symbol, source)
+		if err != nil {
+			log.Printf("market feed: ws subscribe %s %s error: %v", symbol, source, err)
+			continue
+		}
+		sub.stops = append(sub.stops, stop)
+	}
+}
 
-	// Lightweight polling fallback to avoid gaps.
-	go f.pollSnapshots(ctx)
+// streamPrices opens symbol's stream for a non-kline price source and
+// publishes each update as a market.PriceTick until the stream ends.
+func (f *Feed) streamPrices(ctx context.Context, symbol, source string) (func(), error) {
+	publish := func(price float64, ts int64) {
+		f.Bus.Publish(events.EventPriceTick, market.PriceTick{Symbol: symbol, Price: price, Source: source, Time: ts})
+	}
+	switch source {
+	case market.PriceSourceTrade:
+		ch, stop, err := f.Stream.SubscribeTrades(ctx, strings.ToLower(symbol))
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for t := range ch {
+				publish(t.Price, t.Time)
+			}
+		}()
+		return stop, nil
+	case market.PriceSourceAggTrade:
+		ch, stop, err := f.Stream.SubscribeAggTrades(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for t := range ch {
+				publish(t.Price, t.Time)
+			}
+		}()
+		return stop, nil
+	case market.PriceSourceBookMid:
+		ch, stop, err := f.Stream.SubscribeBookTicker(ctx, strings.ToLower(symbol))
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			for b := range ch {
+				publish((b.BidPrice+b.AskPrice)/2, time.Now().UnixMilli())
+			}
+		}()
+		return stop, nil
+	}
+	return nil, fmt.Errorf("unknown price source %q", source)
+}
+
+// streamLost handles a stream that closed while the feed is still running:
+// the stream client has exhausted its reconnect attempts.
+func (f *Feed) streamLost(ctx context.Context, symbol, interval string) {
+	log.Printf("❌ Market stream %s@%s permanently lost", symbol, interval)
+	f.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "market_stream_lost", Symbol: symbol, Key: interval,
+		Message: fmt.Sprintf("market stream %s@%s permanently lost after reconnect attempts; prices for %s are stale", symbol, interval, symbol)})
+	if f.OnStreamLost != nil {
+		f.OnStreamLost(symbol, interval)
+	}
+	if f.RESTFallback {
+		go f.pollFallback(ctx, symbol, interval)
+	}
+}
+
+// pollFallback replaces a lost stream with REST polling of the latest kline.
+func (f *Feed) pollFallback(ctx context.Context, symbol, interval string) {
+	every := f.FallbackPoll
+	if every <= 0 {
+		every = 15 * time.Second
+	}
+	log.Printf("⚠️ Market feed falling back to REST polling for %s@%s every %v", symbol, interval, every)
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			klines, err := f.Client.GetKlines(symbol, interval, 1, 0, 0)
+			if err != nil {
+				log.Printf("market feed fallback %s@%s error: %v", symbol, interval, err)
+				continue
+			}
+			if len(klines) > 0 {
+				f.Bus.Publish(events.EventPriceTick, klines[len(klines)-1])
+			}
+		}
+	}
 }
 
 func (f *Feed) pollSnapshots(ctx context.Context) {
@@ -55,14 +326,16 @@ func (f *Feed) pollSnapshots(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			for _, sym := range f.Symbols {
-				klines, err := f.Client.GetKlines(sym, f.Interval, 2, 0, 0)
-				if err != nil {
-					log.Printf("market feed snapshot %s error: %v", sym, err)
-					continue
-				}
-				if len(klines) > 0 {
-					f.Bus.Publish(events.EventPriceTick, klines[len(klines)-1])
+			for _, sym := range f.StreamedSymbols() {
+				for _, iv := range f.intervals() {
+					klines, err := f.Client.GetKlines(sym, iv, 2, 0, 0)
+					if err != nil {
+						log.Printf("market feed snapshot %s@%s error: %v", sym, iv, err)
+						continue
+					}
+					if len(klines) > 0 {
+						f.Bus.Publish(events.EventPriceTick, klines[len(klines)-1])
+					}
 				}
 			}
 		}
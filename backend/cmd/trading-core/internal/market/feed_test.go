@@ -0,0 +1,221 @@
+package market
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"trading-core/internal/events"
+	market "trading-core/pkg/market/binance"
+)
+
+func TestFeedAlertsWhenStreamPermanentlyFails(t *testing.T) {
+	var dials atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if dials.Add(1) > 1 {
+			// Every reconnect attempt is refused.
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close() // drop the initial stream abnormally
+	}))
+	defer srv.Close()
+
+	stream := market.NewStreamClientWithConfig(false, &market.ReconnectConfig{
+		Enabled:      true,
+		MaxRetries:   3,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     5 * time.Millisecond,
+		Multiplier:   2,
+	})
+	stream.StreamURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	bus := events.NewBus()
+	alerts, unsub := bus.Subscribe(events.EventRiskAlert, 10)
+	defer unsub()
+
+	lost := make(chan string, 1)
+	feed := Feed{
+		Client:   market.NewClient("", "", false),
+		Stream:   stream,
+		Bus:      bus,
+		Symbols:  []string{"BTCUSDT"},
+		Interval: "1m",
+		OnStreamLost: func(symbol, interval string) {
+			lost <- symbol + "@" + interval
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	feed.Start(ctx)
+
+	select {
+	case msg := <-alerts:
+		a, _ := msg.(events.Alert)
+		if a.Type != "market_stream_lost" || a.Symbol != "BTCUSDT" || !strings.Contains(a.Message, "BTCUSDT@1m") {
+			t.Fatalf("alert should name the lost stream, got %v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a permanent-failure risk alert")
+	}
+	if got := <-lost; got != "BTCUSDT@1m" {
+		t.Errorf("OnStreamLost got %q", got)
+	}
+	// Initial dial plus exactly MaxRetries reconnect attempts.
+	if got := dials.Load(); got != 4 {
+		t.Errorf("expected 4 dials (1 + 3 retries), got %d", got)
+	}
+}
+
+func TestFeedSubscribesToAcquiredSymbols(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	dialed := make(chan string, 10)
+	closed := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		stream := strings.TrimPrefix(r.URL.Path, "/")
+		dialed <- stream
+		// Hold the stream open until the client closes it.
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- stream
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	stream := market.NewStreamClientWithConfig(false, &market.ReconnectConfig{Enabled: false})
+	stream.StreamURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	feed := Feed{
+		Client:   market.NewClient("", "", false),
+		Stream:   stream,
+		Bus:      events.NewBus(),
+		Symbols:  []string{"BTCUSDT"},
+		Interval: "1m",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	feed.Start(ctx)
+	expect := func(ch chan string, want string) {
+		t.Helper()
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("got stream %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	expect(dialed, "btcusdt@kline_1m")
+
+	// A strategy on a symbol outside the startup list subscribes it.
+	feed.Acquire("ethusdt")
+	expect(dialed, "ethusdt@kline_1m")
+	feed.Acquire("ETHUSDT") // a second consumer shares the stream
+	if got := feed.StreamedSymbols(); len(got) != 2 || got[0] != "BTCUSDT" || got[1] != "ETHUSDT" {
+		t.Fatalf("StreamedSymbols() = %v", got)
+	}
+	select {
+	case s := <-dialed:
+		t.Fatalf("second Acquire dialed %q again", s)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	feed.Release("ETHUSDT")
+	select {
+	case s := <-closed:
+		t.Fatalf("stream %q closed while a consumer still holds it", s)
+	case <-time.After(50 * time.Millisecond):
+	}
+	feed.Release("ETHUSDT")
+	expect(closed, "ethusdt@kline_1m")
+
+	// Startup symbols stay subscribed however often they are released.
+	feed.Acquire("BTCUSDT")
+	feed.Release("BTCUSDT")
+	feed.Release("BTCUSDT")
+	if got := feed.StreamedSymbols(); len(got) != 1 || got[0] != "BTCUSDT" {
+		t.Fatalf("StreamedSymbols() after release = %v", got)
+	}
+}
+
+func TestFeedReleaseStopsRESTFallback(t *testing.T) {
+	var dials, polls atomic.Int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/klines" {
+			polls.Add(1)
+			w.Write([]byte(`[[0,"1","1","1","1","1",1,"1",1,"1","1","0"]]`))
+			return
+		}
+		if dials.Add(1) > 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	stream := market.NewStreamClientWithConfig(false, &market.ReconnectConfig{Enabled: false})
+	stream.StreamURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	client := market.NewClient("", "", false)
+	client.BaseURL = srv.URL
+	lost := make(chan string, 1)
+	feed := Feed{
+		Client:       client,
+		Stream:       stream,
+		Bus:          events.NewBus(),
+		Interval:     "1m",
+		RESTFallback: true,
+		FallbackPoll: 5 * time.Millisecond,
+		OnStreamLost: func(symbol, interval string) { lost <- symbol },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	feed.Start(ctx)
+	feed.Acquire("ETHUSDT")
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stream to be lost")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for polls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected REST fallback polling after the stream was lost")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Releasing the symbol stops its fallback poller with its streams.
+	feed.Release("ETHUSDT")
+	time.Sleep(20 * time.Millisecond)
+	before := polls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if after := polls.Load(); after != before {
+		t.Fatalf("fallback kept polling after Release: %d -> %d polls", before, after)
+	}
+}
@@ -0,0 +1,174 @@
+package market
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PriceField names one of the prices kept in a PriceSnapshot.
+type PriceField string
+
+const (
+	PriceFieldLast PriceField = "last" // last trade / kline close
+	PriceFieldBid  PriceField = "bid"
+	PriceFieldAsk  PriceField = "ask"
+	PriceFieldMid  PriceField = "mid"  // best bid/ask midpoint
+	PriceFieldMark PriceField = "mark" // futures mark price
+)
+
+// PriceSnapshot is the most recent pricing seen for a symbol. Price is the
+// store's default field, so consumers that do not care keep reading one number;
+// UpdatedAt is when that price last changed.
+type PriceSnapshot struct {
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Last      float64   `json:"last"`
+	Bid       float64   `json:"bid,omitempty"`
+	Ask       float64   `json:"ask,omitempty"`
+	Mid       float64   `json:"mid,omitempty"`
+	Mark      float64   `json:"mark,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Field returns the requested price, falling back to Last when that field has
+// not been seen (e.g. no book ticker or mark price for the symbol yet).
+func (p PriceSnapshot) Field(f PriceField) float64 {
+	if v := p.raw(f); v > 0 {
+		return v
+	}
+	return p.Last
+}
+
+// raw returns field f without falling back.
+func (p PriceSnapshot) raw(f PriceField) float64 {
+	switch f {
+	case PriceFieldLast:
+		return p.Last
+	case PriceFieldBid:
+		return p.Bid
+	case PriceFieldAsk:
+		return p.Ask
+	case PriceFieldMid:
+		return p.Mid
+	case PriceFieldMark:
+		return p.Mark
+	}
+	return 0
+}
+
+// LastPriceStore retains the latest price snapshot per symbol so late
+// subscribers (strategies, websocket clients, portfolio views) have a price
+// immediately.
+type LastPriceStore struct {
+	mu    sync.RWMutex
+	m     map[string]PriceSnapshot
+	field PriceField // served by Get and PriceSnapshot.Price
+}
+
+// NewLastPriceStore creates an empty store serving last prices by default.
+func NewLastPriceStore() *LastPriceStore {
+	return &LastPriceStore{m: make(map[string]PriceSnapshot), field: PriceFieldLast}
+}
+
+// SetDefaultField selects the field Get returns (e.g. mid for risk checks);
+// unknown fields are ignored.
+func (s *LastPriceStore) SetDefaultField(f PriceField) {
+	switch f {
+	case PriceFieldLast, PriceFieldBid, PriceFieldAsk, PriceFieldMid, PriceFieldMark:
+	default:
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.field = f
+	for sym, p := range s.m {
+		p.Price = p.Field(f)
+		s.m[sym] = p
+	}
+}
+
+// update applies fn, which sets the touched fields, to symbol's snapshot and
+// refreshes its default price. UpdatedAt only moves when the default price was
+// among them, so a fresh quote cannot hide a stale last price.
+func (s *LastPriceStore) update(symbol string, fn func(p *PriceSnapshot), touched ...PriceField) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.m[symbol]
+	p.Symbol = symbol
+	fn(&p)
+	p.Price = p.Field(s.field)
+	served := s.field
+	if p.raw(served) <= 0 {
+		served = PriceFieldLast
+	}
+	for _, f := range touched {
+		if f == served {
+			p.UpdatedAt = time.Now().UTC()
+		}
+	}
+	s.m[symbol] = p
+}
+
+// Set records price as the latest trade price for symbol.
+func (s *LastPriceStore) Set(symbol string, price float64) {
+	if symbol == "" || price <= 0 {
+		return
+	}
+	s.update(symbol, func(p *PriceSnapshot) { p.Last = price }, PriceFieldLast)
+}
+
+// SetQuote records symbol's best bid/ask (and their mid); non-positive or
+// crossed quotes are ignored.
+func (s *LastPriceStore) SetQuote(symbol string, bid, ask float64) {
+	if symbol == "" || bid <= 0 || ask <= 0 || bid > ask {
+		return
+	}
+	s.update(strings.ToUpper(symbol), func(p *PriceSnapshot) {
+		p.Bid, p.Ask, p.Mid = bid, ask, (bid+ask)/2
+	}, PriceFieldBid, PriceFieldAsk, PriceFieldMid)
+}
+
+// SetMark records symbol's futures mark price.
+func (s *LastPriceStore) SetMark(symbol string, mark float64) {
+	if symbol == "" || mark <= 0 {
+		return
+	}
+	s.update(strings.ToUpper(symbol), func(p *PriceSnapshot) { p.Mark = mark }, PriceFieldMark)
+}
+
+// Get returns the default-field price for symbol, or 0 if none has been seen.
+func (s *LastPriceStore) Get(symbol string) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[symbol].Price
+}
+
+// GetField returns field f of symbol's snapshot (falling back to the last
+// price), or 0 if none has been seen.
+func (s *LastPriceStore) GetField(symbol string, f PriceField) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[symbol].Field(f)
+}
+
+// Lookup returns the latest price snapshot for symbol.
+func (s *LastPriceStore) Lookup(symbol string) (PriceSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.m[symbol]
+	return p, ok
+}
+
+// All returns every known snapshot sorted by symbol.
+func (s *LastPriceStore) All() []PriceSnapshot {
+	s.mu.RLock()
+	out := make([]PriceSnapshot, 0, len(s.m))
+	for _, p := range s.m {
+		out = append(out, p)
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
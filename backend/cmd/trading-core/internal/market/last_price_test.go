@@ -0,0 +1,61 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriceSnapshotServesEachConsumerItsField(t *testing.T) {
+	prices := NewLastPriceStore()
+	prices.Set("BTCUSDT", 50010)
+	prices.SetQuote("btcusdt", 49990, 50000)
+	prices.SetMark("BTCUSDT", 50020)
+
+	snap, ok := prices.Lookup("BTCUSDT")
+	if !ok {
+		t.Fatal("expected a snapshot for BTCUSDT")
+	}
+	if snap.Last != 50010 || snap.Bid != 49990 || snap.Ask != 50000 || snap.Mid != 49995 || snap.Mark != 50020 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	// Risk checks read the default field; positions mark to mark; makers quote off mid.
+	if got := prices.Get("BTCUSDT"); got != 50010 {
+		t.Errorf("default Get = %v, want last 50010", got)
+	}
+	if got := prices.GetField("BTCUSDT", PriceFieldMark); got != 50020 {
+		t.Errorf("mark = %v, want 50020", got)
+	}
+	if got := prices.GetField("BTCUSDT", PriceFieldMid); got != 49995 {
+		t.Errorf("mid = %v, want 49995", got)
+	}
+
+	prices.SetDefaultField(PriceFieldMid)
+	if got := prices.Get("BTCUSDT"); got != 49995 {
+		t.Errorf("Get with mid default = %v, want 49995", got)
+	}
+
+	// Fields not seen yet fall back to the last price.
+	prices.Set("ETHUSDT", 3000)
+	if got := prices.GetField("ETHUSDT", PriceFieldMark); got != 3000 {
+		t.Errorf("mark without a mark price = %v, want last 3000", got)
+	}
+	if got := prices.Get("ETHUSDT"); got != 3000 {
+		t.Errorf("mid default without a quote = %v, want last 3000", got)
+	}
+}
+
+func TestPriceSnapshotQuoteDoesNotRefreshStaleLast(t *testing.T) {
+	prices := NewLastPriceStore()
+	stale := time.Now().Add(-time.Hour)
+	prices.m["BTCUSDT"] = PriceSnapshot{Symbol: "BTCUSDT", Price: 50000, Last: 50000, UpdatedAt: stale}
+
+	prices.SetQuote("BTCUSDT", 50100, 50110)
+	if p, _ := prices.Lookup("BTCUSDT"); !p.UpdatedAt.Equal(stale) {
+		t.Fatalf("a quote refreshed the last price's timestamp: %v", p.UpdatedAt)
+	}
+	prices.Set("BTCUSDT", 50105)
+	if p, _ := prices.Lookup("BTCUSDT"); time.Since(p.UpdatedAt) > time.Second {
+		t.Fatalf("a new last price should refresh the timestamp, got %v", p.UpdatedAt)
+	}
+}
@@ -4,18 +4,69 @@ import (
 	"context"
 	"log"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"trading-core/internal/events"
 )
 
-// MockFeed generates synthetic ticks for local development.
+// MockFeed generates synthetic ticks for local development: for Symbols and,
+// like Feed, for any symbol held via Acquire.
 type MockFeed struct {
 	Bus        *events.Bus
 	Symbols    []string
 	StartPrice float64
 	Step       float64
 	Interval   time.Duration
+
+	mu       sync.Mutex
+	acquired map[string]int // symbol -> Acquire calls not yet released
+}
+
+// Acquire adds symbol to the generated ticks until the matching Release.
+func (m *MockFeed) Acquire(symbol string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.acquired == nil {
+		m.acquired = make(map[string]int)
+	}
+	m.acquired[strings.ToUpper(symbol)]++
+}
+
+// Release drops one Acquire of symbol.
+func (m *MockFeed) Release(symbol string) {
+	symbol = strings.ToUpper(symbol)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.acquired[symbol] <= 1 {
+		delete(m.acquired, symbol)
+		return
+	}
+	m.acquired[symbol]--
+}
+
+// streamedSymbols returns Symbols plus the acquired symbols, de-duplicated.
+func (m *MockFeed) streamedSymbols() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool)
+	var out []string
+	for _, sym := range m.Symbols {
+		if sym = strings.ToUpper(sym); !seen[sym] {
+			seen[sym] = true
+			out = append(out, sym)
+		}
+	}
+	var extra []string
+	for sym := range m.acquired {
+		if !seen[sym] {
+			extra = append(extra, sym)
+		}
+	}
+	sort.Strings(extra)
+	return append(out, extra...)
 }
 
 func (m *MockFeed) Start(ctx context.Context) {
@@ -45,7 +96,7 @@ func (m *MockFeed) Start(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-t.C:
-				for _, sym := range m.Symbols {
+				for _, sym := range m.streamedSymbols() {
 					// simple random walk
 					price += (rand.Float64()*2 - 1) * m.Step
 					m.Bus.Publish(events.EventPriceTick, struct {
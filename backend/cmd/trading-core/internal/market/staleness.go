@@ -0,0 +1,52 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrStalePrice is returned when no price newer than the staleness threshold is available.
+var ErrStalePrice = errors.New("stale price")
+
+// StalenessGuard hands out prices from a LastPriceStore only while they are
+// fresh, so a stalled feed cannot drive trading on minutes-old data.
+type StalenessGuard struct {
+	Prices *LastPriceStore
+	MaxAge time.Duration // 0 disables the check
+
+	// Refresh fetches a current price (e.g. over REST) from the given market
+	// when the cached one is stale or missing (optional; without it stale
+	// prices are rejected).
+	Refresh func(ctx context.Context, market, symbol string) (float64, error)
+}
+
+// Price returns a price for symbol no older than MaxAge, refreshing it from
+// market (spot, usdtfut, coinfut; empty for the feed's own) when possible.
+// The error wraps ErrStalePrice when neither is available.
+func (g *StalenessGuard) Price(ctx context.Context, market, symbol string) (float64, error) {
+	last, ok := g.Prices.Lookup(symbol)
+	if g.MaxAge <= 0 {
+		return last.Price, nil
+	}
+	age := time.Since(last.UpdatedAt)
+	if ok && age <= g.MaxAge {
+		return last.Price, nil
+	}
+
+	if g.Refresh != nil {
+		price, err := g.Refresh(ctx, market, symbol)
+		if err == nil && price > 0 {
+			log.Printf("⚠️ Price for %s was stale; refreshed over REST: %.8g", symbol, price)
+			g.Prices.Set(symbol, price)
+			return price, nil
+		}
+		log.Printf("price refresh %s error: %v", symbol, err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: no price for %s", ErrStalePrice, symbol)
+	}
+	return 0, fmt.Errorf("%w: %s last updated %s ago (max %s)", ErrStalePrice, symbol, age.Round(time.Second), g.MaxAge)
+}
@@ -0,0 +1,47 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStalenessGuardRejectsStalePrice(t *testing.T) {
+	prices := NewLastPriceStore()
+	prices.Set("BTCUSDT", 50000)
+	// Simulate a stalled feed: the last tick arrived two minutes ago.
+	prices.m["BTCUSDT"] = PriceSnapshot{Symbol: "BTCUSDT", Price: 50000, UpdatedAt: time.Now().Add(-2 * time.Minute)}
+
+	guard := &StalenessGuard{Prices: prices, MaxAge: 30 * time.Second}
+	if _, err := guard.Price(context.Background(), "", "BTCUSDT"); !errors.Is(err, ErrStalePrice) {
+		t.Fatalf("expected ErrStalePrice for a 2m old price, got %v", err)
+	}
+	if _, err := guard.Price(context.Background(), "", "ETHUSDT"); !errors.Is(err, ErrStalePrice) {
+		t.Fatalf("expected ErrStalePrice for a missing price, got %v", err)
+	}
+
+	// A successful refresh replaces the stale price.
+	var refreshedFrom string
+	guard.Refresh = func(_ context.Context, market, _ string) (float64, error) {
+		refreshedFrom = market
+		return 51000, nil
+	}
+	price, err := guard.Price(context.Background(), "usdtfut", "BTCUSDT")
+	if err != nil || price != 51000 {
+		t.Fatalf("expected refreshed price 51000, got %v (%v)", price, err)
+	}
+	if refreshedFrom != "usdtfut" {
+		t.Fatalf("refreshed from market %q, want usdtfut", refreshedFrom)
+	}
+	if p, _ := prices.Lookup("BTCUSDT"); time.Since(p.UpdatedAt) > time.Second {
+		t.Fatalf("refreshed price should be stored as fresh, updated %v", p.UpdatedAt)
+	}
+
+	// A failed refresh still rejects.
+	prices.m["BTCUSDT"] = PriceSnapshot{Symbol: "BTCUSDT", Price: 51000, UpdatedAt: time.Now().Add(-time.Hour)}
+	guard.Refresh = func(context.Context, string, string) (float64, error) { return 0, errors.New("rest down") }
+	if _, err := guard.Price(context.Background(), "", "BTCUSDT"); !errors.Is(err, ErrStalePrice) {
+		t.Fatalf("expected ErrStalePrice when refresh fails, got %v", err)
+	}
+}
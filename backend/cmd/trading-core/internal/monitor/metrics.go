@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"trading-core/internal/gateway"
+	market "trading-core/pkg/market/binance"
 )
 
 // SystemMetrics tracks overall system performance.
@@ -29,6 +30,7 @@ type SystemMetrics struct {
 	errorsCount      uint64
 	apiRequests      uint64
 	apiErrors        uint64
+	streamFailures   uint64 // market streams that gave up reconnecting
 
 	// Gateway pool & multi-user stats (updated periodically from main).
 	gatewayStats       gateway.PoolStats
@@ -174,6 +176,11 @@ func (m *SystemMetrics) IncrementAPIErrors() {
 	atomic.AddUint64(&m.apiErrors, 1)
 }
 
+// IncrementStreamFailures records a market stream that permanently stopped.
+func (m *SystemMetrics) IncrementStreamFailures() {
+	atomic.AddUint64(&m.streamFailures, 1)
+}
+
 // Snapshot returns current metrics snapshot.
 type MetricsSnapshot struct {
 	OrderLatency       LatencyStats      `json:"order_latency"`
@@ -188,6 +195,8 @@ type MetricsSnapshot struct {
 	ErrorsCount        uint64            `json:"errors_count"`
 	APIRequests        uint64            `json:"api_requests"`
 	APIErrors          uint64            `json:"api_errors"`
+	StreamFailures     uint64            `json:"market_stream_failures"`
+	ParseAnomalies     uint64            `json:"market_parse_anomalies"`
 	GatewayPool        gateway.PoolStats `json:"gateway_pool"`
 	RiskActiveUsers    int               `json:"risk_active_users"`
 	BalanceActiveUsers int               `json:"balance_active_users"`
@@ -221,6 +230,8 @@ func (m *SystemMetrics) GetSnapshot() MetricsSnapshot {
 		ErrorsCount:         atomic.LoadUint64(&m.errorsCount),
 		APIRequests:         atomic.LoadUint64(&m.apiRequests),
 		APIErrors:           atomic.LoadUint64(&m.apiErrors),
+		StreamFailures:      atomic.LoadUint64(&m.streamFailures),
+		ParseAnomalies:      market.ParseAnomalies(),
 		GatewayPool:         gwStats,
 		RiskActiveUsers:     riskUsers,
 		BalanceActiveUsers:  balanceUsers,
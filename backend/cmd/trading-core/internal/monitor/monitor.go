@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
@@ -44,6 +45,8 @@ func toString(v any) string {
 	switch t := v.(type) {
 	case string:
 		return t
+	case fmt.Stringer:
+		return t.String()
 	default:
 		return "alert triggered"
 	}
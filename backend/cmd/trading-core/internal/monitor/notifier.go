@@ -0,0 +1,234 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-core/internal/events"
+)
+
+// Notification is one bus event pushed to an external channel.
+type Notification struct {
+	Event   events.Event `json:"event"`
+	Message string       `json:"message"`
+	Data    any          `json:"data,omitempty"` // raw payload for non-text events (e.g. fills)
+	Time    time.Time    `json:"time"`
+}
+
+// Notifier delivers notifications to an external channel (webhook, chat, ...).
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// WebhookNotifier POSTs the Notification as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WebhookNotifier) Name() string { return "webhook" }
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.Client, w.URL, n)
+}
+
+// DiscordNotifier posts the message text to a Discord channel webhook.
+type DiscordNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (d *DiscordNotifier) Name() string { return "discord" }
+
+func (d *DiscordNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, d.Client, d.URL, map[string]string{"content": notificationText(n)})
+}
+
+// TelegramNotifier sends the message text through a Telegram bot.
+type TelegramNotifier struct {
+	BotToken string
+	ChatID   string
+	BaseURL  string // defaults to https://api.telegram.org
+	Client   *http.Client
+}
+
+func (t *TelegramNotifier) Name() string { return "telegram" }
+
+func (t *TelegramNotifier) Notify(ctx context.Context, n Notification) error {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	url := strings.TrimRight(base, "/") + "/bot" + t.BotToken + "/sendMessage"
+	return postJSON(ctx, t.Client, url, map[string]string{"chat_id": t.ChatID, "text": notificationText(n)})
+}
+
+func notificationText(n Notification) string {
+	return fmt.Sprintf("[%s] %s", n.Event, n.Message)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// DispatcherConfig selects which events are forwarded and how often.
+type DispatcherConfig struct {
+	Events        []events.Event // e.g. EventRiskAlert, EventOrderFilled
+	RatePerMinute int            // per event type; 0 = unlimited
+	Timeout       time.Duration  // per delivery (default 10s)
+}
+
+// Dispatcher subscribes to bus events and fans them out to notifiers. Events
+// over the per-type rate are dropped and counted; the count is appended to
+// the next notification of that type.
+type Dispatcher struct {
+	bus       *events.Bus
+	cfg       DispatcherConfig
+	notifiers []Notifier
+	now       func() time.Time
+
+	mu      sync.Mutex
+	windows map[events.Event]*rateWindow
+}
+
+type rateWindow struct {
+	start      time.Time
+	sent       int
+	suppressed int
+}
+
+// NewDispatcher creates a dispatcher for the given notifiers.
+func NewDispatcher(bus *events.Bus, cfg DispatcherConfig, notifiers ...Notifier) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Dispatcher{
+		bus:       bus,
+		cfg:       cfg,
+		notifiers: notifiers,
+		now:       time.Now,
+		windows:   make(map[events.Event]*rateWindow),
+	}
+}
+
+// Start subscribes to the configured events until ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context) {
+	if d.bus == nil || len(d.notifiers) == 0 {
+		return
+	}
+	for _, e := range d.cfg.Events {
+		stream, unsub := d.bus.SubscribeNamed(e, "notifier", 0)
+		go func(e events.Event) {
+			defer unsub()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-stream:
+					if !ok {
+						return
+					}
+					d.dispatch(ctx, e, msg)
+				}
+			}
+		}(e)
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, e events.Event, payload any) {
+	suppressed, ok := d.allow(e)
+	if !ok {
+		return
+	}
+	n := Notification{Event: e, Message: describeEvent(e, payload), Time: d.now()}
+	if _, isText := payload.(string); !isText {
+		n.Data = payload
+	}
+	if suppressed > 0 {
+		n.Message += fmt.Sprintf(" (%d more suppressed by rate limit)", suppressed)
+	}
+
+	for _, nt := range d.notifiers {
+		sendCtx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+		if err := nt.Notify(sendCtx, n); err != nil {
+			log.Printf("⚠️ Notifier %s: deliver %s failed: %v", nt.Name(), e, err)
+		}
+		cancel()
+	}
+}
+
+// allow applies the per-event rate limit and returns how many notifications
+// were suppressed since the last one sent.
+func (d *Dispatcher) allow(e events.Event) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	w := d.windows[e]
+	if w == nil {
+		w = &rateWindow{start: now}
+		d.windows[e] = w
+	}
+	if now.Sub(w.start) >= time.Minute {
+		w.start, w.sent = now, 0
+	}
+	if d.cfg.RatePerMinute > 0 && w.sent >= d.cfg.RatePerMinute {
+		w.suppressed++
+		return 0, false
+	}
+	w.sent++
+	suppressed := w.suppressed
+	w.suppressed = 0
+	return suppressed, true
+}
+
+// fillFields are the fields shared by every EventOrderFilled payload.
+type fillFields struct {
+	ID     string
+	Symbol string
+	Side   string
+	Qty    float64
+	Price  float64
+}
+
+func describeEvent(e events.Event, payload any) string {
+	if s, ok := payload.(string); ok {
+		return s
+	}
+	if e == events.EventOrderFilled {
+		var f fillFields
+		if raw, err := json.Marshal(payload); err == nil && json.Unmarshal(raw, &f) == nil && f.Symbol != "" {
+			return fmt.Sprintf("Filled %s %.8g %s @ %.8g (order %s)", f.Side, f.Qty, f.Symbol, f.Price, f.ID)
+		}
+	}
+	return fmt.Sprintf("%v", payload)
+}
@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"trading-core/internal/events"
+)
+
+func TestWebhookNotifierPostsRiskAlert(t *testing.T) {
+	received := make(chan Notification, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		received <- n
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := events.NewBus()
+	d := NewDispatcher(bus, DispatcherConfig{Events: []events.Event{events.EventRiskAlert}}, &WebhookNotifier{URL: srv.URL})
+	d.Start(ctx)
+
+	bus.Publish(events.EventOrderFilled, "not subscribed")
+	bus.Publish(events.EventRiskAlert, "daily loss limit reached; trading halted")
+
+	select {
+	case n := <-received:
+		if n.Event != events.EventRiskAlert || n.Message != "daily loss limit reached; trading halted" || n.Data != nil || n.Time.IsZero() {
+			t.Fatalf("unexpected payload: %+v", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+type recordingNotifier struct{ got []Notification }
+
+func (r *recordingNotifier) Name() string { return "recording" }
+
+func (r *recordingNotifier) Notify(ctx context.Context, n Notification) error {
+	r.got = append(r.got, n)
+	return nil
+}
+
+func TestDispatcherRateLimitsPerEventType(t *testing.T) {
+	rec := &recordingNotifier{}
+	d := NewDispatcher(nil, DispatcherConfig{RatePerMinute: 2}, rec)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		d.dispatch(ctx, events.EventRiskAlert, "alert")
+	}
+	d.dispatch(ctx, events.EventOrderFilled, struct {
+		ID     string
+		Symbol string
+		Side   string
+		Qty    float64
+		Price  float64
+	}{ID: "o1", Symbol: "BTCUSDT", Side: "BUY", Qty: 0.5, Price: 42000})
+	if len(rec.got) != 3 {
+		t.Fatalf("expected 2 alerts and 1 fill, got %d notifications", len(rec.got))
+	}
+	if fill := rec.got[2]; fill.Message != "Filled BUY 0.5 BTCUSDT @ 42000 (order o1)" || fill.Data == nil {
+		t.Fatalf("unexpected fill notification: %+v", fill)
+	}
+
+	// A new window reports what was dropped.
+	now = now.Add(time.Minute)
+	d.dispatch(ctx, events.EventRiskAlert, "alert")
+	if last := rec.got[len(rec.got)-1]; !strings.Contains(last.Message, "3 more suppressed") {
+		t.Fatalf("expected suppressed count in %q", last.Message)
+	}
+}
@@ -3,6 +3,7 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net"
 	"strings"
@@ -21,6 +22,9 @@ type AsyncExecutor struct {
 	mu           sync.Mutex
 	maxRetries   int           // Maximum retry attempts (default: 3)
 	retryBackoff time.Duration // Initial backoff duration (default: 100ms)
+
+	inflight int             // executions started and not finished; guarded by mu
+	idle     []chan struct{} // WaitIdle callers, closed when inflight drops to 0
 }
 
 // ExecutionResult represents the outcome of an order execution.
@@ -92,86 +96,160 @@ func isRetryableError(err error) bool {
 
 // ExecuteAsync submits an order for asynchronous execution with retry.
 func (a *AsyncExecutor) ExecuteAsync(ctx context.Context, order Order) {
+	slot, ok := a.acquire(order)
+	if !ok {
+		return
+	}
+	go func() {
+		defer a.release(slot)
+		a.execute(ctx, order)
+	}()
+}
+
+// Execute runs an order on a worker slot like ExecuteAsync but returns only
+// once it has been executed (or has failed its retries), for callers that
+// must not acknowledge the order before then.
+func (a *AsyncExecutor) Execute(ctx context.Context, order Order) error {
+	slot, ok := a.acquire(order)
+	if !ok {
+		return fmt.Errorf("async executor closed, order %s rejected", order.ID)
+	}
+	defer a.release(slot)
+	return a.execute(ctx, order).Error
+}
+
+// acquire registers an execution and takes a worker slot for it, reporting
+// ok=false once the executor is closed. Maker-first orders spend most of
+// their time resting on the book between polls, so they run without a slot
+// (slot=false) rather than pinning one for the whole reprice loop.
+func (a *AsyncExecutor) acquire(order Order) (slot, ok bool) {
 	a.mu.Lock()
 	if a.closed {
 		a.mu.Unlock()
 		log.Printf("❌ AsyncExecutor closed, order rejected: %s", order.ID)
-		return
+		return false, false
 	}
+	a.inflight++
 	a.mu.Unlock()
 
 	a.wg.Add(1)
+	if order.Routing == RoutingMakerFirst && a.executor != nil && a.executor.Router != nil {
+		return false, true
+	}
 	a.workerPool <- struct{}{} // Acquire worker slot
+	return true, true
+}
 
-	go func() {
-		defer a.wg.Done()
-		defer func() { <-a.workerPool }() // Release worker slot
-
-		start := time.Now()
-		var err error
-		retryCount := 0
-
-		// Execute with retry logic
-		for attempt := 0; attempt <= a.maxRetries; attempt++ {
-			if attempt > 0 {
-				backoff := a.retryBackoff * time.Duration(1<<(attempt-1)) // Exponential backoff
-				log.Printf("🔄 Retrying order %s (attempt %d/%d) after %v", order.ID, attempt, a.maxRetries, backoff)
-				select {
-				case <-ctx.Done():
-					err = ctx.Err()
-					break
-				case <-time.After(backoff):
-				}
-			}
+func (a *AsyncExecutor) release(slot bool) {
+	if slot {
+		<-a.workerPool // Release worker slot
+	}
+	a.mu.Lock()
+	a.inflight--
+	if a.inflight == 0 {
+		for _, ch := range a.idle {
+			close(ch)
+		}
+		a.idle = nil
+	}
+	a.mu.Unlock()
+	a.wg.Done()
+}
 
-			// Execute using appropriate executor
-			if a.dryRunner != nil {
-				err = a.dryRunner.Execute(ctx, order)
-			} else if a.executor != nil {
-				err = a.executor.Handle(ctx, order)
-			} else {
-				log.Printf("❌ No executor configured for order: %s", order.ID)
-				return
-			}
+// WaitIdle waits up to timeout (0 = no limit) for every execution started so
+// far to finish and reports whether they did. Unlike WaitAll it may be called
+// while new orders keep arriving.
+func (a *AsyncExecutor) WaitIdle(timeout time.Duration) bool {
+	a.mu.Lock()
+	if a.inflight == 0 {
+		a.mu.Unlock()
+		return true
+	}
+	ch := make(chan struct{})
+	a.idle = append(a.idle, ch)
+	a.mu.Unlock()
+
+	if timeout <= 0 {
+		<-ch
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// execute runs order with retries and publishes its result.
+func (a *AsyncExecutor) execute(ctx context.Context, order Order) ExecutionResult {
+	start := time.Now()
+	var err error
+	retryCount := 0
 
-			// Success or non-retryable error
-			if err == nil || !isRetryableError(err) {
+	// Execute with retry logic
+	for attempt := 0; attempt <= a.maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := a.retryBackoff * time.Duration(1<<(attempt-1)) // Exponential backoff
+			log.Printf("🔄 Retrying order %s (attempt %d/%d) after %v", order.ID, attempt, a.maxRetries, backoff)
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
 				break
+			case <-time.After(backoff):
 			}
-			retryCount = attempt + 1
 		}
 
-		result := ExecutionResult{
-			OrderID:    order.ID,
-			Success:    err == nil,
-			Error:      err,
-			Latency:    time.Since(start),
-			Timestamp:  time.Now(),
-			RetryCount: retryCount,
+		// Execute using appropriate executor
+		if a.dryRunner != nil {
+			err = a.dryRunner.Execute(ctx, order)
+		} else if a.executor != nil {
+			err = a.executor.Handle(ctx, order)
+		} else {
+			log.Printf("❌ No executor configured for order: %s", order.ID)
+			return ExecutionResult{OrderID: order.ID, Error: errors.New("no executor configured"), Timestamp: time.Now()}
 		}
 
-		if err != nil {
-			result.ErrorMsg = err.Error()
-			if retryCount > 0 {
-				log.Printf("❌ Order %s failed after %d retries: %v (latency: %v)", order.ID, retryCount, err, result.Latency)
-			} else {
-				log.Printf("❌ Order %s failed: %v (latency: %v)", order.ID, err, result.Latency)
-			}
-		} else {
-			if retryCount > 0 {
-				log.Printf("✅ Order %s executed after %d retries (latency: %v)", order.ID, retryCount, result.Latency)
-			} else {
-				log.Printf("✅ Order %s executed (latency: %v)", order.ID, result.Latency)
-			}
+		// Success or non-retryable error
+		if err == nil || !isRetryableError(err) {
+			break
 		}
+		retryCount = attempt + 1
+	}
+
+	result := ExecutionResult{
+		OrderID:    order.ID,
+		Success:    err == nil,
+		Error:      err,
+		Latency:    time.Since(start),
+		Timestamp:  time.Now(),
+		RetryCount: retryCount,
+	}
 
-		// Send result (non-blocking)
-		select {
-		case a.resultCh <- result:
-		default:
-			log.Printf("⚠️ Result channel full, dropping result for %s", order.ID)
+	if err != nil {
+		result.ErrorMsg = err.Error()
+		if retryCount > 0 {
+			log.Printf("❌ Order %s failed after %d retries: %v (latency: %v)", order.ID, retryCount, err, result.Latency)
+		} else {
+			log.Printf("❌ Order %s failed: %v (latency: %v)", order.ID, err, result.Latency)
 		}
-	}()
+	} else {
+		if retryCount > 0 {
+			log.Printf("✅ Order %s executed after %d retries (latency: %v)", order.ID, retryCount, result.Latency)
+		} else {
+			log.Printf("✅ Order %s executed (latency: %v)", order.ID, result.Latency)
+		}
+	}
+
+	// Send result (non-blocking)
+	select {
+	case a.resultCh <- result:
+	default:
+		log.Printf("⚠️ Result channel full, dropping result for %s", order.ID)
+	}
+	return result
 }
 
 // Results returns the result channel for monitoring.
@@ -0,0 +1,64 @@
+package order
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"trading-core/pkg/db"
+	exspot "trading-core/pkg/exchanges/binance/spot"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+type countingGateway struct{ submits int }
+
+func (g *countingGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	g.submits++
+	return exchange.OrderResult{ExchangeOrderID: "x1", Status: exchange.StatusNew}, nil
+}
+
+func (g *countingGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+type staticPool struct{ gw exchange.Gateway }
+
+func (p staticPool) GetOrCreate(ctx context.Context, userID, connectionID string) (exchange.Gateway, error) {
+	return p.gw, nil
+}
+
+func TestExecutorRejectsOrderWithoutSpotPermission(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	// Margin-only key: account info reports no SPOT permission.
+	info := &exspot.AccountInfo{CanTrade: true, AccountType: "MARGIN", Permissions: []string{"MARGIN"}}
+	ctx := context.Background()
+	if err := database.Queries().CreateConnectionEncrypted(ctx, db.Connection{
+		ID: "c1", UserID: "u1", ExchangeType: "binance-spot", Name: "margin-key",
+		Capabilities: exchange.FormatCapabilities(info.Capabilities()),
+	}); err != nil {
+		t.Fatalf("CreateConnectionEncrypted: %v", err)
+	}
+
+	gw := &countingGateway{}
+	exec := NewExecutor(database, nil, nil, "test", false)
+	exec.SetGatewayPool(staticPool{gw: gw})
+
+	err = exec.Handle(ctx, Order{
+		ID: "o1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Qty: 1,
+		Market: string(exchange.MarketSpot), UserID: "u1", ConnectionID: "c1",
+	})
+	if err == nil || !strings.Contains(err.Error(), "SPOT") {
+		t.Fatalf("expected SPOT permission error, got %v", err)
+	}
+	if gw.submits != 0 {
+		t.Fatalf("order should not reach the gateway, got %d submits", gw.submits)
+	}
+}
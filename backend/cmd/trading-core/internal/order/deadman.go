@@ -0,0 +1,102 @@
+package order
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// CountdownArmer is a futures gateway with an exchange-side auto-cancel
+// timer (Binance countdownCancelAll).
+type CountdownArmer interface {
+	CountdownCancelAll(ctx context.Context, symbol string, countdown time.Duration) error
+}
+
+// DeadmanConnection is a live futures account to keep armed.
+type DeadmanConnection struct {
+	ConnectionID string // empty for the global gateway
+	Gateway      exchange.Gateway
+	Symbols      []string // symbols it trades; symbols with open orders are added
+}
+
+// DeadmanSwitch keeps the exchange's auto-cancel countdown armed on every
+// listed futures account. Each heartbeat re-arms the symbols the account
+// trades and those it has open orders on; if the process dies or loses
+// connectivity, the heartbeats stop and the exchange cancels the orders once
+// Countdown elapses. Refresh must be below Countdown (see config).
+type DeadmanSwitch struct {
+	List      func(ctx context.Context) ([]DeadmanConnection, error)
+	Countdown time.Duration
+	Refresh   time.Duration
+}
+
+// NewDeadmanSwitch creates a switch over the accounts list returns.
+func NewDeadmanSwitch(list func(ctx context.Context) ([]DeadmanConnection, error), countdown, refresh time.Duration) *DeadmanSwitch {
+	return &DeadmanSwitch{List: list, Countdown: countdown, Refresh: refresh}
+}
+
+// Start arms now and then every Refresh until ctx is done.
+func (d *DeadmanSwitch) Start(ctx context.Context) {
+	if d.Countdown <= 0 || d.Refresh <= 0 || d.Refresh >= d.Countdown {
+		log.Printf("⚠️ Dead-man's switch not started: refresh %s must be below countdown %s", d.Refresh, d.Countdown)
+		return
+	}
+	d.Arm(ctx)
+	go func() {
+		ticker := time.NewTicker(d.Refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.Arm(ctx)
+			}
+		}
+	}()
+}
+
+// Arm sends one heartbeat to every listed account that supports the countdown.
+func (d *DeadmanSwitch) Arm(ctx context.Context) {
+	conns, err := d.List(ctx)
+	if err != nil {
+		log.Printf("⚠️ Dead-man's switch: list connections: %v", err)
+	}
+	for _, c := range conns {
+		armer, ok := c.Gateway.(CountdownArmer)
+		if !ok {
+			continue
+		}
+		for _, sym := range d.symbols(ctx, c) {
+			if err := armer.CountdownCancelAll(ctx, sym, d.Countdown); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️ Dead-man's switch: arm countdown for %s on connection %q failed: %v", sym, c.ConnectionID, err)
+			}
+		}
+	}
+}
+
+// symbols returns the connection's traded symbols plus those with open orders.
+func (d *DeadmanSwitch) symbols(ctx context.Context, c DeadmanConnection) []string {
+	set := make(map[string]bool, len(c.Symbols))
+	for _, s := range c.Symbols {
+		set[s] = true
+	}
+	if lister, ok := c.Gateway.(exchange.OpenOrderLister); ok {
+		open, err := lister.ListOpenOrders(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Dead-man's switch: list open orders on connection %q: %v", c.ConnectionID, err)
+		}
+		for _, o := range open {
+			set[o.Symbol] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,59 @@
+package order
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// countdownGateway records the symbols armed on it.
+type countdownGateway struct {
+	mu    sync.Mutex
+	armed []string
+	open  []exchange.OpenOrder
+}
+
+func (g *countdownGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{}, nil
+}
+
+func (g *countdownGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func (g *countdownGateway) CountdownCancelAll(ctx context.Context, symbol string, countdown time.Duration) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.armed = append(g.armed, symbol)
+	return nil
+}
+
+func (g *countdownGateway) ListOpenOrders(ctx context.Context) ([]exchange.OpenOrder, error) {
+	return g.open, nil
+}
+
+func TestDeadmanSwitchArmsEveryFuturesConnection(t *testing.T) {
+	global := &countdownGateway{}
+	user := &countdownGateway{open: []exchange.OpenOrder{{Symbol: "ETHUSDT"}, {Symbol: "BTCUSDT"}}}
+	d := NewDeadmanSwitch(func(ctx context.Context) ([]DeadmanConnection, error) {
+		return []DeadmanConnection{
+			{Gateway: global, Symbols: []string{"BTCUSDT"}},
+			{ConnectionID: "c1", Gateway: user, Symbols: []string{"BTCUSDT"}},
+			{ConnectionID: "spot", Gateway: &countingGateway{}}, // no countdown: skipped
+		}, nil
+	}, 2*time.Minute, 30*time.Second)
+
+	d.Arm(context.Background())
+	if !reflect.DeepEqual(global.armed, []string{"BTCUSDT"}) {
+		t.Fatalf("global gateway armed %v, want [BTCUSDT]", global.armed)
+	}
+	sort.Strings(user.armed)
+	if !reflect.DeepEqual(user.armed, []string{"BTCUSDT", "ETHUSDT"}) {
+		t.Fatalf("connection c1 armed %v, want its strategy and open-order symbols once each", user.armed)
+	}
+}
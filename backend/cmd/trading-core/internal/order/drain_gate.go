@@ -0,0 +1,70 @@
+package order
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// DrainGate lets a reconciliation pass hold off the order queue drain while
+// it corrects local state, so queued orders are not evaluated against state
+// that is about to change. Orders already being handled finish first,
+// including executions the drain handed to async workers when an in-flight
+// waiter is set.
+//
+// A pause is released by the returned resume func or, at the latest, after
+// MaxPause, so a slow or stuck pass cannot stall trading.
+type DrainGate struct {
+	mu       sync.RWMutex
+	MaxPause time.Duration
+	inFlight func(timeout time.Duration) bool
+}
+
+// SetInFlightWaiter sets how Pause waits for executions that outlive the
+// drain handler (e.g. AsyncExecutor.WaitIdle); it reports whether they
+// finished within timeout.
+func (g *DrainGate) SetInFlightWaiter(wait func(timeout time.Duration) bool) {
+	g.inFlight = wait
+}
+
+// NewDrainGate creates a gate whose pauses last at most maxPause (0 = until resumed).
+func NewDrainGate(maxPause time.Duration) *DrainGate {
+	return &DrainGate{MaxPause: maxPause}
+}
+
+// Pause waits for in-flight orders to finish and blocks further ones until
+// resume is called. resume is safe to call more than once.
+func (g *DrainGate) Pause() (resume func()) {
+	g.mu.Lock()
+	if g.inFlight != nil && !g.inFlight(g.MaxPause) {
+		log.Printf("⚠️ Orders still executing after %v; reconciling anyway", g.MaxPause)
+	}
+	var once sync.Once
+	var timer *time.Timer
+	release := func(expired bool) {
+		once.Do(func() {
+			if expired {
+				log.Printf("⚠️ Order drain pause exceeded %v; resuming", g.MaxPause)
+			}
+			g.mu.Unlock()
+		})
+	}
+	if g.MaxPause > 0 {
+		timer = time.AfterFunc(g.MaxPause, func() { release(true) })
+	}
+	return func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		release(false)
+	}
+}
+
+// Wrap gates handler: each order waits out any active pause before it runs.
+func (g *DrainGate) Wrap(handler func(Order)) func(Order) {
+	return func(o Order) {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		handler(o)
+	}
+}
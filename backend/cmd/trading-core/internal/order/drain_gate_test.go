@@ -0,0 +1,98 @@
+package order
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrainGatePausesDuringReconciliation(t *testing.T) {
+	gate := NewDrainGate(time.Second)
+	q := NewQueue(10)
+	handled := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Drain(ctx, gate.Wrap(func(o Order) { handled <- o.ID }))
+
+	q.Enqueue(Order{ID: "before"})
+	select {
+	case id := <-handled:
+		if id != "before" {
+			t.Fatalf("handled %s, want before", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("order was not drained before the pause")
+	}
+
+	// A reconciliation pass starts: queued orders must wait for it.
+	resume := gate.Pause()
+	q.Enqueue(Order{ID: "during"})
+	select {
+	case id := <-handled:
+		t.Fatalf("order %s drained while reconciliation was running", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resume()
+	select {
+	case id := <-handled:
+		if id != "during" {
+			t.Fatalf("handled %s, want during", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drain did not resume after reconciliation")
+	}
+	resume() // idempotent
+}
+
+func TestDrainGatePauseExpires(t *testing.T) {
+	gate := NewDrainGate(20 * time.Millisecond)
+	gate.Pause() // never resumed, e.g. a stuck pass
+
+	done := make(chan struct{})
+	go gate.Wrap(func(Order) { close(done) })(Order{ID: "o1"})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pause did not expire after MaxPause")
+	}
+}
+
+func TestDrainGatePauseWaitsForAsyncExecutions(t *testing.T) {
+	async := NewAsyncExecutor(nil, 2)
+	gate := NewDrainGate(time.Second)
+	gate.SetInFlightWaiter(async.WaitIdle)
+
+	// An order the drain already handed to a worker is still executing.
+	slot, ok := async.acquire(Order{ID: "o1"})
+	if !ok {
+		t.Fatal("acquire failed")
+	}
+	paused := make(chan func())
+	go func() { paused <- gate.Pause() }()
+	select {
+	case <-paused:
+		t.Fatal("reconciliation started while an order was executing")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	async.release(slot)
+	select {
+	case resume := <-paused:
+		resume()
+	case <-time.After(time.Second):
+		t.Fatal("pause did not start after the execution finished")
+	}
+}
+
+func TestAsyncExecutorWaitIdleTimesOut(t *testing.T) {
+	async := NewAsyncExecutor(nil, 1)
+	if !async.WaitIdle(time.Millisecond) {
+		t.Fatal("idle executor reported busy")
+	}
+	slot, _ := async.acquire(Order{ID: "o1"})
+	defer async.release(slot)
+	if async.WaitIdle(20 * time.Millisecond) {
+		t.Fatal("WaitIdle returned true with an execution in flight")
+	}
+}
@@ -5,14 +5,15 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
-
 	"trading-core/internal/events"
 	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+	"trading-core/pkg/ids"
 )
 
 // ExecutionMode controls real vs dry-run.
@@ -29,14 +30,32 @@ type DryRunExecutor struct {
 	realExec *Executor
 	mockExec *MockExecutor
 	cfg      DryRunSimConfig
+	fees     *FeeResolver
 	rng      *rand.Rand
+	rngMu    sync.Mutex
 }
 
 type DryRunSimConfig struct {
-	FeeRate             float64 // decimal, e.g. 0.0004 = 4 bps
-	SlippageBps         float64 // basis points of slippage applied on fills
-	GatewayLatencyMinMs int     // simulated gateway latency lower bound
-	GatewayLatencyMaxMs int     // simulated gateway latency upper bound
+	FeeRate             float64     // decimal, e.g. 0.0004 = 4 bps
+	Fees                FeeSchedule // default maker/taker schedule; zero value = flat FeeRate
+	SlippageBps         float64     // basis points of slippage applied on fills
+	GatewayLatencyMinMs int         // simulated gateway latency lower bound
+	GatewayLatencyMaxMs int         // simulated gateway latency upper bound
+	// InitialAssets seeds the paper wallet with extra holdings (e.g. {"BTC": 0.5});
+	// the initial balance is always credited to the default quote asset.
+	InitialAssets map[string]float64
+	// Rejection simulation for exercising failure handling: each order is
+	// rejected with RejectProbability (0..1), and orders matching any of
+	// RejectRules are always rejected.
+	RejectProbability float64
+	RejectRules       []SimRejectRule
+	// ValidateFilters rejects orders that break the symbol's exchange-info
+	// filters (below LOT_SIZE minimum, off the step, below min notional)
+	// with the same error the venue returns.
+	ValidateFilters bool
+	// Seed makes latency, slippage and rejection draws reproducible
+	// (e.g. for backtests); 0 seeds from the clock.
+	Seed int64
 }
 
 func NewDryRunExecutor(mode ExecutionMode, real *Executor, initialBalance float64, cfg DryRunSimConfig) *DryRunExecutor {
@@ -45,34 +64,71 @@ func NewDryRunExecutor(mode ExecutionMode, real *Executor, initialBalance float6
 	if max > 0 && min > max {
 		min, max = max, min
 	}
+	defFees := cfg.Fees
+	if defFees == (FeeSchedule{}) {
+		defFees = FlatFeeSchedule(cfg.FeeRate)
+	}
+	fees := &FeeResolver{Default: defFees}
+	if real != nil {
+		fees.DB = real.DB
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	mock := NewMockExecutor(initialBalance)
+	for asset, amt := range cfg.InitialAssets {
+		mock.wallet[strings.ToUpper(asset)] += amt
+	}
 	return &DryRunExecutor{
 		mode:     mode,
 		realExec: real,
-		mockExec: NewMockExecutor(initialBalance),
+		mockExec: mock,
 		cfg:      cfg,
-		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		fees:     fees,
+		rng:      rand.New(rand.NewSource(seed)),
 	}
 }
 
-// Execute routes orders to either real or mock executor.
+// Execute routes orders to either real or mock executor. Orders on paper
+// connections are simulated even in production mode.
 func (d *DryRunExecutor) Execute(ctx context.Context, o Order) error {
-	if d.mode == ModeDryRun {
-		// Apply slippage + fee simulation to bring DRY RUN closer to production.
-		price := o.Price
-		if price <= 0 {
-			price = 1 // guard to avoid zero; will be replaced downstream by cached price for PnL
-		}
-		slippageFrac := d.cfg.SlippageBps / 10000.0
-		if slippageFrac > 0 && d.rng != nil {
-			noise := d.rng.Float64() * slippageFrac
-			if strings.ToUpper(o.Side) == "BUY" {
-				price = price * (1 + noise)
-			} else {
-				price = price * (1 - noise)
+	paper := false
+	if d.mode != ModeDryRun && d.realExec != nil {
+		var err error
+		if paper, err = d.realExec.isPaperOrder(ctx, o); err != nil {
+			// Unknown whether the connection is paper: never risk sending it live.
+			err = fmt.Errorf("order %s not sent: %w", o.ID, err)
+			log.Printf("executor: rejecting order %s: %v", o.ID, err)
+			if d.realExec.Bus != nil {
+				d.realExec.Bus.Publish(events.EventOrderRejected, err.Error())
 			}
+			return err
+		}
+	}
+	if paper {
+		log.Printf("DRY-RUN: order %s is on paper connection, simulating", o.ID)
+	}
+	if d.mode == ModeDryRun || paper {
+		adj, rej := d.filterRejection(o)
+		if rej != nil {
+			d.recordRejection(ctx, o, rej)
+			return rej
+		}
+		o = adj
+		if rej := d.simulateRejection(o); rej != nil {
+			d.recordRejection(ctx, o, rej)
+			return rej
 		}
+
+		// Apply slippage + fee simulation to bring DRY RUN closer to production.
+		price := d.fillPrice(o)
 		orderWithPrice := o
 		orderWithPrice.Price = price
+		if orderWithPrice.Market == "" && d.realExec != nil {
+			// Only spot fills move the base asset; futures SELLs open shorts.
+			orderWithPrice.Market = string(d.realExec.marketForOrder(ctx, o))
+		}
 
 		// Simulate gateway latency and emit into metrics (even when skipping exchange).
 		if d.realExec != nil && d.realExec.Metrics != nil {
@@ -87,8 +143,8 @@ func (d *DryRunExecutor) Execute(ctx context.Context, o Order) error {
 				}
 				span := maxMs - minMs
 				delayMs := minMs
-				if span > 0 && d.rng != nil {
-					delayMs += d.rng.Intn(span + 1)
+				if span > 0 {
+					delayMs += d.randIntn(span + 1)
 				}
 				delay := time.Duration(delayMs) * time.Millisecond
 				if delay > 0 {
@@ -100,39 +156,37 @@ func (d *DryRunExecutor) Execute(ctx context.Context, o Order) error {
 
 		// 1) Persist order to DB and emit order events, but do NOT hit exchange.
 		if d.realExec != nil {
-			// Temporarily skip any external gateway so Executor.Handle only stores to DB.
-			d.realExec.SkipExchange = true
-			// We ignore error here since Handle might fail if gateway lookup fails (which is fine in dry run skipping)
-			// But if DB fails, we should probably know.
-			// Currently Handle returns error if gateway lookup fails but we want to ignore that in DryRun?
-			// Actually Handle checks SkipExchange first and doesn't lookup gateway.
-			// So if Handle fails here, it's likely DB error.
-			if err := d.realExec.Handle(ctx, orderWithPrice); err != nil {
+			// Skip any external gateway so Executor.Handle only stores to DB. The
+			// flag rides on the context: live orders may run concurrently.
+			// Handle does not look up a gateway when skipping, so an error here
+			// is likely a DB error.
+			if err := d.realExec.Handle(withSkipExchange(ctx), orderWithPrice); err != nil {
 				log.Printf("DRY-RUN: Warning, persistence failed: %v", err)
 				// We don't block dry-run execution on DB failure, maybe?
 				// But let's return error if we want to be strict.
 				// For now let's just log and continue simulation.
 			}
-			d.realExec.SkipExchange = false
 		}
 
 		// 2) Run in-memory simulation for PnL / balance / positions.
-		if err := d.mockExec.Execute(orderWithPrice, d.cfg.FeeRate); err != nil {
+		feeRate := d.fees.Resolve(ctx, o.UserID, o.ConnectionID).Rate(IsMakerOrder(o))
+		if err := d.mockExec.Execute(orderWithPrice, feeRate); err != nil {
 			fmt.Printf("DRY-RUN execute error: %v\n", err)
 			return err
 		}
 
 		// 3) Store a synthetic trade + emit filled event to exercise downstream logic.
 		if d.realExec != nil && d.realExec.DB != nil {
-			fee := price * o.Qty * d.cfg.FeeRate
+			fee := price * o.Qty * feeRate
 			trade := db.Trade{
-				ID:        uuid.NewString(),
+				ID:        ids.New(),
 				OrderID:   o.ID,
 				Symbol:    o.Symbol,
 				Side:      o.Side,
 				Price:     price,
 				Qty:       o.Qty,
 				Fee:       fee,
+				UserID:    o.UserID,
 				CreatedAt: time.Now(),
 			}
 			if err := d.realExec.DB.CreateTrade(ctx, trade); err != nil {
@@ -140,26 +194,93 @@ func (d *DryRunExecutor) Execute(ctx context.Context, o Order) error {
 			}
 		}
 		if d.realExec != nil && d.realExec.Bus != nil {
-			d.realExec.Bus.Publish(events.EventOrderFilled, struct {
-				ID     string
-				Symbol string
-				Side   string
-				Qty    float64
-				Price  float64
-			}{
-				ID:     o.ID,
-				Symbol: o.Symbol,
-				Side:   o.Side,
-				Qty:    o.Qty,
-				Price:  price,
-			})
+			// Carry the owner and connection; fills on paper connections in
+			// production are marked so they stay out of the live books.
+			fill := o
+			fill.Price = price
+			fill.Status = "FILLED"
+			fill.FilledQty = o.Qty
+			fill.Paper = paper
+			d.realExec.Bus.Publish(events.EventOrderFilled, fill)
 		}
 		return nil
 	}
 	return d.realExec.Handle(ctx, o)
 }
 
-// PrintState prints current mock positions and balance for inspection.
+// fillPrice applies simulated slippage to the order price.
+func (d *DryRunExecutor) fillPrice(o Order) float64 {
+	price := o.Price
+	if price <= 0 {
+		price = 1 // guard to avoid zero; will be replaced downstream by cached price for PnL
+	}
+	slippageFrac := d.cfg.SlippageBps / 10000.0
+	if slippageFrac > 0 {
+		noise := d.randFloat64() * slippageFrac
+		if strings.ToUpper(o.Side) == "BUY" {
+			price = price * (1 + noise)
+		} else {
+			price = price * (1 - noise)
+		}
+	}
+	return price
+}
+
+// SimulatedFill is the outcome of a what-if execution against the paper wallet.
+type SimulatedFill struct {
+	Price        float64 // fill price after slippage
+	Fee          float64 // commission in the quote asset
+	FeeRate      float64
+	Maker        bool
+	QuoteAsset   string
+	QuoteBalance float64 // quote balance after the fill
+}
+
+// Simulate fills o against the paper wallet with slippage and fees, but unlike
+// Execute it never persists, publishes events, sleeps or rolls rejections.
+func (d *DryRunExecutor) Simulate(ctx context.Context, o Order) (SimulatedFill, error) {
+	price := d.fillPrice(o)
+	maker := IsMakerOrder(o)
+	rate := d.fees.Resolve(ctx, o.UserID, o.ConnectionID).Rate(maker)
+	filled := o
+	filled.Price = price
+	if err := d.mockExec.Execute(filled, rate); err != nil {
+		return SimulatedFill{}, err
+	}
+	_, quote := exchange.SplitSymbol(o.Symbol)
+	if quote == "" {
+		quote = defaultQuoteAsset
+	}
+	return SimulatedFill{
+		Price:        price,
+		Fee:          price * o.Qty * rate,
+		FeeRate:      rate,
+		Maker:        maker,
+		QuoteAsset:   quote,
+		QuoteBalance: d.mockExec.Balance(quote),
+	}, nil
+}
+
+// SetFeeDB lets a runner without a real executor resolve per-connection fee schedules.
+func (d *DryRunExecutor) SetFeeDB(database *db.Database) {
+	d.fees.DB = database
+}
+
+// randFloat64 and randIntn serialize access to the shared source; orders are
+// executed from several async workers.
+func (d *DryRunExecutor) randFloat64() float64 {
+	d.rngMu.Lock()
+	defer d.rngMu.Unlock()
+	return d.rng.Float64()
+}
+
+func (d *DryRunExecutor) randIntn(n int) int {
+	d.rngMu.Lock()
+	defer d.rngMu.Unlock()
+	return d.rng.Intn(n)
+}
+
+// PrintState prints current mock positions and per-asset balances for inspection.
 func (d *DryRunExecutor) PrintState() {
 	if d.mode != ModeDryRun || d.mockExec == nil {
 		return
@@ -167,10 +288,15 @@ func (d *DryRunExecutor) PrintState() {
 	d.mockExec.printState()
 }
 
+// defaultQuoteAsset holds the initial balance and settles symbols whose quote is unknown.
+const defaultQuoteAsset = "USDT"
+
 // MockExecutor simulates order execution and simple PnL.
+// Cash is kept in a multi-asset paper wallet keyed by asset; spot fills move both
+// base and quote, while futures fills only settle in the quote asset.
 type MockExecutor struct {
 	positions map[string]*MockPosition
-	balance   float64
+	wallet    map[string]float64
 	orders    []MockOrder
 	mu        sync.RWMutex
 }
@@ -196,18 +322,36 @@ type MockOrder struct {
 func NewMockExecutor(initialBalance float64) *MockExecutor {
 	return &MockExecutor{
 		positions: make(map[string]*MockPosition),
-		balance:   initialBalance,
+		wallet:    map[string]float64{defaultQuoteAsset: initialBalance},
 	}
 }
 
+// Balance returns the paper wallet balance of a single asset.
+func (m *MockExecutor) Balance(asset string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.wallet[strings.ToUpper(asset)]
+}
+
 func (m *MockExecutor) Execute(o Order, feeRate float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	side := strings.ToUpper(o.Side)
+	base, quote := exchange.SplitSymbol(o.Symbol)
+	if quote == "" {
+		quote = defaultQuoteAsset
+	}
+	market := strings.ToUpper(o.Market)
+	spot := base != "" && (market == "" || market == "SPOT")
+
 	// Use provided price; if zero, treat as market and skip balance check.
 	orderValue := o.Qty * o.Price
-	if o.Price > 0 && orderValue > m.balance && strings.ToUpper(o.Side) == "BUY" {
-		return fmt.Errorf("insufficient balance: need %.2f, have %.2f", orderValue, m.balance)
+	if o.Price > 0 && orderValue > m.wallet[quote] && side == "BUY" {
+		return fmt.Errorf("insufficient balance: need %.2f %s, have %.2f", orderValue, quote, m.wallet[quote])
+	}
+	if spot && side == "SELL" && o.Qty > m.wallet[base]+1e-12 {
+		return fmt.Errorf("insufficient %s: need %.8f, have %.8f", base, o.Qty, m.wallet[base])
 	}
 
 	now := time.Now()
@@ -226,20 +370,27 @@ func (m *MockExecutor) Execute(o Order, feeRate float64) error {
 	// Update position
 	m.updatePosition(mockOrder)
 
-	// Update balance (simple cash accounting)
+	// Update wallet: fees are always charged in the quote asset.
 	fee := mathAbs(orderValue) * feeRate
 	if o.Price > 0 {
-		if strings.ToUpper(o.Side) == "BUY" {
-			m.balance -= orderValue
-			m.balance -= fee
-		} else if strings.ToUpper(o.Side) == "SELL" {
-			m.balance += orderValue
-			m.balance -= fee
+		if side == "BUY" {
+			m.wallet[quote] -= orderValue
+			m.wallet[quote] -= fee
+		} else if side == "SELL" {
+			m.wallet[quote] += orderValue
+			m.wallet[quote] -= fee
+		}
+	}
+	if spot {
+		if side == "BUY" {
+			m.wallet[base] += o.Qty
+		} else if side == "SELL" {
+			m.wallet[base] -= o.Qty
 		}
 	}
 
-	fmt.Printf("DRY-RUN: %s %s qty=%.4f price=%.4f balance=%.2f\n",
-		o.Side, o.Symbol, o.Qty, o.Price, m.balance)
+	fmt.Printf("DRY-RUN: %s %s qty=%.4f price=%.4f %s=%.2f\n",
+		o.Side, o.Symbol, o.Qty, o.Price, quote, m.wallet[quote])
 	return nil
 }
 
@@ -272,7 +423,15 @@ func (m *MockExecutor) updatePosition(o MockOrder) {
 func (m *MockExecutor) printState() {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	fmt.Printf("DRY-RUN STATE: balance=%.2f\n", m.balance)
+	fmt.Println("DRY-RUN STATE:")
+	assets := make([]string, 0, len(m.wallet))
+	for asset := range m.wallet {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	for _, asset := range assets {
+		fmt.Printf("  wallet %s=%.8f\n", asset, m.wallet[asset])
+	}
 	for sym, pos := range m.positions {
 		fmt.Printf("  pos %s side=%s qty=%.4f entry=%.4f\n", sym, pos.Side, pos.Quantity, pos.EntryPrice)
 	}
@@ -0,0 +1,172 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"trading-core/internal/events"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// SimRejectReason selects which exchange rejection the dry runner imitates.
+type SimRejectReason string
+
+const (
+	SimRejectInsufficientBalance SimRejectReason = "INSUFFICIENT_BALANCE"
+	SimRejectMinNotional         SimRejectReason = "MIN_NOTIONAL"
+	SimRejectRateLimit           SimRejectReason = "RATE_LIMIT"
+	// Filter rejections are never drawn at random; they come from
+	// filterRejection when the order breaks the symbol's exchange filters.
+	SimRejectLotSize            SimRejectReason = "LOT_SIZE"
+	SimRejectFuturesMinNotional SimRejectReason = "FUTURES_MIN_NOTIONAL"
+)
+
+var simRejectReasons = []SimRejectReason{SimRejectInsufficientBalance, SimRejectMinNotional, SimRejectRateLimit}
+
+// SimRejectRule forces rejection of matching orders in dry-run mode.
+type SimRejectRule struct {
+	Symbol string          // empty matches any symbol
+	Side   string          // BUY/SELL; empty matches both
+	Reason SimRejectReason // empty picks a random reason
+}
+
+func (r SimRejectRule) matches(o Order) bool {
+	if r.Symbol != "" && !strings.EqualFold(r.Symbol, o.Symbol) {
+		return false
+	}
+	if r.Side != "" && !strings.EqualFold(r.Side, o.Side) {
+		return false
+	}
+	return true
+}
+
+// SimulatedRejectError mimics the error returned by the Binance clients when an
+// order is refused, so downstream handlers see the same shape as production.
+type SimulatedRejectError struct {
+	Reason SimRejectReason
+	Status int
+	Code   int
+	Msg    string
+}
+
+func (e *SimulatedRejectError) Error() string {
+	return fmt.Sprintf("binance POST /api/v3/order status %d: {\"code\":%d,\"msg\":%q}", e.Status, e.Code, e.Msg)
+}
+
+func newSimulatedReject(reason SimRejectReason) *SimulatedRejectError {
+	switch reason {
+	case SimRejectMinNotional:
+		return &SimulatedRejectError{Reason: reason, Status: 400, Code: -1013, Msg: "Filter failure: NOTIONAL"}
+	case SimRejectLotSize:
+		return &SimulatedRejectError{Reason: reason, Status: 400, Code: -1013, Msg: "Filter failure: LOT_SIZE"}
+	case SimRejectFuturesMinNotional:
+		return &SimulatedRejectError{Reason: reason, Status: 400, Code: -4164, Msg: "Order's notional must be no smaller than minimum notional (unless you choose reduce only)."}
+	case SimRejectRateLimit:
+		return &SimulatedRejectError{Reason: reason, Status: 429, Code: -1015, Msg: "Too many new orders; current limit is 50 orders per 10 SECONDS."}
+	default:
+		return &SimulatedRejectError{Reason: SimRejectInsufficientBalance, Status: 400, Code: -2010, Msg: "Account has insufficient balance for requested action."}
+	}
+}
+
+// simulateRejection returns a rejection when a rule matches the order or the
+// RejectProbability roll fails; nil lets the order through.
+func (d *DryRunExecutor) simulateRejection(o Order) *SimulatedRejectError {
+	for _, r := range d.cfg.RejectRules {
+		if r.matches(o) {
+			if r.Reason != "" {
+				return newSimulatedReject(r.Reason)
+			}
+			return newSimulatedReject(d.randomRejectReason())
+		}
+	}
+	p := d.cfg.RejectProbability
+	if p <= 0 {
+		return nil
+	}
+	if p < 1 && d.randFloat64() >= p {
+		return nil
+	}
+	return newSimulatedReject(d.randomRejectReason())
+}
+
+// filterRejection checks the order against the symbol's exchange-info filters
+// (LOT_SIZE and minimum notional) the way the live path ends up applying them:
+// the executor rounds an order the venue refuses for precision to the lot step
+// and tick and retries, so the order is rounded here too and only rejected
+// when the rounded quantity or notional is below the minimum. It returns the
+// (rounded) order, and a nil rejection when it passes or no filters are known
+// for the symbol.
+func (d *DryRunExecutor) filterRejection(o Order) (Order, *SimulatedRejectError) {
+	if !d.cfg.ValidateFilters {
+		return o, nil
+	}
+	market := exchange.MarketType(o.Market)
+	info, ok := exchange.LookupOrderSymbol(market, o.Symbol)
+	if !ok {
+		return o, nil
+	}
+	adj := o
+	adj.Qty = info.RoundQty(o.Qty)
+	adj.Price = info.RoundPrice(o.Price)
+	adj.StopPrice = info.RoundPrice(o.StopPrice)
+	if adj.Qty <= 0 || (info.MinQty > 0 && adj.Qty < info.MinQty) {
+		return o, newSimulatedReject(SimRejectLotSize)
+	}
+	if adj.Qty != o.Qty || adj.Price != o.Price || adj.StopPrice != o.StopPrice {
+		log.Printf("DRY-RUN: order %s rounded to the symbol's filters: qty %v->%v price %v->%v",
+			o.ID, o.Qty, adj.Qty, o.Price, adj.Price)
+	}
+	price := adj.Price
+	if price <= 0 {
+		price = adj.RefPrice
+	}
+	if info.MinNotional <= 0 || price <= 0 || price*adj.Qty >= info.MinNotional {
+		return adj, nil
+	}
+	if market == exchange.MarketUSDTFut || market == exchange.MarketCoinFut {
+		if o.ReduceOnly {
+			return adj, nil
+		}
+		return o, newSimulatedReject(SimRejectFuturesMinNotional)
+	}
+	return o, newSimulatedReject(SimRejectMinNotional)
+}
+
+func (d *DryRunExecutor) randomRejectReason() SimRejectReason {
+	return simRejectReasons[d.randIntn(len(simRejectReasons))]
+}
+
+// recordRejection persists the order as REJECTED and emits the same events the
+// real executor does when a gateway refuses an order.
+func (d *DryRunExecutor) recordRejection(ctx context.Context, o Order, rej *SimulatedRejectError) {
+	log.Printf("DRY-RUN: simulated rejection of order %s (%s): %v", o.ID, rej.Reason, rej)
+	if d.realExec == nil {
+		return
+	}
+	model := db.Order{
+		ID:                 o.ID,
+		StrategyInstanceID: o.StrategyInstanceID,
+		Symbol:             o.Symbol,
+		Side:               o.Side,
+		Price:              o.Price,
+		Qty:                o.Qty,
+		Status:             "REJECTED",
+		UserID:             o.UserID,
+		Note:               o.Note,
+		Tags:               o.Tags,
+		CreatedAt:          time.Now(),
+	}
+	if d.realExec.DB != nil {
+		if err := d.realExec.DB.CreateOrder(ctx, model); err != nil {
+			log.Printf("DRY-RUN: store rejected order error: %v", err)
+		}
+	}
+	if d.realExec.Bus != nil {
+		d.realExec.Bus.Publish(events.EventOrderRejected, rej.Error())
+		d.realExec.Bus.Publish(events.EventOrderUpdate, model)
+	}
+}
@@ -0,0 +1,254 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"trading-core/internal/events"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+func TestDryRunSpotWalletBuyThenSell(t *testing.T) {
+	dry := NewDryRunExecutor(ModeDryRun, nil, 1000, DryRunSimConfig{})
+	ctx := context.Background()
+
+	if err := dry.Execute(ctx, Order{ID: "b1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 0.5}); err != nil {
+		t.Fatalf("buy: %v", err)
+	}
+	if got := dry.mockExec.Balance("USDT"); math.Abs(got-950) > 1e-9 {
+		t.Fatalf("expected 950 USDT after buy, got %.8f", got)
+	}
+	if got := dry.mockExec.Balance("BTC"); math.Abs(got-0.5) > 1e-9 {
+		t.Fatalf("expected 0.5 BTC after buy, got %.8f", got)
+	}
+
+	if err := dry.Execute(ctx, Order{ID: "s1", Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Price: 110, Qty: 0.2}); err != nil {
+		t.Fatalf("sell: %v", err)
+	}
+	if got := dry.mockExec.Balance("USDT"); math.Abs(got-972) > 1e-9 {
+		t.Fatalf("expected 972 USDT after sell, got %.8f", got)
+	}
+	if got := dry.mockExec.Balance("BTC"); math.Abs(got-0.3) > 1e-9 {
+		t.Fatalf("expected 0.3 BTC after sell, got %.8f", got)
+	}
+
+	// Selling more than the wallet holds must be rejected.
+	if err := dry.Execute(ctx, Order{ID: "s2", Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Price: 110, Qty: 1}); err == nil {
+		t.Fatal("expected error selling more BTC than owned")
+	}
+	if got := dry.mockExec.Balance("BTC"); math.Abs(got-0.3) > 1e-9 {
+		t.Fatalf("rejected sell should not change BTC, got %.8f", got)
+	}
+}
+
+func TestDryRunRejectAllReachesFailureHandler(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	bus := events.NewBus()
+	rejected, unsub := bus.Subscribe(events.EventOrderRejected, 1)
+	defer unsub()
+
+	dry := NewDryRunExecutor(ModeDryRun, NewExecutor(database, bus, nil, "test", false), 1000, DryRunSimConfig{RejectProbability: 1})
+	async := NewAsyncExecutorWithDryRun(dry, 1)
+	async.ExecuteAsync(context.Background(), Order{ID: "r1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 0.1})
+
+	select {
+	case res := <-async.Results():
+		if res.Success || res.OrderID != "r1" {
+			t.Fatalf("expected failed result for r1, got %+v", res)
+		}
+		var rej *SimulatedRejectError
+		if !errors.As(res.Error, &rej) || rej.Code >= 0 {
+			t.Fatalf("expected simulated exchange rejection, got %v", res.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for execution result")
+	}
+	select {
+	case <-rejected:
+	case <-time.After(time.Second):
+		t.Fatal("expected order rejected event")
+	}
+	if got := dry.mockExec.Balance("USDT"); got != 1000 {
+		t.Fatalf("rejected order must not touch the paper wallet, got %.8f", got)
+	}
+}
+
+func TestDryRunRejectsOrderBelowMinNotional(t *testing.T) {
+	exchange.RegisterMarketSymbols(exchange.MarketSpot, exchange.SymbolInfo{
+		Symbol: "MINUSDT", BaseAsset: "MIN", QuoteAsset: "USDT",
+		StepSize: 0.001, TickSize: 0.01, MinQty: 0.001, MinNotional: 5,
+	})
+	dry := NewDryRunExecutor(ModeDryRun, nil, 1000, DryRunSimConfig{ValidateFilters: true})
+	ctx := context.Background()
+
+	// 0.01 @ 100 = 1 USDT notional, below the 5 USDT minimum.
+	err := dry.Execute(ctx, Order{ID: "n1", Symbol: "MINUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 0.01})
+	var rej *SimulatedRejectError
+	if !errors.As(err, &rej) {
+		t.Fatalf("expected simulated rejection, got %v", err)
+	}
+	live := newSimulatedReject(SimRejectMinNotional)
+	if rej.Status != live.Status || rej.Code != exchange.CodeFilterFailure || rej.Msg != "Filter failure: NOTIONAL" {
+		t.Fatalf("rejection = %+v, want the live NOTIONAL filter failure", rej)
+	}
+	if got := dry.mockExec.Balance("USDT"); got != 1000 {
+		t.Fatalf("rejected order must not touch the paper wallet, got %.8f", got)
+	}
+
+	// Off the lot step is rounded down like the live retry and still fills.
+	if err := dry.Execute(ctx, Order{ID: "n2", Symbol: "MINUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 0.1005}); err != nil {
+		t.Fatalf("off-step order should be rounded and filled: %v", err)
+	}
+	if n := len(dry.mockExec.orders); n != 1 || math.Abs(dry.mockExec.orders[0].Quantity-0.1) > 1e-12 {
+		t.Fatalf("paper fills = %+v, want one of the rounded qty 0.1", dry.mockExec.orders)
+	}
+
+	// Rounding that drops the notional under the minimum is refused.
+	if err := dry.Execute(ctx, Order{ID: "n3", Symbol: "MINUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 0.0499}); !errors.As(err, &rej) || rej.Reason != SimRejectMinNotional {
+		t.Fatalf("expected NOTIONAL rejection after rounding 0.0499 to 0.049, got %v", err)
+	}
+	// So is a quantity that rounds below the minimum lot.
+	if err := dry.Execute(ctx, Order{ID: "n4", Symbol: "MINUSDT", Side: "BUY", Type: "MARKET", Price: 100000, Qty: 0.0009}); !errors.As(err, &rej) || rej.Reason != SimRejectLotSize {
+		t.Fatalf("expected LOT_SIZE rejection, got %v", err)
+	}
+}
+
+func TestDryRunSeedReproducesFills(t *testing.T) {
+	run := func() []MockOrder {
+		dry := NewDryRunExecutor(ModeDryRun, nil, 100000, DryRunSimConfig{SlippageBps: 25, RejectProbability: 0.3, Seed: 42})
+		for i, side := range []string{"BUY", "BUY", "SELL", "BUY", "SELL", "SELL"} {
+			_ = dry.Execute(context.Background(), Order{ID: string(rune('a' + i)), Symbol: "BTCUSDT", Side: side, Type: "MARKET", Price: 100, Qty: 1})
+		}
+		return dry.mockExec.orders
+	}
+
+	first, second := run(), run()
+	if len(first) == 0 || len(first) != len(second) {
+		t.Fatalf("expected the same number of fills, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].ID != second[i].ID || first[i].Price != second[i].Price {
+			t.Fatalf("fill %d differs: %+v vs %+v", i, first[i], second[i])
+		}
+	}
+	if first[0].Price == 100 {
+		t.Fatal("expected slippage to move the fill price")
+	}
+}
+
+func TestPaperConnectionIsSimulatedInProductionMode(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := database.CreateUser(ctx, db.User{ID: "u1", Email: "u1@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	for _, c := range []db.Connection{
+		{ID: "paper", UserID: "u1", ExchangeType: "binance-spot", Name: "paper", Paper: true},
+		{ID: "live", UserID: "u1", ExchangeType: "binance-spot", Name: "live"},
+	} {
+		if err := database.Queries().CreateConnectionEncrypted(ctx, c); err != nil {
+			t.Fatalf("CreateConnectionEncrypted: %v", err)
+		}
+	}
+
+	gw := &leverageGateway{}
+	bus := events.NewBus()
+	fills, unsub := bus.Subscribe(events.EventOrderFilled, 10)
+	defer unsub()
+	exec := NewExecutor(database, bus, nil, "test", false)
+	exec.SetGatewayPool(staticPool{gw: gw})
+	dry := NewDryRunExecutor(ModeProduction, exec, 1000, DryRunSimConfig{})
+
+	for _, o := range []Order{
+		{ID: "o-paper", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 1, UserID: "u1", ConnectionID: "paper"},
+		{ID: "o-live", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 1, UserID: "u1", ConnectionID: "live"},
+	} {
+		if err := dry.Execute(ctx, o); err != nil {
+			t.Fatalf("Execute %s: %v", o.ID, err)
+		}
+	}
+
+	if len(gw.reqs) != 1 || gw.reqs[0].ClientID != "o-live" {
+		t.Fatalf("only the live order should reach the gateway, got %+v", gw.reqs)
+	}
+	if got := dry.mockExec.Balance("USDT"); got != 900 {
+		t.Fatalf("paper order should fill against the paper wallet, balance %.8f", got)
+	}
+	var stored int
+	if err := database.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE id = 'o-paper'`).Scan(&stored); err != nil || stored != 1 {
+		t.Fatalf("paper order should still be stored (count=%d, err=%v)", stored, err)
+	}
+	// The paper fill carries its owner and is flagged so the live books skip it.
+	var paperFill *Order
+	for len(fills) > 0 {
+		if f, ok := (<-fills).(Order); ok && f.ID == "o-paper" {
+			paperFill = &f
+		}
+	}
+	if paperFill == nil || !paperFill.Paper || paperFill.UserID != "u1" || paperFill.ConnectionID != "paper" {
+		t.Fatalf("paper fill = %+v, want a Paper order for u1 on connection paper", paperFill)
+	}
+
+	// When the paper flag can't be read the order is rejected, not sent live.
+	if _, err := database.DB.ExecContext(ctx, `DROP TABLE connections`); err != nil {
+		t.Fatalf("drop connections: %v", err)
+	}
+	if err := dry.Execute(ctx, Order{ID: "o-unknown", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 1, UserID: "u1", ConnectionID: "paper"}); err == nil {
+		t.Fatal("order with an unreadable paper flag should be rejected")
+	}
+	if len(gw.reqs) != 1 {
+		t.Fatalf("order with an unreadable paper flag reached the gateway: %+v", gw.reqs)
+	}
+}
+
+func TestPaperFuturesSellWithoutHoldingsOpensShort(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := database.CreateUser(ctx, db.User{ID: "u1", Email: "u1@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	for _, c := range []db.Connection{
+		{ID: "fut", UserID: "u1", ExchangeType: "binance-usdtfut", Name: "fut", Paper: true},
+		{ID: "spot", UserID: "u1", ExchangeType: "binance-spot", Name: "spot", Paper: true},
+	} {
+		if err := database.Queries().CreateConnectionEncrypted(ctx, c); err != nil {
+			t.Fatalf("CreateConnectionEncrypted: %v", err)
+		}
+	}
+
+	exec := NewExecutor(database, events.NewBus(), nil, "test", false)
+	dry := NewDryRunExecutor(ModeDryRun, exec, 1000, DryRunSimConfig{})
+
+	// No Market on the order: the futures connection's type decides it.
+	if err := dry.Execute(ctx, Order{ID: "o-fut", Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Price: 100, Qty: 1, UserID: "u1", ConnectionID: "fut"}); err != nil {
+		t.Fatalf("futures SELL without holdings should open a short: %v", err)
+	}
+	if err := dry.Execute(ctx, Order{ID: "o-spot", Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Price: 100, Qty: 1, UserID: "u1", ConnectionID: "spot"}); err == nil {
+		t.Fatal("spot SELL without holdings should be rejected")
+	}
+}
@@ -10,13 +10,16 @@ import (
 
 	"trading-core/internal/events"
 	"trading-core/internal/monitor"
+	"trading-core/internal/risk"
+	"trading-core/internal/tracing"
 	"trading-core/pkg/db"
 	exfutcoin "trading-core/pkg/exchanges/binance/futures_coin"
 	exfutusdt "trading-core/pkg/exchanges/binance/futures_usdt"
 	exspot "trading-core/pkg/exchanges/binance/spot"
 	exchange "trading-core/pkg/exchanges/common"
+	"trading-core/pkg/ids"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // KeyManager interface for API key decryption.
@@ -48,8 +51,50 @@ type Executor struct {
 	// Metrics (optional)
 	Metrics *monitor.SystemMetrics
 
+	// Router handles orders with Routing = RoutingMakerFirst (optional; nil submits them as-is).
+	Router *MakerRouter
+
+	// PostOnlyRetries reprices a LIMIT_MAKER order rejected for crossing the
+	// book (-2010) one tick behind the touch and resubmits it up to this many
+	// times. Book supplies the touch (optional; without it the order steps one
+	// tick from its own price).
+	PostOnlyRetries int
+	Book            BookSource
+
+	// Fills dedups fills reported by both the order response and the user
+	// stream (optional; nil books every synchronous FILLED response).
+	Fills *FillLedger
+
+	// HaltStrategy stops a strategy and flattens its position (drawdown stop).
+	// On error the strategy is left running and the stop is retried on its next fill.
+	HaltStrategy func(ctx context.Context, strategyID, reason string) error
+
+	// Futures leverage ceiling (0 = none); users.max_leverage overrides it.
+	MaxLeverage        int
+	RejectOverLeverage bool // reject instead of clamping to the cap
+
+	// DB writes retry transient (locked/busy) errors WriteRetries times with
+	// doubling backoff. Records of exchange-accepted orders that still cannot be
+	// stored are appended to RecoveryLogPath for reconciliation.
+	WriteRetries    int
+	WriteBackoff    time.Duration
+	RecoveryLogPath string
+	store           recordStore // defaults to DB
+
 	mu           sync.RWMutex
 	connGateways map[string]exchange.Gateway // connection_id -> gateway
+	connInfo     map[string]cachedConnection // user_id/connection_id -> connection and leverage cap
+	halting      map[string]bool             // strategies whose drawdown stop is in progress
+}
+
+// connectionCacheTTL bounds how long checkConnection reuses a connection row
+// and its owner's leverage cap before reading them again.
+const connectionCacheTTL = 30 * time.Second
+
+type cachedConnection struct {
+	conn    *db.Connection
+	userLev int // users.max_leverage (0 = no override)
+	at      time.Time
 }
 
 func NewExecutor(database *db.Database, bus *events.Bus, gw exchange.Gateway, venue string, testnet bool) *Executor {
@@ -59,7 +104,10 @@ func NewExecutor(database *db.Database, bus *events.Bus, gw exchange.Gateway, ve
 		Gateway:      gw,
 		Exchange:     venue,
 		Testnet:      testnet,
+		WriteRetries: 3,
+		WriteBackoff: 50 * time.Millisecond,
 		connGateways: make(map[string]exchange.Gateway),
+		connInfo:     make(map[string]cachedConnection),
 	}
 }
 
@@ -73,12 +121,61 @@ func (e *Executor) SetGatewayPool(pool GatewayPool) {
 	e.Pool = pool
 }
 
+// SetLeverageCap configures the futures leverage ceiling.
+func (e *Executor) SetLeverageCap(max int, reject bool) {
+	e.MaxLeverage = max
+	e.RejectOverLeverage = reject
+}
+
+// SetWriteRetry configures DB write retries and the recovery log path.
+func (e *Executor) SetWriteRetry(retries int, backoff time.Duration, recoveryLogPath string) {
+	e.WriteRetries = retries
+	if backoff > 0 {
+		e.WriteBackoff = backoff
+	}
+	e.RecoveryLogPath = recoveryLogPath
+}
+
+// SetMakerRouter configures maker-first routing.
+func (e *Executor) SetMakerRouter(r *MakerRouter) {
+	e.Router = r
+}
+
+// SetPostOnlyRetries configures how many times a LIMIT_MAKER order that would
+// immediately match is repriced and resubmitted (0 = treat as a rejection).
+func (e *Executor) SetPostOnlyRetries(n int) {
+	e.PostOnlyRetries = n
+}
+
+// SetBookSource supplies the best bid/ask post-only retries reprice from.
+func (e *Executor) SetBookSource(book BookSource) {
+	e.Book = book
+}
+
+// SetFillLedger shares a fill ledger with the user streams so each fill is booked once.
+func (e *Executor) SetFillLedger(l *FillLedger) {
+	e.Fills = l
+}
+
 // SetMetrics configures metrics recorder.
 func (e *Executor) SetMetrics(m *monitor.SystemMetrics) {
 	e.Metrics = m
 }
 
+func (e *Executor) SetStrategyHaltFn(fn func(ctx context.Context, strategyID, reason string) error) {
+	e.HaltStrategy = fn
+}
+
 func (e *Executor) Handle(ctx context.Context, o Order) error {
+	ctx, span := tracing.Start(tracing.Extract(ctx, o.Trace), "order.execute",
+		attribute.String("order.id", o.ID), attribute.String("order.symbol", o.Symbol),
+		attribute.String("order.side", o.Side), attribute.String("order.type", o.Type))
+	err := e.handle(ctx, o)
+	tracing.End(span, err)
+	return err
+}
+
+func (e *Executor) handle(ctx context.Context, o Order) error {
 	if e.DB == nil {
 		err := fmt.Errorf("executor: DB not configured")
 		log.Println(err)
@@ -89,7 +186,7 @@ func (e *Executor) Handle(ctx context.Context, o Order) error {
 	req := exchange.OrderRequest{
 		Symbol:       o.Symbol,
 		Side:         exchange.Side(o.Side),
-		Type:         exchange.OrderType(o.Type), // use actual order type from Order
+		Type:         exchange.NormalizeOrderType(exchange.OrderType(o.Type), exchange.MarketType(o.Market)), // map neutral stop types to venue names
 		Qty:          o.Qty,
 		Price:        o.Price,
 		StopPrice:    o.StopPrice,
@@ -115,17 +212,60 @@ func (e *Executor) Handle(ctx context.Context, o Order) error {
 	var exchID string
 	status := "NEW"
 	filled := false
+	submitted := false // accepted by the exchange
 	var execErr error
 	var gwDuration time.Duration
 	var persistDuration time.Duration
 
-	if e.SkipExchange {
+	if e.SkipExchange || skipsExchange(ctx) {
 		log.Printf("executor: SkipExchange enabled, not sending order %s to external gateway", o.ID)
+	} else if err := e.checkConnection(ctx, o, &req); err != nil {
+		log.Printf("executor: rejecting order %s: %v", o.ID, err)
+		status = "REJECTED"
+		execErr = err
+		if e.Bus != nil {
+			e.Bus.Publish(events.EventOrderRejected, err.Error())
+		}
 	} else {
 		gwStart := time.Now()
 		gw, venue := e.gatewayForOrder(ctx, o)
 		if gw != nil {
-			res, err := gw.SubmitOrder(ctx, req)
+			var res exchange.OrderResult
+			var err error
+			submitCtx, submitSpan := tracing.Start(ctx, "exchange.submit_order", attribute.String("exchange.venue", venue))
+			if o.Routing == RoutingMakerFirst && e.Router != nil {
+				res, err = e.Router.Submit(submitCtx, gw, req)
+			} else {
+				res, err = gw.SubmitOrder(submitCtx, req)
+			}
+			// Filter/precision rejections (-1013/-1111): round to the symbol's steps and retry once.
+			if err != nil && exchange.IsFilterFailure(err) {
+				if adj, ok := exchange.AdjustToFilters(req); ok {
+					log.Printf("executor: order %s failed filter check (%v); retrying with qty %v->%v price %v->%v",
+						o.ID, err, req.Qty, adj.Qty, req.Price, adj.Price)
+					req = adj
+					o.Qty, o.Price, o.StopPrice = adj.Qty, adj.Price, adj.StopPrice
+					res, err = gw.SubmitOrder(submitCtx, req)
+				}
+			}
+			// Post-only rejections (-2010 would match): step one tick back from the touch and retry.
+			for attempt := 0; err != nil && attempt < e.PostOnlyRetries &&
+				req.Type == exchange.OrderTypeLimitMaker && exchange.IsPostOnlyReject(err); attempt++ {
+				var bid, ask float64
+				if e.Book != nil {
+					bid, ask, _ = e.Book.BestBidAsk(req.Symbol)
+				}
+				adj, ok := exchange.RepriceAwayFromTouch(req, bid, ask)
+				if !ok {
+					break
+				}
+				log.Printf("executor: LIMIT_MAKER %s would match at %v; repricing to %v (retry %d/%d)",
+					o.ID, req.Price, adj.Price, attempt+1, e.PostOnlyRetries)
+				req = adj
+				o.Price = adj.Price
+				res, err = gw.SubmitOrder(submitCtx, req)
+			}
+			tracing.End(submitSpan, err)
 			if err != nil {
 				log.Printf("executor: submit to %s failed: %v", venue, err)
 				status = "REJECTED"
@@ -134,13 +274,23 @@ func (e *Executor) Handle(ctx context.Context, o Order) error {
 					e.Bus.Publish(events.EventOrderRejected, err.Error())
 				}
 			} else {
+				submitted = true
 				exchID = res.ExchangeOrderID
 				status = string(res.Status)
+				if res.Status == exchange.StatusFilled && res.AvgPrice > 0 {
+					// Book the price actually paid (e.g. a maker-first order that repriced or crossed).
+					o.Price = res.AvgPrice
+				}
 				if e.Bus != nil {
 					e.Bus.Publish(events.EventOrderAccepted, o)
 					if res.Status == exchange.StatusFilled {
-						e.Bus.Publish(events.EventOrderFilled, o)
-						filled = true
+						// The user stream may report the same fill; book it once.
+						if e.Fills.claimSync(o.ID, res.Fills) {
+							e.Bus.Publish(events.EventOrderFilled, o)
+							filled = true
+						} else {
+							log.Printf("executor: order %s fill already booked from the user stream", o.ID)
+						}
 					}
 				}
 			}
@@ -165,22 +315,22 @@ func (e *Executor) Handle(ctx context.Context, o Order) error {
 		Price:              o.Price,
 		Qty:                o.Qty,
 		Status:             status,
+		RefPrice:           o.RefPrice,
 		UserID:             o.UserID,
+		Note:               o.Note,
+		Tags:               o.Tags,
+		ExchangeOrderID:    exchID,
+		PositionSide:       o.PositionSide,
 		CreatedAt:          time.Now(),
 	}
-	persistStart := time.Now()
-	if err := e.DB.CreateOrder(ctx, model); err != nil {
-		log.Printf("executor: store order error: %v", err)
-		return err
+	if model.RefPrice <= 0 {
+		model.RefPrice = o.Price
 	}
-	if e.Metrics != nil {
-		persistDuration = time.Since(persistStart)
-	}
-
-	// If filled, store a trade row (price may be 0 for market; will be reconciled later)
+	// If filled, store a trade row too (price may be 0 for market; will be reconciled later)
+	var trade *db.Trade
 	if filled {
-		trade := db.Trade{
-			ID:        uuid.NewString(),
+		trade = &db.Trade{
+			ID:        ids.New(),
 			OrderID:   model.ID,
 			Symbol:    model.Symbol,
 			Side:      model.Side,
@@ -190,19 +340,58 @@ func (e *Executor) Handle(ctx context.Context, o Order) error {
 			UserID:    o.UserID,
 			CreatedAt: time.Now(),
 		}
-		if err := e.DB.CreateTrade(ctx, trade); err != nil {
-			log.Printf("executor: store trade error: %v", err)
-		}
+	}
 
-		// Update Strategy Position
-		if model.StrategyInstanceID != "" {
-			if err := e.DB.UpdateStrategyPosition(ctx, model.StrategyInstanceID, model.Symbol, model.Side, model.Qty, model.Price); err != nil {
-				log.Printf("executor: update strategy position error: %v", err)
+	store := e.store
+	if store == nil {
+		store = e.DB
+	}
+	// Order, trade and strategy position commit together or not at all.
+	persistStart := time.Now()
+	persistCtx, persistSpan := tracing.Start(ctx, "db.persist_order")
+	err := e.writeWithRetry(persistCtx, "order", func() error {
+		return store.WithTx(ctx, func(tx *db.Tx) error {
+			if err := tx.CreateOrder(ctx, model); err != nil {
+				return fmt.Errorf("store order: %w", err)
 			}
-
-			// Check profit target (Phase 2 feature)
-			e.checkProfitTarget(ctx, model.StrategyInstanceID)
+			if trade == nil {
+				return nil
+			}
+			if err := tx.CreateTrade(ctx, *trade); err != nil {
+				return fmt.Errorf("store trade: %w", err)
+			}
+			if model.StrategyInstanceID != "" {
+				if err := tx.UpdateStrategyPosition(ctx, model.StrategyInstanceID, model.Symbol, model.Side, model.Qty, model.Price); err != nil {
+					return fmt.Errorf("update strategy position: %w", err)
+				}
+			}
+			return nil
+		})
+	})
+	tracing.End(persistSpan, err)
+	if err != nil {
+		log.Printf("executor: persist order %s error: %v", model.ID, err)
+		if !submitted {
+			return err
+		}
+		// The order is live on the exchange: keep it for reconciliation rather
+		// than returning an error that would make callers resubmit it.
+		entry := RecoveryEntry{Time: time.Now().UTC(), Kind: "order", Order: &model, Trade: trade, Error: err.Error()}
+		if rerr := appendRecovery(e.RecoveryLogPath, entry); rerr != nil {
+			log.Printf("❌ executor: order %s (exch_id=%s) lost: recovery log: %v", model.ID, exchID, rerr)
+			return err
 		}
+		log.Printf("⚠️ executor: order %s (exch_id=%s) written to recovery log %s", model.ID, exchID, e.RecoveryLogPath)
+		return execErr
+	}
+	if e.Metrics != nil {
+		persistDuration = time.Since(persistStart)
+	}
+
+	// Check profit target (Phase 2 feature)
+	if trade != nil && model.StrategyInstanceID != "" {
+		e.checkProfitTarget(ctx, model.StrategyInstanceID)
+		e.checkDrawdown(ctx, model.StrategyInstanceID)
 	}
 
 	log.Printf("executor: stored order %s %s qty=%.6f exch_id=%s", model.Symbol, model.Side, model.Qty, exchID)
@@ -223,8 +412,71 @@ func (e *Executor) Handle(ctx context.Context, o Order) error {
 	return execErr
 }
 
+// skipExchangeKey marks a context whose order must only be stored, never sent
+// (dry-run and paper orders); unlike SkipExchange it does not affect orders
+// handled concurrently on other workers.
+type skipExchangeKey struct{}
+
+func withSkipExchange(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipExchangeKey{}, true)
+}
+
+func skipsExchange(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipExchangeKey{}).(bool)
+	return skip
+}
+
+// isPaperOrder reports whether the order's connection (set on the order or
+// bound to its strategy) is a paper connection. It fails closed: when the
+// connection or its paper flag can't be read the error is returned and the
+// order must not be sent.
+func (e *Executor) isPaperOrder(ctx context.Context, o Order) (bool, error) {
+	if e.DB == nil {
+		return false, nil
+	}
+	connID := o.ConnectionID
+	if connID == "" && o.StrategyInstanceID != "" {
+		if err := e.DB.DB.QueryRowContext(ctx, `
+			SELECT COALESCE(connection_id, '') FROM strategy_instances WHERE id = ?
+		`, o.StrategyInstanceID).Scan(&connID); err != nil && err != sql.ErrNoRows {
+			return false, fmt.Errorf("resolve connection of strategy %s: %w", o.StrategyInstanceID, err)
+		}
+	}
+	if connID == "" {
+		return false, nil
+	}
+	paper, err := e.DB.ConnectionIsPaper(ctx, connID)
+	if err != nil && err != db.ErrNotFound {
+		return false, fmt.Errorf("read paper flag of connection %s: %w", connID, err)
+	}
+	return paper, nil
+}
+
 // gatewayForOrder picks an exchange gateway for the given order based on its strategy binding.
 // It falls back to the global gateway when no per-connection binding is found.
+// CancelResting cancels o on its exchange if it is still open there. Orders
+// that were never sent or have already finished are left alone.
+func (e *Executor) CancelResting(ctx context.Context, o Order) error {
+	exchID, status, found, err := e.DB.GetOrderExchangeState(ctx, o.ID)
+	if err != nil {
+		return err
+	}
+	if !found || exchID == "" || db.IsTerminalOrderStatus(status) {
+		return nil
+	}
+	gw, _ := e.gatewayForOrder(ctx, o)
+	if gw == nil {
+		return fmt.Errorf("no gateway to cancel order %s", o.ID)
+	}
+	if err := gw.CancelOrder(ctx, o.Symbol, exchID); err != nil {
+		return fmt.Errorf("cancel order %s: %w", o.ID, err)
+	}
+	if err := e.DB.UpdateOrderStatus(ctx, o.ID, db.OrderStatusCanceled); err != nil {
+		log.Printf("executor: order %s canceled on the exchange but not marked: %v", o.ID, err)
+	}
+	return nil
+}
+
 func (e *Executor) gatewayForOrder(ctx context.Context, o Order) (exchange.Gateway, string) {
 	// Priority 1: Use ConnectionID directly if specified (multi-user mode)
 	if o.ConnectionID != "" {
@@ -400,6 +652,194 @@ func (e *Executor) createGateway(exchangeType, apiKey, apiSecret string) exchang
 	}
 }
 
+// checkConnection rejects orders the connection's account is not permitted to trade,
+// based on the capabilities recorded when the connection was probed, and applies
+// the connection's futures leverage within the user's cap.
+func (e *Executor) checkConnection(ctx context.Context, o Order, req *exchange.OrderRequest) error {
+	if o.ConnectionID == "" || o.UserID == "" || e.DB == nil {
+		return nil
+	}
+	conn, maxLev, err := e.connectionInfo(ctx, o.UserID, o.ConnectionID)
+	if err != nil {
+		return nil // missing connections are reported by gateway resolution
+	}
+	market := orderMarket(o, conn)
+	want := exchange.RequiredCapability(market)
+	if !exchange.HasCapability(conn.Capabilities, want) {
+		return fmt.Errorf("connection %q account lacks %s permission (has: %s)", conn.Name, want, conn.Capabilities)
+	}
+	if market != exchange.MarketSpot {
+		return e.applyLeverageCap(ctx, o, conn, maxLev, req)
+	}
+	return nil
+}
+
+// connectionInfo returns a connection and its owner's leverage cap
+// (users.max_leverage, else MaxLeverage), cached for connectionCacheTTL.
+func (e *Executor) connectionInfo(ctx context.Context, userID, connID string) (*db.Connection, int, error) {
+	key := userID + "/" + connID
+	e.mu.RLock()
+	c, ok := e.connInfo[key]
+	e.mu.RUnlock()
+	if !ok || time.Since(c.at) >= connectionCacheTTL {
+		conn, err := e.DB.Queries().GetConnectionByID(ctx, userID, connID)
+		if err != nil {
+			return nil, 0, err
+		}
+		c = cachedConnection{conn: conn, at: time.Now()}
+		if limits, err := e.DB.Queries().GetUserLimits(ctx, userID); err == nil {
+			c.userLev = limits.MaxLeverage
+		}
+		e.mu.Lock()
+		if e.connInfo == nil {
+			e.connInfo = make(map[string]cachedConnection)
+		}
+		e.connInfo[key] = c
+		e.mu.Unlock()
+	}
+	if c.userLev != 0 {
+		return c.conn, c.userLev, nil
+	}
+	return c.conn, e.MaxLeverage, nil
+}
+
+// applyLeverageCap sets the connection's leverage on req, clamping it to
+// maxLev (or rejecting, when configured) if it exceeds the cap. A connection
+// without a leverage of its own trades at the account's current setting for
+// the symbol, so that setting is checked against the cap instead; when it
+// cannot be read the order is sent at the cap.
+func (e *Executor) applyLeverageCap(ctx context.Context, o Order, conn *db.Connection, maxLev int, req *exchange.OrderRequest) error {
+	lev := conn.Leverage
+	req.Leverage = lev
+	if maxLev <= 0 {
+		return nil
+	}
+	if lev == 0 {
+		if lev = e.exchangeLeverage(ctx, o); lev == 0 {
+			log.Printf("executor: leverage of %s on connection %q unknown; sending order %s at the %dx cap", o.Symbol, conn.Name, o.ID, maxLev)
+			req.Leverage = maxLev
+			return nil
+		}
+	}
+	if lev <= maxLev {
+		return nil
+	}
+	if e.RejectOverLeverage {
+		return fmt.Errorf("connection %q leverage %dx exceeds the %dx cap", conn.Name, lev, maxLev)
+	}
+	log.Printf("executor: clamping leverage for order %s on connection %q from %dx to %dx", o.ID, conn.Name, lev, maxLev)
+	req.Leverage = maxLev
+	return nil
+}
+
+// exchangeLeverage returns the leverage the order's account has set for its
+// symbol, or 0 when the gateway cannot report it.
+func (e *Executor) exchangeLeverage(ctx context.Context, o Order) int {
+	gw, _ := e.gatewayForOrder(ctx, o)
+	reporter, ok := gw.(exchange.LeverageReporter)
+	if !ok {
+		return 0
+	}
+	lev, err := reporter.SymbolLeverage(ctx, o.Symbol)
+	if err != nil {
+		log.Printf("executor: read leverage of %s for order %s: %v", o.Symbol, o.ID, err)
+		return 0
+	}
+	return lev
+}
+
+// marketForOrder returns the order's market, inferring it from its connection
+// (or the global venue, for orders without one) when unset.
+func (e *Executor) marketForOrder(ctx context.Context, o Order) exchange.MarketType {
+	conn := &db.Connection{ExchangeType: e.Exchange}
+	if o.Market == "" && o.ConnectionID != "" && o.UserID != "" && e.DB != nil {
+		if c, _, err := e.connectionInfo(ctx, o.UserID, o.ConnectionID); err == nil {
+			conn = c
+		}
+	}
+	return orderMarket(o, conn)
+}
+
+// orderMarket returns the order's market, inferring it from the connection when unset.
+func orderMarket(o Order, conn *db.Connection) exchange.MarketType {
+	market := exchange.MarketType(o.Market)
+	if market == "" {
+		switch conn.ExchangeType {
+		case "binance-usdtfut":
+			market = exchange.MarketUSDTFut
+		case "binance-coinfut":
+			market = exchange.MarketCoinFut
+		default:
+			market = exchange.MarketSpot
+		}
+	}
+	return market
+}
+
+// checkDrawdown stops and flattens a strategy once its drawdown from peak
+// equity reaches the max_drawdown_pct in its risk config.
+func (e *Executor) checkDrawdown(ctx context.Context, strategyID string) {
+	var maxDrawdown, allocation float64
+	err := e.DB.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(max_drawdown_pct, 0), COALESCE(allocation, 0)
+		FROM strategy_risk_configs WHERE strategy_instance_id = ?
+	`, strategyID).Scan(&maxDrawdown, &allocation)
+	if err != nil || maxDrawdown <= 0 {
+		return
+	}
+	sp, err := e.DB.GetStrategyPosition(ctx, strategyID)
+	if err != nil || sp == nil {
+		return
+	}
+	drawdown, ok := risk.StrategyDrawdownPct(allocation, sp.RealizedPnL, sp.PeakPnL)
+	if !ok || drawdown < maxDrawdown {
+		return
+	}
+
+	// Stop once: the flattening order's own fill comes back through here.
+	var status string
+	if err := e.DB.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(status, '') FROM strategy_instances WHERE id = ?
+	`, strategyID).Scan(&status); err != nil || status == "STOPPED" {
+		return
+	}
+	e.mu.Lock()
+	if e.halting == nil {
+		e.halting = make(map[string]bool)
+	}
+	if e.halting[strategyID] {
+		e.mu.Unlock()
+		return
+	}
+	e.halting[strategyID] = true
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.halting, strategyID)
+		e.mu.Unlock()
+	}()
+
+	reason := fmt.Sprintf("strategy %s stopped: drawdown %.2f%% reached max %.2f%% (realized PnL %.2f, peak %.2f)",
+		strategyID, drawdown, maxDrawdown, sp.RealizedPnL, sp.PeakPnL)
+	if e.HaltStrategy != nil {
+		if err := e.HaltStrategy(ctx, strategyID, reason); err != nil {
+			log.Printf("⚠️ executor: drawdown stop of strategy %s failed, retrying on its next fill: %v", strategyID, err)
+			return
+		}
+	}
+	// Marked stopped only once the flattening order is on its way.
+	if _, err := e.DB.DB.ExecContext(ctx, `
+		UPDATE strategy_instances SET status = 'STOPPED', is_active = 0 WHERE id = ?
+	`, strategyID); err != nil {
+		log.Printf("executor: failed to stop strategy after drawdown: %v", err)
+		return
+	}
+	log.Printf("⚠️ %s", reason)
+	if e.Bus != nil {
+		e.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "strategy_drawdown", Symbol: sp.Symbol, Key: strategyID, Message: reason})
+	}
+}
+
 // checkProfitTarget checks if the strategy has reached its profit target and stops it if so.
 // Supports both USDT (absolute) and PERCENT (percentage of initial balance) targets.
 func (e *Executor) checkProfitTarget(ctx context.Context, strategyID string) {
@@ -454,12 +894,7 @@ func (e *Executor) checkProfitTarget(ctx context.Context, strategyID string) {
 
 	// Publish event
 	if e.Bus != nil {
-		e.Bus.Publish(events.EventRiskAlert, map[string]any{
-			"type":         "PROFIT_TARGET_REACHED",
-			"strategy_id":  strategyID,
-			"realized_pnl": realizedPnL,
-			"target":       profitTarget,
-			"target_type":  profitTargetType,
-		})
+		e.Bus.Publish(events.EventRiskAlert, events.Alert{Type: "profit_target_reached", Key: strategyID,
+			Message: fmt.Sprintf("strategy %s reached its profit target: %.2f %s (target %.2f); stopped", strategyID, realizedPnL, profitTargetType, profitTarget)})
 	}
 }
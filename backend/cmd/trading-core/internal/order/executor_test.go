@@ -0,0 +1,507 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"trading-core/internal/events"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// precisionGateway rejects the first submit with -1111 and accepts the rest.
+type precisionGateway struct{ reqs []exchange.OrderRequest }
+
+func (g *precisionGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	g.reqs = append(g.reqs, req)
+	if len(g.reqs) == 1 {
+		return exchange.OrderResult{}, exchange.NewAPIError("binance POST /api/v3/order", 400,
+			[]byte(`{"code":-1111,"msg":"Precision is over the maximum defined for this asset."}`))
+	}
+	return exchange.OrderResult{ExchangeOrderID: "x1", Status: exchange.StatusNew}, nil
+}
+
+func (g *precisionGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func TestExecutorRetriesFilterFailureWithRoundedQty(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	exchange.RegisterMarketSymbols(exchange.MarketSpot, exchange.SymbolInfo{
+		Symbol: "ROUNDUSDT", BaseAsset: "ROUND", QuoteAsset: "USDT",
+		StepSize: 0.001, TickSize: 0.01, MinQty: 0.001,
+	})
+
+	gw := &precisionGateway{}
+	exec := NewExecutor(database, nil, gw, "test", false)
+	ctx := context.Background()
+	if err := exec.Handle(ctx, Order{
+		ID: "o1", Symbol: "ROUNDUSDT", Side: "BUY", Type: "LIMIT", Qty: 0.12345, Price: 100.004,
+		Market: string(exchange.MarketSpot),
+	}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	if len(gw.reqs) != 2 {
+		t.Fatalf("expected one retry (2 submits), got %d", len(gw.reqs))
+	}
+	if got := gw.reqs[1]; got.Qty != 0.123 || got.Price != 100 {
+		t.Fatalf("retry should use rounded qty/price, got qty=%v price=%v", got.Qty, got.Price)
+	}
+
+	var status string
+	var qty float64
+	if err := database.DB.QueryRowContext(ctx, `SELECT status, qty FROM orders WHERE id = ?`, "o1").Scan(&status, &qty); err != nil {
+		t.Fatalf("load order: %v", err)
+	}
+	if status == db.OrderStatusRejected || qty != 0.123 {
+		t.Fatalf("expected stored order with adjusted qty, got status=%s qty=%v", status, qty)
+	}
+}
+
+type leverageGateway struct{ reqs []exchange.OrderRequest }
+
+func (g *leverageGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	g.reqs = append(g.reqs, req)
+	return exchange.OrderResult{ExchangeOrderID: "x1", Status: exchange.StatusNew}, nil
+}
+
+func (g *leverageGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func TestExecutorEnforcesUserLeverageCap(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := database.CreateUser(ctx, db.User{ID: "u1", Email: "u1@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	// Connection is configured for 50x, the user is capped at 10x (global cap is higher).
+	if err := database.Queries().CreateConnectionEncrypted(ctx, db.Connection{
+		ID: "c1", UserID: "u1", ExchangeType: "binance-usdtfut", Name: "perp",
+		Capabilities: exchange.CapabilityUSDTFutures, Leverage: 50,
+	}); err != nil {
+		t.Fatalf("CreateConnectionEncrypted: %v", err)
+	}
+	if err := database.Queries().SetUserLimits(ctx, "u1", db.UserLimits{MaxLeverage: 10}); err != nil {
+		t.Fatalf("SetUserLimits: %v", err)
+	}
+
+	gw := &leverageGateway{}
+	exec := NewExecutor(database, nil, nil, "test", false)
+	exec.SetGatewayPool(staticPool{gw: gw})
+	exec.SetLeverageCap(20, false)
+
+	o := Order{
+		ID: "o1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Qty: 0.01,
+		Market: string(exchange.MarketUSDTFut), UserID: "u1", ConnectionID: "c1",
+	}
+	if err := exec.Handle(ctx, o); err != nil {
+		t.Fatalf("Handle (clamp): %v", err)
+	}
+	if len(gw.reqs) != 1 || gw.reqs[0].Leverage != 10 {
+		t.Fatalf("expected order clamped to 10x, got %+v", gw.reqs)
+	}
+
+	exec.SetLeverageCap(20, true)
+	o.ID = "o2"
+	if err := exec.Handle(ctx, o); err == nil || !strings.Contains(err.Error(), "exceeds the 10x cap") {
+		t.Fatalf("expected leverage cap rejection, got %v", err)
+	}
+	if len(gw.reqs) != 1 {
+		t.Fatalf("rejected order should not reach the gateway, got %d submits", len(gw.reqs))
+	}
+}
+
+// lockedStore fails the first `fails` order writes with SQLite's busy error.
+type lockedStore struct {
+	*db.Database
+	fails  int
+	writes int
+}
+
+func (s *lockedStore) WithTx(ctx context.Context, fn func(tx *db.Tx) error) error {
+	s.writes++
+	if s.writes <= s.fails {
+		return errors.New("database is locked (5) (SQLITE_BUSY)")
+	}
+	return s.Database.WithTx(ctx, fn)
+}
+
+func TestExecutorRetriesTransientDBWrite(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	gw := &leverageGateway{}
+	exec := NewExecutor(database, nil, gw, "test", false)
+	recoveryPath := filepath.Join(t.TempDir(), "recovery.log")
+	exec.SetWriteRetry(3, time.Millisecond, recoveryPath)
+	store := &lockedStore{Database: database, fails: 2}
+	exec.store = store
+
+	ctx := context.Background()
+	if err := exec.Handle(ctx, Order{ID: "o-retry", Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Qty: 0.01, Price: 50000}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if store.writes != 3 {
+		t.Fatalf("expected 2 failed writes then success, got %d writes", store.writes)
+	}
+	if len(gw.reqs) != 1 {
+		t.Fatalf("order should be submitted once, got %d submits", len(gw.reqs))
+	}
+	var n int
+	if err := database.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE id = ?`, "o-retry").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected stored order, count=%d err=%v", n, err)
+	}
+
+	// Retries exhausted after the exchange accepted: the order goes to the recovery log.
+	store.writes, store.fails = 0, 10
+	if err := exec.Handle(ctx, Order{ID: "o-lost", Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Qty: 0.01, Price: 50000}); err != nil {
+		t.Fatalf("Handle should not ask callers to resubmit a live order: %v", err)
+	}
+	entries, err := ReadRecoveryLog(recoveryPath)
+	if err != nil {
+		t.Fatalf("ReadRecoveryLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Kind != "order" || entries[0].Order == nil || entries[0].Order.ID != "o-lost" {
+		t.Fatalf("expected o-lost in recovery log, got %+v", entries)
+	}
+}
+
+// filledGateway fills every order immediately.
+type filledGateway struct{}
+
+func (filledGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{ExchangeOrderID: "f1", Status: exchange.StatusFilled}, nil
+}
+
+func (filledGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+type filledPool struct{}
+
+func (filledPool) GetOrCreate(ctx context.Context, userID, connectionID string) (exchange.Gateway, error) {
+	return filledGateway{}, nil
+}
+
+func TestExecutorPersistsFilledOrderAtomically(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	// The strategy position write fails after the order and trade rows were inserted.
+	if _, err := database.DB.Exec(`
+		CREATE TRIGGER fail_strategy_position BEFORE INSERT ON strategy_positions
+		BEGIN SELECT RAISE(ABORT, 'injected failure'); END
+	`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	exec := NewExecutor(database, events.NewBus(), nil, "test", false)
+	exec.SetGatewayPool(filledPool{})
+	recoveryPath := filepath.Join(t.TempDir(), "recovery.log")
+	exec.SetWriteRetry(0, time.Millisecond, recoveryPath)
+
+	ctx := context.Background()
+	if err := exec.Handle(ctx, Order{
+		ID: "o-atomic", StrategyInstanceID: "s1", ConnectionID: "c1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Qty: 0.01, Price: 50000,
+	}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	for _, table := range []string{"orders", "trades", "strategy_positions"} {
+		var n int
+		if err := database.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&n); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d row(s) after a failed transaction, want 0", table, n)
+		}
+	}
+	entries, err := ReadRecoveryLog(recoveryPath)
+	if err != nil {
+		t.Fatalf("ReadRecoveryLog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Order == nil || entries[0].Trade == nil || entries[0].Trade.OrderID != "o-atomic" {
+		t.Fatalf("expected order and trade in recovery log, got %+v", entries)
+	}
+}
+
+func TestExecutorStopsStrategyPastMaxDrawdown(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := database.DB.ExecContext(ctx, `
+		INSERT INTO strategy_instances (id, name, strategy_type, symbol, interval, parameters, status)
+		VALUES ('s-dd', 'dd', 'ma_cross', 'BTCUSDT', '1m', '{}', 'ACTIVE')
+	`); err != nil {
+		t.Fatalf("insert strategy: %v", err)
+	}
+	if _, err := database.DB.ExecContext(ctx, `
+		INSERT INTO strategy_risk_configs (strategy_instance_id, max_position_size, min_order_size, max_order_size, allocation, max_drawdown_pct)
+		VALUES ('s-dd', 0, 0, 0, 1000, 20)
+	`); err != nil {
+		t.Fatalf("insert risk config: %v", err)
+	}
+
+	exec := NewExecutor(database, events.NewBus(), nil, "test", false)
+	exec.SetGatewayPool(filledPool{})
+	var halted []string
+	haltErr := errors.New("flatten failed")
+	exec.SetStrategyHaltFn(func(ctx context.Context, strategyID, reason string) error {
+		halted = append(halted, strategyID)
+		return haltErr
+	})
+
+	status := func() string {
+		var s string
+		if err := database.DB.QueryRowContext(ctx, `SELECT status FROM strategy_instances WHERE id = 's-dd'`).Scan(&s); err != nil {
+			t.Fatalf("load status: %v", err)
+		}
+		return s
+	}
+	n := 0
+	roundTrip := func(entry, exit float64) {
+		for _, leg := range []struct {
+			side  string
+			price float64
+		}{{"BUY", entry}, {"SELL", exit}} {
+			n++
+			if err := exec.Handle(ctx, Order{
+				ID: fmt.Sprintf("dd-%d", n), StrategyInstanceID: "s-dd", ConnectionID: "c1",
+				Symbol: "BTCUSDT", Side: leg.side, Type: "MARKET", Qty: 1, Price: leg.price,
+			}); err != nil {
+				t.Fatalf("Handle: %v", err)
+			}
+		}
+	}
+
+	// Equity 1000 -> 1300 (peak) -> 1150: an 11.5% drawdown stays under 20%.
+	roundTrip(100, 400)
+	roundTrip(400, 250)
+	if len(halted) != 0 || status() != "ACTIVE" {
+		t.Fatalf("strategy halted inside the limit: halted=%v status=%s", halted, status())
+	}
+
+	// -> 1000: 300/1300 = 23% breaches the limit, but the first halt fails:
+	// the strategy stays active and the stop is retried on the next fill.
+	roundTrip(250, 100)
+	if len(halted) != 1 || status() != "ACTIVE" {
+		t.Fatalf("failed halt: halted=%v status=%s, want one attempt and ACTIVE", halted, status())
+	}
+
+	haltErr = nil
+	roundTrip(100, 100)
+	if len(halted) != 2 || halted[1] != "s-dd" {
+		t.Fatalf("expected the halt of s-dd to be retried, got %v", halted)
+	}
+	if status() != "STOPPED" {
+		t.Fatalf("status = %s, want STOPPED", status())
+	}
+
+	// Further fills (e.g. the flattening order) do not halt it again.
+	roundTrip(100, 50)
+	if len(halted) != 2 {
+		t.Fatalf("strategy halted again: %v", halted)
+	}
+}
+
+// postOnlyGateway rejects LIMIT_MAKER orders priced at or through the ask.
+type postOnlyGateway struct {
+	ask  float64
+	reqs []exchange.OrderRequest
+}
+
+func (g *postOnlyGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	g.reqs = append(g.reqs, req)
+	if req.Type == exchange.OrderTypeLimitMaker && req.Side == exchange.SideBuy && req.Price >= g.ask {
+		return exchange.OrderResult{}, exchange.NewAPIError("binance POST /api/v3/order", 400,
+			[]byte(`{"code":-2010,"msg":"Order would immediately match and take."}`))
+	}
+	return exchange.OrderResult{ExchangeOrderID: "x1", Status: exchange.StatusNew}, nil
+}
+
+func (g *postOnlyGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func TestExecutorRepricesLimitMakerThatWouldMatch(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	exchange.RegisterMarketSymbols(exchange.MarketSpot, exchange.SymbolInfo{
+		Symbol: "MAKERUSDT", BaseAsset: "MAKER", QuoteAsset: "USDT",
+		StepSize: 0.001, TickSize: 0.01, MinQty: 0.001,
+	})
+
+	gw := &postOnlyGateway{ask: 100}
+	exec := NewExecutor(database, nil, gw, "test", false)
+	exec.SetPostOnlyRetries(2)
+	ctx := context.Background()
+	if err := exec.Handle(ctx, Order{
+		ID: "pm1", Symbol: "MAKERUSDT", Side: "BUY", Type: "LIMIT_MAKER", Qty: 1, Price: 100,
+		Market: string(exchange.MarketSpot),
+	}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	if len(gw.reqs) != 2 {
+		t.Fatalf("expected one reprice (2 submits), got %d", len(gw.reqs))
+	}
+	if got := gw.reqs[1].Price; got != 99.99 {
+		t.Fatalf("retry should be one tick below the ask, got %v", got)
+	}
+
+	var status string
+	var price float64
+	if err := database.DB.QueryRowContext(ctx, `SELECT status, price FROM orders WHERE id = ?`, "pm1").Scan(&status, &price); err != nil {
+		t.Fatalf("load order: %v", err)
+	}
+	if status == db.OrderStatusRejected || price != 99.99 {
+		t.Fatalf("expected stored order at the repriced level, got status=%s price=%v", status, price)
+	}
+}
+
+// fixedBook quotes a constant best bid/ask.
+type fixedBook struct{ bid, ask float64 }
+
+func (b fixedBook) BestBidAsk(string) (float64, float64, bool) { return b.bid, b.ask, true }
+
+func TestExecutorRepricesLimitMakerBehindBookTouch(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	exchange.RegisterMarketSymbols(exchange.MarketSpot, exchange.SymbolInfo{
+		Symbol: "MAKERUSDT", BaseAsset: "MAKER", QuoteAsset: "USDT",
+		StepSize: 0.001, TickSize: 0.01, MinQty: 0.001,
+	})
+
+	// The ask has dropped to 99.5 since the order was priced at 100: one tick
+	// off its own price would still cross.
+	gw := &postOnlyGateway{ask: 99.5}
+	exec := NewExecutor(database, nil, gw, "test", false)
+	exec.SetPostOnlyRetries(2)
+	exec.SetBookSource(fixedBook{bid: 99.4, ask: 99.5})
+	if err := exec.Handle(context.Background(), Order{
+		ID: "pm2", Symbol: "MAKERUSDT", Side: "BUY", Type: "LIMIT_MAKER", Qty: 1, Price: 100,
+		Market: string(exchange.MarketSpot),
+	}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if len(gw.reqs) != 2 || gw.reqs[1].Price != 99.39 {
+		t.Fatalf("retry should rest one tick below the bid (99.39), got %+v", gw.reqs)
+	}
+}
+
+// accountLeverageGateway reports a leverage already set on the account.
+type accountLeverageGateway struct {
+	leverageGateway
+	lev   int
+	reads int
+}
+
+func (g *accountLeverageGateway) SymbolLeverage(ctx context.Context, symbol string) (int, error) {
+	g.reads++
+	return g.lev, nil
+}
+
+func TestExecutorCapsAccountLeverageWhenConnectionSetsNone(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := database.CreateUser(ctx, db.User{ID: "u1", Email: "u1@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	// The connection leaves leverage to the exchange, where it is set to 50x.
+	if err := database.Queries().CreateConnectionEncrypted(ctx, db.Connection{
+		ID: "c1", UserID: "u1", ExchangeType: "binance-usdtfut", Name: "perp",
+		Capabilities: exchange.CapabilityUSDTFutures,
+	}); err != nil {
+		t.Fatalf("CreateConnectionEncrypted: %v", err)
+	}
+
+	gw := &accountLeverageGateway{lev: 50}
+	exec := NewExecutor(database, nil, nil, "test", false)
+	exec.SetGatewayPool(staticPool{gw: gw})
+	exec.SetLeverageCap(20, false)
+
+	o := Order{
+		ID: "o1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Qty: 0.01,
+		Market: string(exchange.MarketUSDTFut), UserID: "u1", ConnectionID: "c1",
+	}
+	if err := exec.Handle(ctx, o); err != nil {
+		t.Fatalf("Handle (clamp): %v", err)
+	}
+	if len(gw.reqs) != 1 || gw.reqs[0].Leverage != 20 {
+		t.Fatalf("expected the 50x account leverage clamped to 20x, got %+v", gw.reqs)
+	}
+
+	// Within the cap the exchange setting is left alone.
+	gw.lev = 5
+	o.ID = "o2"
+	if err := exec.Handle(ctx, o); err != nil {
+		t.Fatalf("Handle (within cap): %v", err)
+	}
+	if len(gw.reqs) != 2 || gw.reqs[1].Leverage != 0 {
+		t.Fatalf("expected no leverage change within the cap, got %+v", gw.reqs[1])
+	}
+
+	exec.SetLeverageCap(20, true)
+	gw.lev = 50
+	o.ID = "o3"
+	if err := exec.Handle(ctx, o); err == nil || !strings.Contains(err.Error(), "leverage 50x exceeds the 20x cap") {
+		t.Fatalf("expected leverage cap rejection, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package order
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+// FeeSchedule describes maker/taker commission for a connection.
+type FeeSchedule struct {
+	MakerBps    float64 // basis points charged on maker fills
+	TakerBps    float64 // basis points charged on taker fills
+	BNBDiscount float64 // fractional discount when fees are paid in BNB, e.g. 0.25 = 25% off
+}
+
+// FlatFeeSchedule returns a schedule charging the same decimal rate for maker and taker fills.
+func FlatFeeSchedule(rate float64) FeeSchedule {
+	return FeeSchedule{MakerBps: rate * 10000, TakerBps: rate * 10000}
+}
+
+// Rate returns the effective decimal fee rate for a maker or taker fill.
+func (f FeeSchedule) Rate(maker bool) float64 {
+	bps := f.TakerBps
+	if maker {
+		bps = f.MakerBps
+	}
+	discount := f.BNBDiscount
+	if discount < 0 || discount >= 1 {
+		discount = 0
+	}
+	return bps / 10000.0 * (1 - discount)
+}
+
+// Fee returns the commission for a fill of the given notional value.
+func (f FeeSchedule) Fee(notional float64, maker bool) float64 {
+	return mathAbs(notional) * f.Rate(maker)
+}
+
+// IsMakerOrder reports whether an order is expected to rest on the book and fill as maker.
+// Resting limit orders are treated as maker; market and stop-triggered orders as taker.
+func IsMakerOrder(o Order) bool {
+	switch strings.ToUpper(o.Type) {
+	case "LIMIT_MAKER":
+		return true
+	case "LIMIT":
+		tif := strings.ToUpper(o.TimeInForce)
+		return tif != "IOC" && tif != "FOK"
+	default:
+		return false
+	}
+}
+
+// FeeScheduleFromConnection builds a schedule from connection settings. A
+// maker or taker rate the connection leaves unset (<= 0) falls back to def's
+// rate for that side, as does the BNB discount.
+func FeeScheduleFromConnection(c *db.Connection, def FeeSchedule) FeeSchedule {
+	if c == nil {
+		return def
+	}
+	f := def
+	if c.MakerFeeBps > 0 {
+		f.MakerBps = c.MakerFeeBps
+	}
+	if c.TakerFeeBps > 0 {
+		f.TakerBps = c.TakerFeeBps
+	}
+	if c.BNBDiscount > 0 {
+		f.BNBDiscount = c.BNBDiscount
+	}
+	return f
+}
+
+// FeeResolver looks up the fee schedule for an order's connection. Schedules
+// are cached per connection for connectionCacheTTL, so fee edits apply within
+// that window without a query per fill.
+type FeeResolver struct {
+	DB      *db.Database
+	Default FeeSchedule
+
+	mu    sync.Mutex
+	cache map[string]cachedFees // user_id/connection_id -> schedule
+}
+
+type cachedFees struct {
+	fees FeeSchedule
+	at   time.Time
+}
+
+// Resolve returns the schedule for the given user's connection, or the default when unknown.
+func (r *FeeResolver) Resolve(ctx context.Context, userID, connectionID string) FeeSchedule {
+	if r == nil {
+		return FeeSchedule{}
+	}
+	if r.DB == nil || userID == "" || connectionID == "" {
+		return r.Default
+	}
+	key := userID + "/" + connectionID
+	r.mu.Lock()
+	c, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Since(c.at) < connectionCacheTTL {
+		return c.fees
+	}
+	conn, err := r.DB.Queries().GetConnectionByID(ctx, userID, connectionID)
+	if err != nil {
+		return r.Default
+	}
+	fees := FeeScheduleFromConnection(conn, r.Default)
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = make(map[string]cachedFees)
+	}
+	r.cache[key] = cachedFees{fees: fees, at: time.Now()}
+	r.mu.Unlock()
+	return fees
+}
@@ -0,0 +1,64 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"trading-core/pkg/db"
+)
+
+func TestDryRunMakerFillCheaperThanTaker(t *testing.T) {
+	cfg := DryRunSimConfig{
+		FeeRate: 0.001,
+		Fees:    FeeSchedule{MakerBps: 2, TakerBps: 4},
+	}
+	maker := NewDryRunExecutor(ModeDryRun, nil, 1000, cfg)
+	taker := NewDryRunExecutor(ModeDryRun, nil, 1000, cfg)
+
+	ctx := context.Background()
+	if err := maker.Execute(ctx, Order{ID: "m1", Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 100, Qty: 1}); err != nil {
+		t.Fatalf("maker execute: %v", err)
+	}
+	if err := taker.Execute(ctx, Order{ID: "t1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Price: 100, Qty: 1}); err != nil {
+		t.Fatalf("taker execute: %v", err)
+	}
+
+	makerFee := 1000 - 100 - maker.mockExec.Balance("USDT")
+	takerFee := 1000 - 100 - taker.mockExec.Balance("USDT")
+	if makerFee >= takerFee {
+		t.Fatalf("expected maker fee < taker fee, got maker=%.6f taker=%.6f", makerFee, takerFee)
+	}
+	if diff := takerFee - 0.04; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("expected taker fee 0.04, got %.6f", takerFee)
+	}
+}
+
+func TestFeeScheduleFromConnection(t *testing.T) {
+	def := FlatFeeSchedule(0.0004)
+
+	if got := FeeScheduleFromConnection(nil, def); got != def {
+		t.Fatalf("nil connection should use default, got %+v", got)
+	}
+
+	conn := &db.Connection{MakerFeeBps: 1, TakerFeeBps: 5, BNBDiscount: 0.25}
+	got := FeeScheduleFromConnection(conn, def)
+	if rate := got.Rate(false); rate < 0.000374 || rate > 0.000376 {
+		t.Fatalf("expected discounted taker rate 0.000375, got %.8f", rate)
+	}
+	if got.Rate(true) >= got.Rate(false) {
+		t.Fatalf("expected maker rate below taker rate: %+v", got)
+	}
+}
+
+func TestFeeScheduleFromConnectionDefaultsUnsetSide(t *testing.T) {
+	def := FeeSchedule{MakerBps: 2, TakerBps: 4}
+
+	got := FeeScheduleFromConnection(&db.Connection{TakerFeeBps: 5}, def)
+	if got.MakerBps != 2 || got.TakerBps != 5 {
+		t.Fatalf("taker-only connection = %+v, want maker 2 (default) and taker 5", got)
+	}
+	got = FeeScheduleFromConnection(&db.Connection{MakerFeeBps: 1, BNBDiscount: 0.25}, def)
+	if got.MakerBps != 1 || got.TakerBps != 4 || got.BNBDiscount != 0.25 {
+		t.Fatalf("maker-only connection = %+v, want maker 1, taker 4 (default), 25%% discount", got)
+	}
+}
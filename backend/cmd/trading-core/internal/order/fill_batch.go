@@ -0,0 +1,211 @@
+package order
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+// fillStore is the subset of db.Database the user streams persist fills through.
+type fillStore interface {
+	UpdateOrderFill(ctx context.Context, id, status string, filledQty, price float64) error
+	CreateTrade(ctx context.Context, tr db.Trade) error
+}
+
+// pendingFill is the fills of one order received within the current window.
+type pendingFill struct {
+	trade    db.Trade // first fill's ID/symbol/side; Qty and Fee are summed
+	notional float64  // sum of qty*price, for the batch VWAP
+	status   string   // latest order status
+	cumQty   float64  // latest cumulative filled quantity
+	price    float64  // latest fill price
+	timer    *time.Timer
+}
+
+// fillBatcher coalesces the fills of each order that arrive within Window into
+// one order update and one trade row, so a burst of small partials does not
+// turn into a DB write per message. Terminal statuses flush immediately.
+//
+// Batches are written outside mu, so slow DB writes do not hold up fills of
+// other orders. Each flush takes a per-order ticket under mu and waits only
+// for that order's earlier tickets, so two batches of one order never land
+// out of sequence while different orders write concurrently.
+type fillBatcher struct {
+	store fillStore
+	name  string // log prefix
+
+	mu      sync.Mutex
+	window  time.Duration // 0 writes every fill through
+	pending map[string]*pendingFill
+
+	writeMu  sync.Mutex
+	turnDone *sync.Cond
+	turns    map[string]*writeTurn // by order ID, while it has unwritten flushes
+}
+
+// writeTurn sequences one order's flushes.
+type writeTurn struct {
+	issued  uint64 // tickets handed out
+	written uint64 // tickets written
+}
+
+func newFillBatcher(store fillStore, window time.Duration, name string) *fillBatcher {
+	b := &fillBatcher{
+		store:   store,
+		window:  window,
+		name:    name,
+		pending: make(map[string]*pendingFill),
+		turns:   make(map[string]*writeTurn),
+	}
+	b.turnDone = sync.NewCond(&b.writeMu)
+	return b
+}
+
+// setWindow changes the batch window for fills that arrive from now on.
+func (b *fillBatcher) setWindow(window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window = window
+}
+
+// add records a fill of tr.Qty at tr.Price for order tr.OrderID, whose
+// exchange-reported status and cumulative quantity are status and cumQty.
+func (b *fillBatcher) add(ctx context.Context, status string, cumQty float64, tr db.Trade) {
+	ctx = context.WithoutCancel(ctx)
+
+	b.mu.Lock()
+	if b.window <= 0 {
+		ticket := b.ticketLocked(tr.OrderID)
+		b.mu.Unlock()
+		b.writeInTurn(ctx, tr.OrderID, ticket, &pendingFill{trade: tr, notional: tr.Qty * tr.Price, status: status, cumQty: cumQty, price: tr.Price})
+		return
+	}
+
+	var flush []*pendingFill
+	p := b.pending[tr.OrderID]
+	if p != nil && p.trade.FeeAsset != tr.FeeAsset {
+		// Fees in different assets cannot be summed; persist what we have first.
+		flush = append(flush, b.takeLocked(tr.OrderID, p))
+		p = nil
+	}
+	if p == nil {
+		p = &pendingFill{trade: tr}
+		p.trade.Qty, p.trade.Fee = 0, 0
+		b.pending[tr.OrderID] = p
+		p.timer = time.AfterFunc(b.window, func() { b.flush(ctx, tr.OrderID, p) })
+	}
+	p.trade.Qty += tr.Qty
+	p.trade.Fee += tr.Fee
+	p.trade.CreatedAt = tr.CreatedAt
+	p.notional += tr.Qty * tr.Price
+	p.status = status
+	p.price = tr.Price
+	if cumQty > p.cumQty {
+		p.cumQty = cumQty
+	}
+
+	if db.IsTerminalOrderStatus(status) {
+		flush = append(flush, b.takeLocked(tr.OrderID, p))
+	}
+	if len(flush) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	ticket := b.ticketLocked(tr.OrderID)
+	b.mu.Unlock()
+	b.writeInTurn(ctx, tr.OrderID, ticket, flush...)
+}
+
+// flush persists p if it is still the pending batch for orderID.
+func (b *fillBatcher) flush(ctx context.Context, orderID string, p *pendingFill) {
+	b.mu.Lock()
+	if b.pending[orderID] != p {
+		b.mu.Unlock()
+		return
+	}
+	b.takeLocked(orderID, p)
+	ticket := b.ticketLocked(orderID)
+	b.mu.Unlock()
+	b.writeInTurn(ctx, orderID, ticket, p)
+}
+
+// flushAll persists every pending batch (used on shutdown).
+func (b *fillBatcher) flushAll(ctx context.Context) {
+	b.mu.Lock()
+	flush := make(map[string]*pendingFill, len(b.pending))
+	tickets := make(map[string]uint64, len(b.pending))
+	for id, p := range b.pending {
+		flush[id] = b.takeLocked(id, p)
+		tickets[id] = b.ticketLocked(id)
+	}
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for id, p := range flush {
+		wg.Add(1)
+		go func(id string, p *pendingFill) {
+			defer wg.Done()
+			b.writeInTurn(ctx, id, tickets[id], p)
+		}(id, p)
+	}
+	wg.Wait()
+}
+
+// takeLocked removes p from the pending batches and returns it for writing.
+func (b *fillBatcher) takeLocked(orderID string, p *pendingFill) *pendingFill {
+	p.timer.Stop()
+	delete(b.pending, orderID)
+	return p
+}
+
+// ticketLocked hands out orderID's next write ticket. b.mu must be held, so
+// tickets follow the order batches were taken in.
+func (b *fillBatcher) ticketLocked(orderID string) uint64 {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	t := b.turns[orderID]
+	if t == nil {
+		t = &writeTurn{}
+		b.turns[orderID] = t
+	}
+	t.issued++
+	return t.issued
+}
+
+// writeInTurn waits until orderID's earlier tickets are written, then writes batches.
+func (b *fillBatcher) writeInTurn(ctx context.Context, orderID string, ticket uint64, batches ...*pendingFill) {
+	b.writeMu.Lock()
+	t := b.turns[orderID]
+	for t.written+1 != ticket {
+		b.turnDone.Wait()
+	}
+	b.writeMu.Unlock()
+
+	for _, p := range batches {
+		b.write(ctx, p)
+	}
+
+	b.writeMu.Lock()
+	t.written = ticket
+	if t.written == t.issued {
+		delete(b.turns, orderID)
+	}
+	b.turnDone.Broadcast()
+	b.writeMu.Unlock()
+}
+
+func (b *fillBatcher) write(ctx context.Context, p *pendingFill) {
+	if err := b.store.UpdateOrderFill(ctx, p.trade.OrderID, p.status, p.cumQty, p.price); err != nil {
+		log.Printf("%s: update order fill error: %v", b.name, err)
+	}
+	tr := p.trade
+	if tr.Qty > 0 {
+		tr.Price = p.notional / tr.Qty
+	}
+	if err := b.store.CreateTrade(ctx, tr); err != nil {
+		log.Printf("%s: create trade error: %v", b.name, err)
+	}
+}
@@ -0,0 +1,92 @@
+package order
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+// blockingFillStore holds every order update until release is closed.
+type blockingFillStore struct {
+	countingFillStore
+	entered chan string
+	release chan struct{}
+}
+
+func (s *blockingFillStore) UpdateOrderFill(ctx context.Context, id, status string, filledQty, price float64) error {
+	s.entered <- id
+	<-s.release
+	return s.countingFillStore.UpdateOrderFill(ctx, id, status, filledQty, price)
+}
+
+func TestFillBatcherWritesOutsideItsLock(t *testing.T) {
+	store := &blockingFillStore{entered: make(chan string, 4), release: make(chan struct{})}
+	b := newFillBatcher(store, time.Minute, "test")
+	ctx := context.Background()
+
+	// o1 completes and its write blocks in the store.
+	go b.add(ctx, "FILLED", 1, db.Trade{OrderID: "o1", Qty: 1, Price: 100})
+	if id := <-store.entered; id != "o1" {
+		t.Fatalf("first write for %s, want o1", id)
+	}
+
+	// A partial of another order is still batched meanwhile.
+	added := make(chan struct{})
+	go func() {
+		b.add(ctx, "PARTIALLY_FILLED", 0.5, db.Trade{OrderID: "o2", Qty: 0.5, Price: 100})
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("add blocked behind another order's DB write")
+	}
+
+	// o2's flush does not wait for o1's write either.
+	go b.flushAll(ctx)
+	select {
+	case id := <-store.entered:
+		if id != "o2" {
+			t.Fatalf("second write for %s, want o2", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("o2's write waited for o1's")
+	}
+	close(store.release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if u, _ := store.counts(); u == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("flushAll did not write the pending batch")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFillBatcherWritesOneOrderInSequence(t *testing.T) {
+	store := &blockingFillStore{entered: make(chan string, 4), release: make(chan struct{})}
+	b := newFillBatcher(store, 0, "test")
+	ctx := context.Background()
+
+	go b.add(ctx, "PARTIALLY_FILLED", 0.5, db.Trade{OrderID: "o1", Qty: 0.5, Price: 100})
+	<-store.entered
+	done := make(chan struct{})
+	go func() {
+		b.add(ctx, "FILLED", 1, db.Trade{OrderID: "o1", Qty: 0.5, Price: 100})
+		close(done)
+	}()
+	select {
+	case <-store.entered:
+		t.Fatal("second write of o1 started before the first finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(store.release)
+	<-done
+	if len(store.updates) != 2 || store.updates[0].Status != "PARTIALLY_FILLED" || store.updates[1].Status != "FILLED" {
+		t.Fatalf("writes = %+v, want the partial then the fill", store.updates)
+	}
+}
@@ -0,0 +1,130 @@
+package order
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// Fill sources for FillLedger.Prefer.
+const (
+	FillSourceFirst  = "first"  // whichever path reports an order's fills first books them
+	FillSourceStream = "stream" // only the user data stream books fills
+)
+
+// FillLedger remembers which fills have been booked, so a fill known both
+// from a synchronous FILLED order response and from the user data stream (or
+// replayed by the stream after a reconnect) is booked once.
+//
+// Stream trades are keyed by exchange trade ID. A synchronous response books
+// the whole order, so it claims the order ID: the stream then skips that
+// order's trades, and a response arriving after the stream already booked
+// some of them is not booked again. Entries are forgotten after TTL.
+type FillLedger struct {
+	Prefer string        // FillSourceFirst (default) or FillSourceStream
+	TTL    time.Duration // how long booked fills are remembered (0 = 24h)
+
+	mu        sync.Mutex
+	trades    map[string]time.Time
+	orders    map[string]fillClaim
+	lastSweep time.Time
+}
+
+type fillClaim struct {
+	stream bool // claimed by the user stream rather than a synchronous response
+	at     time.Time
+}
+
+// NewFillLedger creates a ledger; prefer is FillSourceFirst or FillSourceStream.
+func NewFillLedger(prefer string) *FillLedger {
+	return &FillLedger{
+		Prefer: strings.ToLower(strings.TrimSpace(prefer)),
+		trades: make(map[string]time.Time),
+		orders: make(map[string]fillClaim),
+	}
+}
+
+// claimSync reports whether a synchronous FILLED response for orderID should
+// be booked, claiming the order (and any trade IDs the response listed) when
+// it is. A nil ledger books everything.
+func (l *FillLedger) claimSync(orderID string, fills []exchange.Fill) bool {
+	if l == nil {
+		return true
+	}
+	if l.Prefer == FillSourceStream {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweepLocked(now)
+	if c, ok := l.orders[orderID]; ok && c.stream {
+		return false
+	}
+	for _, f := range fills {
+		if _, seen := l.trades[tradeKey(f.Symbol, f.TradeID)]; seen && f.TradeID != "" {
+			return false
+		}
+	}
+	l.orders[orderID] = fillClaim{at: now}
+	for _, f := range fills {
+		if f.TradeID != "" {
+			l.trades[tradeKey(f.Symbol, f.TradeID)] = now
+		}
+	}
+	return true
+}
+
+func tradeKey(symbol, tradeID string) string {
+	return strings.ToUpper(symbol) + ":" + tradeID
+}
+
+// claimStream reports whether a user-stream trade should be booked: not when
+// its trade ID was already booked or a synchronous response booked its order.
+func (l *FillLedger) claimStream(symbol, tradeID, orderID string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweepLocked(now)
+	if c, ok := l.orders[orderID]; ok && !c.stream {
+		return false
+	}
+	if tradeID != "" {
+		key := tradeKey(symbol, tradeID)
+		if _, seen := l.trades[key]; seen {
+			return false
+		}
+		l.trades[key] = now
+	}
+	if orderID != "" {
+		l.orders[orderID] = fillClaim{stream: true, at: now}
+	}
+	return true
+}
+
+// sweepLocked drops entries older than TTL, at most once per TTL.
+func (l *FillLedger) sweepLocked(now time.Time) {
+	ttl := l.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if now.Sub(l.lastSweep) < ttl {
+		return
+	}
+	l.lastSweep = now
+	for k, at := range l.trades {
+		if now.Sub(at) > ttl {
+			delete(l.trades, k)
+		}
+	}
+	for k, c := range l.orders {
+		if now.Sub(c.at) > ttl {
+			delete(l.orders, k)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-core/pkg/db"
+	"trading-core/pkg/ids"
+	"trading-core/pkg/money"
+)
+
+// Ladder size distributions.
+const (
+	LadderEqual          = "equal"           // same size per tranche
+	LadderPyramid        = "pyramid"         // largest tranche first
+	LadderInversePyramid = "inverse_pyramid" // smallest tranche first
+)
+
+// Ladder scopes: which signals are split.
+const (
+	LadderEntries = "entry"
+	LadderExits   = "exit"
+	LadderBoth    = "both"
+)
+
+// LadderConfig splits one strategy signal into several smaller orders. Each
+// tranche after the first is placed SpacingPct further from the reference
+// price (below it for buys, above for sells) as a LIMIT order, and/or
+// IntervalSec later than the previous one. Tranches <= 1 disables laddering.
+type LadderConfig struct {
+	Tranches     int     `json:"tranches"`
+	SpacingPct   float64 `json:"spacing_pct"`
+	IntervalSec  int     `json:"interval_seconds"`
+	Distribution string  `json:"distribution"` // equal (default), pyramid, inverse_pyramid
+	ApplyTo      string  `json:"apply_to"`     // entry (default), exit, both
+}
+
+// Enabled reports whether signals are split at all.
+func (c LadderConfig) Enabled() bool {
+	return c.Tranches > 1
+}
+
+// Validate checks the config ranges.
+func (c LadderConfig) Validate() error {
+	if c.Tranches < 0 || c.Tranches > 20 {
+		return fmt.Errorf("tranches must be between 0 and 20")
+	}
+	if c.SpacingPct < 0 || c.SpacingPct >= 100/float64(max(c.Tranches, 1)) {
+		return fmt.Errorf("spacing_pct must be >= 0 and keep every level above zero")
+	}
+	if c.IntervalSec < 0 {
+		return fmt.Errorf("interval_seconds must be >= 0")
+	}
+	switch c.Distribution {
+	case "", LadderEqual, LadderPyramid, LadderInversePyramid:
+	default:
+		return fmt.Errorf("distribution must be equal, pyramid or inverse_pyramid")
+	}
+	switch c.ApplyTo {
+	case "", LadderEntries, LadderExits, LadderBoth:
+	default:
+		return fmt.Errorf("apply_to must be entry, exit or both")
+	}
+	return nil
+}
+
+// Applies reports whether a signal that is (or is not) an exit gets laddered.
+func (c LadderConfig) Applies(exit bool) bool {
+	if !c.Enabled() {
+		return false
+	}
+	switch c.ApplyTo {
+	case LadderBoth:
+		return true
+	case LadderExits:
+		return exit
+	default:
+		return !exit
+	}
+}
+
+// LadderFromDB converts a stored strategy ladder setting.
+func LadderFromDB(l db.StrategyLadder) LadderConfig {
+	return LadderConfig{
+		Tranches:     l.Tranches,
+		SpacingPct:   l.SpacingPct,
+		IntervalSec:  l.IntervalSec,
+		Distribution: l.Distribution,
+		ApplyTo:      l.ApplyTo,
+	}
+}
+
+// ToDB converts c for storage, filling in the defaults.
+func (c LadderConfig) ToDB() db.StrategyLadder {
+	l := db.StrategyLadder{
+		Tranches:     c.Tranches,
+		SpacingPct:   c.SpacingPct,
+		IntervalSec:  c.IntervalSec,
+		Distribution: c.Distribution,
+		ApplyTo:      c.ApplyTo,
+	}
+	if l.Distribution == "" {
+		l.Distribution = LadderEqual
+	}
+	if l.ApplyTo == "" {
+		l.ApplyTo = LadderEntries
+	}
+	return l
+}
+
+// weights returns the relative size of each tranche.
+func (c LadderConfig) weights() []float64 {
+	w := make([]float64, c.Tranches)
+	for i := range w {
+		switch c.Distribution {
+		case LadderPyramid:
+			w[i] = float64(c.Tranches - i)
+		case LadderInversePyramid:
+			w[i] = float64(i + 1)
+		default:
+			w[i] = 1
+		}
+	}
+	return w
+}
+
+// LadderTranche is one order of a ladder and how long after the signal it is
+// placed.
+type LadderTranche struct {
+	Order Order
+	Delay time.Duration
+}
+
+// Ladder splits base (the order for the whole signal, already risk-checked
+// and sized) into c.Tranches orders whose quantities sum to base.Qty. The
+// first tranche keeps base's type and price; spaced tranches become GTC LIMIT
+// orders at their level. refPrice is the price the levels are measured from.
+func (c LadderConfig) Ladder(base Order, refPrice float64) []LadderTranche {
+	if !c.Enabled() {
+		return []LadderTranche{{Order: base}}
+	}
+	weights := c.weights()
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	buy := strings.EqualFold(base.Side, "BUY")
+	out := make([]LadderTranche, 0, c.Tranches)
+	remaining := money.FromFloat(base.Qty)
+	for i, w := range weights {
+		o := base
+		if i > 0 {
+			o.ID = ids.New()
+		}
+		if i == len(weights)-1 {
+			// The last tranche takes the rounding remainder so the total is exact.
+			o.Qty = remaining.Float64()
+		} else {
+			o.Qty = base.Qty * w / total
+			remaining = remaining.Sub(money.FromFloat(o.Qty))
+		}
+		if i > 0 && c.SpacingPct > 0 && refPrice > 0 {
+			offset := float64(i) * c.SpacingPct / 100
+			if buy {
+				o.Price = refPrice * (1 - offset)
+			} else {
+				o.Price = refPrice * (1 + offset)
+			}
+			o.Type = "LIMIT"
+			o.TimeInForce = "GTC"
+		}
+		out = append(out, LadderTranche{Order: o, Delay: time.Duration(i*c.IntervalSec) * time.Second})
+	}
+	return out
+}
+
+// LadderScheduler places ladder tranches and keeps track of them until the
+// strategy no longer needs them. Delayed tranches are persisted so they
+// survive a restart, and are only placed if the strategy is still active when
+// they come due. Tranches already sent as resting limit orders are cancelled,
+// together with the pending ones, when the strategy is stopped or flattened.
+type LadderScheduler struct {
+	db     *db.Database
+	queue  OrderQueue
+	active func(ctx context.Context, strategyID string) (bool, error)
+	cancel func(ctx context.Context, o Order) error
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer // order ID -> pending tranche timer
+}
+
+// NewLadderScheduler creates a scheduler placing tranches on q. Without a
+// database it only keeps delayed tranches in memory.
+func NewLadderScheduler(database *db.Database, q OrderQueue) *LadderScheduler {
+	return &LadderScheduler{db: database, queue: q, timers: make(map[string]*time.Timer)}
+}
+
+// SetActiveCheck sets how the scheduler tells whether a strategy may still
+// trade when a delayed tranche comes due. Without one every tranche is placed.
+func (l *LadderScheduler) SetActiveCheck(fn func(ctx context.Context, strategyID string) (bool, error)) {
+	l.active = fn
+}
+
+// SetCanceler sets how resting tranches are cancelled on the exchange.
+func (l *LadderScheduler) SetCanceler(fn func(ctx context.Context, o Order) error) {
+	l.cancel = fn
+}
+
+// Schedule enqueues tranches without delay now and the rest when their delay
+// elapses, unless ctx is done or the strategy was stopped by then.
+func (l *LadderScheduler) Schedule(ctx context.Context, tranches []LadderTranche) {
+	now := time.Now()
+	for i, t := range tranches {
+		o := t.Order
+		// The first tranche is the signal's own order; only the extra ones are
+		// the ladder's to track.
+		tracked := i > 0 && o.StrategyInstanceID != ""
+		if t.Delay <= 0 {
+			if tracked && o.TimeInForce == "GTC" {
+				l.save(ctx, o, now, true)
+			}
+			l.queue.Enqueue(o)
+			continue
+		}
+		if tracked {
+			l.save(ctx, o, now.Add(t.Delay), false)
+		}
+		l.arm(ctx, o, t.Delay)
+	}
+}
+
+// Resume re-arms the delayed tranches persisted before a restart; overdue
+// ones are placed (or dropped) right away. Placed tranches that have finished
+// are forgotten.
+func (l *LadderScheduler) Resume(ctx context.Context) error {
+	if l.db == nil {
+		return nil
+	}
+	pending, err := l.db.ListLadderTranches(ctx, "")
+	if err != nil {
+		return err
+	}
+	for _, t := range pending {
+		if t.Placed {
+			// Stop tracking tranches that have since filled or been cancelled.
+			if _, status, found, err := l.db.GetOrderExchangeState(ctx, t.OrderID); err == nil && found && db.IsTerminalOrderStatus(status) {
+				l.forget(ctx, t.OrderID)
+			}
+			continue
+		}
+		var o Order
+		if err := json.Unmarshal(t.Payload, &o); err != nil {
+			log.Printf("⚠️ Ladder tranche %s unreadable, dropped: %v", t.OrderID, err)
+			l.forget(ctx, t.OrderID)
+			continue
+		}
+		l.arm(ctx, o, time.Until(t.DueAt))
+	}
+	return nil
+}
+
+// CancelStrategy drops strategyID's pending tranches and cancels the ones
+// still resting on the exchange.
+func (l *LadderScheduler) CancelStrategy(ctx context.Context, strategyID string) error {
+	if l.db == nil {
+		return nil
+	}
+	tranches, err := l.db.ListLadderTranches(ctx, strategyID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range tranches {
+		l.mu.Lock()
+		if timer, ok := l.timers[t.OrderID]; ok {
+			timer.Stop()
+			delete(l.timers, t.OrderID)
+		}
+		l.mu.Unlock()
+
+		if t.Placed && l.cancel != nil {
+			var o Order
+			if err := json.Unmarshal(t.Payload, &o); err == nil {
+				if err := l.cancel(ctx, o); err != nil {
+					errs = append(errs, err)
+					continue // keep tracking it so a later stop retries
+				}
+			}
+		}
+		l.forget(ctx, t.OrderID)
+	}
+	if len(tranches) > 0 {
+		log.Printf("🪜 Strategy %s: %d ladder tranches cancelled", strategyID, len(tranches)-len(errs))
+	}
+	return errors.Join(errs...)
+}
+
+func (l *LadderScheduler) arm(ctx context.Context, o Order, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timers[o.ID] = time.AfterFunc(max(delay, 0), func() { l.fire(ctx, o) })
+}
+
+// fire places a delayed tranche if its strategy may still trade.
+func (l *LadderScheduler) fire(ctx context.Context, o Order) {
+	l.mu.Lock()
+	_, armed := l.timers[o.ID]
+	delete(l.timers, o.ID)
+	l.mu.Unlock()
+	if !armed {
+		return // cancelled while the timer was firing
+	}
+	if ctx.Err() != nil {
+		// Left in the store; Resume places it after the restart.
+		log.Printf("⚠️ Ladder tranche %s %s %s deferred: shutting down", o.ID, o.Side, o.Symbol)
+		return
+	}
+	if o.StrategyInstanceID != "" && l.active != nil {
+		active, err := l.active(ctx, o.StrategyInstanceID)
+		if err != nil || !active {
+			reason := "strategy no longer active"
+			if err != nil {
+				reason = err.Error()
+			}
+			log.Printf("⚠️ Ladder tranche %s %s %s dropped: %s", o.ID, o.Side, o.Symbol, reason)
+			l.forget(ctx, o.ID)
+			return
+		}
+	}
+	if o.TimeInForce == "GTC" && l.db != nil {
+		if err := l.db.MarkLadderTranchePlaced(ctx, o.ID); err != nil {
+			log.Printf("⚠️ Ladder tranche %s: %v", o.ID, err)
+		}
+	} else {
+		l.forget(ctx, o.ID)
+	}
+	l.queue.Enqueue(o)
+}
+
+func (l *LadderScheduler) save(ctx context.Context, o Order, dueAt time.Time, placed bool) {
+	if l.db == nil {
+		return
+	}
+	payload, err := json.Marshal(o)
+	if err == nil {
+		err = l.db.SaveLadderTranche(ctx, db.LadderTranche{
+			OrderID: o.ID, StrategyID: o.StrategyInstanceID, Payload: payload, DueAt: dueAt, Placed: placed,
+		})
+	}
+	if err != nil {
+		log.Printf("⚠️ Ladder tranche %s not persisted: %v", o.ID, err)
+	}
+}
+
+func (l *LadderScheduler) forget(ctx context.Context, orderID string) {
+	if l.db == nil {
+		return
+	}
+	if err := l.db.DeleteLadderTranche(context.WithoutCancel(ctx), orderID); err != nil {
+		log.Printf("⚠️ Ladder tranche %s: %v", orderID, err)
+	}
+}
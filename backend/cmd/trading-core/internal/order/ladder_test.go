@@ -0,0 +1,183 @@
+package order
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLadderSplitsSignalIntoThreeTranches(t *testing.T) {
+	base := Order{ID: "sig-1", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Qty: 0.6, StrategyInstanceID: "s1"}
+
+	t.Run("equal by price", func(t *testing.T) {
+		cfg := LadderConfig{Tranches: 3, SpacingPct: 1}
+		tranches := cfg.Ladder(base, 50000)
+		if len(tranches) != 3 {
+			t.Fatalf("got %d tranches, want 3", len(tranches))
+		}
+		wantPrice := []float64{0, 49500, 49000}
+		var total float64
+		seen := map[string]bool{}
+		for i, tr := range tranches {
+			o := tr.Order
+			total += o.Qty
+			seen[o.ID] = true
+			if math.Abs(o.Qty-0.2) > 1e-9 {
+				t.Errorf("tranche %d qty = %v, want 0.2", i, o.Qty)
+			}
+			if math.Abs(o.Price-wantPrice[i]) > 1e-6 {
+				t.Errorf("tranche %d price = %v, want %v", i, o.Price, wantPrice[i])
+			}
+			if tr.Delay != 0 || o.StrategyInstanceID != "s1" || o.Side != "BUY" {
+				t.Errorf("tranche %d = %+v", i, tr)
+			}
+		}
+		if tranches[0].Order.Type != "MARKET" || tranches[0].Order.ID != "sig-1" {
+			t.Errorf("first tranche should keep the signal order, got %+v", tranches[0].Order)
+		}
+		if tranches[1].Order.Type != "LIMIT" || tranches[1].Order.TimeInForce != "GTC" {
+			t.Errorf("spaced tranche should be a GTC limit, got %+v", tranches[1].Order)
+		}
+		if len(seen) != 3 {
+			t.Errorf("tranche IDs not unique: %v", seen)
+		}
+		if math.Abs(total-0.6) > 1e-12 {
+			t.Errorf("tranches sum to %v, want the signal's 0.6", total)
+		}
+	})
+
+	t.Run("pyramid sell over time", func(t *testing.T) {
+		cfg := LadderConfig{Tranches: 3, IntervalSec: 30, Distribution: LadderPyramid, ApplyTo: LadderExits}
+		sell := base
+		sell.Side = "SELL"
+		tranches := cfg.Ladder(sell, 50000)
+		wantQty := []float64{0.3, 0.2, 0.1}
+		for i, tr := range tranches {
+			if math.Abs(tr.Order.Qty-wantQty[i]) > 1e-9 {
+				t.Errorf("tranche %d qty = %v, want %v", i, tr.Order.Qty, wantQty[i])
+			}
+			if tr.Delay != time.Duration(i*30)*time.Second || tr.Order.Type != "MARKET" {
+				t.Errorf("tranche %d = delay %v type %s", i, tr.Delay, tr.Order.Type)
+			}
+		}
+		if !cfg.Applies(true) || cfg.Applies(false) {
+			t.Error("exit-only ladder should split exits but not entries")
+		}
+	})
+}
+
+func TestLadderConfigValidate(t *testing.T) {
+	for _, cfg := range []LadderConfig{
+		{Tranches: 21},
+		{Tranches: 3, SpacingPct: 40},
+		{Tranches: 3, IntervalSec: -1},
+		{Tranches: 3, Distribution: "random"},
+		{Tranches: 3, ApplyTo: "sometimes"},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", cfg)
+		}
+	}
+	if err := (LadderConfig{Tranches: 3, SpacingPct: 0.5, IntervalSec: 10, Distribution: LadderInversePyramid, ApplyTo: LadderBoth}).Validate(); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+}
+
+func TestLadderSchedulerDelaysLaterTranches(t *testing.T) {
+	q := NewQueue(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	NewLadderScheduler(nil, q).Schedule(ctx, []LadderTranche{
+		{Order: Order{ID: "a"}},
+		{Order: Order{ID: "b"}, Delay: 20 * time.Millisecond},
+	})
+	if n := q.Len(); n != 1 {
+		t.Fatalf("queued %d orders immediately, want 1", n)
+	}
+	deadline := time.Now().Add(time.Second)
+	for q.Len() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("delayed tranche was never queued")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLadderSchedulerStopsWithStrategy(t *testing.T) {
+	database := openWALTestDB(t, filepath.Join(t.TempDir(), "ladder.db"))
+	defer database.Close()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	active := map[string]bool{"s1": true}
+	var cancelled []string
+	newScheduler := func(q OrderQueue) *LadderScheduler {
+		l := NewLadderScheduler(database, q)
+		l.SetActiveCheck(func(_ context.Context, id string) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return active[id], nil
+		})
+		l.SetCanceler(func(_ context.Context, o Order) error {
+			mu.Lock()
+			defer mu.Unlock()
+			cancelled = append(cancelled, o.ID)
+			return nil
+		})
+		return l
+	}
+
+	q := NewQueue(10)
+	base := Order{ID: "sig", Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Qty: 0.3, StrategyInstanceID: "s1"}
+	tranches := LadderConfig{Tranches: 3, SpacingPct: 1, IntervalSec: 1}.Ladder(base, 50000)
+	// Send the second tranche now so it rests on the book; keep the third pending.
+	tranches[1].Delay = 0
+	tranches[2].Delay = 40 * time.Millisecond
+	newScheduler(q).Schedule(ctx, tranches)
+	if n := q.Len(); n != 2 {
+		t.Fatalf("queued %d orders immediately, want 2", n)
+	}
+
+	// A restart re-arms the pending tranche from the store.
+	restarted := newScheduler(q)
+	if err := restarted.Resume(ctx); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+
+	// Stopping the strategy cancels the resting tranche and drops the pending one.
+	mu.Lock()
+	active["s1"] = false
+	mu.Unlock()
+	if err := restarted.CancelStrategy(ctx, "s1"); err != nil {
+		t.Fatalf("CancelStrategy: %v", err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if n := q.Len(); n != 2 {
+		t.Fatalf("queue holds %d orders after stop, want 2 (pending tranche placed)", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cancelled) != 1 || cancelled[0] != tranches[1].Order.ID {
+		t.Fatalf("cancelled %v, want [%s]", cancelled, tranches[1].Order.ID)
+	}
+	if left, _ := database.ListLadderTranches(ctx, "s1"); len(left) != 0 {
+		t.Fatalf("%d tranches still tracked after stop", len(left))
+	}
+}
+
+func TestLadderSchedulerDropsTrancheOfInactiveStrategy(t *testing.T) {
+	q := NewQueue(10)
+	l := NewLadderScheduler(nil, q)
+	l.SetActiveCheck(func(context.Context, string) (bool, error) { return false, nil })
+	l.Schedule(context.Background(), []LadderTranche{
+		{Order: Order{ID: "b", StrategyInstanceID: "s1"}, Delay: 10 * time.Millisecond},
+	})
+	time.Sleep(40 * time.Millisecond)
+	if n := q.Len(); n != 0 {
+		t.Fatalf("queued %d tranches of a stopped strategy, want 0", n)
+	}
+}
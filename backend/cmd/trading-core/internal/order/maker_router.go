@@ -0,0 +1,213 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// RoutingMakerFirst posts orders passively at the best bid/ask and only crosses
+// the spread once repricing has not filled them.
+const RoutingMakerFirst = "maker_first"
+
+// makerCancelTimeout bounds cancelling a resting maker order once the
+// submitting context has ended.
+const makerCancelTimeout = 5 * time.Second
+
+// BookSource provides the current best bid/ask (e.g. market.BookStore).
+type BookSource interface {
+	BestBidAsk(symbol string) (bid, ask float64, ok bool)
+}
+
+// MakerFirstConfig tunes maker-first routing.
+type MakerFirstConfig struct {
+	Timeout      time.Duration // wait this long for a fill before repricing
+	MaxReprices  int           // maker reposts before crossing (0 = cross after the first timeout)
+	PollInterval time.Duration // how often the order is queried while waiting
+}
+
+// MakerRouter submits maker-first orders: a post-only LIMIT at the touch
+// (LIMIT_MAKER on spot, GTX on futures), repriced to the new touch after each
+// timeout, and finally a MARKET order for whatever remains unfilled.
+//
+// Submit blocks until the order is filled or crossed, so it should run on its
+// own goroutine rather than a request goroutine; AsyncExecutor runs these
+// orders outside its worker pool.
+type MakerRouter struct {
+	Book   BookSource
+	Config MakerFirstConfig
+}
+
+// NewMakerRouter creates a router with defaults for unset config values.
+func NewMakerRouter(book BookSource, cfg MakerFirstConfig) *MakerRouter {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.PollInterval > cfg.Timeout {
+		cfg.PollInterval = cfg.Timeout
+	}
+	return &MakerRouter{Book: book, Config: cfg}
+}
+
+// Submit routes req through gw. Gateways that cannot report order state, and
+// symbols without a book, fall back to submitting req unchanged.
+func (r *MakerRouter) Submit(ctx context.Context, gw exchange.Gateway, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	querier, ok := gw.(exchange.OrderQuerier)
+	if !ok {
+		log.Printf("maker-first: gateway cannot query orders; submitting %s as-is", req.ClientID)
+		return gw.SubmitOrder(ctx, req)
+	}
+	if _, _, ok := r.Book.BestBidAsk(req.Symbol); !ok {
+		log.Printf("maker-first: no book for %s; submitting %s as-is", req.Symbol, req.ClientID)
+		return gw.SubmitOrder(ctx, req)
+	}
+
+	// Fills are tallied across the maker attempts and the final cross, so the
+	// result carries the average price actually paid.
+	remaining := req.Qty
+	var filledQty, notional float64
+	var last exchange.OrderResult
+	for attempt := 0; attempt <= r.Config.MaxReprices; attempt++ {
+		mreq, err := r.makerRequest(req, remaining)
+		if err != nil {
+			break
+		}
+		if attempt > 0 {
+			log.Printf("maker-first: repricing %s %s to %v (remaining %v)", req.ClientID, req.Symbol, mreq.Price, remaining)
+		}
+		res, err := gw.SubmitOrder(ctx, mreq)
+		if err != nil {
+			// Most often the post-only order would have crossed a moving book.
+			log.Printf("maker-first: post %s at %v rejected: %v", req.ClientID, mreq.Price, err)
+			continue
+		}
+		last = res
+		st, err := r.await(ctx, gw, querier, req.Symbol, res)
+		if err != nil {
+			return last, err
+		}
+		filledQty += st.FilledQty
+		notional += st.FilledQty * fillPrice(st, mreq.Price)
+		remaining -= st.FilledQty
+		if remaining <= 0 {
+			last.Status = exchange.StatusFilled
+			last.AvgPrice = notional / filledQty
+			return last, nil
+		}
+	}
+
+	log.Printf("maker-first: %s unfilled after %d maker attempt(s); crossing with MARKET for %v", req.ClientID, r.Config.MaxReprices+1, remaining)
+	taker := req
+	taker.Type = exchange.OrderTypeMarket
+	taker.Qty = remaining
+	taker.Price = 0
+	taker.TimeInForce = ""
+	res, err := gw.SubmitOrder(ctx, taker)
+	if err != nil || res.Status != exchange.StatusFilled {
+		return res, err
+	}
+	if px := r.takerPrice(ctx, querier, req.Symbol, res); px > 0 {
+		res.AvgPrice = (notional + remaining*px) / (filledQty + remaining)
+	}
+	return res, nil
+}
+
+// fillPrice is the average price of a maker order's fills, falling back to
+// its limit price when the venue did not report one.
+func fillPrice(st exchange.OrderState, limit float64) float64 {
+	if st.AvgPrice > 0 {
+		return st.AvgPrice
+	}
+	return limit
+}
+
+// takerPrice returns the average price of a filled MARKET order, from the
+// fills in its response or else by querying it; 0 when unknown.
+func (r *MakerRouter) takerPrice(ctx context.Context, q exchange.OrderQuerier, symbol string, res exchange.OrderResult) float64 {
+	var qty, notional float64
+	for _, f := range res.Fills {
+		qty += f.Qty
+		notional += f.Qty * f.Price
+	}
+	if qty > 0 {
+		return notional / qty
+	}
+	st, err := q.QueryOrder(ctx, symbol, res.ExchangeOrderID)
+	if err != nil {
+		log.Printf("maker-first: query %s for its fill price: %v", res.ExchangeOrderID, err)
+		return 0
+	}
+	return st.AvgPrice
+}
+
+// makerRequest builds a post-only order for qty at the current touch.
+func (r *MakerRouter) makerRequest(req exchange.OrderRequest, qty float64) (exchange.OrderRequest, error) {
+	bid, ask, ok := r.Book.BestBidAsk(req.Symbol)
+	if !ok {
+		return req, fmt.Errorf("no book for %s", req.Symbol)
+	}
+	m := req
+	m.Qty = qty
+	m.Price = bid
+	if req.Side == exchange.SideSell {
+		m.Price = ask
+	}
+	if req.Market == exchange.MarketSpot || req.Market == "" {
+		m.Type = exchange.OrderTypeLimitMaker
+		m.TimeInForce = ""
+	} else {
+		m.Type = exchange.OrderTypeLimit
+		m.TimeInForce = exchange.TIFGTX
+	}
+	if adj, ok := exchange.AdjustToFilters(m); ok {
+		m = adj
+	}
+	return m, nil
+}
+
+// await polls a resting maker order until it fills or the timeout passes, then
+// cancels it and returns its final state. If ctx ends first the order is
+// cancelled too, so nothing is left resting once Submit has returned.
+func (r *MakerRouter) await(ctx context.Context, gw exchange.Gateway, q exchange.OrderQuerier, symbol string, res exchange.OrderResult) (exchange.OrderState, error) {
+	if res.Status == exchange.StatusFilled {
+		return q.QueryOrder(ctx, symbol, res.ExchangeOrderID)
+	}
+
+	deadline := time.NewTimer(r.Config.Timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(r.Config.PollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), makerCancelTimeout)
+			defer cancel()
+			if err := gw.CancelOrder(cctx, symbol, res.ExchangeOrderID); err != nil {
+				log.Printf("maker-first: cancel %s after %v: %v", res.ExchangeOrderID, ctx.Err(), err)
+			}
+			return exchange.OrderState{}, ctx.Err()
+		case <-poll.C:
+			st, err := q.QueryOrder(ctx, symbol, res.ExchangeOrderID)
+			if err != nil {
+				log.Printf("maker-first: query %s: %v", res.ExchangeOrderID, err)
+				continue
+			}
+			switch st.Status {
+			case exchange.StatusFilled, exchange.StatusCanceled, exchange.StatusExpired, exchange.StatusRejected:
+				return st, nil
+			}
+		case <-deadline.C:
+			if err := gw.CancelOrder(ctx, symbol, res.ExchangeOrderID); err != nil {
+				log.Printf("maker-first: cancel %s: %v", res.ExchangeOrderID, err)
+			}
+			// Re-read after cancelling so fills that raced the cancel are counted.
+			return q.QueryOrder(ctx, symbol, res.ExchangeOrderID)
+		}
+	}
+}
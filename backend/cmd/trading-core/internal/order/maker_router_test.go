@@ -0,0 +1,168 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+type stubBook struct {
+	mu       sync.Mutex
+	bid, ask float64
+}
+
+func (b *stubBook) BestBidAsk(string) (float64, float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bid, b.ask, true
+}
+
+func (b *stubBook) set(bid, ask float64) {
+	b.mu.Lock()
+	b.bid, b.ask = bid, ask
+	b.mu.Unlock()
+}
+
+// makerGateway rests every order; orders priced at fillAt or better fill on the next query.
+type makerGateway struct {
+	mu       sync.Mutex
+	book     *stubBook
+	fillAt   float64
+	reqs     []exchange.OrderRequest
+	canceled []string
+}
+
+func (g *makerGateway) SubmitOrder(_ context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reqs = append(g.reqs, req)
+	return exchange.OrderResult{ExchangeOrderID: fmt.Sprint(len(g.reqs)), Status: exchange.StatusNew, ClientID: req.ClientID}, nil
+}
+
+func (g *makerGateway) CancelOrder(_ context.Context, _, id string) error {
+	g.mu.Lock()
+	g.canceled = append(g.canceled, id)
+	g.mu.Unlock()
+	// The market moves up while the first order rests unfilled.
+	g.book.set(101, 101.5)
+	return nil
+}
+
+func (g *makerGateway) QueryOrder(_ context.Context, _, id string) (exchange.OrderState, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var n int
+	fmt.Sscan(id, &n)
+	req := g.reqs[n-1]
+	for _, c := range g.canceled {
+		if c == id {
+			return exchange.OrderState{Status: exchange.StatusCanceled}, nil
+		}
+	}
+	if req.Price >= g.fillAt {
+		return exchange.OrderState{Status: exchange.StatusFilled, FilledQty: req.Qty}, nil
+	}
+	return exchange.OrderState{Status: exchange.StatusNew}, nil
+}
+
+func TestMakerRouterRepricesUnfilledOrder(t *testing.T) {
+	book := &stubBook{bid: 100, ask: 100.5}
+	gw := &makerGateway{book: book, fillAt: 101}
+	router := NewMakerRouter(book, MakerFirstConfig{Timeout: 30 * time.Millisecond, PollInterval: 5 * time.Millisecond, MaxReprices: 1})
+
+	res, err := router.Submit(context.Background(), gw, exchange.OrderRequest{
+		Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeLimit, Qty: 0.5, Price: 99,
+		ClientID: "o1", Market: exchange.MarketSpot,
+	})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if res.Status != exchange.StatusFilled {
+		t.Fatalf("expected FILLED, got %s", res.Status)
+	}
+	if res.AvgPrice != 101 {
+		t.Fatalf("result should carry the repriced fill price 101, got %v", res.AvgPrice)
+	}
+	if len(gw.reqs) != 2 {
+		t.Fatalf("expected initial post + one reprice, got %d submits: %+v", len(gw.reqs), gw.reqs)
+	}
+	first, second := gw.reqs[0], gw.reqs[1]
+	if first.Type != exchange.OrderTypeLimitMaker || first.Price != 100 {
+		t.Fatalf("first post should be LIMIT_MAKER at the bid 100, got %s @ %v", first.Type, first.Price)
+	}
+	if len(gw.canceled) != 1 || gw.canceled[0] != "1" {
+		t.Fatalf("expected the unfilled order to be canceled, got %v", gw.canceled)
+	}
+	if second.Type != exchange.OrderTypeLimitMaker || second.Price != 101 || second.ClientID != "o1" {
+		t.Fatalf("reprice should post at the new bid 101 under the same client id, got %+v", second)
+	}
+}
+
+func TestMakerRouterCrossesAfterRepricesExhausted(t *testing.T) {
+	book := &stubBook{bid: 100, ask: 100.5}
+	gw := &makerGateway{book: book, fillAt: 1000} // never fills passively
+	router := NewMakerRouter(book, MakerFirstConfig{Timeout: 20 * time.Millisecond, PollInterval: 5 * time.Millisecond})
+
+	if _, err := router.Submit(context.Background(), gw, exchange.OrderRequest{
+		Symbol: "BTCUSDT", Side: exchange.SideSell, Type: exchange.OrderTypeMarket, Qty: 0.5,
+		ClientID: "o2", Market: exchange.MarketUSDTFut,
+	}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if len(gw.reqs) != 2 {
+		t.Fatalf("expected maker post then MARKET, got %+v", gw.reqs)
+	}
+	if m := gw.reqs[0]; m.Type != exchange.OrderTypeLimit || m.TimeInForce != exchange.TIFGTX || m.Price != 100.5 {
+		t.Fatalf("futures maker post should be GTX LIMIT at the ask, got %+v", m)
+	}
+	if x := gw.reqs[1]; x.Type != exchange.OrderTypeMarket || x.Qty != 0.5 {
+		t.Fatalf("expected MARKET for the remaining 0.5, got %+v", x)
+	}
+}
+
+func TestMakerRouterCancelsRestingOrderWhenContextEnds(t *testing.T) {
+	book := &stubBook{bid: 100, ask: 100.5}
+	gw := &makerGateway{book: book, fillAt: 1000}
+	router := NewMakerRouter(book, MakerFirstConfig{Timeout: time.Minute, PollInterval: 5 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err := router.Submit(ctx, gw, exchange.OrderRequest{
+		Symbol: "BTCUSDT", Side: exchange.SideBuy, Type: exchange.OrderTypeLimit, Qty: 0.5, Price: 99,
+		ClientID: "o3", Market: exchange.MarketSpot,
+	})
+	if err == nil {
+		t.Fatal("expected the context error")
+	}
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+	if len(gw.canceled) != 1 || gw.canceled[0] != "1" {
+		t.Fatalf("resting maker order should be cancelled, got %v", gw.canceled)
+	}
+}
+
+func TestAsyncExecutorRunsMakerFirstOrdersOutsideWorkerPool(t *testing.T) {
+	exec := &Executor{Router: &MakerRouter{}}
+	async := NewAsyncExecutor(exec, 1)
+	busy, _ := async.acquire(Order{ID: "o1"})
+	defer async.release(busy)
+
+	done := make(chan bool, 1)
+	go func() {
+		slot, ok := async.acquire(Order{ID: "o2", Routing: RoutingMakerFirst})
+		async.release(slot)
+		done <- ok && !slot
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatal("maker-first order should run without a worker slot")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("maker-first order waited for a worker slot")
+	}
+}
@@ -1,24 +1,19 @@
 package order
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
 // PersistentQueue wraps Queue with Write-Ahead Log (WAL) for crash recovery.
-// Orders are persisted to disk before processing, ensuring no data loss.
+// Orders are persisted to the WAL before processing, ensuring no data loss.
 type PersistentQueue struct {
 	queue      *Queue
-	walPath    string
-	walFile    *os.File
+	wal        WAL
 	mu         sync.Mutex
 	metrics    PersistentQueueMetrics
 	processing map[string]bool // Track orders being processed
@@ -33,33 +28,22 @@ type PersistentQueueMetrics struct {
 	Failed    uint64 // Write failures
 }
 
-// walEntry represents a single WAL entry.
-type walEntry struct {
-	Action    string    `json:"action"` // "ENQUEUE" or "COMPLETE"
-	Order     Order     `json:"order"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
-// NewPersistentQueue creates a persistent queue with WAL at the specified path.
+// NewPersistentQueue creates a persistent queue with a file WAL in walDir.
 func NewPersistentQueue(walDir string, queueSize int) (*PersistentQueue, error) {
-	if err := os.MkdirAll(walDir, 0755); err != nil {
-		return nil, fmt.Errorf("create WAL directory: %w", err)
-	}
-
-	walPath := filepath.Join(walDir, "order_queue.wal")
-	file, err := os.OpenFile(walPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	wal, err := NewFileWAL(walDir)
 	if err != nil {
-		return nil, fmt.Errorf("open WAL file: %w", err)
+		return nil, err
 	}
+	return NewPersistentQueueWithWAL(wal, queueSize), nil
+}
 
-	pq := &PersistentQueue{
+// NewPersistentQueueWithWAL creates a persistent queue on the given WAL.
+func NewPersistentQueueWithWAL(wal WAL, queueSize int) *PersistentQueue {
+	return &PersistentQueue{
 		queue:      NewQueue(queueSize),
-		walPath:    walPath,
-		walFile:    file,
+		wal:        wal,
 		processing: make(map[string]bool),
 	}
-
-	return pq, nil
 }
 
 // Recover loads pending orders from WAL after restart.
@@ -68,29 +52,15 @@ func (pq *PersistentQueue) Recover() error {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	file, err := os.Open(pq.walPath)
+	entries, err := pq.wal.Load()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // No WAL file, nothing to recover
-		}
-		return fmt.Errorf("open WAL for recovery: %w", err)
+		return err
 	}
-	defer file.Close()
 
 	// Build state from WAL: track enqueued and completed orders
 	enqueued := make(map[string]Order)
 	completed := make(map[string]bool)
-
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large orders
-
-	for scanner.Scan() {
-		var entry walEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			log.Printf("⚠️ WAL parse error (skipping): %v", err)
-			continue
-		}
-
+	for _, entry := range entries {
 		switch entry.Action {
 		case "ENQUEUE":
 			enqueued[entry.Order.ID] = entry.Order
@@ -99,16 +69,13 @@ func (pq *PersistentQueue) Recover() error {
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("WAL scan error: %w", err)
-	}
-
-	// Re-enqueue pending orders (enqueued but not completed)
+	// Re-enqueue pending orders (enqueued but not completed) in ID order, which
+	// is creation order for the time-sortable IDs from pkg/ids.
 	recoveredCount := 0
-	for id, order := range enqueued {
+	for _, id := range sortedOrderIDs(enqueued) {
 		if !completed[id] {
 			pq.processing[id] = true
-			pq.queue.Enqueue(order)
+			pq.queue.Enqueue(enqueued[id])
 			recoveredCount++
 		}
 	}
@@ -130,49 +97,32 @@ func (pq *PersistentQueue) Recover() error {
 
 // compactWAL rewrites WAL with only pending entries.
 func (pq *PersistentQueue) compactWAL(enqueued map[string]Order, completed map[string]bool) error {
-	tempPath := pq.walPath + ".tmp"
-	tempFile, err := os.Create(tempPath)
-	if err != nil {
-		return err
-	}
-
-	encoder := json.NewEncoder(tempFile)
-	for id, order := range enqueued {
+	var pending []WALEntry
+	for _, id := range sortedOrderIDs(enqueued) {
 		if !completed[id] {
-			entry := walEntry{
+			order := enqueued[id]
+			pending = append(pending, WALEntry{
 				Action:    "ENQUEUE",
 				Order:     order,
 				Timestamp: order.CreatedAt,
-			}
-			if err := encoder.Encode(entry); err != nil {
-				tempFile.Close()
-				os.Remove(tempPath)
-				return err
-			}
+			})
 		}
 	}
-
-	if err := tempFile.Sync(); err != nil {
-		tempFile.Close()
-		os.Remove(tempPath)
+	if err := pq.wal.Rewrite(pending); err != nil {
 		return err
 	}
-	tempFile.Close()
 
-	// Close current WAL and replace
-	pq.walFile.Close()
-	if err := os.Rename(tempPath, pq.walPath); err != nil {
-		return err
-	}
+	log.Printf("✓ WAL compacted: kept %d pending entries", len(pending))
+	return nil
+}
 
-	// Reopen WAL
-	pq.walFile, err = os.OpenFile(pq.walPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+func sortedOrderIDs(orders map[string]Order) []string {
+	out := make([]string, 0, len(orders))
+	for id := range orders {
+		out = append(out, id)
 	}
-
-	log.Printf("✓ WAL compacted: kept %d pending entries", len(enqueued)-len(completed))
-	return nil
+	sort.Strings(out)
+	return out
 }
 
 // Enqueue adds an order with WAL persistence.
@@ -183,35 +133,19 @@ func (pq *PersistentQueue) Enqueue(o Order) bool {
 		return false
 	}
 
-	// Write to WAL first
-	entry := walEntry{
+	// Write to WAL first, durably
+	entry := WALEntry{
 		Action:    "ENQUEUE",
 		Order:     o,
 		Timestamp: time.Now(),
 	}
-	data, err := json.Marshal(entry)
-	if err != nil {
-		pq.mu.Unlock()
-		atomic.AddUint64(&pq.metrics.Failed, 1)
-		log.Printf("❌ WAL marshal failed: %v", err)
-		return false
-	}
-
-	if _, err := pq.walFile.Write(append(data, '\n')); err != nil {
+	if err := pq.wal.Append(entry, true); err != nil {
 		pq.mu.Unlock()
 		atomic.AddUint64(&pq.metrics.Failed, 1)
 		log.Printf("❌ WAL write failed: %v", err)
 		return false
 	}
 
-	// Sync to disk for durability
-	if err := pq.walFile.Sync(); err != nil {
-		pq.mu.Unlock()
-		atomic.AddUint64(&pq.metrics.Failed, 1)
-		log.Printf("❌ WAL sync failed: %v", err)
-		return false
-	}
-
 	pq.processing[o.ID] = true
 	atomic.AddUint64(&pq.metrics.Written, 1)
 	pq.mu.Unlock()
@@ -229,14 +163,15 @@ func (pq *PersistentQueue) MarkComplete(orderID string) {
 		return // Not tracked or already completed
 	}
 
-	entry := walEntry{
+	entry := WALEntry{
 		Action:    "COMPLETE",
 		Order:     Order{ID: orderID},
 		Timestamp: time.Now(),
 	}
-	data, _ := json.Marshal(entry)
-	pq.walFile.Write(append(data, '\n'))
 	// Don't sync here for performance, accept potential duplicate on crash
+	if err := pq.wal.Append(entry, false); err != nil {
+		log.Printf("⚠️ WAL completion write failed for %s: %v", orderID, err)
+	}
 
 	delete(pq.processing, orderID)
 	atomic.AddUint64(&pq.metrics.Completed, 1)
@@ -271,16 +206,17 @@ func (pq *PersistentQueue) PendingNotional() float64 {
 	return pq.queue.PendingNotional()
 }
 
-// Close closes the persistent queue and WAL file.
+// Close closes the persistent queue and its WAL.
 func (pq *PersistentQueue) Close() {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
 	pq.closed = true
 	pq.queue.Close()
-	if pq.walFile != nil {
-		pq.walFile.Sync()
-		pq.walFile.Close()
+	if pq.wal != nil {
+		if err := pq.wal.Close(); err != nil {
+			log.Printf("⚠️ WAL close failed: %v", err)
+		}
 	}
 	log.Printf("✓ PersistentQueue closed: written=%d completed=%d",
 		atomic.LoadUint64(&pq.metrics.Written),
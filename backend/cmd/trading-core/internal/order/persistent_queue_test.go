@@ -0,0 +1,123 @@
+package order
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+func openWALTestDB(t *testing.T, path string) *db.Database {
+	t.Helper()
+	database, err := db.New(path)
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	return database
+}
+
+func openDBWAL(t *testing.T, database *db.Database) WAL {
+	t.Helper()
+	wal, err := NewDBWAL(database)
+	if err != nil {
+		t.Fatalf("NewDBWAL: %v", err)
+	}
+	return wal
+}
+
+func TestDBWALIsLeasedToOneInstance(t *testing.T) {
+	database := openWALTestDB(t, filepath.Join(t.TempDir(), "wal.db"))
+	defer database.Close()
+
+	first := openDBWAL(t, database)
+	if _, err := NewDBWAL(database); err == nil {
+		t.Fatal("a second instance must not open a leased WAL")
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	second := openDBWAL(t, database)
+	defer second.Close()
+
+	// A crashed holder's lease is taken over once it expires.
+	now := time.Now()
+	if ok, err := database.AcquireOrderWALLease(context.Background(), "crashed", now, now.Add(time.Minute)); err != nil || ok {
+		t.Fatalf("AcquireOrderWALLease while held = %v, %v; want refused", ok, err)
+	}
+	if ok, err := database.AcquireOrderWALLease(context.Background(), "next", now.Add(time.Hour), now.Add(2*time.Hour)); err != nil || !ok {
+		t.Fatalf("AcquireOrderWALLease after expiry = %v, %v; want taken over", ok, err)
+	}
+}
+
+func TestPersistentQueueRecoversFromDBWAL(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "wal.db")
+
+	// First process: three orders are accepted, one is executed, then it dies.
+	database := openWALTestDB(t, dbPath)
+	pq := NewPersistentQueueWithWAL(openDBWAL(t, database), 10)
+	for _, id := range []string{"o-1", "o-2", "o-3"} {
+		if !pq.Enqueue(Order{ID: id, Symbol: "BTCUSDT", Side: "BUY", Qty: 0.01, Price: 50000, CreatedAt: time.Now()}) {
+			t.Fatalf("enqueue %s failed", id)
+		}
+	}
+	pq.MarkComplete("o-2")
+	if m := pq.GetMetrics(); m.Written != 3 || m.Completed != 1 || m.Failed != 0 {
+		t.Fatalf("unexpected metrics before crash: %+v", m)
+	}
+	pq.Close()
+	database.Close()
+
+	// Second process on a fresh local disk: only the shared database remains.
+	database = openWALTestDB(t, dbPath)
+	defer database.Close()
+	recovered := NewPersistentQueueWithWAL(openDBWAL(t, database), 10)
+	if err := recovered.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if m := recovered.GetMetrics(); m.Recovered != 2 {
+		t.Fatalf("recovered %d orders, want 2", m.Recovered)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []string
+	go recovered.Drain(ctx, func(o Order) {
+		got = append(got, o.ID)
+		if len(got) == 2 {
+			cancel()
+		}
+	})
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		cancel()
+		t.Fatalf("recovered orders were not drained, got %v", got)
+	}
+	if len(got) != 2 || got[0] != "o-1" || got[1] != "o-3" {
+		t.Fatalf("drained %v, want [o-1 o-3]", got)
+	}
+
+	// The WAL was compacted to the pending entries, now both completed.
+	time.Sleep(50 * time.Millisecond)
+	recovered.Close()
+	entries, err := openDBWAL(t, database).Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	pending := map[string]bool{}
+	for _, e := range entries {
+		switch e.Action {
+		case "ENQUEUE":
+			pending[e.Order.ID] = true
+		case "COMPLETE":
+			delete(pending, e.Order.ID)
+		}
+	}
+	if len(pending) != 0 || len(entries) != 4 {
+		t.Fatalf("WAL after drain: %d entries, pending %v; want 2 enqueues + 2 completions", len(entries), pending)
+	}
+}
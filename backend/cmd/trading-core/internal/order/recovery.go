@@ -0,0 +1,97 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+// recordStore is the subset of db.Database the executor persists orders through.
+type recordStore interface {
+	WithTx(ctx context.Context, fn func(tx *db.Tx) error) error
+}
+
+// RecoveryEntry is one line of the recovery log: a record that reached the
+// exchange but could not be stored, kept so it can be reconciled later.
+type RecoveryEntry struct {
+	Time  time.Time `json:"time"`
+	Kind  string    `json:"kind"` // "order" (with its trade when filled)
+	Order *db.Order `json:"order,omitempty"`
+	Trade *db.Trade `json:"trade,omitempty"`
+	Error string    `json:"error"`
+}
+
+var recoveryMu sync.Mutex
+
+// appendRecovery appends entry as a JSON line to path.
+func appendRecovery(path string, entry RecoveryEntry) error {
+	if path == "" {
+		return fmt.Errorf("recovery log not configured")
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	recoveryMu.Lock()
+	defer recoveryMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Sync()
+}
+
+// ReadRecoveryLog returns the entries recorded at path (none if it does not exist).
+func ReadRecoveryLog(path string) ([]RecoveryEntry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []RecoveryEntry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var e RecoveryEntry
+		if err := dec.Decode(&e); err != nil {
+			return out, fmt.Errorf("decode recovery log: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// writeWithRetry runs write, retrying transient (locked/busy) DB errors with
+// doubling backoff up to e.WriteRetries times.
+func (e *Executor) writeWithRetry(ctx context.Context, what string, write func() error) error {
+	backoff := e.WriteBackoff
+	err := write()
+	for attempt := 1; err != nil && db.IsTransient(err) && attempt <= e.WriteRetries; attempt++ {
+		log.Printf("🔄 executor: %s write failed (%v); retry %d/%d in %v", what, err, attempt, e.WriteRetries, backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		err = write()
+	}
+	return err
+}
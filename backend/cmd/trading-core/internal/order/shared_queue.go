@@ -0,0 +1,287 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"trading-core/pkg/db"
+)
+
+// SharedQueueConfig tunes a SharedQueue.
+type SharedQueueConfig struct {
+	// Owner identifies this consumer in leases; defaults to host-pid-uuid.
+	Owner string
+	// VisibilityTimeout is how long a claimed order stays hidden from other
+	// consumers. An order not acked within it is redelivered.
+	VisibilityTimeout time.Duration
+	// PollInterval is the wait between claims when the queue is empty.
+	PollInterval time.Duration
+	// EnqueueRetries bounds retries of transient (locked/busy) enqueue writes.
+	EnqueueRetries int
+	// Concurrency is how many claimed orders Drain handles at once.
+	Concurrency int
+}
+
+// SharedQueueMetrics tracks shared queue statistics for this consumer.
+type SharedQueueMetrics struct {
+	Enqueued   uint64 // Orders inserted by this process
+	Duplicates uint64 // Enqueues ignored because the order ID was already queued
+	Claimed    uint64 // Orders leased by this consumer
+	Acked      uint64 // Orders acknowledged (removed) by this consumer
+	LostLeases uint64 // Acks or lease extensions that found the lease already taken over
+	Failed     uint64 // Store errors
+}
+
+// SharedQueue is an OrderQueue backed by an order_jobs store, safe to use from
+// several trading-core processes sharing it. Each order is leased to a single
+// consumer at a time, the lease is extended while its handler runs, and the
+// order is removed once the handler returns, so handlers should return only
+// after the order has been executed. A consumer that dies mid-order lets the
+// lease expire and another picks it up, so delivery is at-least-once and
+// handlers should be idempotent on Order.ID.
+type SharedQueue struct {
+	db      db.OrderJobStore
+	cfg     SharedQueueConfig
+	metrics SharedQueueMetrics
+	mu      sync.Mutex
+	closed  bool
+}
+
+// NewSharedQueue creates a shared queue on store.
+func NewSharedQueue(store db.OrderJobStore, cfg SharedQueueConfig) *SharedQueue {
+	if cfg.Owner == "" {
+		cfg.Owner = instanceOwner()
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 30 * time.Second
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 100 * time.Millisecond
+	}
+	if cfg.EnqueueRetries <= 0 {
+		cfg.EnqueueRetries = 5
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return &SharedQueue{db: store, cfg: cfg}
+}
+
+// Owner returns the lease owner ID of this consumer.
+func (q *SharedQueue) Owner() string {
+	return q.cfg.Owner
+}
+
+// Enqueue inserts the order into the shared store. Re-enqueueing an order ID
+// that is still queued is accepted but not duplicated.
+func (q *SharedQueue) Enqueue(o Order) bool {
+	q.mu.Lock()
+	closed := q.closed
+	q.mu.Unlock()
+	if closed {
+		log.Printf("❌ Shared order queue closed, order rejected: %s", o.ID)
+		return false
+	}
+
+	payload, err := json.Marshal(o)
+	if err != nil {
+		atomic.AddUint64(&q.metrics.Failed, 1)
+		log.Printf("❌ Shared queue marshal failed for %s: %v", o.ID, err)
+		return false
+	}
+	job := db.OrderJob{OrderID: o.ID, Payload: payload, Notional: o.Qty * o.Price}
+
+	backoff := q.cfg.PollInterval
+	inserted, err := q.db.EnqueueOrderJob(context.Background(), job)
+	for attempt := 1; err != nil && db.IsTransient(err) && attempt <= q.cfg.EnqueueRetries; attempt++ {
+		time.Sleep(backoff)
+		backoff *= 2
+		inserted, err = q.db.EnqueueOrderJob(context.Background(), job)
+	}
+	if err != nil {
+		atomic.AddUint64(&q.metrics.Failed, 1)
+		log.Printf("❌ Shared queue enqueue failed for %s: %v", o.ID, err)
+		return false
+	}
+	if inserted {
+		atomic.AddUint64(&q.metrics.Enqueued, 1)
+	} else {
+		atomic.AddUint64(&q.metrics.Duplicates, 1)
+	}
+	return true
+}
+
+// Drain claims orders and runs handler on up to Concurrency of them at once,
+// acking each after handler returns, until ctx is cancelled or the queue is
+// closed. It returns once the in-flight handlers have finished.
+func (q *SharedQueue) Drain(ctx context.Context, handler func(Order)) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	slots := make(chan struct{}, q.cfg.Concurrency)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		if ctx.Err() != nil || q.isClosed() {
+			return
+		}
+		job, err := q.db.ClaimOrderJob(ctx, q.cfg.Owner, time.Now(), q.cfg.VisibilityTimeout)
+		if err != nil && !db.IsTransient(err) && ctx.Err() == nil {
+			atomic.AddUint64(&q.metrics.Failed, 1)
+			log.Printf("⚠️ Shared queue claim failed: %v", err)
+		}
+		if job == nil {
+			<-slots
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.cfg.PollInterval):
+			}
+			continue
+		}
+		atomic.AddUint64(&q.metrics.Claimed, 1)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			q.process(job, handler)
+		}()
+	}
+}
+
+// process runs handler on a claimed job, keeping its lease alive meanwhile,
+// then acks it.
+func (q *SharedQueue) process(job *db.OrderJob, handler func(Order)) {
+	var o Order
+	if err := json.Unmarshal(job.Payload, &o); err != nil {
+		// Unparseable payloads would be redelivered forever; drop them.
+		log.Printf("❌ Shared queue dropping unreadable order %s: %v", job.OrderID, err)
+		q.ack(job.OrderID)
+		return
+	}
+	if job.Attempts > 1 {
+		log.Printf("🔄 Shared queue redelivering order %s (attempt %d)", o.ID, job.Attempts)
+	}
+
+	stop := make(chan struct{})
+	heartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeat)
+		q.keepLease(job.OrderID, stop)
+	}()
+	handler(o)
+	close(stop)
+	<-heartbeat
+	q.ack(job.OrderID)
+}
+
+// keepLease extends the lease on orderID every third of the visibility
+// timeout until stop is closed.
+func (q *SharedQueue) keepLease(orderID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(q.cfg.VisibilityTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ok, err := q.db.ExtendOrderJob(context.Background(), orderID, q.cfg.Owner, time.Now().Add(q.cfg.VisibilityTimeout))
+			switch {
+			case err != nil:
+				log.Printf("⚠️ Shared queue lease extension failed for %s: %v", orderID, err)
+			case !ok:
+				atomic.AddUint64(&q.metrics.LostLeases, 1)
+				log.Printf("⚠️ Shared queue lost the lease on %s while executing it; it may be processed again", orderID)
+				return
+			}
+		}
+	}
+}
+
+// ack removes a finished order, retrying transient errors; if the ack never
+// lands the lease expires and the order is redelivered.
+func (q *SharedQueue) ack(orderID string) {
+	backoff := q.cfg.PollInterval
+	ok, err := q.db.AckOrderJob(context.Background(), orderID, q.cfg.Owner)
+	for attempt := 1; err != nil && db.IsTransient(err) && attempt <= q.cfg.EnqueueRetries; attempt++ {
+		time.Sleep(backoff)
+		backoff *= 2
+		ok, err = q.db.AckOrderJob(context.Background(), orderID, q.cfg.Owner)
+	}
+	switch {
+	case err != nil:
+		atomic.AddUint64(&q.metrics.Failed, 1)
+		log.Printf("⚠️ Shared queue ack failed for %s: %v", orderID, err)
+	case !ok:
+		atomic.AddUint64(&q.metrics.LostLeases, 1)
+		log.Printf("⚠️ Shared queue lease on %s expired before ack; it may be processed again", orderID)
+	default:
+		atomic.AddUint64(&q.metrics.Acked, 1)
+	}
+}
+
+// GetMetrics returns this consumer's queue metrics.
+func (q *SharedQueue) GetMetrics() SharedQueueMetrics {
+	return SharedQueueMetrics{
+		Enqueued:   atomic.LoadUint64(&q.metrics.Enqueued),
+		Duplicates: atomic.LoadUint64(&q.metrics.Duplicates),
+		Claimed:    atomic.LoadUint64(&q.metrics.Claimed),
+		Acked:      atomic.LoadUint64(&q.metrics.Acked),
+		LostLeases: atomic.LoadUint64(&q.metrics.LostLeases),
+		Failed:     atomic.LoadUint64(&q.metrics.Failed),
+	}
+}
+
+// Len returns the number of orders in the shared store across all instances.
+func (q *SharedQueue) Len() int {
+	n, _, err := q.db.OrderJobStats(context.Background())
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// PendingNotional returns the total notional queued across all instances.
+func (q *SharedQueue) PendingNotional() float64 {
+	_, notional, err := q.db.OrderJobStats(context.Background())
+	if err != nil {
+		return 0
+	}
+	return notional
+}
+
+// Close stops accepting orders and ends Drain; queued orders stay in the store
+// for other instances. The database is owned by the caller.
+func (q *SharedQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	log.Printf("✓ SharedQueue closed: owner=%s claimed=%d acked=%d",
+		q.cfg.Owner,
+		atomic.LoadUint64(&q.metrics.Claimed),
+		atomic.LoadUint64(&q.metrics.Acked))
+}
+
+func (q *SharedQueue) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
+// instanceOwner identifies this process in leases: host-pid-uuid.
+func instanceOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
@@ -0,0 +1,184 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Two consumers on separate connections to one database stand in for two
+// trading-core processes; every order must be handled exactly once.
+func TestSharedQueueTwoConsumersProcessEachOrderOnce(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	dbA := openWALTestDB(t, dbPath)
+	defer dbA.Close()
+	dbB := openWALTestDB(t, dbPath)
+	defer dbB.Close()
+
+	cfg := SharedQueueConfig{VisibilityTimeout: 5 * time.Second, PollInterval: 5 * time.Millisecond, EnqueueRetries: 20}
+	cfgA, cfgB := cfg, cfg
+	cfgA.Owner, cfgB.Owner = "instance-a", "instance-b"
+	qa := NewSharedQueue(dbA, cfgA)
+	qb := NewSharedQueue(dbB, cfgB)
+
+	const total = 60
+	for i := 0; i < total; i++ {
+		q := qa
+		if i%2 == 1 {
+			q = qb
+		}
+		if !q.Enqueue(Order{ID: fmt.Sprintf("o-%03d", i), Symbol: "BTCUSDT", Side: "BUY", Qty: 0.01, Price: 50000}) {
+			t.Fatalf("enqueue o-%03d failed", i)
+		}
+	}
+	if n := qa.Len(); n != total {
+		t.Fatalf("Len = %d, want %d", n, total)
+	}
+	// A duplicate enqueue from the other instance must not create a second job.
+	qb.Enqueue(Order{ID: "o-000", Symbol: "BTCUSDT", Side: "BUY", Qty: 0.01, Price: 50000})
+	if n := qa.Len(); n != total {
+		t.Fatalf("Len after duplicate = %d, want %d", n, total)
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+		done = make(chan struct{})
+	)
+	handler := func(o Order) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[o.ID]++
+		if len(seen) == total {
+			select {
+			case <-done:
+			default:
+				close(done)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); qa.Drain(ctx, handler) }()
+	go func() { defer wg.Done(); qb.Drain(ctx, handler) }()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		cancel()
+		wg.Wait()
+		t.Fatalf("only %d/%d orders processed", len(seen), total)
+	}
+	// Let any in-flight claims finish so a double delivery would be observed.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("order %s processed %d times", id, n)
+		}
+	}
+	if n := qa.Len(); n != 0 {
+		t.Fatalf("Len after drain = %d, want 0", n)
+	}
+	if ma, mb := qa.GetMetrics(), qb.GetMetrics(); ma.Acked+mb.Acked != total || ma.LostLeases+mb.LostLeases != 0 {
+		t.Fatalf("metrics a=%+v b=%+v", ma, mb)
+	}
+}
+
+func TestSharedQueueRedeliversAfterVisibilityTimeout(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	database := openWALTestDB(t, dbPath)
+	defer database.Close()
+
+	lease := 50 * time.Millisecond
+	crashed := NewSharedQueue(database, SharedQueueConfig{Owner: "crashed", VisibilityTimeout: lease})
+	survivor := NewSharedQueue(database, SharedQueueConfig{Owner: "survivor", VisibilityTimeout: lease, PollInterval: 5 * time.Millisecond})
+
+	if !crashed.Enqueue(Order{ID: "o-1", Symbol: "BTCUSDT", Side: "BUY", Qty: 1, Price: 100}) {
+		t.Fatal("enqueue failed")
+	}
+	if got := crashed.PendingNotional(); got != 100 {
+		t.Fatalf("PendingNotional = %v, want 100", got)
+	}
+
+	// The first consumer claims the order and dies before acking it.
+	job, err := database.ClaimOrderJob(context.Background(), crashed.Owner(), time.Now(), lease)
+	if err != nil || job == nil {
+		t.Fatalf("claim: job=%v err=%v", job, err)
+	}
+	if again, _ := database.ClaimOrderJob(context.Background(), survivor.Owner(), time.Now(), lease); again != nil {
+		t.Fatal("leased order was visible to another consumer")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got []Order
+	survivor.Drain(ctx, func(o Order) {
+		got = append(got, o)
+		cancel()
+	})
+	if len(got) != 1 || got[0].ID != "o-1" {
+		t.Fatalf("redelivered %v, want o-1", got)
+	}
+
+	// The late ack from the crashed consumer must not remove anything.
+	if ok, err := database.AckOrderJob(context.Background(), "o-1", crashed.Owner()); err != nil || ok {
+		t.Fatalf("stale ack: ok=%v err=%v", ok, err)
+	}
+	if n := survivor.Len(); n != 0 {
+		t.Fatalf("Len = %d, want 0", n)
+	}
+}
+
+// A handler that outlives the visibility timeout keeps its lease, so the
+// order is not redelivered to another consumer while it is still executing.
+func TestSharedQueueExtendsLeaseWhileHandling(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shared.db")
+	database := openWALTestDB(t, dbPath)
+	defer database.Close()
+
+	lease := 60 * time.Millisecond
+	busy := NewSharedQueue(database, SharedQueueConfig{Owner: "busy", VisibilityTimeout: lease, PollInterval: 5 * time.Millisecond})
+	if !busy.Enqueue(Order{ID: "o-1", Symbol: "BTCUSDT", Side: "BUY", Qty: 1, Price: 100}) {
+		t.Fatal("enqueue failed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		busy.Drain(ctx, func(Order) {
+			close(started)
+			<-release
+			cancel()
+		})
+	}()
+	<-started
+
+	// Well past the original lease, no other consumer can claim the order.
+	time.Sleep(4 * lease)
+	if job, err := database.ClaimOrderJob(context.Background(), "other", time.Now(), lease); err != nil || job != nil {
+		t.Fatalf("order in flight was claimable: job=%v err=%v", job, err)
+	}
+	if n := busy.Len(); n != 1 {
+		t.Fatalf("Len while executing = %d, want 1 (acked too early)", n)
+	}
+
+	close(release)
+	<-drained
+	if n := busy.Len(); n != 0 {
+		t.Fatalf("Len after handler returned = %d, want 0", n)
+	}
+	if m := busy.GetMetrics(); m.Acked != 1 || m.LostLeases != 0 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
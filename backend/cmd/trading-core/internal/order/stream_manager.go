@@ -0,0 +1,274 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"trading-core/internal/events"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// ConnectionStream is a running user data stream. Streams that also
+// implement Alive() bool and report false (e.g. the listen key could not be
+// created or the socket dropped) are restarted on the next Sync.
+type ConnectionStream interface {
+	Start(ctx context.Context)
+	Stop()
+}
+
+// StreamConnection is a live connection that should have a user data stream.
+type StreamConnection struct {
+	UserID       string
+	ConnectionID string
+	ExchangeType string // binance-spot, binance-usdtfut, binance-coinfut
+	Gateway      exchange.Gateway
+}
+
+// UserStreamManager keeps one user data stream per active live connection in
+// multi-user mode, so fills on users' own accounts reach the DB and the bus
+// tagged with their user and connection. Each Sync starts streams for new
+// connections and stops those of connections that went away; at most
+// MaxStreams run at once.
+type UserStreamManager struct {
+	// List returns the connections that should be streamed.
+	List func(ctx context.Context) ([]StreamConnection, error)
+	// Factory builds a connection's stream (default: the Binance spot or
+	// futures stream for its exchange type, over the connection's gateway).
+	Factory func(conn StreamConnection) (ConnectionStream, error)
+
+	MaxStreams int           // 0 = unlimited
+	Interval   time.Duration // how often Start re-syncs (0 = 1m)
+
+	// Settings applied to the default Binance streams.
+	DB              *db.Database
+	Bus             *events.Bus
+	Testnet         bool
+	FillBatchWindow time.Duration
+	Fills           *FillLedger
+	ReportingAsset  string
+
+	mu       sync.Mutex
+	streams  map[string]ConnectionStream // connection ID -> running stream
+	starting map[string]bool             // streams whose Start has not returned
+	capped   bool                        // the last Sync hit MaxStreams
+}
+
+// NewUserStreamManager creates a manager that streams the connections list returns.
+func NewUserStreamManager(list func(ctx context.Context) ([]StreamConnection, error), maxStreams int) *UserStreamManager {
+	m := &UserStreamManager{
+		List:       list,
+		MaxStreams: maxStreams,
+		streams:    make(map[string]ConnectionStream),
+		starting:   make(map[string]bool),
+	}
+	m.Factory = m.binanceStream
+	return m
+}
+
+// Start syncs now and then every Interval until ctx is done, when all
+// streams are stopped.
+func (m *UserStreamManager) Start(ctx context.Context) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	m.Sync(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				m.StopAll()
+				return
+			case <-ticker.C:
+				m.Sync(ctx)
+			}
+		}
+	}()
+}
+
+// Sync starts streams for listed connections that have none (up to
+// MaxStreams) and stops streams whose connection is no longer listed.
+// Streams are started and stopped outside the lock, so a slow venue does
+// not hold up other calls.
+func (m *UserStreamManager) Sync(ctx context.Context) error {
+	conns, err := m.List(ctx)
+	if err != nil {
+		log.Printf("⚠️ User streams: list connections: %v", err)
+		return err
+	}
+
+	m.mu.Lock()
+	wanted := make(map[string]bool, len(conns))
+	for _, c := range conns {
+		wanted[c.ConnectionID] = true
+	}
+	var stop []ConnectionStream
+	for id, s := range m.streams {
+		if m.starting[id] {
+			continue
+		}
+		if !wanted[id] {
+			stop = append(stop, s)
+			delete(m.streams, id)
+			log.Printf("User stream for connection %s stopped", id)
+			continue
+		}
+		if a, ok := s.(interface{ Alive() bool }); ok && !a.Alive() {
+			stop = append(stop, s)
+			delete(m.streams, id)
+			log.Printf("⚠️ User stream for connection %s is down; restarting", id)
+		}
+	}
+
+	skipped := 0
+	start := make(map[string]ConnectionStream)
+	for _, c := range conns {
+		if _, running := m.streams[c.ConnectionID]; running {
+			continue
+		}
+		if m.MaxStreams > 0 && len(m.streams) >= m.MaxStreams {
+			skipped++
+			continue
+		}
+		s, err := m.Factory(c)
+		if err != nil {
+			log.Printf("⚠️ User stream for connection %s (user %s): %v", c.ConnectionID, c.UserID, err)
+			continue
+		}
+		m.streams[c.ConnectionID] = s
+		m.starting[c.ConnectionID] = true
+		start[c.ConnectionID] = s
+		log.Printf("User stream for connection %s (user %s, %s) starting", c.ConnectionID, c.UserID, c.ExchangeType)
+	}
+	if skipped > 0 && !m.capped {
+		log.Printf("⚠️ User streams: %d connection(s) not streamed, limit of %d reached", skipped, m.MaxStreams)
+	}
+	m.capped = skipped > 0
+	m.mu.Unlock()
+
+	for _, s := range stop {
+		s.Stop()
+	}
+	var wg sync.WaitGroup
+	for id, s := range start {
+		wg.Add(1)
+		go func(id string, s ConnectionStream) {
+			defer wg.Done()
+			s.Start(ctx)
+			m.mu.Lock()
+			delete(m.starting, id)
+			removed := m.streams[id] != s // StopAll ran while it started
+			m.mu.Unlock()
+			if removed {
+				s.Stop()
+			}
+		}(id, s)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Streams returns the IDs of the connections being streamed.
+func (m *UserStreamManager) Streams() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, 0, len(m.streams))
+	for id := range m.streams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// StopAll stops every running stream. Streams still starting are stopped by
+// the Sync starting them once their Start returns.
+func (m *UserStreamManager) StopAll() {
+	m.mu.Lock()
+	var stop []ConnectionStream
+	for id, s := range m.streams {
+		if !m.starting[id] {
+			stop = append(stop, s)
+		}
+		delete(m.streams, id)
+	}
+	m.mu.Unlock()
+	for _, s := range stop {
+		s.Stop()
+	}
+}
+
+// binanceStream builds the spot or futures stream for a connection from the
+// client behind its gateway.
+func (m *UserStreamManager) binanceStream(c StreamConnection) (ConnectionStream, error) {
+	switch c.ExchangeType {
+	case "binance-spot":
+		client, ok := c.Gateway.(spotStreamClient)
+		if !ok {
+			return nil, fmt.Errorf("gateway %T has no user data stream", c.Gateway)
+		}
+		s := NewSpotUserStream(client, m.DB, m.Bus, m.Testnet)
+		s.SetFillBatchWindow(m.FillBatchWindow)
+		s.Fills = m.Fills
+		s.UserID, s.ConnectionID = c.UserID, c.ConnectionID
+		return s, nil
+	case "binance-usdtfut", "binance-coinfut":
+		client, ok := c.Gateway.(futClient)
+		if !ok {
+			return nil, fmt.Errorf("gateway %T has no user data stream", c.Gateway)
+		}
+		s := NewFuturesUserStream(client, m.DB, m.Bus, m.Testnet, c.ExchangeType == "binance-coinfut")
+		s.ReportingAsset = m.ReportingAsset
+		s.SetFillBatchWindow(m.FillBatchWindow)
+		s.Fills = m.Fills
+		s.UserID, s.ConnectionID = c.UserID, c.ConnectionID
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported exchange type: %s", c.ExchangeType)
+	}
+}
+
+// streamFillEvent is the EventOrderFilled payload for a user-stream fill. Fills
+// of a per-connection stream, or of an order this process stored, are
+// published as an Order carrying the owner, strategy, position side and trade
+// ID, so the fill handler books them to that user, connection, strategy and leg.
+func streamFillEvent(ctx context.Context, database *db.Database, userID, connectionID, orderID, tradeID, symbol, side string, qty, price float64) any {
+	var strategyID, positionSide string
+	if database != nil && orderID != "" {
+		var found bool
+		var err error
+		strategyID, positionSide, found, err = database.GetOrderOrigin(ctx, orderID)
+		if err != nil {
+			log.Printf("user stream: look up order %s: %v", orderID, err)
+		}
+		if !found {
+			strategyID, positionSide = "", ""
+		}
+	}
+	if userID != "" || connectionID != "" || strategyID != "" || positionSide != "" || tradeID != "" {
+		return Order{
+			ID: orderID, TradeID: tradeID, Symbol: symbol, Side: side, Qty: qty, Price: price,
+			UserID: userID, ConnectionID: connectionID,
+			StrategyInstanceID: strategyID, PositionSide: positionSide,
+		}
+	}
+	return struct {
+		ID     string
+		Symbol string
+		Side   string
+		Qty    float64
+		Price  float64
+	}{
+		ID:     orderID,
+		Symbol: symbol,
+		Side:   side,
+		Qty:    qty,
+		Price:  price,
+	}
+}
@@ -23,11 +23,20 @@ type Order struct {
 	PriceProtect    bool    // price protection
 	ActivationPrice float64 // trailing stop
 	CallbackRate    float64 // trailing stop callback %
+	RefPrice        float64 // price the order was valued at on submission (slippage reference)
+	Routing         string  // "" = submit as-is; RoutingMakerFirst = post at the touch, then cross
 	Status          string  // NEW, SUBMITTED, ACCEPTED, PARTIALLY_FILLED, FILLED, CANCELLED, REJECTED, EXPIRED
 	CreatedAt       time.Time
 	// Multi-user routing (Phase 4)
 	UserID       string // Owner of this order
 	ConnectionID string // Exchange connection to route to
+	Paper        bool   // filled by the simulator for a paper connection in production mode
+	TradeID      string // exchange trade ID of a single-trade fill event, when known
+	// Trade journal (manual orders)
+	Note string
+	Tags []string
+	// W3C trace context of the request that created the order, carried across the queue
+	Trace map[string]string
 }
 
 // IsFullyFilled checks if order is fully filled
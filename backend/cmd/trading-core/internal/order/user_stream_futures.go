@@ -8,11 +8,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
 	"trading-core/internal/events"
 	"trading-core/pkg/db"
+	exfutcoin "trading-core/pkg/exchanges/binance/futures_coin"
+	"trading-core/pkg/ids"
 )
 
 // FuturesUserStream listens to Binance Futures user data stream (USDT-M or COIN-M).
@@ -23,11 +24,31 @@ type FuturesUserStream struct {
 	Testnet  bool
 	stopChan chan struct{}
 	basePath string // "/ws" for usdt, "/dstream" for coin
+
+	// ReportingAsset is the currency trade fees are stored in. COIN-M fees settle
+	// in the base coin and are converted at the fill price (default USDT).
+	ReportingAsset string
+
+	fills *fillBatcher // see SetFillBatchWindow
+
+	// Fills skips trades already booked from an order response or seen
+	// before (optional; shared with the Executor).
+	Fills *FillLedger
+
+	// UserID and ConnectionID tag fills of a per-connection stream with
+	// their owner; both are empty for the global account.
+	UserID       string
+	ConnectionID string
+
+	conn      *websocket.Conn
+	listenKey string
+	done      chan struct{} // closed when the reader exits
 }
 
 type futClient interface {
 	CreateListenKey(ctx context.Context) (string, error)
 	KeepAliveListenKey(ctx context.Context, listenKey string) error
+	CloseListenKey(ctx context.Context, listenKey string) error
 }
 
 func NewFuturesUserStream(client futClient, database *db.Database, bus *events.Bus, testnet bool, coinMargin bool) *FuturesUserStream {
@@ -42,6 +63,7 @@ func NewFuturesUserStream(client futClient, database *db.Database, bus *events.B
 		Testnet:  testnet,
 		stopChan: make(chan struct{}),
 		basePath: base,
+		fills:    newFillBatcher(database, 0, "futures user stream"),
 	}
 }
 
@@ -64,6 +86,7 @@ func (s *FuturesUserStream) Start(ctx context.Context) {
 		log.Printf("futures user stream: ws dial error: %v", err)
 		return
 	}
+	s.conn, s.listenKey = conn, listenKey
 	log.Printf("futures user stream started (testnet=%v, path=%s)", s.Testnet, s.basePath)
 
 	// Keep alive ticker
@@ -85,7 +108,9 @@ func (s *FuturesUserStream) Start(ctx context.Context) {
 	}()
 
 	// Reader
+	s.done = make(chan struct{})
 	go func() {
+		defer close(s.done)
 		defer conn.Close()
 		for {
 			_, msg, err := conn.ReadMessage()
@@ -98,8 +123,37 @@ func (s *FuturesUserStream) Start(ctx context.Context) {
 	}()
 }
 
+// Alive reports whether the stream is connected and reading.
+func (s *FuturesUserStream) Alive() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
 func (s *FuturesUserStream) Stop() {
 	close(s.stopChan)
+	if s.conn != nil {
+		_ = s.conn.Close()
+		// Release the listen key; it would otherwise linger until it expires.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.Client.CloseListenKey(ctx, s.listenKey); err != nil {
+			log.Printf("futures user stream: close listen key error: %v", err)
+		}
+		cancel()
+	}
+	s.fills.flushAll(context.Background())
+}
+
+// SetFillBatchWindow coalesces fills of the same order arriving within window
+// into one DB write (0, the default, writes every fill).
+func (s *FuturesUserStream) SetFillBatchWindow(window time.Duration) {
+	s.fills.setWindow(window)
 }
 
 func (s *FuturesUserStream) buildStreamURL(listenKey string) string {
@@ -161,6 +215,7 @@ func (s *FuturesUserStream) handleOrderTradeUpdate(ctx context.Context, msg []by
 			CumQuote      string          `json:"Z"`
 			Commission    string          `json:"n"`
 			CommissionAst string          `json:"N"`
+			TradeID       int64           `json:"t"`
 			TradeTime     json.RawMessage `json:"T"`
 			IsMaker       bool            `json:"m"`
 		} `json:"o"`
@@ -186,40 +241,38 @@ func (s *FuturesUserStream) handleOrderTradeUpdate(ctx context.Context, msg []by
 		fillPrice = cumQuote / cumQty
 	}
 
-	// Update order fill
-	if err := s.DB.UpdateOrderFill(ctx, wrap.Data.ClientOrderID, status, cumQty, fillPrice); err != nil {
-		log.Printf("futures user stream: update order fill error: %v", err)
+	if !s.Fills.claimStream(wrap.Data.Symbol, tradeIDString(wrap.Data.TradeID), wrap.Data.ClientOrderID) {
+		log.Printf("futures user stream: trade %d of order %s already booked; skipping", wrap.Data.TradeID, wrap.Data.ClientOrderID)
+		return
+	}
+
+	// COIN-M commissions settle in the base coin; convert so fees share one currency.
+	fee := toFloat(wrap.Data.Commission)
+	if s.basePath == "/dstream" {
+		converted, err := exfutcoin.ToReporting(fee, wrap.Data.CommissionAst, s.ReportingAsset, fillPrice)
+		if err != nil {
+			log.Printf("futures user stream: fee conversion error: %v", err)
+		} else {
+			fee = converted
+		}
 	}
 
-	// Insert trade
-	trade := db.Trade{
-		ID:        uuid.NewString(),
+	// Persist the order fill and trade row (coalesced per order within FillBatchWindow)
+	s.fills.add(ctx, status, cumQty, db.Trade{
+		ID:        ids.New(),
 		OrderID:   wrap.Data.ClientOrderID,
 		Symbol:    wrap.Data.Symbol,
 		Side:      wrap.Data.Side,
 		Price:     fillPrice,
 		Qty:       lastQty,
-		Fee:       toFloat(wrap.Data.Commission),
+		Fee:       fee,
+		FeeAsset:  wrap.Data.CommissionAst,
+		UserID:    s.UserID,
 		CreatedAt: time.Now(),
-	}
-	if err := s.DB.CreateTrade(ctx, trade); err != nil {
-		log.Printf("futures user stream: create trade error: %v", err)
-	}
+	})
 
 	// Publish filled event
 	if s.Bus != nil && status == "FILLED" {
-		s.Bus.Publish(events.EventOrderFilled, struct {
-			ID     string
-			Symbol string
-			Side   string
-			Qty    float64
-			Price  float64
-		}{
-			ID:     wrap.Data.ClientOrderID,
-			Symbol: wrap.Data.Symbol,
-			Side:   wrap.Data.Side,
-			Qty:    lastQty,
-			Price:  fillPrice,
-		})
+		s.Bus.Publish(events.EventOrderFilled, streamFillEvent(ctx, s.DB, s.UserID, s.ConnectionID, wrap.Data.ClientOrderID, tradeIDString(wrap.Data.TradeID), wrap.Data.Symbol, wrap.Data.Side, lastQty, fillPrice))
 	}
 }
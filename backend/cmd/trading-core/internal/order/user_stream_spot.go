@@ -9,30 +9,55 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
 	"trading-core/internal/events"
 	"trading-core/pkg/db"
 	exspot "trading-core/pkg/exchanges/binance/spot"
+	"trading-core/pkg/ids"
 )
 
 // SpotUserStream listens to Binance Spot user data stream for real fills.
 type SpotUserStream struct {
-	Client   *exspot.Client
+	Client   spotStreamClient
 	DB       *db.Database
 	Bus      *events.Bus
 	Testnet  bool
 	stopChan chan struct{}
+
+	fills *fillBatcher // see SetFillBatchWindow
+
+	// Fills skips trades already booked from an order response or seen
+	// before (optional; shared with the Executor).
+	Fills *FillLedger
+
+	// UserID and ConnectionID tag fills of a per-connection stream with
+	// their owner; both are empty for the global account.
+	UserID       string
+	ConnectionID string
+
+	conn      *websocket.Conn
+	listenKey string
+	done      chan struct{} // closed when the reader exits
+}
+
+// spotStreamClient is the listen-key API of the spot client.
+type spotStreamClient interface {
+	CreateListenKey(ctx context.Context) (string, error)
+	KeepAliveListenKey(ctx context.Context, listenKey string) error
+	CloseListenKey(ctx context.Context, listenKey string) error
 }
 
-func NewSpotUserStream(client *exspot.Client, database *db.Database, bus *events.Bus, testnet bool) *SpotUserStream {
+var _ spotStreamClient = (*exspot.Client)(nil)
+
+func NewSpotUserStream(client spotStreamClient, database *db.Database, bus *events.Bus, testnet bool) *SpotUserStream {
 	return &SpotUserStream{
 		Client:   client,
 		DB:       database,
 		Bus:      bus,
 		Testnet:  testnet,
 		stopChan: make(chan struct{}),
+		fills:    newFillBatcher(database, 0, "spot user stream"),
 	}
 }
 
@@ -55,6 +80,7 @@ func (s *SpotUserStream) Start(ctx context.Context) {
 		log.Printf("spot user stream: ws dial error: %v", err)
 		return
 	}
+	s.conn, s.listenKey = conn, listenKey
 	log.Printf("spot user stream started (testnet=%v)", s.Testnet)
 
 	// Keep alive ticker
@@ -76,7 +102,9 @@ func (s *SpotUserStream) Start(ctx context.Context) {
 	}()
 
 	// Reader
+	s.done = make(chan struct{})
 	go func() {
+		defer close(s.done)
 		defer conn.Close()
 		for {
 			_, msg, err := conn.ReadMessage()
@@ -89,8 +117,37 @@ func (s *SpotUserStream) Start(ctx context.Context) {
 	}()
 }
 
+// Alive reports whether the stream is connected and reading.
+func (s *SpotUserStream) Alive() bool {
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
 func (s *SpotUserStream) Stop() {
 	close(s.stopChan)
+	if s.conn != nil {
+		_ = s.conn.Close()
+		// Release the listen key; it would otherwise linger until it expires.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.Client.CloseListenKey(ctx, s.listenKey); err != nil {
+			log.Printf("spot user stream: close listen key error: %v", err)
+		}
+		cancel()
+	}
+	s.fills.flushAll(context.Background())
+}
+
+// SetFillBatchWindow coalesces fills of the same order arriving within window
+// into one DB write (0, the default, writes every fill).
+func (s *SpotUserStream) SetFillBatchWindow(window time.Duration) {
+	s.fills.setWindow(window)
 }
 
 func buildStreamURL(testnet bool, listenKey string) string {
@@ -146,6 +203,7 @@ func (s *SpotUserStream) handleExecutionReport(ctx context.Context, msg []byte)
 		CumulativeQuote string `json:"Z"`
 		Commission      string `json:"n"`
 		CommissionAsset string `json:"N"`
+		TradeID         int64  `json:"t"`
 		TradeTime       int64  `json:"T"`
 		IsMaker         bool   `json:"m"`
 	}
@@ -165,46 +223,42 @@ func (s *SpotUserStream) handleExecutionReport(ctx context.Context, msg []byte)
 	cumQuote := toFloat(rep.CumulativeQuote)
 	status := strings.ToUpper(rep.Status)
 
-	// Update order status/fill in DB
 	fillPrice := lastPrice
 	if fillPrice == 0 && cumQty > 0 {
 		fillPrice = cumQuote / cumQty
 	}
-	if err := s.DB.UpdateOrderFill(ctx, rep.ClientOrderID, status, cumQty, fillPrice); err != nil {
-		log.Printf("spot user stream: update order fill error: %v", err)
+
+	if !s.Fills.claimStream(rep.Symbol, tradeIDString(rep.TradeID), rep.ClientOrderID) {
+		log.Printf("spot user stream: trade %d of order %s already booked; skipping", rep.TradeID, rep.ClientOrderID)
+		return
 	}
 
-	// Insert trade row
-	trade := db.Trade{
-		ID:        uuid.NewString(),
+	// Persist the order fill and trade row (coalesced per order within FillBatchWindow)
+	s.fills.add(ctx, status, cumQty, db.Trade{
+		ID:        ids.New(),
 		OrderID:   rep.ClientOrderID,
 		Symbol:    rep.Symbol,
 		Side:      rep.Side,
 		Price:     fillPrice,
 		Qty:       lastQty,
 		Fee:       toFloat(rep.Commission),
+		FeeAsset:  rep.CommissionAsset,
+		UserID:    s.UserID,
 		CreatedAt: time.Now(),
-	}
-	if err := s.DB.CreateTrade(ctx, trade); err != nil {
-		log.Printf("spot user stream: create trade error: %v", err)
-	}
+	})
 
 	// Publish filled event with updated info
 	if s.Bus != nil && status == "FILLED" {
-		s.Bus.Publish(events.EventOrderFilled, struct {
-			ID     string
-			Symbol string
-			Side   string
-			Qty    float64
-			Price  float64
-		}{
-			ID:     rep.ClientOrderID,
-			Symbol: rep.Symbol,
-			Side:   rep.Side,
-			Qty:    lastQty,
-			Price:  lastPrice,
-		})
+		s.Bus.Publish(events.EventOrderFilled, streamFillEvent(ctx, s.DB, s.UserID, s.ConnectionID, rep.ClientOrderID, tradeIDString(rep.TradeID), rep.Symbol, rep.Side, lastQty, lastPrice))
+	}
+}
+
+// tradeIDString formats an exchange trade ID; 0 (absent) becomes "".
+func tradeIDString(id int64) string {
+	if id <= 0 {
+		return ""
 	}
+	return strconv.FormatInt(id, 10)
 }
 
 func toFloat(v string) float64 {
@@ -0,0 +1,387 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"trading-core/internal/events"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// countingFillStore records the fill writes a user stream makes.
+type countingFillStore struct {
+	mu      sync.Mutex
+	updates []db.Order
+	trades  []db.Trade
+}
+
+func (s *countingFillStore) UpdateOrderFill(ctx context.Context, id, status string, filledQty, price float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updates = append(s.updates, db.Order{ID: id, Status: status, FilledQty: filledQty, Price: price})
+	return nil
+}
+
+func (s *countingFillStore) CreateTrade(ctx context.Context, tr db.Trade) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trades = append(s.trades, tr)
+	return nil
+}
+
+func (s *countingFillStore) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.updates), len(s.trades)
+}
+
+func executionReport(orderID, status string, lastQty, lastPrice, cumQty, fee float64) []byte {
+	return []byte(fmt.Sprintf(`{"e":"executionReport","s":"BTCUSDT","S":"BUY","o":"LIMIT","x":"TRADE","X":%q,"c":%q,"l":"%g","L":"%g","z":"%g","n":"%g","N":"USDT"}`,
+		status, orderID, lastQty, lastPrice, cumQty, fee))
+}
+
+func TestUserStreamCoalescesPartialFills(t *testing.T) {
+	store := &countingFillStore{}
+	s := &SpotUserStream{stopChan: make(chan struct{})}
+	s.fills = newFillBatcher(store, 50*time.Millisecond, "spot user stream")
+	ctx := context.Background()
+
+	// 20 partials of 0.01 at rising prices, the last one completing the order.
+	const n = 20
+	var cum, notional, fees float64
+	for i := 0; i < n; i++ {
+		price := 100 + float64(i)
+		cum += 0.01
+		notional += 0.01 * price
+		fees += 0.001
+		status := "PARTIALLY_FILLED"
+		if i == n-1 {
+			status = "FILLED"
+		}
+		s.handleMessage(ctx, executionReport("o1", status, 0.01, price, cum, 0.001))
+	}
+
+	updates, trades := store.counts()
+	if updates >= n || trades >= n {
+		t.Fatalf("expected fills to be coalesced, got %d order updates and %d trades for %d partials", updates, trades, n)
+	}
+	last := store.updates[len(store.updates)-1]
+	if last.Status != "FILLED" || math.Abs(last.FilledQty-cum) > 1e-9 {
+		t.Fatalf("final order update = %s %.8f, want FILLED %.8f", last.Status, last.FilledQty, cum)
+	}
+	var qty, value, fee float64
+	for _, tr := range store.trades {
+		qty += tr.Qty
+		value += tr.Qty * tr.Price
+		fee += tr.Fee
+	}
+	if math.Abs(qty-cum) > 1e-9 || math.Abs(value-notional) > 1e-9 || math.Abs(fee-fees) > 1e-9 {
+		t.Fatalf("trade totals qty=%.8f value=%.8f fee=%.8f, want %.8f %.8f %.8f", qty, value, fee, cum, notional, fees)
+	}
+
+	// Partials that never complete are written once the window elapses.
+	for i := 1; i <= 5; i++ {
+		s.handleMessage(ctx, executionReport("o2", "PARTIALLY_FILLED", 0.5, 200, 0.5*float64(i), 0.01))
+	}
+	if u, _ := store.counts(); u != updates {
+		t.Fatalf("open partials written before the window elapsed")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if u, tr := store.counts(); u == updates+1 && tr == trades+1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pending partials were not flushed after the batch window")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if got := store.updates[len(store.updates)-1]; got.FilledQty != 2.5 || got.Status != "PARTIALLY_FILLED" {
+		t.Fatalf("flushed order update = %s %.8f, want PARTIALLY_FILLED 2.5", got.Status, got.FilledQty)
+	}
+	if got := store.trades[len(store.trades)-1]; got.Qty != 2.5 || math.Abs(got.Fee-0.05) > 1e-9 {
+		t.Fatalf("flushed trade qty=%.8f fee=%.8f, want 2.5 0.05", got.Qty, got.Fee)
+	}
+}
+
+// tradeAckGateway fills every order immediately and lists the trade in its ack.
+type tradeAckGateway struct{}
+
+func (tradeAckGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{ExchangeOrderID: "9", Status: exchange.StatusFilled, ClientID: req.ClientID,
+		Fills: []exchange.Fill{{ExchangeOrderID: "9", TradeID: "77", Symbol: req.Symbol, Side: req.Side, Qty: req.Qty, Price: req.Price}}}, nil
+}
+
+func (tradeAckGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func TestFillReportedByResponseAndStreamIsBookedOnce(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	bus := events.NewBus()
+	filled, unsub := bus.Subscribe(events.EventOrderFilled, 4)
+	defer unsub()
+
+	ledger := NewFillLedger(FillSourceFirst)
+	exec := NewExecutor(database, bus, tradeAckGateway{}, "test", false)
+	exec.SetFillLedger(ledger)
+	store := &countingFillStore{}
+	stream := &SpotUserStream{Bus: bus, Fills: ledger, stopChan: make(chan struct{})}
+	stream.fills = newFillBatcher(store, 0, "spot user stream")
+	ctx := context.Background()
+
+	// The synchronous response books the fill...
+	if err := exec.Handle(ctx, Order{ID: "d1", Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 100, Qty: 0.5}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	// ...and the user stream then reports the same trade, twice (reconnect replay).
+	report := []byte(`{"e":"executionReport","s":"BTCUSDT","S":"BUY","o":"LIMIT","x":"TRADE","X":"FILLED","c":"d1","t":77,"l":"0.5","L":"100","z":"0.5","n":"0","N":"USDT"}`)
+	stream.handleMessage(ctx, report)
+	stream.handleMessage(ctx, report)
+
+	var trades int
+	if err := database.DB.QueryRow(`SELECT COUNT(1) FROM trades WHERE order_id = 'd1'`).Scan(&trades); err != nil {
+		t.Fatalf("count trades: %v", err)
+	}
+	if _, streamTrades := store.counts(); trades != 1 || streamTrades != 0 {
+		t.Fatalf("booked %d trades from the response and %d from the stream, want 1 and 0", trades, streamTrades)
+	}
+	select {
+	case <-filled:
+	default:
+		t.Fatal("expected one filled event")
+	}
+	select {
+	case ev := <-filled:
+		t.Fatalf("fill published twice: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A trade the response never saw is still booked from the stream.
+	stream.handleMessage(ctx, []byte(`{"e":"executionReport","s":"BTCUSDT","S":"SELL","o":"MARKET","x":"TRADE","X":"FILLED","c":"d2","t":78,"l":"0.5","L":"101","z":"0.5","n":"0","N":"USDT"}`))
+	if _, streamTrades := store.counts(); streamTrades != 1 {
+		t.Fatalf("unrelated stream trade booked %d times, want 1", streamTrades)
+	}
+}
+
+func TestStreamFillCarriesStoredOrderOrigin(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if err := database.CreateOrder(ctx, db.Order{
+		ID: "h1", StrategyInstanceID: "s1", Symbol: "BTCUSDT", Side: "SELL", Qty: 1, Status: "NEW", PositionSide: "SHORT",
+	}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	bus := events.NewBus()
+	filled, unsub := bus.Subscribe(events.EventOrderFilled, 1)
+	defer unsub()
+	stream := &SpotUserStream{Bus: bus, DB: database, stopChan: make(chan struct{})}
+	stream.fills = newFillBatcher(&countingFillStore{}, 0, "spot user stream")
+
+	stream.handleMessage(ctx, []byte(`{"e":"executionReport","s":"BTCUSDT","S":"SELL","o":"MARKET","x":"TRADE","X":"FILLED","c":"h1","t":5,"l":"1","L":"100","z":"1","n":"0","N":"USDT"}`))
+	select {
+	case ev := <-filled:
+		o, ok := ev.(Order)
+		if !ok || o.StrategyInstanceID != "s1" || o.PositionSide != "SHORT" {
+			t.Fatalf("fill event = %+v, want strategy s1 on the SHORT leg", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a filled event")
+	}
+}
+
+// fakeConnStream is a ConnectionStream that records Start and Stop.
+type fakeConnStream struct {
+	started, stopped bool
+}
+
+func (s *fakeConnStream) Start(ctx context.Context) { s.started = true }
+func (s *fakeConnStream) Stop()                     { s.stopped = true }
+
+func TestUserStreamManagerStreamsEachConnection(t *testing.T) {
+	conns := []StreamConnection{
+		{UserID: "alice", ConnectionID: "c1", ExchangeType: "binance-spot"},
+		{UserID: "bob", ConnectionID: "c2", ExchangeType: "binance-usdtfut"},
+	}
+	m := NewUserStreamManager(func(ctx context.Context) ([]StreamConnection, error) {
+		return conns, nil
+	}, 2)
+	fakes := map[string]*fakeConnStream{}
+	m.Factory = func(c StreamConnection) (ConnectionStream, error) {
+		s := &fakeConnStream{}
+		fakes[c.ConnectionID] = s
+		return s, nil
+	}
+	ctx := context.Background()
+
+	if err := m.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if got := m.Streams(); len(got) != 2 || got[0] != "c1" || got[1] != "c2" {
+		t.Fatalf("streams = %v, want [c1 c2]", got)
+	}
+	if !fakes["c1"].started || !fakes["c2"].started {
+		t.Fatalf("streams were not started")
+	}
+
+	// A third connection is over the limit; dropping c1 stops its stream and
+	// frees the slot.
+	conns = []StreamConnection{conns[1], {UserID: "carol", ConnectionID: "c3", ExchangeType: "binance-spot"}}
+	conns = append(conns, StreamConnection{UserID: "dave", ConnectionID: "c4", ExchangeType: "binance-spot"})
+	m.Sync(ctx)
+	if !fakes["c1"].stopped {
+		t.Fatalf("stream of removed connection c1 still running")
+	}
+	if got := m.Streams(); len(got) != 2 || got[0] != "c2" || got[1] != "c3" {
+		t.Fatalf("streams = %v, want [c2 c3] (limit 2)", got)
+	}
+	if _, ok := fakes["c4"]; ok {
+		t.Fatalf("stream for c4 started beyond the limit")
+	}
+
+	// Fills on each connection's stream are attributed to its owner.
+	bus := events.NewBus()
+	filled, unsub := bus.Subscribe(events.EventOrderFilled, 4)
+	defer unsub()
+	for _, c := range []StreamConnection{{UserID: "alice", ConnectionID: "c1"}, {UserID: "bob", ConnectionID: "c2"}} {
+		store := &countingFillStore{}
+		s := &SpotUserStream{Bus: bus, UserID: c.UserID, ConnectionID: c.ConnectionID, stopChan: make(chan struct{})}
+		s.fills = newFillBatcher(store, 0, "spot user stream")
+		s.handleMessage(ctx, executionReport("o-"+c.ConnectionID, "FILLED", 1, 100, 1, 0.1))
+
+		if len(store.trades) != 1 || store.trades[0].UserID != c.UserID {
+			t.Fatalf("trade for %s = %+v, want user %s", c.ConnectionID, store.trades, c.UserID)
+		}
+		select {
+		case ev := <-filled:
+			o, ok := ev.(Order)
+			if !ok || o.UserID != c.UserID || o.ConnectionID != c.ConnectionID {
+				t.Fatalf("fill event = %+v, want user %s connection %s", ev, c.UserID, c.ConnectionID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no fill event for %s", c.ConnectionID)
+		}
+	}
+}
+
+// blockingConnStream blocks in Start until release is closed.
+type blockingConnStream struct {
+	fakeConnStream
+	release chan struct{}
+}
+
+func (s *blockingConnStream) Start(ctx context.Context) {
+	<-s.release
+	s.fakeConnStream.Start(ctx)
+}
+
+func TestUserStreamManagerStartsOutsideTheLock(t *testing.T) {
+	conns := []StreamConnection{
+		{UserID: "alice", ConnectionID: "slow", ExchangeType: "binance-spot"},
+		{UserID: "bob", ConnectionID: "fast", ExchangeType: "binance-spot"},
+	}
+	m := NewUserStreamManager(func(ctx context.Context) ([]StreamConnection, error) {
+		return conns, nil
+	}, 0)
+	slow := &blockingConnStream{release: make(chan struct{})}
+	fast := &fakeConnStream{}
+	m.Factory = func(c StreamConnection) (ConnectionStream, error) {
+		if c.ConnectionID == "slow" {
+			return slow, nil
+		}
+		return fast, nil
+	}
+
+	synced := make(chan struct{})
+	go func() {
+		m.Sync(context.Background())
+		close(synced)
+	}()
+	// The slow venue's Start holds up neither other calls nor other streams.
+	deadline := time.Now().Add(time.Second)
+	for len(m.Streams()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("streams = %v while one stream is starting", m.Streams())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	m.StopAll()
+	close(slow.release)
+	<-synced
+	if !fast.started || !fast.stopped {
+		t.Fatalf("fast stream = %+v, want started and stopped", fast)
+	}
+	// StopAll ran while slow was starting: Sync stops it once Start returns.
+	if !slow.started || !slow.stopped {
+		t.Fatalf("slow stream = %+v, want started then stopped", slow.fakeConnStream)
+	}
+}
+
+// listenKeyClient records the listen keys it creates and closes.
+type listenKeyClient struct {
+	closed []string
+}
+
+func (c *listenKeyClient) CreateListenKey(ctx context.Context) (string, error) { return "lk1", nil }
+func (c *listenKeyClient) KeepAliveListenKey(ctx context.Context, listenKey string) error {
+	return nil
+}
+func (c *listenKeyClient) CloseListenKey(ctx context.Context, listenKey string) error {
+	c.closed = append(c.closed, listenKey)
+	return nil
+}
+
+func TestFuturesUserStreamStopClosesListenKey(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := &listenKeyClient{}
+	s := NewFuturesUserStream(client, &db.Database{}, nil, false, false)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	s.conn, s.listenKey = conn, "lk1"
+	s.Stop()
+	if len(client.closed) != 1 || client.closed[0] != "lk1" {
+		t.Fatalf("closed listen keys = %v, want [lk1]", client.closed)
+	}
+}
@@ -0,0 +1,276 @@
+package order
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+// WAL is the durable log behind PersistentQueue. The file implementation
+// suits a single host; the DB implementation survives ephemeral local disks.
+type WAL interface {
+	// Append writes entry; with durable it must be persisted before returning.
+	Append(entry WALEntry, durable bool) error
+	// Load returns every entry in append order.
+	Load() ([]WALEntry, error)
+	// Rewrite replaces the log with entries (compaction).
+	Rewrite(entries []WALEntry) error
+	Close() error
+}
+
+// WALEntry represents a single WAL entry.
+type WALEntry struct {
+	Action    string    `json:"action"` // "ENQUEUE" or "COMPLETE"
+	Order     Order     `json:"order"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// fileWAL is a JSON-lines WAL in a local file.
+type fileWAL struct {
+	path string
+	file *os.File
+}
+
+// NewFileWAL opens (creating if needed) the WAL file order_queue.wal in dir.
+func NewFileWAL(dir string) (WAL, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create WAL directory: %w", err)
+	}
+	path := filepath.Join(dir, "order_queue.wal")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open WAL file: %w", err)
+	}
+	return &fileWAL{path: path, file: file}, nil
+}
+
+func (w *fileWAL) Append(entry WALEntry, durable bool) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if durable {
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("sync: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *fileWAL) Load() ([]WALEntry, error) {
+	file, err := os.Open(w.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // No WAL file, nothing to recover
+		}
+		return nil, fmt.Errorf("open WAL for recovery: %w", err)
+	}
+	defer file.Close()
+
+	var entries []WALEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024) // 1MB buffer for large orders
+	for scanner.Scan() {
+		var entry WALEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("⚠️ WAL parse error (skipping): %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("WAL scan error: %w", err)
+	}
+	return entries, nil
+}
+
+// Rewrite writes entries to a temp file and renames it over the WAL.
+func (w *fileWAL) Rewrite(entries []WALEntry) error {
+	tempPath := w.path + ".tmp"
+	tempFile, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(tempFile)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			tempFile.Close()
+			os.Remove(tempPath)
+			return err
+		}
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	tempFile.Close()
+
+	// Close current WAL and replace
+	w.file.Close()
+	if err := os.Rename(tempPath, w.path); err != nil {
+		return err
+	}
+
+	// Reopen WAL
+	w.file, err = os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	return err
+}
+
+func (w *fileWAL) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	w.file.Sync()
+	return w.file.Close()
+}
+
+// dbWALLeaseTTL is how long a dbWAL lease outlives its last renewal, i.e. how
+// long a crashed instance keeps others from taking over its WAL.
+const dbWALLeaseTTL = 30 * time.Second
+
+// dbWAL keeps the WAL in the order_wal table, so it is durable across
+// containers that use the same database. One instance at a time owns it,
+// through a lease renewed in the background, so two instances never both
+// recover and replay the same orders.
+type dbWAL struct {
+	db    *db.Database
+	owner string
+	stop  chan struct{}
+
+	mu   sync.Mutex
+	lost bool // another instance took the lease after ours expired
+}
+
+// NewDBWAL returns a WAL stored in database, leased to this process. It fails
+// while another instance holds the lease; a crashed holder's lease expires
+// after dbWALLeaseTTL.
+func NewDBWAL(database *db.Database) (WAL, error) {
+	w := &dbWAL{db: database, owner: instanceOwner(), stop: make(chan struct{})}
+	now := time.Now()
+	ok, err := database.AcquireOrderWALLease(context.Background(), w.owner, now, now.Add(dbWALLeaseTTL))
+	if err != nil {
+		return nil, fmt.Errorf("acquire WAL lease: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("order WAL is leased by another instance")
+	}
+	go w.renew()
+	return w, nil
+}
+
+// renew extends the lease until Close, marking the WAL lost if another
+// instance took it over meanwhile.
+func (w *dbWAL) renew() {
+	ticker := time.NewTicker(dbWALLeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			ok, err := w.db.AcquireOrderWALLease(context.Background(), w.owner, now, now.Add(dbWALLeaseTTL))
+			if err != nil {
+				log.Printf("⚠️ WAL lease renewal failed: %v", err)
+				continue
+			}
+			if !ok {
+				log.Printf("❌ WAL lease lost to another instance; order WAL writes disabled")
+				w.mu.Lock()
+				w.lost = true
+				w.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// held returns an error once the lease was lost.
+func (w *dbWAL) held() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lost {
+		return fmt.Errorf("order WAL lease lost to another instance")
+	}
+	return nil
+}
+
+// Append always commits, so durable is implied.
+func (w *dbWAL) Append(entry WALEntry, _ bool) error {
+	if err := w.held(); err != nil {
+		return err
+	}
+	rec, err := walRecord(entry)
+	if err != nil {
+		return err
+	}
+	return w.db.AppendOrderWAL(context.Background(), rec)
+}
+
+func (w *dbWAL) Load() ([]WALEntry, error) {
+	if err := w.held(); err != nil {
+		return nil, err
+	}
+	records, err := w.db.ListOrderWAL(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("load WAL: %w", err)
+	}
+	entries := make([]WALEntry, 0, len(records))
+	for _, r := range records {
+		var entry WALEntry
+		if err := json.Unmarshal(r.Payload, &entry); err != nil {
+			log.Printf("⚠️ WAL parse error (skipping): %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (w *dbWAL) Rewrite(entries []WALEntry) error {
+	if err := w.held(); err != nil {
+		return err
+	}
+	records := make([]db.OrderWALRecord, 0, len(entries))
+	for _, entry := range entries {
+		rec, err := walRecord(entry)
+		if err != nil {
+			return err
+		}
+		records = append(records, rec)
+	}
+	return w.db.ReplaceOrderWAL(context.Background(), records)
+}
+
+// Close stops renewing and releases the lease; the database is owned by the
+// caller.
+func (w *dbWAL) Close() error {
+	close(w.stop)
+	return w.db.ReleaseOrderWALLease(context.Background(), w.owner)
+}
+
+func walRecord(entry WALEntry) (db.OrderWALRecord, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return db.OrderWALRecord{}, fmt.Errorf("marshal: %w", err)
+	}
+	return db.OrderWALRecord{
+		Action:    entry.Action,
+		OrderID:   entry.Order.ID,
+		Payload:   data,
+		CreatedAt: entry.Timestamp,
+	}, nil
+}
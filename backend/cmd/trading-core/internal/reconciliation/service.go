@@ -22,13 +22,20 @@ type Position struct {
 	Quantity float64
 }
 
+// DrainPauser holds off order execution while a reconciliation pass corrects
+// state (e.g. order.DrainGate).
+type DrainPauser interface {
+	Pause() (resume func())
+}
+
 // Service handles periodic reconciliation
 type Service struct {
 	exchange ExchangeClient
 	stateMgr *state.Manager
 	database *db.Database
 	interval time.Duration
-	autoSync bool // 是否自動同步
+	autoSync bool        // 是否自動同步
+	pauser   DrainPauser // optional; pauses the order drain during each pass
 	mu       sync.Mutex
 }
 
@@ -68,6 +75,13 @@ func (s *Service) SetAutoSync(enabled bool) {
 	log.Printf("📊 Reconciliation auto-sync: %v", enabled)
 }
 
+// SetDrainPauser pauses order draining while each reconciliation pass runs.
+func (s *Service) SetDrainPauser(p DrainPauser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pauser = p
+}
+
 // Start begins periodic reconciliation
 func (s *Service) Start(ctx context.Context) {
 	ticker := time.NewTicker(s.interval)
@@ -76,13 +90,7 @@ func (s *Service) Start(ctx context.Context) {
 		for {
 			select {
 			case <-ticker.C:
-				report, err := s.Reconcile(ctx)
-				if err != nil {
-					log.Printf("❌ Reconciliation error: %v", err)
-					continue
-				}
-
-				s.handleReport(ctx, report)
+				s.runPass(ctx)
 
 			case <-ctx.Done():
 				return
@@ -93,6 +101,24 @@ func (s *Service) Start(ctx context.Context) {
 	log.Printf("✓ Reconciliation service started (interval: %v, auto-sync: %v)", s.interval, s.autoSync)
 }
 
+// runPass reconciles once, with the order drain paused if a pauser is set.
+func (s *Service) runPass(ctx context.Context) {
+	s.mu.Lock()
+	pauser := s.pauser
+	s.mu.Unlock()
+	if pauser != nil {
+		resume := pauser.Pause()
+		defer resume()
+	}
+
+	report, err := s.Reconcile(ctx)
+	if err != nil {
+		log.Printf("❌ Reconciliation error: %v", err)
+		return
+	}
+	s.handleReport(ctx, report)
+}
+
 // Reconcile performs reconciliation check
 func (s *Service) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
 	s.mu.Lock()
@@ -0,0 +1,250 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"trading-core/internal/state"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// StartupAccount is an exchange account whose positions and open orders are
+// imported before trading starts.
+type StartupAccount struct {
+	UserID       string // empty for the global (single-user) account
+	ConnectionID string
+	Gateway      exchange.Gateway
+	// QuoteAsset is the quote of the pairs a spot account's holdings are
+	// imported as, e.g. a BTC balance as a BTCUSDT position (default USDT).
+	QuoteAsset string
+}
+
+// StartupReport summarises what ImportOnStartup brought into local state.
+type StartupReport struct {
+	Positions int
+	Orders    int
+}
+
+// ImportOnStartup seeds local state with the positions and open orders that
+// already exist on each account, so the first signals are not evaluated as if
+// the account were flat. Positions come from gateways implementing
+// exchange.MarginReporter, or for spot accounts from the base-asset balances
+// of an exchange.SpotBalanceReporter, and open orders from
+// exchange.OpenOrderLister; orders already stored locally are left alone.
+//
+// The global account's positions go to stateMgr; per-user accounts are written
+// to the user's positions rows. Accounts that fail are logged and skipped; the
+// joined errors are returned alongside whatever was imported.
+func ImportOnStartup(ctx context.Context, stateMgr *state.Manager, database *db.Database, accounts []StartupAccount) (StartupReport, error) {
+	var report StartupReport
+	var errs []error
+	for _, acct := range accounts {
+		if acct.Gateway == nil {
+			continue
+		}
+		label := labelFor(acct)
+		n, err := importPositions(ctx, stateMgr, database, acct)
+		report.Positions += n
+		if err != nil {
+			log.Printf("⚠️ Startup import: positions for %s: %v", label, err)
+			errs = append(errs, fmt.Errorf("%s positions: %w", label, err))
+		}
+		n, err = importOpenOrders(ctx, database, acct)
+		report.Orders += n
+		if err != nil {
+			log.Printf("⚠️ Startup import: open orders for %s: %v", label, err)
+			errs = append(errs, fmt.Errorf("%s open orders: %w", label, err))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+func importPositions(ctx context.Context, stateMgr *state.Manager, database *db.Database, acct StartupAccount) (int, error) {
+	reporter, ok := acct.Gateway.(exchange.MarginReporter)
+	if !ok {
+		return importSpotHoldings(ctx, stateMgr, database, acct)
+	}
+	margin, err := reporter.MarginAccount(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Hedge-mode accounts report LONG and SHORT legs separately. With hedge
+	// tracking on, the legs are seeded as they are (SetLeg also stores the
+	// nets); otherwise local state keeps one net position per symbol.
+	seeded := make(map[string]bool)
+	if stateMgr != nil && stateMgr.HedgeMode() {
+		for _, p := range margin.Positions {
+			if p.PositionSide != state.SideLong && p.PositionSide != state.SideShort {
+				continue
+			}
+			if err := stateMgr.SetLeg(ctx, acct.UserID, acct.ConnectionID, p.Symbol, p.PositionSide, p.Qty, p.EntryPrice); err != nil {
+				return 0, err
+			}
+			seeded[p.Symbol] = true
+		}
+	}
+	type net struct{ qty, notional float64 }
+	bySymbol := make(map[string]net)
+	var order []string
+	for _, p := range margin.Positions {
+		n, seen := bySymbol[p.Symbol]
+		if !seen {
+			order = append(order, p.Symbol)
+		}
+		n.qty += p.Qty
+		n.notional += p.Qty * p.EntryPrice
+		bySymbol[p.Symbol] = n
+	}
+
+	imported := 0
+	for _, sym := range order {
+		n := bySymbol[sym]
+		if n.qty == 0 {
+			continue
+		}
+		avg := n.notional / n.qty
+		if !seeded[sym] {
+			stored, err := storePosition(ctx, stateMgr, database, acct, sym, n.qty, avg)
+			if err != nil {
+				return imported, err
+			}
+			if !stored {
+				continue
+			}
+		}
+		log.Printf("📥 Startup import: %s position %s qty=%.6f avg=%.4f", labelFor(acct), sym, n.qty, avg)
+		imported++
+	}
+	return imported, nil
+}
+
+// importSpotHoldings imports a spot account's base-asset balances (free and
+// locked) as long positions in their pair with acct.QuoteAsset. Assets
+// without such a pair on the spot market, and dust below its minimum
+// quantity, are skipped. The venue reports no cost basis, so the entry price
+// is the pair's last price when the gateway can quote it: PnL then counts from
+// the import.
+func importSpotHoldings(ctx context.Context, stateMgr *state.Manager, database *db.Database, acct StartupAccount) (int, error) {
+	reporter, ok := acct.Gateway.(exchange.SpotBalanceReporter)
+	if !ok {
+		return 0, nil
+	}
+	balances, err := reporter.SpotBalances(ctx)
+	if err != nil {
+		return 0, err
+	}
+	quote := strings.ToUpper(acct.QuoteAsset)
+	if quote == "" {
+		quote = "USDT"
+	}
+	quoter, _ := acct.Gateway.(exchange.PriceQuoter)
+
+	imported := 0
+	for _, b := range balances {
+		qty := b.Free + b.Locked
+		if qty <= 0 || strings.EqualFold(b.Asset, quote) {
+			continue
+		}
+		sym := strings.ToUpper(b.Asset) + quote
+		if exchange.ValidateMarketSymbol(exchange.MarketSpot, sym) != nil {
+			continue
+		}
+		if info, ok := exchange.LookupMarketSymbol(exchange.MarketSpot, sym); ok {
+			if info.MinQty > 0 && qty < info.MinQty {
+				continue
+			}
+		}
+		var avg float64
+		if quoter != nil {
+			if avg, err = quoter.LastPrice(ctx, sym); err != nil {
+				log.Printf("⚠️ Startup import: %s price of %s: %v", labelFor(acct), sym, err)
+				avg = 0
+			}
+		}
+		stored, err := storePosition(ctx, stateMgr, database, acct, sym, qty, avg)
+		if err != nil {
+			return imported, err
+		}
+		if !stored {
+			continue
+		}
+		log.Printf("📥 Startup import: %s spot holding %s qty=%.6f priced at %.4f", labelFor(acct), sym, qty, avg)
+		imported++
+	}
+	return imported, nil
+}
+
+// storePosition stores an imported net position: the global account's in
+// stateMgr, a user's as the user's own position. It reports false when there
+// is nowhere to store it.
+func storePosition(ctx context.Context, stateMgr *state.Manager, database *db.Database, acct StartupAccount, symbol string, qty, avg float64) (bool, error) {
+	switch {
+	case acct.UserID == "" && stateMgr != nil:
+		return true, stateMgr.SetPosition(ctx, symbol, qty, avg)
+	case acct.UserID != "" && stateMgr != nil:
+		stateMgr.SetUserPosition(ctx, acct.UserID, symbol, qty, avg)
+		return true, nil
+	case acct.UserID != "" && database != nil:
+		return true, database.Queries().UpsertPositionWithUser(ctx, acct.UserID, symbol, qty, avg)
+	}
+	return false, nil
+}
+
+func importOpenOrders(ctx context.Context, database *db.Database, acct StartupAccount) (int, error) {
+	lister, ok := acct.Gateway.(exchange.OpenOrderLister)
+	if !ok || database == nil {
+		return 0, nil
+	}
+	orders, err := lister.ListOpenOrders(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, o := range orders {
+		// Orders we placed carry our order ID as the client ID; anything else
+		// was placed outside this system and gets an ID from the venue's.
+		id := o.ClientID
+		if id == "" {
+			id = "ext-" + o.ExchangeOrderID
+		}
+		exists, err := database.OrderExists(ctx, id)
+		if err != nil {
+			return imported, err
+		}
+		if exists {
+			continue
+		}
+		if err := database.CreateOrder(ctx, db.Order{
+			ID:        id,
+			Symbol:    o.Symbol,
+			Side:      string(o.Side),
+			Price:     o.Price,
+			Qty:       o.Qty,
+			FilledQty: o.FilledQty,
+			Status:    string(o.Status),
+			RefPrice:  o.Price,
+			UserID:    acct.UserID,
+			Note:      fmt.Sprintf("imported from exchange on startup (exchange order %s)", o.ExchangeOrderID),
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return imported, err
+		}
+		log.Printf("📥 Startup import: %s open order %s %s %s qty=%.6f @ %.4f", labelFor(acct), id, o.Symbol, o.Side, o.Qty, o.Price)
+		imported++
+	}
+	return imported, nil
+}
+
+func labelFor(acct StartupAccount) string {
+	if acct.ConnectionID != "" {
+		return "connection " + acct.ConnectionID
+	}
+	return "global account"
+}
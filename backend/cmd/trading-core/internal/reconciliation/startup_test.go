@@ -0,0 +1,174 @@
+package reconciliation
+
+import (
+	"context"
+	"testing"
+
+	"trading-core/internal/state"
+	"trading-core/pkg/db"
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// startupGateway is a futures account already holding a position and a resting order.
+type startupGateway struct{}
+
+func (startupGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{}, nil
+}
+
+func (startupGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func (startupGateway) MarginAccount(ctx context.Context) (exchange.MarginAccount, error) {
+	return exchange.MarginAccount{Positions: []exchange.MarginPosition{
+		{Symbol: "BTCUSDT", PositionSide: "BOTH", Qty: -0.5, EntryPrice: 60000},
+	}}, nil
+}
+
+func (startupGateway) ListOpenOrders(ctx context.Context) ([]exchange.OpenOrder, error) {
+	return []exchange.OpenOrder{
+		{Symbol: "BTCUSDT", ExchangeOrderID: "9001", Side: exchange.SideBuy, Type: exchange.OrderTypeLimit, Price: 55000, Qty: 0.5, Status: exchange.StatusNew},
+	}, nil
+}
+
+// hedgeGateway is a hedge-mode futures account holding both sides of a symbol.
+type hedgeGateway struct{ startupGateway }
+
+func (hedgeGateway) MarginAccount(ctx context.Context) (exchange.MarginAccount, error) {
+	return exchange.MarginAccount{Positions: []exchange.MarginPosition{
+		{Symbol: "BTCUSDT", PositionSide: "LONG", Qty: 2, EntryPrice: 60000},
+		{Symbol: "BTCUSDT", PositionSide: "SHORT", Qty: -0.5, EntryPrice: 62000},
+	}}, nil
+}
+
+func (hedgeGateway) ListOpenOrders(ctx context.Context) ([]exchange.OpenOrder, error) {
+	return nil, nil
+}
+
+// spotGateway is a spot account holding BTC, dust of SOL and its USDT quote balance.
+type spotGateway struct{}
+
+func (spotGateway) SubmitOrder(ctx context.Context, req exchange.OrderRequest) (exchange.OrderResult, error) {
+	return exchange.OrderResult{}, nil
+}
+
+func (spotGateway) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
+	return nil
+}
+
+func (spotGateway) SpotBalances(ctx context.Context) ([]exchange.SpotBalance, error) {
+	return []exchange.SpotBalance{
+		{Asset: "USDT", Free: 5000},
+		{Asset: "BTC", Free: 0.3, Locked: 0.2},
+		{Asset: "SOL", Free: 0.0001},
+	}, nil
+}
+
+func (spotGateway) LastPrice(ctx context.Context, symbol string) (float64, error) {
+	return 60000, nil
+}
+
+func TestImportOnStartupImportsSpotHoldings(t *testing.T) {
+	exchange.RegisterMarketSymbols(exchange.MarketSpot,
+		exchange.SymbolInfo{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", MinQty: 0.00001},
+		exchange.SymbolInfo{Symbol: "SOLUSDT", BaseAsset: "SOL", QuoteAsset: "USDT", MinQty: 0.01},
+	)
+	defer exchange.RegisterMarketSymbols(exchange.MarketSpot)
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	stateMgr := state.NewManager(database)
+
+	accounts := []StartupAccount{{UserID: "u1", ConnectionID: "c1", Gateway: spotGateway{}}}
+	report, err := ImportOnStartup(ctx, stateMgr, database, accounts)
+	if err != nil || report.Positions != 1 {
+		t.Fatalf("ImportOnStartup: report=%+v err=%v, want the BTC holding only", report, err)
+	}
+	if p := stateMgr.UserPosition("u1", "BTCUSDT"); p.Qty != 0.5 || p.AvgPrice != 60000 {
+		t.Fatalf("u1 BTCUSDT = %+v, want 0.5 priced at 60000", p)
+	}
+	positions, err := database.Queries().GetPositionsByUser(ctx, "u1")
+	if err != nil || len(positions) != 1 || positions[0].Symbol != "BTCUSDT" {
+		t.Fatalf("stored positions = %+v (err %v), want BTCUSDT", positions, err)
+	}
+}
+
+func TestImportOnStartupSeedsHedgeLegs(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	stateMgr := state.NewManager(database)
+	stateMgr.SetHedgeMode(true)
+
+	accounts := []StartupAccount{{UserID: "u1", ConnectionID: "c1", Gateway: hedgeGateway{}}}
+	report, err := ImportOnStartup(ctx, stateMgr, database, accounts)
+	if err != nil || report.Positions != 1 {
+		t.Fatalf("ImportOnStartup: report=%+v err=%v, want 1 position", report, err)
+	}
+	if l := stateMgr.Leg("u1", "c1", "BTCUSDT", "LONG"); l.Qty != 2 || l.AvgPrice != 60000 {
+		t.Fatalf("long leg = %+v, want 2 @ 60000", l)
+	}
+	if s := stateMgr.Leg("u1", "c1", "BTCUSDT", "SHORT"); s.Qty != -0.5 || s.AvgPrice != 62000 {
+		t.Fatalf("short leg = %+v, want -0.5 @ 62000", s)
+	}
+	positions, err := database.Queries().GetPositionsByUser(ctx, "u1")
+	if err != nil || len(positions) != 1 || positions[0].Qty != 1.5 {
+		t.Fatalf("user net positions = %+v (err %v), want 1.5", positions, err)
+	}
+}
+
+func TestImportOnStartupSeedsStateBeforeTrading(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	stateMgr := state.NewManager(database)
+	if err := stateMgr.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p := stateMgr.Position("BTCUSDT"); p.Qty != 0 {
+		t.Fatalf("fresh state should be flat, got %v", p.Qty)
+	}
+
+	accounts := []StartupAccount{{Gateway: startupGateway{}}}
+	report, err := ImportOnStartup(ctx, stateMgr, database, accounts)
+	if err != nil {
+		t.Fatalf("ImportOnStartup: %v", err)
+	}
+	if report.Positions != 1 || report.Orders != 1 {
+		t.Fatalf("report = %+v, want 1 position and 1 order", report)
+	}
+	if p := stateMgr.Position("BTCUSDT"); p.Qty != -0.5 || p.AvgPrice != 60000 {
+		t.Fatalf("imported position = %+v, want short 0.5 @ 60000", p)
+	}
+	open, err := database.ListOpenOrders(ctx)
+	if err != nil {
+		t.Fatalf("ListOpenOrders: %v", err)
+	}
+	if len(open) != 1 || open[0].ID != "ext-9001" || open[0].Qty != 0.5 {
+		t.Fatalf("imported open orders = %+v", open)
+	}
+
+	// A second pass (e.g. a restart) does not duplicate the order.
+	if report, err = ImportOnStartup(ctx, stateMgr, database, accounts); err != nil || report.Orders != 0 {
+		t.Fatalf("re-import: report=%+v err=%v, want no new orders", report, err)
+	}
+}
@@ -0,0 +1,160 @@
+package reconciliation
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+// StrategyPositionHealer compares each strategy's stored strategy_positions
+// row with the position replayed from its trades, and rewrites rows that
+// drifted (e.g. after a missed fill event) beyond the tolerances.
+type StrategyPositionHealer struct {
+	database *db.Database
+	interval time.Duration
+
+	// QtyTolerance is the absolute quantity difference ignored.
+	QtyTolerance float64
+	// PriceTolerancePct is the average-price difference ignored, in percent of
+	// the replayed price (only compared while a position is open).
+	PriceTolerancePct float64
+	// AutoHeal rewrites drifted rows; when false drift is only reported.
+	AutoHeal bool
+	// Pauser, when set, holds off the order drain while a pass runs.
+	Pauser DrainPauser
+}
+
+// StrategyPositionDiff is a strategy whose stored position drifted.
+type StrategyPositionDiff struct {
+	StrategyID  string
+	Symbol      string
+	StoredQty   float64
+	ReplayedQty float64
+	StoredAvg   float64
+	ReplayedAvg float64
+	Healed      bool
+}
+
+// NewStrategyPositionHealer creates a healer that runs every interval once started.
+func NewStrategyPositionHealer(database *db.Database, interval time.Duration) *StrategyPositionHealer {
+	return &StrategyPositionHealer{
+		database:          database,
+		interval:          interval,
+		QtyTolerance:      1e-8,
+		PriceTolerancePct: 0.01,
+		AutoHeal:          true,
+	}
+}
+
+// Start runs Heal immediately and then every interval until ctx is done.
+func (h *StrategyPositionHealer) Start(ctx context.Context) {
+	go func() {
+		h.run(ctx)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.run(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (h *StrategyPositionHealer) run(ctx context.Context) {
+	if h.Pauser != nil {
+		resume := h.Pauser.Pause()
+		defer resume()
+	}
+	diffs, err := h.Heal(ctx)
+	if err != nil {
+		log.Printf("❌ Strategy position reconciliation failed: %v", err)
+		return
+	}
+	for _, d := range diffs {
+		status := "❌ Not healed"
+		if d.Healed {
+			status = "✅ Healed"
+		}
+		log.Printf("⚠️ Strategy position drift %s %s: stored qty=%.8f avg=%.8f, from trades qty=%.8f avg=%.8f [%s]",
+			d.StrategyID, d.Symbol, d.StoredQty, d.StoredAvg, d.ReplayedQty, d.ReplayedAvg, status)
+	}
+}
+
+// Heal checks every strategy and returns the ones that drifted. Each strategy
+// is compared and rewritten in one transaction, so a fill recorded meanwhile
+// cannot be overwritten by a stale replay.
+func (h *StrategyPositionHealer) Heal(ctx context.Context) ([]StrategyPositionDiff, error) {
+	ids, err := h.database.ListStrategyPositionIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []StrategyPositionDiff
+	for _, id := range ids {
+		var (
+			diff    StrategyPositionDiff
+			drifted bool
+		)
+		err := h.database.WithTx(ctx, func(tx *db.Tx) error {
+			stored, err := tx.GetStrategyPosition(ctx, id)
+			if err != nil {
+				return err
+			}
+			replayed, fills, err := tx.ReplayStrategyPosition(ctx, id)
+			if err != nil {
+				return err
+			}
+			if stored == nil {
+				stored = &db.StrategyPosition{StrategyInstanceID: id}
+			}
+			if fills == 0 {
+				// No trades to rebuild from (e.g. a position seeded by hand).
+				return nil
+			}
+			if !h.drifted(*stored, replayed) {
+				return nil
+			}
+			drifted = true
+			diff = StrategyPositionDiff{
+				StrategyID:  id,
+				Symbol:      replayed.Symbol,
+				StoredQty:   stored.Qty,
+				ReplayedQty: replayed.Qty,
+				StoredAvg:   stored.AvgPrice,
+				ReplayedAvg: replayed.AvgPrice,
+			}
+			if !h.AutoHeal {
+				return nil
+			}
+			if err := tx.ReplaceStrategyPosition(ctx, replayed); err != nil {
+				return err
+			}
+			diff.Healed = true
+			return nil
+		})
+		if err != nil {
+			log.Printf("❌ Strategy position reconciliation %s: %v", id, err)
+			continue
+		}
+		if drifted {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+func (h *StrategyPositionHealer) drifted(stored, replayed db.StrategyPosition) bool {
+	if math.Abs(stored.Qty-replayed.Qty) > h.QtyTolerance {
+		return true
+	}
+	if replayed.Qty == 0 || replayed.AvgPrice == 0 {
+		return false
+	}
+	return math.Abs(stored.AvgPrice-replayed.AvgPrice)/replayed.AvgPrice*100 > h.PriceTolerancePct
+}
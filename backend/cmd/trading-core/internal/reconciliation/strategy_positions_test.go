@@ -0,0 +1,87 @@
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+func TestStrategyPositionHealerRebuildsCorruptedRowFromTrades(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	// Buy 1 @ 100, buy 1 @ 200, sell 1 @ 250: long 1 @ 150 with 100 realized.
+	fills := []struct {
+		strategy, side string
+		qty, price     float64
+	}{
+		{"strat-a", "BUY", 1, 100},
+		{"strat-a", "BUY", 1, 200},
+		{"strat-a", "SELL", 1, 250},
+		{"strat-b", "BUY", 2, 50},
+	}
+	base := time.Now().Add(-time.Hour)
+	for i, f := range fills {
+		o := db.Order{ID: fmt.Sprintf("o-%d", i), StrategyInstanceID: f.strategy, Symbol: "BTCUSDT", Side: f.side, Price: f.price, Qty: f.qty, FilledQty: f.qty, Status: "FILLED", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := database.CreateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		if err := database.CreateTrade(ctx, db.Trade{ID: fmt.Sprintf("t-%d", i), OrderID: o.ID, Symbol: o.Symbol, Side: o.Side, Price: o.Price, Qty: o.Qty, CreatedAt: o.CreatedAt}); err != nil {
+			t.Fatalf("CreateTrade: %v", err)
+		}
+		if err := database.UpdateStrategyPosition(ctx, f.strategy, "BTCUSDT", f.side, f.qty, f.price); err != nil {
+			t.Fatalf("UpdateStrategyPosition: %v", err)
+		}
+	}
+
+	healer := NewStrategyPositionHealer(database, time.Minute)
+	if diffs, err := healer.Heal(ctx); err != nil || len(diffs) != 0 {
+		t.Fatalf("consistent positions reported drift: %+v, %v", diffs, err)
+	}
+
+	// A missed SELL event left strat-a at 2 units with the wrong entry.
+	if _, err := database.DB.ExecContext(ctx, `UPDATE strategy_positions SET qty = 2, avg_price = 120 WHERE strategy_instance_id = 'strat-a'`); err != nil {
+		t.Fatalf("corrupt row: %v", err)
+	}
+
+	healer.AutoHeal = false
+	diffs, err := healer.Heal(ctx)
+	if err != nil || len(diffs) != 1 || diffs[0].StrategyID != "strat-a" || diffs[0].Healed {
+		t.Fatalf("report-only heal: %+v, %v", diffs, err)
+	}
+	if sp, _ := database.GetStrategyPosition(ctx, "strat-a"); sp.Qty != 2 {
+		t.Fatalf("report-only heal changed the row: %+v", sp)
+	}
+
+	healer.AutoHeal = true
+	diffs, err = healer.Heal(ctx)
+	if err != nil || len(diffs) != 1 || !diffs[0].Healed || diffs[0].StoredQty != 2 || diffs[0].ReplayedQty != 1 {
+		t.Fatalf("heal: %+v, %v", diffs, err)
+	}
+	sp, err := database.GetStrategyPosition(ctx, "strat-a")
+	if err != nil || sp == nil {
+		t.Fatalf("GetStrategyPosition: %+v, %v", sp, err)
+	}
+	if sp.Qty != 1 || math.Abs(sp.AvgPrice-150) > 1e-9 || math.Abs(sp.RealizedPnL-100) > 1e-9 {
+		t.Fatalf("healed position = qty %v avg %v realized %v, want 1 @ 150 with 100 realized", sp.Qty, sp.AvgPrice, sp.RealizedPnL)
+	}
+
+	// Drift within tolerance is left alone.
+	if _, err := database.DB.ExecContext(ctx, `UPDATE strategy_positions SET avg_price = 150.001 WHERE strategy_instance_id = 'strat-a'`); err != nil {
+		t.Fatalf("nudge row: %v", err)
+	}
+	if diffs, err := healer.Heal(ctx); err != nil || len(diffs) != 0 {
+		t.Fatalf("drift within tolerance reported: %+v, %v", diffs, err)
+	}
+}
@@ -0,0 +1,233 @@
+package risk
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-core/pkg/money"
+)
+
+// ConnectionLimits caps the risk taken through one exchange connection
+// (account), on top of the user and global limits. Zero disables a limit.
+type ConnectionLimits struct {
+	MaxDailyLoss float64 `json:"max_daily_loss"` // realized net loss per day, quote currency
+	MaxExposure  float64 `json:"max_exposure"`   // open notional across symbols, quote currency
+}
+
+// ConnectionLimitsFunc returns the limits stored on a connection.
+type ConnectionLimitsFunc func(connectionID string) (ConnectionLimits, error)
+
+// connectionBook is what the manager knows about a connection from its fills.
+// It is persisted in connection_positions and connection_daily_losses so a
+// restart or an evicted user manager picks up where it left off.
+type connectionBook struct {
+	dailyLoss float64
+	qty       map[string]float64 // signed position by symbol
+	price     map[string]float64 // last fill price by symbol
+}
+
+// exposure values the open positions at their last fill price, using price
+// for symbol when it is known.
+func (b *connectionBook) exposure(symbol string, price float64) float64 {
+	total := 0.0
+	for sym, qty := range b.qty {
+		px := b.price[sym]
+		if sym == symbol && price > 0 {
+			px = price
+		}
+		total += math.Abs(qty) * px
+	}
+	return total
+}
+
+// CacheConnectionLimits wraps fn so each connection's limits are looked up at
+// most once per ttl. Failed lookups are not cached.
+func CacheConnectionLimits(fn ConnectionLimitsFunc, ttl time.Duration) ConnectionLimitsFunc {
+	type entry struct {
+		limits  ConnectionLimits
+		expires time.Time
+	}
+	var (
+		mu    sync.Mutex
+		cache = make(map[string]entry)
+	)
+	return func(connectionID string) (ConnectionLimits, error) {
+		mu.Lock()
+		e, ok := cache[connectionID]
+		mu.Unlock()
+		if ok && time.Now().Before(e.expires) {
+			return e.limits, nil
+		}
+		limits, err := fn(connectionID)
+		if err != nil {
+			return limits, err
+		}
+		mu.Lock()
+		cache[connectionID] = entry{limits: limits, expires: time.Now().Add(ttl)}
+		mu.Unlock()
+		return limits, nil
+	}
+}
+
+// connectionBookLocked returns connectionID's book, loading it from the DB the
+// first time. Caller must hold m.mu for writing.
+func (m *Manager) connectionBookLocked(connectionID string) *connectionBook {
+	if m.connections == nil {
+		m.connections = make(map[string]*connectionBook)
+	}
+	if book, ok := m.connections[connectionID]; ok {
+		return book
+	}
+	book := &connectionBook{qty: make(map[string]float64), price: make(map[string]float64)}
+	if db := m.persistDB(); db != nil {
+		if err := loadConnectionBook(db, connectionID, book); err != nil {
+			log.Printf("⚠️ [Connection %s] failed to load risk book: %v", connectionID, err)
+		}
+	}
+	m.connections[connectionID] = book
+	return book
+}
+
+// loadConnectionBook fills book with today's realized loss and the open
+// positions stored for connectionID.
+func loadConnectionBook(db *sql.DB, connectionID string, book *connectionBook) error {
+	err := db.QueryRow(`SELECT loss FROM connection_daily_losses WHERE connection_id = ? AND day = ?`,
+		connectionID, time.Now().Format("2006-01-02")).Scan(&book.dailyLoss)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	rows, err := db.Query(`SELECT symbol, qty, price FROM connection_positions WHERE connection_id = ?`, connectionID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var symbol string
+		var qty, price float64
+		if err := rows.Scan(&symbol, &qty, &price); err != nil {
+			return err
+		}
+		book.qty[symbol] = qty
+		book.price[symbol] = price
+	}
+	return rows.Err()
+}
+
+// SetConnectionLimits attaches the lookup for per-connection limits. Without
+// one, signals bound to a connection are only checked against the user limits.
+func (m *Manager) SetConnectionLimits(fn ConnectionLimitsFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connLimits = fn
+}
+
+// RecordConnectionTrade books a fill against its connection's daily loss and
+// open exposure. trade.PnL should be net of fees.
+func (m *Manager) RecordConnectionTrade(connectionID string, trade TradeResult) {
+	if connectionID == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book := m.connectionBookLocked(connectionID)
+	db := m.persistDB()
+	if trade.PnL < 0 {
+		book.dailyLoss = money.AddFloats(book.dailyLoss, -trade.PnL)
+		if db != nil {
+			if _, err := db.Exec(`
+				INSERT INTO connection_daily_losses (connection_id, day, loss) VALUES (?, ?, ?)
+				ON CONFLICT(connection_id, day) DO UPDATE SET loss = excluded.loss
+			`, connectionID, time.Now().Format("2006-01-02"), book.dailyLoss); err != nil {
+				log.Printf("⚠️ [Connection %s] failed to persist daily loss: %v", connectionID, err)
+			}
+		}
+	}
+
+	symbol := strings.ToUpper(trade.Symbol)
+	delta := trade.Size
+	if strings.EqualFold(trade.Side, "SELL") {
+		delta = -delta
+	}
+	qty := money.AddFloats(book.qty[symbol], delta)
+	var err error
+	if math.Abs(qty) < 1e-12 {
+		delete(book.qty, symbol)
+		delete(book.price, symbol)
+		if db != nil {
+			_, err = db.Exec(`DELETE FROM connection_positions WHERE connection_id = ? AND symbol = ?`, connectionID, symbol)
+		}
+	} else {
+		book.qty[symbol] = qty
+		book.price[symbol] = trade.Price
+		if db != nil {
+			_, err = db.Exec(`
+				INSERT INTO connection_positions (connection_id, symbol, qty, price, updated_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+				ON CONFLICT(connection_id, symbol) DO UPDATE SET qty = excluded.qty, price = excluded.price, updated_at = CURRENT_TIMESTAMP
+			`, connectionID, symbol, qty, trade.Price)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️ [Connection %s] failed to persist %s position: %v", connectionID, symbol, err)
+	}
+}
+
+// ConnectionUsage returns a connection's realized loss today and its open
+// exposure at the last fill prices.
+func (m *Manager) ConnectionUsage(connectionID string) (dailyLoss, exposure float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	book := m.connectionBookLocked(connectionID)
+	return book.dailyLoss, book.exposure("", 0)
+}
+
+// checkConnection rejects entries on signal.ConnectionID once the connection
+// has lost its daily budget or would exceed its exposure cap. Exits are
+// always allowed. It returns "" when the signal may proceed.
+func (m *Manager) checkConnection(signal SignalInput, position Position) string {
+	if signal.ConnectionID == "" || IsExit(signal.Action, position) {
+		return ""
+	}
+	m.mu.RLock()
+	fn := m.connLimits
+	enabled := m.config != nil && m.config.EnableRisk
+	m.mu.RUnlock()
+	if fn == nil || !enabled {
+		return ""
+	}
+	limits, err := fn(signal.ConnectionID)
+	if err != nil {
+		log.Printf("⚠️ [Connection %s] risk limit lookup failed: %v", signal.ConnectionID, err)
+		return ""
+	}
+
+	m.mu.Lock()
+	book := m.connectionBookLocked(signal.ConnectionID)
+	dailyLoss := book.dailyLoss
+	exposure := book.exposure(strings.ToUpper(signal.Symbol), signal.Price)
+	m.mu.Unlock()
+
+	if limits.MaxDailyLoss > 0 && dailyLoss >= limits.MaxDailyLoss {
+		return fmt.Sprintf("connection %s daily loss limit reached: %.2f >= %.2f", signal.ConnectionID, dailyLoss, limits.MaxDailyLoss)
+	}
+	if limits.MaxExposure > 0 {
+		projected := exposure + math.Abs(signal.Size*signal.Price)
+		if projected > limits.MaxExposure {
+			return fmt.Sprintf("connection %s exposure limit exceeded: %.2f > %.2f", signal.ConnectionID, projected, limits.MaxExposure)
+		}
+	}
+	return ""
+}
+
+// resetConnectionDaily clears the connections' daily losses; open exposure carries over.
+// Caller must hold m.mu.
+func (m *Manager) resetConnectionDaily() {
+	for _, book := range m.connections {
+		book.dailyLoss = 0
+	}
+}
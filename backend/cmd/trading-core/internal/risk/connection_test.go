@@ -0,0 +1,145 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+)
+
+func TestConnectionDailyLossLimitIsolatesAccounts(t *testing.T) {
+	mu := NewMultiUserManager(nil)
+	mu.SetConnectionLimits(func(connectionID string) (ConnectionLimits, error) {
+		if connectionID == "conn-small" {
+			return ConnectionLimits{MaxDailyLoss: 50, MaxExposure: 500}, nil
+		}
+		return ConnectionLimits{}, nil
+	})
+
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+	buy := func(connectionID string) RiskDecision {
+		dec, err := mu.EvaluateForUser("user-1", SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.002, Price: 100000, ConnectionID: connectionID}, Position{}, account, "")
+		if err != nil {
+			t.Fatalf("evaluate: %v", err)
+		}
+		return dec
+	}
+
+	if dec := buy("conn-small"); !dec.Allowed {
+		t.Fatalf("expected entry on fresh connection, got %q", dec.Reason)
+	}
+
+	// A losing round trip on the small account uses up its daily budget.
+	for _, tr := range []TradeResult{
+		{Symbol: "BTCUSDT", Side: "BUY", Size: 0.002, Price: 100000},
+		{Symbol: "BTCUSDT", Side: "SELL", Size: 0.002, Price: 70000, PnL: -60},
+	} {
+		if err := mu.RecordConnectionTradeForUser("user-1", "conn-small", tr); err != nil {
+			t.Fatalf("record trade: %v", err)
+		}
+	}
+
+	dec := buy("conn-small")
+	if dec.Allowed || !strings.Contains(dec.Reason, "daily loss limit") {
+		t.Fatalf("expected conn-small to be blocked by its daily loss limit, got %+v", dec)
+	}
+	if dec := buy("conn-main"); !dec.Allowed {
+		t.Fatalf("expected the user's other connection to keep trading, got %q", dec.Reason)
+	}
+	if dec := buy(""); !dec.Allowed {
+		t.Fatalf("unbound signals should ignore connection limits, got %q", dec.Reason)
+	}
+
+	// Exits still go through so the account can be flattened.
+	exit, _ := mu.EvaluateForUser("user-1", SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.001, Price: 100000, ConnectionID: "conn-small"},
+		Position{Symbol: "BTCUSDT", Side: "LONG", Quantity: 0.001}, account, "")
+	if !exit.Allowed {
+		t.Fatalf("expected exit to be allowed, got %q", exit.Reason)
+	}
+
+	mu.ResetDailyForAll()
+	if dec := buy("conn-small"); !dec.Allowed {
+		t.Fatalf("expected daily reset to lift the loss limit, got %q", dec.Reason)
+	}
+}
+
+func TestConnectionExposureLimit(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	mgr.SetConnectionLimits(func(string) (ConnectionLimits, error) {
+		return ConnectionLimits{MaxExposure: 300}, nil
+	})
+	mgr.RecordConnectionTrade("conn-1", TradeResult{Symbol: "ETHUSDT", Side: "BUY", Size: 0.1, Price: 2000})
+
+	if _, exposure := mgr.ConnectionUsage("conn-1"); exposure != 200 {
+		t.Fatalf("exposure = %v, want 200", exposure)
+	}
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+	dec := mgr.EvaluateFull(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.002, Price: 100000, ConnectionID: "conn-1"}, Position{}, account, "")
+	if dec.Allowed || !strings.Contains(dec.Reason, "exposure limit") {
+		t.Fatalf("expected exposure rejection, got %+v", dec)
+	}
+	dec = mgr.EvaluateFull(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.0005, Price: 100000, ConnectionID: "conn-1"}, Position{}, account, "")
+	if !dec.Allowed {
+		t.Fatalf("expected entry within exposure cap, got %q", dec.Reason)
+	}
+}
+
+func TestConnectionBookSurvivesEviction(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	mu := NewMultiUserManager(database.DB)
+	for _, tr := range []TradeResult{
+		{Symbol: "BTCUSDT", Side: "BUY", Size: 0.002, Price: 100000},
+		{Symbol: "BTCUSDT", Side: "SELL", Size: 0.001, Price: 70000, PnL: -30},
+		{Symbol: "ETHUSDT", Side: "BUY", Size: 1, Price: 2000},
+		{Symbol: "ETHUSDT", Side: "SELL", Size: 1, Price: 1990, PnL: -10},
+	} {
+		if err := mu.RecordConnectionTradeForUser("user-1", "conn-1", tr); err != nil {
+			t.Fatalf("record trade: %v", err)
+		}
+	}
+
+	// A fresh manager, as after a restart or idle eviction, rebuilds the book.
+	mu.Remove("user-1")
+	mgr, err := mu.GetOrCreate("user-1")
+	if err != nil {
+		t.Fatalf("GetOrCreate: %v", err)
+	}
+	loss, exposure := mgr.ConnectionUsage("conn-1")
+	if loss != 40 {
+		t.Errorf("daily loss after reload = %v, want 40", loss)
+	}
+	if exposure != 70 { // 0.001 BTC at the last 70000 fill; ETH is flat
+		t.Errorf("exposure after reload = %v, want 70", exposure)
+	}
+}
+
+func TestCacheConnectionLimits(t *testing.T) {
+	calls := 0
+	fn := CacheConnectionLimits(func(string) (ConnectionLimits, error) {
+		calls++
+		return ConnectionLimits{MaxDailyLoss: 50}, nil
+	}, 20*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if limits, _ := fn("conn-1"); limits.MaxDailyLoss != 50 {
+			t.Fatalf("limits = %+v", limits)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("lookups = %d, want 1 while cached", calls)
+	}
+	time.Sleep(30 * time.Millisecond)
+	fn("conn-1")
+	if calls != 2 {
+		t.Fatalf("lookups = %d, want 2 after the ttl", calls)
+	}
+}
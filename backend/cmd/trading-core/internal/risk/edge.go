@@ -0,0 +1,50 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// EdgeCheck compares an entry's expected gross edge (distance from the entry
+// price to its take profit) with the expected round-trip costs. All values
+// are fractions of the entry price, e.g. 0.001 = 0.1%.
+type EdgeCheck struct {
+	Symbol      string
+	Action      string
+	EdgePct     float64
+	FeePct      float64 // entry + exit commission
+	SpreadPct   float64 // half the spread crossed on entry and again on exit
+	MinMultiple float64 // edge must exceed costs by this factor
+}
+
+// CostPct returns the expected round-trip cost.
+func (c EdgeCheck) CostPct() float64 {
+	return c.FeePct + c.SpreadPct
+}
+
+// Allowed reports whether the edge covers MinMultiple times the costs.
+// MinMultiple <= 0 disables the check.
+func (c EdgeCheck) Allowed() bool {
+	return c.MinMultiple <= 0 || c.EdgePct > c.MinMultiple*c.CostPct()
+}
+
+func (c EdgeCheck) String() string {
+	return fmt.Sprintf("net edge too small on %s %s: edge %.3f%% vs costs %.3f%% (fees %.3f%% + spread %.3f%%) x%.2g",
+		c.Action, c.Symbol, c.EdgePct*100, c.CostPct()*100, c.FeePct*100, c.SpreadPct*100, c.MinMultiple)
+}
+
+// NetEdge builds the check for an entry at price targeting takeProfit, paying
+// entryFeeRate and exitFeeRate (decimal rates) and a spread of spreadBps.
+func NetEdge(symbol, action string, price, takeProfit, entryFeeRate, exitFeeRate, spreadBps, minMultiple float64) EdgeCheck {
+	c := EdgeCheck{
+		Symbol:      symbol,
+		Action:      action,
+		FeePct:      entryFeeRate + exitFeeRate,
+		SpreadPct:   spreadBps / 10000,
+		MinMultiple: minMultiple,
+	}
+	if price > 0 && takeProfit > 0 {
+		c.EdgePct = math.Abs(takeProfit-price) / price
+	}
+	return c
+}
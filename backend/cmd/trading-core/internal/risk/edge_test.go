@@ -0,0 +1,29 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNetEdgeRejectsTightTakeProfit(t *testing.T) {
+	// 0.1% to TP against 0.1% taker fees each way plus a 5 bps spread.
+	tight := NetEdge("BTCUSDT", "BUY", 100, 100.1, 0.001, 0.001, 5, 1.5)
+	if tight.Allowed() {
+		t.Fatalf("expected tight TP to be rejected: %s", tight)
+	}
+	if got := tight.CostPct(); got < 0.00249 || got > 0.00251 {
+		t.Errorf("cost = %v, want 0.0025", got)
+	}
+	if msg := tight.String(); !strings.Contains(msg, "edge 0.100%") || !strings.Contains(msg, "costs 0.250%") {
+		t.Errorf("rejection should report the numbers, got %q", msg)
+	}
+
+	// A 5% TP comfortably clears 1.5x costs, on either side.
+	if wide := NetEdge("BTCUSDT", "SELL", 100, 95, 0.001, 0.001, 5, 1.5); !wide.Allowed() {
+		t.Fatalf("expected wide TP to pass: %s", wide)
+	}
+	// Disabled filter.
+	if off := NetEdge("BTCUSDT", "BUY", 100, 100.1, 0.001, 0.001, 5, 0); !off.Allowed() {
+		t.Fatal("multiple 0 should disable the filter")
+	}
+}
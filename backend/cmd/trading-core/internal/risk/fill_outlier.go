@@ -0,0 +1,238 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FillOutlier is a fill whose price was too far from the reference price to
+// be trusted (bad data, a fat-fingered order or an exchange glitch).
+type FillOutlier struct {
+	FillID       string    `json:"fill_id"`  // quarantine key: connection, symbol and trade ID
+	TradeID      string    `json:"trade_id"` // exchange trade ID, or one assigned when the fill has none
+	OrderID      string    `json:"order_id"`
+	UserID       string    `json:"user_id,omitempty"`
+	ConnectionID string    `json:"connection_id,omitempty"`
+	StrategyID   string    `json:"strategy_id,omitempty"`
+	Symbol       string    `json:"symbol"`
+	Side         string    `json:"side"`
+	PositionSide string    `json:"position_side,omitempty"`
+	Qty          float64   `json:"qty"`
+	FillPrice    float64   `json:"fill_price"`
+	RefPrice     float64   `json:"ref_price"`
+	Deviation    float64   `json:"deviation_pct"` // |fill - ref| / ref, percent
+	MaxDeviation float64   `json:"max_deviation_pct"`
+	At           time.Time `json:"at"`
+}
+
+func (o FillOutlier) String() string {
+	return fmt.Sprintf("fill %s of order %s: %s %g %s @ %.8g is %.2f%% from the reference price %.8g (max %.2f%%); quarantined for review",
+		o.FillID, o.OrderID, o.Side, o.Qty, o.Symbol, o.FillPrice, o.Deviation, o.RefPrice, o.MaxDeviation)
+}
+
+// FillQuarantine holds back fills priced more than maxPct percent away from
+// the reference (last known) price instead of booking them into positions and
+// PnL. Each outlier raises an alert and waits until an operator releases it
+// (it is then booked as reported) or discards it. A nil quarantine, or
+// maxPct <= 0, passes every fill. Fills are keyed by fill ID, so several
+// outlier fills of one order are held separately.
+type FillQuarantine struct {
+	mu        sync.Mutex
+	maxPct    float64
+	db        *sql.DB
+	held      map[string]FillOutlier // fill ID -> held fill
+	released  map[string]bool        // fill IDs released and about to be re-published
+	alertFn   func(FillOutlier)
+	releaseFn func(FillOutlier)
+	now       func() time.Time
+}
+
+// NewFillQuarantine creates a quarantine for fills more than maxPct percent off the reference price.
+func NewFillQuarantine(maxPct float64) *FillQuarantine {
+	return &FillQuarantine{
+		maxPct:   maxPct,
+		held:     make(map[string]FillOutlier),
+		released: make(map[string]bool),
+		now:      time.Now,
+	}
+}
+
+// Restore stores held fills in db from now on and reloads the fills held
+// before a restart, so they still wait for review.
+func (q *FillQuarantine) Restore(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT payload FROM quarantined_fills`)
+	if err != nil {
+		return fmt.Errorf("load quarantined fills: %w", err)
+	}
+	var loaded []FillOutlier
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			rows.Close()
+			return err
+		}
+		var fill FillOutlier
+		if err := json.Unmarshal([]byte(raw), &fill); err != nil {
+			log.Printf("⚠️ Skipping unreadable quarantined fill: %v", err)
+			continue
+		}
+		loaded = append(loaded, fill)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.db = db
+	for _, fill := range loaded {
+		q.held[fill.FillID] = fill
+	}
+	return nil
+}
+
+// SetAlertFn sets the callback invoked when a fill is quarantined.
+func (q *FillQuarantine) SetAlertFn(fn func(FillOutlier)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.alertFn = fn
+}
+
+// SetReleaseFn sets the callback that books a released fill, typically by
+// publishing it again as a filled order.
+func (q *FillQuarantine) SetReleaseFn(fn func(FillOutlier)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseFn = fn
+}
+
+// Check compares fill.FillPrice with fill.RefPrice and reports whether the
+// fill is an outlier that must not be booked; the outlier is then held with
+// its deviation and FillID filled in. A fill without a TradeID is assigned
+// one, which the release callback must publish with the fill. Fills without
+// a reference price, and a fill that was just released, pass.
+func (q *FillQuarantine) Check(fill FillOutlier) (FillOutlier, bool) {
+	if q == nil || q.maxPct <= 0 || fill.FillPrice <= 0 || fill.RefPrice <= 0 {
+		return fill, false
+	}
+	q.mu.Lock()
+	if fill.TradeID != "" {
+		fill.FillID = fillKey(fill)
+	}
+	if fill.FillID != "" && q.released[fill.FillID] {
+		delete(q.released, fill.FillID)
+		q.mu.Unlock()
+		return fill, false
+	}
+	fill.Deviation = math.Abs(fill.FillPrice-fill.RefPrice) / fill.RefPrice * 100
+	if fill.Deviation <= q.maxPct {
+		q.mu.Unlock()
+		return fill, false
+	}
+	fill.MaxDeviation = q.maxPct
+	fill.At = q.now().UTC()
+	if fill.TradeID == "" {
+		fill.TradeID = fmt.Sprintf("q%d", fill.At.UnixNano())
+		fill.FillID = fillKey(fill)
+	}
+	q.held[fill.FillID] = fill
+	fn, db := q.alertFn, q.db
+	q.mu.Unlock()
+
+	if db != nil {
+		if err := saveQuarantinedFill(db, fill); err != nil {
+			log.Printf("⚠️ Persist quarantined fill %s: %v", fill.FillID, err)
+		}
+	}
+
+	log.Printf("⚠️ Abnormal fill price: %s", fill)
+	if fn != nil {
+		fn(fill)
+	}
+	return fill, true
+}
+
+// List returns the held fills, oldest first.
+func (q *FillQuarantine) List() []FillOutlier {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]FillOutlier, 0, len(q.held))
+	for _, f := range q.held {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+// Release books a held fill at its reported price and reports whether it was
+// held. The release callback must re-publish the fill with its TradeID so
+// Check lets it through.
+func (q *FillQuarantine) Release(fillID string) (FillOutlier, bool) {
+	q.mu.Lock()
+	fill, ok := q.held[fillID]
+	if ok {
+		delete(q.held, fillID)
+		q.released[fillID] = true
+	}
+	fn, db := q.releaseFn, q.db
+	q.mu.Unlock()
+
+	if ok && db != nil {
+		deleteQuarantinedFill(db, fillID)
+	}
+	if ok && fn != nil {
+		fn(fill)
+	}
+	return fill, ok
+}
+
+// Discard drops a held fill without booking it and reports whether it was held.
+func (q *FillQuarantine) Discard(fillID string) (FillOutlier, bool) {
+	q.mu.Lock()
+	fill, ok := q.held[fillID]
+	delete(q.held, fillID)
+	db := q.db
+	q.mu.Unlock()
+
+	if ok && db != nil {
+		deleteQuarantinedFill(db, fillID)
+	}
+	return fill, ok
+}
+
+// fillKey identifies a fill: trade IDs are only unique per symbol and account.
+func fillKey(fill FillOutlier) string {
+	return fill.ConnectionID + ":" + fill.Symbol + ":" + fill.TradeID
+}
+
+// saveQuarantinedFill stores a held fill.
+func saveQuarantinedFill(db *sql.DB, fill FillOutlier) error {
+	raw, err := json.Marshal(fill)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO quarantined_fills (fill_id, payload, held_at) VALUES (?, ?, ?)
+		ON CONFLICT(fill_id) DO UPDATE SET payload = excluded.payload
+	`, fill.FillID, string(raw), fill.At)
+	return err
+}
+
+// deleteQuarantinedFill removes a fill that was released or discarded.
+func deleteQuarantinedFill(db *sql.DB, fillID string) {
+	if _, err := db.Exec(`DELETE FROM quarantined_fills WHERE fill_id = ?`, fillID); err != nil {
+		log.Printf("⚠️ Remove quarantined fill %s: %v", fillID, err)
+	}
+}
@@ -0,0 +1,104 @@
+package risk
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"trading-core/pkg/db"
+)
+
+func TestFillQuarantineHoldsOutlierFill(t *testing.T) {
+	var alerts []FillOutlier
+	var booked []FillOutlier
+	q := NewFillQuarantine(10)
+	q.SetAlertFn(func(o FillOutlier) { alerts = append(alerts, o) })
+	q.SetReleaseFn(func(o FillOutlier) { booked = append(booked, o) })
+
+	// 2% off the reference is a normal fill.
+	if _, held := q.Check(FillOutlier{TradeID: "1", OrderID: "o1", Symbol: "BTCUSDT", Side: "BUY", Qty: 1, FillPrice: 102, RefPrice: 100}); held {
+		t.Fatal("a fill 2% from the reference should pass a 10% limit")
+	}
+
+	// 50% off the reference is quarantined and alerts.
+	out, held := q.Check(FillOutlier{TradeID: "2", OrderID: "o2", ConnectionID: "c1", Symbol: "BTCUSDT", Side: "SELL", Qty: 1, FillPrice: 50, RefPrice: 100})
+	if !held {
+		t.Fatal("expected a fill 50% from the reference to be quarantined")
+	}
+	if out.FillID != "c1:BTCUSDT:2" {
+		t.Fatalf("fill ID = %q, want c1:BTCUSDT:2", out.FillID)
+	}
+	if len(alerts) != 1 || alerts[0].OrderID != "o2" {
+		t.Fatalf("expected one outlier alert for o2, got %+v", alerts)
+	}
+	if math.Abs(out.Deviation-50) > 1e-9 {
+		t.Errorf("deviation = %v, want 50", out.Deviation)
+	}
+
+	// A second outlier trade of the same order is held separately.
+	second, held := q.Check(FillOutlier{TradeID: "3", OrderID: "o2", ConnectionID: "c1", Symbol: "BTCUSDT", Side: "SELL", Qty: 2, FillPrice: 49, RefPrice: 100})
+	if !held || len(q.List()) != 2 {
+		t.Fatalf("second trade of o2: held=%v quarantine=%+v, want two fills", held, q.List())
+	}
+
+	// Releasing books the fill once; the re-published fill is not held again.
+	if _, ok := q.Release(out.FillID); !ok || len(booked) != 1 {
+		t.Fatalf("release of %s: ok=%v booked=%+v", out.FillID, ok, booked)
+	}
+	if _, held := q.Check(booked[0]); held {
+		t.Fatal("a released fill must not be quarantined again")
+	}
+	if left := q.List(); len(left) != 1 || left[0].FillID != second.FillID {
+		t.Fatalf("quarantine = %+v, want only %s", left, second.FillID)
+	}
+
+	// Discarded fills are dropped without booking.
+	if _, ok := q.Discard(second.FillID); !ok || len(booked) != 1 {
+		t.Fatalf("discard of %s: ok=%v booked=%+v", second.FillID, ok, booked)
+	}
+
+	// A fill without a trade ID is assigned one that its release carries.
+	anon, held := q.Check(FillOutlier{OrderID: "o3", Symbol: "ETHUSDT", Side: "BUY", Qty: 1, FillPrice: 300, RefPrice: 200})
+	if !held || anon.TradeID == "" {
+		t.Fatalf("fill without trade ID: held=%v fill=%+v", held, anon)
+	}
+	q.Release(anon.FillID)
+	if _, held := q.Check(booked[1]); held {
+		t.Fatal("a released fill without an exchange trade ID must not be quarantined again")
+	}
+}
+
+func TestFillQuarantineSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	q := NewFillQuarantine(10)
+	if err := q.Restore(ctx, database.DB); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	kept, _ := q.Check(FillOutlier{TradeID: "7", OrderID: "o1", Symbol: "BTCUSDT", Side: "BUY", Qty: 1, FillPrice: 150, RefPrice: 100})
+	dropped, _ := q.Check(FillOutlier{TradeID: "8", OrderID: "o2", Symbol: "BTCUSDT", Side: "BUY", Qty: 1, FillPrice: 150, RefPrice: 100})
+	q.Discard(dropped.FillID)
+
+	restarted := NewFillQuarantine(10)
+	if err := restarted.Restore(ctx, database.DB); err != nil {
+		t.Fatalf("Restore after restart: %v", err)
+	}
+	held := restarted.List()
+	if len(held) != 1 || held[0].FillID != kept.FillID || held[0].Qty != 1 {
+		t.Fatalf("restored quarantine = %+v, want %s", held, kept.FillID)
+	}
+	if _, ok := restarted.Release(kept.FillID); !ok {
+		t.Fatal("a restored fill should be releasable")
+	}
+	if again := NewFillQuarantine(10); again.Restore(ctx, database.DB) != nil || len(again.List()) != 0 {
+		t.Fatalf("released fill still stored: %+v", again.List())
+	}
+}
@@ -0,0 +1,134 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymbolHalt records an operator halt on one symbol.
+type SymbolHalt struct {
+	Symbol string    `json:"symbol"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// SymbolHalts is the runtime set of halted symbols. Like a maintenance window
+// it blocks new entries but lets exits through, only for single symbols (e.g.
+// a delisting). Halts are stored once Restore attached a DB, so they last
+// until an operator resumes the symbol. A nil set halts nothing.
+type SymbolHalts struct {
+	mu     sync.RWMutex
+	db     *sql.DB
+	halted map[string]SymbolHalt
+}
+
+// NewSymbolHalts creates an empty halt set.
+func NewSymbolHalts() *SymbolHalts {
+	return &SymbolHalts{halted: make(map[string]SymbolHalt)}
+}
+
+// Restore stores halts in db from now on and reloads the stored ones, so a
+// restart doesn't lift them.
+func (h *SymbolHalts) Restore(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT symbol, reason, since FROM symbol_halts`)
+	if err != nil {
+		return fmt.Errorf("load symbol halts: %w", err)
+	}
+	defer rows.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.db = db
+	for rows.Next() {
+		var halt SymbolHalt
+		if err := rows.Scan(&halt.Symbol, &halt.Reason, &halt.Since); err != nil {
+			return err
+		}
+		h.halted[halt.Symbol] = halt
+	}
+	return rows.Err()
+}
+
+// Halt blocks entries on symbol. Halting an already halted symbol keeps the
+// original time and updates the reason.
+func (h *SymbolHalts) Halt(symbol, reason string) SymbolHalt {
+	symbol = strings.ToUpper(symbol)
+	h.mu.Lock()
+	halt, ok := h.halted[symbol]
+	if !ok {
+		halt = SymbolHalt{Symbol: symbol, Since: time.Now().UTC()}
+	}
+	halt.Reason = reason
+	h.halted[symbol] = halt
+	db := h.db
+	h.mu.Unlock()
+
+	if db != nil {
+		_, err := db.Exec(`
+			INSERT INTO symbol_halts (symbol, reason, since) VALUES (?, ?, ?)
+			ON CONFLICT(symbol) DO UPDATE SET reason = excluded.reason
+		`, halt.Symbol, halt.Reason, halt.Since)
+		if err != nil {
+			log.Printf("⚠️ Persist halt of %s: %v", symbol, err)
+		}
+	}
+	return halt
+}
+
+// Resume lifts the halt on symbol and reports whether it was halted.
+func (h *SymbolHalts) Resume(symbol string) bool {
+	symbol = strings.ToUpper(symbol)
+	h.mu.Lock()
+	_, ok := h.halted[symbol]
+	delete(h.halted, symbol)
+	db := h.db
+	h.mu.Unlock()
+
+	if ok && db != nil {
+		if _, err := db.Exec(`DELETE FROM symbol_halts WHERE symbol = ?`, symbol); err != nil {
+			log.Printf("⚠️ Remove halt of %s: %v", symbol, err)
+		}
+	}
+	return ok
+}
+
+// Halted returns the halt on symbol, if any.
+func (h *SymbolHalts) Halted(symbol string) (SymbolHalt, bool) {
+	if h == nil {
+		return SymbolHalt{}, false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	halt, ok := h.halted[strings.ToUpper(symbol)]
+	return halt, ok
+}
+
+// List returns the halted symbols sorted by symbol.
+func (h *SymbolHalts) List() []SymbolHalt {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]SymbolHalt, 0, len(h.halted))
+	for _, halt := range h.halted {
+		out = append(out, halt)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// BlockReason returns why an order on symbol is rejected, or "" when the
+// symbol is not halted.
+func (halt SymbolHalt) BlockReason() string {
+	if halt.Reason == "" {
+		return fmt.Sprintf("symbol %s halted since %s", halt.Symbol, halt.Since.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("symbol %s halted since %s: %s", halt.Symbol, halt.Since.Format(time.RFC3339), halt.Reason)
+}
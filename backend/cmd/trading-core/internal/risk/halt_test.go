@@ -0,0 +1,70 @@
+package risk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"trading-core/pkg/db"
+)
+
+func TestSymbolHaltBlocksEntriesOnlyOnHaltedSymbol(t *testing.T) {
+	halts := NewSymbolHalts()
+	m := NewInMemory(DefaultConfig())
+	m.SetSymbolHalts(halts)
+	halts.Halt("ethusdt", "delisting")
+
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+	dec := m.EvaluateFull(SignalInput{Symbol: "ETHUSDT", Action: "BUY", Size: 0.1, Price: 2000}, Position{Symbol: "ETHUSDT"}, account, "s1")
+	if dec.Allowed || !strings.Contains(dec.Reason, "ETHUSDT halted") || !strings.Contains(dec.Reason, "delisting") {
+		t.Fatalf("entry on halted symbol should be rejected, got %+v", dec)
+	}
+
+	dec = m.EvaluateFull(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.01, Price: 30000}, Position{Symbol: "BTCUSDT"}, account, "s1")
+	if !dec.Allowed {
+		t.Fatalf("entry on another symbol should be allowed, got %+v", dec)
+	}
+
+	long := Position{Symbol: "ETHUSDT", Side: "LONG", Quantity: 0.1, CurrentPrice: 2000, EntryPrice: 2000}
+	dec = m.EvaluateFull(SignalInput{Symbol: "ETHUSDT", Action: "SELL", Size: 0.1, Price: 2000}, long, account, "s1")
+	if !dec.Allowed {
+		t.Fatalf("exit on halted symbol should be allowed, got %+v", dec)
+	}
+
+	if !halts.Resume("ETHUSDT") || halts.Resume("ETHUSDT") {
+		t.Fatal("Resume should report the halt only once")
+	}
+	dec = m.EvaluateFull(SignalInput{Symbol: "ETHUSDT", Action: "BUY", Size: 0.1, Price: 2000}, Position{Symbol: "ETHUSDT"}, account, "s1")
+	if !dec.Allowed {
+		t.Fatalf("entry after resume should be allowed, got %+v", dec)
+	}
+}
+
+func TestSymbolHaltsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	halts := NewSymbolHalts()
+	if err := halts.Restore(ctx, database.DB); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	halts.Halt("ethusdt", "delisting")
+	halts.Halt("SOLUSDT", "")
+	halts.Resume("SOLUSDT")
+
+	restarted := NewSymbolHalts()
+	if err := restarted.Restore(ctx, database.DB); err != nil {
+		t.Fatalf("Restore after restart: %v", err)
+	}
+	list := restarted.List()
+	if len(list) != 1 || list[0].Symbol != "ETHUSDT" || list[0].Reason != "delisting" {
+		t.Fatalf("restored halts = %+v, want ETHUSDT only", list)
+	}
+}
@@ -0,0 +1,309 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+// LiquidationAccount is one futures account watched by the LiquidationMonitor.
+type LiquidationAccount struct {
+	UserID       string // empty for the global gateway
+	ConnectionID string
+	ExchangeType string // binance-usdtfut / binance-coinfut
+	Reporter     exchange.MarginReporter
+}
+
+// LiquidationAccountSource lists the accounts to check on each pass.
+type LiquidationAccountSource func(ctx context.Context) ([]LiquidationAccount, error)
+
+// LiquidationConfig controls when the monitor alerts and whether it reduces.
+type LiquidationConfig struct {
+	BufferPct      float64 // alert when mark is within this % of the liquidation price
+	AutoReduce     bool
+	ReduceFraction float64 // share of the position to close when reducing (0-1]
+	// TopUpMax is the most margin (in the position's margin asset) added to an
+	// isolated position per stay in the buffer before falling back to reducing;
+	// 0 disables top-ups.
+	TopUpMax float64
+	Interval time.Duration
+}
+
+// ReduceRequest asks the caller to close part of a position with a reduce-only
+// market order.
+type ReduceRequest struct {
+	UserID       string
+	ConnectionID string
+	ExchangeType string
+	Symbol       string
+	Side         string // BUY closes a short, SELL closes a long
+	PositionSide string // LONG/SHORT in hedge mode, empty in one-way mode
+	Qty          float64
+}
+
+// LiquidationAlert reports a position near liquidation or a margin top-up.
+type LiquidationAlert struct {
+	Kind         string // liquidation_risk or liquidation_margin_added
+	UserID       string
+	ConnectionID string
+	Symbol       string
+	Message      string
+}
+
+// LiquidationMonitor periodically checks every open futures position's
+// distance to its liquidation price and raises an alert once per entry into
+// the buffer. While a position stays inside, isolated positions are topped up
+// with margin (up to TopUpMax and the available balance) and, once that is
+// exhausted, reduced if AutoReduce is set.
+type LiquidationMonitor struct {
+	accounts LiquidationAccountSource
+	cfg      LiquidationConfig
+	alertFn  func(LiquidationAlert)
+	reduceFn func(ReduceRequest)
+	markFn   func(symbol string, mark float64)
+
+	mu      sync.Mutex
+	flagged map[string]*liquidationState // positions currently inside the buffer
+}
+
+// liquidationState is what the monitor has done for a position in the buffer.
+type liquidationState struct {
+	toppedUp float64
+	reduced  bool
+}
+
+// NewLiquidationMonitor creates a monitor over the given accounts.
+func NewLiquidationMonitor(accounts LiquidationAccountSource, cfg LiquidationConfig) *LiquidationMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.ReduceFraction <= 0 || cfg.ReduceFraction > 1 {
+		cfg.ReduceFraction = 0.5
+	}
+	return &LiquidationMonitor{
+		accounts: accounts,
+		cfg:      cfg,
+		flagged:  make(map[string]*liquidationState),
+	}
+}
+
+// SetAlertFn sets the callback for liquidation-risk alerts.
+func (m *LiquidationMonitor) SetAlertFn(fn func(LiquidationAlert)) {
+	m.alertFn = fn
+}
+
+// SetMarkFn sets a callback receiving the mark price of every position seen
+// (e.g. to keep the price cache's mark prices current).
+func (m *LiquidationMonitor) SetMarkFn(fn func(symbol string, mark float64)) {
+	m.markFn = fn
+}
+
+// SetReduceFn sets the callback that enqueues reduce orders (used only when
+// AutoReduce is enabled).
+func (m *LiquidationMonitor) SetReduceFn(fn func(ReduceRequest)) {
+	m.reduceFn = fn
+}
+
+// Start runs Check every Interval until ctx is cancelled.
+func (m *LiquidationMonitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Check(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Check runs one pass over all accounts.
+func (m *LiquidationMonitor) Check(ctx context.Context) {
+	accounts, err := m.accounts(ctx)
+	if err != nil {
+		log.Printf("⚠️ Liquidation monitor: list accounts failed: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, acct := range accounts {
+		if acct.Reporter == nil {
+			continue
+		}
+		margin, err := acct.Reporter.MarginAccount(ctx)
+		if err != nil {
+			log.Printf("⚠️ Liquidation monitor: margin for %s failed: %v", accountLabel(acct), err)
+			continue
+		}
+		for _, p := range margin.Positions {
+			if m.markFn != nil && p.MarkPrice > 0 {
+				m.markFn(p.Symbol, p.MarkPrice)
+			}
+			key := acct.ConnectionID + "|" + p.Symbol + "|" + p.PositionSide
+			seen[key] = true
+			m.checkPosition(ctx, acct, margin, key, p)
+		}
+	}
+
+	// Closed positions leave the buffer too.
+	m.mu.Lock()
+	for key := range m.flagged {
+		if !seen[key] {
+			delete(m.flagged, key)
+		}
+	}
+	m.mu.Unlock()
+}
+
+func (m *LiquidationMonitor) checkPosition(ctx context.Context, acct LiquidationAccount, margin exchange.MarginAccount, key string, p exchange.MarginPosition) {
+	dist, ok := LiquidationDistancePct(p)
+	inside := ok && dist <= m.cfg.BufferPct
+
+	m.mu.Lock()
+	state := m.flagged[key]
+	if !inside {
+		delete(m.flagged, key)
+		m.mu.Unlock()
+		return
+	}
+	entered := state == nil
+	if entered {
+		state = &liquidationState{}
+		m.flagged[key] = state
+	}
+	m.mu.Unlock()
+
+	if entered {
+		m.alert("liquidation_risk", acct, p.Symbol, fmt.Sprintf("Liquidation risk: %s %s %.8g @ mark %.8g is %.2f%% from liquidation %.8g (%s)",
+			p.Symbol, positionDirection(p.Qty), math.Abs(p.Qty), p.MarkPrice, dist, p.LiquidationPrice, accountLabel(acct)))
+	}
+	if state.reduced {
+		return
+	}
+	if m.topUp(ctx, acct, margin, p, state) {
+		return
+	}
+
+	if !m.cfg.AutoReduce || m.reduceFn == nil {
+		return
+	}
+	req := ReduceRequest{
+		UserID:       acct.UserID,
+		ConnectionID: acct.ConnectionID,
+		ExchangeType: acct.ExchangeType,
+		Symbol:       p.Symbol,
+		Side:         "SELL",
+		Qty:          math.Abs(p.Qty) * m.cfg.ReduceFraction,
+	}
+	if p.Qty < 0 {
+		req.Side = "BUY"
+	}
+	if p.PositionSide == "LONG" || p.PositionSide == "SHORT" {
+		req.PositionSide = p.PositionSide
+	}
+	state.reduced = true
+	m.reduceFn(req)
+}
+
+// topUp adds margin to an isolated position, bounded by what is left of
+// TopUpMax and the available balance of its margin asset. It reports whether
+// margin was added; false means the caller should fall back to reducing.
+func (m *LiquidationMonitor) topUp(ctx context.Context, acct LiquidationAccount, margin exchange.MarginAccount, p exchange.MarginPosition, state *liquidationState) bool {
+	if m.cfg.TopUpMax <= 0 || !strings.EqualFold(p.MarginType, "isolated") {
+		return false
+	}
+	adjuster, ok := acct.Reporter.(exchange.PositionMarginAdjuster)
+	if !ok {
+		return false
+	}
+
+	amount := m.cfg.TopUpMax - state.toppedUp
+	if need := m.topUpNeeded(acct, p); need > 0 && need < amount {
+		amount = need
+	}
+	if avail := availableMargin(margin, p.MarginAsset); avail < amount {
+		amount = avail
+	}
+	if amount <= 0 {
+		return false
+	}
+
+	positionSide := ""
+	if p.PositionSide == "LONG" || p.PositionSide == "SHORT" {
+		positionSide = p.PositionSide
+	}
+	if err := adjuster.ChangePositionMargin(ctx, p.Symbol, positionSide, amount, 1); err != nil {
+		log.Printf("⚠️ Liquidation monitor: add margin to %s failed: %v", p.Symbol, err)
+		return false
+	}
+	state.toppedUp += amount
+	m.alert("liquidation_margin_added", acct, p.Symbol, fmt.Sprintf("Liquidation guard: added %.8g %s margin to %s (%.8g of %.8g cap used, %s)",
+		amount, p.MarginAsset, p.Symbol, state.toppedUp, m.cfg.TopUpMax, accountLabel(acct)))
+	return true
+}
+
+// topUpNeeded estimates the margin that moves a linear (USDT-M) position's
+// liquidation price out to twice the buffer. COIN-M margin depends on the
+// contract size, so it returns 0 there and the remaining cap is used.
+func (m *LiquidationMonitor) topUpNeeded(acct LiquidationAccount, p exchange.MarginPosition) float64 {
+	if acct.ExchangeType == "binance-coinfut" {
+		return 0
+	}
+	target := p.MarkPrice * (1 - 2*m.cfg.BufferPct/100)
+	if p.Qty < 0 {
+		target = p.MarkPrice * (1 + 2*m.cfg.BufferPct/100)
+	}
+	return math.Abs(p.Qty) * math.Abs(p.LiquidationPrice-target)
+}
+
+func (m *LiquidationMonitor) alert(kind string, acct LiquidationAccount, symbol, msg string) {
+	log.Printf("⚠️ %s", msg)
+	if m.alertFn != nil {
+		m.alertFn(LiquidationAlert{Kind: kind, UserID: acct.UserID, ConnectionID: acct.ConnectionID, Symbol: symbol, Message: msg})
+	}
+}
+
+func availableMargin(margin exchange.MarginAccount, asset string) float64 {
+	for _, a := range margin.Assets {
+		if strings.EqualFold(a.Asset, asset) {
+			return a.AvailableBalance
+		}
+	}
+	return 0
+}
+
+// LiquidationDistancePct returns how far the mark price is from the
+// liquidation price, as a percentage of the mark price, in the adverse
+// direction. ok is false when the venue reports no liquidation price.
+func LiquidationDistancePct(p exchange.MarginPosition) (float64, bool) {
+	if p.LiquidationPrice <= 0 || p.MarkPrice <= 0 || p.Qty == 0 {
+		return 0, false
+	}
+	if p.Qty > 0 {
+		return (p.MarkPrice - p.LiquidationPrice) / p.MarkPrice * 100, true
+	}
+	return (p.LiquidationPrice - p.MarkPrice) / p.MarkPrice * 100, true
+}
+
+func positionDirection(qty float64) string {
+	if qty < 0 {
+		return "SHORT"
+	}
+	return "LONG"
+}
+
+func accountLabel(acct LiquidationAccount) string {
+	if acct.UserID == "" {
+		return "default account"
+	}
+	return fmt.Sprintf("user %s connection %s", acct.UserID, acct.ConnectionID)
+}
@@ -0,0 +1,157 @@
+package risk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	exchange "trading-core/pkg/exchanges/common"
+)
+
+type stubMarginReporter struct {
+	acct exchange.MarginAccount
+}
+
+func (s *stubMarginReporter) MarginAccount(ctx context.Context) (exchange.MarginAccount, error) {
+	return s.acct, nil
+}
+
+func TestLiquidationMonitorAlertsAndReducesNearLiquidation(t *testing.T) {
+	reporter := &stubMarginReporter{acct: exchange.MarginAccount{Positions: []exchange.MarginPosition{
+		// 2% above liquidation: inside a 5% buffer.
+		{Symbol: "BTCUSDT", PositionSide: "BOTH", Qty: 0.4, MarkPrice: 50000, LiquidationPrice: 49000},
+		// 20% below liquidation: safe.
+		{Symbol: "ETHUSDT", PositionSide: "BOTH", Qty: -2, MarkPrice: 2500, LiquidationPrice: 3000},
+	}}}
+	accounts := func(ctx context.Context) ([]LiquidationAccount, error) {
+		return []LiquidationAccount{{UserID: "u1", ConnectionID: "c1", ExchangeType: "binance-usdtfut", Reporter: reporter}}, nil
+	}
+
+	m := NewLiquidationMonitor(accounts, LiquidationConfig{BufferPct: 5, AutoReduce: true, ReduceFraction: 0.5})
+	var alerts []string
+	var reduces []ReduceRequest
+	m.SetAlertFn(func(a LiquidationAlert) { alerts = append(alerts, a.Message) })
+	m.SetReduceFn(func(req ReduceRequest) { reduces = append(reduces, req) })
+
+	m.Check(context.Background())
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "BTCUSDT") {
+		t.Fatalf("expected one BTCUSDT alert, got %v", alerts)
+	}
+	want := ReduceRequest{UserID: "u1", ConnectionID: "c1", ExchangeType: "binance-usdtfut", Symbol: "BTCUSDT", Side: "SELL", Qty: 0.2}
+	if len(reduces) != 1 || reduces[0] != want {
+		t.Fatalf("reduce requests = %+v, want [%+v]", reduces, want)
+	}
+
+	// Still inside the buffer: no repeat.
+	m.Check(context.Background())
+	if len(alerts) != 1 || len(reduces) != 1 {
+		t.Fatalf("alert repeated while position stayed in buffer: %d alerts, %d reduces", len(alerts), len(reduces))
+	}
+
+	// Leaves the buffer, then re-enters as a hedge-mode short.
+	reporter.acct.Positions[0].MarkPrice = 60000
+	m.Check(context.Background())
+	reporter.acct.Positions[0] = exchange.MarginPosition{Symbol: "BTCUSDT", PositionSide: "SHORT", Qty: -1, MarkPrice: 50000, LiquidationPrice: 51000}
+	m.Check(context.Background())
+	if len(alerts) != 2 || len(reduces) != 2 {
+		t.Fatalf("expected a second alert after re-entering the buffer, got %d alerts, %d reduces", len(alerts), len(reduces))
+	}
+	if r := reduces[1]; r.Side != "BUY" || r.PositionSide != "SHORT" || r.Qty != 0.5 {
+		t.Fatalf("short reduce = %+v, want BUY SHORT 0.5", r)
+	}
+}
+
+func TestLiquidationMonitorAlertOnlyWithoutAutoReduce(t *testing.T) {
+	reporter := &stubMarginReporter{acct: exchange.MarginAccount{Positions: []exchange.MarginPosition{
+		{Symbol: "BTCUSD_PERP", Qty: 3, MarkPrice: 50000, LiquidationPrice: 48500},
+	}}}
+	accounts := func(ctx context.Context) ([]LiquidationAccount, error) {
+		return []LiquidationAccount{{Reporter: reporter}}, nil
+	}
+	m := NewLiquidationMonitor(accounts, LiquidationConfig{BufferPct: 5})
+	var alerts, reduces int
+	m.SetAlertFn(func(LiquidationAlert) { alerts++ })
+	m.SetReduceFn(func(ReduceRequest) { reduces++ })
+
+	m.Check(context.Background())
+	if alerts != 1 || reduces != 0 {
+		t.Fatalf("got %d alerts and %d reduces, want 1 and 0", alerts, reduces)
+	}
+}
+
+// stubFuturesClient is a MarginReporter that also records margin top-ups.
+type stubFuturesClient struct {
+	stubMarginReporter
+	added []float64
+	sides []string
+}
+
+func (s *stubFuturesClient) ChangePositionMargin(ctx context.Context, symbol, positionSide string, amount float64, mType int) error {
+	if mType != 1 {
+		return nil
+	}
+	s.added = append(s.added, amount)
+	s.sides = append(s.sides, positionSide)
+	return nil
+}
+
+func TestLiquidationMonitorTopsUpIsolatedMarginBeforeReducing(t *testing.T) {
+	client := &stubFuturesClient{stubMarginReporter: stubMarginReporter{acct: exchange.MarginAccount{
+		Assets: []exchange.MarginAsset{{Asset: "USDT", AvailableBalance: 1000}},
+		Positions: []exchange.MarginPosition{
+			// 2% from liquidation; moving it to 10% (twice the buffer) takes 0.1 * (49000-45000) = 400 USDT.
+			{Symbol: "BTCUSDT", PositionSide: "BOTH", Qty: 0.1, MarkPrice: 50000, LiquidationPrice: 49000, MarginType: "isolated", MarginAsset: "USDT"},
+		},
+	}}}
+	accounts := func(ctx context.Context) ([]LiquidationAccount, error) {
+		return []LiquidationAccount{{UserID: "u1", ConnectionID: "c1", ExchangeType: "binance-usdtfut", Reporter: client}}, nil
+	}
+	m := NewLiquidationMonitor(accounts, LiquidationConfig{BufferPct: 5, AutoReduce: true, TopUpMax: 500})
+	var reduces []ReduceRequest
+	m.SetReduceFn(func(req ReduceRequest) { reduces = append(reduces, req) })
+
+	m.Check(context.Background())
+	if len(client.added) != 1 || client.added[0] != 400 || client.sides[0] != "" {
+		t.Fatalf("margin added = %v on sides %q, want [400] without a position side", client.added, client.sides)
+	}
+	if len(reduces) != 0 {
+		t.Fatalf("position reduced although margin was added: %+v", reduces)
+	}
+
+	// Still in the buffer: only the remaining 100 of the cap is added, then it reduces.
+	m.Check(context.Background())
+	if len(client.added) != 2 || client.added[1] != 100 {
+		t.Fatalf("margin added = %v, want [400 100]", client.added)
+	}
+	m.Check(context.Background())
+	if len(client.added) != 2 || len(reduces) != 1 {
+		t.Fatalf("expected a reduce once the cap was used, got %v added and %d reduces", client.added, len(reduces))
+	}
+
+	// Top-ups never exceed the available balance.
+	client.acct.Assets[0].AvailableBalance = 50
+	m2 := NewLiquidationMonitor(accounts, LiquidationConfig{BufferPct: 5, TopUpMax: 500})
+	client.added = nil
+	m2.Check(context.Background())
+	if len(client.added) != 1 || client.added[0] != 50 {
+		t.Fatalf("margin added = %v, want [50]", client.added)
+	}
+}
+
+func TestLiquidationMonitorTopUpPassesHedgePositionSide(t *testing.T) {
+	client := &stubFuturesClient{stubMarginReporter: stubMarginReporter{acct: exchange.MarginAccount{
+		Assets: []exchange.MarginAsset{{Asset: "USDT", AvailableBalance: 1000}},
+		Positions: []exchange.MarginPosition{
+			{Symbol: "BTCUSDT", PositionSide: "SHORT", Qty: -0.1, MarkPrice: 50000, LiquidationPrice: 51000, MarginType: "isolated", MarginAsset: "USDT"},
+		},
+	}}}
+	accounts := func(ctx context.Context) ([]LiquidationAccount, error) {
+		return []LiquidationAccount{{UserID: "u1", ConnectionID: "c1", ExchangeType: "binance-usdtfut", Reporter: client}}, nil
+	}
+	m := NewLiquidationMonitor(accounts, LiquidationConfig{BufferPct: 5, TopUpMax: 500})
+
+	m.Check(context.Background())
+	if len(client.sides) != 1 || client.sides[0] != "SHORT" {
+		t.Fatalf("margin top-up sides = %q, want [SHORT]", client.sides)
+	}
+}
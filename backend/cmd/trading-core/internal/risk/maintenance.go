@@ -0,0 +1,142 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow is a scheduled period during which new entries are blocked.
+// With Every > 0 the window repeats at that period starting from Start
+// (e.g. 24h for a daily window, 168h for a weekly one).
+type MaintenanceWindow struct {
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Every  time.Duration `json:"every,omitempty"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// ActiveAt reports whether t falls inside the window (or one of its repeats).
+func (w MaintenanceWindow) ActiveAt(t time.Time) bool {
+	length := w.End.Sub(w.Start)
+	if length <= 0 || t.Before(w.Start) {
+		return false
+	}
+	if w.Every <= 0 {
+		return t.Before(w.End)
+	}
+	return t.Sub(w.Start)%w.Every < length
+}
+
+// endAfter returns the end of the occurrence containing t.
+func (w MaintenanceWindow) endAfter(t time.Time) time.Time {
+	if w.Every <= 0 {
+		return w.End
+	}
+	start := w.Start.Add(t.Sub(w.Start) / w.Every * w.Every)
+	return start.Add(w.End.Sub(w.Start))
+}
+
+// MaintenanceSchedule holds the configured windows. Unlike an instant kill
+// switch it is planned ahead: entries are rejected while a window is active,
+// exits are still allowed. A nil schedule is never active.
+type MaintenanceSchedule struct {
+	mu      sync.RWMutex
+	windows []MaintenanceWindow
+	now     func() time.Time
+}
+
+// NewMaintenanceSchedule creates a schedule with the given windows.
+func NewMaintenanceSchedule(windows ...MaintenanceWindow) *MaintenanceSchedule {
+	return &MaintenanceSchedule{windows: windows, now: time.Now}
+}
+
+// Set replaces the configured windows.
+func (s *MaintenanceSchedule) Set(windows []MaintenanceWindow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = append([]MaintenanceWindow(nil), windows...)
+}
+
+// Windows returns a copy of the configured windows.
+func (s *MaintenanceSchedule) Windows() []MaintenanceWindow {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]MaintenanceWindow(nil), s.windows...)
+}
+
+// Active returns the window in effect now, with End set to the end of the
+// current occurrence.
+func (s *MaintenanceSchedule) Active() (MaintenanceWindow, bool) {
+	if s == nil {
+		return MaintenanceWindow{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	for _, w := range s.windows {
+		if w.ActiveAt(now) {
+			end := w.endAfter(now)
+			w.Start = end.Add(-w.End.Sub(w.Start))
+			w.End = end
+			return w, true
+		}
+	}
+	return MaintenanceWindow{}, false
+}
+
+// IsExit reports whether acting on a signal reduces the existing position.
+func IsExit(action string, position Position) bool {
+	switch strings.ToUpper(action) {
+	case "SELL":
+		return position.Side == "LONG"
+	case "BUY":
+		return position.Side == "SHORT"
+	}
+	return false
+}
+
+// ParseMaintenanceWindows parses a comma-separated list of
+// "start/end[/every]" windows, with RFC3339 times and a Go duration for the
+// optional repeat period, e.g. "2026-01-03T02:00:00Z/2026-01-03T04:00:00Z/168h".
+func ParseMaintenanceWindows(spec string) ([]MaintenanceWindow, error) {
+	var out []MaintenanceWindow
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("maintenance window %q: want start/end[/every]", item)
+		}
+		start, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %q: start: %w", item, err)
+		}
+		end, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %q: end: %w", item, err)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("maintenance window %q: end must be after start", item)
+		}
+		w := MaintenanceWindow{Start: start, End: end, Reason: "scheduled maintenance"}
+		if len(parts) == 3 {
+			every, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %q: every: %w", item, err)
+			}
+			if every < end.Sub(start) {
+				return nil, fmt.Errorf("maintenance window %q: repeat period shorter than the window", item)
+			}
+			w.Every = every
+		}
+		out = append(out, w)
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowBlocksEntriesAllowsExits(t *testing.T) {
+	now := time.Now()
+	sched := NewMaintenanceSchedule(MaintenanceWindow{
+		Start:  now.Add(-time.Minute),
+		End:    now.Add(time.Hour),
+		Reason: "exchange upgrade",
+	})
+	m := NewInMemory(DefaultConfig())
+	m.SetMaintenance(sched)
+
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+	flat := Position{Symbol: "BTCUSDT"}
+	dec := m.EvaluateFull(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.01, Price: 30000}, flat, account, "s1")
+	if dec.Allowed || !strings.Contains(dec.Reason, "maintenance") {
+		t.Fatalf("entry during maintenance should be rejected, got %+v", dec)
+	}
+
+	long := Position{Symbol: "BTCUSDT", Side: "LONG", Quantity: 0.01, CurrentPrice: 30000, EntryPrice: 30000}
+	dec = m.EvaluateFull(SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.01, Price: 30000}, long, account, "s1")
+	if !dec.Allowed {
+		t.Fatalf("exit during maintenance should be allowed, got %+v", dec)
+	}
+
+	sched.Set(nil)
+	dec = m.EvaluateFull(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.01, Price: 30000}, flat, account, "s1")
+	if !dec.Allowed {
+		t.Fatalf("entry outside maintenance should be allowed, got %+v", dec)
+	}
+}
+
+func TestParseRecurringMaintenanceWindow(t *testing.T) {
+	windows, err := ParseMaintenanceWindows("2026-01-03T02:00:00Z/2026-01-03T04:00:00Z/168h")
+	if err != nil || len(windows) != 1 {
+		t.Fatalf("ParseMaintenanceWindows: %v, %v", windows, err)
+	}
+	w := windows[0]
+	nextWeek := time.Date(2026, 1, 10, 3, 0, 0, 0, time.UTC)
+	if !w.ActiveAt(nextWeek) {
+		t.Error("weekly window should repeat a week later")
+	}
+	if w.ActiveAt(nextWeek.Add(2 * time.Hour)) {
+		t.Error("window should end after two hours")
+	}
+	if _, err := ParseMaintenanceWindows("2026-01-03T04:00:00Z/2026-01-03T02:00:00Z"); err == nil {
+		t.Error("expected error for end before start")
+	}
+}
@@ -3,23 +3,38 @@ package risk
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
 	"strings"
 	"sync"
 	"time"
+
+	"trading-core/pkg/money"
 )
 
 // Manager handles risk configuration, evaluation, and metrics persistence.
 type Manager struct {
 	db              *sql.DB
+	store           *sql.DB // per-user managers: persists their config and strategy configs (db is nil for them)
+	userID          string  // owner of a per-user manager ("" for the global one)
 	config          *RiskConfig
 	metrics         *RiskMetrics
 	strategyConfigs map[string]*StrategyRiskConfig // Per-strategy config cache
+	maintenance     *MaintenanceSchedule           // optional; blocks entries while active
+	halts           *SymbolHalts                   // optional; blocks entries on halted symbols
+	streaks         *LossStreaks                   // optional; pauses strategies on losing streaks
+	holdings        HoldingsFunc                   // optional; strategy positions for allocation checks
+	connLimits      ConnectionLimitsFunc           // optional; per-connection limits
+	connections     map[string]*connectionBook     // per-connection books, loaded from the DB on first use
 	mu              sync.RWMutex
 }
 
+// HoldingsFunc returns a strategy's current position (signed qty and average
+// entry price) from its strategy_positions row; zeros if it holds nothing.
+type HoldingsFunc func(strategyID string) (qty, avgPrice float64, err error)
+
 // NewManager creates a new risk manager backed by the DB.
 // If no active config exists it inserts DefaultConfig.
 func NewManager(db *sql.DB) (*Manager, error) {
@@ -58,6 +73,35 @@ func NewInMemory(cfg RiskConfig) *Manager {
 	}
 }
 
+// NewUserManager creates a user's risk manager. Its risk config is the one
+// the user saved in db, or cfg if none; config updates and the strategy
+// configs set on it persist in db so they survive restarts and idle eviction.
+// A nil db keeps everything in memory like NewInMemory.
+func NewUserManager(db *sql.DB, userID string, cfg RiskConfig) (*Manager, error) {
+	if db != nil {
+		saved, ok, err := loadUserConfig(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			cfg = saved
+		}
+	}
+	mgr := NewInMemory(cfg)
+	mgr.store = db
+	mgr.userID = userID
+	return mgr, nil
+}
+
+// persistDB is where strategy configs and connection books persist: the
+// global DB or a user manager's store.
+func (m *Manager) persistDB() *sql.DB {
+	if m.db != nil {
+		return m.db
+	}
+	return m.store
+}
+
 // LoadConfig loads active risk configuration from DB or falls back to default.
 func (m *Manager) LoadConfig() error {
 	m.mu.Lock()
@@ -75,7 +119,8 @@ func (m *Manager) LoadConfig() error {
 		       default_stop_loss, default_take_profit, use_trailing_stop, trailing_percent,
 		       max_daily_loss, max_daily_trades, min_order_size, max_order_size, max_slippage,
 		       use_daily_trade_limit, use_daily_loss_limit, use_order_size_limits, use_position_size_limit,
-		       is_active, created_at, updated_at
+		       symbol_position_limits, correlation_groups, COALESCE(sizing_mode, 'fixed'), COALESCE(risk_per_trade, 0.01),
+		       COALESCE(max_concurrent_positions, 0), symbol_sltp, is_active, created_at, updated_at
 		FROM risk_configs
 		WHERE is_active = 1
 		LIMIT 1
@@ -85,6 +130,7 @@ func (m *Manager) LoadConfig() error {
 		useTrailing                                          int
 		useDailyTrades, useDailyLoss, useOrderSize, usePosSz int
 		isActive                                             int
+		symbolLimits, corrGroups, symbolSLTP                 sql.NullString
 	)
 
 	err := m.db.QueryRow(query).Scan(
@@ -106,6 +152,12 @@ func (m *Manager) LoadConfig() error {
 		&useDailyLoss,
 		&useOrderSize,
 		&usePosSz,
+		&symbolLimits,
+		&corrGroups,
+		&cfg.SizingMode,
+		&cfg.RiskPerTrade,
+		&cfg.MaxConcurrentPositions,
+		&symbolSLTP,
 		&isActive,
 		&cfg.CreatedAt,
 		&cfg.UpdatedAt,
@@ -120,6 +172,21 @@ func (m *Manager) LoadConfig() error {
 	cfg.UseOrderSizeLimits = useOrderSize == 1
 	cfg.UsePositionSizeLimit = usePosSz == 1
 	cfg.IsActive = isActive == 1
+	if symbolLimits.Valid && symbolLimits.String != "" {
+		if err := json.Unmarshal([]byte(symbolLimits.String), &cfg.SymbolPositionLimits); err != nil {
+			return fmt.Errorf("decode symbol position limits: %w", err)
+		}
+	}
+	if corrGroups.Valid && corrGroups.String != "" {
+		if err := json.Unmarshal([]byte(corrGroups.String), &cfg.CorrelationGroups); err != nil {
+			return fmt.Errorf("decode correlation groups: %w", err)
+		}
+	}
+	if symbolSLTP.Valid && symbolSLTP.String != "" {
+		if err := json.Unmarshal([]byte(symbolSLTP.String), &cfg.SymbolSLTP); err != nil {
+			return fmt.Errorf("decode symbol sl/tp overrides: %w", err)
+		}
+	}
 
 	m.config = cfg
 	return nil
@@ -130,14 +197,27 @@ func (m *Manager) insertDefaultConfig(cfg RiskConfig) error {
 		m.config = &cfg
 		return nil
 	}
-	_, err := m.db.Exec(`
+	symbolLimits, err := encodeSymbolLimits(cfg.SymbolPositionLimits)
+	if err != nil {
+		return err
+	}
+	corrGroups, err := encodeCorrelationGroups(cfg.CorrelationGroups)
+	if err != nil {
+		return err
+	}
+	symbolSLTP, err := encodeSymbolSLTP(cfg.SymbolSLTP)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.Exec(`
 		INSERT INTO risk_configs (
 			name, max_position_size, max_total_exposure, default_leverage,
 			default_stop_loss, default_take_profit, use_trailing_stop, trailing_percent,
 			max_daily_loss, max_daily_trades, min_order_size, max_order_size, max_slippage,
 			use_daily_trade_limit, use_daily_loss_limit, use_order_size_limits, use_position_size_limit,
-			is_active, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			symbol_position_limits, correlation_groups, sizing_mode, risk_per_trade, max_concurrent_positions,
+			symbol_sltp, is_active, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`,
 		cfg.Name,
 		cfg.MaxPositionSize,
@@ -156,6 +236,12 @@ func (m *Manager) insertDefaultConfig(cfg RiskConfig) error {
 		boolToInt(cfg.UseDailyLossLimit),
 		boolToInt(cfg.UseOrderSizeLimits),
 		boolToInt(cfg.UsePositionSizeLimit),
+		symbolLimits,
+		corrGroups,
+		cfg.SizingMode,
+		cfg.RiskPerTrade,
+		cfg.MaxConcurrentPositions,
+		symbolSLTP,
 	)
 	return err
 }
@@ -167,6 +253,84 @@ func boolToInt(b bool) int {
 	return 0
 }
 
+// encodeSymbolLimits stores per-symbol overrides as a JSON object; nil when empty.
+func encodeSymbolLimits(limits map[string]float64) (any, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(limits)
+	if err != nil {
+		return nil, fmt.Errorf("encode symbol position limits: %w", err)
+	}
+	return string(b), nil
+}
+
+// encodeSymbolSLTP stores per-symbol SL/TP overrides as a JSON object; nil when empty.
+func encodeSymbolSLTP(overrides map[string]SymbolSLTP) (any, error) {
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("encode symbol sl/tp overrides: %w", err)
+	}
+	return string(b), nil
+}
+
+// encodeCorrelationGroups stores correlation groups as a JSON array; nil when empty.
+func encodeCorrelationGroups(groups []CorrelationGroup) (any, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(groups)
+	if err != nil {
+		return nil, fmt.Errorf("encode correlation groups: %w", err)
+	}
+	return string(b), nil
+}
+
+// normalizeCorrelationGroups upper-cases group symbols so they match signals.
+func normalizeCorrelationGroups(groups []CorrelationGroup) []CorrelationGroup {
+	if len(groups) == 0 {
+		return nil
+	}
+	out := make([]CorrelationGroup, len(groups))
+	for i, g := range groups {
+		syms := make([]string, 0, len(g.Symbols))
+		for _, sym := range g.Symbols {
+			if sym = strings.ToUpper(strings.TrimSpace(sym)); sym != "" {
+				syms = append(syms, sym)
+			}
+		}
+		out[i] = CorrelationGroup{Name: strings.TrimSpace(g.Name), Symbols: syms, MaxExposure: g.MaxExposure}
+	}
+	return out
+}
+
+// normalizeSymbolLimits upper-cases symbol keys so lookups are case-insensitive.
+func normalizeSymbolLimits(limits map[string]float64) map[string]float64 {
+	if len(limits) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(limits))
+	for sym, limit := range limits {
+		out[strings.ToUpper(strings.TrimSpace(sym))] = limit
+	}
+	return out
+}
+
+// normalizeSymbolSLTP upper-cases symbol keys so lookups are case-insensitive.
+func normalizeSymbolSLTP(overrides map[string]SymbolSLTP) map[string]SymbolSLTP {
+	if len(overrides) == 0 {
+		return nil
+	}
+	out := make(map[string]SymbolSLTP, len(overrides))
+	for sym, o := range overrides {
+		out[strings.ToUpper(strings.TrimSpace(sym))] = o
+	}
+	return out
+}
+
 // GetConfig returns a copy of current config.
 func (m *Manager) GetConfig() RiskConfig {
 	m.mu.RLock()
@@ -179,7 +343,18 @@ func (m *Manager) UpdateConfig(ctx context.Context, cfg RiskConfig) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	cfg.SymbolPositionLimits = normalizeSymbolLimits(cfg.SymbolPositionLimits)
+	cfg.CorrelationGroups = normalizeCorrelationGroups(cfg.CorrelationGroups)
+	cfg.SymbolSLTP = normalizeSymbolSLTP(cfg.SymbolSLTP)
+	if cfg.SizingMode == "" {
+		cfg.SizingMode = SizingFixed
+	}
 	if m.db == nil {
+		if m.store != nil && m.userID != "" {
+			if err := saveUserConfig(ctx, m.store, m.userID, cfg); err != nil {
+				return err
+			}
+		}
 		m.config = &cfg
 		return nil
 	}
@@ -192,7 +367,8 @@ func (m *Manager) UpdateConfig(ctx context.Context, cfg RiskConfig) error {
 		    min_order_size = ?, max_order_size = ?, max_slippage = ?,
 		    use_daily_trade_limit = ?, use_daily_loss_limit = ?,
 		    use_order_size_limits = ?, use_position_size_limit = ?,
-		    updated_at = CURRENT_TIMESTAMP
+		    symbol_position_limits = ?, correlation_groups = ?, sizing_mode = ?, risk_per_trade = ?,
+		    max_concurrent_positions = ?, symbol_sltp = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND is_active = 1
 	`
 
@@ -201,8 +377,20 @@ func (m *Manager) UpdateConfig(ctx context.Context, cfg RiskConfig) error {
 	useDailyLoss := boolToInt(cfg.UseDailyLossLimit)
 	useOrderSize := boolToInt(cfg.UseOrderSizeLimits)
 	usePosSize := boolToInt(cfg.UsePositionSizeLimit)
+	symbolLimits, err := encodeSymbolLimits(cfg.SymbolPositionLimits)
+	if err != nil {
+		return err
+	}
+	corrGroups, err := encodeCorrelationGroups(cfg.CorrelationGroups)
+	if err != nil {
+		return err
+	}
+	symbolSLTP, err := encodeSymbolSLTP(cfg.SymbolSLTP)
+	if err != nil {
+		return err
+	}
 
-	_, err := m.db.ExecContext(ctx, query,
+	_, err = m.db.ExecContext(ctx, query,
 		cfg.MaxPositionSize,
 		cfg.MaxTotalExposure,
 		cfg.DefaultLeverage,
@@ -219,6 +407,12 @@ func (m *Manager) UpdateConfig(ctx context.Context, cfg RiskConfig) error {
 		useDailyLoss,
 		useOrderSize,
 		usePosSize,
+		symbolLimits,
+		corrGroups,
+		cfg.SizingMode,
+		cfg.RiskPerTrade,
+		cfg.MaxConcurrentPositions,
+		symbolSLTP,
 		m.config.ID,
 	)
 	if err != nil {
@@ -275,6 +469,67 @@ func (m *Manager) QuickCheck() QuickCheckResult {
 // This is the recommended single entry point for risk checks.
 // Combines QuickCheck + EvaluateSignalWithStrategy in one call.
 func (m *Manager) EvaluateFull(signal SignalInput, position Position, account Account, strategyID string) RiskDecision {
+	return m.evaluateFull(signal, position, account, strategyID, 0, true)
+}
+
+// evaluateFull is EvaluateFull with a per-user MaxConcurrentPositions
+// override (0 = the config's; negative lifts the cap). With record false the
+// check is left out of the risk metrics (what-if previews).
+func (m *Manager) evaluateFull(signal SignalInput, position Position, account Account, strategyID string, maxPositions int, record bool) RiskDecision {
+	// Scheduled maintenance blocks new entries; exits may still reduce risk.
+	m.mu.RLock()
+	sched, halts, streaks := m.maintenance, m.halts, m.streaks
+	m.mu.RUnlock()
+	if w, active := sched.Active(); active && !IsExit(signal.Action, position) {
+		return RiskDecision{
+			Allowed:    false,
+			Reason:     fmt.Sprintf("maintenance window active until %s: %s", w.End.UTC().Format(time.RFC3339), w.Reason),
+			LimitLevel: "LIMIT",
+		}
+	}
+	// Operator-halted symbols likewise accept exits only.
+	if halt, halted := halts.Halted(signal.Symbol); halted && !IsExit(signal.Action, position) {
+		return RiskDecision{
+			Allowed:    false,
+			Reason:     halt.BlockReason(),
+			LimitLevel: "LIMIT",
+		}
+	}
+
+	// Strategies on a losing streak may still close what they hold.
+	if pause, paused := streaks.Paused(strategyID); paused && !IsExit(signal.Action, position) {
+		return RiskDecision{
+			Allowed:    false,
+			Reason:     pause.BlockReason(),
+			LimitLevel: "LIMIT",
+		}
+	}
+
+	if reason := m.checkMinHold(signal, position, strategyID); reason != "" {
+		return RiskDecision{
+			Allowed:    false,
+			Reason:     reason,
+			LimitLevel: "LIMIT",
+		}
+	}
+
+	// Per-connection budgets apply on top of the user's limits.
+	if reason := m.checkConnection(signal, position); reason != "" {
+		return RiskDecision{
+			Allowed:    false,
+			Reason:     reason,
+			LimitLevel: "LIMIT",
+		}
+	}
+
+	if reason := m.checkConcurrentPositions(signal, position, account, maxPositions); reason != "" {
+		return RiskDecision{
+			Allowed:    false,
+			Reason:     reason,
+			LimitLevel: "LIMIT",
+		}
+	}
+
 	// First do QuickCheck for fast rejection
 	qr := m.QuickCheck()
 	if !qr.Allowed {
@@ -286,7 +541,7 @@ func (m *Manager) EvaluateFull(signal SignalInput, position Position, account Ac
 	}
 
 	// Then do full evaluation
-	dec := m.EvaluateSignalWithStrategy(signal, position, account, strategyID)
+	dec := m.evaluateSignalWithStrategy(signal, position, account, strategyID, record)
 
 	// Preserve limit level from QuickCheck if it was WARNING/CAUTION
 	if qr.LimitLevel == "WARNING" || qr.LimitLevel == "CAUTION" {
@@ -301,6 +556,123 @@ func (m *Manager) EvaluateFull(signal SignalInput, position Position, account Ac
 	return dec
 }
 
+// checkMinHold defers an exit or reversal of a position the strategy entered
+// less than its min_hold_seconds ago, so whipsawing strategies do not close
+// within seconds. Positions with an unknown entry time are not held. It
+// returns "" when allowed.
+func (m *Manager) checkMinHold(signal SignalInput, position Position, strategyID string) string {
+	if strategyID == "" || position.OpenedAt.IsZero() || !IsExit(signal.Action, position) {
+		return ""
+	}
+	hold := time.Duration(m.GetStrategyConfig(strategyID).MinHoldSeconds) * time.Second
+	if hold <= 0 {
+		return ""
+	}
+	if held := time.Since(position.OpenedAt); held < hold {
+		return fmt.Sprintf("[Strategy %s] exit deferred: %s position held %s of min %s",
+			strategyID, signal.Symbol, held.Truncate(time.Second), hold)
+	}
+	return ""
+}
+
+// checkConcurrentPositions rejects an entry on a symbol not yet held once the
+// account already holds the maximum number of distinct positions. Adds to a
+// held symbol and exits are always allowed. It returns "" when allowed.
+func (m *Manager) checkConcurrentPositions(signal SignalInput, position Position, account Account, max int) string {
+	cfg := m.GetConfig()
+	if max == 0 {
+		max = cfg.MaxConcurrentPositions
+	}
+	if !cfg.EnableRisk || max <= 0 || IsExit(signal.Action, position) {
+		return ""
+	}
+	held := make(map[string]bool, len(account.OpenPositions))
+	for _, sym := range account.OpenPositions {
+		held[strings.ToUpper(sym)] = true
+	}
+	if held[strings.ToUpper(signal.Symbol)] || len(held) < max {
+		return ""
+	}
+	return fmt.Sprintf("max concurrent positions reached (%d/%d); %s would open a new one", len(held), max, strings.ToUpper(signal.Symbol))
+}
+
+// SetMaintenance attaches a maintenance schedule checked by EvaluateFull.
+func (m *Manager) SetMaintenance(s *MaintenanceSchedule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maintenance = s
+}
+
+// SetSymbolHalts attaches the halted-symbol set checked by EvaluateFull.
+func (m *Manager) SetSymbolHalts(h *SymbolHalts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.halts = h
+}
+
+// SetLossStreaks attaches the losing-streak tracker fed by UpdateMetrics and
+// checked by EvaluateFull.
+func (m *Manager) SetLossStreaks(s *LossStreaks) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streaks = s
+}
+
+// CheckOrderSize enforces the global min/max order notional on a single order.
+// Strategy signals get per-strategy limits through EvaluateFull; manual orders,
+// which have no strategy, are checked against the global caps with this.
+func (m *Manager) CheckOrderSize(notional float64) error {
+	cfg := m.GetConfig()
+	if !cfg.EnableRisk || !cfg.UseOrderSizeLimits {
+		return nil
+	}
+	notional = math.Abs(notional)
+	if cfg.MinOrderSize > 0 && notional < cfg.MinOrderSize {
+		return fmt.Errorf("order too small: %.2f < %.2f", notional, cfg.MinOrderSize)
+	}
+	if cfg.MaxOrderSize > 0 && notional > cfg.MaxOrderSize {
+		return fmt.Errorf("order too large: %.2f > %.2f", notional, cfg.MaxOrderSize)
+	}
+	return nil
+}
+
+// NeedsPrice reports whether evaluating an order on connectionID checks a
+// notional-based limit (order size, position size, total, correlation-group
+// or connection exposure) or sizes it by risk fraction, none of which can be
+// judged without a price.
+func (m *Manager) NeedsPrice(connectionID string) bool {
+	cfg := m.GetConfig()
+	if !cfg.EnableRisk {
+		return false
+	}
+	switch {
+	case cfg.UseOrderSizeLimits && (cfg.MinOrderSize > 0 || cfg.MaxOrderSize > 0):
+		return true
+	case cfg.UsePositionSizeLimit && (cfg.MaxPositionSize > 0 || len(cfg.SymbolPositionLimits) > 0):
+		return true
+	case cfg.UseExposureLimit && cfg.MaxTotalExposure > 0:
+		return true
+	case cfg.SizingMode == SizingRiskFraction:
+		return true
+	}
+	for _, g := range cfg.CorrelationGroups {
+		if g.MaxExposure > 0 {
+			return true
+		}
+	}
+	if connectionID == "" {
+		return false
+	}
+	m.mu.RLock()
+	fn := m.connLimits
+	m.mu.RUnlock()
+	if fn == nil {
+		return false
+	}
+	limits, err := fn(connectionID)
+	return err != nil || limits.MaxExposure > 0
+}
+
 // GetStrategyConfig returns risk config for a specific strategy.
 // Returns default config if not found.
 func (m *Manager) GetStrategyConfig(strategyID string) StrategyRiskConfig {
@@ -312,7 +684,7 @@ func (m *Manager) GetStrategyConfig(strategyID string) StrategyRiskConfig {
 	m.mu.RUnlock()
 
 	// Try to load from DB
-	if m.db != nil {
+	if m.persistDB() != nil {
 		cfg, err := m.loadStrategyConfigFromDB(strategyID)
 		if err == nil {
 			m.mu.Lock()
@@ -332,14 +704,14 @@ func (m *Manager) loadStrategyConfigFromDB(strategyID string) (StrategyRiskConfi
 	var stopLoss, takeProfit sql.NullFloat64
 	var useTrailing, enableRisk, usePosSize, useOrderSize int
 
-	err := m.db.QueryRow(`
-		SELECT max_position_size, min_order_size, max_order_size,
-		       stop_loss, take_profit, use_trailing_stop, trailing_percent,
+	err := m.persistDB().QueryRow(`
+		SELECT max_position_size, min_order_size, max_order_size, COALESCE(allocation, 0), COALESCE(max_drawdown_pct, 0),
+		       COALESCE(min_hold_seconds, 0), stop_loss, take_profit, use_trailing_stop, trailing_percent,
 		       enable_risk, use_position_size_limit, use_order_size_limits, updated_at
 		FROM strategy_risk_configs WHERE strategy_instance_id = ?
 	`, strategyID).Scan(
-		&cfg.MaxPositionSize, &cfg.MinOrderSize, &cfg.MaxOrderSize,
-		&stopLoss, &takeProfit, &useTrailing, &cfg.TrailingPercent,
+		&cfg.MaxPositionSize, &cfg.MinOrderSize, &cfg.MaxOrderSize, &cfg.Allocation, &cfg.MaxDrawdownPct,
+		&cfg.MinHoldSeconds, &stopLoss, &takeProfit, &useTrailing, &cfg.TrailingPercent,
 		&enableRisk, &usePosSize, &useOrderSize, &cfg.UpdatedAt,
 	)
 	if err != nil {
@@ -366,7 +738,8 @@ func (m *Manager) SetStrategyConfig(cfg StrategyRiskConfig) error {
 	m.strategyConfigs[cfg.StrategyInstanceID] = &cfg
 	m.mu.Unlock()
 
-	if m.db == nil {
+	db := m.persistDB()
+	if db == nil {
 		return nil
 	}
 
@@ -378,16 +751,19 @@ func (m *Manager) SetStrategyConfig(cfg StrategyRiskConfig) error {
 		takeProfit = *cfg.TakeProfit
 	}
 
-	_, err := m.db.Exec(`
+	_, err := db.Exec(`
 		INSERT INTO strategy_risk_configs (
-			strategy_instance_id, max_position_size, min_order_size, max_order_size,
-			stop_loss, take_profit, use_trailing_stop, trailing_percent,
+			strategy_instance_id, max_position_size, min_order_size, max_order_size, allocation, max_drawdown_pct,
+			min_hold_seconds, stop_loss, take_profit, use_trailing_stop, trailing_percent,
 			enable_risk, use_position_size_limit, use_order_size_limits, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(strategy_instance_id) DO UPDATE SET
 			max_position_size = excluded.max_position_size,
 			min_order_size = excluded.min_order_size,
 			max_order_size = excluded.max_order_size,
+			allocation = excluded.allocation,
+			max_drawdown_pct = excluded.max_drawdown_pct,
+			min_hold_seconds = excluded.min_hold_seconds,
 			stop_loss = excluded.stop_loss,
 			take_profit = excluded.take_profit,
 			use_trailing_stop = excluded.use_trailing_stop,
@@ -397,13 +773,71 @@ func (m *Manager) SetStrategyConfig(cfg StrategyRiskConfig) error {
 			use_order_size_limits = excluded.use_order_size_limits,
 			updated_at = CURRENT_TIMESTAMP
 	`,
-		cfg.StrategyInstanceID, cfg.MaxPositionSize, cfg.MinOrderSize, cfg.MaxOrderSize,
-		stopLoss, takeProfit, boolToInt(cfg.UseTrailingStop), cfg.TrailingPercent,
+		cfg.StrategyInstanceID, cfg.MaxPositionSize, cfg.MinOrderSize, cfg.MaxOrderSize, cfg.Allocation, cfg.MaxDrawdownPct,
+		cfg.MinHoldSeconds, stopLoss, takeProfit, boolToInt(cfg.UseTrailingStop), cfg.TrailingPercent,
 		boolToInt(cfg.EnableRisk), boolToInt(cfg.UsePositionSizeLimit), boolToInt(cfg.UseOrderSizeLimits),
 	)
 	return err
 }
 
+// AllocatedCapital sums the allocations of every strategy configured on this
+// manager except exceptStrategyID (pass "" to include all). A user's manager
+// only counts that user's strategies.
+func (m *Manager) AllocatedCapital(exceptStrategyID string) (float64, error) {
+	if m.db != nil {
+		var total float64
+		err := m.db.QueryRow(`
+			SELECT COALESCE(SUM(allocation), 0) FROM strategy_risk_configs WHERE strategy_instance_id != ?
+		`, exceptStrategyID).Scan(&total)
+		return total, err
+	}
+	if m.store != nil && m.userID != "" {
+		var total float64
+		err := m.store.QueryRow(`
+			SELECT COALESCE(SUM(c.allocation), 0)
+			FROM strategy_risk_configs c
+			JOIN strategy_instances s ON s.id = c.strategy_instance_id
+			WHERE s.user_id = ? AND c.strategy_instance_id != ?
+		`, m.userID, exceptStrategyID).Scan(&total)
+		return total, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var total float64
+	for id, cfg := range m.strategyConfigs {
+		if id != exceptStrategyID && cfg != nil {
+			total += cfg.Allocation
+		}
+	}
+	return total, nil
+}
+
+// SetStrategyHoldings attaches the position source used to enforce strategy
+// allocations. Without one, allocations only cap individual orders.
+func (m *Manager) SetStrategyHoldings(fn HoldingsFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holdings = fn
+}
+
+// strategyHolding returns the strategy's signed qty and the notional it ties up
+// at entry price.
+func (m *Manager) strategyHolding(strategyID string) (qty, notional float64) {
+	m.mu.RLock()
+	fn := m.holdings
+	m.mu.RUnlock()
+	if fn == nil {
+		return 0, 0
+	}
+	qty, avgPrice, err := fn(strategyID)
+	if err != nil {
+		log.Printf("⚠️ [Strategy %s] position lookup failed: %v", strategyID, err)
+		return 0, 0
+	}
+	return qty, math.Abs(qty) * avgPrice
+}
+
 // EvaluateSignal evaluates a trading signal against risk rules.
 func (m *Manager) EvaluateSignal(signal SignalInput, position Position, account Account) RiskDecision {
 	m.mu.RLock()
@@ -438,9 +872,10 @@ func (m *Manager) EvaluateSignal(signal SignalInput, position Position, account
 
 	// 3. Basic order notional & min/max size.
 	orderValue := signal.Size * signal.Price
-	if cfg.MaxPositionSize > 0 && orderValue > cfg.MaxPositionSize {
+	positionLimit := cfg.PositionLimitFor(signal.Symbol)
+	if positionLimit > 0 && orderValue > positionLimit {
 		// Clip to max position size for this order.
-		dec.AdjustedSize = cfg.MaxPositionSize / signal.Price
+		dec.AdjustedSize = positionLimit / signal.Price
 		log.Printf("Position size adjusted: %.4f -> %.4f", signal.Size, dec.AdjustedSize)
 	} else {
 		dec.AdjustedSize = signal.Size
@@ -460,18 +895,18 @@ func (m *Manager) EvaluateSignal(signal SignalInput, position Position, account
 	}
 
 	// 3b. Per-symbol exposure (existing position + new order).
-	if cfg.MaxPositionSize > 0 {
+	if positionLimit > 0 {
 		currentNotional := math.Abs(position.Quantity) * position.CurrentPrice
 		newNotional := dec.AdjustedSize * signal.Price
-		if currentNotional+newNotional > cfg.MaxPositionSize {
-			remaining := cfg.MaxPositionSize - currentNotional
+		if currentNotional+newNotional > positionLimit {
+			remaining := positionLimit - currentNotional
 			if remaining <= 0 {
 				dec.Allowed = false
 				dec.Reason = "symbol exposure limit reached"
 				return dec
 			}
 			newSize := remaining / signal.Price
-			log.Printf("Symbol exposure adjusted: %.4f -> %.4f (limit %.2f)", dec.AdjustedSize, newSize, cfg.MaxPositionSize)
+			log.Printf("Symbol exposure adjusted: %.4f -> %.4f (limit %.2f)", dec.AdjustedSize, newSize, positionLimit)
 			dec.AdjustedSize = newSize
 		}
 	}
@@ -504,6 +939,12 @@ func (m *Manager) EvaluateSignal(signal SignalInput, position Position, account
 // EvaluateSignalWithStrategy evaluates signal using both global and strategy-level risk settings.
 // This is the recommended method for layered risk control.
 func (m *Manager) EvaluateSignalWithStrategy(signal SignalInput, position Position, account Account, strategyID string) RiskDecision {
+	return m.evaluateSignalWithStrategy(signal, position, account, strategyID, true)
+}
+
+// evaluateSignalWithStrategy is EvaluateSignalWithStrategy; record false skips
+// the check metrics.
+func (m *Manager) evaluateSignalWithStrategy(signal SignalInput, position Position, account Account, strategyID string, record bool) RiskDecision {
 	startTime := time.Now()
 
 	m.mu.RLock()
@@ -514,10 +955,19 @@ func (m *Manager) EvaluateSignalWithStrategy(signal SignalInput, position Positi
 	// Get strategy-specific config
 	strategyCfg := m.GetStrategyConfig(strategyID)
 
+	// Fixed-fractional sizing replaces the signal's size for entries.
+	if size, ok := m.riskFractionSize(signal, position, account, globalCfg, strategyCfg); ok {
+		log.Printf("[Strategy %s] Risk sizing (%.2f%% of %.2f equity): %.4f -> %.4f",
+			strategyID, globalCfg.RiskPerTrade*100, account.Balance, signal.Size, size)
+		signal.Size = size
+	}
+
 	// Defer metrics recording
 	var dec RiskDecision
 	defer func() {
-		m.recordCheck(dec, time.Since(startTime).Nanoseconds())
+		if record {
+			m.recordCheck(dec, time.Since(startTime).Nanoseconds())
+		}
 	}()
 
 	dec = RiskDecision{Allowed: true}
@@ -578,18 +1028,22 @@ func (m *Manager) EvaluateSignalWithStrategy(signal SignalInput, position Positi
 	orderValue := signal.Size * signal.Price
 	dec.AdjustedSize = signal.Size
 
-	// S1. Strategy position size limit
-	if strategyCfg.UsePositionSizeLimit && strategyCfg.MaxPositionSize > 0 {
-		if orderValue > strategyCfg.MaxPositionSize {
-			dec.AdjustedSize = strategyCfg.MaxPositionSize / signal.Price
+	// S1. Strategy position size limit, tightened by a global per-symbol override.
+	positionLimit := strategyCfg.MaxPositionSize
+	if limit, ok := globalCfg.SymbolPositionLimit(signal.Symbol); ok && (positionLimit <= 0 || limit < positionLimit) {
+		positionLimit = limit
+	}
+	if strategyCfg.UsePositionSizeLimit && positionLimit > 0 {
+		if orderValue > positionLimit {
+			dec.AdjustedSize = positionLimit / signal.Price
 			log.Printf("[Strategy %s] Position size adjusted: %.4f -> %.4f", strategyID, signal.Size, dec.AdjustedSize)
 		}
 
 		// Check against existing position
 		currentNotional := math.Abs(position.Quantity) * position.CurrentPrice
 		newNotional := dec.AdjustedSize * signal.Price
-		if currentNotional+newNotional > strategyCfg.MaxPositionSize {
-			remaining := strategyCfg.MaxPositionSize - currentNotional
+		if currentNotional+newNotional > positionLimit {
+			remaining := positionLimit - currentNotional
 			if remaining <= 0 {
 				dec.Allowed = false
 				dec.Reason = fmt.Sprintf("[Strategy %s] position limit reached", strategyID)
@@ -599,7 +1053,47 @@ func (m *Manager) EvaluateSignalWithStrategy(signal SignalInput, position Positi
 		}
 	}
 
-	// S2. Strategy order size limits
+	// S2. Strategy capital allocation (entries only; reducing the position frees capital)
+	if strategyCfg.Allocation > 0 {
+		qty, held := m.strategyHolding(strategyID)
+		reducing := (qty > 0 && strings.EqualFold(signal.Action, "SELL")) || (qty < 0 && strings.EqualFold(signal.Action, "BUY"))
+		if !reducing {
+			remaining := strategyCfg.Allocation - held
+			if remaining <= 0 {
+				dec.Allowed = false
+				dec.Reason = fmt.Sprintf("[Strategy %s] allocation used up: %.2f/%.2f", strategyID, held, strategyCfg.Allocation)
+				return dec
+			}
+			if dec.AdjustedSize*signal.Price > remaining {
+				newSize := remaining / signal.Price
+				log.Printf("[Strategy %s] Allocation adjusted: %.4f -> %.4f (%.2f of %.2f left)", strategyID, dec.AdjustedSize, newSize, remaining, strategyCfg.Allocation)
+				dec.AdjustedSize = newSize
+			}
+		}
+	}
+
+	// G4. Correlated-symbol exposure (global, on the size left after the strategy caps)
+	if globalCfg.UseExposureLimit && !IsExit(signal.Action, position) {
+		if group, ok := globalCfg.CorrelationGroupFor(signal.Symbol); ok && group.MaxExposure > 0 {
+			held := 0.0
+			for _, sym := range group.Symbols {
+				held += account.SymbolExposure[sym]
+			}
+			remaining := group.MaxExposure - held
+			if remaining <= 0 {
+				dec.Allowed = false
+				dec.Reason = fmt.Sprintf("correlation group %s exposure limit reached: %.2f/%.2f", group.Name, held, group.MaxExposure)
+				return dec
+			}
+			if dec.AdjustedSize*signal.Price > remaining {
+				newSize := remaining / signal.Price
+				log.Printf("[Strategy %s] Correlation group %s adjusted: %.4f -> %.4f (%.2f of %.2f left)", strategyID, group.Name, dec.AdjustedSize, newSize, remaining, group.MaxExposure)
+				dec.AdjustedSize = newSize
+			}
+		}
+	}
+
+	// S3. Strategy order size limits
 	if strategyCfg.UseOrderSizeLimits {
 		adjOrderValue := dec.AdjustedSize * signal.Price
 		if adjOrderValue < strategyCfg.MinOrderSize {
@@ -634,7 +1128,7 @@ func (m *Manager) approveWithSLTP(signal SignalInput, globalCfg RiskConfig, stra
 
 // applySLTP applies stop loss and take profit to decision.
 func (m *Manager) applySLTP(dec RiskDecision, signal SignalInput, globalCfg RiskConfig, strategyCfg StrategyRiskConfig) RiskDecision {
-	// Use strategy SL/TP if set, otherwise use global defaults
+	// Symbol overrides win, then strategy SL/TP if set, otherwise global defaults
 	stopLoss := globalCfg.DefaultStopLoss
 	takeProfit := globalCfg.DefaultTakeProfit
 
@@ -644,6 +1138,14 @@ func (m *Manager) applySLTP(dec RiskDecision, signal SignalInput, globalCfg Risk
 	if strategyCfg.TakeProfit != nil {
 		takeProfit = *strategyCfg.TakeProfit
 	}
+	if o, ok := globalCfg.SymbolSLTPFor(signal.Symbol); ok {
+		if o.StopLoss > 0 {
+			stopLoss = o.StopLoss
+		}
+		if o.TakeProfit > 0 {
+			takeProfit = o.TakeProfit
+		}
+	}
 
 	if strings.EqualFold(signal.Action, "BUY") {
 		dec.StopLoss = signal.Price * (1 - stopLoss)
@@ -656,6 +1158,31 @@ func (m *Manager) applySLTP(dec RiskDecision, signal SignalInput, globalCfg Risk
 	return dec
 }
 
+// riskFractionSize returns the entry size under SizingRiskFraction, using the
+// same stop loss applySLTP would set. ok is false for fixed sizing, exits, or
+// when equity or the stop distance is unknown.
+func (m *Manager) riskFractionSize(signal SignalInput, position Position, account Account, globalCfg RiskConfig, strategyCfg StrategyRiskConfig) (float64, bool) {
+	if globalCfg.SizingMode != SizingRiskFraction || globalCfg.RiskPerTrade <= 0 || IsExit(signal.Action, position) {
+		return 0, false
+	}
+	stop := m.applySLTP(RiskDecision{}, signal, globalCfg, strategyCfg).StopLoss
+	if stop <= 0 {
+		return 0, false
+	}
+	size := RiskFractionSize(account.Balance, globalCfg.RiskPerTrade, signal.Price, stop)
+	return size, size > 0
+}
+
+// RiskFractionSize returns the quantity that loses riskFraction of equity if
+// the price moves from entry to stop: (equity * riskFraction) / |entry - stop|.
+func RiskFractionSize(equity, riskFraction, entry, stop float64) float64 {
+	distance := math.Abs(entry - stop)
+	if equity <= 0 || riskFraction <= 0 || distance == 0 {
+		return 0
+	}
+	return equity * riskFraction / distance
+}
+
 // getLimitLevel returns the limit level based on usage ratio.
 func (m *Manager) getLimitLevel(usageRatio float64, cfg RiskConfig) string {
 	if usageRatio >= 1.0 {
@@ -716,16 +1243,18 @@ func (m *Manager) UpdateMetrics(trade TradeResult) error {
 	net := trade.PnL
 
 	m.metrics.DailyTrades++
-	m.metrics.DailyPnL += net
+	m.metrics.DailyPnL = money.AddFloats(m.metrics.DailyPnL, net)
 	if net < 0 {
-		m.metrics.DailyLosses += -net
+		m.metrics.DailyLosses = money.AddFloats(m.metrics.DailyLosses, -net)
 	}
 
-	m.metrics.TotalRealizedPnL += net
+	m.streaks.Record(trade)
+
+	m.metrics.TotalRealizedPnL = money.AddFloats(m.metrics.TotalRealizedPnL, net)
 	if m.metrics.TotalRealizedPnL > m.metrics.MaxProfit {
 		m.metrics.MaxProfit = m.metrics.TotalRealizedPnL
 	}
-	drawdown := m.metrics.MaxProfit - m.metrics.TotalRealizedPnL
+	drawdown := money.AddFloats(m.metrics.MaxProfit, -m.metrics.TotalRealizedPnL)
 	if drawdown > m.metrics.MaxDrawdown {
 		m.metrics.MaxDrawdown = drawdown
 	}
@@ -772,6 +1301,7 @@ func (m *Manager) ResetDailyMetrics() {
 	m.metrics.DailyPnL = 0
 	m.metrics.DailyTrades = 0
 	m.metrics.DailyLosses = 0
+	m.resetConnectionDaily()
 }
 
 // GetMetrics returns current metrics snapshot.
@@ -787,6 +1317,9 @@ type SignalInput struct {
 	Action string // BUY, SELL
 	Size   float64
 	Price  float64
+	// ConnectionID is the exchange connection the order would be routed
+	// through; "" skips the per-connection limits.
+	ConnectionID string
 }
 
 // TradeResult represents an executed trade result.
@@ -797,4 +1330,9 @@ type TradeResult struct {
 	Price  float64
 	PnL    float64 // net of fees
 	Fee    float64
+	// Closed is set when the fill reduced or closed a position; only those
+	// count toward a strategy's losing streak.
+	Closed     bool
+	StrategyID string
+	UserID     string
 }
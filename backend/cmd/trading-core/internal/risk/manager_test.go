@@ -1,6 +1,14 @@
 package risk
 
-import "testing"
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"trading-core/pkg/db"
+)
 
 // Ensures UpdateMetrics does not double-subtract fees from already net PnL for
 // either wins or losses.
@@ -69,3 +77,419 @@ func TestUpdateMetricsUsesNetPnL(t *testing.T) {
 		})
 	}
 }
+
+func TestSymbolPositionLimitOverridesGlobalDefault(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	cfg := mgr.GetConfig()
+	cfg.SymbolPositionLimits = map[string]float64{"dogeusdt": 200}
+	if err := mgr.UpdateConfig(context.Background(), cfg); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+
+	// 800 USDT of DOGE is clipped to the 200 USDT symbol cap.
+	dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "DOGEUSDT", Action: "BUY", Size: 4000, Price: 0.2}, Position{}, Account{}, "s1")
+	if !dec.Allowed {
+		t.Fatalf("DOGE order rejected: %s", dec.Reason)
+	}
+	if math.Abs(dec.AdjustedSize-1000) > 1e-9 {
+		t.Errorf("DOGE adjusted size = %v, want 1000", dec.AdjustedSize)
+	}
+
+	// An existing position at the cap blocks further entries.
+	dec = mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "DOGEUSDT", Action: "BUY", Size: 100, Price: 0.2},
+		Position{Symbol: "DOGEUSDT", Side: "LONG", Quantity: 1000, CurrentPrice: 0.2}, Account{}, "s1")
+	if dec.Allowed {
+		t.Error("DOGE order should be rejected once the symbol cap is used up")
+	}
+
+	// Other symbols keep the global 1000 USDT default.
+	dec = mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.016, Price: 50000}, Position{}, Account{}, "s1")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.016) > 1e-9 {
+		t.Errorf("BTC order should pass unchanged, got allowed=%v size=%v reason=%q", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+	if got := mgr.GetConfig().PositionLimitFor("ETHUSDT"); got != cfg.MaxPositionSize {
+		t.Errorf("PositionLimitFor(ETHUSDT) = %v, want global %v", got, cfg.MaxPositionSize)
+	}
+}
+
+func TestSymbolSLTPOverridesGlobalDefault(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	cfg := mgr.GetConfig()
+	cfg.SymbolSLTP = map[string]SymbolSLTP{"dogeusdt": {StopLoss: 0.08}}
+	if err := mgr.UpdateConfig(context.Background(), cfg); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+
+	// DOGE uses its own 8% stop but keeps the global take profit.
+	dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "DOGEUSDT", Action: "BUY", Size: 1000, Price: 0.2}, Position{}, Account{}, "s1")
+	if !dec.Allowed {
+		t.Fatalf("DOGE order rejected: %s", dec.Reason)
+	}
+	if math.Abs(dec.StopLoss-0.184) > 1e-9 {
+		t.Errorf("DOGE stop loss = %v, want 0.184 (8%% below entry)", dec.StopLoss)
+	}
+	if want := 0.2 * (1 + cfg.DefaultTakeProfit); math.Abs(dec.TakeProfit-want) > 1e-9 {
+		t.Errorf("DOGE take profit = %v, want global %v", dec.TakeProfit, want)
+	}
+
+	// Other symbols keep the global stop.
+	dec = mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.01, Price: 50000}, Position{}, Account{}, "s1")
+	if want := 50000 * (1 - cfg.DefaultStopLoss); math.Abs(dec.StopLoss-want) > 1e-6 {
+		t.Errorf("BTC stop loss = %v, want global %v", dec.StopLoss, want)
+	}
+}
+
+func TestStrategyAllocationCapsEachStrategy(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	held := map[string][2]float64{} // strategy -> qty, avg price
+	mgr.SetStrategyHoldings(func(id string) (float64, float64, error) {
+		h := held[id]
+		return h[0], h[1], nil
+	})
+	for id, alloc := range map[string]float64{"s1": 600, "s2": 400} {
+		cfg := DefaultStrategyConfig(id)
+		cfg.Allocation = alloc
+		if err := mgr.SetStrategyConfig(cfg); err != nil {
+			t.Fatalf("SetStrategyConfig(%s): %v", id, err)
+		}
+	}
+	if total, _ := mgr.AllocatedCapital(""); total != 1000 {
+		t.Fatalf("AllocatedCapital = %v, want 1000", total)
+	}
+	if others, _ := mgr.AllocatedCapital("s1"); others != 400 {
+		t.Fatalf("AllocatedCapital(except s1) = %v, want 400", others)
+	}
+
+	buy := SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.012, Price: 50000} // 600 USDT
+
+	// s1 takes its full 600 USDT share.
+	dec := mgr.EvaluateSignalWithStrategy(buy, Position{}, Account{}, "s1")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.012) > 1e-9 {
+		t.Fatalf("s1 entry: allowed=%v size=%v reason=%q", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+	held["s1"] = [2]float64{dec.AdjustedSize, buy.Price}
+
+	if dec := mgr.EvaluateSignalWithStrategy(buy, Position{}, Account{}, "s1"); dec.Allowed {
+		t.Error("s1 should be rejected once its allocation is used up")
+	}
+	if dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.012, Price: 50000}, Position{}, Account{}, "s1"); !dec.Allowed {
+		t.Errorf("s1 exit should not be blocked by its allocation: %s", dec.Reason)
+	}
+
+	// s2 asks for the same 600 USDT but is limited to its own 400 USDT.
+	dec = mgr.EvaluateSignalWithStrategy(buy, Position{}, Account{}, "s2")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.008) > 1e-9 {
+		t.Fatalf("s2 entry: allowed=%v size=%v, want 0.008 (reason %q)", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+	held["s2"] = [2]float64{dec.AdjustedSize, buy.Price}
+	if dec := mgr.EvaluateSignalWithStrategy(buy, Position{}, Account{}, "s2"); dec.Allowed {
+		t.Error("s2 should be rejected once its allocation is used up")
+	}
+}
+
+func TestRiskFractionSizingUsesEquityAndStopDistance(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxTotalExposure = 100000
+	cfg.CorrelationGroups = nil
+	cfg.SizingMode = SizingRiskFraction
+	cfg.RiskPerTrade = 0.01
+	mgr := NewInMemory(cfg)
+	strat := DefaultStrategyConfig("s1")
+	strat.MaxPositionSize = 100000
+	strat.MaxOrderSize = 100000
+	if err := mgr.SetStrategyConfig(strat); err != nil {
+		t.Fatalf("SetStrategyConfig: %v", err)
+	}
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+
+	// Risking 1% of 10000 with the default 2% stop at 50000 (1000 away) buys 0.1.
+	dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.5, Price: 50000}, Position{}, account, "s1")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.1) > 1e-9 {
+		t.Fatalf("risk-sized entry: allowed=%v size=%v, want 0.1 (reason %q)", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+	if math.Abs(dec.StopLoss-49000) > 1e-6 {
+		t.Fatalf("stop loss = %v, want 49000", dec.StopLoss)
+	}
+
+	// A tighter strategy stop (1%) doubles the size for the same risk.
+	sl := 0.01
+	strat.StopLoss = &sl
+	if err := mgr.SetStrategyConfig(strat); err != nil {
+		t.Fatalf("SetStrategyConfig: %v", err)
+	}
+	dec = mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.5, Price: 50000}, Position{}, account, "s1")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.2) > 1e-9 {
+		t.Fatalf("risk-sized short: allowed=%v size=%v, want 0.2 (reason %q)", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+
+	// Exits keep the signal's size.
+	long := Position{Symbol: "BTCUSDT", Side: "LONG", Quantity: 0.3, CurrentPrice: 50000}
+	dec = mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.3, Price: 50000}, long, account, "s1")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.3) > 1e-9 {
+		t.Fatalf("exit: allowed=%v size=%v, want 0.3 (reason %q)", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+}
+
+func TestCorrelationGroupCapsCombinedExposure(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	cfg := mgr.GetConfig()
+	cfg.CorrelationGroups = []CorrelationGroup{{Name: "majors", Symbols: []string{"btcusdt", "ethusdt"}, MaxExposure: 1500}}
+	if err := mgr.UpdateConfig(context.Background(), cfg); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+
+	// 900 USDT of BTC is held; each symbol alone stays under its 1000 USDT cap.
+	btc := Position{Symbol: "BTCUSDT", Side: "LONG", Quantity: 0.018, CurrentPrice: 50000}
+	account := Account{TotalExposure: 900, SymbolExposure: map[string]float64{"BTCUSDT": 900}}
+
+	// 800 USDT of ETH would pass the ETH cap but only 600 is left in the group.
+	buyETH := SignalInput{Symbol: "ETHUSDT", Action: "BUY", Size: 0.4, Price: 2000}
+	dec := mgr.EvaluateSignalWithStrategy(buyETH, Position{}, account, "s1")
+	if !dec.Allowed || math.Abs(dec.AdjustedSize-0.3) > 1e-9 {
+		t.Fatalf("ETH entry: allowed=%v size=%v, want 0.3 (reason %q)", dec.Allowed, dec.AdjustedSize, dec.Reason)
+	}
+
+	// With 600 USDT of ETH on, the group is full although ETH is at 60% of its own cap.
+	eth := Position{Symbol: "ETHUSDT", Side: "LONG", Quantity: 0.3, CurrentPrice: 2000}
+	account = Account{TotalExposure: 1500, SymbolExposure: map[string]float64{"BTCUSDT": 900, "ETHUSDT": 600}}
+	if dec := mgr.EvaluateSignalWithStrategy(buyETH, eth, account, "s1"); dec.Allowed {
+		t.Fatal("ETH entry should be rejected once the majors group cap is reached")
+	}
+	if dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.001, Price: 50000}, btc, account, "s1"); dec.Allowed {
+		t.Fatal("BTC entry should be rejected once the majors group cap is reached")
+	}
+
+	// Exits and symbols outside the group are unaffected.
+	if dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.018, Price: 50000}, btc, account, "s1"); !dec.Allowed {
+		t.Errorf("BTC exit should be allowed: %s", dec.Reason)
+	}
+	if dec := mgr.EvaluateSignalWithStrategy(SignalInput{Symbol: "XRPUSDT", Action: "BUY", Size: 1000, Price: 0.5}, Position{}, account, "s1"); !dec.Allowed {
+		t.Errorf("uncorrelated entry should be allowed: %s", dec.Reason)
+	}
+}
+
+func TestMaxConcurrentPositionsRejectsNewSymbolAtCap(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	cfg := mgr.GetConfig()
+	cfg.MaxConcurrentPositions = 2
+	cfg.CorrelationGroups = nil
+	if err := mgr.UpdateConfig(context.Background(), cfg); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+	buy := func(symbol string) SignalInput {
+		return SignalInput{Symbol: symbol, Action: "BUY", Size: 0.01, Price: 1000}
+	}
+	// Open positions up to the cap.
+	for _, sym := range []string{"BTCUSDT", "ETHUSDT"} {
+		if dec := mgr.EvaluateFull(buy(sym), Position{Symbol: sym}, account, "s1"); !dec.Allowed {
+			t.Fatalf("entry on %s should be allowed: %s", sym, dec.Reason)
+		}
+		account.OpenPositions = append(account.OpenPositions, sym)
+	}
+
+	dec := mgr.EvaluateFull(buy("SOLUSDT"), Position{Symbol: "SOLUSDT"}, account, "s1")
+	if dec.Allowed || !strings.Contains(dec.Reason, "max concurrent positions") {
+		t.Fatalf("a third symbol should be rejected at the cap, got %+v", dec)
+	}
+
+	// Adding to a held symbol and exiting are still allowed.
+	eth := Position{Symbol: "ETHUSDT", Side: "LONG", Quantity: 0.01, CurrentPrice: 1000, EntryPrice: 1000}
+	if dec := mgr.EvaluateFull(buy("ethusdt"), eth, account, "s1"); !dec.Allowed {
+		t.Errorf("add to held symbol should be allowed: %s", dec.Reason)
+	}
+	if dec := mgr.EvaluateFull(SignalInput{Symbol: "ETHUSDT", Action: "SELL", Size: 0.01, Price: 1000}, eth, account, "s1"); !dec.Allowed {
+		t.Errorf("exit should be allowed: %s", dec.Reason)
+	}
+
+	// A per-user override can raise the cap.
+	if dec := mgr.evaluateFull(buy("SOLUSDT"), Position{Symbol: "SOLUSDT"}, account, "s1", 3, true); !dec.Allowed {
+		t.Errorf("entry under the user's raised cap should be allowed: %s", dec.Reason)
+	}
+}
+
+func TestMinHoldDefersExitButNotStopLoss(t *testing.T) {
+	ctx := context.Background()
+	mgr := NewInMemory(DefaultConfig())
+	scfg := DefaultStrategyConfig("s1")
+	scfg.MinHoldSeconds = 60
+	if err := mgr.SetStrategyConfig(scfg); err != nil {
+		t.Fatalf("SetStrategyConfig: %v", err)
+	}
+
+	// The strategy opens a long; its strategy position records the entry time.
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	if err := database.UpdateStrategyPosition(ctx, "s1", "BTCUSDT", "BUY", 0.01, 50000); err != nil {
+		t.Fatalf("UpdateStrategyPosition: %v", err)
+	}
+	// Another strategy trading the symbol later does not reset s1's entry.
+	if err := database.UpdateStrategyPosition(ctx, "s2", "BTCUSDT", "SELL", 0.01, 50050); err != nil {
+		t.Fatalf("UpdateStrategyPosition: %v", err)
+	}
+	sp, err := database.GetStrategyPosition(ctx, "s1")
+	if err != nil || sp == nil {
+		t.Fatalf("GetStrategyPosition: %+v %v", sp, err)
+	}
+	position := Position{Symbol: "BTCUSDT", Side: "LONG", EntryPrice: sp.AvgPrice, CurrentPrice: 50100,
+		Quantity: sp.Qty, OpenedAt: sp.OpenedAt}
+	if position.OpenedAt.IsZero() {
+		t.Fatal("entry time was not tracked")
+	}
+
+	// An exit signal seconds later is deferred.
+	exit := SignalInput{Symbol: "BTCUSDT", Action: "SELL", Size: 0.01, Price: 50100}
+	dec := mgr.EvaluateFull(exit, position, Account{Balance: 10000, AvailableBalance: 10000}, "s1")
+	if dec.Allowed || !strings.Contains(dec.Reason, "exit deferred") {
+		t.Fatalf("exit within the hold should be deferred, got allowed=%v reason=%q", dec.Allowed, dec.Reason)
+	}
+
+	// A stop loss inside the window still fires; it does not go through signal evaluation.
+	stops := NewStopLossManager()
+	stops.AddPosition(StopLossPosition{Symbol: "BTCUSDT", Side: "LONG", EntryPrice: 50000, StopLoss: 49000})
+	if d := stops.UpdatePrice("BTCUSDT", 48900); d == nil || !d.Triggered {
+		t.Fatalf("stop loss within the hold window should trigger, got %+v", d)
+	}
+	if err := database.UpdateStrategyPosition(ctx, "s1", "BTCUSDT", "SELL", 0.01, 48900); err != nil {
+		t.Fatalf("UpdateStrategyPosition: %v", err)
+	}
+	if sp, _ := database.GetStrategyPosition(ctx, "s1"); sp == nil || !sp.OpenedAt.IsZero() {
+		t.Errorf("closing the position should clear its entry time, got %+v", sp)
+	}
+
+	// Once the hold has elapsed, exits pass.
+	position.OpenedAt = time.Now().Add(-2 * time.Minute)
+	if dec := mgr.EvaluateFull(exit, position, Account{Balance: 10000, AvailableBalance: 10000}, "s1"); !dec.Allowed {
+		t.Fatalf("exit after the hold should be allowed: %s", dec.Reason)
+	}
+}
+
+func TestLossStreakPausesStrategyUntilWin(t *testing.T) {
+	mgr := NewInMemory(DefaultConfig())
+	streaks := NewLossStreaks(3)
+	var alerts []LossStreakPause
+	streaks.SetAlertFn(func(p LossStreakPause) { alerts = append(alerts, p) })
+	mgr.SetLossStreaks(streaks)
+
+	account := Account{Balance: 10000, AvailableBalance: 10000}
+	entry := SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 0.001, Price: 50000}
+	loss := TradeResult{Symbol: "BTCUSDT", Side: "SELL", Size: 0.001, Price: 49000, PnL: -1, Closed: true, StrategyID: "s1", UserID: "u1"}
+
+	// Opening fills and other strategies' losses do not count.
+	if err := mgr.UpdateMetrics(TradeResult{Symbol: "BTCUSDT", Side: "BUY", PnL: -0.05, StrategyID: "s1"}); err != nil {
+		t.Fatalf("UpdateMetrics: %v", err)
+	}
+	other := loss
+	other.StrategyID = "s2"
+	mgr.UpdateMetrics(other)
+	for i := 0; i < 3; i++ {
+		if err := mgr.UpdateMetrics(loss); err != nil {
+			t.Fatalf("UpdateMetrics: %v", err)
+		}
+	}
+
+	if _, paused := streaks.Paused("s1"); !paused {
+		t.Fatalf("s1 should be paused after 3 losses, streak=%d", streaks.Streak("s1"))
+	}
+	if len(alerts) != 1 || alerts[0].UserID != "u1" || alerts[0].Losses != 3 {
+		t.Fatalf("alerts = %+v, want one for u1 after 3 losses", alerts)
+	}
+	if dec := mgr.EvaluateFull(entry, Position{Symbol: "BTCUSDT"}, account, "s1"); dec.Allowed || !strings.Contains(dec.Reason, "consecutive losing trades") {
+		t.Fatalf("paused strategy entry: allowed=%v reason=%q", dec.Allowed, dec.Reason)
+	}
+	if dec := mgr.EvaluateFull(entry, Position{Symbol: "BTCUSDT"}, account, "s2"); !dec.Allowed {
+		t.Fatalf("other strategy should still trade: %q", dec.Reason)
+	}
+
+	// The next win clears the streak and the pause.
+	win := loss
+	win.PnL = 2
+	mgr.UpdateMetrics(win)
+	if _, paused := streaks.Paused("s1"); paused || streaks.Streak("s1") != 0 {
+		t.Fatal("a winning trade should reset the streak")
+	}
+}
+
+func TestLossStreakCountsPerUserAndSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+
+	streaks := NewLossStreaks(2)
+	if err := streaks.Restore(ctx, database.DB); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	loss := TradeResult{Symbol: "BTCUSDT", Side: "SELL", PnL: -1, Closed: true, StrategyID: "s1", UserID: "u1"}
+	streaks.Record(loss)
+	manual := loss
+	manual.StrategyID = ""
+	streaks.Record(manual)
+	if got := streaks.UserStreak("u1"); got != 2 {
+		t.Fatalf("u1 streak = %d, want 2 across strategy and manual trades", got)
+	}
+	if got := streaks.Streak("s1"); got != 1 {
+		t.Fatalf("s1 streak = %d, want 1", got)
+	}
+	streaks.Record(loss)
+	if _, paused := streaks.Paused("s1"); !paused {
+		t.Fatal("s1 should be paused after 2 losses")
+	}
+
+	restarted := NewLossStreaks(2)
+	if err := restarted.Restore(ctx, database.DB); err != nil {
+		t.Fatalf("Restore after restart: %v", err)
+	}
+	if p, paused := restarted.Paused("s1"); !paused || p.UserID != "u1" || p.Losses != 2 {
+		t.Fatalf("restored pause = %+v (paused %v), want u1 after 2 losses", p, paused)
+	}
+	if !restarted.Resume("s1") {
+		t.Fatal("Resume should lift the restored pause")
+	}
+	if again := NewLossStreaks(2); again.Restore(ctx, database.DB) != nil {
+		t.Fatal("Restore failed")
+	} else if _, paused := again.Paused("s1"); paused {
+		t.Fatal("a resumed pause must not come back after a restart")
+	}
+
+	var none *LossStreaks
+	if none.Resume("s1") {
+		t.Fatal("a nil tracker has nothing to resume")
+	}
+}
+
+func TestNeedsPriceOnlyForNotionalLimits(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UseOrderSizeLimits = false
+	cfg.UsePositionSizeLimit = false
+	cfg.UseExposureLimit = false
+	cfg.CorrelationGroups = nil
+	mgr := NewInMemory(cfg)
+	if mgr.NeedsPrice("conn-1") {
+		t.Fatal("no notional limit is on; a price should not be needed")
+	}
+
+	mgr.SetConnectionLimits(func(string) (ConnectionLimits, error) {
+		return ConnectionLimits{MaxExposure: 300}, nil
+	})
+	if !mgr.NeedsPrice("conn-1") {
+		t.Fatal("a connection exposure cap needs a price")
+	}
+
+	cfg.UseOrderSizeLimits = true
+	if !NewInMemory(cfg).NeedsPrice("") {
+		t.Fatal("order size limits need a price")
+	}
+}
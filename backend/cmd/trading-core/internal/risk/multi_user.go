@@ -4,6 +4,7 @@ package risk
 import (
 	"context"
 	"database/sql"
+	"log"
 	"sync"
 	"time"
 )
@@ -14,8 +15,19 @@ type MultiUserManager struct {
 	managers map[string]*Manager // userID -> Manager
 	lastSeen map[string]time.Time
 	db       *sql.DB
+
+	maintenance *MaintenanceSchedule // shared by every user's manager
+	halts       *SymbolHalts         // shared by every user's manager
+	streaks     *LossStreaks         // shared by every user's manager
+	holdings    HoldingsFunc         // shared by every user's manager
+	connLimits  ConnectionLimitsFunc // shared by every user's manager
+	positionCap PositionCapFunc      // optional; per-user MaxConcurrentPositions
 }
 
+// PositionCapFunc returns a user's cap on distinct open positions
+// (0 = the user manager's config, negative = no cap).
+type PositionCapFunc func(userID string) (int, error)
+
 // NewMultiUserManager creates a new multi-user risk manager.
 func NewMultiUserManager(db *sql.DB) *MultiUserManager {
 	return &MultiUserManager{
@@ -36,15 +48,78 @@ func (m *MultiUserManager) GetOrCreate(userID string) (*Manager, error) {
 		return mgr, nil
 	}
 
-	// Create new manager
-	// For now, use in-memory with default config
-	// TODO: load per-user config from DB
-	mgr := NewInMemory(DefaultConfig())
+	// The user's config and strategy configs persist in the shared DB.
+	mgr, err := NewUserManager(m.db, userID, DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	mgr.SetMaintenance(m.maintenance)
+	mgr.SetSymbolHalts(m.halts)
+	mgr.SetLossStreaks(m.streaks)
+	mgr.SetStrategyHoldings(m.holdings)
+	mgr.SetConnectionLimits(m.connLimits)
 	m.managers[userID] = mgr
 	m.lastSeen[userID] = time.Now()
 	return mgr, nil
 }
 
+// SetMaintenance applies a maintenance schedule to all current and future user managers.
+func (m *MultiUserManager) SetMaintenance(s *MaintenanceSchedule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maintenance = s
+	for _, mgr := range m.managers {
+		mgr.SetMaintenance(s)
+	}
+}
+
+// SetSymbolHalts applies a halted-symbol set to all current and future user managers.
+func (m *MultiUserManager) SetSymbolHalts(h *SymbolHalts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.halts = h
+	for _, mgr := range m.managers {
+		mgr.SetSymbolHalts(h)
+	}
+}
+
+// SetLossStreaks applies a losing-streak tracker to all current and future user managers.
+func (m *MultiUserManager) SetLossStreaks(s *LossStreaks) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streaks = s
+	for _, mgr := range m.managers {
+		mgr.SetLossStreaks(s)
+	}
+}
+
+// SetStrategyHoldings applies a strategy position source to all current and future user managers.
+func (m *MultiUserManager) SetStrategyHoldings(fn HoldingsFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holdings = fn
+	for _, mgr := range m.managers {
+		mgr.SetStrategyHoldings(fn)
+	}
+}
+
+// SetConnectionLimits applies a connection limit lookup to all current and future user managers.
+func (m *MultiUserManager) SetConnectionLimits(fn ConnectionLimitsFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connLimits = fn
+	for _, mgr := range m.managers {
+		mgr.SetConnectionLimits(fn)
+	}
+}
+
+// SetPositionCap attaches the per-user MaxConcurrentPositions lookup used by EvaluateForUser.
+func (m *MultiUserManager) SetPositionCap(fn PositionCapFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.positionCap = fn
+}
+
 // Get returns the risk manager for a user, or nil if not found. It only
 // refreshes activity for existing managers and never creates a new one.
 func (m *MultiUserManager) Get(userID string) *Manager {
@@ -93,6 +168,16 @@ func (m *MultiUserManager) UpdateMetricsForUser(ctx context.Context, userID stri
 	return mgr.UpdateMetrics(trade)
 }
 
+// RecordConnectionTradeForUser books a fill against one of a user's connections.
+func (m *MultiUserManager) RecordConnectionTradeForUser(userID, connectionID string, trade TradeResult) error {
+	mgr, err := m.GetOrCreate(userID)
+	if err != nil {
+		return err
+	}
+	mgr.RecordConnectionTrade(connectionID, trade)
+	return nil
+}
+
 // ResetDailyForAll resets daily metrics for all users.
 func (m *MultiUserManager) ResetDailyForAll() {
 	m.mu.RLock()
@@ -122,9 +207,29 @@ func (m *MultiUserManager) CleanupIdle(ttl time.Duration) {
 
 // EvaluateForUser evaluates a signal for a specific user.
 func (m *MultiUserManager) EvaluateForUser(userID string, signal SignalInput, position Position, account Account, strategyID string) (RiskDecision, error) {
+	return m.evaluateForUser(userID, signal, position, account, strategyID, true)
+}
+
+// SimulateForUser is EvaluateForUser for what-if previews: the check is not
+// counted in the user's risk metrics.
+func (m *MultiUserManager) SimulateForUser(userID string, signal SignalInput, position Position, account Account, strategyID string) (RiskDecision, error) {
+	return m.evaluateForUser(userID, signal, position, account, strategyID, false)
+}
+
+func (m *MultiUserManager) evaluateForUser(userID string, signal SignalInput, position Position, account Account, strategyID string, record bool) (RiskDecision, error) {
 	mgr, err := m.GetOrCreate(userID)
 	if err != nil {
 		return RiskDecision{Allowed: false, Reason: "failed to get risk manager"}, err
 	}
-	return mgr.EvaluateFull(signal, position, account, strategyID), nil
+	m.mu.RLock()
+	capFn := m.positionCap
+	m.mu.RUnlock()
+	maxPositions := 0
+	if capFn != nil {
+		if maxPositions, err = capFn(userID); err != nil {
+			log.Printf("⚠️ [User %s] position cap lookup failed: %v", userID, err)
+			maxPositions = 0
+		}
+	}
+	return mgr.evaluateFull(signal, position, account, strategyID, maxPositions, record), nil
 }
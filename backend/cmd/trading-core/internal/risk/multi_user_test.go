@@ -3,6 +3,8 @@ package risk
 import (
 	"testing"
 	"time"
+
+	"trading-core/pkg/db"
 )
 
 // TestMultiUserManagerCleanupIdle verifies that CleanupIdle removes only idle managers.
@@ -88,3 +90,102 @@ func TestMultiUserManagerGetMissingDoesNotCreate(t *testing.T) {
 		t.Fatalf("expected lastSeen to remain untouched for missing user")
 	}
 }
+
+// A strategy's risk config set through a user's manager is stored in the DB,
+// where the executor's drawdown stop reads it, and outlives the manager.
+func TestUserManagerPersistsStrategyConfig(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	users := NewMultiUserManager(database.DB)
+	mgr, err := users.GetOrCreate("u1")
+	if err != nil {
+		t.Fatalf("GetOrCreate: %v", err)
+	}
+	cfg := DefaultStrategyConfig("s1")
+	cfg.MaxDrawdownPct = 15
+	if err := mgr.SetStrategyConfig(cfg); err != nil {
+		t.Fatalf("SetStrategyConfig: %v", err)
+	}
+
+	var stored float64
+	if err := database.DB.QueryRow(`SELECT max_drawdown_pct FROM strategy_risk_configs WHERE strategy_instance_id = 's1'`).Scan(&stored); err != nil || stored != 15 {
+		t.Fatalf("stored max_drawdown_pct = %v (err %v), want 15", stored, err)
+	}
+
+	users.Remove("u1")
+	mgr, _ = users.GetOrCreate("u1")
+	if got := mgr.GetStrategyConfig("s1").MaxDrawdownPct; got != 15 {
+		t.Fatalf("config after eviction: max_drawdown_pct = %v, want 15", got)
+	}
+}
+
+func TestUserAllocatedCapitalCountsOnlyOwnStrategies(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	for _, s := range []struct{ id, user string }{{"a1", "u1"}, {"a2", "u1"}, {"b1", "u2"}} {
+		if _, err := database.DB.Exec(`
+			INSERT INTO strategy_instances (id, name, strategy_type, symbol, interval, parameters, user_id)
+			VALUES (?, ?, 'rsi', 'BTCUSDT', '1m', '{}', ?)
+		`, s.id, s.id, s.user); err != nil {
+			t.Fatalf("insert strategy %s: %v", s.id, err)
+		}
+	}
+
+	users := NewMultiUserManager(database.DB)
+	for _, s := range []struct {
+		user, id string
+		alloc    float64
+	}{{"u1", "a1", 300}, {"u1", "a2", 200}, {"u2", "b1", 5000}} {
+		mgr, err := users.GetOrCreate(s.user)
+		if err != nil {
+			t.Fatalf("GetOrCreate: %v", err)
+		}
+		cfg := DefaultStrategyConfig(s.id)
+		cfg.Allocation = s.alloc
+		if err := mgr.SetStrategyConfig(cfg); err != nil {
+			t.Fatalf("SetStrategyConfig(%s): %v", s.id, err)
+		}
+	}
+
+	// Evicted and recreated, the manager reads the allocations back from the DB.
+	users.Remove("u1")
+	mgr, _ := users.GetOrCreate("u1")
+	if total, err := mgr.AllocatedCapital(""); err != nil || total != 500 {
+		t.Fatalf("u1 AllocatedCapital = %v (err %v), want 500", total, err)
+	}
+	if others, _ := mgr.AllocatedCapital("a1"); others != 200 {
+		t.Fatalf("u1 AllocatedCapital(except a1) = %v, want 200", others)
+	}
+}
+
+func TestSimulateForUserDoesNotRecordMetrics(t *testing.T) {
+	mgr := NewMultiUserManager(nil)
+	sig := SignalInput{Symbol: "BTCUSDT", Action: "BUY", Size: 1, Price: 50000}
+
+	if _, err := mgr.SimulateForUser("u1", sig, Position{}, Account{Balance: 10000}, ""); err != nil {
+		t.Fatalf("SimulateForUser: %v", err)
+	}
+	m := mgr.Get("u1").GetMetrics()
+	if m.ChecksTotal != 0 || m.RejectionsTotal != 0 || m.WarningsTotal != 0 {
+		t.Fatalf("simulation recorded metrics: %+v", m)
+	}
+
+	if _, err := mgr.EvaluateForUser("u1", sig, Position{}, Account{Balance: 10000}, ""); err != nil {
+		t.Fatalf("EvaluateForUser: %v", err)
+	}
+	if got := mgr.Get("u1").GetMetrics().ChecksTotal; got != 1 {
+		t.Fatalf("ChecksTotal after a real check = %d, want 1", got)
+	}
+}
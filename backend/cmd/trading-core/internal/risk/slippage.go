@@ -0,0 +1,147 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SlippageEvent describes a fill that executed too far from its reference price.
+type SlippageEvent struct {
+	OrderID    string
+	StrategyID string
+	Symbol     string
+	Side       string
+	RefPrice   float64
+	FillPrice  float64
+	Slippage   float64 // adverse fraction, e.g. 0.012 = 1.2%
+	Max        float64
+}
+
+func (e SlippageEvent) String() string {
+	return fmt.Sprintf("slippage %.2f%% on %s %s (ref %.8g, fill %.8g, max %.2f%%)",
+		e.Slippage*100, e.Side, e.Symbol, e.RefPrice, e.FillPrice, e.Max*100)
+}
+
+type slippageRef struct {
+	strategyID string
+	symbol     string
+	side       string
+	price      float64
+	at         time.Time
+}
+
+// SlippageGuard enforces RiskConfig.MaxSlippage for MARKET orders. The price seen
+// at signal time is tracked per order; when the fill arrives, adverse slippage
+// beyond the limit raises an alert and blocks the strategy from adding to the
+// same side for a cooldown period.
+type SlippageGuard struct {
+	mu       sync.Mutex
+	max      func() float64
+	cooldown time.Duration
+	onAlert  func(SlippageEvent)
+	pending  map[string]slippageRef
+	blocked  map[string]time.Time
+	now      func() time.Time
+}
+
+// pendingTTL bounds how long an unfilled order's reference price is kept.
+const pendingTTL = 10 * time.Minute
+
+// NewSlippageGuard creates a guard; max returns the current limit (<= 0 disables it).
+func NewSlippageGuard(max func() float64, cooldown time.Duration, onAlert func(SlippageEvent)) *SlippageGuard {
+	if cooldown <= 0 {
+		cooldown = 5 * time.Minute
+	}
+	return &SlippageGuard{
+		max:      max,
+		cooldown: cooldown,
+		onAlert:  onAlert,
+		pending:  make(map[string]slippageRef),
+		blocked:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func slippageKey(strategyID, symbol, side string) string {
+	return strategyID + "|" + symbol + "|" + strings.ToUpper(side)
+}
+
+// Track records the reference price for an order about to be submitted.
+func (g *SlippageGuard) Track(orderID, strategyID, symbol, side string, refPrice float64) {
+	if orderID == "" || refPrice <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	for id, ref := range g.pending {
+		if now.Sub(ref.at) > pendingTTL {
+			delete(g.pending, id)
+		}
+	}
+	g.pending[orderID] = slippageRef{strategyID: strategyID, symbol: symbol, side: strings.ToUpper(side), price: refPrice, at: now}
+}
+
+// CheckFill compares a fill against the tracked reference price. It returns the
+// event and true when adverse slippage exceeded the limit.
+func (g *SlippageGuard) CheckFill(orderID string, fillPrice float64) (SlippageEvent, bool) {
+	g.mu.Lock()
+	ref, ok := g.pending[orderID]
+	delete(g.pending, orderID)
+	g.mu.Unlock()
+
+	max := 0.0
+	if g.max != nil {
+		max = g.max()
+	}
+	if !ok || fillPrice <= 0 || max <= 0 {
+		return SlippageEvent{}, false
+	}
+
+	slip := (fillPrice - ref.price) / ref.price
+	if ref.side == "SELL" {
+		slip = -slip
+	}
+	if slip <= max {
+		return SlippageEvent{}, false
+	}
+
+	ev := SlippageEvent{
+		OrderID:    orderID,
+		StrategyID: ref.strategyID,
+		Symbol:     ref.symbol,
+		Side:       ref.side,
+		RefPrice:   ref.price,
+		FillPrice:  fillPrice,
+		Slippage:   slip,
+		Max:        max,
+	}
+	if ref.strategyID != "" {
+		g.mu.Lock()
+		g.blocked[slippageKey(ref.strategyID, ref.symbol, ref.side)] = g.now().Add(g.cooldown)
+		g.mu.Unlock()
+	}
+	if g.onAlert != nil {
+		g.onAlert(ev)
+	}
+	return ev, true
+}
+
+// Blocked reports whether a strategy is cooling down after an over-slippage fill
+// on this symbol and side, so it should not compound into the position.
+func (g *SlippageGuard) Blocked(strategyID, symbol, side string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := slippageKey(strategyID, symbol, side)
+	until, ok := g.blocked[key]
+	if !ok {
+		return false
+	}
+	if g.now().After(until) {
+		delete(g.blocked, key)
+		return false
+	}
+	return true
+}
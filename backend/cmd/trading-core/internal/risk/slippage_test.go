@@ -0,0 +1,51 @@
+package risk
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSlippageGuardAlertsOnOverSlippageFill(t *testing.T) {
+	var alerts []SlippageEvent
+	g := NewSlippageGuard(func() float64 { return 0.005 }, time.Minute, func(ev SlippageEvent) {
+		alerts = append(alerts, ev)
+	})
+
+	// Within tolerance: 0.2% worse on a BUY.
+	g.Track("o1", "s1", "BTCUSDT", "BUY", 100)
+	if _, over := g.CheckFill("o1", 100.2); over {
+		t.Fatal("0.2% slippage should be within a 0.5% limit")
+	}
+
+	// Favorable fills never trip the guard.
+	g.Track("o2", "s1", "BTCUSDT", "SELL", 100)
+	if _, over := g.CheckFill("o2", 101); over {
+		t.Fatal("selling above the reference price is not adverse slippage")
+	}
+
+	// 1% worse on a BUY.
+	g.Track("o3", "s1", "BTCUSDT", "BUY", 100)
+	ev, over := g.CheckFill("o3", 101)
+	if !over {
+		t.Fatal("expected over-slippage fill to trip the guard")
+	}
+	if len(alerts) != 1 || alerts[0].OrderID != "o3" {
+		t.Fatalf("expected one alert for o3, got %+v", alerts)
+	}
+	if math.Abs(ev.Slippage-0.01) > 1e-9 {
+		t.Errorf("slippage = %v, want 0.01", ev.Slippage)
+	}
+
+	if !g.Blocked("s1", "BTCUSDT", "BUY") {
+		t.Error("strategy should be blocked from adding to the long after over-slippage")
+	}
+	if g.Blocked("s1", "BTCUSDT", "SELL") {
+		t.Error("closing side must not be blocked")
+	}
+
+	g.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if g.Blocked("s1", "BTCUSDT", "BUY") {
+		t.Error("block should expire after the cooldown")
+	}
+}
@@ -0,0 +1,241 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// LossStreakPause records a strategy paused for losing too many trades in a row.
+type LossStreakPause struct {
+	StrategyID string    `json:"strategy_id"`
+	UserID     string    `json:"user_id,omitempty"`
+	Losses     int       `json:"losses"`
+	Since      time.Time `json:"since"`
+}
+
+// BlockReason returns why an entry by the paused strategy is rejected.
+func (p LossStreakPause) BlockReason() string {
+	return fmt.Sprintf("[Strategy %s] paused after %d consecutive losing trades since %s",
+		p.StrategyID, p.Losses, p.Since.Format(time.RFC3339))
+}
+
+// LossStreaks counts consecutive losing trades per strategy instance (each
+// owned by one user) and per user, and pauses a strategy's new entries once
+// it loses max trades in a row. Unlike the daily loss limit this reacts to a run of losses
+// of any size, which usually means a broken strategy or a regime change.
+//
+// Exits stay allowed so a paused strategy can still close; its next winning
+// trade, an operator Resume, or the start of a new UTC day clears the streak
+// and the pause. Pauses are stored once Restore attached a DB, so a restart
+// on the same day keeps them. A nil tracker, or max <= 0, pauses nothing.
+type LossStreaks struct {
+	mu      sync.Mutex
+	max     int
+	db      *sql.DB
+	counts  map[string]int // strategy ID -> consecutive losses
+	users   map[string]int // user ID -> consecutive losses across the user's trades
+	paused  map[string]LossStreakPause
+	day     string
+	alertFn func(LossStreakPause)
+	now     func() time.Time
+}
+
+// NewLossStreaks creates a tracker that pauses a strategy after max consecutive losses.
+func NewLossStreaks(max int) *LossStreaks {
+	return &LossStreaks{
+		max:    max,
+		counts: make(map[string]int),
+		users:  make(map[string]int),
+		paused: make(map[string]LossStreakPause),
+		now:    time.Now,
+	}
+}
+
+// Restore stores pauses in db from now on and reloads today's pauses, so a
+// restart doesn't lift them. Pauses from earlier days are dropped.
+func (s *LossStreaks) Restore(ctx context.Context, db *sql.DB) error {
+	today := s.now().UTC().Format("2006-01-02")
+	if _, err := db.ExecContext(ctx, `DELETE FROM loss_streak_pauses WHERE day <> ?`, today); err != nil {
+		return fmt.Errorf("drop stale loss-streak pauses: %w", err)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT strategy_id, user_id, losses, since FROM loss_streak_pauses`)
+	if err != nil {
+		return fmt.Errorf("load loss-streak pauses: %w", err)
+	}
+	defer rows.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolloverLocked()
+	s.db = db
+	for rows.Next() {
+		var p LossStreakPause
+		if err := rows.Scan(&p.StrategyID, &p.UserID, &p.Losses, &p.Since); err != nil {
+			return err
+		}
+		s.paused[p.StrategyID] = p
+		s.counts[p.StrategyID] = p.Losses
+	}
+	return rows.Err()
+}
+
+// SetAlertFn sets the callback invoked when a strategy is paused.
+func (s *LossStreaks) SetAlertFn(fn func(LossStreakPause)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alertFn = fn
+}
+
+// Record books a realized trade. Only fills that closed (part of) a position
+// count, towards the user's streak and, when they carry a strategy ID, the
+// strategy's; break-even trades leave the streaks as they are.
+func (s *LossStreaks) Record(trade TradeResult) {
+	if s == nil || !trade.Closed || trade.PnL == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.rolloverLocked()
+	if trade.UserID != "" {
+		if trade.PnL > 0 {
+			delete(s.users, trade.UserID)
+		} else {
+			s.users[trade.UserID]++
+		}
+	}
+	id := trade.StrategyID
+	if id == "" {
+		s.mu.Unlock()
+		return
+	}
+	db := s.db
+	if trade.PnL > 0 {
+		delete(s.counts, id)
+		_, lifted := s.paused[id]
+		delete(s.paused, id)
+		s.mu.Unlock()
+		if lifted {
+			log.Printf("Strategy %s loss-streak pause lifted after a winning trade", id)
+			deletePause(db, id)
+		}
+		return
+	}
+	s.counts[id]++
+	n := s.counts[id]
+	pause, already := s.paused[id]
+	trip := s.max > 0 && n >= s.max && !already
+	if trip {
+		pause = LossStreakPause{StrategyID: id, UserID: trade.UserID, Losses: n, Since: s.now().UTC()}
+	}
+	if trip || already {
+		pause.Losses = n
+		s.paused[id] = pause
+	}
+	fn := s.alertFn
+	s.mu.Unlock()
+
+	if trip || already {
+		savePause(db, pause)
+	}
+	if trip {
+		log.Printf("⚠️ %s", pause.BlockReason())
+		if fn != nil {
+			fn(pause)
+		}
+	}
+}
+
+// Streak returns the strategy's current number of consecutive losing trades.
+func (s *LossStreaks) Streak(strategyID string) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolloverLocked()
+	return s.counts[strategyID]
+}
+
+// UserStreak returns the user's current number of consecutive losing trades
+// across all of their strategies and manual orders.
+func (s *LossStreaks) UserStreak(userID string) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolloverLocked()
+	return s.users[userID]
+}
+
+// Paused returns the strategy's loss-streak pause, if any.
+func (s *LossStreaks) Paused(strategyID string) (LossStreakPause, bool) {
+	if s == nil || strategyID == "" {
+		return LossStreakPause{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolloverLocked()
+	p, ok := s.paused[strategyID]
+	return p, ok
+}
+
+// Resume clears the strategy's streak and pause and reports whether it was paused.
+func (s *LossStreaks) Resume(strategyID string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	_, ok := s.paused[strategyID]
+	delete(s.paused, strategyID)
+	delete(s.counts, strategyID)
+	db := s.db
+	s.mu.Unlock()
+
+	if ok {
+		deletePause(db, strategyID)
+	}
+	return ok
+}
+
+// rolloverLocked starts every streak afresh on a new UTC day.
+func (s *LossStreaks) rolloverLocked() {
+	today := s.now().UTC().Format("2006-01-02")
+	if s.day == today {
+		return
+	}
+	if s.day != "" && len(s.paused) > 0 {
+		log.Printf("Daily reset: lifting %d loss-streak pause(s)", len(s.paused))
+	}
+	s.day = today
+	s.counts = make(map[string]int)
+	s.users = make(map[string]int)
+	s.paused = make(map[string]LossStreakPause)
+}
+
+// savePause stores a strategy's pause for the day it started.
+func savePause(db *sql.DB, p LossStreakPause) {
+	if db == nil {
+		return
+	}
+	_, err := db.Exec(`
+		INSERT INTO loss_streak_pauses (strategy_id, user_id, losses, since, day) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(strategy_id) DO UPDATE SET losses = excluded.losses
+	`, p.StrategyID, p.UserID, p.Losses, p.Since, p.Since.Format("2006-01-02"))
+	if err != nil {
+		log.Printf("⚠️ Persist loss-streak pause of strategy %s: %v", p.StrategyID, err)
+	}
+}
+
+// deletePause removes a lifted pause.
+func deletePause(db *sql.DB, strategyID string) {
+	if db == nil {
+		return
+	}
+	if _, err := db.Exec(`DELETE FROM loss_streak_pauses WHERE strategy_id = ?`, strategyID); err != nil {
+		log.Printf("⚠️ Remove loss-streak pause of strategy %s: %v", strategyID, err)
+	}
+}
@@ -1,6 +1,7 @@
 package risk
 
 import (
+	"strings"
 	"time"
 )
 
@@ -10,6 +11,12 @@ const (
 	FailModeLimit = "FAIL_LIMIT" // Use fallback size on error
 )
 
+// Position sizing modes
+const (
+	SizingFixed        = "fixed"         // Default: use the signal's size
+	SizingRiskFraction = "risk_fraction" // Risk RiskPerTrade of equity down to the stop loss
+)
+
 // QuickCheckResult represents fast pre-validation result
 type QuickCheckResult struct {
 	Allowed    bool    `json:"allowed"`
@@ -28,16 +35,37 @@ type RiskConfig struct {
 	MaxTotalExposure float64 `json:"max_total_exposure"`
 	DefaultLeverage  float64 `json:"default_leverage"`
 
+	// SymbolPositionLimits overrides MaxPositionSize per symbol (quote notional).
+	SymbolPositionLimits map[string]float64 `json:"symbol_position_limits,omitempty"`
+
+	// MaxConcurrentPositions caps how many distinct symbols may be held at
+	// once; entries on a new symbol are rejected at the cap (0 = no limit).
+	MaxConcurrentPositions int `json:"max_concurrent_positions"`
+
+	// CorrelationGroups caps the combined notional of symbols that move
+	// together (e.g. majors), which the per-symbol limits treat as independent.
+	CorrelationGroups []CorrelationGroup `json:"correlation_groups,omitempty"`
+
 	// Stop Loss / Take Profit
 	DefaultStopLoss   float64 `json:"default_stop_loss"`
 	DefaultTakeProfit float64 `json:"default_take_profit"`
 	UseTrailingStop   bool    `json:"use_trailing_stop"`
 	TrailingPercent   float64 `json:"trailing_percent"`
 
+	// SymbolSLTP overrides the SL/TP fractions per symbol (e.g. wider stops
+	// for volatile alts), ahead of strategy and global defaults.
+	SymbolSLTP map[string]SymbolSLTP `json:"symbol_sltp,omitempty"`
+
 	// Daily Limits
 	MaxDailyLoss   float64 `json:"max_daily_loss"`
 	MaxDailyTrades int     `json:"max_daily_trades"`
 
+	// Position sizing: with SizingRiskFraction, entries are sized so that
+	// hitting the stop loses RiskPerTrade of equity instead of using the
+	// signal's size.
+	SizingMode   string  `json:"sizing_mode"`
+	RiskPerTrade float64 `json:"risk_per_trade"` // 0.01 = 1% of equity
+
 	// Order Validation
 	MinOrderSize float64 `json:"min_order_size"`
 	MaxOrderSize float64 `json:"max_order_size"`
@@ -66,6 +94,14 @@ type RiskConfig struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// CorrelationGroup is a set of correlated symbols whose combined exposure
+// (quote notional) may not exceed MaxExposure.
+type CorrelationGroup struct {
+	Name        string   `json:"name"`
+	Symbols     []string `json:"symbols"`
+	MaxExposure float64  `json:"max_exposure"`
+}
+
 // RiskMetrics tracks current risk status
 type RiskMetrics struct {
 	// Daily Statistics
@@ -110,6 +146,8 @@ type Position struct {
 	Quantity      float64 `json:"quantity"`
 	Value         float64 `json:"value"`
 	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	// OpenedAt is when the current position was entered (zero when unknown).
+	OpenedAt time.Time `json:"opened_at,omitempty"`
 }
 
 // Account represents account information
@@ -118,6 +156,12 @@ type Account struct {
 	AvailableBalance float64 `json:"available_balance"`
 	LockedBalance    float64 `json:"locked_balance"`
 	TotalExposure    float64 `json:"total_exposure"`
+	// SymbolExposure is the absolute notional held per symbol, used for
+	// correlation group limits.
+	SymbolExposure map[string]float64 `json:"symbol_exposure,omitempty"`
+	// OpenPositions lists the distinct symbols with a non-zero position,
+	// used for MaxConcurrentPositions.
+	OpenPositions []string `json:"open_positions,omitempty"`
 }
 
 // DefaultConfig returns default risk configuration
@@ -126,6 +170,7 @@ func DefaultConfig() RiskConfig {
 		Name:                 "default",
 		MaxPositionSize:      1000.0,
 		MaxTotalExposure:     5000.0,
+		CorrelationGroups:    DefaultCorrelationGroups(),
 		DefaultLeverage:      1.0,
 		DefaultStopLoss:      0.02,
 		DefaultTakeProfit:    0.05,
@@ -133,6 +178,8 @@ func DefaultConfig() RiskConfig {
 		TrailingPercent:      0.015,
 		MaxDailyLoss:         2000.0,
 		MaxDailyTrades:       20,
+		SizingMode:           SizingFixed,
+		RiskPerTrade:         0.01,
 		MinOrderSize:         10.0,
 		MaxOrderSize:         10000.0,
 		MaxSlippage:          0.005,
@@ -151,6 +198,55 @@ func DefaultConfig() RiskConfig {
 	}
 }
 
+// SymbolPositionLimit returns the per-symbol override, if one is configured.
+func (c RiskConfig) SymbolPositionLimit(symbol string) (float64, bool) {
+	limit, ok := c.SymbolPositionLimits[strings.ToUpper(symbol)]
+	return limit, ok
+}
+
+// SymbolSLTP is a per-symbol stop loss / take profit override; a zero field
+// inherits the strategy or global default.
+type SymbolSLTP struct {
+	StopLoss   float64 `json:"stop_loss,omitempty"`
+	TakeProfit float64 `json:"take_profit,omitempty"`
+}
+
+// SymbolSLTPFor returns the SL/TP override for symbol, if one is configured.
+func (c RiskConfig) SymbolSLTPFor(symbol string) (SymbolSLTP, bool) {
+	o, ok := c.SymbolSLTP[strings.ToUpper(symbol)]
+	return o, ok
+}
+
+// PositionLimitFor returns the position cap for a symbol, falling back to
+// MaxPositionSize when no override is configured.
+func (c RiskConfig) PositionLimitFor(symbol string) float64 {
+	if limit, ok := c.SymbolPositionLimit(symbol); ok {
+		return limit
+	}
+	return c.MaxPositionSize
+}
+
+// DefaultCorrelationGroups buckets the majors, which tend to move together,
+// under one cap of twice the default per-symbol position size.
+func DefaultCorrelationGroups() []CorrelationGroup {
+	return []CorrelationGroup{
+		{Name: "majors", Symbols: []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT"}, MaxExposure: 2000.0},
+	}
+}
+
+// CorrelationGroupFor returns the first correlation group containing symbol.
+func (c RiskConfig) CorrelationGroupFor(symbol string) (CorrelationGroup, bool) {
+	symbol = strings.ToUpper(symbol)
+	for _, g := range c.CorrelationGroups {
+		for _, s := range g.Symbols {
+			if s == symbol {
+				return g, true
+			}
+		}
+	}
+	return CorrelationGroup{}, false
+}
+
 // StrategyRiskConfig defines per-strategy risk settings
 type StrategyRiskConfig struct {
 	StrategyInstanceID string `json:"strategy_instance_id"`
@@ -160,6 +256,18 @@ type StrategyRiskConfig struct {
 	MinOrderSize    float64 `json:"min_order_size"`
 	MaxOrderSize    float64 `json:"max_order_size"`
 
+	// Capital allocation: max notional the strategy may hold across its
+	// strategy_positions (0 = unlimited)
+	Allocation float64 `json:"allocation"`
+
+	// Drawdown stop: stop and flatten the strategy once its equity (allocation
+	// plus realized PnL) falls this % below its peak (0 = off)
+	MaxDrawdownPct float64 `json:"max_drawdown_pct"`
+
+	// Minimum hold: exit/reversal signals are rejected until the position has
+	// been open this long (0 = off). Stop losses are not subject to it.
+	MinHoldSeconds int `json:"min_hold_seconds"`
+
 	// Stop Loss / Take Profit (nil means use global default)
 	StopLoss        *float64 `json:"stop_loss"`
 	TakeProfit      *float64 `json:"take_profit"`
@@ -177,6 +285,18 @@ type StrategyRiskConfig struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// StrategyDrawdownPct is the strategy's MaxDrawdown scoped to one strategy:
+// how far its equity (allocation + realized PnL) is below the peak, as a % of
+// the peak. Without an allocation it is the share of peak profit given back.
+// ok is false until there is a positive peak to measure against.
+func StrategyDrawdownPct(allocation, realizedPnL, peakPnL float64) (float64, bool) {
+	peak := allocation + peakPnL
+	if peak <= 0 {
+		return 0, false
+	}
+	return (peakPnL - realizedPnL) / peak * 100, true
+}
+
 // DefaultStrategyConfig returns default per-strategy risk config
 func DefaultStrategyConfig(strategyID string) StrategyRiskConfig {
 	return StrategyRiskConfig{
@@ -0,0 +1,101 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// loadUserConfig returns the risk config stored for userID, or ok=false when
+// the user never saved one.
+func loadUserConfig(db *sql.DB, userID string) (cfg RiskConfig, ok bool, err error) {
+	var raw string
+	err = db.QueryRow(`SELECT config FROM user_risk_configs WHERE user_id = ?`, userID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return cfg, false, nil
+	}
+	if err != nil {
+		return cfg, false, err
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return cfg, false, fmt.Errorf("decode risk config of user %s: %w", userID, err)
+	}
+	return cfg, true, nil
+}
+
+// saveUserConfig stores a user's risk config.
+func saveUserConfig(ctx context.Context, db *sql.DB, userID string, cfg RiskConfig) error {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("encode risk config: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO user_risk_configs (user_id, config, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET config = excluded.config, updated_at = CURRENT_TIMESTAMP
+	`, userID, string(raw))
+	return err
+}
+
+// LoosenedLimits lists the protective settings that cfg relaxes compared with
+// base: a risk switch turned off, a cap raised or removed (0 = unlimited), a
+// correlation group dropped or widened, or a per-symbol position limit above
+// base's MaxPositionSize. Changes that only tighten are not listed.
+func LoosenedLimits(base, cfg RiskConfig) []string {
+	var out []string
+	switchOff := func(name string, was, now bool) {
+		if was && !now {
+			out = append(out, name)
+		}
+	}
+	switchOff("enable_risk", base.EnableRisk, cfg.EnableRisk)
+	switchOff("use_daily_loss_limit", base.UseDailyLossLimit, cfg.UseDailyLossLimit)
+	switchOff("use_daily_trade_limit", base.UseDailyTradeLimit, cfg.UseDailyTradeLimit)
+	switchOff("use_order_size_limits", base.UseOrderSizeLimits, cfg.UseOrderSizeLimits)
+	switchOff("use_position_size_limit", base.UsePositionSizeLimit, cfg.UsePositionSizeLimit)
+	switchOff("use_exposure_limit", base.UseExposureLimit, cfg.UseExposureLimit)
+
+	raised := func(name string, was, now float64) {
+		if was > 0 && (now <= 0 || now > was) {
+			out = append(out, name)
+		}
+	}
+	raised("max_position_size", base.MaxPositionSize, cfg.MaxPositionSize)
+	raised("max_total_exposure", base.MaxTotalExposure, cfg.MaxTotalExposure)
+	raised("max_daily_loss", base.MaxDailyLoss, cfg.MaxDailyLoss)
+	raised("max_daily_trades", float64(base.MaxDailyTrades), float64(cfg.MaxDailyTrades))
+	raised("max_order_size", base.MaxOrderSize, cfg.MaxOrderSize)
+	raised("max_slippage", base.MaxSlippage, cfg.MaxSlippage)
+	raised("default_leverage", base.DefaultLeverage, cfg.DefaultLeverage)
+	raised("max_concurrent_positions", float64(base.MaxConcurrentPositions), float64(cfg.MaxConcurrentPositions))
+	if base.FailureMode == FailModeClose && cfg.FailureMode != "" && cfg.FailureMode != FailModeClose {
+		out = append(out, "failure_mode")
+	}
+
+	for _, g := range base.CorrelationGroups {
+		found := false
+		for _, h := range cfg.CorrelationGroups {
+			if strings.EqualFold(h.Name, g.Name) {
+				found = true
+				if g.MaxExposure > 0 && (h.MaxExposure <= 0 || h.MaxExposure > g.MaxExposure) {
+					out = append(out, "correlation_groups."+g.Name)
+				}
+			}
+		}
+		if !found {
+			out = append(out, "correlation_groups."+g.Name)
+		}
+	}
+	if base.MaxPositionSize > 0 {
+		for sym, limit := range cfg.SymbolPositionLimits {
+			if baseLimit, ok := base.SymbolPositionLimit(sym); ok && limit <= baseLimit {
+				continue
+			}
+			if limit <= 0 || limit > base.MaxPositionSize {
+				out = append(out, "symbol_position_limits."+strings.ToUpper(sym))
+			}
+		}
+	}
+	return out
+}
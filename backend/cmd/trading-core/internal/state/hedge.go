@@ -0,0 +1,225 @@
+package state
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	"trading-core/pkg/db"
+	"trading-core/pkg/money"
+)
+
+// Position sides of a hedge-mode futures account.
+const (
+	SideLong  = "LONG"
+	SideShort = "SHORT"
+)
+
+// SetHedgeMode switches fills carrying a LONG/SHORT position side to
+// per-side tracking, matching a futures account in hedge (dual-side) mode
+// where a symbol can be long and short at once. The net position per symbol
+// is still maintained for exposure and risk checks.
+func (m *Manager) SetHedgeMode(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hedge = on
+}
+
+// HedgeMode reports whether per-side tracking is on.
+func (m *Manager) HedgeMode() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.hedge
+}
+
+// legKey identifies one side of a symbol in one account: legs are kept per
+// user and connection, since each exchange account holds its own legs.
+func legKey(userID, connectionID, symbol, positionSide string) string {
+	return userID + "|" + connectionID + "|" + symbol + "|" + positionSide
+}
+
+// hedgeSide returns the normalized LONG/SHORT side, or "" when the fill
+// belongs to the net position (one-way mode, BOTH, or unset).
+func hedgeSide(positionSide string) string {
+	switch side := strings.ToUpper(positionSide); side {
+	case SideLong, SideShort:
+		return side
+	}
+	return ""
+}
+
+// Leg returns one side of a hedge-mode position in the account of userID
+// and connectionID. A LONG leg has a positive Qty and a SHORT leg a negative
+// one; both are zero when the side is flat.
+func (m *Manager) Leg(userID, connectionID, symbol, positionSide string) db.Position {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.legs[legKey(userID, connectionID, symbol, hedgeSide(positionSide))]
+}
+
+// PositionFor returns the position a fill on positionSide applies to: the
+// account's leg in hedge mode, otherwise the user's own net position (the
+// symbol's net position without a userID).
+func (m *Manager) PositionFor(userID, connectionID, symbol, positionSide string) db.Position {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if legSide := hedgeSide(positionSide); m.hedge && legSide != "" {
+		return m.legs[legKey(userID, connectionID, symbol, legSide)]
+	}
+	if userID != "" {
+		return m.users[userPositionKey(userID, symbol)]
+	}
+	return m.positions[symbol]
+}
+
+// Legs returns a snapshot of all open hedge-mode legs, sorted by symbol,
+// user, connection, then side.
+func (m *Manager) Legs() []db.Position {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := make([]db.Position, 0, len(m.legs))
+	for _, l := range m.legs {
+		if l.Qty != 0 {
+			res = append(res, l)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		if a.ConnectionID != b.ConnectionID {
+			return a.ConnectionID < b.ConnectionID
+		}
+		return a.PositionSide < b.PositionSide
+	})
+	return res
+}
+
+// ApplySideFill is ApplyFill for a fill on positionSide in the account of
+// userID and connectionID. In hedge mode a LONG or SHORT fill only moves
+// that side's leg: BUY opens LONG and closes SHORT, SELL the reverse, and
+// PnL is realized against the leg's own average. A leg is never flipped;
+// closing more than it holds just flattens it. The returned position is the
+// user's net on the symbol. Other fills fall through to ApplyFill.
+func (m *Manager) ApplySideFill(ctx context.Context, userID, connectionID, symbol, side, positionSide string, qty, price, fee float64) (db.Position, float64, error) {
+	legSide := hedgeSide(positionSide)
+	if !m.HedgeMode() || legSide == "" {
+		return m.ApplyFill(ctx, userID, symbol, side, qty, price, fee)
+	}
+	side = strings.ToUpper(side)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leg := m.legs[legKey(userID, connectionID, symbol, legSide)]
+	newQty, newAvg, realized := FillPosition(leg.Qty, leg.AvgPrice, side, qty, price)
+	if (legSide == SideLong && newQty < 0) || (legSide == SideShort && newQty > 0) {
+		newQty, newAvg = 0, 0
+	}
+	m.bookRealizedLocked(money.AddFloats(realized, -fee))
+
+	if err := m.storeLegLocked(ctx, userID, connectionID, symbol, legSide, newQty, newAvg); err != nil {
+		return db.Position{}, 0, err
+	}
+	return m.storeLegNetsLocked(ctx, userID, symbol), realized, nil
+}
+
+// SetLeg directly sets one side of an account's hedge-mode position and
+// recomputes the nets, e.g. when importing legs reported by the exchange.
+// It does nothing for a position side other than LONG or SHORT.
+func (m *Manager) SetLeg(ctx context.Context, userID, connectionID, symbol, positionSide string, qty, avgPrice float64) error {
+	legSide := hedgeSide(positionSide)
+	if legSide == "" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.storeLegLocked(ctx, userID, connectionID, symbol, legSide, qty, avgPrice); err != nil {
+		return err
+	}
+	m.storeLegNetsLocked(ctx, userID, symbol)
+	return nil
+}
+
+// storeLegLocked persists one leg and makes it current.
+func (m *Manager) storeLegLocked(ctx context.Context, userID, connectionID, symbol, legSide string, qty, avgPrice float64) error {
+	if qty == 0 {
+		avgPrice = 0
+	}
+	leg := db.Position{
+		Symbol: symbol, PositionSide: legSide, Qty: qty, AvgPrice: avgPrice,
+		UserID: userID, ConnectionID: connectionID,
+	}
+	if m.db != nil {
+		if err := m.db.UpsertPositionLeg(ctx, leg); err != nil {
+			return err
+		}
+	}
+	m.legs[legKey(userID, connectionID, symbol, legSide)] = leg
+	return nil
+}
+
+// storeLegNetsLocked recomputes symbol's net positions from the legs after
+// one of userID's legs moved: the symbol's net across all accounts, and
+// userID's own net, stored as the user's position. It returns the user's net.
+func (m *Manager) storeLegNetsLocked(ctx context.Context, userID, symbol string) db.Position {
+	var all, mine legNet
+	for _, l := range m.legs {
+		if l.Symbol != symbol {
+			continue
+		}
+		all.add(l)
+		if l.UserID == userID {
+			mine.add(l)
+		}
+	}
+	net := all.position(symbol)
+	net.UserID = userID
+	if m.db != nil {
+		_ = m.db.UpsertPosition(ctx, net)
+	}
+	m.positions[symbol] = net
+
+	own := mine.position(symbol)
+	own.UserID = userID
+	if userID != "" {
+		m.storeUserLocked(ctx, own)
+	}
+	return own
+}
+
+// legNet sums hedge-mode legs into a net position.
+type legNet struct {
+	long, short         float64
+	longCost, shortCost float64
+}
+
+func (n *legNet) add(l db.Position) {
+	if l.Qty > 0 {
+		n.long += l.Qty
+		n.longCost += l.Qty * l.AvgPrice
+	} else {
+		n.short += l.Qty
+		n.shortCost += l.Qty * l.AvgPrice
+	}
+}
+
+// position returns the net quantity, priced at the average of the side
+// that outweighs the other.
+func (n legNet) position(symbol string) db.Position {
+	p := db.Position{Symbol: symbol, Qty: n.long + n.short}
+	switch {
+	case math.Abs(p.Qty) < 1e-9:
+		p.Qty = 0
+	case p.Qty > 0:
+		p.AvgPrice = n.longCost / n.long
+	default:
+		p.AvgPrice = n.shortCost / n.short
+	}
+	return p
+}
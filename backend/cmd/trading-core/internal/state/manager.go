@@ -3,22 +3,37 @@ package state
 import (
 	"context"
 	"math"
+	"strings"
 	"sync"
+	"time"
 
 	"trading-core/pkg/db"
+	"trading-core/pkg/money"
 )
 
 // Manager keeps an in-memory view of positions (and later open orders) while persisting to DB for durability.
 type Manager struct {
 	mu        sync.RWMutex
 	positions map[string]db.Position
+	users     map[string]db.Position // userPositionKey(user, symbol) -> the user's own net position
 	db        *db.Database
+
+	// Hedge mode keeps LONG and SHORT legs per symbol (see hedge.go);
+	// positions then holds their net.
+	hedge bool
+	legs  map[string]db.Position // legKey(user, connection, symbol, side) -> leg
+
+	// Realized PnL (net of fees) booked on the current UTC day.
+	realizedDay   string
+	realizedToday money.Amount
 }
 
 func NewManager(database *db.Database) *Manager {
 	return &Manager{
 		db:        database,
 		positions: make(map[string]db.Position),
+		users:     make(map[string]db.Position),
+		legs:      make(map[string]db.Position),
 	}
 }
 
@@ -36,6 +51,20 @@ func (m *Manager) Load(ctx context.Context) error {
 	for _, p := range pos {
 		m.positions[p.Symbol] = p
 	}
+	own, err := m.db.ListUserPositions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range own {
+		m.users[userPositionKey(p.UserID, p.Symbol)] = p
+	}
+	legs, err := m.db.ListPositionLegs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, l := range legs {
+		m.legs[legKey(l.UserID, l.ConnectionID, l.Symbol, l.PositionSide)] = l
+	}
 	return nil
 }
 
@@ -57,19 +86,141 @@ func (m *Manager) Positions() []db.Position {
 	return res
 }
 
+// UserPosition returns userID's own net position in symbol, which other
+// users' fills on the symbol don't move. An empty userID means the global
+// (single-user) position.
+func (m *Manager) UserPosition(userID, symbol string) db.Position {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if userID == "" {
+		return m.positions[symbol]
+	}
+	return m.users[userPositionKey(userID, symbol)]
+}
+
+// UserPositions returns a snapshot of userID's own positions.
+func (m *Manager) UserPositions(userID string) []db.Position {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var res []db.Position
+	for _, p := range m.users {
+		if p.UserID == userID {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// SetUserPosition directly sets userID's own position in symbol, e.g. when
+// importing what the user's account already holds.
+func (m *Manager) SetUserPosition(ctx context.Context, userID, symbol string, qty, avgPrice float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storeUserLocked(ctx, db.Position{Symbol: symbol, Qty: qty, AvgPrice: avgPrice, UserID: userID})
+}
+
+func userPositionKey(userID, symbol string) string {
+	return userID + "|" + symbol
+}
+
+// UnrealizedPnL returns the mark-to-market PnL of a signed position with
+// average entry avgPrice. Longs gain when mark rises, shorts when it falls.
+func UnrealizedPnL(qty, avgPrice, mark float64) float64 {
+	if qty == 0 || mark <= 0 || avgPrice <= 0 {
+		return 0
+	}
+	if qty > 0 {
+		return (mark - avgPrice) * qty
+	}
+	return (avgPrice - mark) * -qty
+}
+
+// UnrealizedPnL returns the unrealized PnL of the in-memory position for symbol at markPrice.
+func (m *Manager) UnrealizedPnL(symbol string, markPrice float64) float64 {
+	p := m.Position(symbol)
+	return UnrealizedPnL(p.Qty, p.AvgPrice, markPrice)
+}
+
+// RealizedToday returns realized PnL net of fees booked since 00:00 UTC.
+func (m *Manager) RealizedToday() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.realizedDay != time.Now().UTC().Format("2006-01-02") {
+		return 0
+	}
+	return m.realizedToday.Float64()
+}
+
+// bookRealizedLocked adds amount to today's realized PnL, rolling over at UTC midnight.
+func (m *Manager) bookRealizedLocked(amount float64) {
+	day := time.Now().UTC().Format("2006-01-02")
+	if m.realizedDay != day {
+		m.realizedDay = day
+		m.realizedToday = money.Zero
+	}
+	m.realizedToday = m.realizedToday.Add(money.FromFloat(amount))
+}
+
 // RecordFill adjusts position in-memory and persists it.
 // This is a simplified PnL model; extend as needed when real fills are available.
 func (m *Manager) RecordFill(ctx context.Context, userID, symbol, side string, qty, price float64) (db.Position, error) {
+	p, _, err := m.ApplyFill(ctx, userID, symbol, side, qty, price, 0)
+	return p, err
+}
+
+// ApplyFill is RecordFill that also returns the PnL realized on any closed
+// quantity (before fees) and books it, net of fee, into RealizedToday. A fill
+// with a userID also moves that user's own position; the user's position and
+// the PnL realized against it are then returned.
+func (m *Manager) ApplyFill(ctx context.Context, userID, symbol, side string, qty, price, fee float64) (db.Position, float64, error) {
+	side = strings.ToUpper(side)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	p := m.positions[symbol]
 	oldQty := p.Qty
-	oldAvg := p.AvgPrice
+	newQty, newAvg, realized := FillPosition(oldQty, p.AvgPrice, side, qty, price)
+
+	p.Symbol = symbol
+	p.Qty = newQty
+	p.AvgPrice = newAvg
+	p.UserID = userID
+	m.storeNetLocked(ctx, p)
+	if userID == "" {
+		m.bookRealizedLocked(money.AddFloats(realized, -fee))
+		return p, realized, nil
+	}
 
-	var newQty float64
-	var newAvg float64
+	own := m.users[userPositionKey(userID, symbol)]
+	ownQty, ownAvg, ownRealized := FillPosition(own.Qty, own.AvgPrice, side, qty, price)
+	m.bookRealizedLocked(money.AddFloats(ownRealized, -fee))
+	own = db.Position{Symbol: symbol, Qty: ownQty, AvgPrice: ownAvg, UserID: userID}
+	m.storeUserLocked(ctx, own)
+	return own, ownRealized, nil
+}
+
+// storeNetLocked persists the net position p and makes it current.
+func (m *Manager) storeNetLocked(ctx context.Context, p db.Position) {
+	if m.db != nil {
+		// Legacy global positions table (backwards compatibility)
+		_ = m.db.UpsertPosition(ctx, p)
+	}
+	m.positions[p.Symbol] = p
+}
+
+// storeUserLocked persists a user's own position (multi-user isolation) and makes it current.
+func (m *Manager) storeUserLocked(ctx context.Context, p db.Position) {
+	if m.db != nil {
+		_ = m.db.Queries().UpsertPositionWithUser(ctx, p.UserID, p.Symbol, p.Qty, p.AvgPrice)
+	}
+	m.users[userPositionKey(p.UserID, p.Symbol)] = p
+}
 
+// FillPosition applies a BUY or SELL of qty at price to a signed position
+// with average entry oldAvg, returning the new position and the PnL realized
+// on any closed quantity (before fees).
+func FillPosition(oldQty, oldAvg float64, side string, qty, price float64) (newQty, newAvg, realized float64) {
 	switch side {
 	case "BUY":
 		newQty = oldQty + qty
@@ -127,29 +278,38 @@ func (m *Manager) RecordFill(ctx context.Context, userID, symbol, side string, q
 		newAvg = oldAvg
 	}
 
-	p.Symbol = symbol
-	p.Qty = newQty
-	p.AvgPrice = newAvg
-	p.UserID = userID
-
-	if m.db != nil {
-		// Legacy global positions table (backwards compatibility)
-		_ = m.db.UpsertPosition(ctx, p)
-
-		// Per-user positions table for multi-user isolation
-		if userID != "" {
-			_ = m.db.Queries().UpsertPositionWithUser(ctx, userID, symbol, newQty, newAvg)
-		}
+	switch {
+	case side == "SELL" && oldQty > 0:
+		realized = db.RealizedPnL(oldAvg, price, math.Min(oldQty, qty))
+	case side == "BUY" && oldQty < 0:
+		realized = db.RealizedPnL(price, oldAvg, math.Min(-oldQty, qty))
 	}
-	m.positions[symbol] = p
-	return p, nil
+	return newQty, newAvg, realized
 }
 
-// SetPosition directly sets a position (used by reconciliation for syncing)
+// SetPosition directly sets a position (used by reconciliation for syncing).
+// In hedge mode it seeds the global account's legs instead, putting qty on
+// the side it points to and flattening the other, so later fills on either
+// side find a leg that agrees with the net.
 func (m *Manager) SetPosition(ctx context.Context, symbol string, qty, avgPrice float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.hedge {
+		long, short := qty, 0.0
+		if qty < 0 {
+			long, short = 0, qty
+		}
+		if err := m.storeLegLocked(ctx, "", "", symbol, SideLong, long, avgPrice); err != nil {
+			return err
+		}
+		if err := m.storeLegLocked(ctx, "", "", symbol, SideShort, short, avgPrice); err != nil {
+			return err
+		}
+		m.storeLegNetsLocked(ctx, "", symbol)
+		return nil
+	}
+
 	p := db.Position{
 		Symbol:   symbol,
 		Qty:      qty,
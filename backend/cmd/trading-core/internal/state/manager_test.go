@@ -0,0 +1,185 @@
+package state
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"trading-core/pkg/db"
+)
+
+func TestUnrealizedPnLLong(t *testing.T) {
+	m := NewManager(nil)
+	ctx := context.Background()
+	if _, err := m.RecordFill(ctx, "", "BTCUSDT", "BUY", 2, 100); err != nil {
+		t.Fatalf("RecordFill: %v", err)
+	}
+
+	if got := m.UnrealizedPnL("BTCUSDT", 110); math.Abs(got-20) > 1e-9 {
+		t.Errorf("long 2 @ 100 marked at 110: got %v, want 20", got)
+	}
+	if got := m.UnrealizedPnL("BTCUSDT", 95); math.Abs(got+10) > 1e-9 {
+		t.Errorf("long 2 @ 100 marked at 95: got %v, want -10", got)
+	}
+}
+
+func TestUnrealizedPnLShort(t *testing.T) {
+	m := NewManager(nil)
+	ctx := context.Background()
+	if _, err := m.RecordFill(ctx, "", "ETHUSDT", "SELL", 3, 200); err != nil {
+		t.Fatalf("RecordFill: %v", err)
+	}
+
+	if got := m.UnrealizedPnL("ETHUSDT", 190); math.Abs(got-30) > 1e-9 {
+		t.Errorf("short 3 @ 200 marked at 190: got %v, want 30", got)
+	}
+	if got := m.UnrealizedPnL("ETHUSDT", 210); math.Abs(got+30) > 1e-9 {
+		t.Errorf("short 3 @ 200 marked at 210: got %v, want -30", got)
+	}
+	if got := m.UnrealizedPnL("ETHUSDT", 0); got != 0 {
+		t.Errorf("unknown mark should yield 0, got %v", got)
+	}
+}
+
+func TestUserPositionIgnoresOtherUsersFills(t *testing.T) {
+	m := NewManager(nil)
+	ctx := context.Background()
+
+	if _, _, err := m.ApplyFill(ctx, "u1", "BTCUSDT", "BUY", 1, 100, 0); err != nil {
+		t.Fatalf("ApplyFill: %v", err)
+	}
+	// u2 opening a short nets the symbol flat but leaves u1 long.
+	own, realized, _ := m.ApplyFill(ctx, "u2", "BTCUSDT", "SELL", 1, 110, 0)
+	if own.Qty != -1 || realized != 0 {
+		t.Fatalf("u2 position = %+v realized %v, want a fresh short with nothing realized", own, realized)
+	}
+	if net := m.Position("BTCUSDT"); net.Qty != 0 {
+		t.Fatalf("net position = %v, want 0", net.Qty)
+	}
+	if p := m.PositionFor("u1", "", "BTCUSDT", ""); p.Qty != 1 || p.AvgPrice != 100 {
+		t.Fatalf("u1 position = %+v, want long 1 @ 100", p)
+	}
+	if ps := m.UserPositions("u1"); len(ps) != 1 || ps[0].Qty != 1 {
+		t.Fatalf("u1 positions = %+v, want only the BTCUSDT long", ps)
+	}
+	if _, realized, _ = m.ApplyFill(ctx, "u1", "BTCUSDT", "SELL", 1, 90, 0); math.Abs(realized+10) > 1e-9 {
+		t.Fatalf("u1 closing long 1 @ 100 at 90: realized %v, want -10", realized)
+	}
+}
+
+func TestRealizedTodayNetOfFees(t *testing.T) {
+	m := NewManager(nil)
+	ctx := context.Background()
+
+	_, realized, _ := m.ApplyFill(ctx, "", "BTCUSDT", "BUY", 1, 100, 0.1)
+	if realized != 0 {
+		t.Fatalf("opening fill should realize nothing, got %v", realized)
+	}
+	_, realized, _ = m.ApplyFill(ctx, "", "BTCUSDT", "SELL", 1, 110, 0.1)
+	if math.Abs(realized-10) > 1e-9 {
+		t.Fatalf("closing long 1 @ 100 at 110: realized %v, want 10", realized)
+	}
+	// Short leg: 2 @ 120 covered at 100 = +40.
+	_, _, _ = m.ApplyFill(ctx, "", "BTCUSDT", "SELL", 2, 120, 0)
+	_, realized, _ = m.ApplyFill(ctx, "", "BTCUSDT", "BUY", 2, 100, 0)
+	if math.Abs(realized-40) > 1e-9 {
+		t.Fatalf("covering short: realized %v, want 40", realized)
+	}
+
+	if got := m.RealizedToday(); math.Abs(got-49.8) > 1e-9 {
+		t.Errorf("RealizedToday = %v, want 49.8 (50 gross - 0.2 fees)", got)
+	}
+}
+
+func TestHedgeModeTracksLongAndShortLegsIndependently(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	m := NewManager(database)
+	m.SetHedgeMode(true)
+
+	// Long 2 @ 100 and short 1 @ 110 on the same symbol do not net out.
+	if _, _, err := m.ApplySideFill(ctx, "u1", "c1", "BTCUSDT", "BUY", "LONG", 2, 100, 0); err != nil {
+		t.Fatalf("long entry: %v", err)
+	}
+	if _, _, err := m.ApplySideFill(ctx, "u1", "c1", "BTCUSDT", "SELL", "SHORT", 1, 110, 0); err != nil {
+		t.Fatalf("short entry: %v", err)
+	}
+	if l := m.Leg("u1", "c1", "BTCUSDT", "LONG"); l.Qty != 2 || l.AvgPrice != 100 {
+		t.Fatalf("long leg = %+v, want 2 @ 100", l)
+	}
+	if s := m.Leg("u1", "c1", "BTCUSDT", "SHORT"); s.Qty != -1 || s.AvgPrice != 110 {
+		t.Fatalf("short leg = %+v, want -1 @ 110", s)
+	}
+	if net := m.Position("BTCUSDT"); net.Qty != 1 {
+		t.Fatalf("net position = %+v, want 1", net)
+	}
+
+	// Closing part of the short realizes PnL against the short's own average
+	// and leaves the long untouched.
+	_, pnl, err := m.ApplySideFill(ctx, "u1", "c1", "BTCUSDT", "BUY", "SHORT", 1, 105, 0)
+	if err != nil {
+		t.Fatalf("short cover: %v", err)
+	}
+	if math.Abs(pnl-5) > 1e-9 {
+		t.Errorf("short cover PnL = %v, want 5", pnl)
+	}
+	if s := m.Leg("u1", "c1", "BTCUSDT", "SHORT"); s.Qty != 0 {
+		t.Errorf("short leg should be flat, got %+v", s)
+	}
+	if l := m.Leg("u1", "c1", "BTCUSDT", "LONG"); l.Qty != 2 || l.AvgPrice != 100 {
+		t.Errorf("long leg changed to %+v", l)
+	}
+
+	// Another account's legs are kept apart from this one's.
+	if _, _, err := m.ApplySideFill(ctx, "u2", "c2", "BTCUSDT", "SELL", "SHORT", 3, 100, 0); err != nil {
+		t.Fatalf("other account short entry: %v", err)
+	}
+	if s := m.Leg("u1", "c1", "BTCUSDT", "SHORT"); s.Qty != 0 {
+		t.Errorf("u1 short leg picked up u2's fill: %+v", s)
+	}
+	if s := m.Leg("u2", "c2", "BTCUSDT", "SHORT"); s.Qty != -3 {
+		t.Errorf("u2 short leg = %+v, want -3", s)
+	}
+	if net := m.Position("BTCUSDT"); net.Qty != -1 {
+		t.Errorf("net position across accounts = %+v, want -1", net)
+	}
+
+	// Legs survive a restart.
+	reloaded := NewManager(database)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if legs := reloaded.Legs(); len(legs) != 2 || legs[0].UserID != "u1" || legs[0].PositionSide != "LONG" || legs[0].Qty != 2 {
+		t.Fatalf("reloaded legs = %+v, want u1's long and u2's short", legs)
+	}
+}
+
+func TestHedgeModeSetPositionSeedsLegs(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(nil)
+	m.SetHedgeMode(true)
+
+	if err := m.SetPosition(ctx, "ETHUSDT", -2, 3000); err != nil {
+		t.Fatalf("SetPosition: %v", err)
+	}
+	if s := m.Leg("", "", "ETHUSDT", "SHORT"); s.Qty != -2 || s.AvgPrice != 3000 {
+		t.Fatalf("short leg = %+v, want -2 @ 3000", s)
+	}
+	if net := m.Position("ETHUSDT"); net.Qty != -2 {
+		t.Fatalf("net position = %+v, want -2", net)
+	}
+	// Covering the synced short realizes against its average.
+	if _, pnl, err := m.ApplySideFill(ctx, "", "", "ETHUSDT", "BUY", "SHORT", 2, 2900, 0); err != nil || math.Abs(pnl-200) > 1e-9 {
+		t.Fatalf("cover: pnl=%v err=%v, want 200", pnl, err)
+	}
+	if net := m.Position("ETHUSDT"); net.Qty != 0 {
+		t.Fatalf("net after cover = %+v, want flat", net)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,7 +20,12 @@ import (
 // Engine orchestrates multiple strategies and emits signals on the event bus.
 type Engine struct {
 	strategies  []Strategy
-	paused      map[string]bool // Set of paused strategy IDs
+	paused      map[string]bool   // Set of paused strategy IDs
+	intervals   map[string]string // Strategy ID -> kline interval it trades on
+	closedOnly  map[string]bool   // Strategy IDs that only tick on closed candles
+	sources     map[string]string // Strategy ID -> price source, when not the default
+	defaultIntv string            // Interval for strategies registered without one
+	defaultSrc  string            // Price source for strategies that do not select one
 	bus         *events.Bus
 	ctx         Context
 	db          *sql.DB
@@ -28,6 +34,28 @@ type Engine struct {
 	// Worker pool for parallel strategy execution (V2)
 	workerPool chan struct{}
 	poolSize   int
+
+	// Market data subscriptions for the symbols loaded strategies trade.
+	symbols map[string]string // Strategy ID -> symbol
+	feed    SymbolSubscriber
+	// sourceFeed streams the price sources loaded strategies select.
+	sourceFeed PriceSourceSubscriber
+
+	// symbolAllowed vets a strategy's owner and symbol before it runs (nil = any).
+	symbolAllowed func(userID, symbol string) (bool, error)
+}
+
+// SymbolSubscriber streams market data for a symbol between Acquire and the
+// matching Release (e.g. market.Feed).
+type SymbolSubscriber interface {
+	Acquire(symbol string)
+	Release(symbol string)
+}
+
+// PriceSourceSubscriber streams a non-kline price source once it is added
+// (e.g. market.Feed).
+type PriceSourceSubscriber interface {
+	AddPriceSource(source string)
 }
 
 func NewEngine(bus *events.Bus, db *sql.DB, ctx Context) *Engine {
@@ -39,6 +67,12 @@ func NewEngine(bus *events.Bus, db *sql.DB, ctx Context) *Engine {
 
 	return &Engine{
 		paused:      make(map[string]bool),
+		intervals:   make(map[string]string),
+		closedOnly:  make(map[string]bool),
+		sources:     make(map[string]string),
+		symbols:     make(map[string]string),
+		defaultIntv: "1m",
+		defaultSrc:  market.PriceSourceKline,
 		bus:         bus,
 		db:          db,
 		ctx:         ctx,
@@ -48,36 +82,251 @@ func NewEngine(bus *events.Bus, db *sql.DB, ctx Context) *Engine {
 	}
 }
 
-// Add registers a strategy implementation.
+// SetDefaultInterval sets the interval assumed for strategies that do not declare one.
+func (e *Engine) SetDefaultInterval(interval string) {
+	if interval != "" {
+		e.defaultIntv = interval
+	}
+}
+
+// SetDefaultPriceSource sets the price source for strategies that do not select one.
+func (e *Engine) SetDefaultPriceSource(source string) error {
+	if source == "" {
+		return nil
+	}
+	if !market.ValidPriceSource(source) {
+		return fmt.Errorf("unknown price source %q", source)
+	}
+	e.defaultSrc = source
+	return nil
+}
+
+// Add registers a strategy implementation on the default interval.
 func (e *Engine) Add(s Strategy) {
+	e.AddWithInterval(s, "")
+}
+
+// AddWithInterval registers a strategy that only receives klines of interval.
+func (e *Engine) AddWithInterval(s Strategy, interval string) {
 	e.strategies = append(e.strategies, s)
+	if interval != "" {
+		e.intervals[s.ID()] = interval
+	} else {
+		delete(e.intervals, s.ID())
+	}
+}
+
+// SetSymbolSubscriber makes the engine acquire the symbol of every loaded
+// strategy from sub, and release it when the strategy stops, so the feed
+// streams symbols that were not configured at startup.
+func (e *Engine) SetSymbolSubscriber(sub SymbolSubscriber) {
+	e.feed = sub
+	for _, symbol := range e.symbols {
+		sub.Acquire(symbol)
+	}
+}
+
+// SetPriceSourceSubscriber makes the engine add the price source of every
+// loaded strategy to sub, so strategies loaded after startup that select a
+// source nothing else uses still get ticks.
+func (e *Engine) SetPriceSourceSubscriber(sub PriceSourceSubscriber) {
+	e.sourceFeed = sub
+	for _, src := range e.PriceSources() {
+		sub.AddPriceSource(src)
+	}
+}
+
+// SetSymbolAllowlist makes the engine refuse to load or start a strategy
+// unless allowed(userID, symbol) reports its owner may trade that symbol, so a
+// bad strategy_instances row cannot point a strategy at an unintended market.
+func (e *Engine) SetSymbolAllowlist(allowed func(userID, symbol string) (bool, error)) {
+	e.symbolAllowed = allowed
+}
+
+// checkSymbol returns why strategy id may not run on symbol, or nil.
+func (e *Engine) checkSymbol(id, userID, symbol string) error {
+	if e.symbolAllowed == nil {
+		return nil
+	}
+	ok, err := e.symbolAllowed(userID, symbol)
+	if err != nil {
+		return fmt.Errorf("strategy %s: check allowed symbols: %w", id, err)
+	}
+	if !ok {
+		return fmt.Errorf("strategy %s: symbol %s is not allowed for user %s", id, symbol, userID)
+	}
+	return nil
+}
+
+// trackSymbol records the symbol strategy id trades and acquires it.
+func (e *Engine) trackSymbol(id, symbol string) {
+	old, ok := e.symbols[id]
+	if ok && old == symbol {
+		return
+	}
+	e.symbols[id] = symbol
+	if e.feed != nil {
+		e.feed.Acquire(symbol)
+		if ok {
+			e.feed.Release(old)
+		}
+	}
+}
+
+// untrackSymbol releases the symbol of a strategy that stopped.
+func (e *Engine) untrackSymbol(id string) {
+	symbol, ok := e.symbols[id]
+	if !ok {
+		return
+	}
+	delete(e.symbols, id)
+	if e.feed != nil {
+		e.feed.Release(symbol)
+	}
+}
+
+// SetClosedCandleOnly makes a strategy skip intra-bar kline updates and only
+// receive OnTick once the candle closes. Non-kline ticks are never gated.
+func (e *Engine) SetClosedCandleOnly(id string, on bool) {
+	if on {
+		e.closedOnly[id] = true
+	} else {
+		delete(e.closedOnly, id)
+	}
+}
+
+// closedCandleOnly reads the optional "closed_candle_only" flag from strategy parameters.
+func closedCandleOnly(paramsJSON string) bool {
+	var p struct {
+		ClosedCandleOnly bool `json:"closed_candle_only"`
+	}
+	_ = json.Unmarshal([]byte(paramsJSON), &p)
+	return p.ClosedCandleOnly
+}
+
+// SetPriceSource selects the price stream a strategy is ticked from:
+// market.PriceSourceKline (kline closes), PriceSourceTrade, PriceSourceAggTrade
+// or PriceSourceBookMid. Empty, or an unknown source, restores the default.
+func (e *Engine) SetPriceSource(id, source string) {
+	if source != "" && !market.ValidPriceSource(source) {
+		log.Printf("strategy %s: unknown price source %q, using %s", id, source, e.defaultSrc)
+		source = ""
+	}
+	if source != "" {
+		e.sources[id] = source
+	} else {
+		delete(e.sources, id)
+	}
+	if e.sourceFeed != nil {
+		e.sourceFeed.AddPriceSource(e.sourceOf(id))
+	}
+}
+
+// priceSource reads the optional "price_source" from strategy parameters.
+func priceSource(paramsJSON string) string {
+	var p struct {
+		PriceSource string `json:"price_source"`
+	}
+	_ = json.Unmarshal([]byte(paramsJSON), &p)
+	return p.PriceSource
+}
+
+// sourceOf returns the price source a strategy is ticked from.
+func (e *Engine) sourceOf(id string) string {
+	if src := e.sources[id]; src != "" {
+		return src
+	}
+	return e.defaultSrc
+}
+
+// PriceSources returns the sorted non-kline price sources selected by loaded
+// strategies. The market feed streams these alongside klines.
+func (e *Engine) PriceSources() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range e.strategies {
+		if src := e.sourceOf(s.ID()); src != market.PriceSourceKline && !seen[src] {
+			seen[src] = true
+			out = append(out, src)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// intervalOf returns the kline interval a strategy is bound to.
+func (e *Engine) intervalOf(id string) string {
+	if iv := e.intervals[id]; iv != "" {
+		return iv
+	}
+	return e.defaultIntv
+}
+
+// Intervals returns the sorted union of intervals required by loaded strategies
+// (including secondary intervals), always including the default interval. The market feed subscribes to these.
+func (e *Engine) Intervals() []string {
+	seen := map[string]bool{e.defaultIntv: true}
+	out := []string{e.defaultIntv}
+	for _, s := range e.strategies {
+		if iv := e.intervalOf(s.ID()); !seen[iv] {
+			seen[iv] = true
+			out = append(out, iv)
+		}
+		if mtf, ok := s.(MultiTimeframe); ok {
+			if iv := mtf.SecondaryInterval(); iv != "" && !seen[iv] {
+				seen[iv] = true
+				out = append(out, iv)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
 }
 
 // LoadStrategies loads active strategies from the database.
 func (e *Engine) LoadStrategies(db *sql.DB) error {
 	// Load strategies that are ACTIVE or PAUSED
 	rows, err := db.Query(`
-		SELECT id, strategy_type, symbol, parameters, status
+		SELECT id, strategy_type, symbol, COALESCE(interval, ''), parameters, status, COALESCE(user_id, '')
 		FROM strategy_instances 
 		WHERE status IN ('ACTIVE', 'PAUSED') OR (status IS NULL AND is_active = 1)
 	`)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
+	// Read every row before vetting symbols: the allow-list check queries the
+	// same (single-connection) database.
+	type instanceRow struct {
+		id, sType, symbol, interval, status, userID, paramsJSON string
+	}
+	var instances []instanceRow
+	for rows.Next() {
+		var r instanceRow
+		if err := rows.Scan(&r.id, &r.sType, &r.symbol, &r.interval, &r.paramsJSON, &r.status, &r.userID); err != nil {
+			rows.Close()
+			return err
+		}
+		instances = append(instances, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
 	e.strategies = nil // Reset strategies
 	e.paused = make(map[string]bool)
+	e.intervals = make(map[string]string)
+	e.closedOnly = make(map[string]bool)
+	e.sources = make(map[string]string)
+	for id := range e.symbols {
+		e.untrackSymbol(id)
+	}
 
-	for rows.Next() {
-		var id, sType, symbol, status string
-		var paramsJSON string
-		// Handle potential NULL status by scanning into sql.NullString if needed,
-		// but we used OR in query so we expect status to be populated or fallback.
-		// Actually, let's just scan status. If it's NULL (old rows), it might fail if we don't handle it.
-		// Let's assume schema migration set default 'ACTIVE'.
-		if err := rows.Scan(&id, &sType, &symbol, &paramsJSON, &status); err != nil {
-			return err
+	for _, r := range instances {
+		id, sType, symbol, interval, status, paramsJSON := r.id, r.sType, r.symbol, r.interval, r.status, r.paramsJSON
+		if err := e.checkSymbol(id, r.userID, symbol); err != nil {
+			log.Printf("⚠️ Not loading %v", err)
+			continue
 		}
 
 		if status == "PAUSED" {
@@ -124,13 +373,41 @@ func (e *Engine) LoadStrategies(db *sql.DB) error {
 			}
 			strategy = NewBollingerStrategy(id, symbol, p.Period, p.NumStdDev, p.Size)
 
+		case "ob_imbalance":
+			var p struct {
+				Threshold float64 `json:"threshold"`
+				Size      float64 `json:"size"`
+			}
+			if err := json.Unmarshal([]byte(paramsJSON), &p); err != nil {
+				log.Printf("failed to unmarshal params for %s: %v", id, err)
+				continue
+			}
+			strategy = NewImbalanceStrategy(id, symbol, p.Threshold, p.Size)
+
+		case "mtf":
+			var p struct {
+				FastPeriod    int     `json:"fast"`
+				SlowPeriod    int     `json:"slow"`
+				TrendInterval string  `json:"trend_interval"`
+				TrendPeriod   int     `json:"trend_period"`
+				Size          float64 `json:"size"`
+			}
+			if err := json.Unmarshal([]byte(paramsJSON), &p); err != nil {
+				log.Printf("failed to unmarshal params for %s: %v", id, err)
+				continue
+			}
+			strategy = NewMTFStrategy(id, symbol, p.FastPeriod, p.SlowPeriod, p.TrendInterval, p.TrendPeriod, p.Size)
+
 		default:
 			log.Printf("unknown strategy type: %s", sType)
 			continue
 		}
 
 		if strategy != nil {
-			e.Add(strategy)
+			e.AddWithInterval(strategy, interval)
+			e.SetClosedCandleOnly(id, closedCandleOnly(paramsJSON))
+			e.SetPriceSource(id, priceSource(paramsJSON))
+			e.trackSymbol(id, symbol)
 			log.Printf("Loaded strategy: %s (%s)", strategy.Name(), id)
 		}
 	}
@@ -165,6 +442,16 @@ func (e *Engine) Start(ctx context.Context, priceStream <-chan any) {
 		var symbol string
 		_ = e.db.QueryRow("SELECT symbol, interval FROM strategy_instances WHERE id = ?", s.ID()).Scan(&symbol, &interval)
 
+		if mtf, ok := s.(MultiTimeframe); ok && symbol != "" && mtf.SecondaryInterval() != "" {
+			klines, err := e.dataService.GetKlines(ctx, symbol, mtf.SecondaryInterval(), 100)
+			if err != nil {
+				log.Printf("⚠️ Failed to fetch %s warm-up data for %s: %v", mtf.SecondaryInterval(), s.Name(), err)
+			} else {
+				for _, k := range klines {
+					mtf.OnSecondaryTick(symbol, k.Close, nil)
+				}
+			}
+		}
 		if symbol != "" && interval != "" {
 			klines, err := e.dataService.GetKlines(ctx, symbol, interval, 100)
 			if err != nil {
@@ -221,12 +508,22 @@ func (e *Engine) saveAllStates() {
 
 func (e *Engine) handleTick(msg any) {
 	symbol := ""
+	interval := ""
+	source := "" // untagged ticks (mock feed) reach every strategy
+	final := true
 	price := 0.0
 
 	switch v := msg.(type) {
 	case market.Kline:
 		symbol = v.Symbol
+		interval = v.Interval
+		source = market.PriceSourceKline
+		final = v.IsFinal
 		price = v.Close
+	case market.PriceTick:
+		symbol = v.Symbol
+		source = v.Source
+		price = v.Price
 	case struct {
 		Symbol string
 		Close  float64
@@ -239,17 +536,51 @@ func (e *Engine) handleTick(msg any) {
 		return
 	}
 
+	// Indicator windows are kept per (symbol, interval) so a 1h series is never
+	// polluted by 1m closes. Ticks without an interval (mock feed) share the symbol key.
+	// Non-kline sources get their own window too.
+	indKey := symbol
+	if interval != "" {
+		indKey = symbol + "@" + interval
+	} else if source != "" {
+		indKey = symbol + "@" + source
+	}
 	indVals := map[string]float64{}
 	if e.ctx.Indicators != nil {
-		indVals = e.ctx.Indicators.Update(symbol, price)
+		indVals = e.ctx.Indicators.Update(indKey, price)
+	}
+	// Order-book features from the depth stream ride along in the indicator map.
+	for k, v := range e.ctx.Book.Features(symbol) {
+		indVals[k] = v
 	}
 
-	// Collect non-paused strategies
+	// Closed candles on a strategy's secondary interval feed its higher-timeframe view.
+	if interval != "" && final {
+		for _, s := range e.strategies {
+			mtf, ok := s.(MultiTimeframe)
+			if !ok || e.paused[s.ID()] || mtf.SecondaryInterval() != interval || e.intervalOf(s.ID()) == interval {
+				continue
+			}
+			e.secondaryTick(s.ID(), mtf, symbol, price, indVals)
+		}
+	}
+
+	// Collect non-paused strategies bound to this tick's interval
 	activeStrategies := make([]Strategy, 0, len(e.strategies))
 	for _, s := range e.strategies {
-		if !e.paused[s.ID()] {
-			activeStrategies = append(activeStrategies, s)
+		if e.paused[s.ID()] {
+			continue
+		}
+		if source != "" && e.sourceOf(s.ID()) != source {
+			continue
+		}
+		if interval != "" && e.intervalOf(s.ID()) != interval {
+			continue
+		}
+		if !final && e.closedOnly[s.ID()] {
+			continue
 		}
+		activeStrategies = append(activeStrategies, s)
 	}
 
 	if len(activeStrategies) == 0 {
@@ -296,6 +627,11 @@ func (e *Engine) handleTick(msg any) {
 	}
 }
 
+func (e *Engine) secondaryTick(id string, mtf MultiTimeframe, symbol string, price float64, ind map[string]float64) {
+	defer e.recoverFromPanic(id)
+	mtf.OnSecondaryTick(symbol, price, ind)
+}
+
 // recoverFromPanic handles panics in strategy execution (V2 P1-A).
 func (e *Engine) recoverFromPanic(strategyID string) {
 	if r := recover(); r != nil {
@@ -336,10 +672,19 @@ func (e *Engine) PauseStrategy(id string) error {
 	return err
 }
 
+// ResumeStrategy unpauses a loaded strategy, or loads one that is not running
+// yet (e.g. created after startup).
 func (e *Engine) ResumeStrategy(id string) error {
 	delete(e.paused, id)
-	_, err := e.db.Exec("UPDATE strategy_instances SET status = 'ACTIVE' WHERE id = ?", id)
-	return err
+	if _, err := e.db.Exec("UPDATE strategy_instances SET status = 'ACTIVE', is_active = 1 WHERE id = ?", id); err != nil {
+		return err
+	}
+	for _, s := range e.strategies {
+		if s.ID() == id {
+			return nil
+		}
+	}
+	return e.reloadSingleStrategy(id)
 }
 
 func (e *Engine) StopStrategy(id string) error {
@@ -352,6 +697,10 @@ func (e *Engine) StopStrategy(id string) error {
 	}
 	e.strategies = newStrategies
 	delete(e.paused, id)
+	delete(e.intervals, id)
+	delete(e.closedOnly, id)
+	delete(e.sources, id)
+	e.untrackSymbol(id)
 
 	// Update DB
 	_, err := e.db.Exec("UPDATE strategy_instances SET status = 'STOPPED', is_active = 0 WHERE id = ?", id)
@@ -409,15 +758,19 @@ func (e *Engine) UpdateParams(id string, paramsJSON []byte) error {
 }
 
 func (e *Engine) reloadSingleStrategy(id string) error {
-	var sType, symbol, status string
+	var sType, symbol, interval, status, userID string
 	var paramsJSON string
 	err := e.db.QueryRow(`
-		SELECT strategy_type, symbol, parameters, status 
+		SELECT strategy_type, symbol, COALESCE(interval, ''), parameters, status, COALESCE(user_id, '')
 		FROM strategy_instances 
-		WHERE id = ?`, id).Scan(&sType, &symbol, &paramsJSON, &status)
+		WHERE id = ?`, id).Scan(&sType, &symbol, &interval, &paramsJSON, &status, &userID)
 	if err != nil {
 		return err
 	}
+	if err := e.checkSymbol(id, userID, symbol); err != nil {
+		log.Printf("⚠️ Not starting %v", err)
+		return err
+	}
 
 	var strategy Strategy
 
@@ -455,6 +808,29 @@ func (e *Engine) reloadSingleStrategy(id string) error {
 			return err
 		}
 		strategy = NewBollingerStrategy(id, symbol, p.Period, p.NumStdDev, p.Size)
+
+	case "ob_imbalance":
+		var p struct {
+			Threshold float64 `json:"threshold"`
+			Size      float64 `json:"size"`
+		}
+		if err := json.Unmarshal([]byte(paramsJSON), &p); err != nil {
+			return err
+		}
+		strategy = NewImbalanceStrategy(id, symbol, p.Threshold, p.Size)
+
+	case "mtf":
+		var p struct {
+			FastPeriod    int     `json:"fast"`
+			SlowPeriod    int     `json:"slow"`
+			TrendInterval string  `json:"trend_interval"`
+			TrendPeriod   int     `json:"trend_period"`
+			Size          float64 `json:"size"`
+		}
+		if err := json.Unmarshal([]byte(paramsJSON), &p); err != nil {
+			return err
+		}
+		strategy = NewMTFStrategy(id, symbol, p.FastPeriod, p.SlowPeriod, p.TrendInterval, p.TrendPeriod, p.Size)
 	}
 
 	if strategy != nil {
@@ -465,7 +841,10 @@ func (e *Engine) reloadSingleStrategy(id string) error {
 			_ = strategy.SetState(json.RawMessage(stateData))
 		}
 
-		e.Add(strategy)
+		e.AddWithInterval(strategy, interval)
+		e.SetClosedCandleOnly(id, closedCandleOnly(paramsJSON))
+		e.SetPriceSource(id, priceSource(paramsJSON))
+		e.trackSymbol(id, symbol)
 		if status == "PAUSED" {
 			e.paused[id] = true
 		}
@@ -0,0 +1,273 @@
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"trading-core/internal/events"
+	"trading-core/internal/indicators"
+	"trading-core/pkg/db"
+	market "trading-core/pkg/market/binance"
+)
+
+// recordingStrategy records every price it is ticked with.
+type recordingStrategy struct {
+	id     string
+	prices []float64
+}
+
+func (r *recordingStrategy) ID() string   { return r.id }
+func (r *recordingStrategy) Name() string { return r.id }
+func (r *recordingStrategy) OnTick(symbol string, price float64, ind map[string]float64) (*Signal, error) {
+	r.prices = append(r.prices, price)
+	return nil, nil
+}
+func (r *recordingStrategy) GetState() (json.RawMessage, error) { return json.RawMessage(`{}`), nil }
+func (r *recordingStrategy) SetState(json.RawMessage) error     { return nil }
+
+func TestEngineRoutesKlinesByInterval(t *testing.T) {
+	e := NewEngine(events.NewBus(), nil, Context{})
+	hourly := &recordingStrategy{id: "hourly"}
+	minutely := &recordingStrategy{id: "minutely"}
+	e.AddWithInterval(hourly, "1h")
+	e.Add(minutely)
+
+	if got, want := e.Intervals(), []string{"1h", "1m"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Intervals() = %v, want %v", got, want)
+	}
+
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 101})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1h", Close: 150})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 102})
+
+	if want := []float64{150}; !reflect.DeepEqual(hourly.prices, want) {
+		t.Errorf("1h strategy saw %v, want only 1h closes %v", hourly.prices, want)
+	}
+	if want := []float64{100, 101, 102}; !reflect.DeepEqual(minutely.prices, want) {
+		t.Errorf("1m strategy saw %v, want %v", minutely.prices, want)
+	}
+}
+
+func TestEngineRoutesTicksByPriceSource(t *testing.T) {
+	e := NewEngine(events.NewBus(), nil, Context{})
+	klines := &recordingStrategy{id: "klines"}
+	agg := &recordingStrategy{id: "agg"}
+	e.Add(klines)
+	e.Add(agg)
+	e.SetPriceSource("agg", priceSource(`{"price_source":"aggTrade"}`))
+
+	if got, want := e.PriceSources(), []string{market.PriceSourceAggTrade}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("PriceSources() = %v, want %v", got, want)
+	}
+
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+	e.handleTick(market.PriceTick{Symbol: "BTCUSDT", Price: 100.2, Source: market.PriceSourceAggTrade})
+	e.handleTick(market.PriceTick{Symbol: "BTCUSDT", Price: 100.3, Source: market.PriceSourceBookMid})
+
+	if want := []float64{100}; !reflect.DeepEqual(klines.prices, want) {
+		t.Errorf("kline strategy saw %v, want %v", klines.prices, want)
+	}
+	if want := []float64{100.2}; !reflect.DeepEqual(agg.prices, want) {
+		t.Errorf("aggTrade strategy saw %v, want %v", agg.prices, want)
+	}
+}
+
+// sourceRecorder records the price sources added to it.
+type sourceRecorder struct{ sources []string }
+
+func (r *sourceRecorder) AddPriceSource(source string) { r.sources = append(r.sources, source) }
+
+func TestEngineSubscribesPriceSourcesOfStrategiesLoadedLater(t *testing.T) {
+	e := NewEngine(events.NewBus(), nil, Context{})
+	if err := e.SetDefaultPriceSource("lastTrade"); err == nil {
+		t.Fatal("an unknown default price source should be rejected")
+	}
+	feed := &sourceRecorder{}
+	e.SetPriceSourceSubscriber(feed)
+
+	e.Add(&recordingStrategy{id: "book"})
+	e.SetPriceSource("book", market.PriceSourceBookMid)
+	e.Add(&recordingStrategy{id: "typo"})
+	e.SetPriceSource("typo", "bookmid")
+
+	if want := []string{market.PriceSourceBookMid, market.PriceSourceKline}; !reflect.DeepEqual(feed.sources, want) {
+		t.Fatalf("added sources = %v, want %v", feed.sources, want)
+	}
+	if got, want := e.PriceSources(), []string{market.PriceSourceBookMid}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("PriceSources() = %v, want %v (unknown source falls back to the default)", got, want)
+	}
+}
+
+// alwaysBuyStrategy emits a BUY signal on every tick.
+type alwaysBuyStrategy struct{ recordingStrategy }
+
+func (a *alwaysBuyStrategy) OnTick(symbol string, price float64, ind map[string]float64) (*Signal, error) {
+	a.prices = append(a.prices, price)
+	return &Signal{Action: "BUY", Symbol: symbol, Size: 1}, nil
+}
+
+func TestEngineClosedCandleOnly(t *testing.T) {
+	bus := events.NewBus()
+	signals, unsub := bus.Subscribe(events.EventStrategySignal, 10)
+	defer unsub()
+
+	e := NewEngine(bus, nil, Context{})
+	strat := &alwaysBuyStrategy{recordingStrategy{id: "ma"}}
+	e.Add(strat)
+	e.SetClosedCandleOnly("ma", true)
+
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 101})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 102, IsFinal: true})
+
+	if got := len(signals); got != 1 {
+		t.Fatalf("expected exactly one signal on candle close, got %d", got)
+	}
+	if want := []float64{102}; !reflect.DeepEqual(strat.prices, want) {
+		t.Errorf("strategy ticked with %v, want only the closing price %v", strat.prices, want)
+	}
+}
+
+func TestClosedCandleOnlyParam(t *testing.T) {
+	if !closedCandleOnly(`{"fast":7,"closed_candle_only":true}`) {
+		t.Error("expected closed_candle_only=true to be honored")
+	}
+	if closedCandleOnly(`{"fast":7}`) {
+		t.Error("closed-candle gating should default to off")
+	}
+}
+
+func TestEngineFeedsOrderBookFeaturesToImbalanceStrategy(t *testing.T) {
+	bus := events.NewBus()
+	signals, unsub := bus.Subscribe(events.EventStrategySignal, 10)
+	defer unsub()
+
+	book := indicators.NewBookStore(2)
+	e := NewEngine(bus, nil, Context{Book: book})
+	e.Add(NewImbalanceStrategy("ob", "BTCUSDT", 0.5, 1))
+
+	// No book yet: nothing to act on.
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+	// Balanced book (third level ignored): imbalance 0, below threshold.
+	book.Update("BTCUSDT", [][2]float64{{99.9, 2}, {99.8, 2}, {99.7, 50}}, [][2]float64{{100.1, 2}, {100.2, 2}})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+	if got := len(signals); got != 0 {
+		t.Fatalf("expected no signal below threshold, got %d", got)
+	}
+
+	feats := book.Features("btcusdt")
+	if feats[indicators.BookImbalance] != 0 || feats[indicators.BookMicroprice] != 100 {
+		t.Fatalf("unexpected features for a balanced book: %v", feats)
+	}
+
+	// Bids 9 vs asks 3: imbalance 0.5 hits the threshold.
+	book.Update("BTCUSDT", [][2]float64{{99.9, 6}, {99.8, 3}}, [][2]float64{{100.1, 2}, {100.2, 1}})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+	e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: 100})
+
+	if got := len(signals); got != 1 {
+		t.Fatalf("expected one BUY signal at the threshold, got %d", got)
+	}
+	sig := (<-signals).(Signal)
+	if sig.Action != "BUY" || sig.Symbol != "BTCUSDT" {
+		t.Fatalf("unexpected signal: %+v", sig)
+	}
+}
+
+func TestMTFStrategySuppressesLongAgainstHigherTimeframe(t *testing.T) {
+	run := func(trendCloses []float64) []Signal {
+		bus := events.NewBus()
+		signals, unsub := bus.Subscribe(events.EventStrategySignal, 10)
+		defer unsub()
+
+		e := NewEngine(bus, nil, Context{})
+		e.AddWithInterval(NewMTFStrategy("mtf", "BTCUSDT", 2, 3, "1h", 3, 1), "1m")
+		if got := e.Intervals(); !reflect.DeepEqual(got, []string{"1h", "1m"}) {
+			t.Fatalf("expected the feed to cover the trend interval, got %v", got)
+		}
+
+		for _, c := range trendCloses {
+			e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1h", Close: c, IsFinal: true})
+		}
+		// Intra-bar trend updates are ignored.
+		e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1h", Close: 1, IsFinal: false})
+
+		// 1m closes: death cross on 10,9,8 then golden cross on 12.
+		for _, c := range []float64{10, 9, 8, 12} {
+			e.handleTick(market.Kline{Symbol: "BTCUSDT", Interval: "1m", Close: c, IsFinal: true})
+		}
+		var out []Signal
+		for len(signals) > 0 {
+			out = append(out, (<-signals).(Signal))
+		}
+		return out
+	}
+
+	bearish := run([]float64{120, 110, 100})
+	if len(bearish) != 1 || bearish[0].Action != "SELL" {
+		t.Fatalf("expected only the SELL under a bearish 1h trend, got %+v", bearish)
+	}
+
+	bullish := run([]float64{100, 110, 120})
+	if len(bullish) != 1 || bullish[0].Action != "BUY" {
+		t.Fatalf("expected only the BUY under a bullish 1h trend, got %+v", bullish)
+	}
+}
+
+func TestLoadStrategiesSkipsDisallowedSymbol(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+	if err := db.ApplyMigrations(database); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := database.DB.Exec(`INSERT INTO users (id, email, password_hash) VALUES ('u1', 'u1@example.com', 'x')`); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if err := database.Queries().SetUserLimits(ctx, "u1", db.UserLimits{AllowedSymbols: []string{"BTCUSDT"}}); err != nil {
+		t.Fatalf("SetUserLimits: %v", err)
+	}
+	for _, row := range [][2]string{{"s-btc", "BTCUSDT"}, {"s-eth", "ETHUSDT"}} {
+		if _, err := database.DB.Exec(`
+			INSERT INTO strategy_instances (id, name, strategy_type, symbol, interval, parameters, user_id, status)
+			VALUES (?, ?, 'ma_cross', ?, '1m', '{"fast":5,"slow":20,"size":1}', 'u1', 'ACTIVE')`, row[0], row[0], row[1]); err != nil {
+			t.Fatalf("insert strategy: %v", err)
+		}
+	}
+
+	e := NewEngine(events.NewBus(), database.DB, Context{})
+	e.SetSymbolAllowlist(func(userID, symbol string) (bool, error) {
+		limits, err := database.Queries().GetUserLimits(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		return limits.AllowsSymbol(symbol), nil
+	})
+	if err := e.LoadStrategies(database.DB); err != nil {
+		t.Fatalf("LoadStrategies: %v", err)
+	}
+	if len(e.strategies) != 1 || e.strategies[0].ID() != "s-btc" {
+		ids := make([]string, 0, len(e.strategies))
+		for _, s := range e.strategies {
+			ids = append(ids, s.ID())
+		}
+		t.Fatalf("loaded %v, want only s-btc", ids)
+	}
+	if _, tracked := e.symbols["s-eth"]; tracked {
+		t.Fatal("disallowed strategy's symbol should not be subscribed")
+	}
+
+	// Starting it explicitly is refused as well.
+	if err := e.ResumeStrategy("s-eth"); err == nil {
+		t.Fatal("expected starting a strategy on a disallowed symbol to fail")
+	}
+	if len(e.strategies) != 1 {
+		t.Fatalf("disallowed strategy started: %d strategies loaded", len(e.strategies))
+	}
+}
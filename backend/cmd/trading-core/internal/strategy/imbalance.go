@@ -0,0 +1,86 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"trading-core/internal/indicators"
+)
+
+// ImbalanceStrategy is the reference consumer of the order-book features in
+// the indicator map: BUY when the book imbalance reaches +threshold, SELL at
+// -threshold, once per change of side. Ticks without book features are ignored.
+type ImbalanceStrategy struct {
+	id        string
+	symbol    string
+	threshold float64 // e.g. 0.3 = bids outweigh asks by 30% of top-of-book size
+	size      float64
+
+	lastSignal string
+}
+
+// NewImbalanceStrategy creates an order-book imbalance strategy.
+func NewImbalanceStrategy(id, symbol string, threshold, size float64) *ImbalanceStrategy {
+	return &ImbalanceStrategy{
+		id:         id,
+		symbol:     symbol,
+		threshold:  threshold,
+		size:       size,
+		lastSignal: "HOLD",
+	}
+}
+
+func (s *ImbalanceStrategy) ID() string {
+	return s.id
+}
+
+func (s *ImbalanceStrategy) Name() string {
+	return fmt.Sprintf("OBImbalance_%.2f", s.threshold)
+}
+
+// ImbalanceState defines the serializable state for ImbalanceStrategy
+type ImbalanceState struct {
+	LastSignal string `json:"last_signal"`
+}
+
+func (s *ImbalanceStrategy) GetState() (json.RawMessage, error) {
+	return json.Marshal(ImbalanceState{LastSignal: s.lastSignal})
+}
+
+func (s *ImbalanceStrategy) SetState(data json.RawMessage) error {
+	var state ImbalanceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	s.lastSignal = state.LastSignal
+	return nil
+}
+
+func (s *ImbalanceStrategy) OnTick(symbol string, price float64, ind map[string]float64) (*Signal, error) {
+	if symbol != "" && symbol != s.symbol {
+		return nil, nil
+	}
+	imbalance, ok := ind[indicators.BookImbalance]
+	if !ok {
+		return nil, nil
+	}
+
+	action := ""
+	switch {
+	case imbalance >= s.threshold:
+		action = "BUY"
+	case imbalance <= -s.threshold:
+		action = "SELL"
+	}
+	if action == "" || action == s.lastSignal {
+		return nil, nil
+	}
+	s.lastSignal = action
+	return &Signal{
+		Action: action,
+		Symbol: s.symbol,
+		Size:   s.size,
+		Note: fmt.Sprintf("Order book imbalance %.2f (microprice %.8g, spread %.1f bps)",
+			imbalance, ind[indicators.BookMicroprice], ind[indicators.BookSpreadBps]),
+	}, nil
+}
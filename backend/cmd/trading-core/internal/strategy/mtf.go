@@ -0,0 +1,109 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MTFStrategy is a moving average crossover on the primary interval that only
+// trades when a higher timeframe agrees: BUY needs the last trend-interval
+// close above its trendPeriod SMA, SELL needs it below. Crosses against (or
+// before) an established trend are suppressed.
+type MTFStrategy struct {
+	primary       *MACrossStrategy
+	trendInterval string // e.g. "1h"
+	trendPeriod   int    // SMA period on trend-interval closes
+
+	trendCloses []float64
+}
+
+// NewMTFStrategy creates a multi-timeframe MA cross strategy.
+func NewMTFStrategy(id, symbol string, fastPeriod, slowPeriod int, trendInterval string, trendPeriod int, size float64) *MTFStrategy {
+	return &MTFStrategy{
+		primary:       NewMACrossStrategy(id, symbol, fastPeriod, slowPeriod, size),
+		trendInterval: trendInterval,
+		trendPeriod:   trendPeriod,
+		trendCloses:   make([]float64, 0, trendPeriod),
+	}
+}
+
+func (s *MTFStrategy) ID() string {
+	return s.primary.ID()
+}
+
+func (s *MTFStrategy) Name() string {
+	return fmt.Sprintf("MTF_%d_%d_%s%d", s.primary.fastPeriod, s.primary.slowPeriod, s.trendInterval, s.trendPeriod)
+}
+
+// SecondaryInterval implements MultiTimeframe.
+func (s *MTFStrategy) SecondaryInterval() string {
+	return s.trendInterval
+}
+
+// OnSecondaryTick implements MultiTimeframe: records a closed trend-interval candle.
+func (s *MTFStrategy) OnSecondaryTick(symbol string, price float64, ind map[string]float64) {
+	if symbol != "" && symbol != s.primary.symbol {
+		return
+	}
+	s.trendCloses = append(s.trendCloses, price)
+	if len(s.trendCloses) > s.trendPeriod {
+		s.trendCloses = s.trendCloses[1:]
+	}
+}
+
+// trend returns "BUY" when the higher timeframe is bullish, "SELL" when
+// bearish and "" until trendPeriod closes have been seen.
+func (s *MTFStrategy) trend() string {
+	if s.trendPeriod <= 0 || len(s.trendCloses) < s.trendPeriod {
+		return ""
+	}
+	last := s.trendCloses[len(s.trendCloses)-1]
+	sma := calculateMA(s.trendCloses, s.trendPeriod)
+	switch {
+	case last > sma:
+		return "BUY"
+	case last < sma:
+		return "SELL"
+	}
+	return ""
+}
+
+// MTFState defines the serializable state for MTFStrategy
+type MTFState struct {
+	Primary     json.RawMessage `json:"primary"`
+	TrendCloses []float64       `json:"trend_closes"`
+}
+
+func (s *MTFStrategy) GetState() (json.RawMessage, error) {
+	primary, err := s.primary.GetState()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(MTFState{Primary: primary, TrendCloses: s.trendCloses})
+}
+
+func (s *MTFStrategy) SetState(data json.RawMessage) error {
+	var state MTFState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if len(state.Primary) > 0 {
+		if err := s.primary.SetState(state.Primary); err != nil {
+			return err
+		}
+	}
+	s.trendCloses = state.TrendCloses
+	return nil
+}
+
+func (s *MTFStrategy) OnTick(symbol string, price float64, ind map[string]float64) (*Signal, error) {
+	sig, err := s.primary.OnTick(symbol, price, ind)
+	if err != nil || sig == nil {
+		return sig, err
+	}
+	if trend := s.trend(); trend != sig.Action {
+		return nil, nil
+	}
+	sig.Note += fmt.Sprintf(" (confirmed by %s trend)", s.trendInterval)
+	return sig, nil
+}
@@ -12,6 +12,14 @@ type Signal struct {
 	Symbol     string
 	Size       float64
 	Note       string
+
+	// Optional IOC/FOK execution: when TimeInForce is set the signal becomes a
+	// LIMIT order at LimitPrice (or the last price when zero) instead of MARKET.
+	TimeInForce string
+	LimitPrice  float64
+
+	// Optional routing preference, e.g. "maker_first" for fee-sensitive strategies.
+	Routing string
 }
 
 // Strategy defines the interface for all strategies.
@@ -30,7 +38,18 @@ type Strategy interface {
 	SetState(data json.RawMessage) error
 }
 
+// MultiTimeframe is implemented by strategies that also watch a secondary
+// (typically higher) kline interval. The engine subscribes the feed to it and
+// delivers each closed candle on it to OnSecondaryTick.
+type MultiTimeframe interface {
+	SecondaryInterval() string
+	OnSecondaryTick(symbol string, price float64, ind map[string]float64)
+}
+
 // Context bundles shared services for strategies.
 type Context struct {
 	Indicators *indicators.Engine
+	// Book supplies order-book features (indicators.BookImbalance etc.) that
+	// are merged into the indicator map; nil without a depth stream.
+	Book *indicators.BookStore
 }
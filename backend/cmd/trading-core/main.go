@@ -3,21 +3,24 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
-
 	"trading-core/internal/api"
 	"trading-core/internal/balance"
+	"trading-core/internal/data"
 	"trading-core/internal/engine"
+	"trading-core/internal/equity"
 	"trading-core/internal/events"
 	"trading-core/internal/gateway"
 	"trading-core/internal/indicators"
@@ -28,6 +31,7 @@ import (
 	"trading-core/internal/risk"
 	"trading-core/internal/state"
 	"trading-core/internal/strategy"
+	"trading-core/internal/tracing"
 	"trading-core/pkg/binance"
 	"trading-core/pkg/config"
 	"trading-core/pkg/crypto"
@@ -37,12 +41,23 @@ import (
 	exspot "trading-core/pkg/exchanges/binance/spot"
 	exchange "trading-core/pkg/exchanges/common"
 	"trading-core/pkg/i18n"
+	"trading-core/pkg/ids"
 	marketbinance "trading-core/pkg/market/binance"
+	"trading-core/pkg/money"
 )
 
-type priceCache struct {
-	mu sync.RWMutex
-	m  map[string]float64
+// logEnvironmentBanner states loudly where orders will be sent.
+func logEnvironmentBanner(cfg *config.Config) {
+	line := strings.Repeat("=", 60)
+	log.Println(line)
+	log.Printf("  ENVIRONMENT: %s   TRADING MODE: %s", strings.ToUpper(cfg.Environment), cfg.TradingMode())
+	if cfg.ForcedDryRun {
+		log.Printf("  ⚠️ Live mainnet trading is only allowed with ENVIRONMENT=prod; dry-run forced")
+	}
+	if cfg.TradingMode() == "LIVE MAINNET" {
+		log.Printf("  ⚠️ Orders WILL be sent to mainnet with real funds")
+	}
+	log.Println(line)
 }
 
 type exposureCache struct {
@@ -52,21 +67,6 @@ type exposureCache struct {
 	ttl time.Duration
 }
 
-func (p *priceCache) set(sym string, price float64) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if p.m == nil {
-		p.m = make(map[string]float64)
-	}
-	p.m[sym] = price
-}
-
-func (p *priceCache) get(sym string) float64 {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return p.m[sym]
-}
-
 func (e *exposureCache) get(compute func() float64) float64 {
 	e.mu.RLock()
 	if time.Since(e.ts) < e.ttl && e.ttl > 0 {
@@ -95,6 +95,7 @@ func main() {
 
 	i18n.SetLanguage(i18n.Language(cfg.Language))
 	log.Println(i18n.Get("Starting"))
+	logEnvironmentBanner(cfg)
 
 	dbPath := cfg.DBPath
 	if cfg.DryRun && cfg.DryRunDBPath != "" {
@@ -108,18 +109,34 @@ func main() {
 
 	// Core services
 	bus := events.NewBus()
+	bus.SetDefaultBuffer(cfg.EventBusBuffer)
+	bus.SetDedupWindow(events.EventRiskAlert, time.Duration(cfg.AlertDedupWindowSeconds)*time.Second)
+	bus.SetSlowConsumerHandler(func(sc events.SlowConsumer) {
+		if sc.Event != events.EventRiskAlert {
+			bus.Publish(events.EventRiskAlert, events.Alert{Type: "slow_consumer", Key: sc.Name + "@" + string(sc.Event),
+				Message: fmt.Sprintf("slow event consumer %q on %s (%d/%d buffered, %d dropped)", sc.Name, sc.Event, sc.Len, sc.Cap, sc.Dropped)})
+		}
+	})
+
+	startNotifications(ctx, bus, cfg)
 
-	database, err := db.New(dbPath)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{Endpoint: cfg.TracingEndpoint, ServiceName: cfg.TracingServiceName, SampleRatio: cfg.TracingSampleRatio})
+	if err != nil {
+		log.Printf("⚠️ Tracing disabled: %v", err)
+		shutdownTracing = func(context.Context) error { return nil }
+	} else if cfg.TracingEndpoint != "" {
+		log.Printf("✓ Exporting traces to %s", cfg.TracingEndpoint)
+	}
+
+	database, err := openDatabase(ctx, cfg, dbPath)
 	if err != nil {
 		log.Fatalf(i18n.Get("DBInitFailed"), err)
 	}
 	defer database.Close()
-	if err := db.ApplyMigrations(database); err != nil {
-		log.Fatalf(i18n.Get("DBMigrationsFailed"), err)
-	}
 
 	// In-memory state seeded from DB
 	stateMgr := state.NewManager(database)
+	stateMgr.SetHedgeMode(cfg.HedgeMode)
 	if err := stateMgr.Load(ctx); err != nil {
 		log.Fatalf(i18n.Get("StateLoadFailed"), err)
 	}
@@ -135,7 +152,26 @@ func main() {
 	cfgCopy := riskMgr.GetConfig()
 	log.Printf(i18n.Get("RiskManagerInit"), cfgCopy.DefaultStopLoss*100, cfgCopy.DefaultTakeProfit*100)
 	stopLossMgr := risk.NewStopLossManager()
-	priceCache := &priceCache{m: make(map[string]float64)}
+	priceCache := market.NewLastPriceStore()
+	priceCache.SetDefaultField(market.PriceField(cfg.PriceCacheField))
+	slippageGuard := risk.NewSlippageGuard(func() float64 { return riskMgr.GetConfig().MaxSlippage }, 5*time.Minute, func(ev risk.SlippageEvent) {
+		log.Printf("⚠️ Max slippage exceeded: %s", ev)
+		bus.Publish(events.EventRiskAlert, events.Alert{Type: "max_slippage", Symbol: ev.Symbol, Key: ev.StrategyID, Message: ev.String()})
+	})
+	// Fills priced far from the last known price are held for operator review.
+	fillQuarantine := risk.NewFillQuarantine(cfg.MaxFillDeviationPct)
+	fillQuarantine.SetAlertFn(func(o risk.FillOutlier) {
+		bus.Publish(events.EventRiskAlert, events.Alert{Type: "fill_price_outlier", UserID: o.UserID, Symbol: o.Symbol, Message: o.String()})
+	})
+	fillQuarantine.SetReleaseFn(func(o risk.FillOutlier) {
+		bus.Publish(events.EventOrderFilled, order.Order{
+			ID: o.OrderID, TradeID: o.TradeID, Symbol: o.Symbol, Side: o.Side, Qty: o.Qty, Price: o.FillPrice, PositionSide: o.PositionSide,
+			UserID: o.UserID, ConnectionID: o.ConnectionID, StrategyInstanceID: o.StrategyID,
+		})
+	})
+	if err := fillQuarantine.Restore(ctx, database.DB); err != nil {
+		log.Printf("⚠️ Restore quarantined fills: %v", err)
+	}
 	expCache := &exposureCache{ttl: 1 * time.Second}
 
 	// Multi-user: Key Manager (for encrypted API keys)
@@ -152,12 +188,19 @@ func main() {
 	// Multi-user: Gateway Manager (per-connection gateways)
 	var gatewayMgr *gateway.Manager
 	if keyMgr != nil {
+		factory := gateway.DefaultFactory
+		if cfg.TestnetOnly() {
+			factory = gateway.TestnetFactory
+		}
 		gatewayMgr = gateway.NewManager(
 			database.Queries(),
 			keyMgr,
-			gateway.DefaultFactory,
+			factory,
 			gateway.DefaultConfig(),
 		)
+		gatewayMgr.SetAlertFn(func(msg string) {
+			bus.Publish(events.EventRiskAlert, events.Alert{Type: "gateway_evictions", Message: msg})
+		})
 		gatewayMgr.Start(ctx)
 		log.Println("🌐 GatewayManager started (multi-user mode)")
 	}
@@ -165,6 +208,71 @@ func main() {
 	// Multi-user: per-user risk manager
 	multiUserRisk := risk.NewMultiUserManager(database.DB)
 
+	// Scheduled maintenance: blocks new entries in risk evaluation and at queue drain.
+	maintenance := risk.NewMaintenanceSchedule()
+	if windows, err := risk.ParseMaintenanceWindows(cfg.MaintenanceWindows); err != nil {
+		log.Printf("⚠️ Ignoring MAINTENANCE_WINDOWS: %v", err)
+	} else if len(windows) > 0 {
+		maintenance.Set(windows)
+		log.Printf("✓ %d maintenance window(s) configured", len(windows))
+	}
+	riskMgr.SetMaintenance(maintenance)
+	multiUserRisk.SetMaintenance(maintenance)
+
+	// Operator symbol halts (/admin/symbols): checked with maintenance above.
+	symbolHalts := risk.NewSymbolHalts()
+	if err := symbolHalts.Restore(ctx, database.DB); err != nil {
+		log.Printf("⚠️ Restore symbol halts: %v", err)
+	}
+	riskMgr.SetSymbolHalts(symbolHalts)
+	multiUserRisk.SetSymbolHalts(symbolHalts)
+
+	// Losing-streak breaker: fed by realized fills, checked with the halts above.
+	lossStreaks := risk.NewLossStreaks(cfg.MaxLossStreak)
+	lossStreaks.SetAlertFn(func(p risk.LossStreakPause) {
+		bus.Publish(events.EventRiskAlert, events.Alert{Type: "loss_streak", UserID: p.UserID, Message: p.BlockReason()})
+	})
+	if err := lossStreaks.Restore(ctx, database.DB); err != nil {
+		log.Printf("⚠️ Restore loss-streak pauses: %v", err)
+	}
+	riskMgr.SetLossStreaks(lossStreaks)
+	multiUserRisk.SetLossStreaks(lossStreaks)
+
+	// Strategy allocations are checked against each strategy's recorded position.
+	strategyHoldings := func(strategyID string) (float64, float64, error) {
+		sp, err := database.GetStrategyPosition(context.Background(), strategyID)
+		if err != nil || sp == nil {
+			return 0, 0, err
+		}
+		return sp.Qty, sp.AvgPrice, nil
+	}
+	riskMgr.SetStrategyHoldings(strategyHoldings)
+	multiUserRisk.SetStrategyHoldings(strategyHoldings)
+
+	// Per-connection daily-loss and exposure budgets are stored on the connection;
+	// they only change when it is created, so a short cache spares a query per signal.
+	connectionLimits := risk.CacheConnectionLimits(func(connectionID string) (risk.ConnectionLimits, error) {
+		maxLoss, maxExposure, err := database.GetConnectionRiskLimits(context.Background(), connectionID)
+		if err != nil {
+			return risk.ConnectionLimits{}, err
+		}
+		return risk.ConnectionLimits{MaxDailyLoss: maxLoss, MaxExposure: maxExposure}, nil
+	}, 30*time.Second)
+	riskMgr.SetConnectionLimits(connectionLimits)
+	multiUserRisk.SetConnectionLimits(connectionLimits)
+
+	// Users' concurrent-position caps: their admin override, else the global config.
+	multiUserRisk.SetPositionCap(func(userID string) (int, error) {
+		limits, err := database.Queries().GetUserLimits(context.Background(), userID)
+		if err != nil {
+			return 0, err
+		}
+		if limits.MaxConcurrentPositions != 0 {
+			return limits.MaxConcurrentPositions, nil
+		}
+		return riskMgr.GetConfig().MaxConcurrentPositions, nil
+	})
+
 	// Exchange gateway selection (fallback for single-user mode)
 	var exchGateway exchange.Gateway
 	venue := "none"
@@ -178,23 +286,80 @@ func main() {
 		exchGateway = exspot.New(exspot.Config{
 			APIKey:    cfg.BinanceAPIKey,
 			APISecret: cfg.BinanceAPISecret,
-			Testnet:   false,
+			Testnet:   cfg.TestnetOnly(),
 		})
 	case cfg.EnableBinanceUSDTFutures:
 		venue = "binance-usdtfut"
 		exchGateway = exfutusdt.NewClient(exfutusdt.Config{
-			APIKey:    cfg.BinanceUSDTKey,
-			APISecret: cfg.BinanceUSDTSecret,
-			Testnet:   false,
+			APIKey:      cfg.BinanceUSDTKey,
+			APISecret:   cfg.BinanceUSDTSecret,
+			Testnet:     cfg.TestnetOnly(),
+			UseWSOrders: cfg.BinanceUSDTUseWSOrders,
 		})
 	case cfg.EnableBinanceCoinFutures:
 		venue = "binance-coinfut"
 		exchGateway = exfutcoin.NewClient(exfutcoin.Config{
 			APIKey:    cfg.BinanceCoinKey,
 			APISecret: cfg.BinanceCoinSecret,
-			Testnet:   false,
+			Testnet:   cfg.TestnetOnly(),
 		})
 	}
+	// Dead-man's switch: if we stop heartbeating, the exchange cancels the
+	// futures orders of the global gateway and of every live futures connection.
+	if cfg.DeadmanSwitchEnabled && !cfg.DryRun {
+		countdown := time.Duration(cfg.DeadmanCountdownSecs) * time.Second
+		order.NewDeadmanSwitch(func(ctx context.Context) ([]order.DeadmanConnection, error) {
+			var conns []order.DeadmanConnection
+			if _, ok := exchGateway.(order.CountdownArmer); ok {
+				global := order.DeadmanConnection{Gateway: exchGateway}
+				if venue == "binance-usdtfut" {
+					global.Symbols = cfg.BinanceSymbols
+				}
+				conns = append(conns, global)
+			}
+			if gatewayMgr == nil {
+				return conns, nil
+			}
+			live, err := database.ListActiveConnectionsByType(ctx, "binance-usdtfut", "binance-coinfut")
+			if err != nil {
+				return conns, err
+			}
+			for _, c := range live {
+				if c.Paper {
+					continue
+				}
+				gw, err := gatewayMgr.GetOrCreate(ctx, c.UserID, c.ID)
+				if err != nil {
+					log.Printf("⚠️ Dead-man's switch: gateway for connection %s failed: %v", c.ID, err)
+					continue
+				}
+				symbols, err := database.ActiveStrategySymbols(ctx, c.ID)
+				if err != nil {
+					log.Printf("⚠️ Dead-man's switch: strategy symbols of connection %s: %v", c.ID, err)
+				}
+				conns = append(conns, order.DeadmanConnection{ConnectionID: c.ID, Gateway: gw, Symbols: symbols})
+			}
+			return conns, nil
+		}, countdown, time.Duration(cfg.DeadmanRefreshSecs)*time.Second).Start(ctx)
+		log.Printf("✓ Dead-man's switch armed for futures accounts (countdown %s)", countdown)
+	}
+	// Exchange info for every market (public endpoints), so symbols can be
+	// validated against any user's connection type.
+	go func() {
+		sources := map[exchange.MarketType]exchange.SymbolInfoSource{
+			exchange.MarketSpot:    exspot.New(exspot.Config{}),
+			exchange.MarketUSDTFut: exfutusdt.NewClient(exfutusdt.Config{}),
+			exchange.MarketCoinFut: exfutcoin.NewClient(exfutcoin.Config{}),
+		}
+		for market, src := range sources {
+			n, err := exchange.LoadMarketSymbols(ctx, market, src)
+			if err != nil {
+				log.Printf("⚠️ Exchange info load failed for %s, using symbol heuristics: %v", market, err)
+				continue
+			}
+			log.Printf("✓ Loaded %d %s symbols from exchange info", n, market)
+		}
+	}()
 
 	// Balance manager with exchange integration (global account)
 	var balanceMgr *balance.Manager
@@ -262,10 +427,42 @@ func main() {
 	if cfg.DryRun && cfg.DryRunEnableOrderWAL {
 		walPath = cfg.DryRunOrderWALPath
 	}
-	if enableWal {
-		pq, err := order.NewPersistentQueue(walPath, 200)
-		if err != nil {
-			log.Printf(i18n.Get("PersistentQueueFailed"), err)
+	const asyncWorkers = 4
+	sharedQueue := cfg.OrderQueueBackend == "shared"
+	if sharedQueue {
+		// The trading database only reaches processes on this host; a
+		// PostgreSQL store lets instances on other hosts share the queue.
+		var store db.OrderJobStore = database
+		storeName := "database (" + dbPath + ")"
+		if cfg.SharedQueueDSN != "" {
+			pg, err := db.NewPostgresOrderJobs(ctx, cfg.SharedQueueDSN)
+			if err != nil {
+				log.Fatalf("Shared order queue store unavailable: %v", err)
+			}
+			defer pg.Close()
+			store, storeName = pg, "postgres"
+		}
+		sq := order.NewSharedQueue(store, order.SharedQueueConfig{
+			VisibilityTimeout: time.Duration(cfg.SharedQueueVisibilitySec) * time.Second,
+			Concurrency:       asyncWorkers,
+		})
+		orderQueue = sq
+		log.Printf("📬 Shared order queue enabled (owner=%s, store=%s, visibility=%ds)", sq.Owner(), storeName, cfg.SharedQueueVisibilitySec)
+	} else if enableWal {
+		var (
+			pq     *order.PersistentQueue
+			walErr error
+		)
+		if cfg.OrderWALBackend == "db" {
+			var wal order.WAL
+			if wal, walErr = order.NewDBWAL(database); walErr == nil {
+				pq, walPath = order.NewPersistentQueueWithWAL(wal, 200), "database ("+dbPath+")"
+			}
+		} else {
+			pq, walErr = order.NewPersistentQueue(walPath, 200)
+		}
+		if walErr != nil {
+			log.Printf(i18n.Get("PersistentQueueFailed"), walErr)
 			orderQueue = order.NewQueue(200)
 		} else {
 			if err := pq.Recover(); err != nil {
@@ -278,18 +475,49 @@ func main() {
 		orderQueue = order.NewQueue(200)
 	}
 	exec := order.NewExecutor(database, bus, exchGateway, venue, cfg.BinanceTestnet)
+	// Ladder tranches: delayed ones persist across restarts and are dropped
+	// once their strategy stops; resting ones are cancelled with it.
+	ladders := order.NewLadderScheduler(database, orderQueue)
+	ladders.SetActiveCheck(func(ctx context.Context, strategyID string) (bool, error) {
+		var status string
+		err := database.DB.QueryRowContext(ctx, `SELECT COALESCE(status, '') FROM strategy_instances WHERE id = ?`, strategyID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return status == "ACTIVE", err
+	})
+	ladders.SetCanceler(exec.CancelResting)
+	if err := ladders.Resume(ctx); err != nil {
+		log.Printf("⚠️ Ladder tranches not resumed: %v", err)
+	}
 	mode := order.ModeProduction
 	if cfg.DryRun || !cfg.ExecutionEnabled {
 		mode = order.ModeDryRun
 		log.Println(i18n.Get("DryRunMode"))
 	}
-	dryRunner := order.NewDryRunExecutor(mode, exec, cfg.DryRunInitialBalance, order.DryRunSimConfig{
+	rejectRules := make([]order.SimRejectRule, 0, len(cfg.DryRunRejectSymbols))
+	for _, sym := range cfg.DryRunRejectSymbols {
+		rejectRules = append(rejectRules, order.SimRejectRule{Symbol: sym})
+	}
+	simCfg := order.DryRunSimConfig{
 		FeeRate:             cfg.DryRunFeeRate,
 		SlippageBps:         cfg.DryRunSlippageBps,
 		GatewayLatencyMinMs: cfg.DryRunGwLatencyMinMs,
 		GatewayLatencyMaxMs: cfg.DryRunGwLatencyMaxMs,
-	})
-	asyncExec := order.NewAsyncExecutorWithDryRun(dryRunner, 4) // V2 P0-B: Async Execution
+		RejectProbability:   cfg.DryRunRejectProb,
+		RejectRules:         rejectRules,
+		Seed:                cfg.DryRunSeed,
+		ValidateFilters:     cfg.DryRunValidateFilters,
+	}
+	dryRunner := order.NewDryRunExecutor(mode, exec, cfg.DryRunInitialBalance, simCfg)
+	asyncExec := order.NewAsyncExecutorWithDryRun(dryRunner, asyncWorkers) // V2 P0-B: Async Execution
+
+	exec.SetLeverageCap(cfg.MaxLeverage, cfg.LeverageCapMode == "reject")
+	exec.SetWriteRetry(cfg.DBWriteRetries, time.Duration(cfg.DBWriteRetryBackoff)*time.Millisecond, cfg.RecoveryLogPath)
+	exec.SetPostOnlyRetries(cfg.PostOnlyRetries)
+	// Shared with the user streams below so a fill is booked once.
+	fillLedger := order.NewFillLedger(cfg.FillSource)
+	exec.SetFillLedger(fillLedger)
 
 	// Multi-user: inject KeyManager and Gateway pool
 	if keyMgr != nil {
@@ -333,10 +561,19 @@ func main() {
 		}
 	}()
 
+	// Reconciliation passes pause the order drain while they correct state.
+	var drainGate *order.DrainGate
+	if cfg.ReconPauseDrain {
+		drainGate = order.NewDrainGate(time.Duration(cfg.ReconPauseMaxMs) * time.Millisecond)
+	}
+
 	// Reconciliation service (only in production mode)
 	if !cfg.DryRun {
 		if reconClient, ok := exchGateway.(reconciliation.ExchangeClient); ok {
 			reconService := reconciliation.NewService(reconClient, stateMgr, database, 5*time.Minute)
+			if drainGate != nil {
+				reconService.SetDrainPauser(drainGate)
+			}
 			reconService.Start(ctx)
 			log.Println(i18n.Get("ReconStarted"))
 		} else {
@@ -344,35 +581,183 @@ func main() {
 		}
 	}
 
-	// Market data (mock first, real later)
-	binanceClient := binance.NewClient(cfg.BinanceAPIKey, cfg.BinanceAPISecret, false)
-	streamClient := binance.NewStreamClient(false)
-	if cfg.UseMockFeed {
-		mock := market.MockFeed{
-			Bus:        bus,
-			Symbols:    cfg.BinanceSymbols,
-			StartPrice: 100,
-			Step:       0.8,
-			Interval:   time.Second,
+	// Strategy position healer: strategy_positions is updated per fill and can
+	// drift when a fill event is missed; rebuild it from the trade history.
+	if cfg.StrategyReconMinutes > 0 {
+		healer := reconciliation.NewStrategyPositionHealer(database, time.Duration(cfg.StrategyReconMinutes)*time.Minute)
+		healer.QtyTolerance = cfg.StrategyReconQtyTol
+		healer.PriceTolerancePct = cfg.StrategyReconPricePct
+		healer.AutoHeal = cfg.StrategyReconAutoHeal
+		if drainGate != nil {
+			healer.Pauser = drainGate
 		}
-		mock.Start(ctx)
-		log.Println(i18n.Get("MockFeedStarted"))
-	} else {
-		feed := market.Feed{
-			Client:   binanceClient,
-			Stream:   streamClient,
-			Bus:      bus,
-			Symbols:  cfg.BinanceSymbols,
-			Interval: "1m",
+		healer.Start(ctx)
+		log.Printf("✓ Strategy position reconciliation every %d min (auto-heal=%v)", cfg.StrategyReconMinutes, cfg.StrategyReconAutoHeal)
+	}
+
+	// Funding import: attribute futures funding payments to the strategies
+	// holding each symbol so PnL attribution can net them out. COIN-M
+	// payments settle in the base coin and are converted into the reporting
+	// asset at the coin's perpetual mark price.
+	// Per-user futures connections are imported too, attributed to the
+	// strategies bound to each connection.
+	globalFunding := fundingPayments(ctx, exchGateway, venue, cfg.ReportingAsset)
+	var fundingSource equity.FundingSource
+	if globalFunding != nil || gatewayMgr != nil {
+		fundingSource = func(ctx context.Context) ([]equity.FundingPayment, error) {
+			var payments []equity.FundingPayment
+			if globalFunding != nil {
+				p, err := globalFunding()
+				if err != nil {
+					return nil, err
+				}
+				payments = append(payments, p...)
+			}
+			if gatewayMgr == nil {
+				return payments, nil
+			}
+			conns, err := database.ListActiveConnectionsByType(ctx, "binance-usdtfut", "binance-coinfut")
+			if err != nil {
+				return payments, err
+			}
+			for _, c := range conns {
+				if c.Paper {
+					continue
+				}
+				gw, err := gatewayMgr.GetOrCreate(ctx, c.UserID, c.ID)
+				if err != nil {
+					log.Printf("⚠️ Funding sync: gateway for connection %s failed: %v", c.ID, err)
+					continue
+				}
+				fetch := fundingPayments(ctx, gw, c.ID, cfg.ReportingAsset)
+				if fetch == nil {
+					continue
+				}
+				p, err := fetch()
+				if err != nil {
+					log.Printf("⚠️ Funding sync: connection %s: %v", c.ID, err)
+					continue
+				}
+				for i := range p {
+					p[i].UserID, p[i].ConnectionID = c.UserID, c.ID
+				}
+				payments = append(payments, p...)
+			}
+			return payments, nil
+		}
+	}
+	if fundingSource != nil && cfg.FundingSyncMinutes > 0 && !cfg.DryRun {
+		equity.NewFundingSync(database, fundingSource, time.Duration(cfg.FundingSyncMinutes)*time.Minute).Start(ctx)
+	}
+
+	// Liquidation guard: alert (and optionally reduce) futures positions whose
+	// mark price is close to the liquidation price.
+	if cfg.LiquidationBufferPct > 0 {
+		liqMonitor := risk.NewLiquidationMonitor(func(ctx context.Context) ([]risk.LiquidationAccount, error) {
+			var accounts []risk.LiquidationAccount
+			if reporter, ok := exchGateway.(exchange.MarginReporter); ok {
+				accounts = append(accounts, risk.LiquidationAccount{ExchangeType: venue, Reporter: reporter})
+			}
+			if gatewayMgr == nil {
+				return accounts, nil
+			}
+			conns, err := database.ListActiveConnectionsByType(ctx, "binance-usdtfut", "binance-coinfut")
+			if err != nil {
+				return accounts, err
+			}
+			for _, conn := range conns {
+				gw, err := gatewayMgr.GetOrCreate(ctx, conn.UserID, conn.ID)
+				if err != nil {
+					log.Printf("⚠️ Liquidation monitor: gateway for connection %s failed: %v", conn.ID, err)
+					continue
+				}
+				if reporter, ok := gw.(exchange.MarginReporter); ok {
+					accounts = append(accounts, risk.LiquidationAccount{UserID: conn.UserID, ConnectionID: conn.ID, ExchangeType: conn.ExchangeType, Reporter: reporter})
+				}
+			}
+			return accounts, nil
+		}, risk.LiquidationConfig{
+			BufferPct:      cfg.LiquidationBufferPct,
+			AutoReduce:     cfg.LiquidationAutoReduce,
+			ReduceFraction: cfg.LiquidationReduceFraction,
+			TopUpMax:       cfg.LiquidationMarginTopUpMax,
+			Interval:       time.Duration(cfg.LiquidationCheckSeconds) * time.Second,
+		})
+		liqMonitor.SetAlertFn(func(a risk.LiquidationAlert) {
+			bus.Publish(events.EventRiskAlert, events.Alert{Type: a.Kind, UserID: a.UserID, Symbol: a.Symbol, Key: a.ConnectionID, Message: a.Message})
+		})
+		liqMonitor.SetMarkFn(priceCache.SetMark)
+		liqMonitor.SetReduceFn(func(req risk.ReduceRequest) {
+			orderQueue.Enqueue(order.Order{
+				ID:           ids.New(),
+				Symbol:       req.Symbol,
+				Side:         req.Side,
+				Type:         "MARKET",
+				Qty:          req.Qty,
+				Status:       "NEW",
+				CreatedAt:    time.Now(),
+				ReduceOnly:   req.PositionSide == "",
+				PositionSide: req.PositionSide,
+				Market:       marketFromVenue(req.ExchangeType),
+				UserID:       req.UserID,
+				ConnectionID: req.ConnectionID,
+			})
+			log.Printf("🔄 Liquidation guard: reducing %s %s %.8g (%s)", req.Symbol, req.Side, req.Qty, req.ConnectionID)
+		})
+		liqMonitor.Start(ctx)
+		log.Printf("✓ Liquidation monitor enabled (buffer %.2f%%, margin top-up cap %g, auto-reduce %v)", cfg.LiquidationBufferPct, cfg.LiquidationMarginTopUpMax, cfg.LiquidationAutoReduce)
+	}
+
+	// Equity curve: periodic per-user snapshots of balance + marked positions.
+	// Equity is marked to the futures mark price where one is known.
+	markPrice := func(symbol string) float64 { return priceCache.GetField(symbol, market.PriceFieldMark) }
+	equitySnapshotter := equity.NewSnapshotter(database, userBalanceMgr, markPrice, 5*time.Minute)
+	// Users trading only through futures connections (or, without any, on a
+	// futures default venue) hold margined positions.
+	equitySnapshotter.Margined = func(ctx context.Context, userID string) bool {
+		conns, err := database.Queries().GetConnectionsByUser(ctx, userID)
+		if err != nil {
+			log.Printf("⚠️ Equity snapshot: connections of user %s: %v", userID, err)
+		}
+		active := 0
+		for _, c := range conns {
+			if !c.IsActive {
+				continue
+			}
+			if c.ExchangeType == "binance-spot" {
+				return false
+			}
+			active++
+		}
+		if active == 0 {
+			return venue == "binance-usdtfut" || venue == "binance-coinfut"
+		}
+		return true
+	}
+	equitySnapshotter.Start(ctx)
+
+	// Optional tick recorder: live klines to disk for later backtests.
+	if cfg.RecordTicks {
+		recorder, err := data.NewRecorder(data.RecorderConfig{
+			Dir:          cfg.RecordDir,
+			Symbols:      cfg.RecordSymbols,
+			MaxFileBytes: int64(cfg.RecordMaxFileMB) << 20,
+			RotateEvery:  time.Duration(cfg.RecordRotateMinutes) * time.Minute,
+		})
+		if err != nil {
+			log.Printf("❌ Tick recorder disabled: %v", err)
+		} else {
+			recordSub, unsubRecord := bus.SubscribeNamed(events.EventPriceTick, "tick-recorder", 0)
+			defer unsubRecord()
+			go recorder.Run(ctx, recordSub)
+			log.Printf("✓ Recording ticks to %s", cfg.RecordDir)
 		}
-		feed.Start(ctx)
-		log.Println(i18n.Get("BinanceFeedStarted"))
 	}
 
 	// Price cache subscriber (for risk pricing + trailing stop + auto-close)
-	priceSub, unsubPrice := bus.Subscribe(events.EventPriceTick, 100)
+	priceSub, unsubPrice := bus.SubscribeNamed(events.EventPriceTick, "price-cache", 0)
 	defer unsubPrice()
-	filledSub, unsubFilled := bus.Subscribe(events.EventOrderFilled, 100)
+	filledSub, unsubFilled := bus.SubscribeNamed(events.EventOrderFilled, "position-updater", 0)
 	defer unsubFilled()
 
 	// Helper function to handle stop loss trigger
@@ -382,7 +767,7 @@ func main() {
 		if qty > 0 {
 			closeSide := oppositeSide(sideFromQty(pos.Qty))
 			orderQueue.Enqueue(order.Order{
-				ID:        uuid.NewString(),
+				ID:        ids.New(),
 				Symbol:    symbol,
 				Side:      closeSide,
 				Type:      "MARKET",
@@ -403,6 +788,11 @@ func main() {
 			switch v := msg.(type) {
 			case marketbinance.Kline:
 				symbol, price = v.Symbol, v.Close
+			case marketbinance.PriceTick:
+				if v.Source == marketbinance.PriceSourceBookMid {
+					continue // quotes reach the cache from book tickers
+				}
+				symbol, price = v.Symbol, v.Price
 			case struct {
 				Symbol string
 				Close  float64
@@ -416,7 +806,9 @@ func main() {
 				continue
 			}
 
-			priceCache.set(symbol, price)
+			priceCache.Set(symbol, price)
+			// Stops watch the configured cache field (last, mid, mark, ...).
+			price = priceCache.Get(symbol)
 
 			// Check stop loss trigger
 			if decision := stopLossMgr.UpdatePrice(symbol, price); decision != nil && decision.Triggered {
@@ -425,20 +817,36 @@ func main() {
 		}
 	}()
 
+	// Fee estimator for fills whose trades carry no commission.
+	feeResolver := &order.FeeResolver{DB: database, Default: order.FlatFeeSchedule(cfg.DryRunFeeRate)}
+
 	// Filled orders -> update positions and risk metrics (price fallback to latest cache)
 	go func() {
 		for msg := range filledSub {
 			var (
-				symbol string
-				side   string
-				qty    float64
-				price  float64
-				userID string
+				orderID    string
+				symbol     string
+				side       string
+				qty        float64
+				price      float64
+				userID     string
+				connID     string
+				strategyID string
+				posSide    string
+				tradeID    string
 			)
 			switch v := msg.(type) {
 			case order.Order:
-				symbol, side, qty, price = v.Symbol, v.Side, v.Qty, v.Price
-				userID = v.UserID
+				orderID, symbol, side, qty, price = v.ID, v.Symbol, v.Side, v.Qty, v.Price
+				userID, connID, strategyID = v.UserID, v.ConnectionID, v.StrategyInstanceID
+				posSide, tradeID = v.PositionSide, v.TradeID
+				if v.Paper {
+					// Simulated on a paper connection: the paper wallet already
+					// booked it; live positions, risk and balances must not move.
+					log.Printf("Paper fill %s %s %g %s @ %.8g (user %s, connection %s) kept out of live books",
+						v.ID, v.Side, v.Qty, v.Symbol, v.Price, v.UserID, v.ConnectionID)
+					continue
+				}
 			case struct {
 				ID     string
 				Symbol string
@@ -446,15 +854,27 @@ func main() {
 				Qty    float64
 				Price  float64
 			}:
-				symbol, side, qty, price = v.Symbol, v.Side, v.Qty, v.Price
+				orderID, symbol, side, qty, price = v.ID, v.Symbol, v.Side, v.Qty, v.Price
 			default:
 				log.Printf(i18n.Get("UnknownFilledOrderType"), msg)
 				continue
 			}
 
+			// Compare the executed price with the signal-time reference (MaxSlippage)
+			slippageGuard.CheckFill(orderID, price)
+
+			// Hold fills at an implausible price instead of booking them into positions and PnL.
+			if _, held := fillQuarantine.Check(risk.FillOutlier{
+				TradeID: tradeID, OrderID: orderID, UserID: userID, ConnectionID: connID, StrategyID: strategyID,
+				Symbol: symbol, Side: side, PositionSide: posSide, Qty: qty,
+				FillPrice: price, RefPrice: priceCache.Get(symbol),
+			}); held {
+				continue
+			}
+
 			fillPrice := price
 			if fillPrice == 0 {
-				if p := priceCache.get(symbol); p > 0 {
+				if p := priceCache.Get(symbol); p > 0 {
 					fillPrice = p
 					log.Printf(i18n.Get("UsingCachedPrice"), symbol, fillPrice)
 				}
@@ -464,30 +884,6 @@ func main() {
 				log.Printf(i18n.Get("FillPriceZeroFallback"), symbol)
 			}
 
-			// Snapshot previous position for realized PnL
-			prev := stateMgr.Position(symbol)
-
-			// Update in-memory + DB position
-			_, _ = stateMgr.RecordFill(ctx, userID, symbol, side, qty, fillPrice)
-
-			// Get updated position for cleanup check
-			newPos := stateMgr.Position(symbol)
-
-			// Compute simple realized PnL on closing quantity
-			var pnl float64
-			closeQty := math.Min(math.Abs(prev.Qty), qty)
-			if closeQty > 0 {
-				switch {
-				case prev.Qty > 0 && strings.ToUpper(side) == "SELL":
-					pnl = (fillPrice - prev.AvgPrice) * closeQty
-				case prev.Qty < 0 && strings.ToUpper(side) == "BUY":
-					pnl = (prev.AvgPrice - fillPrice) * closeQty
-				}
-				log.Printf(i18n.Get("RealizedPnL"), pnl, symbol, side, closeQty, fillPrice)
-			} else {
-				log.Printf(i18n.Get("PositionOpened"), symbol, side, qty, fillPrice)
-			}
-
 			// Lookup fee for this order (best-effort; default 0 if not found)
 			var fee float64
 			switch v := msg.(type) {
@@ -506,22 +902,62 @@ func main() {
 					"SELECT COALESCE(SUM(fee),0) FROM trades WHERE order_id = ?", v.ID)
 				_ = row.Scan(&fee)
 			}
+			if fee == 0 {
+				// Estimate from the connection's fee schedule when the actual fee is missing.
+				schedule := feeResolver.Default
+				maker := false
+				if v, ok := msg.(order.Order); ok {
+					schedule = feeResolver.Resolve(ctx, v.UserID, v.ConnectionID)
+					maker = order.IsMakerOrder(v)
+				}
+				fee = schedule.Fee(qty*fillPrice, maker)
+			}
+			// Snapshot the position the fill applies to (the user's own, or its leg in hedge mode)
+			prev := stateMgr.PositionFor(userID, connID, symbol, posSide)
+
+			// Update in-memory + DB position; realized PnL comes from the state manager's average-cost book
+			_, pnl, _ := stateMgr.ApplySideFill(ctx, userID, connID, symbol, side, posSide, qty, fillPrice, fee)
+
+			// Get updated position for cleanup check
+			newPos := stateMgr.Position(symbol)
+
+			if closeQty := math.Min(math.Abs(prev.Qty), qty); closeQty > 0 {
+				log.Printf(i18n.Get("RealizedPnL"), pnl, symbol, side, closeQty, fillPrice)
+			} else {
+				log.Printf(i18n.Get("PositionOpened"), symbol, side, qty, fillPrice)
+			}
+
 			netPnL := pnl - fee
 
 			// Update risk metrics with net PnL
-			if err := riskMgr.UpdateMetrics(risk.TradeResult{
+			trade := risk.TradeResult{
 				Symbol: symbol,
 				Side:   side,
 				Size:   qty,
 				Price:  fillPrice,
 				PnL:    netPnL,
 				Fee:    fee,
-			}); err != nil {
+
+				Closed:     (prev.Qty > 0 && strings.EqualFold(side, "SELL")) || (prev.Qty < 0 && strings.EqualFold(side, "BUY")),
+				StrategyID: strategyID,
+				UserID:     userID,
+			}
+			if err := riskMgr.UpdateMetrics(trade); err != nil {
 				log.Printf(i18n.Get("RiskMetricsUpdateFailed"), err)
 			}
+			// Book the fill against its connection's budget in the manager that evaluates its signals.
+			if connID != "" {
+				if userID != "" && multiUserRisk != nil {
+					if err := multiUserRisk.RecordConnectionTradeForUser(userID, connID, trade); err != nil {
+						log.Printf("per-user risk manager init failed for user %s (fill): %v", userID, err)
+					}
+				} else {
+					riskMgr.RecordConnectionTrade(connID, trade)
+				}
+			}
 
 			// Handle balance updates based on trade side (per-user when possible)
-			orderValue := qty * fillPrice
+			orderValue := money.FromFloat(qty).Mul(money.FromFloat(fillPrice)).Float64()
 			balTarget := balanceMgr
 			if userID != "" && userBalanceMgr != nil {
 				if userBalMgr, err := userBalanceMgr.GetOrCreate(userID); err == nil {
@@ -549,9 +985,28 @@ func main() {
 	}()
 
 	// Strategies
-	priceStream, unsubscribe := bus.Subscribe(events.EventPriceTick, 100)
+	priceStream, unsubscribe := bus.SubscribeNamed(events.EventPriceTick, "strategy-engine", 0)
 	defer unsubscribe()
-	stratEngine := strategy.NewEngine(bus, database.DB, strategy.Context{Indicators: indEngine})
+	var obBook *indicators.BookStore
+	if cfg.OrderBookFeatures {
+		obBook = indicators.NewBookStore(cfg.OrderBookDepthLevels)
+	}
+	stratEngine := strategy.NewEngine(bus, database.DB, strategy.Context{Indicators: indEngine, Book: obBook})
+	stratEngine.SetDefaultInterval(cfg.KlineInterval)
+	if err := stratEngine.SetDefaultPriceSource(cfg.PriceSource); err != nil {
+		log.Fatalf("Invalid PRICE_SOURCE: %v", err)
+	}
+	// Strategies only run on symbols their owner is allowed (users.allowed_symbols, empty = any).
+	stratEngine.SetSymbolAllowlist(func(userID, symbol string) (bool, error) {
+		if userID == "" {
+			return true, nil
+		}
+		limits, err := database.Queries().GetUserLimits(context.Background(), userID)
+		if err != nil {
+			return false, err
+		}
+		return limits.AllowsSymbol(symbol), nil
+	})
 
 	// Load strategies from YAML config and sync to DB
 	stratConfigs, err := strategy.LoadConfig("strategies.yaml")
@@ -584,6 +1039,35 @@ func main() {
 			log.Printf(i18n.Get("PythonWorkerEnabled"), cfg.PythonWorkerAddr)
 		}
 	}
+
+	// Startup import: seed positions/open orders from the exchange before any
+	// strategy can signal, so an account that is already in a position is not
+	// traded as if flat.
+	if cfg.ReconcileOnStartup && !cfg.DryRun {
+		var accounts []reconciliation.StartupAccount
+		if exchGateway != nil {
+			accounts = append(accounts, reconciliation.StartupAccount{Gateway: exchGateway, QuoteAsset: cfg.ReportingAsset})
+		}
+		if gatewayMgr != nil {
+			conns, err := database.ListActiveConnectionsByType(ctx, "binance-spot", "binance-usdtfut", "binance-coinfut")
+			if err != nil {
+				log.Printf("⚠️ Startup import: list connections: %v", err)
+			}
+			for _, conn := range conns {
+				gw, err := gatewayMgr.GetOrCreate(ctx, conn.UserID, conn.ID)
+				if err != nil {
+					log.Printf("⚠️ Startup import: gateway for connection %s failed: %v", conn.ID, err)
+					continue
+				}
+				accounts = append(accounts, reconciliation.StartupAccount{UserID: conn.UserID, ConnectionID: conn.ID, Gateway: gw, QuoteAsset: cfg.ReportingAsset})
+			}
+		}
+		report, err := reconciliation.ImportOnStartup(ctx, stateMgr, database, accounts)
+		if err != nil {
+			log.Printf("⚠️ Startup import incomplete: %v", err)
+		}
+		log.Printf("✓ Startup import: %d position(s), %d open order(s) from %d account(s)", report.Positions, report.Orders, len(accounts))
+	}
 	stratEngine.Start(ctx, priceStream)
 	defer func() {
 		if pyClient != nil {
@@ -591,7 +1075,104 @@ func main() {
 		}
 	}()
 
-	sigStream, unsubSig := bus.Subscribe(events.EventStrategySignal, 100)
+	// Market data (mock first, real later)
+	binanceClient := binance.NewClient(cfg.BinanceAPIKey, cfg.BinanceAPISecret, false)
+	reconnectCfg := marketbinance.DefaultReconnectConfig()
+	reconnectCfg.MaxRetries = cfg.MarketWSMaxRetries
+	streamClient := marketbinance.NewStreamClientWithConfig(false, reconnectCfg)
+	if cfg.UseMockFeed {
+		mock := market.MockFeed{
+			Bus:        bus,
+			Symbols:    cfg.BinanceSymbols,
+			StartPrice: 100,
+			Step:       0.8,
+			Interval:   time.Second,
+		}
+		if cfg.FeedDynamicSymbols {
+			stratEngine.SetSymbolSubscriber(&mock)
+		}
+		mock.Start(ctx)
+		log.Println(i18n.Get("MockFeedStarted"))
+	} else {
+		feed := market.Feed{
+			Client:       binanceClient,
+			Stream:       streamClient,
+			Bus:          bus,
+			Symbols:      cfg.BinanceSymbols,
+			Interval:     cfg.KlineInterval,
+			Intervals:    stratEngine.Intervals(),
+			PriceSources: stratEngine.PriceSources(),
+			RESTFallback: cfg.MarketRESTFallback,
+			OnStreamLost: func(symbol, interval string) {
+				sysMetrics.IncrementStreamFailures()
+			},
+		}
+		if cfg.FeedDynamicSymbols {
+			stratEngine.SetSymbolSubscriber(&feed)
+		}
+		stratEngine.SetPriceSourceSubscriber(&feed)
+		feed.Start(ctx)
+		log.Println(i18n.Get("BinanceFeedStarted"))
+	}
+	// Best bid/ask from book tickers, for maker-first routing, post-only
+	// reprices, the net-edge filter and price cache fields derived from quotes.
+	var bookStore *market.BookStore
+	quoteField := false
+	switch market.PriceField(cfg.PriceCacheField) {
+	case market.PriceFieldBid, market.PriceFieldAsk, market.PriceFieldMid:
+		quoteField = true
+	}
+	if (cfg.MakerFirstRouting || cfg.NetEdgeMinMultiple > 0 || cfg.PostOnlyRetries > 0 || quoteField) && !cfg.UseMockFeed {
+		bookStore = market.NewBookStore()
+		bookStore.OnQuote = priceCache.SetQuote
+		market.StreamBookTickers(ctx, streamClient, cfg.BinanceSymbols, bookStore)
+		exec.SetBookSource(bookStore)
+	}
+	if cfg.MakerFirstRouting {
+		if cfg.UseMockFeed {
+			log.Println("⚠️ Maker-first routing needs the live book ticker feed; disabled with USE_MOCK_FEED")
+		} else {
+			exec.SetMakerRouter(order.NewMakerRouter(bookStore, order.MakerFirstConfig{
+				Timeout:     time.Duration(cfg.MakerFirstTimeoutSec) * time.Second,
+				MaxReprices: cfg.MakerFirstMaxReprices,
+			}))
+			log.Printf("✓ Maker-first routing enabled (timeout %ds, %d reprices)", cfg.MakerFirstTimeoutSec, cfg.MakerFirstMaxReprices)
+		}
+	}
+	if obBook != nil {
+		if cfg.UseMockFeed {
+			log.Println("⚠️ Order-book features need the live depth feed; disabled with USE_MOCK_FEED")
+		} else {
+			market.StreamDepth(ctx, streamClient, cfg.BinanceSymbols, cfg.OrderBookDepthLevels, obBook)
+			log.Printf("✓ Order-book features enabled (top %d levels)", cfg.OrderBookDepthLevels)
+		}
+	}
+
+	// Signals are only priced from fresh data; the live feed can refresh over REST.
+	priceGuard := &market.StalenessGuard{
+		Prices: priceCache,
+		MaxAge: time.Duration(cfg.PriceMaxAgeSec) * time.Second,
+	}
+	if !cfg.UseMockFeed {
+		klineClients := map[string]*binance.Client{
+			string(exchange.MarketSpot):    binanceClient,
+			string(exchange.MarketUSDTFut): marketbinance.NewUSDTFuturesClient(false),
+			string(exchange.MarketCoinFut): marketbinance.NewCoinFuturesClient(false),
+		}
+		priceGuard.Refresh = func(ctx context.Context, mkt, symbol string) (float64, error) {
+			client, ok := klineClients[mkt]
+			if !ok {
+				client = binanceClient
+			}
+			klines, err := client.GetKlines(symbol, "1m", 1, 0, 0)
+			if err != nil || len(klines) == 0 {
+				return 0, fmt.Errorf("rest klines %s %s: %v", mkt, symbol, err)
+			}
+			return klines[len(klines)-1].Close, nil
+		}
+	}
+
+	sigStream, unsubSig := bus.SubscribeNamed(events.EventStrategySignal, "signal-processor", 0)
 	defer unsubSig()
 	go func() {
 		for msg := range sigStream {
@@ -600,7 +1181,7 @@ func main() {
 				defer func() {
 					if r := recover(); r != nil {
 						log.Printf(i18n.Get("SignalProcessingPanic"), r)
-						bus.Publish(events.EventRiskAlert, fmt.Sprintf("Signal processing panic: %v", r))
+						bus.Publish(events.EventRiskAlert, events.Alert{Type: "signal_panic", Message: fmt.Sprintf("Signal processing panic: %v", r)})
 					}
 				}()
 
@@ -614,13 +1195,17 @@ func main() {
 					stratUserID     sql.NullString
 					stratConnID     sql.NullString
 					stratExchangeTy sql.NullString
+					ladder          db.StrategyLadder
 				)
 				if err := database.DB.QueryRowContext(ctx, `
-					SELECT si.user_id, si.connection_id, c.exchange_type
+					SELECT si.user_id, si.connection_id, c.exchange_type,
+					       COALESCE(si.ladder_tranches, 0), COALESCE(si.ladder_spacing_pct, 0), COALESCE(si.ladder_interval_sec, 0),
+					       COALESCE(si.ladder_distribution, 'equal'), COALESCE(si.ladder_apply_to, 'entry')
 					FROM strategy_instances si
 					LEFT JOIN connections c ON si.connection_id = c.id
 					WHERE si.id = ?
-				`, sig.StrategyID).Scan(&stratUserID, &stratConnID, &stratExchangeTy); err != nil && err != sql.ErrNoRows {
+				`, sig.StrategyID).Scan(&stratUserID, &stratConnID, &stratExchangeTy,
+					&ladder.Tranches, &ladder.SpacingPct, &ladder.IntervalSec, &ladder.Distribution, &ladder.ApplyTo); err != nil && err != sql.ErrNoRows {
 					log.Printf("strategy owner lookup failed for %s: %v", sig.StrategyID, err)
 				}
 				userID := ""
@@ -631,10 +1216,42 @@ func main() {
 				if stratConnID.Valid {
 					connectionID = stratConnID.String
 				}
+				orderMarket := marketFromVenue(venue)
+				if stratExchangeTy.Valid {
+					orderMarket = marketFromVenue(stratExchangeTy.String)
+				}
 
 				// Gather context for risk decision
-				price := priceCache.get(sig.Symbol)
-				pos := stateMgr.Position(sig.Symbol)
+				price, err := priceGuard.Price(ctx, orderMarket, sig.Symbol)
+				if err != nil {
+					log.Printf("⚠️ Skipping signal from strategy %s: %v", sig.StrategyID, err)
+					bus.Publish(events.EventRiskAlert, events.Alert{Type: "signal_skipped", UserID: userID, Symbol: sig.Symbol, Key: sig.StrategyID,
+						Message: fmt.Sprintf("strategy %s signal skipped: %v", sig.StrategyID, err)})
+					return
+				}
+				if orderMarket != "" {
+					if err := exchange.ValidateMarketSymbol(exchange.MarketType(orderMarket), sig.Symbol); err != nil {
+						log.Printf("⚠️ Dropping signal from strategy %s: %v", sig.StrategyID, err)
+						bus.Publish(events.EventRiskAlert, events.Alert{Type: "signal_rejected", UserID: userID, Symbol: sig.Symbol, Key: sig.StrategyID,
+							Message: fmt.Sprintf("strategy %s signal rejected: %v", sig.StrategyID, err)})
+						return
+					}
+				}
+				orderType, limitPrice, tif, err := signalOrderType(sig, price, orderMarket)
+				if err != nil {
+					log.Printf("⚠️ Dropping signal from strategy %s: %v", sig.StrategyID, err)
+					return
+				}
+				// Exits, halts, min hold and the entry caps are judged against the
+				// signal owner's own position, not the cross-user net.
+				pos := stateMgr.UserPosition(userID, sig.Symbol)
+				// Min hold runs from the strategy's own entry, which other users' and strategies' fills don't reset.
+				var openedAt time.Time
+				if sp, err := database.GetStrategyPosition(ctx, sig.StrategyID); err != nil {
+					log.Printf("strategy position lookup failed for %s: %v", sig.StrategyID, err)
+				} else if sp != nil && sp.Symbol == sig.Symbol && sp.Qty != 0 {
+					openedAt = sp.OpenedAt
+				}
 				position := risk.Position{
 					Symbol:        pos.Symbol,
 					Side:          sideFromQty(pos.Qty),
@@ -642,7 +1259,13 @@ func main() {
 					CurrentPrice:  price,
 					Quantity:      pos.Qty,
 					Value:         pos.Qty * price,
-					UnrealizedPnL: (price - pos.AvgPrice) * pos.Qty,
+					UnrealizedPnL: state.UnrealizedPnL(pos.Qty, pos.AvgPrice, price),
+					OpenedAt:      openedAt,
+				}
+				// Don't compound into a position whose last entry slipped past MaxSlippage
+				if position.Side != "" && position.Side == sideFromAction(sig.Action) && slippageGuard.Blocked(sig.StrategyID, sig.Symbol, sig.Action) {
+					log.Printf("⚠️ Skipping %s %s for strategy %s: cooling down after over-slippage fill", sig.Action, sig.Symbol, sig.StrategyID)
+					return
 				}
 				// Build account snapshot for risk evaluation (per-user when possible)
 				balSource := balanceMgr
@@ -655,19 +1278,49 @@ func main() {
 				}
 
 				balSnap := balSource.GetBalance()
-				totalExposure := expCache.get(func() float64 {
-					sum := 0.0
+				// Exposure and concurrent positions come from the user's stored
+				// positions for per-user signals, else from the global book.
+				var totalExposure float64
+				symbolExposure := make(map[string]float64)
+				var openPositions []string
+				if userID != "" {
+					userPositions, err := database.Queries().GetPositionsByUser(ctx, userID)
+					if err != nil {
+						// Never let the exposure and position caps fail open on a DB error.
+						log.Printf("load positions for user %s: %v - using in-memory positions", userID, err)
+						userPositions = stateMgr.UserPositions(userID)
+					}
+					for _, p := range userPositions {
+						notional := math.Abs(p.Qty * priceCache.Get(p.Symbol))
+						totalExposure += notional
+						symbolExposure[strings.ToUpper(p.Symbol)] += notional
+						if p.Qty != 0 {
+							openPositions = append(openPositions, p.Symbol)
+						}
+					}
+				} else {
+					totalExposure = expCache.get(func() float64 {
+						sum := 0.0
+						for _, p := range stateMgr.Positions() {
+							px := priceCache.Get(p.Symbol)
+							sum += math.Abs(p.Qty * px)
+						}
+						return sum
+					})
 					for _, p := range stateMgr.Positions() {
-						px := priceCache.get(p.Symbol)
-						sum += math.Abs(p.Qty * px)
+						symbolExposure[strings.ToUpper(p.Symbol)] += math.Abs(p.Qty * priceCache.Get(p.Symbol))
+						if p.Qty != 0 {
+							openPositions = append(openPositions, p.Symbol)
+						}
 					}
-					return sum
-				})
+				}
 				account := risk.Account{
 					Balance:          balSnap.Total,
 					AvailableBalance: balSnap.Available,
 					LockedBalance:    balSnap.Locked,
 					TotalExposure:    totalExposure,
+					SymbolExposure:   symbolExposure,
+					OpenPositions:    openPositions,
 				}
 
 				// I2: Single entry point for all risk checks (per-user when possible)
@@ -676,6 +1329,8 @@ func main() {
 					Action: sig.Action,
 					Size:   sig.Size,
 					Price:  price,
+					// Bound strategies are also held to their connection's budget.
+					ConnectionID: connectionID,
 				}
 
 				var decision risk.RiskDecision
@@ -692,13 +1347,31 @@ func main() {
 				}
 				if !decision.Allowed {
 					log.Printf(i18n.Get("RiskRejected"), decision.Reason)
-					bus.Publish(events.EventRiskAlert, decision.Reason)
+					bus.Publish(events.EventRiskAlert, events.Alert{Type: "risk_rejected", UserID: userID, Symbol: sig.Symbol, Message: decision.Reason})
 					return
 				}
 				if decision.Warning != "" {
 					log.Printf(i18n.Get("RiskWarning"), decision.Warning)
 				}
 
+				// Reject entries whose edge to take profit would be eaten by fees and spread.
+				if cfg.NetEdgeMinMultiple > 0 && decision.TakeProfit > 0 && !risk.IsExit(sig.Action, position) {
+					spreadBps := cfg.NetEdgeDefaultSpreadBps
+					if bookStore != nil {
+						if bps, ok := bookStore.SpreadBps(sig.Symbol, time.Minute); ok {
+							spreadBps = bps
+						}
+					}
+					fees := feeResolver.Resolve(ctx, userID, connectionID)
+					maker := order.IsMakerOrder(order.Order{Type: orderType, TimeInForce: tif})
+					edge := risk.NetEdge(sig.Symbol, sig.Action, price, decision.TakeProfit, fees.Rate(maker), fees.Rate(false), spreadBps, cfg.NetEdgeMinMultiple)
+					if !edge.Allowed() {
+						log.Printf("⚠️ Dropping signal from strategy %s: %s", sig.StrategyID, edge)
+						bus.Publish(events.EventRiskAlert, events.Alert{Type: "net_edge_rejected", UserID: userID, Symbol: sig.Symbol, Message: edge.String()})
+						return
+					}
+				}
+
 				// Determine final order size
 				size := decision.AdjustedSize
 				if size == 0 {
@@ -709,7 +1382,7 @@ func main() {
 				finalOrderValue := size * price
 				if err := balSource.Lock(finalOrderValue); err != nil {
 					log.Printf(i18n.Get("BalanceLockFailed"), err)
-					bus.Publish(events.EventRiskAlert, fmt.Sprintf("Insufficient balance: %v", err))
+					bus.Publish(events.EventRiskAlert, events.Alert{Type: "insufficient_balance", UserID: userID, Symbol: sig.Symbol, Message: fmt.Sprintf("Insufficient balance: %v", err)})
 					return
 				}
 
@@ -728,17 +1401,16 @@ func main() {
 				})
 
 				// Create order with locked balance
-				orderMarket := marketFromVenue(venue)
-				if stratExchangeTy.Valid {
-					orderMarket = marketFromVenue(stratExchangeTy.String)
-				}
-
 				o := order.Order{
-					ID:                 uuid.NewString(),
+					ID:                 ids.New(),
 					StrategyInstanceID: sig.StrategyID,
 					Symbol:             sig.Symbol,
 					Side:               sig.Action,
-					Type:               "MARKET",
+					Type:               orderType,
+					Price:              limitPrice,
+					RefPrice:           price,
+					Routing:            sig.Routing,
+					TimeInForce:        tif,
 					Qty:                size,
 					Status:             "NEW",
 					CreatedAt:          time.Now(),
@@ -748,14 +1420,59 @@ func main() {
 					UserID:             userID,
 					ConnectionID:       connectionID,
 				}
-				orderQueue.Enqueue(o)
+				// Risk and the balance lock above cover the whole signal; a ladder
+				// only splits it into tranches by price level and/or time.
+				tranches := []order.LadderTranche{{Order: o}}
+				if lc := order.LadderFromDB(ladder); lc.Applies(risk.IsExit(sig.Action, position)) {
+					tranches = lc.Ladder(o, price)
+					log.Printf("🪜 Strategy %s %s %s %.8f split into %d tranches", sig.StrategyID, sig.Action, sig.Symbol, size, len(tranches))
+				}
+				for _, t := range tranches {
+					// IOC/FOK limits cap their own slippage; only MARKET fills are tracked.
+					if t.Order.Type == string(exchange.OrderTypeMarket) {
+						slippageGuard.Track(t.Order.ID, sig.StrategyID, sig.Symbol, sig.Action, price)
+					}
+				}
+				ladders.Schedule(ctx, tranches)
 			}() // End of panic recovery wrapper
 		}
 	}()
 
-	go orderQueue.Drain(ctx, func(o order.Order) {
+	drainHandler := func(o order.Order) {
+		// Orders queued before a maintenance window opened or their symbol was
+		// halted must not enter new positions.
+		if w, active := maintenance.Active(); active && !o.ReduceOnly {
+			pos := stateMgr.UserPosition(o.UserID, o.Symbol)
+			if !risk.IsExit(o.Side, risk.Position{Side: sideFromQty(pos.Qty)}) {
+				reason := fmt.Sprintf("order %s %s %s blocked: maintenance window active until %s", o.ID, o.Side, o.Symbol, w.End.UTC().Format(time.RFC3339))
+				log.Printf("⚠️ %s", reason)
+				bus.Publish(events.EventOrderRejected, reason)
+				return
+			}
+		}
+		if halt, halted := symbolHalts.Halted(o.Symbol); halted && !o.ReduceOnly {
+			pos := stateMgr.UserPosition(o.UserID, o.Symbol)
+			if !risk.IsExit(o.Side, risk.Position{Side: sideFromQty(pos.Qty)}) {
+				reason := fmt.Sprintf("order %s %s blocked: %s", o.ID, o.Side, halt.BlockReason())
+				log.Printf("⚠️ %s", reason)
+				bus.Publish(events.EventOrderRejected, reason)
+				return
+			}
+		}
+		if sharedQueue {
+			// Shared orders are acked when the handler returns, so run them to
+			// completion; the queue leases one order per worker.
+			_ = asyncExec.Execute(ctx, o)
+			return
+		}
 		asyncExec.ExecuteAsync(ctx, o) // V2 P0-B: Async Execution
-	})
+	}
+	if drainGate != nil {
+		drainHandler = drainGate.Wrap(drainHandler)
+		// The drain hands orders to async workers; a pause also waits for those.
+		drainGate.SetInFlightWaiter(asyncExec.WaitIdle)
+	}
+	go orderQueue.Drain(ctx, drainHandler)
 
 	// Monitor async execution results (V2 P0-B)
 	go func() {
@@ -777,6 +1494,8 @@ func main() {
 			APISecret: cfg.BinanceAPISecret,
 			Testnet:   cfg.BinanceTestnet,
 		}), database, bus, cfg.BinanceTestnet)
+		spotStream.SetFillBatchWindow(time.Duration(cfg.UserStreamFillBatchMs) * time.Millisecond)
+		spotStream.Fills = fillLedger
 		spotStream.Start(ctx)
 	}
 	// Start Futures User Data Stream (USDT)
@@ -786,6 +1505,8 @@ func main() {
 			APISecret: cfg.BinanceUSDTSecret,
 			Testnet:   cfg.BinanceTestnet,
 		}), database, bus, cfg.BinanceTestnet, false)
+		usdtStream.SetFillBatchWindow(time.Duration(cfg.UserStreamFillBatchMs) * time.Millisecond)
+		usdtStream.Fills = fillLedger
 		usdtStream.Start(ctx)
 	}
 	// Start Futures User Data Stream (COIN)
@@ -795,8 +1516,39 @@ func main() {
 			APISecret: cfg.BinanceCoinSecret,
 			Testnet:   cfg.BinanceTestnet,
 		}), database, bus, cfg.BinanceTestnet, true)
+		coinStream.ReportingAsset = cfg.ReportingAsset
+		coinStream.SetFillBatchWindow(time.Duration(cfg.UserStreamFillBatchMs) * time.Millisecond)
+		coinStream.Fills = fillLedger
 		coinStream.Start(ctx)
 	}
+	// Multi-user: one user data stream per active live connection.
+	if gatewayMgr != nil && cfg.ConnectionUserStreams && !cfg.DryRun {
+		connStreams := order.NewUserStreamManager(func(ctx context.Context) ([]order.StreamConnection, error) {
+			conns, err := database.ListActiveConnectionsByType(ctx, "binance-spot", "binance-usdtfut", "binance-coinfut")
+			if err != nil {
+				return nil, err
+			}
+			var out []order.StreamConnection
+			for _, conn := range conns {
+				if conn.Paper {
+					continue
+				}
+				gw, err := gatewayMgr.GetOrCreate(ctx, conn.UserID, conn.ID)
+				if err != nil {
+					log.Printf("⚠️ User streams: gateway for connection %s failed: %v", conn.ID, err)
+					continue
+				}
+				out = append(out, order.StreamConnection{UserID: conn.UserID, ConnectionID: conn.ID, ExchangeType: conn.ExchangeType, Gateway: gw})
+			}
+			return out, nil
+		}, cfg.MaxConnectionUserStreams)
+		connStreams.DB, connStreams.Bus = database, bus
+		connStreams.Testnet = cfg.TestnetOnly()
+		connStreams.FillBatchWindow = time.Duration(cfg.UserStreamFillBatchMs) * time.Millisecond
+		connStreams.Fills = fillLedger
+		connStreams.ReportingAsset = cfg.ReportingAsset
+		connStreams.Start(ctx)
+	}
 
 	// Create Engine Service (Phase 1 Architecture)
 	engService := engine.NewImpl(engine.Config{
@@ -804,6 +1556,7 @@ func main() {
 		RiskMgr:     riskMgr,
 		BalanceMgr:  balanceMgr,
 		OrderQueue:  orderQueue,
+		Ladders:     ladders,
 		Bus:         bus,
 		DB:          database,
 		Meta: engine.SystemStatus{
@@ -822,6 +1575,16 @@ func main() {
 		MultiUserRiskMgr: multiUserRisk,
 	})
 	log.Println(i18n.Get("EngineServiceInit"))
+	// Drawdown stop: flatten, then stop, a strategy past its max_drawdown_pct.
+	exec.SetStrategyHaltFn(func(ctx context.Context, strategyID, reason string) error {
+		if err := engService.PanicSellStrategy(ctx, strategyID, ""); err != nil && !errors.Is(err, engine.ErrNoPosition) {
+			return fmt.Errorf("flatten strategy %s: %w", strategyID, err)
+		}
+		if err := engService.StopStrategy(ctx, strategyID); err != nil {
+			return fmt.Errorf("stop strategy %s: %w", strategyID, err)
+		}
+		return nil
+	})
 
 	// API
 	server := api.NewServer(
@@ -841,6 +1604,61 @@ func main() {
 		keyMgr,
 		userBalanceMgr,
 	)
+	if gatewayMgr != nil {
+		server.Gateways = gatewayMgr
+	}
+	server.Prices = priceCache
+	server.Risk = multiUserRisk
+	server.StopLoss = stopLossMgr
+	server.Maintenance = maintenance
+	server.Halts = symbolHalts
+	server.Quarantine = fillQuarantine
+	server.AdminEmails = cfg.AdminEmails
+	if cfg.FreezeStrategies {
+		server.Freeze.Freeze("FREEZE_NEW_STRATEGIES set at startup")
+		log.Println("🧊 New strategies frozen at startup (FREEZE_NEW_STRATEGIES)")
+	}
+	server.SimConfig = simCfg
+	server.Limits = db.UserLimits{
+		MaxStrategies:  cfg.MaxStrategiesPerUser,
+		MaxConnections: cfg.MaxConnectionsPerUser,
+	}
+	server.Tokens = api.TokenConfig{
+		TTL:      time.Duration(cfg.JWTAccessTTLMinutes) * time.Minute,
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+		Leeway:   time.Duration(cfg.JWTClockSkewSec) * time.Second,
+	}
+	server.Passwords = api.PasswordConfig{
+		MinLength:         cfg.PasswordMinLength,
+		RequireUpper:      cfg.PasswordRequireUpper,
+		RequireLower:      cfg.PasswordRequireLower,
+		RequireDigit:      cfg.PasswordRequireDigit,
+		RequireSymbol:     cfg.PasswordRequireSymbol,
+		Algorithm:         cfg.PasswordHash,
+		BcryptCost:        cfg.BcryptCost,
+		Argon2Memory:      uint32(max(cfg.Argon2MemoryKB, 0)),
+		Argon2Iterations:  uint32(max(cfg.Argon2Iterations, 0)),
+		Argon2Parallelism: uint8(min(max(cfg.Argon2Parallelism, 0), 255)),
+	}
+	if _, err := server.Passwords.Hash("startup-check"); err != nil {
+		log.Fatalf("Invalid password hash settings: %v", err)
+	}
+	server.Security = api.HTTPSecurity{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedMethods: cfg.CORSAllowedMethods,
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+		HSTS:           cfg.EnableHSTS,
+	}
+	server.RequestLimits = api.RequestLimits{
+		MaxBodyBytes:   cfg.APIMaxBodyBytes,
+		HandlerTimeout: time.Duration(cfg.APIHandlerTimeoutSec) * time.Second,
+	}
+	if !cfg.DryRun {
+		server.ProbeCapabilities = func(ctx context.Context, exchangeType, apiKey, apiSecret string) ([]string, error) {
+			return gateway.ProbeCapabilities(ctx, exchangeType, apiKey, apiSecret, cfg.BinanceTestnet)
+		}
+	}
 	go func() {
 		if err := server.Start(":" + cfg.Port); err != nil {
 			log.Fatalf(i18n.Get("APIServerError"), err)
@@ -851,6 +1669,89 @@ func main() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
 	log.Println(i18n.Get("ShuttingDown"))
+
+	// Let in-flight API requests finish and close websocket streams before exiting.
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ API server shutdown: %v", err)
+	} else {
+		log.Println("✓ API server drained")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("⚠️ Flushing traces: %v", err)
+	}
+}
+
+// apiShutdownTimeout bounds how long shutdown waits for in-flight API requests.
+const apiShutdownTimeout = 15 * time.Second
+
+// fundingPayments returns a fetcher of the futures gateway's recent funding
+// payments, with IDs prefixed by idPrefix (the venue for the global gateway,
+// the connection ID otherwise). COIN-M payments settle in the base coin and
+// are converted into reportingAsset at the coin's perpetual mark price. It is
+// nil for gateways without funding.
+func fundingPayments(ctx context.Context, gw exchange.Gateway, idPrefix, reportingAsset string) func() ([]equity.FundingPayment, error) {
+	switch fut := gw.(type) {
+	case *exfutusdt.Client:
+		return func() ([]equity.FundingPayment, error) {
+			income, err := fut.GetIncome(ctx, "", db.IncomeFundingFee, 1000)
+			if err != nil {
+				return nil, err
+			}
+			payments := make([]equity.FundingPayment, 0, len(income))
+			for _, in := range income {
+				amount, err := strconv.ParseFloat(in.Income, 64)
+				if err != nil {
+					log.Printf("⚠️ Skipping funding record %d: invalid amount %q", in.TranID, in.Income)
+					continue
+				}
+				payments = append(payments, equity.FundingPayment{
+					ID:     fmt.Sprintf("%s:%d", idPrefix, in.TranID),
+					Symbol: in.Symbol,
+					Amount: amount,
+					Asset:  in.Asset,
+					Time:   time.UnixMilli(in.Time).UTC(),
+				})
+			}
+			return payments, nil
+		}
+	case *exfutcoin.Client:
+		return func() ([]equity.FundingPayment, error) {
+			income, err := fut.GetIncome(ctx, "", db.IncomeFundingFee, 1000)
+			if err != nil {
+				return nil, err
+			}
+			marks := make(map[string]float64)
+			markPrice := func(asset string) float64 {
+				if px, ok := marks[asset]; ok {
+					return px
+				}
+				px, err := fut.GetMarkPrice(ctx, asset+"USD_PERP")
+				if err != nil {
+					log.Printf("⚠️ Funding sync: mark price for %s: %v", asset, err)
+				}
+				marks[asset] = px
+				return px
+			}
+			reported, err := exfutcoin.ConvertIncome(income, reportingAsset, markPrice)
+			if err != nil {
+				return nil, err
+			}
+			payments := make([]equity.FundingPayment, 0, len(reported))
+			for _, in := range reported {
+				payments = append(payments, equity.FundingPayment{
+					ID:     fmt.Sprintf("%s:%d", idPrefix, in.TranID),
+					Symbol: in.Symbol,
+					Amount: in.Reported,
+					Asset:  reportingAsset,
+					Time:   time.UnixMilli(in.Time).UTC(),
+				})
+			}
+			return payments, nil
+		}
+	}
+	return nil
 }
 
 func sideFromQty(qty float64) string {
@@ -873,6 +1774,79 @@ func sideFromAction(action string) string {
 	return ""
 }
 
+// startNotifications pushes risk alerts (and fills when enabled) to the
+// configured webhook, Discord and Telegram channels.
+func startNotifications(ctx context.Context, bus *events.Bus, cfg *config.Config) {
+	var notifiers []monitor.Notifier
+	if cfg.NotifyWebhookURL != "" {
+		notifiers = append(notifiers, &monitor.WebhookNotifier{URL: cfg.NotifyWebhookURL})
+	}
+	if cfg.NotifyDiscordWebhookURL != "" {
+		notifiers = append(notifiers, &monitor.DiscordNotifier{URL: cfg.NotifyDiscordWebhookURL})
+	}
+	if cfg.NotifyTelegramBotToken != "" && cfg.NotifyTelegramChatID != "" {
+		notifiers = append(notifiers, &monitor.TelegramNotifier{BotToken: cfg.NotifyTelegramBotToken, ChatID: cfg.NotifyTelegramChatID})
+	}
+	var topics []events.Event
+	if cfg.NotifyRiskAlerts {
+		topics = append(topics, events.EventRiskAlert)
+	}
+	if cfg.NotifyFills {
+		topics = append(topics, events.EventOrderFilled)
+	}
+	if len(notifiers) == 0 || len(topics) == 0 {
+		return
+	}
+	monitor.NewDispatcher(bus, monitor.DispatcherConfig{Events: topics, RatePerMinute: cfg.NotifyRatePerMinute}, notifiers...).Start(ctx)
+	log.Printf("✓ Notifications enabled (%d channels, events %v, %d/min per event)", len(notifiers), topics, cfg.NotifyRatePerMinute)
+}
+
+// openDatabase opens and migrates the DB, retrying per DB_STARTUP_*. If that
+// fails and DB_DEGRADED_START is set, it serves a degraded API (503 on every
+// route, /ready reporting why) on the API port and keeps retrying until the
+// DB comes back, then releases the port for the full server.
+func openDatabase(ctx context.Context, cfg *config.Config, dbPath string) (*db.Database, error) {
+	policy := db.RetryPolicy{
+		Attempts:       cfg.DBStartupRetries,
+		InitialBackoff: time.Duration(cfg.DBStartupBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.DBStartupMaxBackoffMs) * time.Millisecond,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			log.Printf("⚠️ Database unavailable (attempt %d): %v; retrying in %v", attempt, err, wait)
+		},
+	}
+	database, err := db.OpenWithRetry(ctx, dbPath, policy)
+	if err == nil || !cfg.DBDegradedStart {
+		return database, err
+	}
+
+	readiness := api.NewReadiness()
+	readiness.MarkUnavailable(err)
+	degraded := &http.Server{Addr: ":" + cfg.Port, Handler: api.NewDegradedHandler(readiness)}
+	go func() {
+		if err := degraded.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("⚠️ Degraded API server error: %v", err)
+		}
+	}()
+	log.Printf("⚠️ Database unavailable, serving degraded API on :%s until it recovers", cfg.Port)
+
+	policy.Attempts = 0 // until the DB is back or ctx ends
+	policy.OnRetry = func(attempt int, err error, wait time.Duration) {
+		readiness.MarkUnavailable(err)
+		log.Printf("⚠️ Database still unavailable (attempt %d): %v; retrying in %v", attempt, err, wait)
+	}
+	database, err = db.OpenWithRetry(ctx, dbPath, policy)
+	if err == nil {
+		readiness.MarkReady()
+		log.Println("✓ Database available, leaving degraded mode")
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if shutdownErr := degraded.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Printf("⚠️ Degraded API shutdown: %v", shutdownErr)
+	}
+	return database, err
+}
+
 func marketFromVenue(venue string) string {
 	switch venue {
 	case "binance-spot":
@@ -886,6 +1860,27 @@ func marketFromVenue(venue string) string {
 	}
 }
 
+// signalOrderType maps a strategy signal to an order type, limit price and time
+// in force. Signals without a TIF stay MARKET; IOC/FOK signals become LIMIT
+// orders at the signal's limit price, or refPrice when none was given.
+func signalOrderType(sig strategy.Signal, refPrice float64, market string) (string, float64, string, error) {
+	tif := exchange.TimeInForce(strings.ToUpper(strings.TrimSpace(sig.TimeInForce)))
+	if tif == "" {
+		return string(exchange.OrderTypeMarket), 0, "", nil
+	}
+	if !exchange.OrderTypeLimit.AcceptsTimeInForce(tif, exchange.MarketType(market)) {
+		return "", 0, "", fmt.Errorf("time in force %q not supported for LIMIT orders on %q", sig.TimeInForce, market)
+	}
+	price := sig.LimitPrice
+	if price <= 0 {
+		price = refPrice
+	}
+	if price <= 0 {
+		return "", 0, "", fmt.Errorf("no price available for %s LIMIT order on %s", tif, sig.Symbol)
+	}
+	return string(exchange.OrderTypeLimit), price, string(tif), nil
+}
+
 // oppositeSide returns SELL for BUY and BUY for SELL.
 func oppositeSide(side string) string {
 	switch strings.ToUpper(side) {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -8,25 +9,72 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// Deployment environments. Only EnvProd may trade live on mainnet.
+const (
+	EnvDev     = "dev"
+	EnvStaging = "staging"
+	EnvProd    = "prod"
+)
+
 // Config holds environment-driven settings for the trading core.
 type Config struct {
 	Port string
 
+	// Deployment environment (dev, staging, prod). Outside prod, orders go to
+	// testnet when BINANCE_TESTNET=true and are forced to dry-run otherwise.
+	Environment  string
+	ForcedDryRun bool // set when the environment guardrail turned dry-run on
+
 	// Binance
 	BinanceTestnet       bool
 	BinanceAPIKey        string
 	BinanceAPISecret     string
 	BinanceSymbols       []string
+	KlineInterval        string // default kline interval for the market feed and strategies
+	PriceSource          string // default strategy price source: kline, trade, aggTrade or bookMid
+	PriceCacheField      string // price cache field risk checks and stops read: last, mid, bid, ask or mark
+	MarketWSMaxRetries   int    // reconnect attempts before a stream gives up (0 = unlimited)
+	MarketRESTFallback   bool   // poll REST for streams that gave up
+	FeedDynamicSymbols   bool   // also stream symbols of strategies started later
+	PriceMaxAgeSec       int    // signals priced older than this are refreshed or skipped (0 = off)
 	UseMockFeed          bool
 	EnableBinanceTrading bool
+
+	// Tick recording (live klines to rotating JSONL files for backtests)
+	RecordTicks         bool
+	RecordDir           string   // per-symbol files + manifest.json
+	RecordSymbols       []string // empty = all feed symbols
+	RecordMaxFileMB     int      // rotate at this size
+	RecordRotateMinutes int      // rotate after this age
+
 	// Binance Futures (USDT)
 	EnableBinanceUSDTFutures bool
 	BinanceUSDTKey           string
 	BinanceUSDTSecret        string
+	BinanceUSDTUseWSOrders   bool // place orders over the websocket API (REST fallback)
+
+	// Dead-man's switch: keep Binance's auto-cancel countdown armed for the
+	// traded symbols so open orders are cancelled if we stop heartbeating.
+	DeadmanSwitchEnabled bool
+	DeadmanCountdownSecs int // exchange cancels orders this long after the last heartbeat
+	DeadmanRefreshSecs   int // heartbeat interval; keep well below the countdown
+
 	// Binance Futures (Coin-M)
 	EnableBinanceCoinFutures bool
 	BinanceCoinKey           string
 	BinanceCoinSecret        string
+	ReportingAsset           string // currency COIN-M fees/funding are converted into
+
+	// User data streams: fills of one order arriving within this many
+	// milliseconds are persisted as a single write (0 = every fill).
+	UserStreamFillBatchMs int
+	// Which path books fills reported by both the order response and the
+	// user stream: "first" (whichever arrives first) or "stream".
+	FillSource string
+	// Multi-user: run a user data stream per active live connection, at
+	// most MaxConnectionUserStreams at once (0 = unlimited).
+	ConnectionUserStreams    bool
+	MaxConnectionUserStreams int
 
 	// Python worker
 	EnablePythonWorker bool
@@ -40,26 +88,187 @@ type Config struct {
 	DryRunDBPath         string
 	DryRunEnableOrderWAL bool
 	DryRunOrderWALPath   string
-	DryRunFeeRate        float64 // decimal (e.g. 0.0004 = 4 bps)
-	DryRunSlippageBps    float64 // slippage applied on fills (bps)
-	DryRunGwLatencyMinMs int     // simulated gateway latency lower bound
-	DryRunGwLatencyMaxMs int     // simulated gateway latency upper bound
+	DryRunFeeRate        float64  // decimal (e.g. 0.0004 = 4 bps)
+	DryRunSlippageBps    float64  // slippage applied on fills (bps)
+	DryRunGwLatencyMinMs int      // simulated gateway latency lower bound
+	DryRunGwLatencyMaxMs int      // simulated gateway latency upper bound
+	DryRunRejectProb     float64  // chance (0..1) a dry-run order is rejected
+	DryRunRejectSymbols  []string // dry-run orders for these symbols are always rejected
+	DryRunSeed           int64    // seed for simulated randomness; 0 = time-seeded
+
+	// Reject dry-run orders that break the symbol's exchange-info filters
+	// (min notional, lot size) the way the live venue would.
+	DryRunValidateFilters bool
 
 	// Order persistence
-	EnableOrderWAL bool
-	OrderWALPath   string
+	EnableOrderWAL  bool
+	OrderWALPath    string
+	OrderWALBackend string // "file" (OrderWALPath) or "db" (survives ephemeral disks)
+
+	// Order queue shared across instances
+	OrderQueueBackend        string // "local" (in-process) or "shared" (order_jobs table, multi-instance)
+	SharedQueueVisibilitySec int    // Lease before an unacked shared order is redelivered
+	SharedQueueDSN           string // PostgreSQL DSN for a queue shared across hosts; "" = order_jobs in DBPath
 
 	// Database
 	DBPath string
 
+	// Startup: attempts and backoff for opening the DB, and whether to serve a
+	// degraded (503) API while retrying instead of exiting.
+	DBStartupRetries      int
+	DBStartupBackoffMs    int
+	DBStartupMaxBackoffMs int
+	DBDegradedStart       bool
+
+	// Executor DB writes: retries for transient lock errors, and where records
+	// of exchange-accepted orders that still fail are kept for reconciliation.
+	DBWriteRetries      int
+	DBWriteRetryBackoff int // milliseconds, doubled per retry
+	RecoveryLogPath     string
+
+	// Strategy position healer: replays each strategy's trades and rewrites
+	// strategy_positions rows that drifted beyond the tolerances (0 minutes = off).
+	StrategyReconMinutes  int
+	StrategyReconQtyTol   float64 // absolute quantity
+	StrategyReconPricePct float64 // average price, percent
+	StrategyReconAutoHeal bool
+
+	// Import positions and open orders already on the exchange (global account
+	// and every active connection) before strategies start.
+	ReconcileOnStartup bool
+
+	// Pause the order queue drain while a reconciliation pass corrects state,
+	// for at most ReconPauseMaxMs so trading is never held up for long.
+	ReconPauseDrain bool
+	ReconPauseMaxMs int
+
+	// Funding import for PnL attribution (USDT-M futures; 0 minutes = off).
+	FundingSyncMinutes int
+
 	// Execution toggle and balance source
 	ExecutionEnabled bool
 	BalanceSource    string // "auto" (default), "exchange", "fixed"
 
+	// Scheduled maintenance windows ("start/end[/every]", comma-separated);
+	// new entries are blocked while one is active.
+	MaintenanceWindows string
+
+	// Pause a strategy's new entries after this many consecutive losing
+	// trades until its next win or the next UTC day (0 = off).
+	MaxLossStreak int
+
+	// Event bus
+	EventBusBuffer int // default subscriber channel buffer
+	// Repeated risk alerts (same type/user/symbol) within this many seconds
+	// are collapsed into one alert with a count (0 = off).
+	AlertDedupWindowSeconds int
+
+	// Per-user resource caps (0 = unlimited; admins can override per user)
+	MaxStrategiesPerUser  int
+	MaxConnectionsPerUser int
+
+	// Futures leverage ceiling (0 = none; admins can override per user).
+	// LeverageCapMode is "clamp" (lower to the cap) or "reject".
+	MaxLeverage     int
+	LeverageCapMode string
+
+	// Track LONG and SHORT futures legs separately (account in hedge mode)
+	// instead of netting fills into one position per symbol.
+	HedgeMode bool
+
+	// Maker-first routing: streams book tickers for BINANCE_SYMBOLS and reprices
+	// unfilled post-only orders every MakerFirstTimeoutSec before crossing.
+	MakerFirstRouting     bool
+	MakerFirstTimeoutSec  int
+	MakerFirstMaxReprices int
+
+	// LIMIT_MAKER orders rejected for crossing the book are repriced one tick
+	// away and resubmitted up to PostOnlyRetries times (0 = fail immediately).
+	PostOnlyRetries int
+
+	// Order-book features for strategies (imbalance, microprice, spread) from
+	// the top OrderBookDepthLevels of the partial depth stream (5, 10 or 20).
+	OrderBookFeatures    bool
+	OrderBookDepthLevels int
+
+	// Net-edge filter: entries whose distance to take profit is not more than
+	// NetEdgeMinMultiple x (round-trip fees + spread) are rejected (0 = off).
+	// NetEdgeDefaultSpreadBps is assumed when no recent book ticker is known.
+	NetEdgeMinMultiple      float64
+	NetEdgeDefaultSpreadBps float64
+
+	// Liquidation guard: alert when a futures position's mark price is within
+	// LiquidationBufferPct of its liquidation price (0 = off), optionally
+	// closing LiquidationReduceFraction of it with a reduce-only market order.
+	// Isolated positions are first topped up with up to LiquidationMarginTopUpMax
+	// of margin (in the position's margin asset; 0 = never top up).
+	LiquidationBufferPct      float64
+	LiquidationAutoReduce     bool
+	LiquidationReduceFraction float64
+	LiquidationMarginTopUpMax float64
+	LiquidationCheckSeconds   int
+
+	// Fills priced more than MaxFillDeviationPct percent from the last known
+	// price are quarantined for review instead of booked (0 = off).
+	MaxFillDeviationPct float64
+
+	// Push notifications: risk alerts (and optionally fills) are sent to every
+	// configured channel, at most NotifyRatePerMinute per event type (0 = no cap).
+	NotifyWebhookURL        string
+	NotifyDiscordWebhookURL string
+	NotifyTelegramBotToken  string
+	NotifyTelegramChatID    string
+	NotifyRiskAlerts        bool
+	NotifyFills             bool
+	NotifyRatePerMinute     int
+
+	// Distributed tracing: spans for API requests and the order path are
+	// exported over OTLP/HTTP to TracingEndpoint ("" = off), recording
+	// TracingSampleRatio of new traces.
+	TracingEndpoint    string
+	TracingServiceName string
+	TracingSampleRatio float64
+
+	// Browser access: CORS allow-lists (origins default to "*" in dev and to
+	// none in staging/prod) and HSTS (default on in prod).
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	EnableHSTS         bool
+
+	// AdminEmails may call the /admin operator endpoints (e.g. symbol halts).
+	AdminEmails []string
+	// FreezeStrategies starts with creating/starting strategies blocked
+	// (lifted via POST /admin/strategies/unfreeze); running ones are unaffected.
+	FreezeStrategies bool
+
+	// API request limits: larger bodies get 413, and handlers running past the
+	// timeout have their context cancelled and the request answered with 408.
+	APIMaxBodyBytes      int64
+	APIHandlerTimeoutSec int
+
 	// Auth / licensing
 	JWTSecret     string
 	LicenseServer string
 
+	// Access tokens: lifetime and the iss/aud claims required on every request.
+	JWTAccessTTLMinutes int
+	JWTIssuer           string
+	JWTAudience         string
+	JWTClockSkewSec     int // leeway for exp/nbf/iat when host clocks drift
+
+	// Password policy for registration and the hash used for new passwords.
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordHash          string // "argon2id" or "bcrypt"
+	BcryptCost            int
+	Argon2MemoryKB        int
+	Argon2Iterations      int
+	Argon2Parallelism     int
+
 	// Localization
 	Language string // "en" or "zh"
 }
@@ -75,40 +284,204 @@ func Load() (*Config, error) {
 		dbPath = getEnv("DATABASE_PATH", "./data/trading.db")
 	}
 
-	return &Config{
-		Port:                     getEnv("PORT", "8080"),
-		BinanceTestnet:           getEnv("BINANCE_TESTNET", "false") == "true",
-		BinanceAPIKey:            os.Getenv("BINANCE_API_KEY"),
-		BinanceAPISecret:         os.Getenv("BINANCE_API_SECRET"),
-		BinanceSymbols:           splitAndTrim(getEnv("BINANCE_SYMBOLS", "BTCUSDT,ETHUSDT")),
-		UseMockFeed:              getEnv("USE_MOCK_FEED", "true") == "true",
-		EnableBinanceTrading:     getEnv("ENABLE_BINANCE_TRADING", "false") == "true",
-		EnableBinanceUSDTFutures: getEnv("ENABLE_BINANCE_USDT_FUTURES", "false") == "true",
-		BinanceUSDTKey:           os.Getenv("BINANCE_USDT_KEY"),
-		BinanceUSDTSecret:        os.Getenv("BINANCE_USDT_SECRET"),
-		EnableBinanceCoinFutures: getEnv("ENABLE_BINANCE_COIN_FUTURES", "false") == "true",
-		BinanceCoinKey:           os.Getenv("BINANCE_COIN_KEY"),
-		BinanceCoinSecret:        os.Getenv("BINANCE_COIN_SECRET"),
-		EnablePythonWorker:       getEnv("ENABLE_PYTHON_WORKER", "false") == "true",
-		PythonWorkerAddr:         getEnv("PYTHON_WORKER_ADDR", "localhost:50051"),
-		DryRun:                   getEnv("DRY_RUN", "false") == "true",
-		DryRunInitialBalance:     getEnvFloat("DRY_RUN_INITIAL_BALANCE", 10000.0),
-		DryRunDBPath:             getEnv("DRY_RUN_DB_PATH", "./trading_dry.db"),
-		DryRunEnableOrderWAL:     getEnv("DRY_RUN_ENABLE_ORDER_WAL", "false") == "true",
-		DryRunOrderWALPath:       getEnv("DRY_RUN_ORDER_WAL_PATH", "./data/order_wal_dry"),
-		DryRunFeeRate:            getEnvFloat("DRY_RUN_FEE_RATE", 0.0004),
-		DryRunSlippageBps:        getEnvFloat("DRY_RUN_SLIPPAGE_BPS", 2),
-		DryRunGwLatencyMinMs:     getEnvInt("DRY_RUN_GATEWAY_LATENCY_MIN_MS", 0),
-		DryRunGwLatencyMaxMs:     getEnvInt("DRY_RUN_GATEWAY_LATENCY_MAX_MS", 0),
-		EnableOrderWAL:           getEnv("ENABLE_ORDER_WAL", "true") == "true",
-		OrderWALPath:             getEnv("ORDER_WAL_PATH", "./data/order_wal"),
-		DBPath:                   dbPath,
-		JWTSecret:                getEnv("JWT_SECRET", "dev-secret"),
-		LicenseServer:            getEnv("LICENSE_SERVER", ""),
-		Language:                 getEnv("LANGUAGE", "en"),
-		ExecutionEnabled:         getEnv("EXECUTION_ENABLED", "true") == "true",
-		BalanceSource:            strings.ToLower(getEnv("BALANCE_SOURCE", "auto")),
-	}, nil
+	cfg := &Config{
+		Port:                      getEnv("PORT", "8080"),
+		Environment:               strings.ToLower(strings.TrimSpace(getEnv("ENVIRONMENT", EnvDev))),
+		BinanceTestnet:            getEnv("BINANCE_TESTNET", "false") == "true",
+		BinanceAPIKey:             os.Getenv("BINANCE_API_KEY"),
+		BinanceAPISecret:          os.Getenv("BINANCE_API_SECRET"),
+		BinanceSymbols:            splitAndTrim(getEnv("BINANCE_SYMBOLS", "BTCUSDT,ETHUSDT")),
+		KlineInterval:             getEnv("KLINE_INTERVAL", "1m"),
+		PriceSource:               getEnv("PRICE_SOURCE", "kline"),
+		PriceCacheField:           getEnv("PRICE_CACHE_FIELD", "last"),
+		ReportingAsset:            strings.ToUpper(getEnv("REPORTING_ASSET", "USDT")),
+		UserStreamFillBatchMs:     getEnvInt("USER_STREAM_FILL_BATCH_MS", 250),
+		FillSource:                strings.ToLower(getEnv("FILL_SOURCE", "first")),
+		ConnectionUserStreams:     getEnv("CONNECTION_USER_STREAMS", "true") == "true",
+		MaxConnectionUserStreams:  getEnvInt("MAX_CONNECTION_USER_STREAMS", 50),
+		MarketWSMaxRetries:        getEnvInt("MARKET_WS_MAX_RETRIES", 10),
+		MarketRESTFallback:        getEnv("MARKET_REST_FALLBACK", "true") == "true",
+		FeedDynamicSymbols:        getEnv("FEED_DYNAMIC_SYMBOLS", "true") == "true",
+		PriceMaxAgeSec:            getEnvInt("PRICE_MAX_AGE_SECONDS", 30),
+		RecordTicks:               getEnv("RECORD_TICKS", "false") == "true",
+		RecordDir:                 getEnv("RECORD_DIR", "./data/ticks"),
+		RecordSymbols:             splitAndTrim(getEnv("RECORD_SYMBOLS", "")),
+		RecordMaxFileMB:           getEnvInt("RECORD_MAX_FILE_MB", 64),
+		RecordRotateMinutes:       getEnvInt("RECORD_ROTATE_MINUTES", 60),
+		UseMockFeed:               getEnv("USE_MOCK_FEED", "true") == "true",
+		EnableBinanceTrading:      getEnv("ENABLE_BINANCE_TRADING", "false") == "true",
+		EnableBinanceUSDTFutures:  getEnv("ENABLE_BINANCE_USDT_FUTURES", "false") == "true",
+		BinanceUSDTKey:            os.Getenv("BINANCE_USDT_KEY"),
+		BinanceUSDTSecret:         os.Getenv("BINANCE_USDT_SECRET"),
+		BinanceUSDTUseWSOrders:    getEnv("BINANCE_USDT_WS_ORDERS", "false") == "true",
+		DeadmanSwitchEnabled:      getEnv("DEADMAN_SWITCH_ENABLED", "false") == "true",
+		DeadmanCountdownSecs:      getEnvInt("DEADMAN_COUNTDOWN_SECONDS", 120),
+		DeadmanRefreshSecs:        getEnvInt("DEADMAN_REFRESH_SECONDS", 30),
+		EnableBinanceCoinFutures:  getEnv("ENABLE_BINANCE_COIN_FUTURES", "false") == "true",
+		BinanceCoinKey:            os.Getenv("BINANCE_COIN_KEY"),
+		BinanceCoinSecret:         os.Getenv("BINANCE_COIN_SECRET"),
+		EnablePythonWorker:        getEnv("ENABLE_PYTHON_WORKER", "false") == "true",
+		PythonWorkerAddr:          getEnv("PYTHON_WORKER_ADDR", "localhost:50051"),
+		DryRun:                    getEnv("DRY_RUN", "false") == "true",
+		DryRunInitialBalance:      getEnvFloat("DRY_RUN_INITIAL_BALANCE", 10000.0),
+		DryRunDBPath:              getEnv("DRY_RUN_DB_PATH", "./trading_dry.db"),
+		DryRunEnableOrderWAL:      getEnv("DRY_RUN_ENABLE_ORDER_WAL", "false") == "true",
+		DryRunOrderWALPath:        getEnv("DRY_RUN_ORDER_WAL_PATH", "./data/order_wal_dry"),
+		DryRunFeeRate:             getEnvFloat("DRY_RUN_FEE_RATE", 0.0004),
+		DryRunSlippageBps:         getEnvFloat("DRY_RUN_SLIPPAGE_BPS", 2),
+		DryRunGwLatencyMinMs:      getEnvInt("DRY_RUN_GATEWAY_LATENCY_MIN_MS", 0),
+		DryRunGwLatencyMaxMs:      getEnvInt("DRY_RUN_GATEWAY_LATENCY_MAX_MS", 0),
+		DryRunRejectProb:          getEnvFloat("DRY_RUN_REJECT_PROBABILITY", 0),
+		DryRunRejectSymbols:       splitAndTrim(getEnv("DRY_RUN_REJECT_SYMBOLS", "")),
+		DryRunSeed:                int64(getEnvInt("DRY_RUN_SEED", 0)),
+		DryRunValidateFilters:     getEnv("DRY_RUN_VALIDATE_FILTERS", "true") == "true",
+		EnableOrderWAL:            getEnv("ENABLE_ORDER_WAL", "true") == "true",
+		OrderWALPath:              getEnv("ORDER_WAL_PATH", "./data/order_wal"),
+		OrderWALBackend:           strings.ToLower(getEnv("ORDER_WAL_BACKEND", "file")),
+		OrderQueueBackend:         strings.ToLower(getEnv("ORDER_QUEUE_BACKEND", "local")),
+		SharedQueueVisibilitySec:  getEnvInt("SHARED_QUEUE_VISIBILITY_SECONDS", 30),
+		SharedQueueDSN:            getEnv("SHARED_QUEUE_DSN", ""),
+		DBPath:                    dbPath,
+		DBStartupRetries:          getEnvInt("DB_STARTUP_RETRIES", 5),
+		DBStartupBackoffMs:        getEnvInt("DB_STARTUP_BACKOFF_MS", 500),
+		DBStartupMaxBackoffMs:     getEnvInt("DB_STARTUP_MAX_BACKOFF_MS", 10000),
+		DBDegradedStart:           getEnv("DB_DEGRADED_START", "false") == "true",
+		DBWriteRetries:            getEnvInt("DB_WRITE_RETRIES", 3),
+		DBWriteRetryBackoff:       getEnvInt("DB_WRITE_RETRY_BACKOFF_MS", 50),
+		RecoveryLogPath:           getEnv("RECOVERY_LOG_PATH", "./data/recovery.log"),
+		StrategyReconMinutes:      getEnvInt("STRATEGY_RECON_INTERVAL_MINUTES", 15),
+		StrategyReconQtyTol:       getEnvFloat("STRATEGY_RECON_QTY_TOLERANCE", 1e-8),
+		StrategyReconPricePct:     getEnvFloat("STRATEGY_RECON_PRICE_TOLERANCE_PCT", 0.01),
+		StrategyReconAutoHeal:     getEnv("STRATEGY_RECON_AUTO_HEAL", "true") == "true",
+		ReconcileOnStartup:        getEnv("RECONCILE_ON_STARTUP", "false") == "true",
+		ReconPauseDrain:           getEnv("RECON_PAUSE_DRAIN", "true") == "true",
+		ReconPauseMaxMs:           getEnvInt("RECON_PAUSE_MAX_MS", 2000),
+		FundingSyncMinutes:        getEnvInt("FUNDING_SYNC_INTERVAL_MINUTES", 10),
+		EventBusBuffer:            getEnvInt("EVENT_BUS_BUFFER", 100),
+		AlertDedupWindowSeconds:   getEnvInt("ALERT_DEDUP_WINDOW_SECONDS", 60),
+		MaxStrategiesPerUser:      getEnvInt("MAX_STRATEGIES_PER_USER", 50),
+		MaxConnectionsPerUser:     getEnvInt("MAX_CONNECTIONS_PER_USER", 10),
+		MaxLeverage:               getEnvInt("MAX_LEVERAGE", 0),
+		HedgeMode:                 getEnv("HEDGE_MODE", "false") == "true",
+		LeverageCapMode:           strings.ToLower(getEnv("LEVERAGE_CAP_MODE", "clamp")),
+		MakerFirstRouting:         getEnv("MAKER_FIRST_ROUTING", "false") == "true",
+		MakerFirstTimeoutSec:      getEnvInt("MAKER_FIRST_TIMEOUT_SECONDS", 10),
+		MakerFirstMaxReprices:     getEnvInt("MAKER_FIRST_MAX_REPRICES", 2),
+		PostOnlyRetries:           getEnvInt("POST_ONLY_RETRIES", 2),
+		OrderBookFeatures:         getEnv("ORDERBOOK_FEATURES", "false") == "true",
+		OrderBookDepthLevels:      getEnvInt("ORDERBOOK_DEPTH_LEVELS", 10),
+		NetEdgeMinMultiple:        getEnvFloat("NET_EDGE_MIN_MULTIPLE", 0),
+		NetEdgeDefaultSpreadBps:   getEnvFloat("NET_EDGE_DEFAULT_SPREAD_BPS", 2),
+		LiquidationBufferPct:      getEnvFloat("LIQUIDATION_ALERT_BUFFER_PCT", 5),
+		LiquidationAutoReduce:     getEnv("LIQUIDATION_AUTO_REDUCE", "false") == "true",
+		LiquidationReduceFraction: getEnvFloat("LIQUIDATION_REDUCE_FRACTION", 0.5),
+		LiquidationMarginTopUpMax: getEnvFloat("LIQUIDATION_MARGIN_TOPUP_MAX", 0),
+		LiquidationCheckSeconds:   getEnvInt("LIQUIDATION_CHECK_SECONDS", 30),
+		MaxFillDeviationPct:       getEnvFloat("MAX_FILL_PRICE_DEVIATION_PCT", 20),
+		NotifyWebhookURL:          getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyDiscordWebhookURL:   getEnv("NOTIFY_DISCORD_WEBHOOK_URL", ""),
+		NotifyTelegramBotToken:    getEnv("NOTIFY_TELEGRAM_BOT_TOKEN", ""),
+		NotifyTelegramChatID:      getEnv("NOTIFY_TELEGRAM_CHAT_ID", ""),
+		NotifyRiskAlerts:          getEnv("NOTIFY_RISK_ALERTS", "true") == "true",
+		NotifyFills:               getEnv("NOTIFY_FILLS", "false") == "true",
+		NotifyRatePerMinute:       getEnvInt("NOTIFY_RATE_PER_MINUTE", 20),
+		TracingEndpoint:           getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TracingServiceName:        getEnv("OTEL_SERVICE_NAME", "trading-core"),
+		TracingSampleRatio:        getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		JWTSecret:                 getEnv("JWT_SECRET", "dev-secret"),
+		LicenseServer:             getEnv("LICENSE_SERVER", ""),
+		JWTAccessTTLMinutes:       getEnvInt("JWT_ACCESS_TTL_MINUTES", 4320),
+		JWTIssuer:                 getEnv("JWT_ISSUER", "des-trading-core"),
+		JWTAudience:               getEnv("JWT_AUDIENCE", "des-trading-api"),
+		JWTClockSkewSec:           getEnvInt("JWT_CLOCK_SKEW_SECONDS", 30),
+		PasswordMinLength:         getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:      getEnv("PASSWORD_REQUIRE_UPPER", "true") == "true",
+		PasswordRequireLower:      getEnv("PASSWORD_REQUIRE_LOWER", "true") == "true",
+		PasswordRequireDigit:      getEnv("PASSWORD_REQUIRE_DIGIT", "true") == "true",
+		PasswordRequireSymbol:     getEnv("PASSWORD_REQUIRE_SYMBOL", "false") == "true",
+		PasswordHash:              strings.ToLower(getEnv("PASSWORD_HASH", "argon2id")),
+		BcryptCost:                getEnvInt("BCRYPT_COST", 10),
+		Argon2MemoryKB:            getEnvInt("ARGON2_MEMORY_KB", 19456),
+		Argon2Iterations:          getEnvInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism:         getEnvInt("ARGON2_PARALLELISM", 1),
+		Language:                  getEnv("LANGUAGE", "en"),
+		ExecutionEnabled:          getEnv("EXECUTION_ENABLED", "true") == "true",
+		BalanceSource:             strings.ToLower(getEnv("BALANCE_SOURCE", "auto")),
+		MaintenanceWindows:        getEnv("MAINTENANCE_WINDOWS", ""),
+		MaxLossStreak:             getEnvInt("MAX_LOSS_STREAK", 0),
+		CORSAllowedOrigins:        splitAndTrim(getEnv("CORS_ALLOWED_ORIGINS", "")),
+		AdminEmails:               splitAndTrim(getEnv("ADMIN_EMAILS", "")),
+		FreezeStrategies:          getEnv("FREEZE_NEW_STRATEGIES", "false") == "true",
+		CORSAllowedMethods:        splitAndTrim(getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS")),
+		CORSAllowedHeaders:        splitAndTrim(getEnv("CORS_ALLOWED_HEADERS", "Authorization,Content-Type,X-Request-ID")),
+		APIMaxBodyBytes:           int64(getEnvInt("API_MAX_BODY_BYTES", 1<<20)),
+		APIHandlerTimeoutSec:      getEnvInt("API_HANDLER_TIMEOUT_SECONDS", 30),
+	}
+	cfg.enforceEnvironment()
+	cfg.applyHTTPDefaults(os.Getenv("ENABLE_HSTS"))
+	if err := cfg.validateDeadman(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validateDeadman rejects a dead-man's switch whose heartbeat could miss the
+// countdown: the exchange would cancel every order between two heartbeats.
+func (c *Config) validateDeadman() error {
+	if !c.DeadmanSwitchEnabled {
+		return nil
+	}
+	if c.DeadmanCountdownSecs <= 0 || c.DeadmanRefreshSecs <= 0 {
+		return fmt.Errorf("DEADMAN_COUNTDOWN_SECONDS (%d) and DEADMAN_REFRESH_SECONDS (%d) must be positive", c.DeadmanCountdownSecs, c.DeadmanRefreshSecs)
+	}
+	if c.DeadmanRefreshSecs >= c.DeadmanCountdownSecs {
+		return fmt.Errorf("DEADMAN_REFRESH_SECONDS (%d) must be below DEADMAN_COUNTDOWN_SECONDS (%d)", c.DeadmanRefreshSecs, c.DeadmanCountdownSecs)
+	}
+	return nil
+}
+
+// enforceEnvironment keeps non-prod environments off mainnet: unknown values
+// are treated as dev, and live trading without testnet is turned into dry-run.
+func (c *Config) enforceEnvironment() {
+	switch c.Environment {
+	case EnvDev, EnvStaging, EnvProd:
+	default:
+		c.Environment = EnvDev
+	}
+	if c.Environment != EnvProd && !c.DryRun && !c.BinanceTestnet {
+		c.DryRun = true
+		c.ForcedDryRun = true
+	}
+}
+
+// applyHTTPDefaults makes browser access permissive in dev and strict
+// elsewhere unless configured: dev allows any origin, and prod sends HSTS.
+func (c *Config) applyHTTPDefaults(hsts string) {
+	if len(c.CORSAllowedOrigins) == 0 && c.Environment == EnvDev {
+		c.CORSAllowedOrigins = []string{"*"}
+	}
+	c.EnableHSTS = c.Environment == EnvProd
+	if hsts != "" {
+		c.EnableHSTS = hsts == "true"
+	}
+}
+
+// TestnetOnly reports whether orders that leave the process must go to testnet
+// (a non-prod environment running without dry-run).
+func (c *Config) TestnetOnly() bool {
+	return c.Environment != EnvProd && !c.DryRun
+}
+
+// TradingMode describes where orders end up: "DRY-RUN", "TESTNET" or "LIVE MAINNET".
+func (c *Config) TradingMode() string {
+	switch {
+	case c.DryRun || !c.ExecutionEnabled:
+		return "DRY-RUN"
+	case c.TestnetOnly():
+		return "TESTNET"
+	default:
+		return "LIVE MAINNET"
+	}
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,80 @@
+package config
+
+import "testing"
+
+func TestDevEnvironmentForcesDryRunWithLiveKeys(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "dev")
+	t.Setenv("DRY_RUN", "false")
+	t.Setenv("BINANCE_TESTNET", "false")
+	t.Setenv("ENABLE_BINANCE_TRADING", "true")
+	t.Setenv("BINANCE_API_KEY", "live-key")
+	t.Setenv("BINANCE_API_SECRET", "live-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.DryRun || !cfg.ForcedDryRun {
+		t.Fatalf("dev with live keys must be forced to dry-run, got DryRun=%v ForcedDryRun=%v", cfg.DryRun, cfg.ForcedDryRun)
+	}
+	if mode := cfg.TradingMode(); mode != "DRY-RUN" {
+		t.Fatalf("TradingMode = %q, want DRY-RUN", mode)
+	}
+}
+
+func TestEnvironmentGuardrails(t *testing.T) {
+	tests := []struct {
+		env             string
+		dryRun, testnet bool
+		wantDryRun      bool
+		wantMode        string
+	}{
+		{env: "staging", testnet: true, wantMode: "TESTNET"},
+		{env: "staging", wantDryRun: true, wantMode: "DRY-RUN"},
+		{env: "bogus", wantDryRun: true, wantMode: "DRY-RUN"}, // unknown means dev
+		{env: "prod", wantMode: "LIVE MAINNET"},
+		{env: "prod", dryRun: true, wantDryRun: true, wantMode: "DRY-RUN"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Environment: tt.env, DryRun: tt.dryRun, BinanceTestnet: tt.testnet, ExecutionEnabled: true}
+		cfg.enforceEnvironment()
+		if cfg.DryRun != tt.wantDryRun || cfg.TradingMode() != tt.wantMode {
+			t.Errorf("%s dry_run=%v testnet=%v: got DryRun=%v mode=%s, want DryRun=%v mode=%s",
+				tt.env, tt.dryRun, tt.testnet, cfg.DryRun, cfg.TradingMode(), tt.wantDryRun, tt.wantMode)
+		}
+	}
+}
+
+func TestHTTPDefaultsFollowEnvironment(t *testing.T) {
+	dev := &Config{Environment: EnvDev}
+	dev.applyHTTPDefaults("")
+	if len(dev.CORSAllowedOrigins) != 1 || dev.CORSAllowedOrigins[0] != "*" || dev.EnableHSTS {
+		t.Fatalf("dev: origins=%v hsts=%v, want [*] false", dev.CORSAllowedOrigins, dev.EnableHSTS)
+	}
+
+	prod := &Config{Environment: EnvProd}
+	prod.applyHTTPDefaults("")
+	if len(prod.CORSAllowedOrigins) != 0 || !prod.EnableHSTS {
+		t.Fatalf("prod: origins=%v hsts=%v, want [] true", prod.CORSAllowedOrigins, prod.EnableHSTS)
+	}
+
+	staging := &Config{Environment: EnvStaging, CORSAllowedOrigins: []string{"https://dash.example.com"}}
+	staging.applyHTTPDefaults("true")
+	if len(staging.CORSAllowedOrigins) != 1 || !staging.EnableHSTS {
+		t.Fatalf("staging: origins=%v hsts=%v", staging.CORSAllowedOrigins, staging.EnableHSTS)
+	}
+}
+
+func TestDeadmanRefreshMustBeBelowCountdown(t *testing.T) {
+	t.Setenv("DEADMAN_SWITCH_ENABLED", "true")
+	t.Setenv("DEADMAN_COUNTDOWN_SECONDS", "30")
+	t.Setenv("DEADMAN_REFRESH_SECONDS", "30")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load should reject a refresh interval equal to the countdown")
+	}
+
+	t.Setenv("DEADMAN_REFRESH_SECONDS", "10")
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+}
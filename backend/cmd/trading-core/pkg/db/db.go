@@ -1,11 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite" // SQLite driver
@@ -36,6 +38,74 @@ func New(path string) (*Database, error) {
 	return &Database{DB: db}, nil
 }
 
+// RetryPolicy controls OpenWithRetry.
+type RetryPolicy struct {
+	Attempts       int           // total tries; <= 0 retries until ctx is done
+	InitialBackoff time.Duration // wait after the first failure, doubled each retry
+	MaxBackoff     time.Duration // cap on the wait (0 = uncapped)
+	// OnRetry is called after each failed attempt that will be retried (optional).
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// OpenWithRetry opens the database at path and applies migrations, retrying
+// with exponential backoff so a brief outage at startup is not fatal. It
+// returns the last error once the attempts are used up or ctx is done.
+func OpenWithRetry(ctx context.Context, path string, p RetryPolicy) (*Database, error) {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 500 * time.Millisecond
+	}
+	wait := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		d, err := openAndMigrate(ctx, path)
+		if err == nil {
+			return d, nil
+		}
+		if p.Attempts > 0 && attempt >= p.Attempts {
+			return nil, err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(wait):
+		}
+		wait *= 2
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+	}
+}
+
+func openAndMigrate(ctx context.Context, path string) (*Database, error) {
+	d, err := New(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.DB.PingContext(ctx); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+	if err := ApplyMigrations(d); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("migrations: %w", err)
+	}
+	return d, nil
+}
+
+// IsTransient reports whether err is a lock/busy error that may succeed on retry.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "sqlite_busy") ||
+		strings.Contains(msg, "sqlite_locked")
+}
+
 // Close releases the underlying DB handle.
 func (d *Database) Close() error {
 	if d == nil || d.DB == nil {
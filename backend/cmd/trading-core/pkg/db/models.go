@@ -3,9 +3,10 @@ package db
 import (
 	"context"
 	"database/sql"
-	"math"
 	"strings"
 	"time"
+
+	"trading-core/pkg/money"
 )
 
 // Order represents a trading order stored in the DB.
@@ -18,7 +19,12 @@ type Order struct {
 	Qty                float64
 	FilledQty          float64
 	Status             string
-	UserID             string // Multi-user isolation
+	RefPrice           float64  // price at submission, for slippage reporting (0 = unknown)
+	UserID             string   // Multi-user isolation
+	Note               string   // trade-journal annotation from the user
+	Tags               []string // trade-journal tags (lower-case), e.g. setup names
+	ExchangeOrderID    string   // the venue's order ID, "" when never sent
+	PositionSide       string   // LONG/SHORT for a hedge-mode order, "" otherwise
 	CreatedAt          time.Time
 }
 
@@ -31,17 +37,20 @@ type Trade struct {
 	Price     float64
 	Qty       float64
 	Fee       float64
+	FeeAsset  string // settlement asset of the fee (Fee is in the reporting currency)
 	UserID    string // Multi-user isolation
 	CreatedAt time.Time
 }
 
 // Position tracks net position per symbol (global).
 type Position struct {
-	Symbol    string
-	Qty       float64
-	AvgPrice  float64
-	UserID    string // Multi-user isolation
-	UpdatedAt time.Time
+	Symbol       string
+	Qty          float64
+	AvgPrice     float64
+	UserID       string // Multi-user isolation
+	PositionSide string // LONG/SHORT for a hedge-mode leg; empty for a net position
+	ConnectionID string // exchange connection holding a hedge-mode leg ("" = global account)
+	UpdatedAt    time.Time
 }
 
 // StrategyPosition tracks per-strategy exposure/PnL.
@@ -51,6 +60,8 @@ type StrategyPosition struct {
 	Qty                float64
 	AvgPrice           float64
 	RealizedPnL        float64
+	PeakPnL            float64   // highest RealizedPnL reached (drawdown reference)
+	OpenedAt           time.Time // when the open position was entered; zero when flat or unknown
 	UpdatedAt          time.Time
 }
 
@@ -78,6 +89,16 @@ type User struct {
 	UpdatedAt    time.Time
 }
 
+// EquitySnapshot is a point-in-time record of a user's account equity.
+type EquitySnapshot struct {
+	ID            int64
+	UserID        string
+	Balance       float64 // cash balance
+	PositionValue float64 // open positions marked to market (unrealized PnL for futures)
+	Equity        float64 // Balance + PositionValue
+	CreatedAt     time.Time
+}
+
 // Connection represents a user's exchange connection/API key.
 type Connection struct {
 	ID                 string
@@ -86,9 +107,17 @@ type Connection struct {
 	Name               string
 	APIKey             string
 	APISecret          string
-	APIKeyEncrypted    string // Phase 1: encrypted storage
-	APISecretEncrypted string // Phase 1: encrypted storage
-	KeyVersion         int    // Phase 1: key version
+	APIKeyEncrypted    string  // Phase 1: encrypted storage
+	APISecretEncrypted string  // Phase 1: encrypted storage
+	KeyVersion         int     // Phase 1: key version
+	MakerFeeBps        float64 // 0 = use default fee schedule
+	TakerFeeBps        float64 // 0 = use default fee schedule
+	BNBDiscount        float64 // fractional discount when paying fees in BNB, e.g. 0.25
+	Capabilities       string  // comma-separated account permissions, e.g. "SPOT,MARGIN"; "" = unknown
+	Leverage           int     // futures leverage to apply on order; 0 = leave the exchange setting
+	MaxDailyLoss       float64 // realized loss per day before entries are blocked; 0 = no limit
+	MaxExposure        float64 // open notional cap across symbols; 0 = no limit
+	Paper              bool    // orders are simulated by the dry runner regardless of the global mode
 	IsActive           bool
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
@@ -97,42 +126,65 @@ type Connection struct {
 
 // CreateOrder inserts a new order row.
 func (d *Database) CreateOrder(ctx context.Context, o Order) error {
-	_, err := d.DB.ExecContext(ctx, `
+	return createOrder(ctx, d.DB, o)
+}
+
+func createOrder(ctx context.Context, q execer, o Order) error {
+	_, err := q.ExecContext(ctx, `
 		INSERT INTO orders (
-			id, strategy_instance_id, symbol, side, price, qty, filled_qty, status, user_id, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
+			id, strategy_instance_id, symbol, side, price, qty, filled_qty, status, ref_price, user_id, note, tags,
+			exchange_order_id, position_side, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
 	`,
-		o.ID, o.StrategyInstanceID, o.Symbol, o.Side, o.Price, o.Qty, o.FilledQty, o.Status, o.UserID, o.CreatedAt,
+		o.ID, o.StrategyInstanceID, o.Symbol, o.Side, o.Price, o.Qty, o.FilledQty, o.Status, o.RefPrice, o.UserID, o.Note, JoinTags(o.Tags),
+		o.ExchangeOrderID, o.PositionSide, o.CreatedAt,
 	)
 	return err
 }
 
+// JoinTags stores journal tags as a comma-separated list ("" when none).
+func JoinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+// SplitTags parses a stored tag list; nil when empty.
+func SplitTags(stored string) []string {
+	if stored == "" {
+		return nil
+	}
+	return strings.Split(stored, ",")
+}
+
 // CreateTrade inserts a new trade row.
 func (d *Database) CreateTrade(ctx context.Context, t Trade) error {
-	_, err := d.DB.ExecContext(ctx, `
+	return createTrade(ctx, d.DB, t)
+}
+
+func createTrade(ctx context.Context, q execer, t Trade) error {
+	_, err := q.ExecContext(ctx, `
 		INSERT INTO trades (
-			id, order_id, symbol, side, price, qty, fee, user_id, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
+			id, order_id, symbol, side, price, qty, fee, fee_asset, user_id, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
 	`,
-		t.ID, t.OrderID, t.Symbol, t.Side, t.Price, t.Qty, t.Fee, t.UserID, t.CreatedAt,
+		t.ID, t.OrderID, t.Symbol, t.Side, t.Price, t.Qty, t.Fee, t.FeeAsset, t.UserID, t.CreatedAt,
 	)
 	return err
 }
 
-// UpdateOrderStatus sets the status of an order.
+// UpdateOrderStatus sets the status of an order. Transitions not allowed by the
+// order state machine (see ValidOrderTransition) return ErrInvalidOrderTransition.
 func (d *Database) UpdateOrderStatus(ctx context.Context, id, status string) error {
-	_, err := d.DB.ExecContext(ctx, `UPDATE orders SET status = ? WHERE id = ?`, status, id)
-	return err
+	return d.WithTx(ctx, func(tx *Tx) error {
+		return transitionOrder(ctx, tx.Tx, id, status, "")
+	})
 }
 
-// UpdateOrderFill sets status and filled quantity (and optionally price).
+// UpdateOrderFill sets status and filled quantity (and optionally price),
+// subject to the same transition rules as UpdateOrderStatus.
 func (d *Database) UpdateOrderFill(ctx context.Context, id, status string, filledQty, price float64) error {
-	_, err := d.DB.ExecContext(ctx, `
-		UPDATE orders
-		SET status = ?, filled_qty = ?, price = ?
-		WHERE id = ?
-	`, status, filledQty, price, id)
-	return err
+	return d.WithTx(ctx, func(tx *Tx) error {
+		return transitionOrder(ctx, tx.Tx, id, status, ", filled_qty = ?, price = ?", filledQty, price)
+	})
 }
 
 // UpsertPosition stores the latest position for a symbol.
@@ -149,12 +201,31 @@ func (d *Database) UpsertPosition(ctx context.Context, p Position) error {
 	return err
 }
 
+// GetOrderOrigin returns the strategy and hedge-mode position side an order
+// was placed for; found is false when the order was never stored.
+func (d *Database) GetOrderOrigin(ctx context.Context, id string) (strategyID, positionSide string, found bool, err error) {
+	err = d.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(strategy_instance_id, ''), COALESCE(position_side, '') FROM orders WHERE id = ?
+	`, id).Scan(&strategyID, &positionSide)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	return strategyID, positionSide, err == nil, err
+}
+
+// OrderExists reports whether an order with id is stored.
+func (d *Database) OrderExists(ctx context.Context, id string) (bool, error) {
+	var n int
+	err := d.DB.QueryRowContext(ctx, `SELECT COUNT(1) FROM orders WHERE id = ?`, id).Scan(&n)
+	return n > 0, err
+}
+
 // ListOpenOrders returns orders that are not filled/closed.
 func (d *Database) ListOpenOrders(ctx context.Context) ([]Order, error) {
 	rows, err := d.DB.QueryContext(ctx, `
 		SELECT id, strategy_instance_id, symbol, side, price, qty, filled_qty, status, created_at
-		FROM orders WHERE status NOT IN ('FILLED','CANCELLED')
-		ORDER BY created_at DESC`)
+		FROM orders WHERE status NOT IN ('FILLED','CANCELED','CANCELLED','REJECTED','EXPIRED')
+		ORDER BY created_at DESC, id DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -192,15 +263,83 @@ func (d *Database) ListPositions(ctx context.Context) ([]Position, error) {
 	return res, rows.Err()
 }
 
+// ListUserPositions returns every user's own positions (user_positions).
+func (d *Database) ListUserPositions(ctx context.Context) ([]Position, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT symbol, qty, avg_price, user_id, updated_at
+		FROM user_positions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Position
+	for rows.Next() {
+		var p Position
+		if err := rows.Scan(&p.Symbol, &p.Qty, &p.AvgPrice, &p.UserID, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// UpsertPositionLeg stores one side (LONG/SHORT) of a hedge-mode position
+// held by p.UserID on p.ConnectionID.
+func (d *Database) UpsertPositionLeg(ctx context.Context, p Position) error {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT INTO position_legs (user_id, connection_id, symbol, position_side, qty, avg_price, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, connection_id, symbol, position_side) DO UPDATE SET
+			qty = excluded.qty,
+			avg_price = excluded.avg_price,
+			updated_at = CURRENT_TIMESTAMP
+	`, p.UserID, p.ConnectionID, p.Symbol, p.PositionSide, p.Qty, p.AvgPrice)
+	return err
+}
+
+// ListPositionLegs returns the stored hedge-mode legs with a non-zero quantity.
+func (d *Database) ListPositionLegs(ctx context.Context) ([]Position, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT user_id, connection_id, symbol, position_side, qty, avg_price, updated_at
+		FROM position_legs WHERE qty != 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Position
+	for rows.Next() {
+		var p Position
+		if err := rows.Scan(&p.UserID, &p.ConnectionID, &p.Symbol, &p.PositionSide, &p.Qty, &p.AvgPrice, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// GetStrategyPosition returns a strategy's position or nil if it has none.
+func (d *Database) GetStrategyPosition(ctx context.Context, strategyID string) (*StrategyPosition, error) {
+	sp, err := getStrategyPosition(ctx, d.DB, strategyID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sp, nil
+}
+
 // UpdateStrategyPosition upserts per-strategy position and realized PnL.
-// Simple logic: BUY increases qty/avg; SELL decreases qty and realizes PnL on the closed portion.
+// Uses average-cost accounting: adding to a side updates the average entry, while
+// reducing or flipping realizes PnL on the closed portion (see applyAverageCostFill).
 func (d *Database) UpdateStrategyPosition(ctx context.Context, strategyID, symbol, side string, qty, price float64) error {
-	var sp StrategyPosition
-	err := d.DB.QueryRowContext(ctx, `
-		SELECT strategy_instance_id, symbol, qty, avg_price, realized_pnl, updated_at
-		FROM strategy_positions WHERE strategy_instance_id = ?
-	`, strategyID).Scan(&sp.StrategyInstanceID, &sp.Symbol, &sp.Qty, &sp.AvgPrice, &sp.RealizedPnL, &sp.UpdatedAt)
+	return updateStrategyPosition(ctx, d.DB, strategyID, symbol, side, qty, price)
+}
 
+func updateStrategyPosition(ctx context.Context, q execer, strategyID, symbol, side string, qty, price float64) error {
+	sp, err := getStrategyPosition(ctx, q, strategyID)
 	if err != nil && err != sql.ErrNoRows {
 		return err
 	}
@@ -216,64 +355,95 @@ func (d *Database) UpdateStrategyPosition(ctx context.Context, strategyID, symbo
 		}
 	}
 
-	switch strings.ToUpper(side) {
-	case "BUY":
-		newQty := sp.Qty + qty
-		if math.Abs(newQty) < 1e-9 {
-			// Position essentially closed, reset to avoid float precision issues
-			sp.Qty = 0
-			sp.AvgPrice = 0
-		} else if newQty > 0 {
-			sp.AvgPrice = (sp.AvgPrice*sp.Qty + price*qty) / newQty
-			sp.Qty = newQty
-		} else {
-			sp.Qty = newQty
-		}
-	case "SELL":
-		closeQty := math.Min(sp.Qty, qty)
-		if closeQty > 0 {
-			sp.RealizedPnL += (price - sp.AvgPrice) * closeQty
-		}
-		sp.Qty -= qty
-		if sp.Qty < 1e-9 {
-			sp.Qty = 0
-			sp.AvgPrice = 0
-		}
-	default:
-		// Unknown side, no-op
+	var realized float64
+	oldQty := sp.Qty
+	sp.Qty, sp.AvgPrice, realized = applyAverageCostFill(sp.Qty, sp.AvgPrice, side, qty, price)
+	switch {
+	case sp.Qty == 0:
+		sp.OpenedAt = time.Time{}
+	case oldQty == 0 || (oldQty > 0) != (sp.Qty > 0):
+		sp.OpenedAt = time.Now()
+	}
+	sp.RealizedPnL = money.AddFloats(sp.RealizedPnL, realized)
+	if sp.RealizedPnL > sp.PeakPnL {
+		sp.PeakPnL = sp.RealizedPnL
 	}
 
 	sp.Symbol = symbol
 	sp.UpdatedAt = time.Now()
 
-	_, execErr := d.DB.ExecContext(ctx, `
-		INSERT INTO strategy_positions (strategy_instance_id, symbol, qty, avg_price, realized_pnl, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+	var openedAt sql.NullTime
+	if !sp.OpenedAt.IsZero() {
+		openedAt = sql.NullTime{Time: sp.OpenedAt, Valid: true}
+	}
+	_, execErr := q.ExecContext(ctx, `
+		INSERT INTO strategy_positions (strategy_instance_id, symbol, qty, avg_price, realized_pnl, peak_pnl, opened_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(strategy_instance_id) DO UPDATE SET
 			symbol = excluded.symbol,
 			qty = excluded.qty,
 			avg_price = excluded.avg_price,
 			realized_pnl = excluded.realized_pnl,
+			peak_pnl = excluded.peak_pnl,
+			opened_at = excluded.opened_at,
 			updated_at = excluded.updated_at
-	`, sp.StrategyInstanceID, sp.Symbol, sp.Qty, sp.AvgPrice, sp.RealizedPnL, sp.UpdatedAt)
+	`, sp.StrategyInstanceID, sp.Symbol, sp.Qty, sp.AvgPrice, sp.RealizedPnL, sp.PeakPnL, openedAt, sp.UpdatedAt)
 	return execErr
 }
 
-// CreateUser inserts a new user row.
+// getStrategyPosition reads a strategy's position row; sql.ErrNoRows when it has none.
+func getStrategyPosition(ctx context.Context, q execer, strategyID string) (StrategyPosition, error) {
+	var (
+		sp       StrategyPosition
+		openedAt sql.NullTime
+	)
+	err := q.QueryRowContext(ctx, `
+		SELECT strategy_instance_id, symbol, qty, avg_price, realized_pnl, COALESCE(peak_pnl, 0), opened_at, updated_at
+		FROM strategy_positions WHERE strategy_instance_id = ?
+	`, strategyID).Scan(&sp.StrategyInstanceID, &sp.Symbol, &sp.Qty, &sp.AvgPrice, &sp.RealizedPnL, &sp.PeakPnL, &openedAt, &sp.UpdatedAt)
+	sp.OpenedAt = openedAt.Time
+	return sp, err
+}
+
+// CreateUser inserts a new user row. Emails are stored trimmed and lowercased;
+// ErrDuplicate is returned when the email is already registered.
 func (d *Database) CreateUser(ctx context.Context, u User) error {
 	_, err := d.DB.ExecContext(ctx, `
 		INSERT INTO users (id, email, password_hash, created_at, updated_at)
 		VALUES (?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), COALESCE(?, CURRENT_TIMESTAMP))
-	`, u.ID, strings.ToLower(u.Email), u.PasswordHash, u.CreatedAt, u.UpdatedAt)
+	`, u.ID, normalizeEmail(u.Email), u.PasswordHash, u.CreatedAt, u.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
 	return err
 }
 
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // GetUserByEmail returns a user by email or nil if not found.
 func (d *Database) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	row := d.DB.QueryRowContext(ctx, `
 		SELECT id, email, password_hash, created_at, updated_at
 		FROM users WHERE email = ?
-	`, strings.ToLower(email))
+	`, normalizeEmail(email))
+	var u User
+	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUserByID returns a user by ID or nil if not found.
+func (d *Database) GetUserByID(ctx context.Context, id string) (*User, error) {
+	row := d.DB.QueryRowContext(ctx, `
+		SELECT id, email, password_hash, created_at, updated_at
+		FROM users WHERE id = ?
+	`, id)
 	var u User
 	if err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt); err != nil {
 		if err == sql.ErrNoRows {
@@ -319,7 +489,7 @@ func (d *Database) CreateConnection(ctx context.Context, c Connection) error {
 // ListConnectionsByUser returns all connections for a user.
 func (d *Database) ListConnectionsByUser(ctx context.Context, userID string) ([]Connection, error) {
 	rows, err := d.DB.QueryContext(ctx, `
-		SELECT id, user_id, exchange_type, name, api_key, api_secret, is_active, created_at, updated_at
+		SELECT id, user_id, exchange_type, name, api_key, api_secret, COALESCE(paper, 0), is_active, created_at, updated_at
 		FROM connections WHERE user_id = ?
 		ORDER BY created_at DESC
 	`, userID)
@@ -331,7 +501,7 @@ func (d *Database) ListConnectionsByUser(ctx context.Context, userID string) ([]
 	var res []Connection
 	for rows.Next() {
 		var c Connection
-		if err := rows.Scan(&c.ID, &c.UserID, &c.ExchangeType, &c.Name, &c.APIKey, &c.APISecret, &c.IsActive, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err := rows.Scan(&c.ID, &c.UserID, &c.ExchangeType, &c.Name, &c.APIKey, &c.APISecret, &c.Paper, &c.IsActive, &c.CreatedAt, &c.UpdatedAt); err != nil {
 			return nil, err
 		}
 		res = append(res, c)
@@ -339,6 +509,131 @@ func (d *Database) ListConnectionsByUser(ctx context.Context, userID string) ([]
 	return res, rows.Err()
 }
 
+// ListActiveConnectionsByType returns active connections of the given exchange
+// types across all users (used by background monitors; secrets are not loaded).
+func (d *Database) ListActiveConnectionsByType(ctx context.Context, exchangeTypes ...string) ([]Connection, error) {
+	if len(exchangeTypes) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(exchangeTypes))
+	for i, t := range exchangeTypes {
+		args[i] = t
+	}
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT id, user_id, exchange_type, name, is_active, COALESCE(paper, 0), created_at, updated_at
+		FROM connections
+		WHERE is_active = 1 AND exchange_type IN (?`+strings.Repeat(",?", len(exchangeTypes)-1)+`)
+		ORDER BY user_id, created_at
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Connection
+	for rows.Next() {
+		var c Connection
+		if err := rows.Scan(&c.ID, &c.UserID, &c.ExchangeType, &c.Name, &c.IsActive, &c.Paper, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// ActiveStrategySymbols returns the distinct symbols of the active strategies
+// bound to a connection.
+func (d *Database) ActiveStrategySymbols(ctx context.Context, connectionID string) ([]string, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT DISTINCT symbol FROM strategy_instances
+		WHERE connection_id = ? AND is_active = 1
+		ORDER BY symbol
+	`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var sym string
+		if err := rows.Scan(&sym); err != nil {
+			return nil, err
+		}
+		res = append(res, sym)
+	}
+	return res, rows.Err()
+}
+
+// GetConnectionRiskLimits returns a connection's daily-loss and exposure caps
+// (cross-user; used by the risk manager, which only knows the connection ID).
+func (d *Database) GetConnectionRiskLimits(ctx context.Context, connectionID string) (maxDailyLoss, maxExposure float64, err error) {
+	err = d.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(max_daily_loss, 0), COALESCE(max_exposure, 0)
+		FROM connections WHERE id = ?
+	`, connectionID).Scan(&maxDailyLoss, &maxExposure)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrNotFound
+	}
+	return maxDailyLoss, maxExposure, err
+}
+
+// ConnectionIsPaper reports whether a connection trades on paper
+// (cross-user; used by the executor to route its orders).
+func (d *Database) ConnectionIsPaper(ctx context.Context, connectionID string) (bool, error) {
+	var paper bool
+	err := d.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(paper, 0) FROM connections WHERE id = ?
+	`, connectionID).Scan(&paper)
+	if err == sql.ErrNoRows {
+		return false, ErrNotFound
+	}
+	return paper, err
+}
+
+// LeaderboardEntry is an opted-in user's first and last equity in a period.
+type LeaderboardEntry struct {
+	UserID      string
+	DisplayName string
+	StartEquity float64
+	EndEquity   float64
+}
+
+// ListLeaderboardEntries returns every user who opted into the leaderboard and
+// has equity snapshots within [from, to] (cross-user; used by the leaderboard).
+func (d *Database) ListLeaderboardEntries(ctx context.Context, from, to time.Time) ([]LeaderboardEntry, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT u.id, COALESCE(u.display_name, ''),
+			(SELECT e.equity FROM equity_snapshots e
+				WHERE e.user_id = u.id AND e.created_at >= ? AND e.created_at <= ?
+				ORDER BY e.created_at ASC, e.id ASC LIMIT 1),
+			(SELECT e.equity FROM equity_snapshots e
+				WHERE e.user_id = u.id AND e.created_at >= ? AND e.created_at <= ?
+				ORDER BY e.created_at DESC, e.id DESC LIMIT 1)
+		FROM users u
+		WHERE COALESCE(u.leaderboard_opt_in, 0) = 1
+	`, from.UTC(), to.UTC(), from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []LeaderboardEntry
+	for rows.Next() {
+		var e LeaderboardEntry
+		var start, end sql.NullFloat64
+		if err := rows.Scan(&e.UserID, &e.DisplayName, &start, &end); err != nil {
+			return nil, err
+		}
+		if !start.Valid || !end.Valid {
+			continue
+		}
+		e.StartEquity, e.EndEquity = start.Float64, end.Float64
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
 // DeactivateConnection marks a connection as inactive for a user.
 func (d *Database) DeactivateConnection(ctx context.Context, id, userID string) error {
 	res, err := d.DB.ExecContext(ctx, `
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// OrderJobStore holds the shared order queue. *Database keeps it in the
+// trading database's order_jobs table, which only processes on one host can
+// share; PostgresOrderJobs serves several hosts.
+type OrderJobStore interface {
+	EnqueueOrderJob(ctx context.Context, j OrderJob) (bool, error)
+	ClaimOrderJob(ctx context.Context, owner string, now time.Time, lease time.Duration) (*OrderJob, error)
+	ExtendOrderJob(ctx context.Context, orderID, owner string, until time.Time) (bool, error)
+	AckOrderJob(ctx context.Context, orderID, owner string) (bool, error)
+	OrderJobStats(ctx context.Context) (int, float64, error)
+}
+
+// OrderJob is an order waiting in the shared order_jobs queue. Any number of
+// processes may enqueue into and claim from the same table.
+type OrderJob struct {
+	OrderID  string
+	Payload  []byte // JSON-encoded order
+	Notional float64
+	Attempts int
+}
+
+// EnqueueOrderJob adds a job, visible immediately. Re-enqueueing an order ID
+// that is still queued is a no-op and reports false.
+func (d *Database) EnqueueOrderJob(ctx context.Context, j OrderJob) (bool, error) {
+	res, err := d.DB.ExecContext(ctx, `
+		INSERT OR IGNORE INTO order_jobs (order_id, payload, notional)
+		VALUES (?, ?, ?)
+	`, j.OrderID, string(j.Payload), j.Notional)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ClaimOrderJob leases the oldest visible job to owner until now+lease and
+// returns it, or nil when nothing is visible. The claim is one UPDATE, so two
+// processes can never lease the same job at once; a job whose lease expires
+// without an ack becomes visible again (at-least-once delivery).
+func (d *Database) ClaimOrderJob(ctx context.Context, owner string, now time.Time, lease time.Duration) (*OrderJob, error) {
+	var (
+		j       OrderJob
+		payload string
+	)
+	err := d.DB.QueryRowContext(ctx, `
+		UPDATE order_jobs
+		SET lease_owner = ?, visible_at = ?, attempts = attempts + 1
+		WHERE seq = (
+			SELECT seq FROM order_jobs WHERE visible_at <= ? ORDER BY seq LIMIT 1
+		)
+		RETURNING order_id, payload, notional, attempts
+	`, owner, now.Add(lease).UnixMilli(), now.UnixMilli()).Scan(&j.OrderID, &payload, &j.Notional, &j.Attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.Payload = []byte(payload)
+	return &j, nil
+}
+
+// ExtendOrderJob pushes the lease owner holds on a job out to until. It
+// reports false when the lease was lost.
+func (d *Database) ExtendOrderJob(ctx context.Context, orderID, owner string, until time.Time) (bool, error) {
+	res, err := d.DB.ExecContext(ctx, `
+		UPDATE order_jobs SET visible_at = ? WHERE order_id = ? AND lease_owner = ?
+	`, until.UnixMilli(), orderID, owner)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// AckOrderJob removes a job leased by owner. It reports false when the lease
+// was lost (expired and re-claimed by another consumer).
+func (d *Database) AckOrderJob(ctx context.Context, orderID, owner string) (bool, error) {
+	res, err := d.DB.ExecContext(ctx, `
+		DELETE FROM order_jobs WHERE order_id = ? AND lease_owner = ?
+	`, orderID, owner)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// OrderJobStats returns the number of queued jobs (leased or not) and their
+// total notional.
+func (d *Database) OrderJobStats(ctx context.Context) (int, float64, error) {
+	var (
+		count    int
+		notional float64
+	)
+	err := d.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(notional), 0) FROM order_jobs
+	`).Scan(&count, &notional)
+	return count, notional, err
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Order statuses as stored in the orders table (Binance spelling).
+const (
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusPendingCancel   = "PENDING_CANCEL"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusCanceled        = "CANCELED"
+	OrderStatusRejected        = "REJECTED"
+	OrderStatusExpired         = "EXPIRED"
+)
+
+// ErrInvalidOrderTransition is returned when a status update would move an
+// order backwards or out of a terminal state (e.g. FILLED -> NEW).
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderTransitions lists the statuses each status may move to. Terminal
+// statuses have no entry. Re-applying the current status is always allowed so
+// duplicate exchange events stay harmless.
+var orderTransitions = map[string][]string{
+	OrderStatusNew: {
+		OrderStatusPartiallyFilled, OrderStatusPendingCancel, OrderStatusFilled,
+		OrderStatusCanceled, OrderStatusRejected, OrderStatusExpired,
+	},
+	OrderStatusPartiallyFilled: {
+		OrderStatusPendingCancel, OrderStatusFilled, OrderStatusCanceled, OrderStatusExpired,
+	},
+	OrderStatusPendingCancel: {
+		OrderStatusPartiallyFilled, OrderStatusFilled, OrderStatusCanceled, OrderStatusExpired,
+	},
+}
+
+// NormalizeOrderStatus maps exchange and legacy spellings onto the stored set.
+func NormalizeOrderStatus(status string) string {
+	switch s := strings.ToUpper(strings.TrimSpace(status)); s {
+	case "CANCELLED":
+		return OrderStatusCanceled
+	case "PARTIAL":
+		return OrderStatusPartiallyFilled
+	case "EXPIRED_IN_MATCH":
+		return OrderStatusExpired
+	default:
+		return s
+	}
+}
+
+// IsTerminalOrderStatus reports whether no further transitions are allowed.
+func IsTerminalOrderStatus(status string) bool {
+	switch NormalizeOrderStatus(status) {
+	case OrderStatusFilled, OrderStatusCanceled, OrderStatusRejected, OrderStatusExpired:
+		return true
+	}
+	return false
+}
+
+// ValidOrderTransition reports whether an order may move from one status to another.
+func ValidOrderTransition(from, to string) bool {
+	from, to = NormalizeOrderStatus(from), NormalizeOrderStatus(to)
+	if from == to {
+		return IsTerminalOrderStatus(to) || orderTransitions[to] != nil
+	}
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionOrder validates and applies a status change inside tx; set holds the
+// extra column assignments (after status) and args their values. Unknown order
+// IDs are a no-op, matching the previous unconditional UPDATE.
+func transitionOrder(ctx context.Context, tx *sql.Tx, id, status, set string, args ...any) error {
+	var current string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	status = NormalizeOrderStatus(status)
+	if !ValidOrderTransition(current, status) {
+		log.Printf("⚠️ order %s: rejected status transition %s -> %s", id, current, status)
+		return fmt.Errorf("%w: order %s %s -> %s", ErrInvalidOrderTransition, id, current, status)
+	}
+
+	query := `UPDATE orders SET status = ?` + set + ` WHERE id = ?`
+	params := append(append([]any{status}, args...), id)
+	_, err = tx.ExecContext(ctx, query, params...)
+	return err
+}
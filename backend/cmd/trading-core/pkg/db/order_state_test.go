@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFilledOrderCannotMoveBackToNew(t *testing.T) {
+	database, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer database.Close()
+	if err := ApplyMigrations(database); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := database.CreateOrder(ctx, Order{ID: "o1", Symbol: "BTCUSDT", Side: "BUY", Qty: 1, Status: OrderStatusNew}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := database.UpdateOrderFill(ctx, "o1", OrderStatusPartiallyFilled, 0.5, 100); err != nil {
+		t.Fatalf("NEW -> PARTIALLY_FILLED: %v", err)
+	}
+	if err := database.UpdateOrderFill(ctx, "o1", OrderStatusFilled, 1, 100); err != nil {
+		t.Fatalf("PARTIALLY_FILLED -> FILLED: %v", err)
+	}
+	// Duplicate fill events are tolerated.
+	if err := database.UpdateOrderStatus(ctx, "o1", OrderStatusFilled); err != nil {
+		t.Fatalf("FILLED -> FILLED: %v", err)
+	}
+
+	err = database.UpdateOrderStatus(ctx, "o1", OrderStatusNew)
+	if !errors.Is(err, ErrInvalidOrderTransition) {
+		t.Fatalf("FILLED -> NEW: expected ErrInvalidOrderTransition, got %v", err)
+	}
+
+	var status string
+	var filled float64
+	if err := database.DB.QueryRow(`SELECT status, filled_qty FROM orders WHERE id = ?`, "o1").Scan(&status, &filled); err != nil {
+		t.Fatalf("read order: %v", err)
+	}
+	if status != OrderStatusFilled || filled != 1 {
+		t.Fatalf("order = %s/%v, want FILLED/1", status, filled)
+	}
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// OrderWALRecord is one entry of the order queue's write-ahead log when it is
+// kept in the database instead of a local file.
+type OrderWALRecord struct {
+	Action    string // "ENQUEUE" or "COMPLETE"
+	OrderID   string
+	Payload   []byte // JSON-encoded entry
+	CreatedAt time.Time
+}
+
+// AppendOrderWAL appends a record to the order WAL.
+func (d *Database) AppendOrderWAL(ctx context.Context, r OrderWALRecord) error {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT INTO order_wal (action, order_id, payload, created_at)
+		VALUES (?, ?, ?, ?)
+	`, r.Action, r.OrderID, string(r.Payload), r.CreatedAt.UTC())
+	return err
+}
+
+// ListOrderWAL returns the order WAL in append order.
+func (d *Database) ListOrderWAL(ctx context.Context) ([]OrderWALRecord, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT action, order_id, payload, created_at FROM order_wal ORDER BY seq
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []OrderWALRecord
+	for rows.Next() {
+		var (
+			r       OrderWALRecord
+			payload string
+		)
+		if err := rows.Scan(&r.Action, &r.OrderID, &payload, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		r.Payload = []byte(payload)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// ReplaceOrderWAL atomically replaces the order WAL with records (compaction).
+func (d *Database) ReplaceOrderWAL(ctx context.Context, records []OrderWALRecord) error {
+	return d.WithTx(ctx, func(tx *Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM order_wal`); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO order_wal (action, order_id, payload, created_at)
+				VALUES (?, ?, ?, ?)
+			`, r.Action, r.OrderID, string(r.Payload), r.CreatedAt.UTC()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AcquireOrderWALLease takes or renews the single lease on the order WAL for
+// owner until until. It reports false while another owner's lease has not
+// expired at now.
+func (d *Database) AcquireOrderWALLease(ctx context.Context, owner string, now, until time.Time) (bool, error) {
+	res, err := d.DB.ExecContext(ctx, `
+		INSERT INTO order_wal_lease (id, owner, expires_at) VALUES (1, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE order_wal_lease.owner = excluded.owner OR order_wal_lease.expires_at <= ?
+	`, owner, until.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseOrderWALLease drops the lease on the order WAL if owner holds it.
+func (d *Database) ReleaseOrderWALLease(ctx context.Context, owner string) error {
+	_, err := d.DB.ExecContext(ctx, `DELETE FROM order_wal_lease WHERE owner = ?`, owner)
+	return err
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"trading-core/pkg/money"
+)
+
+// DailyRealizedPnL is realized PnL (net of fees) booked on a single UTC day.
+type DailyRealizedPnL struct {
+	Date string
+	PnL  float64
+}
+
+// RealizedPnL returns (exit - entry) * qty computed in fixed point, so the
+// result is exact to 1e-8 regardless of float rounding in the inputs.
+func RealizedPnL(entry, exit, qty float64) float64 {
+	return money.FromFloat(exit).Sub(money.FromFloat(entry)).Mul(money.FromFloat(qty)).Float64()
+}
+
+// applyAverageCostFill applies a fill to an average-cost position and returns the
+// resulting position plus the PnL realized on any closed quantity.
+// Long and short positions are both supported; a fill that flips the position
+// closes the old side first and opens the remainder at the fill price.
+func applyAverageCostFill(qty, avgPrice float64, side string, fillQty, price float64) (newQty, newAvg, realized float64) {
+	signed := fillQty
+	switch strings.ToUpper(side) {
+	case "BUY":
+	case "SELL":
+		signed = -fillQty
+	default:
+		return qty, avgPrice, 0
+	}
+
+	newQty = qty + signed
+	switch {
+	case qty == 0 || (qty > 0) == (signed > 0):
+		// Opening or adding to the same side: weighted average entry.
+		total := math.Abs(qty) + fillQty
+		newAvg = (math.Abs(qty)*avgPrice + fillQty*price) / total
+	default:
+		// Reducing, closing or flipping: book PnL on the closed quantity.
+		closeQty := math.Min(math.Abs(qty), fillQty)
+		if qty > 0 {
+			realized = RealizedPnL(avgPrice, price, closeQty)
+		} else {
+			realized = RealizedPnL(price, avgPrice, closeQty)
+		}
+		newAvg = avgPrice
+		if (newQty > 0) != (qty > 0) {
+			newAvg = price // flipped: remainder opened at the fill price
+		}
+	}
+
+	if math.Abs(newQty) < 1e-9 {
+		// Position essentially closed, reset to avoid float precision issues
+		newQty = 0
+		newAvg = 0
+	}
+	return newQty, newAvg, realized
+}
+
+// StrategyDailyRealizedPnL replays a strategy's fills with average-cost accounting
+// and returns realized PnL (net of fees) per UTC day within [from, to], oldest first.
+// Fills before from are replayed to establish the entry price but are not reported.
+func (d *Database) StrategyDailyRealizedPnL(ctx context.Context, strategyID string, from, to time.Time) ([]DailyRealizedPnL, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(t.side, ''), o.side), t.qty, t.price, COALESCE(t.fee, 0), t.created_at
+		FROM trades t
+		JOIN orders o ON t.order_id = o.id
+		WHERE o.strategy_instance_id = ?
+		ORDER BY t.created_at ASC, t.rowid ASC
+	`, strategyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		daily    []DailyRealizedPnL
+		dayPnL   money.Amount
+		qty, avg float64
+	)
+	for rows.Next() {
+		var (
+			side           string
+			fillQty, price float64
+			fee            float64
+			createdAt      time.Time
+			realized       float64
+		)
+		if err := rows.Scan(&side, &fillQty, &price, &fee, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.After(to) {
+			break
+		}
+		qty, avg, realized = applyAverageCostFill(qty, avg, side, fillQty, price)
+		if createdAt.Before(from) {
+			continue
+		}
+
+		day := createdAt.UTC().Format("2006-01-02")
+		net := money.FromFloat(realized).Sub(money.FromFloat(fee))
+		if n := len(daily); n > 0 && daily[n-1].Date == day {
+			dayPnL = dayPnL.Add(net)
+			daily[n-1].PnL = dayPnL.Float64()
+		} else {
+			dayPnL = net
+			daily = append(daily, DailyRealizedPnL{Date: day, PnL: net.Float64()})
+		}
+	}
+	return daily, rows.Err()
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"trading-core/pkg/money"
+)
+
+// Income types stored in income_records (exchange naming).
+const (
+	IncomeFundingFee = "FUNDING_FEE"
+)
+
+// IncomeRecord is a cash flow booked by the exchange outside of trades (e.g. a
+// funding payment), attributed to the strategy that held the position.
+// Amount is in the reporting currency with the exchange's sign: negative when
+// paid, positive when received. One exchange record (SourceID) may be split
+// into several IncomeRecords when more than one strategy held the symbol.
+type IncomeRecord struct {
+	ID                 string
+	SourceID           string
+	UserID             string
+	StrategyInstanceID string
+	ConnectionID       string
+	Symbol             string
+	IncomeType         string
+	Amount             float64
+	Asset              string
+	CreatedAt          time.Time
+}
+
+// CreateIncomeRecord stores r. Records are keyed by ID, so re-importing the
+// same exchange record is a no-op and reports false.
+func (d *Database) CreateIncomeRecord(ctx context.Context, r IncomeRecord) (bool, error) {
+	return createIncomeRecord(ctx, d.DB, r)
+}
+
+// CreateIncomeRecord stores r within the transaction (see Database.CreateIncomeRecord).
+func (t *Tx) CreateIncomeRecord(ctx context.Context, r IncomeRecord) (bool, error) {
+	return createIncomeRecord(ctx, t.Tx, r)
+}
+
+func createIncomeRecord(ctx context.Context, ex execer, r IncomeRecord) (bool, error) {
+	res, err := ex.ExecContext(ctx, `
+		INSERT OR IGNORE INTO income_records (
+			id, source_id, user_id, strategy_instance_id, connection_id, symbol, income_type, amount, asset, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ID, r.SourceID, r.UserID, r.StrategyInstanceID, r.ConnectionID,
+		strings.ToUpper(r.Symbol), r.IncomeType, r.Amount, r.Asset, r.CreatedAt.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// IncomeSourceImported reports whether any record of exchange record sourceID
+// is stored.
+func (d *Database) IncomeSourceImported(ctx context.Context, sourceID string) (bool, error) {
+	var n int
+	err := d.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM income_records WHERE source_id = ?`, sourceID).Scan(&n)
+	return n > 0, err
+}
+
+// StrategyHolding is a strategy's open position on a symbol.
+type StrategyHolding struct {
+	StrategyInstanceID string
+	UserID             string
+	Qty                float64
+}
+
+// StrategyHoldings returns the strategies with an open position on symbol that
+// trade through connectionID ("" for the default gateway).
+func (d *Database) StrategyHoldings(ctx context.Context, symbol, connectionID string) ([]StrategyHolding, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT sp.strategy_instance_id, COALESCE(si.user_id, ''), sp.qty
+		FROM strategy_positions sp
+		JOIN strategy_instances si ON si.id = sp.strategy_instance_id
+		WHERE UPPER(sp.symbol) = ? AND sp.qty != 0 AND COALESCE(si.connection_id, '') = ?
+		ORDER BY sp.strategy_instance_id
+	`, strings.ToUpper(symbol), connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []StrategyHolding
+	for rows.Next() {
+		var h StrategyHolding
+		if err := rows.Scan(&h.StrategyInstanceID, &h.UserID, &h.Qty); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// PnLBreakdown decomposes a strategy's PnL over a range. Funding is the net
+// funding cost (positive when paid), so Net = Gross - Fees - Funding.
+// StrategyID is "" for orders placed outside any strategy.
+type PnLBreakdown struct {
+	StrategyID string
+	Gross      float64
+	Fees       float64
+	Funding    float64
+	Net        float64
+	Trades     int
+}
+
+// PnLAttribution returns the PnL breakdown of userID's strategies within
+// [from, to], one entry per strategy sorted by strategy ID. A non-empty
+// strategyID limits it to that strategy. Gross is realized with average-cost
+// accounting per strategy and symbol; fills before from are replayed for the
+// entry price but not reported.
+func (d *Database) PnLAttribution(ctx context.Context, userID, strategyID string, from, to time.Time) ([]PnLBreakdown, error) {
+	type acc struct {
+		gross, fees, funding money.Amount
+		trades               int
+	}
+	byStrategy := make(map[string]*acc)
+	get := func(id string) *acc {
+		a, ok := byStrategy[id]
+		if !ok {
+			a = &acc{}
+			byStrategy[id] = a
+		}
+		return a
+	}
+
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT COALESCE(o.strategy_instance_id, ''), t.symbol, COALESCE(NULLIF(t.side, ''), o.side),
+		       t.qty, t.price, COALESCE(t.fee, 0), t.created_at
+		FROM trades t
+		JOIN orders o ON t.order_id = o.id
+		WHERE COALESCE(NULLIF(t.user_id, ''), o.user_id, '') = ?
+		  AND (? = '' OR o.strategy_instance_id = ?)
+		ORDER BY t.created_at ASC, t.rowid ASC
+	`, userID, strategyID, strategyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type position struct{ qty, avg float64 }
+	positions := make(map[[2]string]position)
+	for rows.Next() {
+		var (
+			sid, symbol, side string
+			fillQty, price    float64
+			fee               float64
+			createdAt         time.Time
+		)
+		if err := rows.Scan(&sid, &symbol, &side, &fillQty, &price, &fee, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt.After(to) {
+			break
+		}
+		key := [2]string{sid, strings.ToUpper(symbol)}
+		p := positions[key]
+		var realized float64
+		p.qty, p.avg, realized = applyAverageCostFill(p.qty, p.avg, side, fillQty, price)
+		positions[key] = p
+		if createdAt.Before(from) {
+			continue
+		}
+		a := get(sid)
+		a.gross = a.gross.Add(money.FromFloat(realized))
+		a.fees = a.fees.Add(money.FromFloat(fee))
+		a.trades++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	incRows, err := d.DB.QueryContext(ctx, `
+		SELECT COALESCE(strategy_instance_id, ''), amount
+		FROM income_records
+		WHERE user_id = ? AND income_type = ?
+		  AND (? = '' OR strategy_instance_id = ?)
+		  AND created_at >= ? AND created_at <= ?
+	`, userID, IncomeFundingFee, strategyID, strategyID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer incRows.Close()
+	for incRows.Next() {
+		var (
+			sid    string
+			amount float64
+		)
+		if err := incRows.Scan(&sid, &amount); err != nil {
+			return nil, err
+		}
+		a := get(sid)
+		// Paid funding is booked negative by the exchange; report it as a cost.
+		a.funding = a.funding.Sub(money.FromFloat(amount))
+	}
+	if err := incRows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]PnLBreakdown, 0, len(byStrategy))
+	for sid, a := range byStrategy {
+		out = append(out, PnLBreakdown{
+			StrategyID: sid,
+			Gross:      a.gross.Float64(),
+			Fees:       a.fees.Float64(),
+			Funding:    a.funding.Float64(),
+			Net:        a.gross.Sub(a.fees).Sub(a.funding).Float64(),
+			Trades:     a.trades,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StrategyID < out[j].StrategyID })
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func insertStrategyFill(t *testing.T, d *Database, strategyID, id, side string, qty, price, fee float64, at time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	if err := d.CreateOrder(ctx, Order{
+		ID: id, StrategyInstanceID: strategyID, Symbol: "BTCUSDT", Side: side,
+		Price: price, Qty: qty, FilledQty: qty, Status: "FILLED", CreatedAt: at,
+	}); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if err := d.CreateTrade(ctx, Trade{
+		ID: "t-" + id, OrderID: id, Symbol: "BTCUSDT", Side: side,
+		Price: price, Qty: qty, Fee: fee, CreatedAt: at,
+	}); err != nil {
+		t.Fatalf("CreateTrade: %v", err)
+	}
+}
+
+func TestStrategyDailyRealizedPnLAcrossMidnight(t *testing.T) {
+	database, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer database.Close()
+	if err := ApplyMigrations(database); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	day1 := time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := time.Date(2025, 3, 2, 1, 0, 0, 0, time.UTC)
+	insertStrategyFill(t, database, "s1", "o1", "BUY", 1, 100, 0, day1)
+	insertStrategyFill(t, database, "s1", "o2", "SELL", 1, 110, 0, day2)
+
+	daily, err := database.StrategyDailyRealizedPnL(context.Background(), "s1",
+		time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("StrategyDailyRealizedPnL: %v", err)
+	}
+	if len(daily) != 2 {
+		t.Fatalf("expected 2 days, got %+v", daily)
+	}
+	if daily[0].Date != "2025-03-01" || daily[0].PnL != 0 {
+		t.Errorf("opening day should book no realized PnL, got %+v", daily[0])
+	}
+	if daily[1].Date != "2025-03-02" || math.Abs(daily[1].PnL-10) > 1e-9 {
+		t.Errorf("closing day should book 10, got %+v", daily[1])
+	}
+
+	// Range starting after the entry still uses the carried average price.
+	daily, err = database.StrategyDailyRealizedPnL(context.Background(), "s1",
+		time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("StrategyDailyRealizedPnL: %v", err)
+	}
+	if len(daily) != 1 || math.Abs(daily[0].PnL-10) > 1e-9 {
+		t.Errorf("expected single day with PnL 10, got %+v", daily)
+	}
+}
+
+func TestStrategyDailyRealizedPnLShort(t *testing.T) {
+	database, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer database.Close()
+	if err := ApplyMigrations(database); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	insertStrategyFill(t, database, "s2", "o1", "SELL", 2, 100, 0.5, at)
+	insertStrategyFill(t, database, "s2", "o2", "BUY", 2, 90, 0.5, at.Add(time.Hour))
+
+	daily, err := database.StrategyDailyRealizedPnL(context.Background(), "s2", at.Add(-time.Hour), at.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("StrategyDailyRealizedPnL: %v", err)
+	}
+	// Short 2 @ 100 covered @ 90 = +20, minus 1.0 in fees.
+	if len(daily) != 1 || math.Abs(daily[0].PnL-19) > 1e-9 {
+		t.Fatalf("expected 19 realized on short cover, got %+v", daily)
+	}
+}
+
+func TestPnLAttributionNetsFeesAndFunding(t *testing.T) {
+	database, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer database.Close()
+	if err := ApplyMigrations(database); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	fill := func(strategyID, id, side string, qty, price, fee float64, at time.Time) {
+		t.Helper()
+		if err := database.CreateOrder(ctx, Order{
+			ID: id, StrategyInstanceID: strategyID, Symbol: "BTCUSDT", Side: side, UserID: "u1",
+			Price: price, Qty: qty, FilledQty: qty, Status: "FILLED", CreatedAt: at,
+		}); err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		if err := database.CreateTrade(ctx, Trade{
+			ID: "t-" + id, OrderID: id, Symbol: "BTCUSDT", Side: side, UserID: "u1",
+			Price: price, Qty: qty, Fee: fee, CreatedAt: at,
+		}); err != nil {
+			t.Fatalf("CreateTrade: %v", err)
+		}
+	}
+	funding := func(id, strategyID string, amount float64, at time.Time) {
+		t.Helper()
+		if _, err := database.CreateIncomeRecord(ctx, IncomeRecord{
+			ID: id, SourceID: id, UserID: "u1", StrategyInstanceID: strategyID, Symbol: "BTCUSDT",
+			IncomeType: IncomeFundingFee, Amount: amount, Asset: "USDT", CreatedAt: at,
+		}); err != nil {
+			t.Fatalf("CreateIncomeRecord: %v", err)
+		}
+	}
+
+	at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	// s1: long 2 @ 100, sell 2 @ 110 (+20 gross), 1.5 fees, paid 0.7 and received 0.2 funding.
+	fill("s1", "a1", "BUY", 2, 100, 0.8, at.Add(time.Hour))
+	fill("s1", "a2", "SELL", 2, 110, 0.7, at.Add(10*time.Hour))
+	funding("f1", "s1", -0.7, at.Add(8*time.Hour))
+	funding("f2", "s1", 0.2, at.Add(9*time.Hour))
+	// Re-importing the same exchange record must not double count.
+	if ok, err := database.CreateIncomeRecord(ctx, IncomeRecord{
+		ID: "f1", SourceID: "f1", UserID: "u1", StrategyInstanceID: "s1", Symbol: "BTCUSDT",
+		IncomeType: IncomeFundingFee, Amount: -0.7, CreatedAt: at.Add(8 * time.Hour),
+	}); err != nil || ok {
+		t.Fatalf("duplicate income record inserted=%v err=%v", ok, err)
+	}
+	// s2: short 1 @ 100 covered @ 105 (-5 gross), 0.2 fees, received 0.3 funding.
+	fill("s2", "b1", "SELL", 1, 100, 0.1, at.Add(2*time.Hour))
+	fill("s2", "b2", "BUY", 1, 105, 0.1, at.Add(12*time.Hour))
+	funding("f3", "s2", 0.3, at.Add(8*time.Hour))
+	// Outside the range: ignored.
+	funding("f4", "s1", -5, at.Add(48*time.Hour))
+
+	got, err := database.PnLAttribution(ctx, "u1", "", at, at.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("PnLAttribution: %v", err)
+	}
+	want := []PnLBreakdown{
+		{StrategyID: "s1", Gross: 20, Fees: 1.5, Funding: 0.5, Trades: 2},
+		{StrategyID: "s2", Gross: -5, Fees: 0.2, Funding: -0.3, Trades: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %d strategies", got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.StrategyID != w.StrategyID || g.Trades != w.Trades ||
+			math.Abs(g.Gross-w.Gross) > 1e-9 || math.Abs(g.Fees-w.Fees) > 1e-9 || math.Abs(g.Funding-w.Funding) > 1e-9 {
+			t.Errorf("strategy %d = %+v, want %+v", i, g, w)
+		}
+		if math.Abs(g.Net-(g.Gross-g.Fees-g.Funding)) > 1e-9 {
+			t.Errorf("%s: net %v != gross - fees - funding (%v)", g.StrategyID, g.Net, g.Gross-g.Fees-g.Funding)
+		}
+	}
+
+	// Other users see nothing; a strategy filter narrows to that strategy.
+	if other, err := database.PnLAttribution(ctx, "u2", "", at, at.Add(24*time.Hour)); err != nil || len(other) != 0 {
+		t.Errorf("other user got %+v err=%v", other, err)
+	}
+	only, err := database.PnLAttribution(ctx, "u1", "s2", at, at.Add(24*time.Hour))
+	if err != nil || len(only) != 1 || only[0].StrategyID != "s2" {
+		t.Errorf("strategy filter got %+v err=%v", only, err)
+	}
+}
@@ -6,14 +6,21 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
 var (
 	ErrUserIDRequired = errors.New("user_id is required for data isolation")
 	ErrNotFound       = errors.New("record not found")
+	ErrDuplicate      = errors.New("record already exists")
 )
 
+// isUniqueViolation reports whether err is SQLite rejecting a duplicate key.
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
 // UserQueries provides user-isolated database queries.
 type UserQueries struct {
 	db *sql.DB
@@ -80,18 +87,34 @@ func (q *UserQueries) UpsertPositionWithUser(ctx context.Context, userID, symbol
 
 // GetOrdersByUser returns orders for a specific user.
 func (q *UserQueries) GetOrdersByUser(ctx context.Context, userID string, limit int) ([]Order, error) {
+	return q.ListOrdersByUser(ctx, userID, ListFilter{Limit: limit})
+}
+
+// ListFilter narrows order and trade listings.
+type ListFilter struct {
+	Limit int
+	Tag   string // only orders carrying this journal tag (and their trades)
+}
+
+// tagMatch matches rows whose comma-separated tags column contains the filter
+// tag; it is bound twice (empty tag matches everything).
+const tagMatch = `(? = '' OR instr(',' || COALESCE(%s, '') || ',', ',' || ? || ',') > 0)`
+
+// ListOrdersByUser returns a user's most recent orders, optionally by tag.
+func (q *UserQueries) ListOrdersByUser(ctx context.Context, userID string, f ListFilter) ([]Order, error) {
 	if userID == "" {
 		return nil, ErrUserIDRequired
 	}
 
 	rows, err := q.db.QueryContext(ctx, `
 		SELECT id, COALESCE(strategy_instance_id, ''), symbol, side, price, qty, 
-		       COALESCE(filled_qty, 0), status, COALESCE(user_id, ''), created_at
+		       COALESCE(filled_qty, 0), status, COALESCE(user_id, ''),
+		       COALESCE(note, ''), COALESCE(tags, ''), created_at
 		FROM orders
-		WHERE user_id = ?
-		ORDER BY created_at DESC
+		WHERE user_id = ? AND `+fmt.Sprintf(tagMatch, "tags")+`
+		ORDER BY created_at DESC, id DESC
 		LIMIT ?
-	`, userID, limit)
+	`, userID, f.Tag, f.Tag, f.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("query orders: %w", err)
 	}
@@ -100,9 +123,11 @@ func (q *UserQueries) GetOrdersByUser(ctx context.Context, userID string, limit
 	var orders []Order
 	for rows.Next() {
 		var o Order
-		if err := rows.Scan(&o.ID, &o.StrategyInstanceID, &o.Symbol, &o.Side, &o.Price, &o.Qty, &o.FilledQty, &o.Status, &o.UserID, &o.CreatedAt); err != nil {
+		var tags string
+		if err := rows.Scan(&o.ID, &o.StrategyInstanceID, &o.Symbol, &o.Side, &o.Price, &o.Qty, &o.FilledQty, &o.Status, &o.UserID, &o.Note, &tags, &o.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan order: %w", err)
 		}
+		o.Tags = SplitTags(tags)
 		orders = append(orders, o)
 	}
 	return orders, rows.Err()
@@ -120,7 +145,7 @@ func (q *UserQueries) GetOpenOrdersByUser(ctx context.Context, userID string) ([
 		FROM orders
 		WHERE user_id = ? 
 		  AND status IN ('NEW', 'PARTIALLY_FILLED')
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 	`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("query open orders: %w", err)
@@ -138,6 +163,33 @@ func (q *UserQueries) GetOpenOrdersByUser(ctx context.Context, userID string) ([
 	return orders, rows.Err()
 }
 
+// GetOrderByID returns an order, verifying user ownership.
+func (q *UserQueries) GetOrderByID(ctx context.Context, userID, orderID string) (*Order, error) {
+	if userID == "" {
+		return nil, ErrUserIDRequired
+	}
+
+	var o Order
+	var tags string
+	err := q.db.QueryRowContext(ctx, `
+		SELECT id, COALESCE(strategy_instance_id, ''), symbol, side, price, qty,
+		       COALESCE(filled_qty, 0), status, COALESCE(ref_price, 0), COALESCE(user_id, ''),
+		       COALESCE(note, ''), COALESCE(tags, ''), created_at
+		FROM orders
+		WHERE id = ? AND user_id = ?
+	`, orderID, userID).Scan(&o.ID, &o.StrategyInstanceID, &o.Symbol, &o.Side, &o.Price, &o.Qty,
+		&o.FilledQty, &o.Status, &o.RefPrice, &o.UserID, &o.Note, &tags, &o.CreatedAt)
+	o.Tags = SplitTags(tags)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query order: %w", err)
+	}
+	return &o, nil
+}
+
 // CreateOrderWithUser inserts a new order with user_id.
 func (q *UserQueries) CreateOrderWithUser(ctx context.Context, o Order) error {
 	if o.UserID == "" {
@@ -145,9 +197,9 @@ func (q *UserQueries) CreateOrderWithUser(ctx context.Context, o Order) error {
 	}
 
 	_, err := q.db.ExecContext(ctx, `
-		INSERT INTO orders (id, strategy_instance_id, symbol, side, price, qty, filled_qty, status, user_id, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
-	`, o.ID, o.StrategyInstanceID, o.Symbol, o.Side, o.Price, o.Qty, o.FilledQty, o.Status, o.UserID, o.CreatedAt)
+		INSERT INTO orders (id, strategy_instance_id, symbol, side, price, qty, filled_qty, status, ref_price, user_id, note, tags, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))
+	`, o.ID, o.StrategyInstanceID, o.Symbol, o.Side, o.Price, o.Qty, o.FilledQty, o.Status, o.RefPrice, o.UserID, o.Note, JoinTags(o.Tags), o.CreatedAt)
 
 	return err
 }
@@ -158,17 +210,53 @@ func (q *UserQueries) CreateOrderWithUser(ctx context.Context, o Order) error {
 
 // GetTradesByUser returns trades for a specific user.
 func (q *UserQueries) GetTradesByUser(ctx context.Context, userID string, limit int) ([]Trade, error) {
+	return q.ListTradesByUser(ctx, userID, ListFilter{Limit: limit})
+}
+
+// ListTradesByUser returns a user's most recent fills, optionally only those
+// of orders carrying a journal tag.
+func (q *UserQueries) ListTradesByUser(ctx context.Context, userID string, f ListFilter) ([]Trade, error) {
 	if userID == "" {
 		return nil, ErrUserIDRequired
 	}
 
 	rows, err := q.db.QueryContext(ctx, `
-		SELECT id, order_id, symbol, side, price, qty, COALESCE(fee, 0), COALESCE(user_id, ''), created_at
-		FROM trades
-		WHERE user_id = ?
-		ORDER BY created_at DESC
+		SELECT t.id, t.order_id, t.symbol, t.side, t.price, t.qty, COALESCE(t.fee, 0), COALESCE(t.fee_asset, ''),
+		       COALESCE(t.user_id, ''), t.created_at
+		FROM trades t
+		LEFT JOIN orders o ON o.id = t.order_id
+		WHERE t.user_id = ? AND `+fmt.Sprintf(tagMatch, "o.tags")+`
+		ORDER BY t.created_at DESC, t.id DESC
 		LIMIT ?
-	`, userID, limit)
+	`, userID, f.Tag, f.Tag, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query trades: %w", err)
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var t Trade
+		if err := rows.Scan(&t.ID, &t.OrderID, &t.Symbol, &t.Side, &t.Price, &t.Qty, &t.Fee, &t.FeeAsset, &t.UserID, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan trade: %w", err)
+		}
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+// GetTradesByOrder returns a user's fills for one order, oldest first.
+func (q *UserQueries) GetTradesByOrder(ctx context.Context, userID, orderID string) ([]Trade, error) {
+	if userID == "" {
+		return nil, ErrUserIDRequired
+	}
+
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT id, order_id, symbol, side, price, qty, COALESCE(fee, 0), COALESCE(fee_asset, ''), COALESCE(user_id, ''), created_at
+		FROM trades
+		WHERE order_id = ? AND user_id = ?
+		ORDER BY created_at ASC, id ASC
+	`, orderID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("query trades: %w", err)
 	}
@@ -177,7 +265,7 @@ func (q *UserQueries) GetTradesByUser(ctx context.Context, userID string, limit
 	var trades []Trade
 	for rows.Next() {
 		var t Trade
-		if err := rows.Scan(&t.ID, &t.OrderID, &t.Symbol, &t.Side, &t.Price, &t.Qty, &t.Fee, &t.UserID, &t.CreatedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.OrderID, &t.Symbol, &t.Side, &t.Price, &t.Qty, &t.Fee, &t.FeeAsset, &t.UserID, &t.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan trade: %w", err)
 		}
 		trades = append(trades, t)
@@ -213,7 +301,11 @@ func (q *UserQueries) GetConnectionsByUser(ctx context.Context, userID string) (
 		SELECT id, user_id, exchange_type, name, 
 		       COALESCE(api_key, ''), COALESCE(api_secret, ''),
 		       COALESCE(api_key_encrypted, ''), COALESCE(api_secret_encrypted, ''),
-		       COALESCE(key_version, 1), is_active, created_at, updated_at, last_rotated_at
+		       COALESCE(key_version, 1),
+		       COALESCE(maker_fee_bps, 0), COALESCE(taker_fee_bps, 0), COALESCE(bnb_discount, 0),
+		       COALESCE(capabilities, ''), COALESCE(leverage, 0),
+		       COALESCE(max_daily_loss, 0), COALESCE(max_exposure, 0), COALESCE(paper, 0),
+		       is_active, created_at, updated_at, last_rotated_at
 		FROM connections
 		WHERE user_id = ? AND is_active = 1
 		ORDER BY created_at DESC
@@ -228,7 +320,8 @@ func (q *UserQueries) GetConnectionsByUser(ctx context.Context, userID string) (
 		var c Connection
 		if err := rows.Scan(&c.ID, &c.UserID, &c.ExchangeType, &c.Name,
 			&c.APIKey, &c.APISecret, &c.APIKeyEncrypted, &c.APISecretEncrypted,
-			&c.KeyVersion, &c.IsActive, &c.CreatedAt, &c.UpdatedAt, &c.LastRotatedAt); err != nil {
+			&c.KeyVersion, &c.MakerFeeBps, &c.TakerFeeBps, &c.BNBDiscount, &c.Capabilities, &c.Leverage, &c.MaxDailyLoss, &c.MaxExposure, &c.Paper,
+			&c.IsActive, &c.CreatedAt, &c.UpdatedAt, &c.LastRotatedAt); err != nil {
 			return nil, fmt.Errorf("scan connection: %w", err)
 		}
 		conns = append(conns, c)
@@ -247,12 +340,17 @@ func (q *UserQueries) GetConnectionByID(ctx context.Context, userID, connectionI
 		SELECT id, user_id, exchange_type, name,
 		       COALESCE(api_key, ''), COALESCE(api_secret, ''),
 		       COALESCE(api_key_encrypted, ''), COALESCE(api_secret_encrypted, ''),
-		       COALESCE(key_version, 1), is_active, created_at, updated_at, last_rotated_at
+		       COALESCE(key_version, 1),
+		       COALESCE(maker_fee_bps, 0), COALESCE(taker_fee_bps, 0), COALESCE(bnb_discount, 0),
+		       COALESCE(capabilities, ''), COALESCE(leverage, 0),
+		       COALESCE(max_daily_loss, 0), COALESCE(max_exposure, 0), COALESCE(paper, 0),
+		       is_active, created_at, updated_at, last_rotated_at
 		FROM connections
 		WHERE id = ? AND user_id = ?
 	`, connectionID, userID).Scan(&c.ID, &c.UserID, &c.ExchangeType, &c.Name,
 		&c.APIKey, &c.APISecret, &c.APIKeyEncrypted, &c.APISecretEncrypted,
-		&c.KeyVersion, &c.IsActive, &c.CreatedAt, &c.UpdatedAt, &c.LastRotatedAt)
+		&c.KeyVersion, &c.MakerFeeBps, &c.TakerFeeBps, &c.BNBDiscount, &c.Capabilities, &c.Leverage, &c.MaxDailyLoss, &c.MaxExposure, &c.Paper,
+		&c.IsActive, &c.CreatedAt, &c.UpdatedAt, &c.LastRotatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -280,10 +378,346 @@ func (q *UserQueries) CreateConnectionEncrypted(ctx context.Context, c Connectio
 			id, user_id, exchange_type, name,
 			api_key, api_secret,
 			api_key_encrypted, api_secret_encrypted,
-			key_version, is_active, created_at, updated_at, last_rotated_at
+			key_version, maker_fee_bps, taker_fee_bps, bnb_discount, capabilities, leverage,
+			max_daily_loss, max_exposure, paper,
+			is_active, created_at, updated_at, last_rotated_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, COALESCE(?, CURRENT_TIMESTAMP))
-	`, c.ID, c.UserID, c.ExchangeType, c.Name, c.APIKey, c.APISecret, c.APIKeyEncrypted, c.APISecretEncrypted, c.KeyVersion, c.LastRotatedAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, COALESCE(?, CURRENT_TIMESTAMP))
+	`, c.ID, c.UserID, c.ExchangeType, c.Name, c.APIKey, c.APISecret, c.APIKeyEncrypted, c.APISecretEncrypted, c.KeyVersion,
+		c.MakerFeeBps, c.TakerFeeBps, c.BNBDiscount, c.Capabilities, c.Leverage,
+		c.MaxDailyLoss, c.MaxExposure, c.Paper, c.LastRotatedAt)
+
+	return err
+}
+
+// UpdateConnectionCapabilities stores the latest probed account permissions.
+func (q *UserQueries) UpdateConnectionCapabilities(ctx context.Context, userID, connectionID, capabilities string) error {
+	if userID == "" {
+		return ErrUserIDRequired
+	}
+
+	_, err := q.db.ExecContext(ctx, `
+		UPDATE connections SET capabilities = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+	`, capabilities, connectionID, userID)
+	return err
+}
+
+// ----------------------------------------
+// Equity Snapshot Queries
+// ----------------------------------------
+
+// CreateEquitySnapshot records an equity snapshot for a user.
+func (q *UserQueries) CreateEquitySnapshot(ctx context.Context, s EquitySnapshot) error {
+	if s.UserID == "" {
+		return ErrUserIDRequired
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO equity_snapshots (user_id, balance, position_value, equity, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, s.UserID, s.Balance, s.PositionValue, s.Equity, s.CreatedAt)
+	return err
+}
+
+// GetEquitySnapshotsByUser returns a user's equity snapshots within [from, to], oldest first.
+func (q *UserQueries) GetEquitySnapshotsByUser(ctx context.Context, userID string, from, to time.Time) ([]EquitySnapshot, error) {
+	if userID == "" {
+		return nil, ErrUserIDRequired
+	}
+
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT id, user_id, balance, position_value, equity, created_at
+		FROM equity_snapshots
+		WHERE user_id = ? AND created_at >= ? AND created_at <= ?
+		ORDER BY created_at ASC, id ASC
+	`, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query equity snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []EquitySnapshot
+	for rows.Next() {
+		var s EquitySnapshot
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Balance, &s.PositionValue, &s.Equity, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan equity snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// ----------------------------------------
+// Profile Queries
+// ----------------------------------------
+
+// UserProfile holds the user-editable public profile.
+type UserProfile struct {
+	DisplayName      string
+	LeaderboardOptIn bool
+}
+
+// GetUserProfile returns a user's public profile.
+func (q *UserQueries) GetUserProfile(ctx context.Context, userID string) (UserProfile, error) {
+	if userID == "" {
+		return UserProfile{}, ErrUserIDRequired
+	}
+
+	var p UserProfile
+	err := q.db.QueryRowContext(ctx, `
+		SELECT COALESCE(display_name, ''), COALESCE(leaderboard_opt_in, 0)
+		FROM users WHERE id = ?
+	`, userID).Scan(&p.DisplayName, &p.LeaderboardOptIn)
+	if err == sql.ErrNoRows {
+		return UserProfile{}, ErrNotFound
+	}
+	if err != nil {
+		return UserProfile{}, fmt.Errorf("query user profile: %w", err)
+	}
+	return p, nil
+}
+
+// SetUserProfile stores a user's public profile.
+func (q *UserQueries) SetUserProfile(ctx context.Context, userID string, p UserProfile) error {
+	if userID == "" {
+		return ErrUserIDRequired
+	}
+
+	res, err := q.db.ExecContext(ctx, `
+		UPDATE users SET display_name = ?, leaderboard_opt_in = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, p.DisplayName, p.LeaderboardOptIn, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ----------------------------------------
+// Resource Limit Queries
+// ----------------------------------------
+
+// UserLimits holds per-user caps; 0 means "use the global default".
+type UserLimits struct {
+	MaxStrategies  int
+	MaxConnections int
+	MaxLeverage    int // futures leverage ceiling enforced at order time
+	// MaxConcurrentPositions caps distinct symbols held at once (negative lifts the global cap)
+	MaxConcurrentPositions int
+	// AllowedSymbols are the only symbols the user's strategies may run on (empty = any)
+	AllowedSymbols []string
+}
+
+// AllowsSymbol reports whether symbol is in AllowedSymbols, or no allow-list is set.
+func (l UserLimits) AllowsSymbol(symbol string) bool {
+	if len(l.AllowedSymbols) == 0 {
+		return true
+	}
+	for _, s := range l.AllowedSymbols {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserLimits returns the admin-set limit overrides for a user.
+func (q *UserQueries) GetUserLimits(ctx context.Context, userID string) (UserLimits, error) {
+	if userID == "" {
+		return UserLimits{}, ErrUserIDRequired
+	}
+
+	var l UserLimits
+	var symbols string
+	err := q.db.QueryRowContext(ctx, `
+		SELECT COALESCE(max_strategies, 0), COALESCE(max_connections, 0), COALESCE(max_leverage, 0),
+		       COALESCE(max_concurrent_positions, 0), COALESCE(allowed_symbols, '')
+		FROM users WHERE id = ?
+	`, userID).Scan(&l.MaxStrategies, &l.MaxConnections, &l.MaxLeverage, &l.MaxConcurrentPositions, &symbols)
+	if err == sql.ErrNoRows {
+		return UserLimits{}, nil
+	}
+	if err != nil {
+		return UserLimits{}, fmt.Errorf("query user limits: %w", err)
+	}
+	for _, s := range strings.Split(symbols, ",") {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			l.AllowedSymbols = append(l.AllowedSymbols, s)
+		}
+	}
+	return l, nil
+}
+
+// SetUserLimits stores admin limit overrides for a user (0 = use the global default).
+func (q *UserQueries) SetUserLimits(ctx context.Context, userID string, l UserLimits) error {
+	if userID == "" {
+		return ErrUserIDRequired
+	}
+
+	res, err := q.db.ExecContext(ctx, `
+		UPDATE users SET max_strategies = ?, max_connections = ?, max_leverage = ?, max_concurrent_positions = ?,
+		    allowed_symbols = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, l.MaxStrategies, l.MaxConnections, l.MaxLeverage, l.MaxConcurrentPositions, strings.Join(l.AllowedSymbols, ","), userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CountStrategiesByUser counts a user's strategy instances that have not been stopped.
+func (q *UserQueries) CountStrategiesByUser(ctx context.Context, userID string) (int, error) {
+	if userID == "" {
+		return 0, ErrUserIDRequired
+	}
+
+	var n int
+	err := q.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM strategy_instances
+		WHERE user_id = ? AND COALESCE(status, 'ACTIVE') != 'STOPPED'
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// CountActiveConnectionsByUser counts a user's active exchange connections.
+func (q *UserQueries) CountActiveConnectionsByUser(ctx context.Context, userID string) (int, error) {
+	if userID == "" {
+		return 0, ErrUserIDRequired
+	}
+
+	var n int
+	err := q.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM connections WHERE user_id = ? AND is_active = 1
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// ----------------------------------------
+// API Key Queries
+// ----------------------------------------
+
+// APIKey is a user-generated key for programmatic access. Only the SHA-256
+// hash of the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	LastUsedAt time.Time // zero = never used
+	RevokedAt  time.Time // zero = active
+	CreatedAt  time.Time
+}
+
+// CreateAPIKey stores a new API key.
+func (q *UserQueries) CreateAPIKey(ctx context.Context, k APIKey) error {
+	if k.UserID == "" {
+		return ErrUserIDRequired
+	}
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now().UTC()
+	}
 
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, k.ID, k.UserID, k.Name, k.Prefix, k.KeyHash, JoinTags(k.Scopes), k.CreatedAt)
 	return err
 }
+
+// ListAPIKeysByUser returns a user's API keys, newest first, including revoked ones.
+func (q *UserQueries) ListAPIKeysByUser(ctx context.Context, userID string) ([]APIKey, error) {
+	if userID == "" {
+		return nil, ErrUserIDRequired
+	}
+
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT id, user_id, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at
+		FROM api_keys WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// GetActiveAPIKeyByHash returns the unrevoked key with the given hash.
+func (q *UserQueries) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	row := q.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at
+		FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL
+	`, keyHash)
+	k, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// RevokeAPIKey revokes one of the user's active keys.
+func (q *UserQueries) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	if userID == "" {
+		return ErrUserIDRequired
+	}
+
+	res, err := q.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, time.Now().UTC(), keyID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records that a key was just used.
+func (q *UserQueries) TouchAPIKey(ctx context.Context, keyID string) error {
+	_, err := q.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, time.Now().UTC(), keyID)
+	return err
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }) (APIKey, error) {
+	var (
+		k                 APIKey
+		scopes            string
+		lastUsed, revoked sql.NullTime
+	)
+	if err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, &scopes, &lastUsed, &revoked, &k.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKey{}, err
+		}
+		return APIKey{}, fmt.Errorf("scan api key: %w", err)
+	}
+	k.Scopes = SplitTags(scopes)
+	k.LastUsedAt = lastUsed.Time
+	k.RevokedAt = revoked.Time
+	return k, nil
+}
@@ -54,6 +54,17 @@ CREATE TABLE IF NOT EXISTS user_positions (
     PRIMARY KEY (symbol, user_id)
 );
 
+CREATE TABLE IF NOT EXISTS position_legs (
+    user_id TEXT NOT NULL DEFAULT '',
+    connection_id TEXT NOT NULL DEFAULT '',
+    symbol TEXT NOT NULL,
+    position_side TEXT NOT NULL,
+    qty REAL NOT NULL,
+    avg_price REAL NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, connection_id, symbol, position_side)
+);
+
 CREATE TABLE IF NOT EXISTS users (
     id TEXT PRIMARY KEY,
     email TEXT NOT NULL UNIQUE,
@@ -94,11 +105,39 @@ CREATE TABLE IF NOT EXISTS risk_configs (
     use_daily_loss_limit INTEGER DEFAULT 1,
     use_order_size_limits INTEGER DEFAULT 1,
     use_position_size_limit INTEGER DEFAULT 1,
+    symbol_position_limits TEXT,
+    correlation_groups TEXT,
+    sizing_mode TEXT DEFAULT 'fixed',
+    risk_per_trade REAL DEFAULT 0.01,
+    max_concurrent_positions INTEGER DEFAULT 0,
+    symbol_sltp TEXT,
     is_active INTEGER DEFAULT 1,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS connection_positions (
+    connection_id TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    qty REAL NOT NULL,
+    price REAL NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (connection_id, symbol)
+);
+
+CREATE TABLE IF NOT EXISTS connection_daily_losses (
+    connection_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    loss REAL NOT NULL,
+    PRIMARY KEY (connection_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS user_risk_configs (
+    user_id TEXT PRIMARY KEY,
+    config TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE IF NOT EXISTS risk_metrics (
     date TEXT PRIMARY KEY,
     daily_pnl REAL DEFAULT 0,
@@ -158,6 +197,100 @@ CREATE TABLE IF NOT EXISTS strategy_risk_configs (
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY(strategy_instance_id) REFERENCES strategy_instances(id)
 );
+
+CREATE TABLE IF NOT EXISTS equity_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    balance REAL NOT NULL DEFAULT 0,
+    position_value REAL NOT NULL DEFAULT 0,
+    equity REAL NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS symbol_halts (
+    symbol TEXT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    since DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS loss_streak_pauses (
+    strategy_id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL DEFAULT '',
+    losses INTEGER NOT NULL,
+    since DATETIME NOT NULL,
+    day TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS quarantined_fills (
+    fill_id TEXT PRIMARY KEY,
+    payload TEXT NOT NULL,
+    held_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_wal (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    action TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS order_wal_lease (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    owner TEXT NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ladder_tranches (
+    order_id TEXT PRIMARY KEY,
+    strategy_instance_id TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    due_at INTEGER NOT NULL,
+    placed INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_ladder_tranches_strategy ON ladder_tranches(strategy_instance_id);
+
+CREATE TABLE IF NOT EXISTS order_jobs (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id TEXT NOT NULL UNIQUE,
+    payload TEXT NOT NULL,
+    notional REAL NOT NULL DEFAULT 0,
+    lease_owner TEXT,
+    visible_at INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_order_jobs_visible ON order_jobs(visible_at, seq);
+
+CREATE TABLE IF NOT EXISTS income_records (
+    id TEXT PRIMARY KEY,
+    source_id TEXT NOT NULL,
+    user_id TEXT,
+    strategy_instance_id TEXT,
+    connection_id TEXT,
+    symbol TEXT NOT NULL,
+    income_type TEXT NOT NULL,
+    amount REAL NOT NULL,
+    asset TEXT,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_income_records_source ON income_records(source_id);
+CREATE INDEX IF NOT EXISTS idx_income_records_user_time ON income_records(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_income_records_strategy_time ON income_records(strategy_instance_id, created_at);
 `
 
 // ApplyMigrations bootstraps the schema; keep lightweight for fast startup.
@@ -192,6 +325,24 @@ func ApplyMigrations(d *Database) error {
 	if err := ensureColumn(d.DB, "risk_configs", "use_position_size_limit", "INTEGER DEFAULT 1"); err != nil {
 		return err
 	}
+	if err := ensureColumn(d.DB, "risk_configs", "symbol_position_limits", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "risk_configs", "correlation_groups", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "risk_configs", "symbol_sltp", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "risk_configs", "sizing_mode", "TEXT DEFAULT 'fixed'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "risk_configs", "risk_per_trade", "REAL DEFAULT 0.01"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "risk_configs", "max_concurrent_positions", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
 
 	// Advanced Strategy Features
 	if err := ensureColumn(d.DB, "strategy_instances", "status", "TEXT DEFAULT 'ACTIVE'"); err != nil {
@@ -229,6 +380,22 @@ func ApplyMigrations(d *Database) error {
 	if err := ensureColumn(d.DB, "strategy_instances", "profit_target_type", "TEXT DEFAULT 'USDT'"); err != nil {
 		return err
 	}
+	// Laddered entries/exits: one signal split into several orders (tranches <= 1 = off)
+	if err := ensureColumn(d.DB, "strategy_instances", "ladder_tranches", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "strategy_instances", "ladder_spacing_pct", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "strategy_instances", "ladder_interval_sec", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "strategy_instances", "ladder_distribution", "TEXT DEFAULT 'equal'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "strategy_instances", "ladder_apply_to", "TEXT DEFAULT 'entry'"); err != nil {
+		return err
+	}
 
 	// Phase 1 Multi-User: Encrypted API Keys
 	if err := ensureColumn(d.DB, "connections", "api_key_encrypted", "TEXT"); err != nil {
@@ -259,15 +426,151 @@ func ApplyMigrations(d *Database) error {
 		return err
 	}
 
+	// Per-connection fee schedule (0 = fall back to the global flat rate)
+	if err := ensureColumn(d.DB, "connections", "maker_fee_bps", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "connections", "taker_fee_bps", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "connections", "bnb_discount", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Per-user resource caps set by an admin (0 = use the global default)
+	if err := ensureColumn(d.DB, "users", "max_strategies", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "users", "max_connections", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	// Account permissions recorded by the capability probe ("" = not probed)
+	if err := ensureColumn(d.DB, "connections", "capabilities", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Futures leverage applied to a connection's orders (0 = leave the exchange setting)
+	if err := ensureColumn(d.DB, "connections", "leverage", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "users", "max_leverage", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "users", "max_concurrent_positions", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	// Comma-separated symbols a user's strategies may trade ("" = any symbol)
+	if err := ensureColumn(d.DB, "users", "allowed_symbols", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Per-connection risk budgets enforced by the risk manager (0 = no limit)
+	if err := ensureColumn(d.DB, "connections", "max_daily_loss", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "connections", "max_exposure", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Paper connections route orders through the dry runner even in live mode
+	if err := ensureColumn(d.DB, "connections", "paper", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	// Price an order was valued at when submitted, for slippage reporting (0 = unknown)
+	if err := ensureColumn(d.DB, "orders", "ref_price", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Trade-journal note and comma-separated tags on manual orders
+	if err := ensureColumn(d.DB, "orders", "note", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "orders", "tags", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Venue order ID, to cancel resting orders (e.g. ladder tranches)
+	if err := ensureColumn(d.DB, "orders", "exchange_order_id", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Hedge-mode position side (LONG/SHORT), so stream fills book to the right leg
+	if err := ensureColumn(d.DB, "orders", "position_side", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Per-strategy capital allocation enforced by the risk manager (0 = unlimited)
+	if err := ensureColumn(d.DB, "strategy_risk_configs", "allocation", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Per-strategy drawdown stop: running peak of realized PnL and the limit (0 = off)
+	if err := ensureColumn(d.DB, "strategy_positions", "peak_pnl", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Entry time of the strategy's open position (min hold), NULL when flat or unknown
+	if err := ensureColumn(d.DB, "strategy_positions", "opened_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "strategy_risk_configs", "max_drawdown_pct", "REAL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Per-strategy minimum hold before exits (0 = off)
+	if err := ensureColumn(d.DB, "strategy_risk_configs", "min_hold_seconds", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	// Asset a trade's fee was settled in (fee itself is in the reporting currency)
+	if err := ensureColumn(d.DB, "trades", "fee_asset", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Public profile: leaderboard display name and opt-in (anonymous and off by default)
+	if err := ensureColumn(d.DB, "users", "display_name", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(d.DB, "users", "leaderboard_opt_in", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	// Hedge-mode legs are kept per account; older files keyed them by symbol only
+	if err := migratePositionLegs(d.DB); err != nil {
+		return err
+	}
+
 	// Add indexes for user_id queries (manual SQL for performance)
 	d.DB.Exec("CREATE INDEX IF NOT EXISTS idx_orders_user_time ON orders(user_id, created_at)")
 	d.DB.Exec("CREATE INDEX IF NOT EXISTS idx_trades_user_time ON trades(user_id, created_at)")
 	d.DB.Exec("CREATE INDEX IF NOT EXISTS idx_positions_user ON positions(user_id)")
 	d.DB.Exec("CREATE INDEX IF NOT EXISTS idx_user_positions_user ON user_positions(user_id, symbol)")
+	d.DB.Exec("CREATE INDEX IF NOT EXISTS idx_equity_snapshots_user_time ON equity_snapshots(user_id, created_at)")
 
 	return nil
 }
 
+// migratePositionLegs rebuilds a position_legs table created before legs
+// were keyed by user and connection; existing legs move to the global account.
+func migratePositionLegs(db *sql.DB) error {
+	exists, err := columnExists(db, "position_legs", "user_id")
+	if err != nil || exists {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		`ALTER TABLE position_legs RENAME TO position_legs_old`,
+		`CREATE TABLE position_legs (
+			user_id TEXT NOT NULL DEFAULT '',
+			connection_id TEXT NOT NULL DEFAULT '',
+			symbol TEXT NOT NULL,
+			position_side TEXT NOT NULL,
+			qty REAL NOT NULL,
+			avg_price REAL NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, connection_id, symbol, position_side)
+		)`,
+		`INSERT INTO position_legs (symbol, position_side, qty, avg_price, updated_at)
+			SELECT symbol, position_side, qty, avg_price, updated_at FROM position_legs_old`,
+		`DROP TABLE position_legs_old`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate position_legs: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
 // ensureColumn adds a column if it does not already exist.
 func ensureColumn(db *sql.DB, table, column, definition string) error {
 	exists, err := columnExists(db, table, column)
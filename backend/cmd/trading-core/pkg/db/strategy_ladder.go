@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// StrategyLadder is a strategy's scale-in/scale-out setting: how one signal is
+// split into several orders (Tranches <= 1 = a single order).
+type StrategyLadder struct {
+	Tranches     int
+	SpacingPct   float64
+	IntervalSec  int
+	Distribution string
+	ApplyTo      string
+}
+
+// GetStrategyLadder returns a strategy's ladder setting; found is false when
+// the strategy does not exist.
+func (d *Database) GetStrategyLadder(ctx context.Context, strategyID string) (l StrategyLadder, found bool, err error) {
+	err = d.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(ladder_tranches, 0), COALESCE(ladder_spacing_pct, 0), COALESCE(ladder_interval_sec, 0),
+		       COALESCE(ladder_distribution, 'equal'), COALESCE(ladder_apply_to, 'entry')
+		FROM strategy_instances WHERE id = ?
+	`, strategyID).Scan(&l.Tranches, &l.SpacingPct, &l.IntervalSec, &l.Distribution, &l.ApplyTo)
+	if err == sql.ErrNoRows {
+		return l, false, nil
+	}
+	return l, err == nil, err
+}
+
+// UpdateStrategyLadder stores a strategy's ladder setting.
+func (d *Database) UpdateStrategyLadder(ctx context.Context, strategyID string, l StrategyLadder) error {
+	_, err := d.DB.ExecContext(ctx, `
+		UPDATE strategy_instances
+		SET ladder_tranches = ?, ladder_spacing_pct = ?, ladder_interval_sec = ?,
+		    ladder_distribution = ?, ladder_apply_to = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, l.Tranches, l.SpacingPct, l.IntervalSec, l.Distribution, l.ApplyTo, strategyID)
+	return err
+}
+
+// LadderTranche is a laddered order the scheduler still tracks: either
+// waiting for its delay (Placed false) or sent to the exchange where, as a
+// GTC limit order, it may still be resting (Placed true).
+type LadderTranche struct {
+	OrderID    string
+	StrategyID string
+	Payload    []byte // JSON-encoded order
+	DueAt      time.Time
+	Placed     bool
+}
+
+// SaveLadderTranche records a tranche, replacing any row for its order ID.
+func (d *Database) SaveLadderTranche(ctx context.Context, t LadderTranche) error {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT OR REPLACE INTO ladder_tranches (order_id, strategy_instance_id, payload, due_at, placed)
+		VALUES (?, ?, ?, ?, ?)
+	`, t.OrderID, t.StrategyID, string(t.Payload), t.DueAt.UnixMilli(), t.Placed)
+	return err
+}
+
+// MarkLadderTranchePlaced records that a tranche has been sent.
+func (d *Database) MarkLadderTranchePlaced(ctx context.Context, orderID string) error {
+	_, err := d.DB.ExecContext(ctx, `UPDATE ladder_tranches SET placed = 1 WHERE order_id = ?`, orderID)
+	return err
+}
+
+// DeleteLadderTranche stops tracking a tranche.
+func (d *Database) DeleteLadderTranche(ctx context.Context, orderID string) error {
+	_, err := d.DB.ExecContext(ctx, `DELETE FROM ladder_tranches WHERE order_id = ?`, orderID)
+	return err
+}
+
+// ListLadderTranches returns the tracked tranches of strategyID, or of every
+// strategy when strategyID is "", oldest due first.
+func (d *Database) ListLadderTranches(ctx context.Context, strategyID string) ([]LadderTranche, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT order_id, strategy_instance_id, payload, due_at, placed
+		FROM ladder_tranches WHERE ? = '' OR strategy_instance_id = ?
+		ORDER BY due_at
+	`, strategyID, strategyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LadderTranche
+	for rows.Next() {
+		var (
+			t       LadderTranche
+			payload string
+			dueAt   int64
+			placed  int
+		)
+		if err := rows.Scan(&t.OrderID, &t.StrategyID, &payload, &dueAt, &placed); err != nil {
+			return nil, err
+		}
+		t.Payload = []byte(payload)
+		t.DueAt = time.UnixMilli(dueAt)
+		t.Placed = placed == 1
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// GetOrderExchangeState returns an order's venue ID and status; found is
+// false when the order was never stored.
+func (d *Database) GetOrderExchangeState(ctx context.Context, orderID string) (exchangeOrderID, status string, found bool, err error) {
+	err = d.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(exchange_order_id, ''), status FROM orders WHERE id = ?
+	`, orderID).Scan(&exchangeOrderID, &status)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	return exchangeOrderID, status, err == nil, err
+}
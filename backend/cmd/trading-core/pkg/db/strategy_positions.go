@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"trading-core/pkg/money"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ListStrategyPositionIDs returns every strategy that has a strategy_positions
+// row or at least one fill.
+func (d *Database) ListStrategyPositionIDs(ctx context.Context) ([]string, error) {
+	rows, err := d.DB.QueryContext(ctx, `
+		SELECT strategy_instance_id FROM strategy_positions
+		UNION
+		SELECT o.strategy_instance_id
+		FROM trades t JOIN orders o ON t.order_id = o.id
+		WHERE COALESCE(o.strategy_instance_id, '') != ''
+		ORDER BY 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ReplayStrategyPosition recomputes a strategy's position from its fills with
+// the same average-cost accounting as UpdateStrategyPosition. The int is the
+// number of trades replayed.
+func (t *Tx) ReplayStrategyPosition(ctx context.Context, strategyID string) (StrategyPosition, int, error) {
+	return replayStrategyPosition(ctx, t.Tx, strategyID)
+}
+
+// GetStrategyPosition returns a strategy's position within the transaction,
+// or nil if it has none.
+func (t *Tx) GetStrategyPosition(ctx context.Context, strategyID string) (*StrategyPosition, error) {
+	sp, err := getStrategyPosition(ctx, t.Tx, strategyID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sp, nil
+}
+
+// ReplaceStrategyPosition overwrites a strategy's position (used to heal
+// drift). The peak PnL never decreases; the entry time is kept while the
+// position stays open.
+func (t *Tx) ReplaceStrategyPosition(ctx context.Context, sp StrategyPosition) error {
+	_, err := t.ExecContext(ctx, `
+		INSERT INTO strategy_positions (strategy_instance_id, symbol, qty, avg_price, realized_pnl, peak_pnl, updated_at)
+		VALUES (?, ?, ?, ?, ?, MAX(?, 0), ?)
+		ON CONFLICT(strategy_instance_id) DO UPDATE SET
+			symbol = excluded.symbol,
+			qty = excluded.qty,
+			avg_price = excluded.avg_price,
+			realized_pnl = excluded.realized_pnl,
+			peak_pnl = MAX(COALESCE(strategy_positions.peak_pnl, 0), excluded.realized_pnl),
+			opened_at = CASE WHEN excluded.qty = 0 THEN NULL ELSE strategy_positions.opened_at END,
+			updated_at = excluded.updated_at
+	`, sp.StrategyInstanceID, sp.Symbol, sp.Qty, sp.AvgPrice, sp.RealizedPnL, sp.RealizedPnL, time.Now())
+	return err
+}
+
+func replayStrategyPosition(ctx context.Context, q querier, strategyID string) (StrategyPosition, int, error) {
+	sp := StrategyPosition{StrategyInstanceID: strategyID}
+	rows, err := q.QueryContext(ctx, `
+		SELECT t.symbol, COALESCE(NULLIF(t.side, ''), o.side), t.qty, t.price
+		FROM trades t
+		JOIN orders o ON t.order_id = o.id
+		WHERE o.strategy_instance_id = ?
+		ORDER BY t.created_at ASC, t.rowid ASC
+	`, strategyID)
+	if err != nil {
+		return sp, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		fills    int
+		realized money.Amount
+	)
+	for rows.Next() {
+		var (
+			side           string
+			fillQty, price float64
+			pnl            float64
+		)
+		if err := rows.Scan(&sp.Symbol, &side, &fillQty, &price); err != nil {
+			return sp, fills, err
+		}
+		sp.Qty, sp.AvgPrice, pnl = applyAverageCostFill(sp.Qty, sp.AvgPrice, side, fillQty, price)
+		realized = realized.Add(money.FromFloat(pnl))
+		fills++
+	}
+	sp.RealizedPnL = realized.Float64()
+	return sp, fills, rows.Err()
+}
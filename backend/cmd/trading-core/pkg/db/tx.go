@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx, so writes can run either
+// standalone or inside a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx is a transaction opened by WithTx. It offers the same writes as Database.
+type Tx struct {
+	*sql.Tx
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise (including on panic).
+func (d *Database) WithTx(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	sqlTx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(&Tx{Tx: sqlTx}); err != nil {
+		_ = sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// CreateOrder inserts a new order row within the transaction.
+func (t *Tx) CreateOrder(ctx context.Context, o Order) error {
+	return createOrder(ctx, t.Tx, o)
+}
+
+// CreateTrade inserts a new trade row within the transaction.
+func (t *Tx) CreateTrade(ctx context.Context, tr Trade) error {
+	return createTrade(ctx, t.Tx, tr)
+}
+
+// UpdateStrategyPosition applies a fill to a strategy position within the transaction.
+func (t *Tx) UpdateStrategyPosition(ctx context.Context, strategyID, symbol, side string, qty, price float64) error {
+	return updateStrategyPosition(ctx, t.Tx, strategyID, symbol, side, qty, price)
+}
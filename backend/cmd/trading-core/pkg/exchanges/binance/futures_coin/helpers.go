@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"strconv"
+	"strings"
 )
 
 func sign(data, secret string) string {
@@ -16,3 +17,18 @@ func sign(data, secret string) string {
 func formatFloat(v float64) string {
 	return strconv.FormatFloat(v, 'f', -1, 64)
 }
+
+// parseFloat parses a decimal string from the API, treating blanks as 0.
+func parseFloat(v string) float64 {
+	f, _ := strconv.ParseFloat(v, 64)
+	return f
+}
+
+// marginAssetOf returns the collateral coin of a COIN-M symbol
+// (BTCUSD_PERP and BTCUSD_250328 are margined in BTC).
+func marginAssetOf(symbol string) string {
+	if i := strings.Index(symbol, "USD"); i > 0 {
+		return symbol[:i]
+	}
+	return symbol
+}
@@ -0,0 +1,105 @@
+package futures_coin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultReportingAsset is the currency COIN-M amounts are converted into.
+const DefaultReportingAsset = "USDT"
+
+// usdAssets are treated as interchangeable when converting; COIN-M contracts
+// are quoted in USD while accounts usually report in USDT.
+var usdAssets = map[string]bool{"USD": true, "USDT": true, "USDC": true, "BUSD": true, "FDUSD": true}
+
+// ToReporting converts an amount settled in asset into reportingAsset using
+// markPrice (reporting units per 1 asset). Same-asset and USD-to-USD amounts
+// pass through unchanged.
+func ToReporting(amount float64, asset, reportingAsset string, markPrice float64) (float64, error) {
+	asset, reportingAsset = strings.ToUpper(asset), strings.ToUpper(reportingAsset)
+	if reportingAsset == "" {
+		reportingAsset = DefaultReportingAsset
+	}
+	if amount == 0 || asset == "" || asset == reportingAsset || (usdAssets[asset] && usdAssets[reportingAsset]) {
+		return amount, nil
+	}
+	if markPrice <= 0 {
+		return 0, fmt.Errorf("binance coin futures: no mark price to convert %s to %s", asset, reportingAsset)
+	}
+	return amount * markPrice, nil
+}
+
+// ReportedIncome is an income record tagged with its settlement asset and
+// converted into the reporting currency.
+type ReportedIncome struct {
+	Income
+	SettlementAsset string  // asset the income was paid in (e.g. BTC)
+	Amount          float64 // amount in the settlement asset
+	Reported        float64 // amount in the reporting asset
+}
+
+// ConvertIncome converts income records (realized PnL, commission, funding) into
+// reportingAsset. markPrice returns the price of a settlement asset in reporting
+// units, e.g. the BTCUSD_PERP mark for BTC.
+func ConvertIncome(records []Income, reportingAsset string, markPrice func(asset string) float64) ([]ReportedIncome, error) {
+	out := make([]ReportedIncome, 0, len(records))
+	for _, r := range records {
+		amount, err := strconv.ParseFloat(r.Income, 64)
+		if err != nil {
+			return nil, fmt.Errorf("binance coin futures: income %d amount %q: %w", r.TranID, r.Income, err)
+		}
+		var mark float64
+		if markPrice != nil {
+			mark = markPrice(strings.ToUpper(r.Asset))
+		}
+		reported, err := ToReporting(amount, r.Asset, reportingAsset, mark)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ReportedIncome{
+			Income:          r,
+			SettlementAsset: strings.ToUpper(r.Asset),
+			Amount:          amount,
+			Reported:        reported,
+		})
+	}
+	return out, nil
+}
+
+// GetMarkPrice fetches the current mark price for a COIN-M symbol (e.g. BTCUSD_PERP).
+func (c *Client) GetMarkPrice(ctx context.Context, symbol string) (float64, error) {
+	endpoint := c.baseURL + "/dapi/v1/premiumIndex?" + url.Values{"symbol": {symbol}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("premium index status %d: %s", resp.StatusCode, string(b))
+	}
+	// The COIN-M endpoint always returns an array, even for a single symbol.
+	var res []struct {
+		Symbol    string `json:"symbol"`
+		MarkPrice string `json:"markPrice"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, fmt.Errorf("decode premium index: %w", err)
+	}
+	for _, r := range res {
+		if r.Symbol == symbol {
+			return strconv.ParseFloat(r.MarkPrice, 64)
+		}
+	}
+	return 0, fmt.Errorf("premium index: symbol %s not found", symbol)
+}
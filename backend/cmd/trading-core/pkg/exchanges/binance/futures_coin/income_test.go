@@ -0,0 +1,38 @@
+package futures_coin
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertIncomeBTCToUSDT(t *testing.T) {
+	records := []Income{
+		{Symbol: "BTCUSD_PERP", IncomeType: "REALIZED_PNL", Income: "0.01", Asset: "BTC", TranID: 1},
+		{Symbol: "BTCUSD_PERP", IncomeType: "COMMISSION", Income: "-0.0002", Asset: "BTC", TranID: 2},
+		{IncomeType: "TRANSFER", Income: "25", Asset: "USDT", TranID: 3},
+	}
+	marks := map[string]float64{"BTC": 60000}
+
+	got, err := ConvertIncome(records, "USDT", func(asset string) float64 { return marks[asset] })
+	if err != nil {
+		t.Fatalf("ConvertIncome: %v", err)
+	}
+	want := []float64{600, -12, 25}
+	for i, r := range got {
+		if math.Abs(r.Reported-want[i]) > 1e-9 {
+			t.Errorf("record %d: reported %v, want %v", i, r.Reported, want[i])
+		}
+	}
+	if got[0].SettlementAsset != "BTC" || got[0].Amount != 0.01 {
+		t.Errorf("record should keep its settlement asset and raw amount, got %+v", got[0])
+	}
+}
+
+func TestToReportingRequiresMarkPrice(t *testing.T) {
+	if _, err := ToReporting(0.5, "ETH", "USDT", 0); err == nil {
+		t.Fatal("expected error converting without a mark price")
+	}
+	if v, err := ToReporting(10, "USD", "USDT", 0); err != nil || v != 10 {
+		t.Fatalf("USD amounts should pass through, got %v, %v", v, err)
+	}
+}
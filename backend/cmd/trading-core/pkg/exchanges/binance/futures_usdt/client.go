@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"trading-core/pkg/exchanges/common"
@@ -21,6 +23,10 @@ type Config struct {
 	APISecret  string
 	Testnet    bool
 	RecvWindow int64 // ms
+
+	// UseWSOrders places orders over the websocket API (lower latency), falling back to REST.
+	UseWSOrders bool
+	WSURL       string // optional websocket API endpoint override
 }
 
 // Client handles Binance USDT-M futures.
@@ -30,6 +36,10 @@ type Client struct {
 	httpClient  *http.Client
 	timeSync    *common.TimeSync
 	rateLimiter *common.RateLimiter
+	leverage    common.LeverageCache
+
+	wsOnce sync.Once
+	ws     *wsSession
 }
 
 // NewClient creates a new USDT-M futures client.
@@ -97,6 +107,25 @@ func (c *Client) KeepAliveListenKey(ctx context.Context, listenKey string) error
 	return nil
 }
 
+// CloseListenKey closes a user data stream.
+func (c *Client) CloseListenKey(ctx context.Context, listenKey string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.baseURL+"/fapi/v1/listenKey?listenKey="+listenKey, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", c.cfg.APIKey)
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("close listen key status %d: %s", res.StatusCode, string(b))
+	}
+	return nil
+}
+
 // Helper: convert to consistent timestamp with time sync if available.
 func (c *Client) now() int64 {
 	if c.timeSync != nil && c.timeSync.Offset() != 0 {
@@ -105,11 +134,41 @@ func (c *Client) now() int64 {
 	return time.Now().UnixMilli()
 }
 
-// SubmitOrder places an order.
+// SubmitOrder places an order. When UseWSOrders is enabled the order is sent over
+// the websocket API first, falling back to REST if the session is unavailable.
 func (c *Client) SubmitOrder(ctx context.Context, req common.OrderRequest) (common.OrderResult, error) {
 	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
 		return common.OrderResult{}, errors.New("binance usdt futures: API key/secret required")
 	}
+	if cur, ok := c.leverage.Get(req.Symbol); req.Leverage > 0 && (!ok || cur != req.Leverage) {
+		if err := c.SetLeverage(ctx, req.Symbol, req.Leverage); err != nil {
+			return common.OrderResult{}, fmt.Errorf("set leverage %dx: %w", req.Leverage, err)
+		}
+	}
+	if c.cfg.UseWSOrders {
+		res, err := c.SubmitOrderWS(ctx, req)
+		if !errors.Is(err, ErrWSUnavailable) {
+			return res, err
+		}
+		log.Printf("binance usdt futures: %v, falling back to REST", err)
+	}
+
+	params := orderParams(req)
+
+	// Use synchronized time
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
+	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
+
+	endpoint := c.baseURL + "/fapi/v1/order"
+	body, err := c.doSigned(ctx, http.MethodPost, endpoint, params)
+	if err != nil {
+		return common.OrderResult{}, err
+	}
+	return decodeOrderResult(body)
+}
+
+// orderParams builds the unsigned order parameters shared by REST and websocket placement.
+func orderParams(req common.OrderRequest) url.Values {
 	params := url.Values{}
 	params.Set("symbol", req.Symbol)
 	params.Set("side", strings.ToUpper(string(req.Side)))
@@ -119,16 +178,14 @@ func (c *Client) SubmitOrder(ctx context.Context, req common.OrderRequest) (comm
 	// Set price for limit orders
 	if req.Type == common.OrderTypeLimit ||
 		req.Type == common.OrderTypeStopLossLimit ||
-		req.Type == common.OrderTypeTakeProfitLimit {
+		req.Type == common.OrderTypeTakeProfitLimit ||
+		req.Type == common.OrderTypeStop {
 		params.Set("price", formatFloat(req.Price))
 		params.Set("timeInForce", string(toBinanceTIF(req.TimeInForce)))
 	}
 
 	// Set stopPrice for stop orders
-	if req.Type == common.OrderTypeStopLoss ||
-		req.Type == common.OrderTypeStopLossLimit ||
-		req.Type == common.OrderTypeTakeProfit ||
-		req.Type == common.OrderTypeTakeProfitLimit {
+	if req.Type.RequiresStopPrice() {
 		params.Set("stopPrice", formatFloat(req.StopPrice))
 		if req.WorkingType != "" {
 			params.Set("workingType", req.WorkingType)
@@ -155,20 +212,11 @@ func (c *Client) SubmitOrder(ctx context.Context, req common.OrderRequest) (comm
 	if req.ReduceOnly {
 		params.Set("reduceOnly", "true")
 	}
+	return params
+}
 
-	// Use synchronized time
-	timestamp := time.Now().UnixMilli()
-	if c.timeSync != nil && c.timeSync.Offset() != 0 {
-		timestamp = c.timeSync.Now()
-	}
-	params.Set("timestamp", strconv.FormatInt(timestamp, 10))
-	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
-
-	endpoint := c.baseURL + "/fapi/v1/order"
-	body, err := c.doSigned(ctx, http.MethodPost, endpoint, params)
-	if err != nil {
-		return common.OrderResult{}, err
-	}
+// decodeOrderResult converts an order response body into an OrderResult.
+func decodeOrderResult(body []byte) (common.OrderResult, error) {
 	var resp orderResp
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return common.OrderResult{}, fmt.Errorf("decode order: %w", err)
@@ -212,6 +260,24 @@ func (c *Client) CancelAllOpenOrders(ctx context.Context, symbol string) error {
 	return err
 }
 
+// CountdownCancelAll arms the exchange's auto-cancel timer for a symbol: unless
+// called again within countdown, all of its open orders are cancelled.
+// A countdown of 0 disarms the timer.
+func (c *Client) CountdownCancelAll(ctx context.Context, symbol string, countdown time.Duration) error {
+	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
+		return errors.New("binance usdt futures: API key/secret required")
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("countdownTime", strconv.FormatInt(countdown.Milliseconds(), 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
+	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
+
+	endpoint := c.baseURL + "/fapi/v1/countdownCancelAll"
+	_, err := c.doSigned(ctx, http.MethodPost, endpoint, params)
+	return err
+}
+
 // GetAccountInfo returns futures account balances and flags.
 func (c *Client) GetAccountInfo(ctx context.Context) (*FuturesAccountInfo, error) {
 	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
@@ -232,6 +298,23 @@ func (c *Client) GetAccountInfo(ctx context.Context) (*FuturesAccountInfo, error
 	return &info, nil
 }
 
+// AccountSummary reports whether the futures account can trade.
+func (c *Client) AccountSummary(ctx context.Context) (common.AccountSummary, error) {
+	info, err := c.GetAccountInfo(ctx)
+	if err != nil {
+		return common.AccountSummary{}, err
+	}
+	perms := []string{common.CapabilityUSDTFutures}
+	if !info.CanTrade {
+		perms = []string{common.CapabilityReadOnly}
+	}
+	return common.AccountSummary{
+		CanTrade:    info.CanTrade,
+		AccountType: common.CapabilityUSDTFutures,
+		Permissions: perms,
+	}, nil
+}
+
 // GetPositions returns position risk view.
 func (c *Client) GetPositions(ctx context.Context, symbol string) ([]PositionRisk, error) {
 	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
@@ -255,6 +338,61 @@ func (c *Client) GetPositions(ctx context.Context, symbol string) ([]PositionRis
 	return pos, nil
 }
 
+// MarginAccount implements common.MarginReporter: account totals in USDT plus
+// each open position with its mark and estimated liquidation price.
+func (c *Client) MarginAccount(ctx context.Context) (common.MarginAccount, error) {
+	info, err := c.GetAccountInfo(ctx)
+	if err != nil {
+		return common.MarginAccount{}, err
+	}
+	risks, err := c.GetPositions(ctx, "")
+	if err != nil {
+		return common.MarginAccount{}, err
+	}
+
+	acct := common.MarginAccount{}
+	wallet := parseFloat(info.TotalWalletBalance)
+	marginBal := parseFloat(info.TotalMarginBalance)
+	maint := parseFloat(info.TotalMaintMargin)
+	acct.Assets = append(acct.Assets, common.MarginAsset{
+		Asset:            "USDT",
+		WalletBalance:    wallet,
+		UnrealizedPnL:    parseFloat(info.TotalUnrealizedProfit),
+		MarginBalance:    marginBal,
+		MaintMargin:      maint,
+		AvailableBalance: parseFloat(info.AvailableBalance),
+		MarginRatio:      common.MarginRatio(maint, marginBal),
+	})
+
+	// The account endpoint carries maintenance margin per position; positionRisk
+	// carries mark and liquidation prices.
+	maintBy := make(map[string]string, len(info.Positions))
+	for _, p := range info.Positions {
+		maintBy[p.Symbol+"/"+p.PositionSide] = p.MaintMargin
+	}
+	for _, p := range risks {
+		qty := parseFloat(p.PositionAmt)
+		if qty == 0 {
+			continue
+		}
+		lev, _ := strconv.Atoi(p.Leverage)
+		acct.Positions = append(acct.Positions, common.MarginPosition{
+			Symbol:           p.Symbol,
+			PositionSide:     p.PositionSide,
+			Qty:              qty,
+			EntryPrice:       parseFloat(p.EntryPrice),
+			MarkPrice:        parseFloat(p.MarkPrice),
+			UnrealizedPnL:    parseFloat(p.UnRealizedProfit),
+			Leverage:         lev,
+			MarginType:       p.MarginType,
+			MarginAsset:      "USDT",
+			MaintMargin:      parseFloat(maintBy[p.Symbol+"/"+p.PositionSide]),
+			LiquidationPrice: parseFloat(p.LiquidationPrice),
+		})
+	}
+	return acct, nil
+}
+
 // GetOpenOrders returns open orders; symbol optional.
 func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]OpenOrder, error) {
 	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
@@ -278,6 +416,60 @@ func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]OpenOrder,
 	return orders, nil
 }
 
+// GetOrder fetches a single order by symbol and orderId.
+func (c *Client) GetOrder(ctx context.Context, symbol, orderID string) (*OpenOrder, error) {
+	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
+		return nil, errors.New("binance usdt futures: API key/secret required")
+	}
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("orderId", orderID)
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
+	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
+	endpoint := c.baseURL + "/fapi/v1/order"
+	body, err := c.doSigned(ctx, http.MethodGet, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	var ord OpenOrder
+	if err := json.Unmarshal(body, &ord); err != nil {
+		return nil, fmt.Errorf("decode order: %w", err)
+	}
+	return &ord, nil
+}
+
+// QueryOrder implements common.OrderQuerier.
+func (c *Client) QueryOrder(ctx context.Context, symbol, exchangeOrderID string) (common.OrderState, error) {
+	ord, err := c.GetOrder(ctx, symbol, exchangeOrderID)
+	if err != nil {
+		return common.OrderState{}, err
+	}
+	return common.OrderState{Status: mapStatus(ord.Status), FilledQty: parseFloat(ord.ExecQty), AvgPrice: parseFloat(ord.AvgPrice)}, nil
+}
+
+// ListOpenOrders implements common.OpenOrderLister.
+func (c *Client) ListOpenOrders(ctx context.Context) ([]common.OpenOrder, error) {
+	orders, err := c.GetOpenOrders(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]common.OpenOrder, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, common.OpenOrder{
+			Symbol:          o.Symbol,
+			ExchangeOrderID: strconv.FormatInt(o.OrderID, 10),
+			ClientID:        o.ClientOrderID,
+			Side:            common.Side(o.Side),
+			Type:            common.OrderType(o.Type),
+			Price:           parseFloat(o.Price),
+			Qty:             parseFloat(o.OrigQty),
+			FilledQty:       parseFloat(o.ExecQty),
+			Status:          mapStatus(o.Status),
+		})
+	}
+	return out, nil
+}
+
 // GetBalance returns futures balances.
 func (c *Client) GetBalance(ctx context.Context) ([]FuturesBalance, error) {
 	params := url.Values{}
@@ -303,8 +495,30 @@ func (c *Client) SetLeverage(ctx context.Context, symbol string, leverage int) e
 	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 	endpoint := c.baseURL + "/fapi/v1/leverage"
-	_, err := c.doSigned(ctx, http.MethodPost, endpoint, params)
-	return err
+	if _, err := c.doSigned(ctx, http.MethodPost, endpoint, params); err != nil {
+		return err
+	}
+	c.leverage.Set(symbol, leverage)
+	return nil
+}
+
+// SymbolLeverage implements common.LeverageReporter, reading the account's
+// leverage for symbol from positionRisk unless it was set or read before.
+func (c *Client) SymbolLeverage(ctx context.Context, symbol string) (int, error) {
+	if lev, ok := c.leverage.Get(symbol); ok {
+		return lev, nil
+	}
+	pos, err := c.GetPositions(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range pos {
+		if lev, err := strconv.Atoi(p.Leverage); err == nil && lev > 0 {
+			c.leverage.Set(symbol, lev)
+			return lev, nil
+		}
+	}
+	return 0, fmt.Errorf("no leverage reported for %s", symbol)
 }
 
 // SetMarginType sets margin type (ISOLATED or CROSSED).
@@ -330,10 +544,14 @@ func (c *Client) SetPositionSideDual(ctx context.Context, dual bool) error {
 	return err
 }
 
-// ChangePositionMargin adjusts position margin.
-func (c *Client) ChangePositionMargin(ctx context.Context, symbol string, amount float64, mType int) error {
+// ChangePositionMargin adjusts position margin. positionSide (LONG/SHORT) is
+// required on a hedge-mode account and omitted when empty.
+func (c *Client) ChangePositionMargin(ctx context.Context, symbol, positionSide string, amount float64, mType int) error {
 	params := url.Values{}
 	params.Set("symbol", symbol)
+	if positionSide != "" {
+		params.Set("positionSide", positionSide)
+	}
 	params.Set("amount", formatFloat(amount))
 	params.Set("type", strconv.Itoa(mType))
 	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
@@ -393,6 +611,24 @@ func (c *Client) GetIncome(ctx context.Context, symbol, incomeType string, limit
 	return income, nil
 }
 
+// Ping checks REST connectivity using the lightweight /fapi/v1/ping endpoint (weight 1).
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/fapi/v1/ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ping status %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
 // GetServerTime fetches futures server time.
 func (c *Client) GetServerTime() (int64, error) {
 	resp, err := c.httpClient.Get(c.baseURL + "/fapi/v1/time")
@@ -413,8 +649,65 @@ func (c *Client) GetServerTime() (int64, error) {
 	return res.ServerTime, nil
 }
 
-// doSigned handles signing and sending requests.
+// GetExchangeInfo fetches symbol metadata (base/quote assets) from the public
+// exchangeInfo endpoint.
+func (c *Client) GetExchangeInfo(ctx context.Context) ([]common.SymbolInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/fapi/v1/exchangeInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("exchange info status %d: %s", resp.StatusCode, string(b))
+	}
+	var res struct {
+		Symbols []struct {
+			Symbol       string                  `json:"symbol"`
+			BaseAsset    string                  `json:"baseAsset"`
+			QuoteAsset   string                  `json:"quoteAsset"`
+			ContractType string                  `json:"contractType"`
+			Filters      []common.ExchangeFilter `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("decode exchange info: %w", err)
+	}
+	out := make([]common.SymbolInfo, 0, len(res.Symbols))
+	for _, s := range res.Symbols {
+		info := common.SymbolInfo{
+			Symbol:       s.Symbol,
+			BaseAsset:    s.BaseAsset,
+			QuoteAsset:   s.QuoteAsset,
+			ContractType: s.ContractType,
+		}
+		info.ApplyFilters(s.Filters)
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// doSigned signs and sends a request. If the exchange rejects the timestamp
+// (local clock drift), it resyncs the server-time offset and retries once.
 func (c *Client) doSigned(ctx context.Context, method, endpoint string, params url.Values) ([]byte, error) {
+	body, err := c.sendSigned(ctx, method, endpoint, params)
+	if !common.IsTimestampError(err) || c.timeSync == nil || params.Get("timestamp") == "" {
+		return body, err
+	}
+	if serr := c.timeSync.Sync(ctx); serr != nil {
+		return nil, fmt.Errorf("%w (time resync failed: %v)", err, serr)
+	}
+	params.Del("signature")
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
+	return c.sendSigned(ctx, method, endpoint, params)
+}
+
+// sendSigned handles signing and sending requests.
+func (c *Client) sendSigned(ctx context.Context, method, endpoint string, params url.Values) ([]byte, error) {
 	sig := sign(params.Encode(), c.cfg.APISecret)
 	params.Set("signature", sig)
 
@@ -448,7 +741,7 @@ func (c *Client) doSigned(ctx context.Context, method, endpoint string, params u
 
 	body, _ := io.ReadAll(res.Body)
 	if res.StatusCode >= 300 {
-		return nil, fmt.Errorf("binance usdt futures %s %s status %d: %s", method, endpoint, res.StatusCode, string(body))
+		return nil, common.NewAPIError(fmt.Sprintf("binance usdt futures %s %s", method, endpoint), res.StatusCode, body)
 	}
 	return body, nil
 }
@@ -467,10 +760,22 @@ type FuturesAccountInfo struct {
 		Asset            string `json:"asset"`
 		WalletBalance    string `json:"walletBalance"`
 		UnrealizedProfit string `json:"unrealizedProfit"`
+		MarginBalance    string `json:"marginBalance"`
+		MaintMargin      string `json:"maintMargin"`
+		AvailableBalance string `json:"availableBalance"`
 	} `json:"assets"`
 	Positions []PositionRisk `json:"positions"`
+
+	// Account totals in USDT (across assets in multi-assets mode).
+	TotalWalletBalance    string `json:"totalWalletBalance"`
+	TotalUnrealizedProfit string `json:"totalUnrealizedProfit"`
+	TotalMarginBalance    string `json:"totalMarginBalance"`
+	TotalMaintMargin      string `json:"totalMaintMargin"`
+	AvailableBalance      string `json:"availableBalance"`
 }
 
+// PositionRisk is a position as reported by positionRisk (mark and liquidation
+// prices) or inside the account endpoint (maintenance margin).
 type PositionRisk struct {
 	Symbol           string `json:"symbol"`
 	PositionSide     string `json:"positionSide"`
@@ -478,11 +783,15 @@ type PositionRisk struct {
 	EntryPrice       string `json:"entryPrice"`
 	UnRealizedProfit string `json:"unRealizedProfit"`
 	Leverage         string `json:"leverage"`
+	MarkPrice        string `json:"markPrice,omitempty"`
+	LiquidationPrice string `json:"liquidationPrice,omitempty"`
+	MarginType       string `json:"marginType,omitempty"`
+	MaintMargin      string `json:"maintMargin,omitempty"`
 }
 
 func toBinanceTIF(tif common.TimeInForce) common.TimeInForce {
 	if tif == "" {
 		return common.TIFGTC
 	}
-	return tif
+	return common.TimeInForce(strings.ToUpper(string(tif)))
 }
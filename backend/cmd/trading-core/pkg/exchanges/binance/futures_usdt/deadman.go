@@ -0,0 +1,37 @@
+package futures_usdt
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// KeepCountdownCancelAll is a dead-man's switch: it arms the auto-cancel
+// countdown for each symbol and re-arms it every refresh until ctx is done.
+// If the process crashes or loses connectivity, the heartbeats stop and the
+// exchange cancels our open orders once countdown elapses. refresh must be
+// well below countdown so a single failed call does not trip the switch.
+func (c *Client) KeepCountdownCancelAll(ctx context.Context, symbols []string, countdown, refresh time.Duration) {
+	if len(symbols) == 0 || countdown <= 0 || refresh <= 0 {
+		return
+	}
+	arm := func() {
+		for _, sym := range symbols {
+			if err := c.CountdownCancelAll(ctx, sym, countdown); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️ dead-man's switch: arm countdown for %s failed: %v", sym, err)
+			}
+		}
+	}
+
+	arm()
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			arm()
+		}
+	}
+}
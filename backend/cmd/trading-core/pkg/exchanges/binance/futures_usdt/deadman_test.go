@@ -0,0 +1,66 @@
+package futures_usdt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeepCountdownCancelAllRefreshesCountdown(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/fapi/v1/countdownCancelAll" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if r.PostForm.Get("countdownTime") != "120000" || r.PostForm.Get("signature") == "" || r.Header.Get("X-MBX-APIKEY") != "key" {
+			t.Errorf("unexpected countdown request: %v", r.PostForm)
+		}
+		mu.Lock()
+		calls[r.PostForm.Get("symbol")]++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"symbol":"` + r.PostForm.Get("symbol") + `","countdownTime":"120000"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{APIKey: "key", APISecret: "secret"})
+	c.baseURL = srv.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.KeepCountdownCancelAll(ctx, []string{"BTCUSDT", "ETHUSDT"}, 2*time.Minute, 20*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		btc, eth := calls["BTCUSDT"], calls["ETHUSDT"]
+		mu.Unlock()
+		if btc >= 3 && eth >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected countdown to be armed and refreshed, got BTCUSDT=%d ETHUSDT=%d", btc, eth)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dead-man's switch did not stop on cancel")
+	}
+}
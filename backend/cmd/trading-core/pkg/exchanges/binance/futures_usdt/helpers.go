@@ -16,3 +16,9 @@ func sign(data, secret string) string {
 func formatFloat(v float64) string {
 	return strconv.FormatFloat(v, 'f', -1, 64)
 }
+
+// parseFloat parses a decimal string from the API, treating blanks as 0.
+func parseFloat(v string) float64 {
+	f, _ := strconv.ParseFloat(v, 64)
+	return f
+}
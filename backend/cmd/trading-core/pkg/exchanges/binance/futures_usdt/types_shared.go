@@ -15,6 +15,7 @@ type OpenOrder struct {
 	Price         string `json:"price"`
 	OrigQty       string `json:"origQty"`
 	ExecQty       string `json:"executedQty"`
+	AvgPrice      string `json:"avgPrice"`
 	Status        string `json:"status"`
 	PositionSide  string `json:"positionSide"`
 	ReduceOnly    bool   `json:"reduceOnly"`
@@ -0,0 +1,209 @@
+package futures_usdt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"trading-core/pkg/exchanges/common"
+)
+
+const (
+	wsAPIURL        = "wss://ws-fapi.binance.com/ws-fapi/v1"
+	wsAPITestnetURL = "wss://testnet.binancefuture.com/ws-fapi/v1"
+)
+
+// ErrWSUnavailable is returned when the websocket session cannot deliver a request
+// (dial failure or disconnect). SubmitOrder falls back to REST on this error.
+var ErrWSUnavailable = errors.New("binance usdt futures: websocket session unavailable")
+
+type wsRequest struct {
+	ID     string            `json:"id"`
+	Method string            `json:"method"`
+	Params map[string]string `json:"params"`
+}
+
+type wsResponse struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"error"`
+}
+
+// wsSession is a persistent websocket API connection. Requests are signed
+// individually and matched to responses by id; the connection is redialed lazily
+// after a disconnect.
+type wsSession struct {
+	url string
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	pending map[string]chan wsResponse
+	nextID  uint64
+
+	writeMu sync.Mutex
+}
+
+func newWSSession(url string) *wsSession {
+	return &wsSession{url: url, pending: make(map[string]chan wsResponse)}
+}
+
+func (s *wsSession) connect(ctx context.Context) (*websocket.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	go s.readLoop(conn)
+	return conn, nil
+}
+
+func (s *wsSession) readLoop(conn *websocket.Conn) {
+	for {
+		var resp wsResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			s.drop(conn)
+			return
+		}
+		s.mu.Lock()
+		ch, ok := s.pending[resp.ID]
+		delete(s.pending, resp.ID)
+		s.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// drop closes conn and fails every in-flight request if conn is still current.
+func (s *wsSession) drop(conn *websocket.Conn) {
+	s.mu.Lock()
+	if s.conn != conn {
+		s.mu.Unlock()
+		return
+	}
+	s.conn = nil
+	pending := s.pending
+	s.pending = make(map[string]chan wsResponse)
+	s.mu.Unlock()
+
+	_ = conn.Close()
+	for _, ch := range pending {
+		close(ch)
+	}
+}
+
+func (s *wsSession) do(ctx context.Context, method string, params map[string]string) (wsResponse, error) {
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return wsResponse{}, fmt.Errorf("%w: %v", ErrWSUnavailable, err)
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.FormatUint(s.nextID, 10)
+	ch := make(chan wsResponse, 1)
+	s.pending[id] = ch
+	s.mu.Unlock()
+
+	s.writeMu.Lock()
+	err = conn.WriteJSON(wsRequest{ID: id, Method: method, Params: params})
+	s.writeMu.Unlock()
+	if err != nil {
+		s.drop(conn)
+		return wsResponse{}, fmt.Errorf("%w: %v", ErrWSUnavailable, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return wsResponse{}, fmt.Errorf("%w: connection closed", ErrWSUnavailable)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return wsResponse{}, ctx.Err()
+	}
+}
+
+func (s *wsSession) close() {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn != nil {
+		s.drop(conn)
+	}
+}
+
+func (c *Client) wsSession() *wsSession {
+	c.wsOnce.Do(func() {
+		url := c.cfg.WSURL
+		if url == "" {
+			url = wsAPIURL
+			if c.cfg.Testnet {
+				url = wsAPITestnetURL
+			}
+		}
+		c.ws = newWSSession(url)
+	})
+	return c.ws
+}
+
+// SubmitOrderWS places an order over the websocket API (order.place). Parameters
+// are signed exactly like the REST request; when ClientID is set, a REST retry
+// after a disconnect reuses it so the venue rejects duplicates.
+func (c *Client) SubmitOrderWS(ctx context.Context, req common.OrderRequest) (common.OrderResult, error) {
+	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
+		return common.OrderResult{}, errors.New("binance usdt futures: API key/secret required")
+	}
+	params := orderParams(req)
+	params.Set("apiKey", c.cfg.APIKey)
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
+	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
+	params.Set("signature", sign(params.Encode(), c.cfg.APISecret))
+
+	flat := make(map[string]string, len(params))
+	for k := range params {
+		flat[k] = params.Get(k)
+	}
+
+	resp, err := c.wsSession().do(ctx, "order.place", flat)
+	if err != nil {
+		return common.OrderResult{}, err
+	}
+	if resp.Error != nil {
+		return common.OrderResult{}, &common.APIError{
+			Op:     "binance usdt futures ws order.place",
+			Status: resp.Status,
+			Code:   resp.Error.Code,
+			Msg:    resp.Error.Msg,
+			Body:   fmt.Sprintf("code %d: %s", resp.Error.Code, resp.Error.Msg),
+		}
+	}
+	if resp.Status >= 300 {
+		return common.OrderResult{}, fmt.Errorf("binance usdt futures ws order.place status %d", resp.Status)
+	}
+	return decodeOrderResult(resp.Result)
+}
+
+// CloseWS closes the websocket order session, if one is open.
+func (c *Client) CloseWS() {
+	if c.ws != nil {
+		c.ws.close()
+	}
+}
@@ -0,0 +1,92 @@
+package futures_usdt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"trading-core/pkg/exchanges/common"
+)
+
+func TestSubmitOrderWSEchoAck(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			var req wsRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			if req.Method != "order.place" || req.Params["signature"] == "" || req.Params["apiKey"] != "key" {
+				t.Errorf("unexpected request: %+v", req)
+			}
+			_ = conn.WriteJSON(map[string]any{
+				"id":     req.ID,
+				"status": 200,
+				"result": map[string]any{
+					"symbol":        req.Params["symbol"],
+					"orderId":       4242,
+					"clientOrderId": req.Params["newClientOrderId"],
+					"status":        "NEW",
+				},
+			})
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{
+		APIKey:      "key",
+		APISecret:   "secret",
+		UseWSOrders: true,
+		WSURL:       "ws" + strings.TrimPrefix(srv.URL, "http"),
+	})
+	defer c.CloseWS()
+
+	res, err := c.SubmitOrder(context.Background(), common.OrderRequest{
+		Symbol: "BTCUSDT", Side: common.SideBuy, Type: common.OrderTypeMarket, Qty: 0.01, ClientID: "cid-1",
+	})
+	if err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	if res.ExchangeOrderID != "4242" || res.ClientID != "cid-1" || res.Status != common.StatusNew {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestSubmitOrderFallsBackToREST(t *testing.T) {
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/order" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"symbol":"BTCUSDT","orderId":7,"clientOrderId":"cid-2","status":"NEW"}`))
+	}))
+	defer rest.Close()
+
+	c := NewClient(Config{
+		APIKey:      "key",
+		APISecret:   "secret",
+		UseWSOrders: true,
+		WSURL:       "ws://127.0.0.1:1/unreachable",
+	})
+	c.baseURL = rest.URL
+
+	res, err := c.SubmitOrder(context.Background(), common.OrderRequest{
+		Symbol: "BTCUSDT", Side: common.SideBuy, Type: common.OrderTypeMarket, Qty: 0.01, ClientID: "cid-2",
+	})
+	if err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	if res.ExchangeOrderID != "7" {
+		t.Fatalf("expected REST fallback result, got %+v", res)
+	}
+}
@@ -2,9 +2,9 @@ package spot
 
 import (
 	"context"
-	"strconv"
 	"trading-core/internal/balance"
 	"trading-core/internal/reconciliation"
+	"trading-core/pkg/money"
 )
 
 // GetBalance implements balance.ExchangeClient interface
@@ -15,21 +15,20 @@ func (c *Client) GetBalance(ctx context.Context) (balance.Balance, error) {
 	}
 
 	// Sum all USDT balances (or you can specify which asset)
-	var total, available, locked float64
+	var available, locked money.Amount
 	for _, bal := range info.Balances {
 		if bal.Asset == "USDT" || bal.Asset == "BUSD" {
-			free, _ := strconv.ParseFloat(bal.Free, 64)
-			lock, _ := strconv.ParseFloat(bal.Locked, 64)
-			total += free + lock
-			available += free
-			locked += lock
+			free, _ := money.Parse(bal.Free)
+			lock, _ := money.Parse(bal.Locked)
+			available = available.Add(free)
+			locked = locked.Add(lock)
 		}
 	}
 
 	return balance.Balance{
-		Total:     total,
-		Available: available,
-		Locked:    locked,
+		Total:     available.Add(locked).Float64(),
+		Available: available.Float64(),
+		Locked:    locked.Float64(),
 	}, nil
 }
 
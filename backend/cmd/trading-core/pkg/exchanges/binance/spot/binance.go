@@ -48,7 +48,7 @@ func New(cfg Config) *Client {
 		baseURL:    base,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
 	}
-	// Initialize TimeSync (will sync on first use)
+	// Initialize TimeSync (synced when the exchange first rejects a timestamp)
 	client.timeSync = common.NewTimeSync(func() (int64, error) {
 		return client.GetServerTime()
 	})
@@ -57,6 +57,15 @@ func New(cfg Config) *Client {
 	return client
 }
 
+// now returns the request timestamp, corrected by the server-time offset once
+// one has been measured.
+func (c *Client) now() int64 {
+	if c.timeSync != nil && c.timeSync.Offset() != 0 {
+		return c.timeSync.Now()
+	}
+	return time.Now().UnixMilli()
+}
+
 func (c *Client) SubmitOrder(ctx context.Context, req common.OrderRequest) (common.OrderResult, error) {
 	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
 		return common.OrderResult{}, errors.New("binance: API key/secret required")
@@ -98,12 +107,7 @@ func (c *Client) SubmitOrder(ctx context.Context, req common.OrderRequest) (comm
 	if req.ClientID != "" {
 		params.Set("newClientOrderId", req.ClientID)
 	}
-	// Use synchronized time to avoid timestamp errors
-	timestamp := time.Now().UnixMilli()
-	if c.timeSync != nil && c.timeSync.Offset() != 0 {
-		timestamp = c.timeSync.Now()
-	}
-	params.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 
 	endpoint := c.baseURL + "/api/v3/order"
@@ -117,11 +121,24 @@ func (c *Client) SubmitOrder(ctx context.Context, req common.OrderRequest) (comm
 		return common.OrderResult{}, fmt.Errorf("decode order response: %w", err)
 	}
 
-	return common.OrderResult{
+	res := common.OrderResult{
 		ExchangeOrderID: fmt.Sprintf("%d", resp.OrderID),
 		Status:          mapStatus(resp.Status),
 		ClientID:        resp.ClientOrderID,
-	}, nil
+	}
+	for _, f := range resp.Fills {
+		price, _ := strconv.ParseFloat(f.Price, 64)
+		qty, _ := strconv.ParseFloat(f.Qty, 64)
+		res.Fills = append(res.Fills, common.Fill{
+			ExchangeOrderID: res.ExchangeOrderID,
+			TradeID:         strconv.FormatInt(f.TradeID, 10),
+			Symbol:          resp.Symbol,
+			Side:            common.Side(side),
+			Qty:             qty,
+			Price:           price,
+		})
+	}
+	return res, nil
 }
 
 func (c *Client) CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error {
@@ -134,12 +151,7 @@ func (c *Client) CancelOrder(ctx context.Context, symbol, exchangeOrderID string
 		params.Set("orderId", exchangeOrderID)
 	}
 
-	// Use synchronized time
-	timestamp := time.Now().UnixMilli()
-	if c.timeSync != nil && c.timeSync.Offset() != 0 {
-		timestamp = c.timeSync.Now()
-	}
-	params.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 
 	endpoint := c.baseURL + "/api/v3/order"
@@ -155,12 +167,7 @@ func (c *Client) CancelAllOpenOrders(ctx context.Context, symbol string) error {
 	params := url.Values{}
 	params.Set("symbol", symbol)
 
-	// Use synchronized time
-	timestamp := time.Now().UnixMilli()
-	if c.timeSync != nil && c.timeSync.Offset() != 0 {
-		timestamp = c.timeSync.Now()
-	}
-	params.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 
 	endpoint := c.baseURL + "/api/v3/openOrders"
@@ -168,8 +175,23 @@ func (c *Client) CancelAllOpenOrders(ctx context.Context, symbol string) error {
 	return err
 }
 
-// doSigned signs the query and performs the HTTP request.
+// doSigned signs and sends a request. If the exchange rejects the timestamp
+// (local clock drift), it resyncs the server-time offset and retries once.
 func (c *Client) doSigned(ctx context.Context, method, endpoint string, params url.Values) ([]byte, error) {
+	body, err := c.sendSigned(ctx, method, endpoint, params)
+	if !common.IsTimestampError(err) || c.timeSync == nil || params.Get("timestamp") == "" {
+		return body, err
+	}
+	if serr := c.timeSync.Sync(ctx); serr != nil {
+		return nil, fmt.Errorf("%w (time resync failed: %v)", err, serr)
+	}
+	params.Del("signature")
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
+	return c.sendSigned(ctx, method, endpoint, params)
+}
+
+// sendSigned signs the query and performs the HTTP request.
+func (c *Client) sendSigned(ctx context.Context, method, endpoint string, params url.Values) ([]byte, error) {
 	sig := sign(params.Encode(), c.cfg.APISecret)
 	params.Set("signature", sig)
 
@@ -207,11 +229,29 @@ func (c *Client) doSigned(ctx context.Context, method, endpoint string, params u
 
 	body, _ := io.ReadAll(res.Body)
 	if res.StatusCode >= 300 {
-		return nil, fmt.Errorf("binance %s %s status %d: %s", method, endpoint, res.StatusCode, string(body))
+		return nil, common.NewAPIError(fmt.Sprintf("binance %s %s", method, endpoint), res.StatusCode, body)
 	}
 	return body, nil
 }
 
+// Ping checks REST connectivity using the lightweight /api/v3/ping endpoint (weight 1).
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v3/ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ping status %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
 // GetServerTime fetches server time (ms).
 func (c *Client) GetServerTime() (int64, error) {
 	resp, err := c.httpClient.Get(c.baseURL + "/api/v3/time")
@@ -232,11 +272,86 @@ func (c *Client) GetServerTime() (int64, error) {
 	return res.ServerTime, nil
 }
 
+// GetExchangeInfo fetches symbol metadata (base/quote assets) from the public
+// exchangeInfo endpoint.
+func (c *Client) GetExchangeInfo(ctx context.Context) ([]common.SymbolInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v3/exchangeInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("exchange info status %d: %s", resp.StatusCode, string(b))
+	}
+	var res struct {
+		Symbols []struct {
+			Symbol       string                  `json:"symbol"`
+			BaseAsset    string                  `json:"baseAsset"`
+			QuoteAsset   string                  `json:"quoteAsset"`
+			ContractType string                  `json:"contractType"`
+			Filters      []common.ExchangeFilter `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("decode exchange info: %w", err)
+	}
+	out := make([]common.SymbolInfo, 0, len(res.Symbols))
+	for _, s := range res.Symbols {
+		info := common.SymbolInfo{
+			Symbol:       s.Symbol,
+			BaseAsset:    s.BaseAsset,
+			QuoteAsset:   s.QuoteAsset,
+			ContractType: s.ContractType,
+		}
+		info.ApplyFilters(s.Filters)
+		out = append(out, info)
+	}
+	return out, nil
+}
+
 // AccountInfo holds balances and permissions.
 type AccountInfo struct {
-	CanTrade   bool      `json:"canTrade"`
-	UpdateTime int64     `json:"updateTime"`
-	Balances   []Balance `json:"balances"`
+	CanTrade    bool      `json:"canTrade"`
+	UpdateTime  int64     `json:"updateTime"`
+	AccountType string    `json:"accountType"` // e.g. SPOT, MARGIN
+	Permissions []string  `json:"permissions"` // e.g. ["SPOT", "MARGIN"] or ["TRD_GRP_002"]
+	Balances    []Balance `json:"balances"`
+}
+
+// Capabilities returns the trading permissions granted to the account.
+// Falls back to the account type when no explicit permissions are reported;
+// an account that cannot trade is reported as READ_ONLY. Trading-group
+// permissions (TRD_GRP_*), which Binance reports instead of SPOT for many
+// accounts, grant spot trading.
+func (a *AccountInfo) Capabilities() []string {
+	if a == nil {
+		return nil
+	}
+	if !a.CanTrade {
+		return []string{common.CapabilityReadOnly}
+	}
+	perms := a.Permissions
+	if len(perms) == 0 && a.AccountType != "" {
+		perms = []string{a.AccountType}
+	}
+	out := make([]string, 0, len(perms))
+	seen := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if strings.HasPrefix(p, "TRD_GRP_") {
+			p = common.CapabilitySpot
+		}
+		if p != "" && !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
 }
 
 // Balance represents an asset balance.
@@ -252,7 +367,7 @@ func (c *Client) GetAccountInfo(ctx context.Context) (*AccountInfo, error) {
 		return nil, errors.New("binance: API key/secret required")
 	}
 	params := url.Values{}
-	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 	endpoint := c.baseURL + "/api/v3/account"
 	body, err := c.doSigned(ctx, http.MethodGet, endpoint, params)
@@ -266,16 +381,78 @@ func (c *Client) GetAccountInfo(ctx context.Context) (*AccountInfo, error) {
 	return &info, nil
 }
 
+// SpotBalances returns the account's non-zero asset balances.
+func (c *Client) SpotBalances(ctx context.Context) ([]common.SpotBalance, error) {
+	info, err := c.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []common.SpotBalance
+	for _, b := range info.Balances {
+		free, _ := strconv.ParseFloat(b.Free, 64)
+		locked, _ := strconv.ParseFloat(b.Locked, 64)
+		if free == 0 && locked == 0 {
+			continue
+		}
+		out = append(out, common.SpotBalance{Asset: strings.ToUpper(b.Asset), Free: free, Locked: locked})
+	}
+	return out, nil
+}
+
+// LastPrice returns symbol's last traded price from the public ticker.
+func (c *Client) LastPrice(ctx context.Context, symbol string) (float64, error) {
+	endpoint := c.baseURL + "/api/v3/ticker/price?" + url.Values{"symbol": {strings.ToUpper(symbol)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("ticker price status %d: %s", resp.StatusCode, string(b))
+	}
+	var res struct {
+		Price string `json:"price"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(res.Price, 64)
+}
+
+// AccountSummary reports spot account type and permissions.
+func (c *Client) AccountSummary(ctx context.Context) (common.AccountSummary, error) {
+	info, err := c.GetAccountInfo(ctx)
+	if err != nil {
+		return common.AccountSummary{}, err
+	}
+	accountType := info.AccountType
+	if accountType == "" {
+		accountType = common.CapabilitySpot
+	}
+	return common.AccountSummary{
+		CanTrade:    info.CanTrade,
+		AccountType: accountType,
+		Permissions: info.Capabilities(),
+	}, nil
+}
+
 // OpenOrder represents a simplified open order view.
 type OpenOrder struct {
-	Symbol  string `json:"symbol"`
-	OrderID int64  `json:"orderId"`
-	Side    string `json:"side"`
-	Type    string `json:"type"`
-	Price   string `json:"price"`
-	OrigQty string `json:"origQty"`
-	ExecQty string `json:"executedQty"`
-	Status  string `json:"status"`
+	Symbol        string `json:"symbol"`
+	OrderID       int64  `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	Price         string `json:"price"`
+	OrigQty       string `json:"origQty"`
+	ExecQty       string `json:"executedQty"`
+	CumQuoteQty   string `json:"cummulativeQuoteQty"` // sic, as spelled by the API
+	Status        string `json:"status"`
 }
 
 // GetOpenOrders returns current open orders; if symbol is empty, all symbols.
@@ -287,7 +464,7 @@ func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]OpenOrder,
 	if symbol != "" {
 		params.Set("symbol", symbol)
 	}
-	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 	endpoint := c.baseURL + "/api/v3/openOrders"
 	body, err := c.doSigned(ctx, http.MethodGet, endpoint, params)
@@ -311,7 +488,7 @@ func (c *Client) GetOrder(ctx context.Context, symbol, orderID string) (*OpenOrd
 	if orderID != "" {
 		params.Set("orderId", orderID)
 	}
-	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 	endpoint := c.baseURL + "/api/v3/order"
 	body, err := c.doSigned(ctx, http.MethodGet, endpoint, params)
@@ -325,6 +502,47 @@ func (c *Client) GetOrder(ctx context.Context, symbol, orderID string) (*OpenOrd
 	return &ord, nil
 }
 
+// QueryOrder implements common.OrderQuerier.
+func (c *Client) QueryOrder(ctx context.Context, symbol, exchangeOrderID string) (common.OrderState, error) {
+	ord, err := c.GetOrder(ctx, symbol, exchangeOrderID)
+	if err != nil {
+		return common.OrderState{}, err
+	}
+	filled, _ := strconv.ParseFloat(ord.ExecQty, 64)
+	st := common.OrderState{Status: mapStatus(ord.Status), FilledQty: filled}
+	if quote, _ := strconv.ParseFloat(ord.CumQuoteQty, 64); filled > 0 && quote > 0 {
+		st.AvgPrice = quote / filled
+	}
+	return st, nil
+}
+
+// ListOpenOrders implements common.OpenOrderLister.
+func (c *Client) ListOpenOrders(ctx context.Context) ([]common.OpenOrder, error) {
+	orders, err := c.GetOpenOrders(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	num := func(v string) float64 {
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	out := make([]common.OpenOrder, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, common.OpenOrder{
+			Symbol:          o.Symbol,
+			ExchangeOrderID: strconv.FormatInt(o.OrderID, 10),
+			ClientID:        o.ClientOrderID,
+			Side:            common.Side(o.Side),
+			Type:            common.OrderType(o.Type),
+			Price:           num(o.Price),
+			Qty:             num(o.OrigQty),
+			FilledQty:       num(o.ExecQty),
+			Status:          mapStatus(o.Status),
+		})
+	}
+	return out, nil
+}
+
 // GetAllOrders returns historical orders; beware of rate limits.
 func (c *Client) GetAllOrders(ctx context.Context, symbol string, limit int) ([]OpenOrder, error) {
 	if c.cfg.APIKey == "" || c.cfg.APISecret == "" {
@@ -335,7 +553,7 @@ func (c *Client) GetAllOrders(ctx context.Context, symbol string, limit int) ([]
 	if limit > 0 {
 		params.Set("limit", strconv.Itoa(limit))
 	}
-	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 	endpoint := c.baseURL + "/api/v3/allOrders"
 	body, err := c.doSigned(ctx, http.MethodGet, endpoint, params)
@@ -377,7 +595,7 @@ func (c *Client) GetMyTrades(ctx context.Context, symbol string, limit int, from
 	if fromID != "" {
 		params.Set("fromId", fromID)
 	}
-	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("timestamp", strconv.FormatInt(c.now(), 10))
 	params.Set("recvWindow", strconv.FormatInt(c.cfg.RecvWindow, 10))
 	endpoint := c.baseURL + "/api/v3/myTrades"
 	body, err := c.doSigned(ctx, http.MethodGet, endpoint, params)
@@ -396,6 +614,12 @@ type orderResponse struct {
 	OrderID       int64  `json:"orderId"`
 	ClientOrderID string `json:"clientOrderId"`
 	Status        string `json:"status"`
+	// Fills is present in FULL responses (the default for MARKET and LIMIT).
+	Fills []struct {
+		TradeID int64  `json:"tradeId"`
+		Price   string `json:"price"`
+		Qty     string `json:"qty"`
+	} `json:"fills"`
 }
 
 func mapStatus(s string) common.OrderStatus {
@@ -421,7 +645,7 @@ func toBinanceTIF(tif common.TimeInForce) common.TimeInForce {
 	if tif == "" {
 		return common.TIFGTC
 	}
-	return tif
+	return common.TimeInForce(strings.ToUpper(string(tif)))
 }
 
 func sign(data, secret string) string {
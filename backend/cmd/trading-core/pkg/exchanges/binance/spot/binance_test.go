@@ -0,0 +1,140 @@
+package spot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"trading-core/pkg/exchanges/common"
+)
+
+func TestSignedRequestsUseServerTimeOffset(t *testing.T) {
+	// The exchange clock runs 10s ahead of ours, twice the recvWindow.
+	const skew = 10 * time.Second
+	serverNow := func() int64 { return time.Now().Add(skew).UnixMilli() }
+
+	var timeCalls, accountCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/time":
+			atomic.AddInt32(&timeCalls, 1)
+			fmt.Fprintf(w, `{"serverTime":%d}`, serverNow())
+		case "/api/v3/account":
+			atomic.AddInt32(&accountCalls, 1)
+			ts, _ := strconv.ParseInt(r.URL.Query().Get("timestamp"), 10, 64)
+			if d := serverNow() - ts; d > 5000 || d < -1000 {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"code":-1021,"msg":"Timestamp for this request is outside of the recvWindow."}`)
+				return
+			}
+			fmt.Fprint(w, `{"accountType":"SPOT","canTrade":true}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{APIKey: "key", APISecret: "secret"})
+	c.baseURL = srv.URL
+
+	// The first request is rejected, resyncs the offset and is retried.
+	info, err := c.GetAccountInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccountInfo with skewed clock: %v", err)
+	}
+	if info.AccountType != "SPOT" {
+		t.Fatalf("unexpected account info: %+v", info)
+	}
+	if off := c.timeSync.Offset(); off < 9000 || off > 11000 {
+		t.Fatalf("offset = %dms, want ~%dms", off, skew.Milliseconds())
+	}
+
+	// Later requests are stamped with the synced offset and pass first time.
+	if _, err := c.GetAccountInfo(context.Background()); err != nil {
+		t.Fatalf("second GetAccountInfo: %v", err)
+	}
+	if got := atomic.LoadInt32(&timeCalls); got != 1 {
+		t.Errorf("server time fetched %d times, want 1", got)
+	}
+	if got := atomic.LoadInt32(&accountCalls); got != 3 {
+		t.Errorf("account endpoint called %d times, want 3 (reject, retry, reuse)", got)
+	}
+}
+
+func TestAllSignedCallsUseSyncedTimestamp(t *testing.T) {
+	const offset = time.Hour
+	var stamps sync.Map // "METHOD path" -> timestamp
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		ts, _ := strconv.ParseInt(r.Form.Get("timestamp"), 10, 64)
+		stamps.Store(r.Method+" "+r.URL.Path, ts)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/order":
+			fmt.Fprint(w, `{"symbol":"BTCUSDT","orderId":1,"status":"NEW"}`)
+		case r.Method == http.MethodGet && (r.URL.Path == "/api/v3/order" || r.URL.Path == "/api/v3/account"):
+			fmt.Fprint(w, `{}`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(Config{APIKey: "key", APISecret: "secret"})
+	c.baseURL = srv.URL
+	c.timeSync = common.NewTimeSync(func() (int64, error) {
+		return time.Now().Add(offset).UnixMilli(), nil
+	})
+	ctx := context.Background()
+	if err := c.timeSync.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	calls := map[string]func() error{
+		"POST /api/v3/order": func() error {
+			_, err := c.SubmitOrder(ctx, common.OrderRequest{Symbol: "BTCUSDT", Side: common.SideBuy, Type: common.OrderTypeMarket, Qty: 0.01})
+			return err
+		},
+		"DELETE /api/v3/order":      func() error { return c.CancelOrder(ctx, "BTCUSDT", "1") },
+		"DELETE /api/v3/openOrders": func() error { return c.CancelAllOpenOrders(ctx, "BTCUSDT") },
+		"GET /api/v3/account":       func() error { _, err := c.GetAccountInfo(ctx); return err },
+		"GET /api/v3/openOrders":    func() error { _, err := c.GetOpenOrders(ctx, "BTCUSDT"); return err },
+		"GET /api/v3/order":         func() error { _, err := c.GetOrder(ctx, "BTCUSDT", "1"); return err },
+		"GET /api/v3/allOrders":     func() error { _, err := c.GetAllOrders(ctx, "BTCUSDT", 10); return err },
+		"GET /api/v3/myTrades":      func() error { _, err := c.GetMyTrades(ctx, "BTCUSDT", 10, ""); return err },
+	}
+	for name, call := range calls {
+		if err := call(); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		v, ok := stamps.Load(name)
+		if !ok {
+			t.Errorf("%s: request not seen", name)
+			continue
+		}
+		want := time.Now().Add(offset).UnixMilli()
+		if d := want - v.(int64); d < 0 || d > 5000 {
+			t.Errorf("%s: timestamp %d is %dms off the synced clock; local time used?", name, v, d)
+		}
+	}
+}
+
+func TestCapabilitiesTreatTradingGroupsAsSpot(t *testing.T) {
+	info := &AccountInfo{CanTrade: true, AccountType: "SPOT", Permissions: []string{"TRD_GRP_002", "TRD_GRP_009", "MARGIN"}}
+	got := info.Capabilities()
+	if len(got) != 2 || got[0] != common.CapabilitySpot || got[1] != "MARGIN" {
+		t.Fatalf("Capabilities() = %v, want [SPOT MARGIN]", got)
+	}
+	if !common.HasCapability(common.FormatCapabilities(got), common.CapabilitySpot) {
+		t.Fatal("a trading-group account should be allowed to trade spot")
+	}
+}
@@ -0,0 +1,43 @@
+package common
+
+import "strings"
+
+// Account capabilities recorded per connection by the capability probe.
+// Spot permissions reported by the venue (e.g. MARGIN, LEVERAGED) are stored as-is.
+const (
+	CapabilitySpot        = "SPOT"
+	CapabilityUSDTFutures = "USDT_FUTURES"
+	CapabilityCoinFutures = "COIN_FUTURES"
+	CapabilityReadOnly    = "READ_ONLY" // key cannot place orders
+)
+
+// RequiredCapability returns the capability an account needs to trade on market.
+func RequiredCapability(market MarketType) string {
+	switch market {
+	case MarketUSDTFut:
+		return CapabilityUSDTFutures
+	case MarketCoinFut:
+		return CapabilityCoinFutures
+	default:
+		return CapabilitySpot
+	}
+}
+
+// FormatCapabilities joins capabilities for storage.
+func FormatCapabilities(caps []string) string {
+	return strings.Join(caps, ",")
+}
+
+// HasCapability reports whether a stored capability list contains want.
+// An empty list means the account was never probed and is treated as capable.
+func HasCapability(stored, want string) bool {
+	if strings.TrimSpace(stored) == "" {
+		return true
+	}
+	for _, c := range strings.Split(stored, ",") {
+		if strings.EqualFold(strings.TrimSpace(c), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Binance error codes for orders that only failed a symbol filter.
+const (
+	CodeFilterFailure    = -1013 // e.g. "Filter failure: LOT_SIZE"
+	CodeInvalidPrecision = -1111 // "Precision is over the maximum defined for this asset."
+)
+
+// CodeTimestampOutsideWindow is Binance's rejection of a request whose
+// timestamp is outside recvWindow of server time (local clock drift).
+const CodeTimestampOutsideWindow = -1021
+
+// CodeNewOrderRejected is Binance's generic order rejection; with the message
+// "Order would immediately match and take." it rejects a LIMIT_MAKER that
+// would have crossed the book.
+const CodeNewOrderRejected = -2010
+
+// APIError is a non-2xx response from an exchange API. Error() keeps the
+// "<op> status <n>: <body>" shape the clients have always logged.
+type APIError struct {
+	Op     string // e.g. "binance POST /api/v3/order"
+	Status int
+	Code   int // venue error code from the body; 0 when absent
+	Msg    string
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s status %d: %s", e.Op, e.Status, e.Body)
+}
+
+// NewAPIError builds an APIError, decoding Binance's {"code":..,"msg":..} body when present.
+func NewAPIError(op string, status int, body []byte) *APIError {
+	e := &APIError{Op: op, Status: status, Body: string(body)}
+	var payload struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Code, e.Msg = payload.Code, payload.Msg
+	}
+	return e
+}
+
+// IsFilterFailure reports whether err is a rejection that rounding quantity or
+// price to the symbol's filters may fix.
+func IsFilterFailure(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == CodeFilterFailure || apiErr.Code == CodeInvalidPrecision
+}
+
+// IsTimestampError reports whether err is a recvWindow rejection that a
+// fresh server-time offset may fix.
+func IsTimestampError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == CodeTimestampOutsideWindow
+}
+
+// IsPostOnlyReject reports whether err rejected a post-only (LIMIT_MAKER)
+// order because it would have matched immediately.
+func IsPostOnlyReject(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeNewOrderRejected {
+		return false
+	}
+	return strings.Contains(strings.ToLower(apiErr.Msg), "immediately match")
+}
+
+// ExchangeFilter is one entry of an exchangeInfo symbol's "filters" array.
+type ExchangeFilter struct {
+	FilterType string `json:"filterType"`
+	TickSize   string `json:"tickSize"`
+	StepSize   string `json:"stepSize"`
+	MinQty     string `json:"minQty"`
+	// Spot MIN_NOTIONAL and NOTIONAL use minNotional; futures MIN_NOTIONAL uses notional.
+	MinNotional string `json:"minNotional"`
+	Notional    string `json:"notional"`
+}
+
+// ApplyFilters copies LOT_SIZE, PRICE_FILTER and minimum-notional values onto info.
+func (info *SymbolInfo) ApplyFilters(filters []ExchangeFilter) {
+	for _, f := range filters {
+		switch f.FilterType {
+		case "LOT_SIZE":
+			info.StepSize, _ = strconv.ParseFloat(f.StepSize, 64)
+			info.MinQty, _ = strconv.ParseFloat(f.MinQty, 64)
+		case "PRICE_FILTER":
+			info.TickSize, _ = strconv.ParseFloat(f.TickSize, 64)
+		case "MIN_NOTIONAL", "NOTIONAL":
+			v := f.MinNotional
+			if v == "" {
+				v = f.Notional
+			}
+			info.MinNotional, _ = strconv.ParseFloat(v, 64)
+		}
+	}
+}
+
+// RoundQty floors qty to the LOT_SIZE step, so the order never grows.
+func (info SymbolInfo) RoundQty(qty float64) float64 {
+	return roundToStep(qty, info.StepSize, true)
+}
+
+// RoundPrice rounds price to the nearest PRICE_FILTER tick.
+func (info SymbolInfo) RoundPrice(price float64) float64 {
+	return roundToStep(price, info.TickSize, false)
+}
+
+func roundToStep(v, step float64, floor bool) float64 {
+	if step <= 0 || v <= 0 {
+		return v
+	}
+	n := v / step
+	if floor {
+		n = math.Floor(n + 1e-9)
+	} else {
+		n = math.Round(n)
+	}
+	// Trim float noise to the step's own precision (e.g. 0.001 -> 3 decimals).
+	decimals := 0
+	if s := strconv.FormatFloat(step, 'f', -1, 64); strings.Contains(s, ".") {
+		decimals = len(s) - strings.Index(s, ".") - 1
+	}
+	out, _ := strconv.ParseFloat(strconv.FormatFloat(n*step, 'f', decimals, 64), 64)
+	return out
+}
+
+// LookupOrderSymbol is LookupMarketSymbol for an order's market, treating an
+// empty market as spot.
+func LookupOrderSymbol(market MarketType, symbol string) (SymbolInfo, bool) {
+	if market == "" {
+		market = MarketSpot
+	}
+	return LookupMarketSymbol(market, symbol)
+}
+
+// AdjustToFilters rounds an order's quantity and prices to the symbol's
+// filters for req.Market. It returns false when no filters are known, nothing
+// changed, or the rounded quantity falls below the minimum.
+func AdjustToFilters(req OrderRequest) (OrderRequest, bool) {
+	info, ok := LookupOrderSymbol(req.Market, req.Symbol)
+	if !ok || (info.StepSize <= 0 && info.TickSize <= 0) {
+		return req, false
+	}
+
+	adj := req
+	adj.Qty = info.RoundQty(req.Qty)
+	adj.Price = info.RoundPrice(req.Price)
+	adj.StopPrice = info.RoundPrice(req.StopPrice)
+	if adj.Qty <= 0 || (info.MinQty > 0 && adj.Qty < info.MinQty) {
+		return req, false
+	}
+	if adj.Qty == req.Qty && adj.Price == req.Price && adj.StopPrice == req.StopPrice {
+		return req, false
+	}
+	return adj, true
+}
+
+// RepriceAwayFromTouch reprices a post-only order rejected for crossing so it
+// may rest: a BUY goes one PRICE_FILTER tick below the best bid, a SELL one
+// tick above the best ask. Without a book quote (bid/ask 0) it steps one tick
+// from the order's own price instead, and it never moves the price toward the
+// book. It returns false when the symbol's tick size is unknown or the new
+// price would not be positive.
+func RepriceAwayFromTouch(req OrderRequest, bid, ask float64) (OrderRequest, bool) {
+	info, ok := LookupOrderSymbol(req.Market, req.Symbol)
+	if !ok || info.TickSize <= 0 || req.Price <= 0 {
+		return req, false
+	}
+	adj := req
+	if req.Side == SideSell {
+		price := req.Price + info.TickSize
+		if ask > 0 && ask+info.TickSize > price {
+			price = ask + info.TickSize
+		}
+		adj.Price = info.RoundPrice(price)
+	} else {
+		price := req.Price - info.TickSize
+		if bid > 0 && bid-info.TickSize < price {
+			price = bid - info.TickSize
+		}
+		adj.Price = info.RoundPrice(price)
+	}
+	if adj.Price <= 0 {
+		return req, false
+	}
+	return adj, true
+}
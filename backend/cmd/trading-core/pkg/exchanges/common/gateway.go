@@ -1,9 +1,159 @@
 package common
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
 // Gateway abstracts a trading venue.
 type Gateway interface {
 	SubmitOrder(ctx context.Context, req OrderRequest) (OrderResult, error)
 	CancelOrder(ctx context.Context, symbol, exchangeOrderID string) error
 }
+
+// AccountSummary is a venue-neutral view of an account's trading permissions.
+type AccountSummary struct {
+	CanTrade    bool
+	AccountType string   // e.g. SPOT, USDT_FUTURES
+	Permissions []string // see Capability* constants
+}
+
+// AccountSummarizer is implemented by gateways that can report account permissions
+// without placing an order (used by connection tests and capability probes).
+type AccountSummarizer interface {
+	AccountSummary(ctx context.Context) (AccountSummary, error)
+}
+
+// OrderState is a venue-neutral snapshot of a submitted order.
+type OrderState struct {
+	Status    OrderStatus
+	FilledQty float64
+	AvgPrice  float64 // average fill price (0 = nothing filled or not reported)
+}
+
+// OrderQuerier is implemented by gateways that can look up a submitted order
+// (used by maker-first routing to poll for fills).
+type OrderQuerier interface {
+	QueryOrder(ctx context.Context, symbol, exchangeOrderID string) (OrderState, error)
+}
+
+// OpenOrder is a venue-neutral view of an order resting on the exchange.
+type OpenOrder struct {
+	Symbol          string
+	ExchangeOrderID string
+	ClientID        string
+	Side            Side
+	Type            OrderType
+	Price           float64
+	Qty             float64
+	FilledQty       float64
+	Status          OrderStatus
+}
+
+// OpenOrderLister is implemented by gateways that can list the account's
+// open orders across all symbols (used by the startup import).
+type OpenOrderLister interface {
+	ListOpenOrders(ctx context.Context) ([]OpenOrder, error)
+}
+
+// MarginAsset is the margin state of one collateral asset of a futures account.
+type MarginAsset struct {
+	Asset            string
+	WalletBalance    float64
+	UnrealizedPnL    float64
+	MarginBalance    float64 // wallet balance + unrealized PnL
+	MaintMargin      float64
+	AvailableBalance float64
+	MarginRatio      float64 // MaintMargin / MarginBalance; the account is liquidated at 1
+}
+
+// MarginPosition is one open futures position with its liquidation risk.
+type MarginPosition struct {
+	Symbol           string
+	PositionSide     string // BOTH, LONG or SHORT
+	Qty              float64
+	EntryPrice       float64
+	MarkPrice        float64
+	UnrealizedPnL    float64
+	Leverage         int
+	MarginType       string // cross or isolated
+	MarginAsset      string // collateral asset (USDT for USDT-M, the base coin for COIN-M)
+	MaintMargin      float64
+	LiquidationPrice float64 // venue estimate; 0 when there is none
+}
+
+// MarginAccount is a venue-neutral view of a futures account's margin.
+type MarginAccount struct {
+	Assets    []MarginAsset
+	Positions []MarginPosition // open positions only
+}
+
+// MarginReporter is implemented by futures gateways that can report wallet
+// balance, margin usage and per-position liquidation prices.
+type MarginReporter interface {
+	MarginAccount(ctx context.Context) (MarginAccount, error)
+}
+
+// SpotBalance is one asset's balance in a spot account.
+type SpotBalance struct {
+	Asset  string
+	Free   float64
+	Locked float64
+}
+
+// SpotBalanceReporter is implemented by spot gateways that can list the
+// account's asset balances.
+type SpotBalanceReporter interface {
+	SpotBalances(ctx context.Context) ([]SpotBalance, error)
+}
+
+// PriceQuoter is implemented by gateways that can quote a symbol's last price.
+type PriceQuoter interface {
+	LastPrice(ctx context.Context, symbol string) (float64, error)
+}
+
+// LeverageReporter is implemented by futures gateways that can report the
+// leverage currently set for a symbol on the account.
+type LeverageReporter interface {
+	SymbolLeverage(ctx context.Context, symbol string) (int, error)
+}
+
+// LeverageCache remembers the leverage last set or read per symbol, so a
+// gateway only calls the leverage endpoint when the setting changes.
+type LeverageCache struct {
+	mu  sync.Mutex
+	lev map[string]int
+}
+
+// Get returns the cached leverage of symbol.
+func (c *LeverageCache) Get(symbol string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lev, ok := c.lev[symbol]
+	return lev, ok
+}
+
+// Set records symbol's leverage.
+func (c *LeverageCache) Set(symbol string, leverage int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lev == nil {
+		c.lev = make(map[string]int)
+	}
+	c.lev[symbol] = leverage
+}
+
+// PositionMarginAdjuster is implemented by futures gateways that can add or
+// remove margin on an isolated position (mType 1 = add, 2 = reduce).
+// positionSide is LONG or SHORT on a hedge-mode account, empty in one-way mode.
+type PositionMarginAdjuster interface {
+	ChangePositionMargin(ctx context.Context, symbol, positionSide string, amount float64, mType int) error
+}
+
+// MarginRatio returns maintenance margin over margin balance (0 without balance).
+func MarginRatio(maintMargin, marginBalance float64) float64 {
+	if marginBalance <= 0 {
+		return 0
+	}
+	return maintMargin / marginBalance
+}
@@ -0,0 +1,187 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SymbolInfo describes how a venue symbol maps to its base and quote assets.
+type SymbolInfo struct {
+	Symbol       string
+	BaseAsset    string
+	QuoteAsset   string
+	ContractType string // empty for spot; e.g. PERPETUAL, CURRENT_QUARTER for futures
+
+	// Trading filters from exchange info (0 = unknown).
+	StepSize    float64 // LOT_SIZE quantity increment
+	TickSize    float64 // PRICE_FILTER price increment
+	MinQty      float64 // LOT_SIZE minimum quantity
+	MinNotional float64 // MIN_NOTIONAL / NOTIONAL minimum price*qty
+}
+
+// fallbackQuoteAssets is checked in order when a symbol is not in the registry.
+// Stablecoins come first so "BTCUSDC" resolves to USDC rather than a shorter match.
+var fallbackQuoteAssets = []string{
+	"FDUSD", "USDT", "USDC", "BUSD", "TUSD",
+	"BTC", "ETH", "BNB", "EUR", "TRY", "USD",
+}
+
+var (
+	symbolMu       sync.RWMutex
+	symbolRegistry = map[string]SymbolInfo{}
+	// marketSymbols holds the tradable symbols per market, once loaded.
+	marketSymbols = map[MarketType]map[string]SymbolInfo{}
+)
+
+// ErrSymbolNotOnMarket is returned when a symbol cannot be traded on a market,
+// e.g. a spot pair sent to a coin-margined futures connection.
+var ErrSymbolNotOnMarket = errors.New("symbol not tradable on market")
+
+// RegisterSymbols stores symbol metadata (typically loaded from exchange info).
+// Later registrations overwrite earlier ones for the same symbol.
+func RegisterSymbols(infos ...SymbolInfo) {
+	symbolMu.Lock()
+	defer symbolMu.Unlock()
+	for _, info := range infos {
+		if info.Symbol == "" {
+			continue
+		}
+		info.Symbol = strings.ToUpper(info.Symbol)
+		info.BaseAsset = strings.ToUpper(info.BaseAsset)
+		info.QuoteAsset = strings.ToUpper(info.QuoteAsset)
+		symbolRegistry[info.Symbol] = info
+	}
+}
+
+// LookupSymbol returns symbol metadata from the registry, falling back to a
+// suffix heuristic for offline use. ok is false only when neither resolves.
+func LookupSymbol(symbol string) (SymbolInfo, bool) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	symbolMu.RLock()
+	info, ok := symbolRegistry[symbol]
+	symbolMu.RUnlock()
+	if ok {
+		return info, true
+	}
+	return guessSymbolInfo(symbol)
+}
+
+// SplitSymbol splits a symbol such as "ETHBTC" into base and quote assets.
+// Returns empty strings when the symbol cannot be resolved.
+func SplitSymbol(symbol string) (base, quote string) {
+	info, ok := LookupSymbol(symbol)
+	if !ok {
+		return "", ""
+	}
+	return info.BaseAsset, info.QuoteAsset
+}
+
+// guessSymbolInfo derives base/quote from known quote suffixes. Delivery and
+// coin-margined symbols ("BTCUSD_PERP", "BTCUSDT_250328") are split on "_" first.
+func guessSymbolInfo(symbol string) (SymbolInfo, bool) {
+	pair, suffix, _ := strings.Cut(symbol, "_")
+	contract := ""
+	switch {
+	case suffix == "PERP":
+		contract = "PERPETUAL"
+	case suffix != "":
+		contract = "DELIVERY"
+	}
+	for _, q := range fallbackQuoteAssets {
+		if len(pair) > len(q) && strings.HasSuffix(pair, q) {
+			return SymbolInfo{
+				Symbol:       symbol,
+				BaseAsset:    strings.TrimSuffix(pair, q),
+				QuoteAsset:   q,
+				ContractType: contract,
+			}, true
+		}
+	}
+	return SymbolInfo{Symbol: symbol}, false
+}
+
+// SymbolInfoSource is implemented by clients that can list venue symbols.
+type SymbolInfoSource interface {
+	GetExchangeInfo(ctx context.Context) ([]SymbolInfo, error)
+}
+
+// LoadSymbols fetches exchange info from src and registers every symbol.
+func LoadSymbols(ctx context.Context, src SymbolInfoSource) (int, error) {
+	infos, err := src.GetExchangeInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	RegisterSymbols(infos...)
+	return len(infos), nil
+}
+
+// LoadMarketSymbols is LoadSymbols for a known market: it also records which
+// symbols that market lists, so ValidateMarketSymbol can check against it.
+func LoadMarketSymbols(ctx context.Context, market MarketType, src SymbolInfoSource) (int, error) {
+	infos, err := src.GetExchangeInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	RegisterSymbols(infos...)
+	RegisterMarketSymbols(market, infos...)
+	return len(infos), nil
+}
+
+// RegisterMarketSymbols replaces the tradable symbol list for market.
+func RegisterMarketSymbols(market MarketType, infos ...SymbolInfo) {
+	set := make(map[string]SymbolInfo, len(infos))
+	for _, info := range infos {
+		if info.Symbol != "" {
+			info.Symbol = strings.ToUpper(info.Symbol)
+			set[info.Symbol] = info
+		}
+	}
+	symbolMu.Lock()
+	defer symbolMu.Unlock()
+	marketSymbols[market] = set
+}
+
+// ValidateMarketSymbol checks that symbol is tradable on market. When the
+// market's exchange info has not been loaded it falls back to naming rules:
+// spot pairs have no contract suffix, USDⓈ-M futures settle in USDT/USDC, and
+// COIN-M futures are USD contracts such as BTCUSD_PERP.
+func ValidateMarketSymbol(market MarketType, symbol string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	symbolMu.RLock()
+	set, loaded := marketSymbols[market]
+	symbolMu.RUnlock()
+	if loaded && len(set) > 0 {
+		if _, ok := set[symbol]; ok {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is not listed on %s", ErrSymbolNotOnMarket, symbol, market)
+	}
+
+	pair, suffix, hasSuffix := strings.Cut(symbol, "_")
+	_, quote := SplitSymbol(pair)
+	ok := true
+	switch market {
+	case MarketSpot:
+		ok = !hasSuffix
+	case MarketUSDTFut:
+		ok = quote == "USDT" || quote == "USDC"
+	case MarketCoinFut:
+		ok = hasSuffix && suffix != "" && quote == "USD"
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s does not look like a %s symbol", ErrSymbolNotOnMarket, symbol, market)
+	}
+	return nil
+}
+
+// LookupMarketSymbol returns symbol metadata (including filters) as listed by
+// a specific market's exchange info.
+func LookupMarketSymbol(market MarketType, symbol string) (SymbolInfo, bool) {
+	symbolMu.RLock()
+	defer symbolMu.RUnlock()
+	info, ok := marketSymbols[market][strings.ToUpper(strings.TrimSpace(symbol))]
+	return info, ok
+}
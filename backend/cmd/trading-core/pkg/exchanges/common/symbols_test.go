@@ -0,0 +1,59 @@
+package common
+
+import "testing"
+
+func TestSplitSymbolFallback(t *testing.T) {
+	cases := []struct {
+		symbol, base, quote string
+	}{
+		{"BTCUSDT", "BTC", "USDT"},
+		{"ETHBTC", "ETH", "BTC"},
+		{"BTCUSDC", "BTC", "USDC"},
+		{"BNBFDUSD", "BNB", "FDUSD"},
+		{"btcusd_perp", "BTC", "USD"},
+		{"UNKNOWN", "", ""},
+	}
+	for _, tc := range cases {
+		base, quote := SplitSymbol(tc.symbol)
+		if base != tc.base || quote != tc.quote {
+			t.Errorf("SplitSymbol(%q) = %q/%q, want %q/%q", tc.symbol, base, quote, tc.base, tc.quote)
+		}
+	}
+}
+
+func TestSplitSymbolPrefersRegistry(t *testing.T) {
+	// PLN is not a fallback quote, so only exchange info can resolve this pair.
+	if base, _ := SplitSymbol("ETHPLN"); base != "" {
+		t.Fatalf("expected unresolved symbol before registration, got base %q", base)
+	}
+	RegisterSymbols(
+		SymbolInfo{Symbol: "ethpln", BaseAsset: "eth", QuoteAsset: "pln"},
+		SymbolInfo{Symbol: "BTCUSDC", BaseAsset: "BTC", QuoteAsset: "USDC", ContractType: "PERPETUAL"},
+	)
+
+	if base, quote := SplitSymbol("ETHPLN"); base != "ETH" || quote != "PLN" {
+		t.Fatalf("expected registered ETH/PLN, got %q/%q", base, quote)
+	}
+	info, ok := LookupSymbol("btcusdc")
+	if !ok || info.ContractType != "PERPETUAL" || info.QuoteAsset != "USDC" {
+		t.Fatalf("expected registered perpetual info, got %+v ok=%v", info, ok)
+	}
+}
+
+func TestValidateMarketSymbolUsesLoadedExchangeInfo(t *testing.T) {
+	// Naming heuristics before exchange info is loaded.
+	if err := ValidateMarketSymbol(MarketSpot, "BTCUSD_PERP"); err == nil {
+		t.Error("coin-M contract should not validate as spot")
+	}
+	if err := ValidateMarketSymbol(MarketUSDTFut, "ETHBTC"); err == nil {
+		t.Error("BTC-quoted pair should not validate as USDⓈ-M futures")
+	}
+
+	RegisterMarketSymbols(MarketCoinFut, SymbolInfo{Symbol: "BTCUSD_PERP"})
+	if err := ValidateMarketSymbol(MarketCoinFut, "btcusd_perp"); err != nil {
+		t.Errorf("listed symbol rejected: %v", err)
+	}
+	if err := ValidateMarketSymbol(MarketCoinFut, "ETHUSD_PERP"); err == nil {
+		t.Error("symbol missing from loaded exchange info should be rejected")
+	}
+}
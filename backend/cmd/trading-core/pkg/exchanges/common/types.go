@@ -20,8 +20,47 @@ const (
 	OrderTypeTakeProfitLimit OrderType = "TAKE_PROFIT_LIMIT"
 	OrderTypeLimitMaker      OrderType = "LIMIT_MAKER"
 	OrderTypeTrailingStop    OrderType = "TRAILING_STOP_MARKET" // Futures only
+
+	// Venue-neutral protective order types accepted by the API; use
+	// NormalizeOrderType to translate them into the venue's native names.
+	OrderTypeStopMarket       OrderType = "STOP_MARKET"
+	OrderTypeTakeProfitMarket OrderType = "TAKE_PROFIT_MARKET"
+	OrderTypeStopLimit        OrderType = "STOP_LIMIT"
+	OrderTypeStop             OrderType = "STOP" // Futures native stop-limit
 )
 
+// NormalizeOrderType maps venue-neutral stop/take-profit types to the native
+// names used by the given market. Other types are returned unchanged.
+func NormalizeOrderType(t OrderType, market MarketType) OrderType {
+	if market == MarketSpot || market == "" {
+		switch t {
+		case OrderTypeStopMarket:
+			return OrderTypeStopLoss
+		case OrderTypeTakeProfitMarket:
+			return OrderTypeTakeProfit
+		case OrderTypeStopLimit:
+			return OrderTypeStopLossLimit
+		}
+		return t
+	}
+
+	// USDT-M / COIN-M futures natively support STOP_MARKET / TAKE_PROFIT_MARKET.
+	if t == OrderTypeStopLimit {
+		return OrderTypeStop
+	}
+	return t
+}
+
+// RequiresStopPrice reports whether the order type needs a trigger price.
+func (t OrderType) RequiresStopPrice() bool {
+	switch t {
+	case OrderTypeStopLoss, OrderTypeStopLossLimit, OrderTypeTakeProfit, OrderTypeTakeProfitLimit,
+		OrderTypeStopMarket, OrderTypeTakeProfitMarket, OrderTypeStopLimit, OrderTypeStop:
+		return true
+	}
+	return false
+}
+
 // TimeInForce captures TIF semantics.
 type TimeInForce string
 
@@ -32,6 +71,28 @@ const (
 	TIFGTX TimeInForce = "GTX" // Post Only / Maker Only
 )
 
+// AcceptsTimeInForce reports whether tif may be sent with this order type on
+// market. An empty tif is always accepted and leaves the venue default (GTC).
+// Market and stop-market orders take no TIF; GTX is futures-only.
+func (t OrderType) AcceptsTimeInForce(tif TimeInForce, market MarketType) bool {
+	switch tif {
+	case "":
+		return true
+	case TIFGTC, TIFIOC, TIFFOK:
+	case TIFGTX:
+		if market == MarketSpot || market == "" {
+			return false
+		}
+	default:
+		return false
+	}
+	switch NormalizeOrderType(t, market) {
+	case OrderTypeLimit, OrderTypeStopLossLimit, OrderTypeTakeProfitLimit, OrderTypeStop:
+		return true
+	}
+	return false
+}
+
 // OrderStatus normalizes exchange status into a small set.
 type OrderStatus string
 
@@ -82,6 +143,8 @@ type OrderResult struct {
 	ExchangeOrderID string
 	Status          OrderStatus
 	ClientID        string
+	Fills           []Fill  // trades reported in the response itself (spot FULL acks); often empty
+	AvgPrice        float64 // average fill price of a FILLED result when known (0 = unknown)
 }
 
 // Fill represents a trade fill update.
@@ -0,0 +1,122 @@
+// Package ids generates time-sortable identifiers for orders and trades.
+package ids
+
+import (
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+)
+
+// crockford is the ULID alphabet (Crockford base32, no I/L/O/U).
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Len is the length of a ULID string.
+const Len = 26
+
+var (
+	mu      sync.Mutex
+	lastMs  uint64
+	lastRnd [10]byte
+)
+
+// New returns a ULID: a 48-bit millisecond timestamp followed by 80 random
+// bits, encoded as 26 Crockford base32 characters. IDs from this process sort
+// lexically in creation order; within one millisecond (or if the clock steps
+// back) the random part is incremented instead of redrawn.
+func New() string {
+	return newAt(time.Now())
+}
+
+func newAt(t time.Time) string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ms := uint64(t.UnixMilli())
+	if ms <= lastMs {
+		ms = lastMs
+		if !increment(&lastRnd) {
+			// 80-bit overflow within a millisecond: borrow the next one.
+			ms++
+			readRandom(&lastRnd)
+		}
+	} else {
+		readRandom(&lastRnd)
+	}
+	lastMs = ms
+
+	var b [16]byte
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*uint(i)))
+	}
+	copy(b[6:], lastRnd[:])
+	return encode(b)
+}
+
+// Time returns the creation time embedded in a ULID.
+func Time(id string) (time.Time, error) {
+	if len(id) != Len {
+		return time.Time{}, errors.New("ids: not a ULID")
+	}
+	var ms uint64
+	// The first 10 characters carry the 48-bit timestamp (50 bits, top 2 zero).
+	for i := 0; i < 10; i++ {
+		v := decodeChar(id[i])
+		if v < 0 {
+			return time.Time{}, errors.New("ids: invalid ULID character")
+		}
+		ms = ms<<5 | uint64(v)
+	}
+	if ms >= 1<<48 {
+		return time.Time{}, errors.New("ids: ULID timestamp overflow")
+	}
+	return time.UnixMilli(int64(ms)), nil
+}
+
+func readRandom(r *[10]byte) {
+	if _, err := rand.Read(r[:]); err != nil {
+		panic("ids: crypto/rand failed: " + err.Error())
+	}
+}
+
+// increment adds one to r as a big-endian integer; false on overflow.
+func increment(r *[10]byte) bool {
+	for i := len(r) - 1; i >= 0; i-- {
+		r[i]++
+		if r[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encode writes 128 bits as 26 base32 characters, most significant first.
+func encode(b [16]byte) string {
+	var out [Len]byte
+	// 130 output bits: the leading 2 are zero padding.
+	var acc uint32
+	bits := 2
+	pos := 0
+	for _, c := range b {
+		acc = acc<<8 | uint32(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = crockford[(acc>>uint(bits))&31]
+			pos++
+		}
+	}
+	return string(out[:])
+}
+
+func decodeChar(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
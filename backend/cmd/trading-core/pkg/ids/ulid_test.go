@@ -0,0 +1,49 @@
+package ids
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSortsInCreationOrder(t *testing.T) {
+	const n = 5000 // many land in the same millisecond
+	prev := New()
+	seen := map[string]bool{prev: true}
+	for i := 1; i < n; i++ {
+		id := New()
+		if len(id) != Len {
+			t.Fatalf("id %q has length %d", id, len(id))
+		}
+		if id <= prev {
+			t.Fatalf("id %d (%s) does not sort after %s", i, id, prev)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %s", id)
+		}
+		seen[id] = true
+		prev = id
+	}
+}
+
+func TestNewStaysMonotonicWhenClockStepsBack(t *testing.T) {
+	now := time.Now().Add(time.Hour)
+	a := newAt(now)
+	b := newAt(now.Add(-time.Minute))
+	if b <= a {
+		t.Fatalf("expected %s > %s after clock step back", b, a)
+	}
+}
+
+func TestTimeRoundTrip(t *testing.T) {
+	at := time.Date(2030, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	got, err := Time(newAt(at))
+	if err != nil {
+		t.Fatalf("Time: %v", err)
+	}
+	if !got.Equal(at) {
+		t.Fatalf("expected %v, got %v", at, got)
+	}
+	if _, err := Time("not-a-ulid"); err == nil {
+		t.Fatal("expected error for malformed id")
+	}
+}
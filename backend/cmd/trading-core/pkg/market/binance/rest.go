@@ -16,6 +16,7 @@ type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Testnet    bool
+	KlinesPath string // defaults to the spot /api/v3/klines
 }
 
 // NewClient builds a REST client; use Testnet to switch base URLs.
@@ -33,6 +34,28 @@ func NewClient(apiKey, apiSecret string, testnet bool) *Client {
 	}
 }
 
+// NewUSDTFuturesClient builds a REST client for USDT-M futures market data.
+func NewUSDTFuturesClient(testnet bool) *Client {
+	c := NewClient("", "", testnet)
+	c.BaseURL = "https://fapi.binance.com"
+	if testnet {
+		c.BaseURL = "https://testnet.binancefuture.com"
+	}
+	c.KlinesPath = "/fapi/v1/klines"
+	return c
+}
+
+// NewCoinFuturesClient builds a REST client for COIN-M futures market data.
+func NewCoinFuturesClient(testnet bool) *Client {
+	c := NewClient("", "", testnet)
+	c.BaseURL = "https://dapi.binance.com"
+	if testnet {
+		c.BaseURL = "https://testnet.binancefuture.com"
+	}
+	c.KlinesPath = "/dapi/v1/klines"
+	return c
+}
+
 // GetKlines fetches historical klines using the public endpoint.
 // Set startTime/endTime to 0 to use default behavior (most recent klines).
 func (c *Client) GetKlines(symbol, interval string, limit int, startTime, endTime int64) ([]Kline, error) {
@@ -49,7 +72,11 @@ func (c *Client) GetKlines(symbol, interval string, limit int, startTime, endTim
 		params.Set("endTime", strconv.FormatInt(endTime, 10))
 	}
 
-	u := fmt.Sprintf("%s/api/v3/klines?%s", c.BaseURL, params.Encode())
+	path := c.KlinesPath
+	if path == "" {
+		path = "/api/v3/klines"
+	}
+	u := fmt.Sprintf("%s%s?%s", c.BaseURL, path, params.Encode())
 	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
 		return nil, err
@@ -70,6 +97,7 @@ func (c *Client) GetKlines(symbol, interval string, limit int, startTime, endTim
 		return nil, err
 	}
 
+	nowMs := time.Now().UnixMilli()
 	klines := make([]Kline, 0, len(raw))
 	for _, item := range raw {
 		// Binance returns 12 fields per kline
@@ -77,6 +105,8 @@ func (c *Client) GetKlines(symbol, interval string, limit int, startTime, endTim
 			continue
 		}
 		k := Kline{
+			Symbol:              symbol,
+			Interval:            interval,
 			OpenTime:            toInt64(item[0]),
 			Open:                toFloat(item[1]),
 			High:                toFloat(item[2]),
@@ -90,6 +120,7 @@ func (c *Client) GetKlines(symbol, interval string, limit int, startTime, endTim
 			TakerBuyQuoteVolume: toFloat(item[10]),
 			// item[11] is unused/ignore
 		}
+		k.IsFinal = k.CloseTime > 0 && k.CloseTime < nowMs
 		klines = append(klines, k)
 	}
 	return klines, nil
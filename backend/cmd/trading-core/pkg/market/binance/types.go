@@ -3,6 +3,7 @@ package market
 // Kline represents a single candlestick with all official Binance fields.
 type Kline struct {
 	Symbol              string  // trading pair symbol
+	Interval            string  // candle interval (e.g. "1m", "1h"); empty when unknown
 	OpenTime            int64   // 0: Open time (ms)
 	Open                float64 // 1: Open price
 	High                float64 // 2: High price
@@ -14,6 +15,7 @@ type Kline struct {
 	NumberOfTrades      int     // 8: Number of trades
 	TakerBuyBaseVolume  float64 // 9: Taker buy base asset volume
 	TakerBuyQuoteVolume float64 // 10: Taker buy quote asset volume
+	IsFinal             bool    // stream "x": bar is closed (REST: close time has passed)
 	// Field 11 is unused/ignore
 }
 
@@ -41,6 +43,43 @@ type Trade struct {
 	IsBuyerMaker bool
 }
 
+// AggTrade represents an aggregate trade: fills of one taker order at one price.
+type AggTrade struct {
+	Symbol       string
+	AggTradeID   int64
+	Price        float64
+	Qty          float64
+	FirstTradeID int64
+	LastTradeID  int64
+	Time         int64
+	IsBuyerMaker bool
+}
+
+// Price sources a strategy can be fed from.
+const (
+	PriceSourceKline    = "kline"    // kline close (default)
+	PriceSourceTrade    = "trade"    // last trade
+	PriceSourceAggTrade = "aggTrade" // last aggregate trade
+	PriceSourceBookMid  = "bookMid"  // best bid/ask midpoint
+)
+
+// ValidPriceSource reports whether source is one of the PriceSource* constants.
+func ValidPriceSource(source string) bool {
+	switch source {
+	case PriceSourceKline, PriceSourceTrade, PriceSourceAggTrade, PriceSourceBookMid:
+		return true
+	}
+	return false
+}
+
+// PriceTick is a price sampled from a non-kline stream, tagged with its source.
+type PriceTick struct {
+	Symbol string
+	Price  float64
+	Source string // one of the PriceSource* constants
+	Time   int64
+}
+
 // DepthUpdate represents a diff depth update snapshot.
 type DepthUpdate struct {
 	Symbol string
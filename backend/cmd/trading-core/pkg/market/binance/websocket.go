@@ -3,16 +3,37 @@ package market
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// ErrParseAnomaly marks a payload that decoded but failed sanity checks
+// (unexpected event type, missing symbol, non-positive price). Such messages
+// are dropped instead of being forwarded as zero-valued ticks.
+var ErrParseAnomaly = errors.New("binance ws: payload failed validation")
+
+var parseAnomalies atomic.Uint64
+
+// ParseAnomalies returns how many stream payloads were dropped by validation.
+func ParseAnomalies() uint64 {
+	return parseAnomalies.Load()
+}
+
+// anomaly counts a rejected payload and returns a wrapped ErrParseAnomaly.
+func anomaly(format string, args ...any) error {
+	parseAnomalies.Add(1)
+	return fmt.Errorf("%w: %s", ErrParseAnomaly, fmt.Sprintf(format, args...))
+}
+
 // StreamClient manages lightweight streaming from Binance public websockets.
 type StreamClient struct {
 	StreamURL       string
@@ -23,7 +44,7 @@ type StreamClient struct {
 // ReconnectConfig defines the reconnection behavior.
 type ReconnectConfig struct {
 	Enabled      bool          // Whether auto-reconnect is enabled
-	MaxRetries   int           // Maximum number of reconnection attempts (0 = unlimited)
+	MaxRetries   int           // Maximum number of reconnection attempts (0 = unlimited, retries until stopped)
 	InitialDelay time.Duration // Initial delay before first reconnect attempt
 	MaxDelay     time.Duration // Maximum delay between reconnect attempts
 	Multiplier   float64       // Delay multiplier for exponential backoff
@@ -62,13 +83,21 @@ func NewStreamClientWithConfig(testnet bool, reconnectCfg *ReconnectConfig) *Str
 	return c
 }
 
+// retryLimit formats a MaxRetries value for logging.
+func retryLimit(n int) string {
+	if n == 0 {
+		return "∞"
+	}
+	return strconv.Itoa(n)
+}
+
 // calculateBackoff returns the delay for the given retry attempt using exponential backoff.
 func (c *StreamClient) calculateBackoff(attempt int) time.Duration {
 	if c.ReconnectConfig == nil {
 		return time.Second
 	}
 	delay := float64(c.ReconnectConfig.InitialDelay)
-	for i := 0; i < attempt; i++ {
+	for i := 0; i < attempt && delay < float64(c.ReconnectConfig.MaxDelay); i++ {
 		delay *= c.ReconnectConfig.Multiplier
 	}
 	if time.Duration(delay) > c.ReconnectConfig.MaxDelay {
@@ -114,12 +143,9 @@ func (c *StreamClient) SubscribeKlines(ctx context.Context, symbol, interval str
 			return nil, fmt.Errorf("reconnect disabled")
 		}
 
-		maxRetries := c.ReconnectConfig.MaxRetries
-		if maxRetries == 0 {
-			maxRetries = 100 // Effectively unlimited but bounded
-		}
+		maxRetries := c.ReconnectConfig.MaxRetries // 0 = retry until stopped
 
-		for attempt := 0; attempt < maxRetries; attempt++ {
+		for attempt := 0; maxRetries == 0 || attempt < maxRetries; attempt++ {
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -129,7 +155,7 @@ func (c *StreamClient) SubscribeKlines(ctx context.Context, symbol, interval str
 			}
 
 			delay := c.calculateBackoff(attempt)
-			log.Printf("🔄 [%s] WebSocket reconnecting in %v (attempt %d/%d)", symbol, delay, attempt+1, maxRetries)
+			log.Printf("🔄 [%s] WebSocket reconnecting in %v (attempt %d/%s)", symbol, delay, attempt+1, retryLimit(maxRetries))
 
 			select {
 			case <-time.After(delay):
@@ -277,6 +303,58 @@ func (c *StreamClient) SubscribeTrades(ctx context.Context, symbol string) (<-ch
 	return out, stop, nil
 }
 
+// SubscribeAggTrades subscribes to the aggregate trade stream and emits parsed
+// aggregate trades: less noisy than @trade, finer than @kline.
+func (c *StreamClient) SubscribeAggTrades(ctx context.Context, symbol string) (<-chan AggTrade, func(), error) {
+	stream := fmt.Sprintf("%s@aggTrade", strings.ToLower(symbol))
+	u := fmt.Sprintf("%s/%s", c.StreamURL, stream)
+
+	conn, _, err := c.dialer.DialContext(ctx, u, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial binance ws aggTrades: %w", err)
+	}
+
+	out := make(chan AggTrade, 100)
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			_ = conn.Close()
+			close(out)
+		})
+	}
+
+	go func() {
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) ||
+					strings.Contains(err.Error(), "use of closed network connection") {
+					return
+				}
+				log.Printf("binance ws aggTrade read error: %v", err)
+				return
+			}
+
+			parsed, err := parseAggTradeMessage(msg)
+			if err != nil {
+				log.Printf("binance ws aggTrade parse error: %v", err)
+				continue
+			}
+			out <- parsed
+		}
+	}()
+
+	return out, stop, nil
+}
+
 // SubscribeBookTicker subscribes to best bid/ask updates.
 func (c *StreamClient) SubscribeBookTicker(ctx context.Context, symbol string) (<-chan BookTicker, func(), error) {
 	stream := fmt.Sprintf("%s@bookTicker", symbol)
@@ -330,7 +408,16 @@ func (c *StreamClient) SubscribeBookTicker(ctx context.Context, symbol string) (
 
 // SubscribeDepth subscribes to diff depth stream.
 func (c *StreamClient) SubscribeDepth(ctx context.Context, symbol string) (<-chan DepthUpdate, func(), error) {
-	stream := fmt.Sprintf("%s@depth", symbol)
+	return c.subscribeDepth(ctx, symbol, fmt.Sprintf("%s@depth", symbol))
+}
+
+// SubscribePartialDepth subscribes to the top-levels book snapshot stream
+// (levels is 5, 10 or 20), pushed every 100ms.
+func (c *StreamClient) SubscribePartialDepth(ctx context.Context, symbol string, levels int) (<-chan DepthUpdate, func(), error) {
+	return c.subscribeDepth(ctx, symbol, fmt.Sprintf("%s@depth%d@100ms", symbol, levels))
+}
+
+func (c *StreamClient) subscribeDepth(ctx context.Context, symbol, stream string) (<-chan DepthUpdate, func(), error) {
 	u := fmt.Sprintf("%s/%s", c.StreamURL, stream)
 
 	conn, _, err := c.dialer.DialContext(ctx, u, nil)
@@ -372,6 +459,10 @@ func (c *StreamClient) SubscribeDepth(ctx context.Context, symbol string) (<-cha
 				log.Printf("binance ws depth parse error: %v", err)
 				continue
 			}
+			if parsed.Symbol == "" {
+				// Partial depth snapshots carry no symbol.
+				parsed.Symbol = strings.ToUpper(symbol)
+			}
 			out <- parsed
 		}
 	}()
@@ -430,10 +521,11 @@ func (c *StreamClient) SubscribeTicker(ctx context.Context, symbol string) (<-ch
 	return out, stop, nil
 }
 
-// parseKlineMessage decodes only the fields we need.
+// parseKlineMessage decodes only the fields we need and validates them.
 func parseKlineMessage(msg []byte) (Kline, error) {
 	var raw struct {
-		Data struct {
+		EventType string `json:"e"`
+		Data      struct {
 			StartTime int64       `json:"t"`
 			CloseTime int64       `json:"T"`
 			Symbol    string      `json:"s"`
@@ -443,13 +535,18 @@ func parseKlineMessage(msg []byte) (Kline, error) {
 			High      interface{} `json:"h"`
 			Low       interface{} `json:"l"`
 			Volume    interface{} `json:"v"`
+			IsFinal   bool        `json:"x"`
 		} `json:"k"`
 	}
 	if err := json.Unmarshal(msg, &raw); err != nil {
 		return Kline{}, err
 	}
-	return Kline{
+	if raw.EventType != "" && raw.EventType != "kline" {
+		return Kline{}, anomaly("unexpected event type %q on kline stream", raw.EventType)
+	}
+	k := Kline{
 		Symbol:    raw.Data.Symbol,
+		Interval:  raw.Data.Interval,
 		OpenTime:  raw.Data.StartTime,
 		CloseTime: raw.Data.CloseTime,
 		Open:      toFloat(raw.Data.Open),
@@ -457,7 +554,17 @@ func parseKlineMessage(msg []byte) (Kline, error) {
 		High:      toFloat(raw.Data.High),
 		Low:       toFloat(raw.Data.Low),
 		Volume:    toFloat(raw.Data.Volume),
-	}, nil
+		IsFinal:   raw.Data.IsFinal,
+	}
+	switch {
+	case k.Symbol == "":
+		return Kline{}, anomaly("kline without symbol")
+	case k.Open <= 0 || k.Close <= 0 || k.High <= 0 || k.Low <= 0:
+		return Kline{}, anomaly("kline %s with non-positive price (o=%v h=%v l=%v c=%v)", k.Symbol, k.Open, k.High, k.Low, k.Close)
+	case k.High < k.Low:
+		return Kline{}, anomaly("kline %s with high %v below low %v", k.Symbol, k.High, k.Low)
+	}
+	return k, nil
 }
 
 func parseTradeMessage(msg []byte) (Trade, error) {
@@ -472,6 +579,9 @@ func parseTradeMessage(msg []byte) (Trade, error) {
 	if err := json.Unmarshal(msg, &raw); err != nil {
 		return Trade{}, err
 	}
+	if raw.Symbol == "" || toFloat(raw.Price) <= 0 {
+		return Trade{}, anomaly("trade with missing symbol or non-positive price")
+	}
 	return Trade{
 		Symbol:       raw.Symbol,
 		Price:        toFloat(raw.Price),
@@ -481,6 +591,41 @@ func parseTradeMessage(msg []byte) (Trade, error) {
 	}, nil
 }
 
+func parseAggTradeMessage(msg []byte) (AggTrade, error) {
+	var raw struct {
+		EventType    string      `json:"e"`
+		EventTime    interface{} `json:"E"` // declared so it cannot match "e"
+		Symbol       string      `json:"s"`
+		AggTradeID   int64       `json:"a"`
+		Price        interface{} `json:"p"`
+		Qty          interface{} `json:"q"`
+		FirstTradeID int64       `json:"f"`
+		LastTradeID  int64       `json:"l"`
+		TradeTime    interface{} `json:"T"`
+		BuyerIsMM    bool        `json:"m"`
+		BestMatch    bool        `json:"M"` // declared so it cannot match "m"
+	}
+	if err := json.Unmarshal(msg, &raw); err != nil {
+		return AggTrade{}, err
+	}
+	if raw.EventType != "" && raw.EventType != "aggTrade" {
+		return AggTrade{}, anomaly("unexpected event type %q on aggTrade stream", raw.EventType)
+	}
+	if raw.Symbol == "" || toFloat(raw.Price) <= 0 {
+		return AggTrade{}, anomaly("aggTrade with missing symbol or non-positive price")
+	}
+	return AggTrade{
+		Symbol:       raw.Symbol,
+		AggTradeID:   raw.AggTradeID,
+		Price:        toFloat(raw.Price),
+		Qty:          toFloat(raw.Qty),
+		FirstTradeID: raw.FirstTradeID,
+		LastTradeID:  raw.LastTradeID,
+		Time:         toInt64(raw.TradeTime),
+		IsBuyerMaker: raw.BuyerIsMM,
+	}, nil
+}
+
 func parseBookTickerMessage(msg []byte) (BookTicker, error) {
 	var raw struct {
 		Symbol string      `json:"s"`
@@ -490,6 +635,9 @@ func parseBookTickerMessage(msg []byte) (BookTicker, error) {
 	if err := json.Unmarshal(msg, &raw); err != nil {
 		return BookTicker{}, err
 	}
+	if raw.Symbol == "" || toFloat(raw.Bid) <= 0 || toFloat(raw.Ask) <= 0 {
+		return BookTicker{}, anomaly("book ticker with missing symbol or non-positive bid/ask")
+	}
 	return BookTicker{
 		Symbol:   raw.Symbol,
 		BidPrice: toFloat(raw.Bid),
@@ -504,10 +652,16 @@ func parseDepthMessage(msg []byte) (DepthUpdate, error) {
 		Time   interface{}     `json:"E"`
 		Bids   [][]interface{} `json:"b"`
 		Asks   [][]interface{} `json:"a"`
+		// Partial depth snapshots use full key names.
+		SnapshotBids [][]interface{} `json:"bids"`
+		SnapshotAsks [][]interface{} `json:"asks"`
 	}
 	if err := json.Unmarshal(msg, &raw); err != nil {
 		return DepthUpdate{}, err
 	}
+	if len(raw.Bids) == 0 && len(raw.Asks) == 0 {
+		raw.Bids, raw.Asks = raw.SnapshotBids, raw.SnapshotAsks
+	}
 	var bids [][2]float64
 	for _, b := range raw.Bids {
 		if len(b) < 2 {
@@ -539,6 +693,9 @@ func parseTickerMessage(msg []byte) (Ticker, error) {
 	if err := json.Unmarshal(msg, &raw); err != nil {
 		return Ticker{}, err
 	}
+	if raw.Symbol == "" || toFloat(raw.Last) <= 0 {
+		return Ticker{}, anomaly("ticker with missing symbol or non-positive price")
+	}
 	return Ticker{
 		Symbol: raw.Symbol,
 		Price:  toFloat(raw.Last),
@@ -0,0 +1,85 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeKlinesDropsMalformedPayload(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Renamed price fields decode to zero; must not reach consumers.
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"kline","k":{"s":"BTCUSDT","i":"1m","open":"1","close":"1"}}`))
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"kline","k":{"s":"BTCUSDT","i":"1m","o":"100","h":"101","l":"99","c":"100.5","v":"3","x":true}}`))
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c := NewStreamClientWithConfig(false, &ReconnectConfig{Enabled: false})
+	c.StreamURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	before := ParseAnomalies()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ch, stop, err := c.SubscribeKlines(ctx, "BTCUSDT", "1m")
+	if err != nil {
+		t.Fatalf("SubscribeKlines: %v", err)
+	}
+	defer stop()
+
+	select {
+	case k := <-ch:
+		if k.Close != 100.5 || !k.IsFinal {
+			t.Fatalf("first forwarded kline should be the valid one, got %+v", k)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for valid kline")
+	}
+	if got := ParseAnomalies() - before; got != 1 {
+		t.Errorf("anomaly counter delta = %d, want 1", got)
+	}
+}
+
+func TestParseKlineMessageRejectsWrongEventType(t *testing.T) {
+	_, err := parseKlineMessage([]byte(`{"e":"trade","k":{"s":"BTCUSDT","o":"1","h":"1","l":"1","c":"1"}}`))
+	if !errors.Is(err, ErrParseAnomaly) {
+		t.Fatalf("expected ErrParseAnomaly, got %v", err)
+	}
+}
+
+func TestParseAggTradeMessage(t *testing.T) {
+	msg := []byte(`{"e":"aggTrade","E":1672515782136,"s":"BNBBTC","a":12345,"p":"0.001","q":"100","f":100,"l":105,"T":1672515782136,"m":true,"M":true}`)
+	got, err := parseAggTradeMessage(msg)
+	if err != nil {
+		t.Fatalf("parseAggTradeMessage: %v", err)
+	}
+	want := AggTrade{
+		Symbol:       "BNBBTC",
+		AggTradeID:   12345,
+		Price:        0.001,
+		Qty:          100,
+		FirstTradeID: 100,
+		LastTradeID:  105,
+		Time:         1672515782136,
+		IsBuyerMaker: true,
+	}
+	if got != want {
+		t.Fatalf("parseAggTradeMessage = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseAggTradeMessage([]byte(`{"e":"trade","s":"BNBBTC","p":"0.001"}`)); !errors.Is(err, ErrParseAnomaly) {
+		t.Fatalf("wrong event type: expected ErrParseAnomaly, got %v", err)
+	}
+}
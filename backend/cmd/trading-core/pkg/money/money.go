@@ -0,0 +1,211 @@
+// Package money provides a fixed-point decimal type for balances, fees and PnL.
+//
+// Amounts are stored as int64 counts of 1e-8 units, the precision Binance
+// reports quantities and balances in, so repeated additions never drift the
+// way float64 sums do. The representable range is about ±92 billion.
+package money
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Decimals is the number of fractional digits an Amount keeps.
+const Decimals = 8
+
+// scale is 10^Decimals.
+const scale = 100_000_000
+
+// Amount is a fixed-point decimal with Decimals fractional digits.
+type Amount int64
+
+// Zero is the zero amount.
+const Zero Amount = 0
+
+// MaxAmount and MinAmount are the largest and smallest representable amounts.
+const (
+	MaxAmount Amount = math.MaxInt64
+	MinAmount Amount = math.MinInt64
+)
+
+// FromFloat converts a float, rounding to the nearest 1e-8. NaN converts to
+// zero; values out of range, including infinities, saturate.
+func FromFloat(f float64) Amount {
+	if math.IsNaN(f) {
+		return Zero
+	}
+	r := math.Round(f * scale)
+	switch {
+	case r >= math.MaxInt64: // float64(MaxInt64) is 2^63, one past the range
+		return MaxAmount
+	case r <= math.MinInt64:
+		return MinAmount
+	}
+	return Amount(r)
+}
+
+// FromUnits builds an amount from raw 1e-8 units.
+func FromUnits(units int64) Amount { return Amount(units) }
+
+// Parse converts an exchange decimal string (e.g. "0.00012345") exactly,
+// rounding half away from zero past the eighth fractional digit.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Zero, fmt.Errorf("money: empty amount")
+	}
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" {
+		return Zero, fmt.Errorf("money: invalid amount %q", s)
+	}
+	roundUp := false
+	if len(frac) > Decimals {
+		roundUp = frac[Decimals] >= '5'
+		for _, r := range frac[Decimals:] {
+			if r < '0' || r > '9' {
+				return Zero, fmt.Errorf("money: invalid amount %q", s)
+			}
+		}
+		frac = frac[:Decimals]
+	}
+	frac += strings.Repeat("0", Decimals-len(frac))
+
+	var units int64
+	for _, r := range intPart + frac {
+		if r < '0' || r > '9' {
+			return Zero, fmt.Errorf("money: invalid amount %q", s)
+		}
+		if units > (math.MaxInt64-9)/10 {
+			return Zero, fmt.Errorf("money: amount %q out of range", s)
+		}
+		units = units*10 + int64(r-'0')
+	}
+	if roundUp {
+		units++
+	}
+	if neg {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+// AddFloats adds two float amounts in fixed point. Use it for float-typed
+// fields (JSON/DB models) that accumulate money over many updates.
+func AddFloats(a, b float64) float64 { return FromFloat(a).Add(FromFloat(b)).Float64() }
+
+// Units returns the raw 1e-8 units.
+func (a Amount) Units() int64 { return int64(a) }
+
+// Float64 converts to float64 for APIs and display.
+func (a Amount) Float64() float64 { return float64(a) / scale }
+
+// Add returns a + b.
+func (a Amount) Add(b Amount) Amount { return a + b }
+
+// Sub returns a - b.
+func (a Amount) Sub(b Amount) Amount { return a - b }
+
+// Neg returns -a.
+func (a Amount) Neg() Amount { return -a }
+
+// Abs returns |a|.
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Mul returns a * b (e.g. qty * price), rounded half away from zero.
+func (a Amount) Mul(b Amount) Amount {
+	p := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+	return fromBig(roundDiv(p, big.NewInt(scale)))
+}
+
+// Div returns a / b rounded half away from zero; dividing by zero returns zero.
+func (a Amount) Div(b Amount) Amount {
+	if b == 0 {
+		return Zero
+	}
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(scale))
+	return fromBig(roundDiv(n, big.NewInt(int64(b))))
+}
+
+// roundDiv returns n/d rounded half away from zero.
+func roundDiv(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if new(big.Int).Abs(new(big.Int).Lsh(r, 1)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		if (n.Sign() < 0) != (d.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// fromBig converts a unit count, saturating outside the int64 range.
+func fromBig(units *big.Int) Amount {
+	if units.IsInt64() {
+		return Amount(units.Int64())
+	}
+	if units.Sign() < 0 {
+		return MinAmount
+	}
+	return MaxAmount
+}
+
+// Cmp returns -1, 0 or +1 as a is less than, equal to or greater than b.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether a is zero.
+func (a Amount) IsZero() bool { return a == 0 }
+
+// String formats the amount exactly, trimming trailing fractional zeros.
+func (a Amount) String() string {
+	u := int64(a)
+	sign := ""
+	if u < 0 {
+		sign = "-"
+	}
+	abs := new(big.Int).Abs(big.NewInt(u)).String()
+	if len(abs) <= Decimals {
+		abs = strings.Repeat("0", Decimals-len(abs)+1) + abs
+	}
+	intPart, frac := abs[:len(abs)-Decimals], strings.TrimRight(abs[len(abs)-Decimals:], "0")
+	if frac == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + frac
+}
+
+// MarshalJSON encodes the amount as a JSON number.
+func (a Amount) MarshalJSON() ([]byte, error) { return []byte(a.String()), nil }
+
+// UnmarshalJSON accepts a JSON number or decimal string.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	v, err := Parse(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,63 @@
+package money
+
+import "testing"
+
+func TestSumOfFractionalTradesIsExact(t *testing.T) {
+	// 10,000 fills of 0.001 @ 0.3 with a 0.1% fee: notional 0.0003, fee 0.0000003.
+	qty, price, feeRate := FromFloat(0.001), FromFloat(0.3), FromUnits(100_000)
+
+	var notional, fees Amount
+	for i := 0; i < 10000; i++ {
+		n := qty.Mul(price)
+		notional = notional.Add(n)
+		fees = fees.Add(n.Mul(feeRate))
+	}
+
+	if got := notional.String(); got != "3" {
+		t.Errorf("notional = %s, want 3", got)
+	}
+	if got := fees.String(); got != "0.003" {
+		t.Errorf("fees = %s, want 0.003", got)
+	}
+	if got := notional.Sub(fees); got != FromUnits(299_700_000) {
+		t.Errorf("net = %s, want 2.997", got)
+	}
+}
+
+func TestParseAndString(t *testing.T) {
+	cases := map[string]string{
+		"0.00012345":   "0.00012345",
+		"-12.5":        "-12.5",
+		"100":          "100",
+		"0.000000015":  "0.00000002", // rounds half away from zero
+		"-0.000000015": "-0.00000002",
+	}
+	for in, want := range cases {
+		a, err := Parse(in)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", in, err)
+		}
+		if got := a.String(); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", in, got, want)
+		}
+	}
+	if _, err := Parse("1.2x"); err == nil {
+		t.Error("expected error for malformed amount")
+	}
+}
+
+func TestOutOfRangeSaturates(t *testing.T) {
+	if got := FromFloat(1e12); got != MaxAmount {
+		t.Errorf("FromFloat(1e12) = %d, want MaxAmount", got)
+	}
+	if got := FromFloat(-1e12); got != MinAmount {
+		t.Errorf("FromFloat(-1e12) = %d, want MinAmount", got)
+	}
+	huge := FromFloat(9e10)
+	if got := huge.Mul(FromFloat(2)); got != MaxAmount {
+		t.Errorf("9e10 * 2 = %s, want MaxAmount", got)
+	}
+	if got := huge.Neg().Div(FromFloat(0.5)); got != MinAmount {
+		t.Errorf("-9e10 / 0.5 = %s, want MinAmount", got)
+	}
+}
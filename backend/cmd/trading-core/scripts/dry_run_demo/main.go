@@ -5,10 +5,9 @@ import (
 	"log"
 	"time"
 
-	"github.com/google/uuid"
-
 	"trading-core/internal/order"
 	"trading-core/pkg/config"
+	"trading-core/pkg/ids"
 )
 
 // dry_run_demo simulates a few realistic order flows using the in‑memory
@@ -49,7 +48,7 @@ func main() {
 
 	log.Printf("[SCENARIO 1] Simple BUY then SELL on %s", symbol)
 	buyOrder := order.Order{
-		ID:        uuid.NewString(),
+		ID:        ids.New(),
 		Symbol:    symbol,
 		Side:      "BUY",
 		Type:      "LIMIT",
@@ -61,7 +60,7 @@ func main() {
 	dry.Execute(ctx, buyOrder)
 
 	sellOrder := order.Order{
-		ID:        uuid.NewString(),
+		ID:        ids.New(),
 		Symbol:    symbol,
 		Side:      "SELL",
 		Type:      "LIMIT",
@@ -74,7 +73,7 @@ func main() {
 
 	log.Printf("[SCENARIO 2] Oversized BUY to trigger insufficient balance")
 	bigBuy := order.Order{
-		ID:        uuid.NewString(),
+		ID:        ids.New(),
 		Symbol:    symbol,
 		Side:      "BUY",
 		Type:      "LIMIT",
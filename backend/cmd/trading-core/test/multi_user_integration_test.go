@@ -664,7 +664,7 @@ func TestMultiUserConnectionOwnershipEnforced(t *testing.T) {
 		map[string]string{
 			"username": "bob",
 			"email":    "bob@example.com",
-			"password": "Bob123!",
+			"password": "Bob12345!",
 		}, &regB)
 	if status != http.StatusCreated || regB.UserID == "" {
 		t.Fatalf("register bob failed, status=%d, resp=%+v", status, regB)
@@ -683,7 +683,7 @@ func TestMultiUserConnectionOwnershipEnforced(t *testing.T) {
 	status = doRequest(t, client, http.MethodPost, baseURL+"/api/v1/auth/login", "",
 		map[string]string{
 			"email":    "bob@example.com",
-			"password": "Bob123!",
+			"password": "Bob12345!",
 		}, &loginB)
 	if status != http.StatusOK || loginB.Token == "" {
 		t.Fatalf("login bob failed, status=%d, resp=%+v", status, loginB)